@@ -5,15 +5,100 @@ import (
 )
 
 // ApplicationConfig represents additional configuration for an application.
-type ApplicationConfig struct{}
+type ApplicationConfig struct {
+	UpdateChannel string            `json:"update_channel,omitempty" yaml:"update_channel,omitempty"` // Update channel to track instead of the system's.
+	UpdateVersion string            `json:"update_version,omitempty" yaml:"update_version,omitempty"` // Version to hold the application at.
+	Hooks         *ApplicationHooks `json:"hooks,omitempty"          yaml:"hooks,omitempty"`          // Override the hooks declared by the application.
+
+	// Application-specific settings, delivered to the application when it's initialized and after each update.
+	Settings map[string]string `json:"settings,omitempty" yaml:"settings,omitempty"`
+}
+
+// ApplicationHookFailurePolicy represents what happens when a hook fails.
+type ApplicationHookFailurePolicy string
+
+const (
+	// ApplicationHookFailurePolicyAbort aborts the operation the hook was run for.
+	ApplicationHookFailurePolicyAbort ApplicationHookFailurePolicy = "abort"
+
+	// ApplicationHookFailurePolicyIgnore logs the failure and carries on with the operation.
+	ApplicationHookFailurePolicyIgnore ApplicationHookFailurePolicy = "ignore"
+)
+
+// ApplicationHook represents a command run by IncusOS at a given step of an update or shutdown.
+type ApplicationHook struct {
+	Command   []string                     `json:"command"              yaml:"command"`              // Absolute path of the executable, followed by its arguments.
+	Timeout   int                          `json:"timeout,omitempty"    yaml:"timeout,omitempty"`    // In seconds, defaults to 300.
+	OnFailure ApplicationHookFailurePolicy `json:"on_failure,omitempty" yaml:"on_failure,omitempty"` // Defaults to "abort".
+}
+
+// ApplicationHooks represents the hooks of an application.
+type ApplicationHooks struct {
+	PreUpdate   *ApplicationHook `json:"pre_update,omitempty"    yaml:"pre_update,omitempty"`    // Before a new version of the application is activated.
+	PostUpdate  *ApplicationHook `json:"post_update,omitempty"   yaml:"post_update,omitempty"`   // Once the updated application is healthy.
+	PreOSUpdate *ApplicationHook `json:"pre_os_update,omitempty" yaml:"pre_os_update,omitempty"` // Before an OS update is applied.
+	PreShutdown *ApplicationHook `json:"pre_shutdown,omitempty"  yaml:"pre_shutdown,omitempty"`  // Before the application is stopped as the system shuts down.
+}
+
+// ApplicationCertificate represents the server certificate of an application and any ongoing rotation.
+type ApplicationCertificate struct {
+	Fingerprint         string     `json:"fingerprint"                    yaml:"fingerprint"`
+	Expiry              *time.Time `json:"expiry,omitempty"               yaml:"expiry,omitempty"`               // In system's timezone.
+	NextFingerprint     string     `json:"next_fingerprint,omitempty"     yaml:"next_fingerprint,omitempty"`     // Staged replacement certificate, not yet in use.
+	PreviousFingerprint string     `json:"previous_fingerprint,omitempty" yaml:"previous_fingerprint,omitempty"` // Replaced certificate, exposed during the overlap window.
+}
+
+// ApplicationHealthStatus represents the health of an application.
+type ApplicationHealthStatus string
+
+const (
+	// ApplicationHealthStatusHealthy is reported when the last health check succeeded.
+	ApplicationHealthStatusHealthy ApplicationHealthStatus = "healthy"
+
+	// ApplicationHealthStatusUnhealthy is reported when the last health check failed.
+	ApplicationHealthStatusUnhealthy ApplicationHealthStatus = "unhealthy"
+)
+
+// ApplicationHealthEvent represents a change in the health of an application, or an automatic restart.
+type ApplicationHealthEvent struct {
+	Time      time.Time               `json:"time"              yaml:"time"` // In system's timezone.
+	Status    ApplicationHealthStatus `json:"status"            yaml:"status"`
+	Message   string                  `json:"message,omitempty" yaml:"message,omitempty"`
+	Restarted bool                    `json:"restarted"         yaml:"restarted"`
+}
+
+// ApplicationHealth represents the health of an application, as periodically checked since the system started.
+type ApplicationHealth struct {
+	Status      ApplicationHealthStatus  `json:"status"                 yaml:"status"`
+	Message     string                   `json:"message,omitempty"      yaml:"message,omitempty"`      // Reason of the last failed check.
+	LastCheck   time.Time                `json:"last_check"             yaml:"last_check"`             // In system's timezone.
+	Failures    int                      `json:"failures"               yaml:"failures"`               // Consecutive failed checks.
+	Restarts    int                      `json:"restarts"               yaml:"restarts"`               // Automatic restarts since the application was last healthy.
+	NextRestart *time.Time               `json:"next_restart,omitempty" yaml:"next_restart,omitempty"` // In system's timezone.
+	History     []ApplicationHealthEvent `json:"history"                yaml:"history"`                // Most recent last.
+}
 
 // Application represents the state and configuration of a generic application.
 type Application struct {
 	State struct {
-		Initialized  bool       `json:"initialized"             yaml:"initialized"`
-		Version      string     `json:"version"                 yaml:"version"`
-		LastRestored *time.Time `json:"last_restored,omitempty" yaml:"last_restored,omitempty"` // In system's timezone.
+		Initialized     bool                    `json:"initialized"              yaml:"initialized"`
+		Version         string                  `json:"version"                  yaml:"version"`
+		StagedVersion   string                  `json:"staged_version,omitempty" yaml:"staged_version,omitempty"`     // Downloaded but not yet applied.
+		LastRestored    *time.Time              `json:"last_restored,omitempty"  yaml:"last_restored,omitempty"`      // In system's timezone.
+		PreviousVersion string                  `json:"previous_version,omitempty" yaml:"previous_version,omitempty"` // Kept to roll back a failed update.
+		FailedVersion   string                  `json:"failed_version,omitempty"   yaml:"failed_version,omitempty"`   // Rolled back update, which won't be retried.
+		Certificate     *ApplicationCertificate `incusos:"-"                     json:"certificate,omitempty"    yaml:"certificate,omitempty"`
+		Health          *ApplicationHealth      `incusos:"-"                     json:"health,omitempty"         yaml:"health,omitempty"`
+		Running         bool                    `incusos:"-"                     json:"running"                  yaml:"running"`
+		Operation       string                  `incusos:"-"                     json:"operation,omitempty"      yaml:"operation,omitempty"` // Long running operation in progress, such as "backup" or "restore".
 	} `json:"state" yaml:"state"`
 
 	Config ApplicationConfig `json:"config" yaml:"config"`
 }
+
+// ApplicationListEntry represents an installed application, as returned when listing applications with recursion.
+type ApplicationListEntry struct {
+	Name string `json:"name" yaml:"name"`
+
+	Application `yaml:",inline"`
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"time"
+)
+
+// ClusterPeer represents another IncusOS system discovered on the local network.
+type ClusterPeer struct {
+	Name         string            `json:"name"         yaml:"name"`
+	Addresses    []string          `json:"addresses"    yaml:"addresses"`
+	Port         int               `json:"port"         yaml:"port"`
+	OSName       string            `json:"os_name"      yaml:"os_name"`
+	OSVersion    string            `json:"os_version"   yaml:"os_version"`
+	Applications map[string]string `json:"applications" yaml:"applications"` // Version of each installed application.
+	LastSeen     time.Time         `json:"last_seen"    yaml:"last_seen"`
+}
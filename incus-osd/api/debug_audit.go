@@ -0,0 +1,18 @@
+package api
+
+import (
+	"time"
+)
+
+// DebugAuditEntry records a single mutating call made to the REST API.
+type DebugAuditEntry struct {
+	Time          time.Time `json:"time"                     yaml:"time"`
+	Source        string    `json:"source"                   yaml:"source"`   // Either "local" for the local socket or "remote" for the remote API.
+	Identity      string    `json:"identity"                 yaml:"identity"` // Peer credentials of local callers or client certificate of remote ones.
+	Method        string    `json:"method"                   yaml:"method"`
+	Endpoint      string    `json:"endpoint"                 yaml:"endpoint"`
+	PayloadSha256 string    `json:"payload_sha256,omitempty" yaml:"payload_sha256,omitempty"` // Digest of the request body, if any.
+	PayloadSize   int       `json:"payload_size"             yaml:"payload_size"`
+	StatusCode    int       `json:"status_code"              yaml:"status_code"`
+	Duration      int64     `json:"duration"                 yaml:"duration"` // Time taken to handle the request, in milliseconds.
+}
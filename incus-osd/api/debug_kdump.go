@@ -0,0 +1,13 @@
+package api
+
+import (
+	"time"
+)
+
+// DebugCrashDump describes a kernel crash dump captured by the kdump service.
+type DebugCrashDump struct {
+	Name  string    `json:"name"  yaml:"name"`
+	Time  time.Time `json:"time"  yaml:"time"`
+	Files []string  `json:"files" yaml:"files"`
+	Size  int64     `json:"size"  yaml:"size"` // Total size of the dump files, in bytes.
+}
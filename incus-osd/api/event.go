@@ -0,0 +1,128 @@
+package api
+
+import (
+	"time"
+)
+
+// EventType represents the type of an event sent to event subscribers.
+type EventType string
+
+const (
+	// EventTypeNotification is sent for every system notification, whether or not it's routed to a notification backend.
+	EventTypeNotification EventType = "notification"
+
+	// EventTypeLag is sent to a subscriber after some of its events were dropped because it couldn't keep up.
+	EventTypeLag EventType = "lag"
+
+	// EventTypeLifecycle is sent when the state of an update, a service or the system changes.
+	EventTypeLifecycle EventType = "lifecycle"
+)
+
+// EventTypes lists all the supported event types.
+var EventTypes = []EventType{
+	EventTypeNotification,
+	EventTypeLag,
+	EventTypeLifecycle,
+}
+
+// EventDropPolicy represents what happens to new events when a subscriber's queue is full.
+type EventDropPolicy string
+
+const (
+	// EventDropPolicyOldest drops the oldest queued event to make room for the new one.
+	EventDropPolicyOldest EventDropPolicy = "drop-oldest"
+
+	// EventDropPolicyNewest drops the new event, keeping the queued ones.
+	EventDropPolicyNewest EventDropPolicy = "drop-newest"
+
+	// EventDropPolicyDisconnect disconnects the subscriber.
+	EventDropPolicyDisconnect EventDropPolicy = "disconnect"
+)
+
+// Event defines a single event sent to event subscribers.
+type Event struct {
+	Type      EventType `json:"type"      yaml:"type"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Metadata  any       `json:"metadata"  yaml:"metadata"` // SystemNotificationsEvent, EventLag or EventLifecycle, depending on the type.
+}
+
+// EventLag holds the metadata of a lag event.
+type EventLag struct {
+	Dropped uint64 `json:"dropped" yaml:"dropped"` // Number of events dropped since the previous lag event.
+}
+
+// EventLifecycleAction represents what happened for a lifecycle event.
+type EventLifecycleAction string
+
+const (
+	// EventLifecycleUpdateAvailable is sent when a new OS or application update is found.
+	EventLifecycleUpdateAvailable EventLifecycleAction = "update-available"
+
+	// EventLifecycleUpdateDownloadProgress is sent as an update download progresses.
+	EventLifecycleUpdateDownloadProgress EventLifecycleAction = "update-download-progress"
+
+	// EventLifecycleServiceStarted is sent when a service is started.
+	EventLifecycleServiceStarted EventLifecycleAction = "service-started"
+
+	// EventLifecycleServiceStopped is sent when a service is stopped.
+	EventLifecycleServiceStopped EventLifecycleAction = "service-stopped"
+
+	// EventLifecycleApplicationRemoved is sent when an application is removed from the system.
+	EventLifecycleApplicationRemoved EventLifecycleAction = "application-removed"
+
+	// EventLifecycleApplicationUnhealthy is sent when an application fails its health check.
+	EventLifecycleApplicationUnhealthy EventLifecycleAction = "application-unhealthy"
+
+	// EventLifecycleApplicationRestarted is sent when an unhealthy application is automatically restarted.
+	EventLifecycleApplicationRestarted EventLifecycleAction = "application-restarted"
+
+	// EventLifecycleApplicationRecovered is sent when an unhealthy application passes its health check again.
+	EventLifecycleApplicationRecovered EventLifecycleAction = "application-recovered"
+
+	// EventLifecycleApplicationBackupProgress is sent as an application backup is streamed.
+	EventLifecycleApplicationBackupProgress EventLifecycleAction = "application-backup-progress"
+
+	// EventLifecycleApplicationBackupCreated is sent when an application backup was fully streamed.
+	EventLifecycleApplicationBackupCreated EventLifecycleAction = "application-backup-created"
+
+	// EventLifecycleApplicationRestoreProgress is sent as an application backup is uploaded for restoration.
+	EventLifecycleApplicationRestoreProgress EventLifecycleAction = "application-restore-progress"
+
+	// EventLifecycleApplicationRestored is sent when an application backup was restored.
+	EventLifecycleApplicationRestored EventLifecycleAction = "application-restored"
+
+	// EventLifecycleApplicationRolledBack is sent when an application update is rolled back after failing its health check.
+	EventLifecycleApplicationRolledBack EventLifecycleAction = "application-rolled-back"
+
+	// EventLifecycleApplicationHookFailed is sent when an application hook fails.
+	EventLifecycleApplicationHookFailed EventLifecycleAction = "application-hook-failed"
+
+	// EventLifecycleSystemSeedApplied is sent when seed data is re-applied on an installed system.
+	EventLifecycleSystemSeedApplied EventLifecycleAction = "system-seed-applied"
+
+	// EventLifecycleSystemConfigImported is sent when a system configuration document is imported.
+	EventLifecycleSystemConfigImported EventLifecycleAction = "system-config-imported"
+
+	// EventLifecycleRebootPending is sent when a reboot is required to finalize an update.
+	EventLifecycleRebootPending EventLifecycleAction = "reboot-pending"
+
+	// EventLifecycleEncryptionWarning is sent when the encrypted volumes require attention.
+	EventLifecycleEncryptionWarning EventLifecycleAction = "encryption-warning"
+)
+
+// EventLifecycle holds the metadata of a lifecycle event.
+type EventLifecycle struct {
+	Action  EventLifecycleAction `json:"action"            yaml:"action"`
+	Source  string               `json:"source"            yaml:"source"`            // API path of the affected entity.
+	Context map[string]any       `json:"context,omitempty" yaml:"context,omitempty"` // Action-specific details.
+}
+
+// EventSubscriber defines a struct that holds information about a connected event subscriber.
+type EventSubscriber struct {
+	Types      []EventType     `json:"types"       yaml:"types"` // All events if empty.
+	DropPolicy EventDropPolicy `json:"drop_policy" yaml:"drop_policy"`
+	QueueSize  int             `json:"queue_size"  yaml:"queue_size"`
+	Queued     int             `json:"queued"      yaml:"queued"`
+	Dropped    uint64          `json:"dropped"     yaml:"dropped"` // Total number of events dropped for this subscriber.
+	Connected  time.Time       `json:"connected"   yaml:"connected"`
+}
@@ -0,0 +1,46 @@
+package api
+
+// HealthStatus represents the coarse health of the system.
+type HealthStatus string
+
+const (
+	// HealthStatusOK is used when the system is fully functional.
+	HealthStatusOK HealthStatus = "ok"
+
+	// HealthStatusDegraded is used when the system is running but one of its components is failing.
+	HealthStatusDegraded HealthStatus = "degraded"
+)
+
+// Health is returned by the health probe endpoint. It intentionally doesn't include any detail about the system.
+type Health struct {
+	Status HealthStatus `json:"status" yaml:"status"`
+}
+
+// HealthSeverity represents how severe the outcome of a health check is.
+type HealthSeverity string
+
+const (
+	// HealthSeverityOK is used when the check passed.
+	HealthSeverityOK HealthSeverity = "ok"
+
+	// HealthSeverityWarning is used when the check found an issue which doesn't prevent the system from working.
+	HealthSeverityWarning HealthSeverity = "warning"
+
+	// HealthSeverityCritical is used when the check found an issue which prevents the system from working properly.
+	HealthSeverityCritical HealthSeverity = "critical"
+)
+
+// HealthCheck represents the outcome of a single health check.
+type HealthCheck struct {
+	Name     string         `json:"name"              yaml:"name"` // One of "state", "tpm", "services", "disk", "time" or "provider".
+	Severity HealthSeverity `json:"severity"          yaml:"severity"`
+	Message  string         `json:"message,omitempty" yaml:"message,omitempty"` // Description of the issue, if any.
+}
+
+// HealthReport represents the detailed health of the system. The status is degraded as soon as one check is critical,
+// while the severity is the highest one among all the checks.
+type HealthReport struct {
+	Status   HealthStatus   `json:"status"   yaml:"status"`
+	Severity HealthSeverity `json:"severity" yaml:"severity"`
+	Checks   []HealthCheck  `json:"checks"   yaml:"checks"`
+}
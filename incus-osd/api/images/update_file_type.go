@@ -34,6 +34,9 @@ const (
 	// UpdateFileTypeUpdateSecureboot represents a SecureBoot key update.
 	UpdateFileTypeUpdateSecureboot UpdateFileType = "update-secureboot"
 
+	// UpdateFileTypeUpdateSecurebootDbx represents a signed UEFI dbx revocation list update.
+	UpdateFileTypeUpdateSecurebootDbx UpdateFileType = "update-secureboot-dbx"
+
 	// UpdateFileTypeApplication represents an application.
 	UpdateFileTypeApplication UpdateFileType = "application"
 )
@@ -49,6 +52,7 @@ var UpdateFileTypes = map[UpdateFileType]struct{}{
 	UpdateFileTypeUpdateUsr:                {},
 	UpdateFileTypeUpdateUsrVerity:          {},
 	UpdateFileTypeUpdateUsrVeritySignature: {},
+	UpdateFileTypeUpdateSecurebootDbx:      {},
 	UpdateFileTypeApplication:              {},
 }
 
@@ -1,5 +1,9 @@
 package seed
 
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
 // Applications represents the applications seed file.
 type Applications struct {
 	Version string `json:"version" yaml:"version"`
@@ -9,5 +13,6 @@ type Applications struct {
 
 // Application represents a single application with the applications seed.
 type Application struct {
-	Name string `json:"name" yaml:"name"`
+	Name   string                 `json:"name"             yaml:"name"`
+	Config *api.ApplicationConfig `json:"config,omitempty" yaml:"config,omitempty"` // Update channel, hooks and application-specific settings.
 }
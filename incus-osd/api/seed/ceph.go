@@ -0,0 +1,12 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Ceph represents the Ceph client service seed.
+type Ceph struct {
+	api.ServiceCephConfig `yaml:",inline"`
+
+	Version string `json:"version" yaml:"version"`
+}
@@ -7,9 +7,22 @@ type Install struct {
 	ForceInstall bool           `json:"force_install" yaml:"force_install"` // If true, ignore any existing data on target install disk.
 	ForceReboot  bool           `json:"force_reboot"  yaml:"force_reboot"`  // If true, reboot the system automatically upon completion rather than waiting for the install media to be removed.
 	Target       *InstallTarget `json:"target"        yaml:"target"`        // Optional selector for the target install disk; if not set, expect a single drive to be present.
+
+	AllowMissingTPM      bool   `json:"allow_missing_tpm"     yaml:"allow_missing_tpm"`     // If true, allow installing on systems without a working TPM, in which case the encrypted volumes are only protected by a passphrase.
+	EncryptionPassphrase string `json:"encryption_passphrase" yaml:"encryption_passphrase"` // Passphrase protecting the encrypted volumes when installing without a TPM.
+
+	LocalDataSize  string `json:"local_data_size,omitempty"  yaml:"local_data_size,omitempty"`  // Size of the local data partition, such as "200GiB"; if not set, it fills the rest of the target disk.
+	WipeOtherDisks bool   `json:"wipe_other_disks,omitempty" yaml:"wipe_other_disks,omitempty"` // If true, wipe the partition tables and signatures of all the other non-removable disks.
 }
 
 // InstallTarget defines options used to select the target install disk.
+// All the provided criteria must match.
 type InstallTarget struct {
-	ID string `json:"id" yaml:"id"` // Name as listed in /dev/disk/by-id/, glob supported.
+	ID            string `json:"id"                       yaml:"id"`                       // Name as listed in /dev/disk/by-id/, glob supported.
+	NVMeNamespace int    `json:"nvme_namespace,omitempty" yaml:"nvme_namespace,omitempty"` // Only consider the NVMe namespace with this ID.
+	Serial        string `json:"serial,omitempty"         yaml:"serial,omitempty"`         // Serial number of the disk.
+	WWN           string `json:"wwn,omitempty"            yaml:"wwn,omitempty"`            // World Wide Name of the disk.
+	Path          string `json:"path,omitempty"           yaml:"path,omitempty"`           // Device path, such as /dev/sda or a /dev/disk/by-path/ link.
+	MinSize       string `json:"min_size,omitempty"       yaml:"min_size,omitempty"`       // Minimum size of the disk, such as "500GB".
+	MaxSize       string `json:"max_size,omitempty"       yaml:"max_size,omitempty"`       // Maximum size of the disk, such as "2TB".
 }
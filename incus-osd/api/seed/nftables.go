@@ -0,0 +1,12 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Nftables represents the nftables firewall service seed.
+type Nftables struct {
+	api.ServiceNftablesConfig `yaml:",inline"`
+
+	Version string `json:"version" yaml:"version"`
+}
@@ -0,0 +1,12 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// NTP represents the NTP service seed.
+type NTP struct {
+	api.ServiceNTPConfig `yaml:",inline"`
+
+	Version string `json:"version" yaml:"version"`
+}
@@ -0,0 +1,15 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Security represents the security seed.
+type Security struct {
+	NetworkUnlock *api.SystemSecurityNetworkUnlock `json:"network_unlock" yaml:"network_unlock"`
+
+	UpdateTrustAnchors        []string `json:"update_trust_anchors"         yaml:"update_trust_anchors"`         // PEM encoded CA certificates additionally trusted to sign the update metadata.
+	ReplaceUpdateTrustAnchors bool     `json:"replace_update_trust_anchors" yaml:"replace_update_trust_anchors"` // Only trust the provided certificates, rather than also the built-in one.
+
+	Version string `json:"version" yaml:"version"`
+}
@@ -0,0 +1,12 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Tuning represents the kernel tuning service seed.
+type Tuning struct {
+	api.ServiceTuningConfig `yaml:",inline"`
+
+	Version string `json:"version" yaml:"version"`
+}
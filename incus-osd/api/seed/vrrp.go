@@ -0,0 +1,12 @@
+package seed
+
+import (
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// VRRP represents the VRRP service seed.
+type VRRP struct {
+	api.ServiceVRRPConfig `yaml:",inline"`
+
+	Version string `json:"version" yaml:"version"`
+}
@@ -2,10 +2,11 @@ package api
 
 // ServiceCephCluster represents a single Ceph cluster.
 type ServiceCephCluster struct {
-	FSID         string                        `json:"fsid"          yaml:"fsid"`
-	Monitors     []string                      `json:"monitors"      yaml:"monitors"`
-	Keyrings     map[string]ServiceCephKeyring `json:"keyrings"      yaml:"keyrings"`
-	ClientConfig map[string]string             `json:"client_config" yaml:"client_config"`
+	FSID          string                        `json:"fsid"           yaml:"fsid"`
+	Monitors      []string                      `json:"monitors"       yaml:"monitors"`
+	Keyrings      map[string]ServiceCephKeyring `json:"keyrings"       yaml:"keyrings"`
+	ClientConfig  map[string]string             `json:"client_config"  yaml:"client_config"`
+	MessengerMode string                        `json:"messenger_mode" yaml:"messenger_mode"` // Messenger v2 connection mode, either "crc" or "secure" (defaults to the cluster's preference).
 }
 
 // ServiceCephKeyring represents a single Ceph keyring entry.
@@ -0,0 +1,21 @@
+package api
+
+// ServiceConsoleConfig represents additional configuration for the serial console service.
+type ServiceConsoleConfig struct {
+	Enabled       bool   `json:"enabled"        yaml:"enabled"`
+	Device        string `json:"device"         yaml:"device"`         // Serial device name, such as "ttyS0".
+	BaudRate      int    `json:"baud_rate"      yaml:"baud_rate"`      // Defaults to 115200.
+	StatusDisplay bool   `json:"status_display" yaml:"status_display"` // Show the IncusOS status console on the device.
+}
+
+// ServiceConsoleState represents the state for the serial console service.
+type ServiceConsoleState struct {
+	KernelConsoles []string `json:"kernel_consoles" yaml:"kernel_consoles"` // Devices currently used by the kernel as consoles.
+}
+
+// ServiceConsole represents the state and configuration of the serial console service.
+type ServiceConsole struct {
+	State ServiceConsoleState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceConsoleConfig `json:"config" yaml:"config"`
+}
@@ -1,12 +1,29 @@
 package api
 
-// ServiceISCSITarget represents a single ISCSI target.
-type ServiceISCSITarget struct {
-	Target  string `json:"target"  yaml:"target"`
+// ServiceISCSIPortal represents an additional portal through which an ISCSI target can be reached.
+type ServiceISCSIPortal struct {
 	Address string `json:"address" yaml:"address"`
 	Port    int    `json:"port"    yaml:"port"`
 }
 
+// ServiceISCSICHAP represents the CHAP credentials used to log into an ISCSI target.
+type ServiceISCSICHAP struct {
+	Username       string `json:"username"        yaml:"username"`
+	Password       string `json:"password"        yaml:"password"`
+	MutualUsername string `json:"mutual_username" yaml:"mutual_username"` // Credentials used by the target to authenticate itself (optional).
+	MutualPassword string `json:"mutual_password" yaml:"mutual_password"`
+}
+
+// ServiceISCSITarget represents a single ISCSI target.
+type ServiceISCSITarget struct {
+	Target  string               `json:"target"            yaml:"target"`
+	Address string               `json:"address"           yaml:"address"`
+	Port    int                  `json:"port"              yaml:"port"`
+	Portals []ServiceISCSIPortal `json:"portals,omitempty" yaml:"portals,omitempty"` // Additional discovery portals, such as for multipath.
+	CHAP    *ServiceISCSICHAP    `json:"chap,omitempty"    yaml:"chap,omitempty"`
+	Logout  bool                 `json:"logout"            yaml:"logout"` // Keep the target configured but logged out.
+}
+
 // ServiceISCSIConfig represents additional configuration for the ISCSI service.
 type ServiceISCSIConfig struct {
 	Enabled bool                 `json:"enabled" yaml:"enabled"`
@@ -20,7 +37,15 @@ type ServiceISCSI struct {
 	Config ServiceISCSIConfig `json:"config" yaml:"config"`
 }
 
+// ServiceISCSISession represents an active ISCSI session.
+type ServiceISCSISession struct {
+	Target string `json:"target" yaml:"target"`
+	Portal string `json:"portal" yaml:"portal"`
+	State  string `json:"state"  yaml:"state"`
+}
+
 // ServiceISCSIState represents the state for the ISCSI service.
 type ServiceISCSIState struct {
-	InitiatorName string `json:"initiator_name" yaml:"initiator_name"`
+	InitiatorName string                `json:"initiator_name" yaml:"initiator_name"`
+	Sessions      []ServiceISCSISession `json:"sessions"       yaml:"sessions"`
 }
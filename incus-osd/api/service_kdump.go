@@ -0,0 +1,19 @@
+package api
+
+// ServiceKdumpConfig represents additional configuration for the kdump service.
+type ServiceKdumpConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// ServiceKdump represents the state and configuration of the kdump service.
+type ServiceKdump struct {
+	State ServiceKdumpState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceKdumpConfig `json:"config" yaml:"config"`
+}
+
+// ServiceKdumpState represents the state for the kdump service.
+type ServiceKdumpState struct {
+	Loaded         bool  `json:"loaded"          yaml:"loaded"`          // Whether the capture kernel is loaded.
+	ReservedMemory int64 `json:"reserved_memory" yaml:"reserved_memory"` // Memory reserved for the capture kernel, in bytes.
+}
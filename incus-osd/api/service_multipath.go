@@ -21,10 +21,41 @@ type ServiceMultipathPath struct {
 	Status string `json:"status" yaml:"status"`
 }
 
+// ServiceMultipathSettings represents the multipath settings which can be overridden globally, per-vendor or per-WWN.
+type ServiceMultipathSettings struct {
+	PathSelector       string `json:"path_selector"        yaml:"path_selector"`        // Such as "service-time 0".
+	PathGroupingPolicy string `json:"path_grouping_policy" yaml:"path_grouping_policy"` // Such as "multibus" or "group_by_prio".
+	NoPathRetry        string `json:"no_path_retry"        yaml:"no_path_retry"`        // Either "queue", "fail" or a number of retries.
+}
+
+// ServiceMultipathVendor represents the multipath settings for the devices of a given vendor and product.
+type ServiceMultipathVendor struct {
+	ServiceMultipathSettings `yaml:",inline"`
+
+	Vendor  string `json:"vendor"  yaml:"vendor"`  // Regular expression matching the SCSI vendor.
+	Product string `json:"product" yaml:"product"` // Regular expression matching the SCSI product (optional).
+}
+
+// ServiceMultipathBlacklistDevice represents a vendor and product to exclude from multipath.
+type ServiceMultipathBlacklistDevice struct {
+	Vendor  string `json:"vendor"  yaml:"vendor"`
+	Product string `json:"product" yaml:"product"`
+}
+
+// ServiceMultipathBlacklist represents the devices to exclude from multipath.
+type ServiceMultipathBlacklist struct {
+	WWNs    []string                          `json:"wwns"    yaml:"wwns"`
+	Devices []ServiceMultipathBlacklistDevice `json:"devices" yaml:"devices"`
+}
+
 // ServiceMultipathConfig represents additional configuration for the Multipath service.
 type ServiceMultipathConfig struct {
-	Enabled bool     `json:"enabled" yaml:"enabled"`
-	WWNs    []string `json:"wwns"    yaml:"wwns"`
+	Enabled   bool                                `json:"enabled"   yaml:"enabled"`
+	WWNs      []string                            `json:"wwns"      yaml:"wwns"`
+	Defaults  ServiceMultipathSettings            `json:"defaults"  yaml:"defaults"`
+	Vendors   []ServiceMultipathVendor            `json:"vendors"   yaml:"vendors"`
+	Settings  map[string]ServiceMultipathSettings `json:"settings"  yaml:"settings"` // Per-WWN settings, keyed by WWN.
+	Blacklist ServiceMultipathBlacklist           `json:"blacklist" yaml:"blacklist"`
 }
 
 // ServiceMultipath represents the state and configuration of the Multipath service.
@@ -0,0 +1,23 @@
+package api
+
+// ServiceNftablesConfig represents additional configuration for the nftables service.
+type ServiceNftablesConfig struct {
+	Enabled            bool     `json:"enabled"             yaml:"enabled"`
+	ManagementNetworks []string `json:"management_networks" yaml:"management_networks"` // Networks (CIDR) allowed to reach the management ports, any if empty.
+	TCPPorts           []int    `json:"tcp_ports"           yaml:"tcp_ports"`           // Management TCP ports, such as the Incus API or the remote API.
+	UDPPorts           []int    `json:"udp_ports"           yaml:"udp_ports"`
+	DefaultPolicy      string   `json:"default_policy"      yaml:"default_policy"` // Either "drop" (default) or "accept".
+}
+
+// ServiceNftablesState represents the state for the nftables service.
+type ServiceNftablesState struct {
+	Active     bool     `json:"active"     yaml:"active"`
+	Interfaces []string `json:"interfaces" yaml:"interfaces"` // Management interfaces the policy applies to.
+}
+
+// ServiceNftables represents the state and configuration of the nftables service.
+type ServiceNftables struct {
+	State ServiceNftablesState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceNftablesConfig `json:"config" yaml:"config"`
+}
@@ -0,0 +1,22 @@
+package api
+
+// ServiceNTPConfig represents additional configuration for the NTP service.
+type ServiceNTPConfig struct {
+	Enabled         bool     `json:"enabled"          yaml:"enabled"`
+	Servers         []string `json:"servers"          yaml:"servers"`
+	FallbackServers []string `json:"fallback_servers" yaml:"fallback_servers"` // Used when none of the servers can be reached.
+}
+
+// ServiceNTP represents the state and configuration of the NTP service.
+type ServiceNTP struct {
+	State ServiceNTPState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceNTPConfig `json:"config" yaml:"config"`
+}
+
+// ServiceNTPState represents the state for the NTP service.
+type ServiceNTPState struct {
+	Synchronized  bool   `json:"synchronized"             yaml:"synchronized"`
+	ServerName    string `json:"server_name,omitempty"    yaml:"server_name,omitempty"`    // Server currently used for synchronization.
+	ServerAddress string `json:"server_address,omitempty" yaml:"server_address,omitempty"` // Address of the server currently used for synchronization.
+}
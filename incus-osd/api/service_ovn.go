@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // ServiceOVNConfig represents additional configuration for the OVN service.
 type ServiceOVNConfig struct {
 	Enabled              bool   `json:"enabled"                yaml:"enabled"`
@@ -13,7 +17,11 @@ type ServiceOVNConfig struct {
 }
 
 // ServiceOVNState represents state for the OVN service.
-type ServiceOVNState struct{}
+type ServiceOVNState struct {
+	TLSClientCertificateFingerprint string     `json:"tls_client_certificate_fingerprint" yaml:"tls_client_certificate_fingerprint"`
+	TLSClientCertificateExpiry      *time.Time `json:"tls_client_certificate_expiry"      yaml:"tls_client_certificate_expiry"`
+	TLSCACertificateExpiry          *time.Time `json:"tls_ca_certificate_expiry"          yaml:"tls_ca_certificate_expiry"` // Earliest expiry of the CA certificates.
+}
 
 // ServiceOVN represents the state and configuration of the OVN service.
 type ServiceOVN struct {
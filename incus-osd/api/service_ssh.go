@@ -0,0 +1,21 @@
+package api
+
+// ServiceSSHConfig represents additional configuration for the SSH service.
+type ServiceSSHConfig struct {
+	Enabled             bool     `json:"enabled"               yaml:"enabled"`
+	Port                int      `json:"port"                  yaml:"port"`                  // Defaults to 22.
+	AuthorizedKeys      []string `json:"authorized_keys"       yaml:"authorized_keys"`       // Public keys allowed to log in as root, in authorized_keys format.
+	ConsolePasswordHash string   `json:"console_password_hash" yaml:"console_password_hash"` // crypt(3) hash of the root password for the local console recovery login, disabled if empty.
+}
+
+// ServiceSSHState represents the state for the SSH service.
+type ServiceSSHState struct {
+	HostKeyFingerprints []string `json:"host_key_fingerprints" yaml:"host_key_fingerprints"` // SHA256 fingerprints of the server host keys.
+}
+
+// ServiceSSH represents the state and configuration of the SSH service.
+type ServiceSSH struct {
+	State ServiceSSHState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceSSHConfig `json:"config" yaml:"config"`
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"time"
+)
+
+// ServiceStatus represents the runtime status of a service.
+type ServiceStatus struct {
+	Status      string              `json:"status"       yaml:"status"` // One of "running", "stopped", "degraded" or "failed".
+	Units       []ServiceUnitStatus `json:"units"        yaml:"units"`
+	LastFailure string              `json:"last_failure" yaml:"last_failure"` // Reason for the most recent failure of one of the units, if any.
+}
+
+// ServiceUnitStatus represents the status of a systemd unit used by a service.
+type ServiceUnitStatus struct {
+	Name        string     `json:"name"         yaml:"name"`
+	ActiveState string     `json:"active_state" yaml:"active_state"`
+	SubState    string     `json:"sub_state"    yaml:"sub_state"`
+	Result      string     `json:"result"       yaml:"result"`
+	Restarts    int        `json:"restarts"     yaml:"restarts"`
+	Since       *time.Time `json:"since"        yaml:"since"`
+}
+
+// ServiceLogEntry represents a single journal entry of one of the units of a service.
+type ServiceLogEntry struct {
+	Time     time.Time `json:"time"     yaml:"time"`
+	Unit     string    `json:"unit"     yaml:"unit"`
+	Priority int       `json:"priority" yaml:"priority"`
+	Message  string    `json:"message"  yaml:"message"`
+}
@@ -0,0 +1,26 @@
+package api
+
+// ServiceTuningConfig represents additional configuration for the tuning service.
+//
+// Tunables left to 0 keep the kernel default.
+type ServiceTuningConfig struct {
+	Enabled          bool `json:"enabled"            yaml:"enabled"`
+	Hugepages        int  `json:"hugepages"          yaml:"hugepages"`          // Number of 2MiB huge pages to reserve.
+	Hugepages1G      int  `json:"hugepages_1g"       yaml:"hugepages_1g"`       // Number of 1GiB huge pages to reserve.
+	Swappiness       int  `json:"swappiness"         yaml:"swappiness"`         // Between 1 and 200.
+	NetdevBudget     int  `json:"netdev_budget"      yaml:"netdev_budget"`      // Maximum number of packets processed in a single NAPI polling cycle.
+	NetdevMaxBacklog int  `json:"netdev_max_backlog" yaml:"netdev_max_backlog"` // Maximum number of packets queued on the input side.
+	ConntrackMax     int  `json:"conntrack_max"      yaml:"conntrack_max"`      // Maximum number of tracked connections.
+}
+
+// ServiceTuningState represents the state for the tuning service.
+type ServiceTuningState struct {
+	Values map[string]int `json:"values" yaml:"values"` // Current value of each supported tunable, by sysctl name.
+}
+
+// ServiceTuning represents the state and configuration of the tuning service.
+type ServiceTuning struct {
+	State ServiceTuningState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceTuningConfig `json:"config" yaml:"config"`
+}
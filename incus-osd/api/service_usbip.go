@@ -9,10 +9,25 @@ type ServiceUSBIPTarget struct {
 // ServiceUSBIPConfig represents additional configuration for the USBIP service.
 type ServiceUSBIPConfig struct {
 	Targets []ServiceUSBIPTarget `json:"targets" yaml:"targets"`
+	Exports []string             `json:"exports" yaml:"exports"` // Bus IDs of the local devices to export.
+}
+
+// ServiceUSBIPDevice represents a local USB device.
+type ServiceUSBIPDevice struct {
+	BusID        string `json:"bus_id"       yaml:"bus_id"`
+	VendorID     string `json:"vendor_id"    yaml:"vendor_id"`
+	ProductID    string `json:"product_id"   yaml:"product_id"`
+	Manufacturer string `json:"manufacturer" yaml:"manufacturer"`
+	Product      string `json:"product"      yaml:"product"`
+	Exported     bool   `json:"exported"     yaml:"exported"`
+	InUse        bool   `json:"in_use"       yaml:"in_use"` // Whether a remote client is attached to the exported device.
 }
 
 // ServiceUSBIPState represents state for the USBIP service.
-type ServiceUSBIPState struct{}
+type ServiceUSBIPState struct {
+	Devices []ServiceUSBIPDevice `json:"devices" yaml:"devices"`
+	Clients []string             `json:"clients" yaml:"clients"` // Addresses of the remote clients attached to exported devices.
+}
 
 // ServiceUSBIP represents the state and configuration of the USBIP service.
 type ServiceUSBIP struct {
@@ -0,0 +1,23 @@
+package api
+
+// ServiceVRRPConfig represents additional configuration for the VRRP service.
+type ServiceVRRPConfig struct {
+	Enabled         bool     `json:"enabled"           yaml:"enabled"`
+	Interface       string   `json:"interface"         yaml:"interface"`         // Name of the configured interface, bond or VLAN to run VRRP on.
+	VirtualRouterID int      `json:"virtual_router_id" yaml:"virtual_router_id"` // Between 1 and 255, identical on all the hosts sharing the addresses.
+	Priority        int      `json:"priority"          yaml:"priority"`          // Between 1 and 254, the host with the highest priority holds the addresses.
+	Addresses       []string `json:"addresses"         yaml:"addresses"`         // Floating addresses (CIDR), all of the same address family.
+	Peers           []string `json:"peers"             yaml:"peers"`             // Addresses of the other hosts to use unicast with, multicast is used if empty.
+}
+
+// ServiceVRRPState represents the state for the VRRP service.
+type ServiceVRRPState struct {
+	Role string `json:"role" yaml:"role"` // Either "master" when holding the floating addresses or "backup", empty if not running.
+}
+
+// ServiceVRRP represents the state and configuration of the VRRP service.
+type ServiceVRRP struct {
+	State ServiceVRRPState `incusos:"-" json:"state" yaml:"state"`
+
+	Config ServiceVRRPConfig `json:"config" yaml:"config"`
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"time"
+)
+
+// StatusSummary represents a summary of the host OS status, periodically exported to a well-known
+// world-readable location for consumption by applications such as Incus.
+type StatusSummary struct {
+	OSName          string            `json:"os_name"                     yaml:"os_name"`
+	OSVersion       string            `json:"os_version"                  yaml:"os_version"`
+	OSNextVersion   string            `json:"os_next_version,omitempty"   yaml:"os_next_version,omitempty"`
+	OSStagedVersion string            `json:"os_staged_version,omitempty" yaml:"os_staged_version,omitempty"`
+	Applications    map[string]string `json:"applications"                yaml:"applications"`
+	PendingUpdates  bool              `json:"pending_updates"             yaml:"pending_updates"`
+	NeedsReboot     bool              `json:"needs_reboot"                yaml:"needs_reboot"`
+	UpdateStatus    string            `json:"update_status"               yaml:"update_status"`
+	LastUpdateCheck time.Time         `json:"last_update_check"           yaml:"last_update_check"` // In system's timezone.
+	GeneratedAt     time.Time         `json:"generated_at"                yaml:"generated_at"`      // In system's timezone.
+}
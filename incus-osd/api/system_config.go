@@ -0,0 +1,24 @@
+package api
+
+// SystemConfigVersion is the version of the system configuration document format.
+const SystemConfigVersion = "1"
+
+// SystemConfig defines a struct holding the declarative configuration of a system, exported and imported as a
+// single document. Sections which aren't set are left unchanged on import.
+type SystemConfig struct {
+	Version       string                       `json:"version"                 yaml:"version"`
+	Network       *SystemNetworkConfig         `json:"network,omitempty"       yaml:"network,omitempty"`
+	DNS           *SystemDNSConfig             `json:"dns,omitempty"           yaml:"dns,omitempty"`
+	Logging       *SystemLoggingConfig         `json:"logging,omitempty"       yaml:"logging,omitempty"`
+	Notifications *SystemNotificationsConfig   `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	Provider      *SystemProviderConfig        `json:"provider,omitempty"      yaml:"provider,omitempty"`
+	Security      *SystemSecurityConfig        `json:"security,omitempty"      yaml:"security,omitempty"` // The encryption recovery keys are specific to each system and never exported or imported.
+	Update        *SystemUpdateConfig          `json:"update,omitempty"        yaml:"update,omitempty"`
+	Services      map[string]map[string]any    `json:"services,omitempty"      yaml:"services,omitempty"`     // Service configurations, keyed by service name.
+	Applications  map[string]ApplicationConfig `json:"applications,omitempty"  yaml:"applications,omitempty"` // Application configurations, keyed by name. Missing applications are installed.
+}
+
+// SystemConfigExport defines the options used to export the system configuration.
+type SystemConfigExport struct {
+	RedactSecrets bool `json:"redact_secrets" yaml:"redact_secrets"` // Replace the credentials with a placeholder, which keeps the current value on import.
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemDNSProvider defines a DNS provider through which records can be managed.
+type SystemDNSProvider struct {
+	Name   string            `json:"name"   yaml:"name"`
+	Type   string            `json:"type"   yaml:"type"` // One of "rfc2136", "cloudflare" or "route53".
+	Zone   string            `json:"zone"   yaml:"zone"`
+	Config map[string]string `json:"config" yaml:"config"` // Credentials are stored encrypted and redacted when retrieved.
+}
+
+// SystemDNSDynamicRecord defines a record kept pointing to the system's current addresses.
+type SystemDNSDynamicRecord struct {
+	Provider  string `json:"provider"  yaml:"provider"`
+	Name      string `json:"name"      yaml:"name"`      // Fully qualified name of the record.
+	Interface string `json:"interface" yaml:"interface"` // Only use the addresses of this interface, all interfaces if empty.
+	TTL       int    `json:"ttl"       yaml:"ttl"`       // Defaults to 300 seconds.
+}
+
+// SystemDNSConfig holds the modifiable part of the DNS providers data.
+type SystemDNSConfig struct {
+	Providers      []SystemDNSProvider      `json:"providers"       yaml:"providers"`
+	DynamicRecords []SystemDNSDynamicRecord `json:"dynamic_records" yaml:"dynamic_records"`
+}
+
+// SystemDNSDynamicRecordState holds the last update of a dynamic record.
+type SystemDNSDynamicRecordState struct {
+	Name       string     `json:"name"                  yaml:"name"`
+	Addresses  []string   `json:"addresses"             yaml:"addresses"`
+	LastUpdate *time.Time `json:"last_update,omitempty" yaml:"last_update,omitempty"`
+	Error      string     `json:"error,omitempty"       yaml:"error,omitempty"`
+}
+
+// SystemDNSState represents state for the system's DNS providers.
+type SystemDNSState struct {
+	DynamicRecords []SystemDNSDynamicRecordState `json:"dynamic_records" yaml:"dynamic_records"`
+}
+
+// SystemDNS defines a struct to hold information about the system's DNS providers.
+type SystemDNS struct {
+	Config SystemDNSConfig `json:"config" yaml:"config"`
+	State  SystemDNSState  `incusos:"-"   json:"state"  yaml:"state"`
+}
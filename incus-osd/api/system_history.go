@@ -0,0 +1,22 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemHistoryEntry records a change made to the system configuration through the REST API.
+type SystemHistoryEntry struct {
+	Time     time.Time             `json:"time"     yaml:"time"`
+	Source   string                `json:"source"   yaml:"source"`   // Either "local" for the local socket or "remote" for the remote API.
+	Identity string                `json:"identity" yaml:"identity"` // Peer credentials of local callers or client certificate of remote ones.
+	Method   string                `json:"method"   yaml:"method"`
+	Endpoint string                `json:"endpoint" yaml:"endpoint"`
+	Changes  []SystemHistoryChange `json:"changes"  yaml:"changes"`
+}
+
+// SystemHistoryChange records the change of a single configuration value.
+type SystemHistoryChange struct {
+	Path string `json:"path"          yaml:"path"`          // JSON pointer to the changed value within the exported configuration, such as "/services/ssh/enabled".
+	Old  any    `json:"old,omitempty" yaml:"old,omitempty"` // Unset if the value was added.
+	New  any    `json:"new,omitempty" yaml:"new,omitempty"` // Unset if the value was removed.
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"slices"
+	"time"
 )
 
 const (
@@ -31,6 +32,14 @@ type SystemNetworkConfig struct {
 	Time  *SystemNetworkTime  `json:"time,omitempty"  yaml:"time,omitempty"`
 	Proxy *SystemNetworkProxy `json:"proxy,omitempty" yaml:"proxy,omitempty"`
 
+	// When set, the system's own traffic and management services are restricted to the single
+	// interface, bond or VLAN holding the management role.
+	DedicatedManagement bool `json:"dedicated_management,omitempty" yaml:"dedicated_management,omitempty"`
+
+	// When set, duplicate address detection is performed for the addresses of the devices holding
+	// the management role, so addresses already in use on the network aren't configured.
+	DetectAddressConflicts bool `json:"detect_address_conflicts,omitempty" yaml:"detect_address_conflicts,omitempty"`
+
 	Interfaces []SystemNetworkInterface `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
 	Bonds      []SystemNetworkBond      `json:"bonds,omitempty"      yaml:"bonds,omitempty"`
 	VLANs      []SystemNetworkVLAN      `json:"vlans,omitempty"      yaml:"vlans,omitempty"`
@@ -38,42 +47,60 @@ type SystemNetworkConfig struct {
 
 // SystemNetworkInterface contains information about a network interface.
 type SystemNetworkInterface struct {
-	Name              string               `json:"name"                          yaml:"name"`
-	MTU               int                  `json:"mtu,omitempty"                 yaml:"mtu,omitempty"`
-	VLANTags          []int                `json:"vlan_tags,omitempty"           yaml:"vlan_tags,omitempty"`
-	Addresses         []string             `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
-	RequiredForOnline string               `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
-	Routes            []SystemNetworkRoute `json:"routes,omitempty"              yaml:"routes,omitempty"`
-	Hwaddr            string               `json:"hwaddr"                        yaml:"hwaddr"`
-	Roles             []string             `json:"roles,omitempty"               yaml:"roles,omitempty"`
-	LLDP              bool                 `json:"lldp"                          yaml:"lldp"`
+	Name              string                  `json:"name"                          yaml:"name"`
+	MTU               int                     `json:"mtu,omitempty"                 yaml:"mtu,omitempty"`
+	VLANTags          []int                   `json:"vlan_tags,omitempty"           yaml:"vlan_tags,omitempty"`
+	Addresses         []string                `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
+	RequiredForOnline string                  `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
+	Routes            []SystemNetworkRoute    `json:"routes,omitempty"              yaml:"routes,omitempty"`
+	Neighbors         []SystemNetworkNeighbor `json:"neighbors,omitempty"           yaml:"neighbors,omitempty"` // Static neighbor (ARP/NDP) entries.
+	Hwaddr            string                  `json:"hwaddr"                        yaml:"hwaddr"`
+	Roles             []string                `json:"roles,omitempty"               yaml:"roles,omitempty"`
+	LLDP              bool                    `json:"lldp"                          yaml:"lldp"`
+	Auth              *SystemNetworkAuth      `json:"auth,omitempty"                yaml:"auth,omitempty"` // 802.1X port authentication.
+}
+
+// SystemNetworkAuth contains the 802.1X (EAP) credentials used to authenticate a wired interface with its switch port.
+type SystemNetworkAuth struct {
+	Method            string `json:"method"                       yaml:"method"` // Either "tls" (EAP-TLS) or "peap" (PEAP/MSCHAPv2).
+	Identity          string `json:"identity"                     yaml:"identity"`
+	AnonymousIdentity string `json:"anonymous_identity,omitempty" yaml:"anonymous_identity,omitempty"`
+	Password          string `json:"password,omitempty"           yaml:"password,omitempty"`
+	CACertificate     string `json:"ca_certificate,omitempty"     yaml:"ca_certificate,omitempty"`
+	ClientCertificate string `json:"client_certificate,omitempty" yaml:"client_certificate,omitempty"`
+	ClientKey         string `json:"client_key,omitempty"         yaml:"client_key,omitempty"`
 }
 
 // SystemNetworkBond contains information about a network bond.
 type SystemNetworkBond struct {
-	Name              string               `json:"name"                          yaml:"name"`
-	Mode              string               `json:"mode"                          yaml:"mode"`
-	MTU               int                  `json:"mtu,omitempty"                 yaml:"mtu,omitempty"`
-	VLANTags          []int                `json:"vlan_tags,omitempty"           yaml:"vlan_tags,omitempty"`
-	Addresses         []string             `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
-	RequiredForOnline string               `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
-	Routes            []SystemNetworkRoute `json:"routes,omitempty"              yaml:"routes,omitempty"`
-	Hwaddr            string               `json:"hwaddr,omitempty"              yaml:"hwaddr,omitempty"`
-	Members           []string             `json:"members,omitempty"             yaml:"members,omitempty"`
-	Roles             []string             `json:"roles,omitempty"               yaml:"roles,omitempty"`
-	LLDP              bool                 `json:"lldp"                          yaml:"lldp"`
+	Name              string                  `json:"name"                          yaml:"name"`
+	Mode              string                  `json:"mode"                          yaml:"mode"`
+	LACPRate          string                  `json:"lacp_rate,omitempty"           yaml:"lacp_rate,omitempty"`   // Either "slow" or "fast", only for the "802.3ad" mode.
+	HashPolicy        string                  `json:"hash_policy,omitempty"         yaml:"hash_policy,omitempty"` // Transmit hash policy, only for the "802.3ad", "balance-xor" and "balance-tlb" modes.
+	Primary           string                  `json:"primary,omitempty"             yaml:"primary,omitempty"`     // MAC address of the preferred member, only for the "active-backup", "balance-tlb" and "balance-alb" modes.
+	MTU               int                     `json:"mtu,omitempty"                 yaml:"mtu,omitempty"`
+	VLANTags          []int                   `json:"vlan_tags,omitempty"           yaml:"vlan_tags,omitempty"`
+	Addresses         []string                `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
+	RequiredForOnline string                  `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
+	Routes            []SystemNetworkRoute    `json:"routes,omitempty"              yaml:"routes,omitempty"`
+	Neighbors         []SystemNetworkNeighbor `json:"neighbors,omitempty"           yaml:"neighbors,omitempty"` // Static neighbor (ARP/NDP) entries.
+	Hwaddr            string                  `json:"hwaddr,omitempty"              yaml:"hwaddr,omitempty"`
+	Members           []string                `json:"members,omitempty"             yaml:"members,omitempty"`
+	Roles             []string                `json:"roles,omitempty"               yaml:"roles,omitempty"`
+	LLDP              bool                    `json:"lldp"                          yaml:"lldp"`
 }
 
 // SystemNetworkVLAN contains information about a network vlan.
 type SystemNetworkVLAN struct {
-	Name              string               `json:"name"                          yaml:"name"`
-	Parent            string               `json:"parent"                        yaml:"parent"`
-	ID                int                  `json:"id"                            yaml:"id"`
-	MTU               int                  `json:"mtu,omitempty"                 yaml:"mtu,omitempty"`
-	Addresses         []string             `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
-	RequiredForOnline string               `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
-	Routes            []SystemNetworkRoute `json:"routes,omitempty"              yaml:"routes,omitempty"`
-	Roles             []string             `json:"roles,omitempty"               yaml:"roles,omitempty"`
+	Name              string                  `json:"name"                          yaml:"name"`
+	Parent            string                  `json:"parent"                        yaml:"parent"`
+	ID                int                     `json:"id"                            yaml:"id"`
+	MTU               int                     `json:"mtu,omitempty"                 yaml:"mtu,omitempty"`
+	Addresses         []string                `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
+	RequiredForOnline string                  `json:"required_for_online,omitempty" yaml:"required_for_online,omitempty"`
+	Routes            []SystemNetworkRoute    `json:"routes,omitempty"              yaml:"routes,omitempty"`
+	Neighbors         []SystemNetworkNeighbor `json:"neighbors,omitempty"           yaml:"neighbors,omitempty"` // Static neighbor (ARP/NDP) entries.
+	Roles             []string                `json:"roles,omitempty"               yaml:"roles,omitempty"`
 }
 
 // SystemNetworkRoute defines a route.
@@ -82,6 +109,12 @@ type SystemNetworkRoute struct {
 	Via string `json:"via" yaml:"via"`
 }
 
+// SystemNetworkNeighbor defines a static neighbor entry.
+type SystemNetworkNeighbor struct {
+	Address string `json:"address" yaml:"address"`
+	Hwaddr  string `json:"hwaddr"  yaml:"hwaddr"`
+}
+
 // SystemNetworkDNS defines DNS configuration options.
 type SystemNetworkDNS struct {
 	Hostname      string   `json:"hostname"                 yaml:"hostname"`
@@ -98,8 +131,9 @@ type SystemNetworkTime struct {
 
 // SystemNetworkProxy defines proxy configuration.
 type SystemNetworkProxy struct {
-	Servers map[string]SystemNetworkProxyServer `json:"servers,omitempty" yaml:"servers,omitempty"`
-	Rules   []SystemNetworkProxyRule            `json:"rules,omitempty"   yaml:"rules,omitempty"`
+	Servers map[string]SystemNetworkProxyServer `json:"servers,omitempty"  yaml:"servers,omitempty"`
+	Rules   []SystemNetworkProxyRule            `json:"rules,omitempty"    yaml:"rules,omitempty"`
+	NoProxy []string                            `json:"no_proxy,omitempty" yaml:"no_proxy,omitempty"`
 }
 
 // SystemNetworkProxyServer defines a proxy server configuration.
@@ -118,6 +152,30 @@ type SystemNetworkProxyRule struct {
 	Target      string `json:"target"      yaml:"target"`
 }
 
+// SystemNetworkProxyLog holds the recent activity of the local proxy, as recorded in its log.
+type SystemNetworkProxyLog struct {
+	Entries      []SystemNetworkProxyLogEntry    `json:"entries"      yaml:"entries"`
+	Destinations []SystemNetworkProxyDestination `json:"destinations" yaml:"destinations"`
+}
+
+// SystemNetworkProxyLogEntry is a single structured entry from the local proxy's log.
+type SystemNetworkProxyLogEntry struct {
+	Time        time.Time `json:"time"                  yaml:"time"`
+	Method      string    `json:"method,omitempty"      yaml:"method,omitempty"`
+	Destination string    `json:"destination,omitempty" yaml:"destination,omitempty"` // Host and port, when the entry relates to a request.
+	Failed      bool      `json:"failed"                yaml:"failed"`
+	Message     string    `json:"message"               yaml:"message"`
+}
+
+// SystemNetworkProxyDestination holds the request and failure counters of a single destination, since the system booted.
+type SystemNetworkProxyDestination struct {
+	Destination string     `json:"destination"            yaml:"destination"`
+	Requests    int        `json:"requests"               yaml:"requests"`
+	Failures    int        `json:"failures"               yaml:"failures"`
+	LastFailure *time.Time `json:"last_failure,omitempty" yaml:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"   yaml:"last_error,omitempty"`
+}
+
 // SystemNetworkState holds information about the current network state.
 type SystemNetworkState struct {
 	Interfaces map[string]SystemNetworkInterfaceState `json:"interfaces" yaml:"interfaces"`
@@ -148,6 +206,8 @@ type SystemNetworkInterfaceState struct {
 	Stats     SystemNetworkInterfaceStats            `json:"stats"               yaml:"stats"`
 	LLDP      []SystemNetworkLLDPState               `json:"lldp,omitempty"      yaml:"lldp,omitempty"`
 	LACP      *SystemNetworkLACPState                `json:"lacp,omitempty"      yaml:"lacp,omitempty"`
+	Bond      *SystemNetworkBondState                `json:"bond,omitempty"      yaml:"bond,omitempty"`
+	Auth      string                                 `json:"auth,omitempty"      yaml:"auth,omitempty"` // 802.1X supplicant state.
 	Members   map[string]SystemNetworkInterfaceState `json:"members,omitempty"   yaml:"members,omitempty"`
 	Roles     []string                               `json:"roles,omitempty"     yaml:"roles,omitempty"`
 }
@@ -173,3 +233,29 @@ type SystemNetworkLACPState struct {
 	LocalMAC  string `json:"local_mac"  yaml:"local_mac"`
 	RemoteMAC string `json:"remote_mac" yaml:"remote_mac"`
 }
+
+// SystemNetworkBondState holds the runtime state of a bond, or of one of its members.
+type SystemNetworkBondState struct {
+	Mode         string `json:"mode,omitempty"          yaml:"mode,omitempty"`
+	LACPRate     string `json:"lacp_rate,omitempty"     yaml:"lacp_rate,omitempty"`
+	HashPolicy   string `json:"hash_policy,omitempty"   yaml:"hash_policy,omitempty"`
+	ActiveMember string `json:"active_member,omitempty" yaml:"active_member,omitempty"` // MAC address of the active member in the "active-backup" mode.
+	State        string `json:"state,omitempty"         yaml:"state,omitempty"`         // Either "active" or "backup", for a member.
+	LinkState    string `json:"link_state,omitempty"    yaml:"link_state,omitempty"`    // Either "up" or "down", as detected by the bond.
+	LinkFailures int    `json:"link_failures"           yaml:"link_failures"`
+	AggregatorID int    `json:"aggregator_id,omitempty" yaml:"aggregator_id,omitempty"` // LACP aggregator in use by the bond, or joined by a member.
+}
+
+// SystemNetworkTestResult represents the outcome of a single network reachability check.
+type SystemNetworkTestResult struct {
+	Name    string `json:"name"              yaml:"name"` // One of "dns", "gateway", "ntp" or "provider".
+	Passed  bool   `json:"passed"            yaml:"passed"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // Details about what was tested or the reason for the failure.
+}
+
+// SystemNetworkTest represents the report of a network reachability self-test.
+type SystemNetworkTest struct {
+	Time    time.Time                 `json:"time"    yaml:"time"`
+	Passed  bool                      `json:"passed"  yaml:"passed"`
+	Results []SystemNetworkTestResult `json:"results" yaml:"results"`
+}
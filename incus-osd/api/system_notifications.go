@@ -0,0 +1,77 @@
+package api
+
+// SystemNotificationsEventType represents the type of an event which can be notified.
+type SystemNotificationsEventType string
+
+const (
+	// SystemNotificationsEventUpdateInstalled is sent when an OS or application update has been installed.
+	SystemNotificationsEventUpdateInstalled SystemNotificationsEventType = "update-installed"
+
+	// SystemNotificationsEventUpdateFailed is sent when an update check or update failed.
+	SystemNotificationsEventUpdateFailed SystemNotificationsEventType = "update-failed"
+
+	// SystemNotificationsEventRebootRequired is sent when the system needs to be rebooted to complete an update.
+	SystemNotificationsEventRebootRequired SystemNotificationsEventType = "reboot-required"
+
+	// SystemNotificationsEventSystemDiskError is sent when a problem with the system disk, such as a failing state storage, is detected.
+	SystemNotificationsEventSystemDiskError SystemNotificationsEventType = "system-disk-error"
+
+	// SystemNotificationsEventBootOrderDrifted is sent when the IncusOS boot entry is no longer first in the EFI boot order.
+	SystemNotificationsEventBootOrderDrifted SystemNotificationsEventType = "boot-order-drifted"
+
+	// SystemNotificationsEventCertificateRotation is sent when an application's server certificate is nearing expiry, staged for rotation or replaced.
+	SystemNotificationsEventCertificateRotation SystemNotificationsEventType = "certificate-rotation"
+
+	// SystemNotificationsEventTPMBindings is sent when the TPM can no longer unlock the encrypted volumes, or its bindings were automatically reset.
+	SystemNotificationsEventTPMBindings SystemNotificationsEventType = "tpm-bindings"
+
+	// SystemNotificationsEventIPConflict is sent when an address of the system is found to be in use by another host.
+	SystemNotificationsEventIPConflict SystemNotificationsEventType = "ip-conflict"
+)
+
+// SystemNotificationsEventTypes lists all the supported event types.
+var SystemNotificationsEventTypes = []SystemNotificationsEventType{
+	SystemNotificationsEventUpdateInstalled,
+	SystemNotificationsEventUpdateFailed,
+	SystemNotificationsEventRebootRequired,
+	SystemNotificationsEventSystemDiskError,
+	SystemNotificationsEventBootOrderDrifted,
+	SystemNotificationsEventCertificateRotation,
+	SystemNotificationsEventTPMBindings,
+	SystemNotificationsEventIPConflict,
+}
+
+// SystemNotificationsEvent represents a single event sent to notification backends.
+type SystemNotificationsEvent struct {
+	Type     SystemNotificationsEventType `json:"type"     yaml:"type"`
+	Hostname string                       `json:"hostname" yaml:"hostname"`
+	Message  string                       `json:"message"  yaml:"message"`
+}
+
+// SystemNotificationsBackend defines a notification backend, such as a webhook or an email server.
+type SystemNotificationsBackend struct {
+	Name   string            `json:"name"   yaml:"name"`
+	Type   string            `json:"type"   yaml:"type"`
+	Config map[string]string `json:"config" yaml:"config"`
+}
+
+// SystemNotificationsRoute defines which backends are notified of which events.
+type SystemNotificationsRoute struct {
+	Events   []SystemNotificationsEventType `json:"events"   yaml:"events"` // All events if empty.
+	Backends []string                       `json:"backends" yaml:"backends"`
+}
+
+// SystemNotificationsConfig holds the modifiable part of the notifications data.
+type SystemNotificationsConfig struct {
+	Backends []SystemNotificationsBackend `json:"backends" yaml:"backends"`
+	Routes   []SystemNotificationsRoute   `json:"routes"   yaml:"routes"`
+}
+
+// SystemNotificationsState represents state for the system's notifications configuration.
+type SystemNotificationsState struct{}
+
+// SystemNotifications defines a struct to hold information about the system's notifications configuration.
+type SystemNotifications struct {
+	Config SystemNotificationsConfig `json:"config" yaml:"config"`
+	State  SystemNotificationsState  `incusos:"-"   json:"state"  yaml:"state"`
+}
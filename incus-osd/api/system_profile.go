@@ -0,0 +1,15 @@
+package api
+
+import (
+	"encoding/json"
+)
+
+// SystemProfile defines a struct holding the combined configuration of a node, applied in a single call.
+// Sections which aren't set are left unchanged.
+type SystemProfile struct {
+	Network      *SystemNetworkConfig       `json:"network,omitempty"      yaml:"network,omitempty"`
+	Proxy        *SystemNetworkProxy        `json:"proxy,omitempty"        yaml:"proxy,omitempty"`    // Replaces the proxy of the network configuration.
+	Services     map[string]json.RawMessage `json:"services,omitempty"     yaml:"services,omitempty"` // Service configurations, as provided to the service's endpoint, keyed by name.
+	Update       *SystemUpdateConfig        `json:"update,omitempty"       yaml:"update,omitempty"`
+	Applications []string                   `json:"applications,omitempty" yaml:"applications,omitempty"` // Applications to install, if not already installed.
+}
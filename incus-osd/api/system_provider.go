@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // SystemProviderConfig holds the modifiable part of the provider data.
 type SystemProviderConfig struct {
 	Name   string            `json:"name"   yaml:"name"`
@@ -8,7 +12,11 @@ type SystemProviderConfig struct {
 
 // SystemProviderState holds information about the current provider state.
 type SystemProviderState struct {
-	Registered bool `json:"registered" yaml:"registered"`
+	Registered       bool      `json:"registered" yaml:"registered"`
+	LastCheck        time.Time `incusos:"-"       json:"last_check"         yaml:"last_check"` // In system's timezone.
+	LatestVersion    string    `incusos:"-"       json:"latest_version"     yaml:"latest_version"`
+	RateLimitedUntil time.Time `incusos:"-"       json:"rate_limited_until" yaml:"rate_limited_until"` // In system's timezone.
+	LastError        string    `incusos:"-"       json:"last_error"         yaml:"last_error"`
 }
 
 // SystemProvider defines a struct to hold information about the system's update and configuration provider.
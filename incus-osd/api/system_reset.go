@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // SystemReset defines a struct that takes an optional map of seed data to set as part of the factory reset.
@@ -9,4 +10,22 @@ type SystemReset struct {
 	AllowTPMResetFailure bool                       `json:"allow_tpm_reset_failure" yaml:"allow_tpm_reset_failure"`
 	Seeds                map[string]json.RawMessage `json:"seeds"                   yaml:"seeds"`
 	WipeExistingSeeds    bool                       `json:"wipe_existing_seeds"     yaml:"wipe_existing_seeds"`
+	SecureWipe           bool                       `json:"secure_wipe"             yaml:"secure_wipe"` // Cryptographically erase the encrypted volumes before resetting.
+}
+
+// SystemResetWipeReport records the secure wipe of the encrypted volumes performed as part of a factory reset.
+type SystemResetWipeReport struct {
+	Time     time.Time               `json:"time"     yaml:"time"`
+	Hostname string                  `json:"hostname" yaml:"hostname"`
+	Volumes  []SystemResetWipeVolume `json:"volumes"  yaml:"volumes"`
+}
+
+// SystemResetWipeVolume records how a single encrypted volume was wiped.
+type SystemResetWipeVolume struct {
+	Name             string `json:"name"              yaml:"name"`
+	Device           string `json:"device"            yaml:"device"`
+	Keyslots         int    `json:"keyslots"          yaml:"keyslots"`          // Number of keyslots destroyed.
+	KeyslotsVerified bool   `json:"keyslots_verified" yaml:"keyslots_verified"` // No keyslot remained once destroyed.
+	HeaderSize       int64  `json:"header_size"       yaml:"header_size"`       // Size of the overwritten LUKS header, in bytes.
+	Discarded        bool   `json:"discarded"         yaml:"discarded"`         // The header was also discarded, on devices supporting it.
 }
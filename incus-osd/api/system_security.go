@@ -1,5 +1,9 @@
 package api
 
+import (
+	"time"
+)
+
 // SystemSecurityState holds information about the current security state.
 type SystemSecurityState struct {
 	EncryptionRecoveryKeysRetrieved bool                                  `json:"encryption_recovery_keys_retrieved" yaml:"encryption_recovery_keys_retrieved"`
@@ -8,11 +12,57 @@ type SystemSecurityState struct {
 	SecureBootCertificates          []SystemSecuritySecureBootCertificate `incusos:"-"                               json:"secure_boot_certificates"           yaml:"secure_boot_certificates"`
 	TPMStatus                       string                                `incusos:"-"                               json:"tpm_status"                         yaml:"tpm_status"`
 	PoolRecoveryKeys                map[string]string                     `incusos:"-"                               json:"pool_recovery_keys"                 yaml:"pool_recovery_keys"`
+	VerityVolumes                   []SystemSecurityVerityVolume          `incusos:"-"                               json:"verity_volumes"                     yaml:"verity_volumes"`
+	BootOrder                       SystemSecurityBootOrder               `incusos:"-"                               json:"boot_order"                         yaml:"boot_order"`
+	PCRPrediction                   *SystemSecurityPCRPrediction          `incusos:"-"                               json:"pcr_prediction,omitempty"           yaml:"pcr_prediction,omitempty"`
+	DebugAccessExpiry               *time.Time                            `incusos:"-"                               json:"debug_access_expiry,omitempty"      yaml:"debug_access_expiry,omitempty"` // Set while a time-limited debug access grant is active.
+	FIDO2Tokens                     []SystemSecurityFIDO2Token            `json:"fido2_tokens"                       yaml:"fido2_tokens"`
+	PassphraseOnly                  bool                                  `incusos:"-"                               json:"passphrase_only"                    yaml:"passphrase_only"`      // Degraded mode for systems without a TPM, where the encrypted volumes are only protected by passphrases.
+	UpdateTrustAnchors              []SystemSecurityTrustAnchor           `incusos:"-"                               json:"update_trust_anchors"               yaml:"update_trust_anchors"` // Certificates trusted to sign the update metadata.
+	RemoteAPI                       *SystemSecurityRemoteAPIState         `incusos:"-"                               json:"remote_api,omitempty"               yaml:"remote_api,omitempty"` // Set while the REST API is exposed over HTTPS.
 }
 
 // SystemSecurityConfig holds additional security configuration settings.
 type SystemSecurityConfig struct {
-	EncryptionRecoveryKeys []string `json:"encryption_recovery_keys" yaml:"encryption_recovery_keys"`
+	EncryptionRecoveryKeys []string                     `json:"encryption_recovery_keys"       yaml:"encryption_recovery_keys"`
+	AutoRepairBootOrder    bool                         `json:"auto_repair_boot_order"         yaml:"auto_repair_boot_order"`
+	AutoTPMRebind          bool                         `json:"auto_tpm_rebind"                yaml:"auto_tpm_rebind"`                // Automatically reset the TPM bindings after a firmware update.
+	RestrictDebug          bool                         `json:"restrict_debug"                 yaml:"restrict_debug"`                 // Only allow debug endpoints while a time-limited grant is active.
+	NetworkUnlock          *SystemSecurityNetworkUnlock `json:"network_unlock,omitempty"       yaml:"network_unlock,omitempty"`       // Additionally bind the encrypted volumes to Tang servers.
+	HealthProbeAddress     string                       `json:"health_probe_address,omitempty" yaml:"health_probe_address,omitempty"` // Address to serve the unauthenticated health probe on, such as ":8080". Disabled if empty.
+	RemoteAPI              *SystemSecurityRemoteAPI     `json:"remote_api,omitempty"           yaml:"remote_api,omitempty"`           // Expose the REST API over HTTPS on the management address. Disabled if nil.
+}
+
+// SystemSecurityRemoteAPI defines the HTTPS listener exposing the REST API on the management address.
+type SystemSecurityRemoteAPI struct {
+	Port                int                                `json:"port"                 yaml:"port"` // Defaults to 9443.
+	TrustedCertificates []SystemSecurityTrustedCertificate `json:"trusted_certificates" yaml:"trusted_certificates"`
+}
+
+// SystemSecurityTrustedCertificate defines a client certificate allowed to use the remote API.
+type SystemSecurityTrustedCertificate struct {
+	Name        string `json:"name"        yaml:"name"`
+	Certificate string `json:"certificate" yaml:"certificate"` // PEM encoded.
+}
+
+// SystemSecurityRemoteAPIState holds the state of the HTTPS listener exposing the REST API.
+type SystemSecurityRemoteAPIState struct {
+	Address     string `json:"address"     yaml:"address"`
+	Certificate string `json:"certificate" yaml:"certificate"` // PEM encoded server certificate.
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+}
+
+// SystemSecurityNetworkUnlock defines the Tang servers the encrypted volumes are bound to for network-bound disk encryption.
+type SystemSecurityNetworkUnlock struct {
+	TangServers []SystemSecurityTangServer `json:"tang_servers" yaml:"tang_servers"`
+	Threshold   int                        `json:"threshold"    yaml:"threshold"` // Number of Tang servers which must be reachable to unlock, defaults to 1.
+	TPM         string                     `json:"tpm"          yaml:"tpm"`       // Either "fallback" (default) to keep unlocking through the TPM, or "disabled".
+}
+
+// SystemSecurityTangServer defines a single Tang server.
+type SystemSecurityTangServer struct {
+	URL        string `json:"url"                  yaml:"url"`
+	Thumbprint string `json:"thumbprint,omitempty" yaml:"thumbprint,omitempty"` // Trust any advertised signing key if empty.
 }
 
 // SystemSecurity defines a struct to hold information about the system's security state.
@@ -22,6 +72,25 @@ type SystemSecurity struct {
 	State SystemSecurityState `json:"state" yaml:"state"`
 }
 
+// SystemSecurityRecoveryKey is used to add, rotate or remove an encryption recovery key.
+type SystemSecurityRecoveryKey struct {
+	Key    string `json:"key"               yaml:"key"`               // A new key is generated when adding an empty key.
+	NewKey string `json:"new_key,omitempty" yaml:"new_key,omitempty"` // Replacement for Key when rotating, generated if empty.
+}
+
+// SystemSecurityFIDO2Token defines a FIDO2 security key enrolled to unlock the encrypted volumes.
+type SystemSecurityFIDO2Token struct {
+	Name  string         `json:"name"  yaml:"name"`
+	Slots map[string]int `json:"slots" yaml:"slots"` // LUKS key slot used by the token in each encrypted volume.
+}
+
+// SystemSecurityFIDO2TokenEnroll is used to enroll a new FIDO2 security key.
+type SystemSecurityFIDO2TokenEnroll struct {
+	Name   string `json:"name"             yaml:"name"`
+	Device string `json:"device,omitempty" yaml:"device,omitempty"` // hidraw device of the security key, detected automatically if empty.
+	PIN    string `json:"pin,omitempty"    yaml:"pin,omitempty"`    // Require the security key's PIN to unlock, in addition to user presence.
+}
+
 // SystemSecuritySecureBootCertificate defines a struct that holds information about Secure Boot keys present on the host.
 type SystemSecuritySecureBootCertificate struct {
 	Type        string `json:"type"        yaml:"type"`
@@ -30,8 +99,114 @@ type SystemSecuritySecureBootCertificate struct {
 	Issuer      string `json:"issuer"      yaml:"issuer"`
 }
 
+// SystemSecurityTrustAnchor defines a struct that holds information about a certificate trusted to sign the update metadata.
+type SystemSecurityTrustAnchor struct {
+	Source      string `json:"source"      yaml:"source"` // One of "builtin", "seed" or "provider".
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+	Subject     string `json:"subject"     yaml:"subject"`
+	Issuer      string `json:"issuer"      yaml:"issuer"`
+}
+
 // SystemSecurityEncryptedVolume defines a struct that holds basic information about an encrypted volume.
 type SystemSecurityEncryptedVolume struct {
+	Volume    string `json:"volume"     yaml:"volume"`
+	State     string `json:"state"      yaml:"state"`
+	TangBound bool   `json:"tang_bound" yaml:"tang_bound"`
+}
+
+// SystemSecurityVerityVolume defines a struct that holds basic information about a dm-verity volume.
+type SystemSecurityVerityVolume struct {
 	Volume string `json:"volume" yaml:"volume"`
 	State  string `json:"state"  yaml:"state"`
 }
+
+// SystemSecurityBootOrder defines a struct that holds information about the EFI boot order.
+type SystemSecurityBootOrder struct {
+	Entries  []string `json:"entries"  yaml:"entries"`
+	Expected string   `json:"expected" yaml:"expected"`
+	Drifted  bool     `json:"drifted"  yaml:"drifted"`
+}
+
+// SystemSecurityPCRPrediction defines a struct that holds the PCR values expected when next booting into a given release.
+type SystemSecurityPCRPrediction struct {
+	Release string `json:"release"         yaml:"release"`
+	Bank    string `json:"bank"            yaml:"bank"` // PCR bank the values are computed for, either "sha256" or "sha384".
+	PCR4    string `json:"pcr4"            yaml:"pcr4"`
+	PCR7    string `json:"pcr7"            yaml:"pcr7"`
+	PCR11   string `json:"pcr11"           yaml:"pcr11"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"` // Set instead of the values when they couldn't be predicted.
+}
+
+// SystemSecurityPCRPreview defines a struct that holds the current PCR values along with those expected on next boot,
+// once pending changes to the EFI variables and the boot image are measured.
+type SystemSecurityPCRPreview struct {
+	Release          string                   `json:"release"           yaml:"release"`
+	Bank             string                   `json:"bank"              yaml:"bank"` // PCR bank the values are computed for, either "sha256" or "sha384".
+	PCRs             []SystemSecurityPCRValue `json:"pcrs"              yaml:"pcrs"`
+	RecoveryRequired bool                     `json:"recovery_required" yaml:"recovery_required"` // Whether the TPM won't unlock the encrypted volumes on next boot.
+}
+
+// SystemSecurityPCRValue defines a struct that holds the current and expected value of a single PCR.
+type SystemSecurityPCRValue struct {
+	Index   int    `json:"index"             yaml:"index"`
+	Current string `json:"current,omitempty" yaml:"current,omitempty"` // Not reported for PCR11, which keeps being extended after the volumes are unlocked.
+	Next    string `json:"next"              yaml:"next"`
+	Bound   bool   `json:"bound"             yaml:"bound"` // Whether the encrypted volumes are bound to the exact value of the PCR.
+}
+
+// SystemSecurityRebindPlan defines a struct that holds what re-binding the encrypted volumes to the TPM would do,
+// as reported by a dry run.
+type SystemSecurityRebindPlan struct {
+	Operation          string                                `json:"operation"                     yaml:"operation"`                     // Either "tpm-rebind" or "secure-boot-key-change".
+	Bank               string                                `json:"bank"                          yaml:"bank"`                          // PCR bank the volumes would be bound to, either "sha256" or "sha384".
+	PCR7               string                                `json:"pcr7"                          yaml:"pcr7"`                          // PCR7 value the volumes would be bound to.
+	SigningCertificate *SystemSecuritySecureBootCertificate  `json:"signing_certificate,omitempty" yaml:"signing_certificate,omitempty"` // Secure Boot certificate matching the key the PCR11 policies would be verified with.
+	Certificates       []SystemSecuritySecureBootCertificate `json:"certificates"                  yaml:"certificates"`                  // Secure Boot certificates detected in the EFI variables.
+	Volumes            []SystemSecurityRebindVolume          `json:"volumes"                       yaml:"volumes"`                       // Volumes which would be re-enrolled.
+	Reboot             bool                                  `json:"reboot"                        yaml:"reboot"`                        // Whether the system would be rebooted afterwards.
+	Blockers           []string                              `json:"blockers"                      yaml:"blockers"`                      // Reasons the operation would currently be refused.
+}
+
+// SystemSecurityRebindVolume defines a struct that holds an encrypted volume which would be re-enrolled in the TPM.
+type SystemSecurityRebindVolume struct {
+	Name   string `json:"name"   yaml:"name"`
+	Device string `json:"device" yaml:"device"`
+}
+
+// SystemSecurityMeasuredBootReport defines a struct that holds the evidence of the integrity of the current boot.
+type SystemSecurityMeasuredBootReport struct {
+	Time                   time.Time                               `json:"time"                     yaml:"time"`
+	Hostname               string                                  `json:"hostname"                 yaml:"hostname"`
+	OSName                 string                                  `json:"os_name"                  yaml:"os_name"`
+	OSVersion              string                                  `json:"os_version"               yaml:"os_version"`
+	UKISHA256              string                                  `json:"uki_sha256"               yaml:"uki_sha256"`      // Checksum of the running UKI file.
+	UKICertificate         string                                  `json:"uki_certificate"          yaml:"uki_certificate"` // PEM encoded certificate the running UKI is signed with.
+	SecureBootEnabled      bool                                    `json:"secure_boot_enabled"      yaml:"secure_boot_enabled"`
+	SecureBootCertificates []SystemSecurityMeasuredBootCertificate `json:"secure_boot_certificates" yaml:"secure_boot_certificates"`
+	PCRBank                string                                  `json:"pcr_bank"                 yaml:"pcr_bank"`
+	PCRs                   map[string]string                       `json:"pcrs"                     yaml:"pcrs"`      // Current value of each PCR, by index.
+	EventLog               string                                  `json:"event_log"                yaml:"event_log"` // Base64 encoded TCG event log, as exposed by the kernel.
+	Events                 []SystemSecurityMeasuredBootEvent       `json:"events"                   yaml:"events"`
+}
+
+// SystemSecurityMeasuredBootCertificate defines a struct that holds a certificate from the Secure Boot EFI variables.
+type SystemSecurityMeasuredBootCertificate struct {
+	Type        string `json:"type"        yaml:"type"`        // One of "PK", "KEK" or "db".
+	Certificate string `json:"certificate" yaml:"certificate"` // PEM encoded.
+}
+
+// SystemSecurityMeasuredBootEvent defines a struct that holds a single parsed event from the TCG event log.
+type SystemSecurityMeasuredBootEvent struct {
+	PCR    int    `json:"pcr"    yaml:"pcr"`
+	Type   string `json:"type"   yaml:"type"`
+	Digest string `json:"digest" yaml:"digest"`
+}
+
+// SystemSecuritySignedMeasuredBootReport defines a struct that holds a measured boot report, signed with the key of
+// the primary application's server certificate.
+type SystemSecuritySignedMeasuredBootReport struct {
+	Report             string `json:"report"              yaml:"report"` // JSON encoded SystemSecurityMeasuredBootReport, exactly as signed.
+	SignatureAlgorithm string `json:"signature_algorithm" yaml:"signature_algorithm"`
+	Signature          string `json:"signature"           yaml:"signature"`   // Base64 encoded.
+	Certificate        string `json:"certificate"         yaml:"certificate"` // PEM encoded certificate of the signing key.
+}
@@ -0,0 +1,17 @@
+package api
+
+const (
+	// SystemSeedSectionNetwork re-applies the network seed.
+	SystemSeedSectionNetwork = "network"
+
+	// SystemSeedSectionProvider re-applies the provider seed.
+	SystemSeedSectionProvider = "provider"
+
+	// SystemSeedSectionApplications re-applies the applications seed.
+	SystemSeedSectionApplications = "applications"
+)
+
+// SystemSeedApply defines a struct holding the seed sections to re-apply on an installed system.
+type SystemSeedApply struct {
+	Sections []string `json:"sections,omitempty" yaml:"sections,omitempty"` // Defaults to all the supported sections.
+}
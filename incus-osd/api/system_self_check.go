@@ -0,0 +1,32 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemSelfCheckResult represents the outcome of a single self-check.
+type SystemSelfCheckResult struct {
+	Name    string `json:"name"              yaml:"name"` // One of "security", "storage", "connectivity" or "boot".
+	Passed  bool   `json:"passed"            yaml:"passed"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"` // Reason for the failure.
+}
+
+// SystemSelfCheckReport represents the report of a self-check run.
+type SystemSelfCheckReport struct {
+	Time    time.Time               `json:"time"    yaml:"time"`
+	Passed  bool                    `json:"passed"  yaml:"passed"`
+	Results []SystemSelfCheckResult `json:"results" yaml:"results"`
+}
+
+// SystemSelfCheckHistoryEntry summarizes a past self-check run.
+type SystemSelfCheckHistoryEntry struct {
+	Time   time.Time `json:"time"             yaml:"time"`
+	Passed bool      `json:"passed"           yaml:"passed"`
+	Failed []string  `json:"failed,omitempty" yaml:"failed,omitempty"` // Names of the failed checks.
+}
+
+// SystemSelfCheck represents the latest self-check report, along with the pass/fail history of the previous runs.
+type SystemSelfCheck struct {
+	LastReport *SystemSelfCheckReport        `json:"last_report" yaml:"last_report"`
+	History    []SystemSelfCheckHistoryEntry `json:"history"     yaml:"history"` // Oldest first.
+}
@@ -7,8 +7,17 @@ type SystemStorageConfig struct {
 
 // SystemStorageState represents additional state for the system's local storage.
 type SystemStorageState struct {
-	Drives []SystemStorageDrive `json:"drives" yaml:"drives"`
-	Pools  []SystemStoragePool  `json:"pools"  yaml:"pools"`
+	Drives          []SystemStorageDrive        `json:"drives"                     yaml:"drives"`
+	Pools           []SystemStoragePool         `json:"pools"                      yaml:"pools"`
+	InstallGeometry *SystemStorageDriveGeometry `json:"install_geometry,omitempty" yaml:"install_geometry,omitempty"` // Geometry of the drive IncusOS is installed on.
+}
+
+// SystemStorageDriveGeometry describes the sector sizes of a drive and, for NVMe drives, the namespace in use.
+type SystemStorageDriveGeometry struct {
+	Device            string `json:"device"                   yaml:"device"`
+	LogicalBlockSize  int    `json:"logical_block_size"       yaml:"logical_block_size"`
+	PhysicalBlockSize int    `json:"physical_block_size"      yaml:"physical_block_size"`
+	NVMeNamespace     int    `json:"nvme_namespace,omitempty" yaml:"nvme_namespace,omitempty"`
 }
 
 // SystemStorage defines a struct to hold information about the system's local storage.
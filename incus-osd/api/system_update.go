@@ -1,6 +1,10 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -13,29 +17,165 @@ type SystemUpdate struct {
 
 // SystemUpdateConfig defines a struct to hold configuration details for the update checks.
 type SystemUpdateConfig struct {
-	AutoReboot         bool                            `json:"auto_reboot"                   yaml:"auto_reboot"`
-	Channel            string                          `json:"channel"                       yaml:"channel"`
-	CheckFrequency     string                          `json:"check_frequency"               yaml:"check_frequency"`
-	MaintenanceWindows []SystemUpdateMaintenanceWindow `json:"maintenance_windows,omitempty" yaml:"maintenance_windows,omitempty"`
+	AutoReboot           bool                            `json:"auto_reboot"                     yaml:"auto_reboot"`
+	Channel              string                          `json:"channel"                         yaml:"channel"`
+	CheckFrequency       string                          `json:"check_frequency"                 yaml:"check_frequency"`
+	DownloadOnly         bool                            `json:"download_only"                   yaml:"download_only"`
+	MaintenanceWindows   []SystemUpdateMaintenanceWindow `json:"maintenance_windows,omitempty"   yaml:"maintenance_windows,omitempty"`
+	MeteredQuota         string                          `json:"metered_quota,omitempty"         yaml:"metered_quota,omitempty"`         // Monthly download quota for metered connections, such as "10GiB".
+	VerificationProvider *SystemProviderConfig           `json:"verification_provider,omitempty" yaml:"verification_provider,omitempty"` // Optional independent provider which must publish an identical OS update before it's applied.
 }
 
 // SystemUpdateState holds information about the current update state.
 type SystemUpdateState struct {
-	LastCheck   time.Time `json:"last_check"   yaml:"last_check"` // In system's timezone.
-	Status      string    `json:"status"       yaml:"status"`
-	NeedsReboot bool      `json:"needs_reboot" yaml:"needs_reboot"`
+	LastCheck          time.Time                      `json:"last_check"                    yaml:"last_check"` // In system's timezone.
+	Status             string                         `json:"status"                        yaml:"status"`
+	NeedsReboot        bool                           `json:"needs_reboot"                  yaml:"needs_reboot"`
+	StagedRelease      string                         `json:"staged_release,omitempty"      yaml:"staged_release,omitempty"`
+	StagedApplications map[string]string              `json:"staged_applications,omitempty" yaml:"staged_applications,omitempty"` // Application updates downloaded but not yet applied, by application name.
+	CurrentRelease     string                         `json:"current_release"               yaml:"current_release"`
+	NextRelease        string                         `json:"next_release,omitempty"        yaml:"next_release,omitempty"`       // Release which will run after the next reboot, if different from the current one.
+	InProgress         bool                           `json:"in_progress"                   yaml:"in_progress"`                  // Set while an update check or download is in progress.
+	InsufficientSpace  *SystemUpdateInsufficientSpace `json:"insufficient_space,omitempty"  yaml:"insufficient_space,omitempty"` // Set when the last update couldn't be downloaded due to lack of disk space.
+	DataUsage          []SystemUpdateDataUsage        `json:"data_usage,omitempty"          yaml:"data_usage,omitempty"`
+	Progress           *SystemUpdateProgress          `json:"progress,omitempty"            yaml:"progress,omitempty"`   // Set while an update is being downloaded or applied.
+	Available          []SystemUpdateAvailable        `json:"available,omitempty"           yaml:"available,omitempty"`  // Updates found during the last check which haven't been applied yet.
+	Provenance         []SystemUpdateProvenance       `json:"provenance,omitempty"          yaml:"provenance,omitempty"` // Origin of the recently downloaded OS and application updates, most recent last.
+}
+
+// SystemUpdateProgressPhase represents the phase of an in-progress update.
+type SystemUpdateProgressPhase string
+
+const (
+	// SystemUpdateProgressPhaseDownloading indicates the update is being downloaded.
+	SystemUpdateProgressPhaseDownloading SystemUpdateProgressPhase = "downloading"
+
+	// SystemUpdateProgressPhaseVerifying indicates the update is being verified, against the verification provider or by checking its signature.
+	SystemUpdateProgressPhaseVerifying SystemUpdateProgressPhase = "verifying"
+
+	// SystemUpdateProgressPhaseApplying indicates the update is being applied.
+	SystemUpdateProgressPhaseApplying SystemUpdateProgressPhase = "applying"
+)
+
+// SystemUpdateProgress holds the progress of an in-progress update.
+type SystemUpdateProgress struct {
+	Phase            SystemUpdateProgressPhase `json:"phase"             yaml:"phase"`
+	Component        string                    `json:"component"         yaml:"component"` // Either "os" or the name of an application.
+	Version          string                    `json:"version"           yaml:"version"`
+	File             string                    `json:"file,omitempty"    yaml:"file,omitempty"`
+	FilePercentage   int                       `json:"file_percentage"   yaml:"file_percentage"`
+	BytesTransferred int64                     `json:"bytes_transferred" yaml:"bytes_transferred"`
+	BytesTotal       int64                     `json:"bytes_total"       yaml:"bytes_total"`
+	ETA              int64                     `json:"eta,omitempty"     yaml:"eta,omitempty"` // Estimated number of seconds until the download completes.
+}
+
+// SystemUpdateAvailable holds details about an update which is available but not yet applied, to help schedule it.
+type SystemUpdateAvailable struct {
+	Component    string `json:"component"            yaml:"component"` // Either "os" or the name of an application.
+	Version      string `json:"version"              yaml:"version"`
+	Staged       bool   `json:"staged"               yaml:"staged"` // Already downloaded, waiting to be applied.
+	DownloadSize int64  `json:"download_size"        yaml:"download_size"`
+	StagingSize  int64  `json:"staging_size"         yaml:"staging_size"`         // Estimated disk space needed to download the update.
+	ApplyTime    int64  `json:"apply_time,omitempty" yaml:"apply_time,omitempty"` // Estimated number of seconds needed to apply the update, based on previous updates.
+}
+
+// SystemUpdateProvenance records where the files of a downloaded OS or application update came from.
+type SystemUpdateProvenance struct {
+	Component  string                       `json:"component"           yaml:"component"` // Either "os" or the name of an application.
+	Version    string                       `json:"version"             yaml:"version"`
+	Provider   string                       `json:"provider"            yaml:"provider"`
+	Signer     string                       `json:"signer,omitempty"    yaml:"signer,omitempty"` // Fingerprint of the certificate which signed the update metadata, if signed.
+	Files      []SystemUpdateProvenanceFile `json:"files"               yaml:"files"`
+	Downloaded time.Time                    `json:"downloaded"          yaml:"downloaded"`
+	Installed  *time.Time                   `json:"installed,omitempty" yaml:"installed,omitempty"` // Unset until the update has been applied.
+}
+
+// SystemUpdateProvenanceFile records where a single file of an update came from.
+type SystemUpdateProvenanceFile struct {
+	Filename string `json:"filename" yaml:"filename"`
+	Source   string `json:"source"   yaml:"source"` // URL or path the file was retrieved from.
+	Sha256   string `json:"sha256"   yaml:"sha256"`
+}
+
+// SystemUpdateUpload holds the state of a chunked upload of an update bundle.
+type SystemUpdateUpload struct {
+	ID   string `json:"id"   yaml:"id"`
+	Size int64  `json:"size" yaml:"size"` // Number of bytes received so far.
+}
+
+// SystemUpdateDataUsage holds the amount of data downloaded from a provider during a given month.
+type SystemUpdateDataUsage struct {
+	Month    string `json:"month"    yaml:"month"` // Formatted as YYYY-MM.
+	Provider string `json:"provider" yaml:"provider"`
+	Bytes    int64  `json:"bytes"    yaml:"bytes"`
+}
+
+// SystemUpdateInsufficientSpace holds details about an update which couldn't be downloaded due to lack of disk space.
+type SystemUpdateInsufficientSpace struct {
+	Path      string `json:"path"      yaml:"path"`
+	Required  int64  `json:"required"  yaml:"required"`
+	Available int64  `json:"available" yaml:"available"`
 }
 
 // SystemUpdateMaintenanceWindow defines a maintenance window for when it is acceptable to check for and apply updates.
 // StartDayOfWeek and EndDayOfWeek are optional, and if non-zero can be used to limit the migration window to certain day(s).
-// Times are assumed to be in UTC.
+// Times are in the given timezone, or in the system's timezone if none is provided.
 type SystemUpdateMaintenanceWindow struct {
-	StartDayOfWeek Weekday `json:"start_day_of_week,omitempty" yaml:"start_day_of_week,omitempty"`
-	StartHour      int     `json:"start_hour"                  yaml:"start_hour"`
-	StartMinute    int     `json:"start_minute"                yaml:"start_minute"`
-	EndDayOfWeek   Weekday `json:"end_day_of_week,omitempty"   yaml:"end_day_of_week,omitempty"`
-	EndHour        int     `json:"end_hour"                    yaml:"end_hour"`
-	EndMinute      int     `json:"end_minute"                  yaml:"end_minute"`
+	StartDayOfWeek Weekday  `json:"start_day_of_week,omitempty" yaml:"start_day_of_week,omitempty"`
+	StartHour      int      `json:"start_hour"                  yaml:"start_hour"`
+	StartMinute    int      `json:"start_minute"                yaml:"start_minute"`
+	EndDayOfWeek   Weekday  `json:"end_day_of_week,omitempty"   yaml:"end_day_of_week,omitempty"`
+	EndHour        int      `json:"end_hour"                    yaml:"end_hour"`
+	EndMinute      int      `json:"end_minute"                  yaml:"end_minute"`
+	Timezone       string   `json:"timezone,omitempty"          yaml:"timezone,omitempty"`       // IANA timezone name, such as "Europe/Paris".
+	WeekOfMonth    int      `json:"week_of_month,omitempty"     yaml:"week_of_month,omitempty"`  // Only start on the Nth StartDayOfWeek of the month, from 1 to 4, or -1 for the last one.
+	BlackoutDates  []string `json:"blackout_dates,omitempty"    yaml:"blackout_dates,omitempty"` // Dates (YYYY-MM-DD) or inclusive date ranges (YYYY-MM-DD/YYYY-MM-DD) on which the window doesn't start.
+}
+
+// maxSkippedMaintenanceWindows is the number of consecutive occurrences of a maintenance window, excluded
+// by week of month or blackout dates, after which it's considered as never becoming active again.
+const maxSkippedMaintenanceWindows = 1000
+
+// Validate checks that the maintenance window is valid.
+func (w *SystemUpdateMaintenanceWindow) Validate() error {
+	// To simplify logic, we don't allow a week-long migration window
+	// to start and end on the same day.
+	if w.StartDayOfWeek != NONE && w.StartDayOfWeek == w.EndDayOfWeek {
+		if w.EndHour*60+w.EndMinute < w.StartHour*60+w.StartMinute {
+			return errors.New("invalid migration window: end time is before start time")
+		}
+	}
+
+	// If either StartDayOfWeek or EndDayOfWeek is specified, the other must be too.
+	if (w.StartDayOfWeek == NONE && w.EndDayOfWeek != NONE) || (w.StartDayOfWeek != NONE && w.EndDayOfWeek == NONE) {
+		return errors.New("invalid migration window: both StartDayOfWeek and EndDayOfWeek must be provided")
+	}
+
+	if w.Timezone != "" {
+		_, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid migration window: unknown timezone %q", w.Timezone)
+		}
+	}
+
+	if w.WeekOfMonth != 0 {
+		if w.StartDayOfWeek == NONE {
+			return errors.New("invalid migration window: WeekOfMonth requires StartDayOfWeek")
+		}
+
+		if w.WeekOfMonth < -1 || w.WeekOfMonth > 4 {
+			return errors.New("invalid migration window: WeekOfMonth must be between 1 and 4, or -1")
+		}
+	}
+
+	for _, blackout := range w.BlackoutDates {
+		_, _, err := parseBlackoutDates(blackout)
+		if err != nil {
+			return fmt.Errorf("invalid migration window: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // IsCurrentlyActive returns true if the maintenance window is active.
@@ -54,8 +194,130 @@ func (w *SystemUpdateMaintenanceWindow) TimeUntilActive() time.Duration {
 }
 
 // TimeUntilActiveReference returns a time.Duration representing the amount of time until the maintenance window becomes
-// active compared to the given reference time.
+// active compared to the given reference time. Occurrences of the window which don't start on the expected week of the
+// month, or which start on a blackout date, are skipped.
 func (w *SystemUpdateMaintenanceWindow) TimeUntilActiveReference(t time.Time) time.Duration {
+	if w.Timezone != "" {
+		loc, err := time.LoadLocation(w.Timezone)
+		if err == nil {
+			t = t.In(loc)
+		}
+	}
+
+	ref := t
+
+	for range maxSkippedMaintenanceWindows {
+		untilActive := w.timeUntilWeeklyActive(ref)
+
+		// Get the start of the current or next occurrence of the window.
+		var start time.Time
+		if untilActive == 0 {
+			start = w.previousStart(ref)
+		} else {
+			start = w.nextStart(ref)
+		}
+
+		if w.occurrenceAllowed(start) {
+			if ref.Equal(t) {
+				return untilActive
+			}
+
+			return start.Sub(t.Truncate(time.Minute))
+		}
+
+		// Skip this occurrence and look past its end.
+		ref = w.occurrenceEnd(start).Add(time.Minute)
+	}
+
+	return time.Duration(math.MaxInt64)
+}
+
+// previousStart returns the start of the most recent occurrence of the window, at or before the given time.
+func (w *SystemUpdateMaintenanceWindow) previousStart(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), w.StartHour, w.StartMinute, 0, 0, t.Location())
+
+	for start.After(t) || (w.StartDayOfWeek != NONE && start.Weekday() != w.StartDayOfWeek.ToWeekday()) {
+		start = time.Date(start.Year(), start.Month(), start.Day()-1, w.StartHour, w.StartMinute, 0, 0, t.Location())
+	}
+
+	return start
+}
+
+// nextStart returns the start of the next occurrence of the window, at or after the given time.
+func (w *SystemUpdateMaintenanceWindow) nextStart(t time.Time) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), w.StartHour, w.StartMinute, 0, 0, t.Location())
+
+	for start.Before(t) || (w.StartDayOfWeek != NONE && start.Weekday() != w.StartDayOfWeek.ToWeekday()) {
+		start = time.Date(start.Year(), start.Month(), start.Day()+1, w.StartHour, w.StartMinute, 0, 0, t.Location())
+	}
+
+	return start
+}
+
+// occurrenceEnd returns the end of the occurrence of the window starting at the given time.
+func (w *SystemUpdateMaintenanceWindow) occurrenceEnd(start time.Time) time.Time {
+	end := time.Date(start.Year(), start.Month(), start.Day(), w.EndHour, w.EndMinute, 0, 0, start.Location())
+
+	for end.Before(start) || (w.EndDayOfWeek != NONE && end.Weekday() != w.EndDayOfWeek.ToWeekday()) {
+		end = time.Date(end.Year(), end.Month(), end.Day()+1, w.EndHour, w.EndMinute, 0, 0, start.Location())
+	}
+
+	return end
+}
+
+// occurrenceAllowed returns whether the occurrence of the window starting at the given time is on the expected
+// week of the month and not on a blackout date.
+func (w *SystemUpdateMaintenanceWindow) occurrenceAllowed(start time.Time) bool {
+	switch {
+	case w.WeekOfMonth > 0 && (start.Day()-1)/7+1 != w.WeekOfMonth:
+		return false
+	case w.WeekOfMonth == -1 && start.AddDate(0, 0, 7).Month() == start.Month():
+		return false
+	}
+
+	day := start.Format(time.DateOnly)
+
+	for _, blackout := range w.BlackoutDates {
+		first, last, err := parseBlackoutDates(blackout)
+		if err != nil {
+			continue
+		}
+
+		if day >= first.Format(time.DateOnly) && day <= last.Format(time.DateOnly) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseBlackoutDates parses a blackout date or inclusive range of dates, returning its first and last day.
+func parseBlackoutDates(blackout string) (time.Time, time.Time, error) {
+	firstStr, lastStr, isRange := strings.Cut(blackout, "/")
+	if !isRange {
+		lastStr = firstStr
+	}
+
+	first, err := time.Parse(time.DateOnly, firstStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid blackout date %q", blackout)
+	}
+
+	last, err := time.Parse(time.DateOnly, lastStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid blackout date %q", blackout)
+	}
+
+	if last.Before(first) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid blackout date range %q: end is before start", blackout)
+	}
+
+	return first, last, nil
+}
+
+// timeUntilWeeklyActive returns the amount of time until the weekly recurring maintenance window becomes active
+// compared to the given reference time, without considering the week of month or blackout dates.
+func (w *SystemUpdateMaintenanceWindow) timeUntilWeeklyActive(t time.Time) time.Duration {
 	// Compute maintenance windows as the number of minutes since 00:00 on Sunday.
 	// We don't care about actual dates, just (potentially) days of the week and
 	// a start/end time for each window.
@@ -278,3 +278,119 @@ func TestMigrationWindows(t *testing.T) {
 		require.Equal(t, timeUntilActive, tst.Duration, "Test %d failed", i)
 	}
 }
+
+func TestMigrationWindowRules(t *testing.T) {
+	t.Parallel()
+
+	// Second Tuesday of the month.
+	secondTuesday := api.SystemUpdateMaintenanceWindow{
+		StartDayOfWeek: api.Tuesday,
+		StartHour:      2,
+		EndDayOfWeek:   api.Tuesday,
+		EndHour:        4,
+		WeekOfMonth:    2,
+	}
+
+	// Last Friday of the month, ending on Saturday.
+	lastFriday := api.SystemUpdateMaintenanceWindow{
+		StartDayOfWeek: api.Friday,
+		StartHour:      22,
+		EndDayOfWeek:   api.Saturday,
+		EndHour:        2,
+		WeekOfMonth:    -1,
+	}
+
+	// Daily window with blackout dates.
+	blackout := mw1
+	blackout.BlackoutDates = []string{"2025-08-28", "2025-09-10/2025-09-12"}
+
+	// Daily window in another timezone.
+	newYork := mw1
+	newYork.Timezone = "America/New_York"
+
+	tests := []testInfo{
+		{
+			MigrationWindow: secondTuesday,
+			Time:            time.Date(2025, 8, 12, 3, 0, 0, 0, time.UTC),
+			Result:          true,
+			Duration:        0 * time.Minute,
+		},
+		{
+			MigrationWindow: secondTuesday,
+			Time:            time.Date(2025, 8, 5, 2, 0, 0, 0, time.UTC),
+			Result:          false,
+			Duration:        7 * 24 * time.Hour,
+		},
+		{
+			MigrationWindow: secondTuesday,
+			Time:            time.Date(2025, 8, 12, 5, 0, 0, 0, time.UTC),
+			Result:          false,
+			Duration:        27*24*time.Hour + 21*time.Hour,
+		},
+		{
+			MigrationWindow: lastFriday,
+			Time:            time.Date(2025, 8, 30, 1, 0, 0, 0, time.UTC),
+			Result:          true,
+			Duration:        0 * time.Minute,
+		},
+		{
+			MigrationWindow: lastFriday,
+			Time:            time.Date(2025, 8, 23, 1, 0, 0, 0, time.UTC),
+			Result:          false,
+			Duration:        6*24*time.Hour + 21*time.Hour,
+		},
+		{
+			MigrationWindow: blackout,
+			Time:            time.Date(2025, 8, 27, 10, 30, 0, 0, time.UTC),
+			Result:          true,
+			Duration:        0 * time.Minute,
+		},
+		{
+			MigrationWindow: blackout,
+			Time:            time.Date(2025, 8, 28, 10, 30, 0, 0, time.UTC),
+			Result:          false,
+			Duration:        23*time.Hour + 30*time.Minute,
+		},
+		{
+			MigrationWindow: blackout,
+			Time:            time.Date(2025, 9, 10, 9, 0, 0, 0, time.UTC),
+			Result:          false,
+			Duration:        3*24*time.Hour + 1*time.Hour,
+		},
+		{
+			MigrationWindow: newYork,
+			Time:            time.Date(2025, 8, 28, 14, 30, 0, 0, time.UTC),
+			Result:          true,
+			Duration:        0 * time.Minute,
+		},
+		{
+			MigrationWindow: newYork,
+			Time:            time.Date(2025, 8, 28, 10, 30, 0, 0, time.UTC),
+			Result:          false,
+			Duration:        3*time.Hour + 30*time.Minute,
+		},
+	}
+
+	for i, tst := range tests {
+		require.NoError(t, tst.MigrationWindow.Validate(), "Test %d failed", i)
+
+		isActive := tst.MigrationWindow.IsActive(tst.Time)
+		require.Equal(t, tst.Result, isActive, "Test %d failed", i)
+
+		timeUntilActive := tst.MigrationWindow.TimeUntilActiveReference(tst.Time)
+		require.Equal(t, tst.Duration, timeUntilActive, "Test %d failed", i)
+	}
+
+	// Invalid rules.
+	invalid := []api.SystemUpdateMaintenanceWindow{
+		{StartHour: 2, EndHour: 4, WeekOfMonth: 2},
+		{StartDayOfWeek: api.Monday, EndDayOfWeek: api.Monday, EndHour: 1, WeekOfMonth: 5},
+		{EndHour: 1, Timezone: "Invalid/Timezone"},
+		{EndHour: 1, BlackoutDates: []string{"2025-13-01"}},
+		{EndHour: 1, BlackoutDates: []string{"2025-09-12/2025-09-10"}},
+	}
+
+	for i, mw := range invalid {
+		require.Error(t, mw.Validate(), "Test %d failed", i)
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+// SystemWarningType represents the type of a system warning.
+type SystemWarningType string
+
+const (
+	// SystemWarningTypeDeprecated is used when a deprecated configuration field is in use.
+	SystemWarningTypeDeprecated SystemWarningType = "deprecated"
+
+	// SystemWarningTypeInsecure is used when the system's configuration or state weakens its security.
+	SystemWarningTypeInsecure SystemWarningType = "insecure"
+
+	// SystemWarningTypeActionRequired is used when an action is pending from the administrator.
+	SystemWarningTypeActionRequired SystemWarningType = "action-required"
+)
+
+// SystemWarning defines a struct to hold a machine-readable warning about the system's configuration or state.
+type SystemWarning struct {
+	Type    SystemWarningType `json:"type"    yaml:"type"`
+	Entity  string            `json:"entity"  yaml:"entity"` // API endpoint the warning relates to.
+	Message string            `json:"message" yaml:"message"`
+}
@@ -39,6 +39,10 @@ func (c *cmdAdminOSApplication) command() *cobra.Command {
 	}
 	cmd.AddCommand(backupCmd.command())
 
+	// Edit.
+	editCmd := cmdGenericEdit{os: c.os, entity: "application", entityShort: "application", endpoint: "applications"}
+	cmd.AddCommand(editCmd.command())
+
 	// Factory reset.
 	factoryResetCmd := cmdGenericRun{
 		os:          c.os,
@@ -56,6 +60,19 @@ func (c *cmdAdminOSApplication) command() *cobra.Command {
 	listCmd := cmdGenericList{os: c.os, entity: "applications", endpoint: "applications"}
 	cmd.AddCommand(listCmd.command())
 
+	// Remove.
+	removeCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "remove",
+		description: "Remove the application and wipe its local data",
+		endpoint:    "applications",
+		entity:      "application",
+		hasData:     true,
+		defaultData: "{}",
+		confirm:     "remove the application",
+	}
+	cmd.AddCommand(removeCmd.command())
+
 	// Restart.
 	restartCmd := cmdGenericRun{
 		os:          c.os,
@@ -79,6 +96,37 @@ func (c *cmdAdminOSApplication) command() *cobra.Command {
 	}
 	cmd.AddCommand(restoreCmd.command())
 
+	// Start.
+	startCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "start",
+		description: "Start the application",
+		endpoint:    "applications",
+		entity:      "application",
+	}
+	cmd.AddCommand(startCmd.command())
+
+	// Stop.
+	stopCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "stop",
+		description: "Stop the application",
+		endpoint:    "applications",
+		entity:      "application",
+		confirm:     "stop the application",
+	}
+	cmd.AddCommand(stopCmd.command())
+
+	// Update.
+	updateCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "update",
+		description: "Apply the staged update of the application, or check for updates",
+		endpoint:    "applications",
+		entity:      "application",
+	}
+	cmd.AddCommand(updateCmd.command())
+
 	// Show.
 	showCmd := cmdGenericShow{os: c.os, entity: "application", entityShort: "application", endpoint: "applications"}
 	cmd.AddCommand(showCmd.command())
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	cli "github.com/lxc/incus/v6/shared/cmd"
+	"github.com/spf13/cobra"
+)
+
+// IncusOS cluster command.
+type cmdAdminOSCluster struct {
+	os *cmdAdminOS
+}
+
+func (c *cmdAdminOSCluster) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("cluster")
+	cmd.Short = "Manage IncusOS cluster peers"
+	cmd.Long = cli.FormatSection("Description", "Manage IncusOS cluster peers")
+
+	// Peers.
+	peersCmd := &cobra.Command{}
+	peersCmd.Use = cli.Usage("peers")
+	peersCmd.Short = "Peers discovered on the local network"
+	peersCmd.Long = cli.FormatSection("Description", "Peers discovered on the local network")
+
+	showCmd := cmdGenericShow{os: c.os, endpoint: "cluster/peers"}
+	peersCmd.AddCommand(showCmd.command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+	peersCmd.Args = cobra.NoArgs
+	peersCmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+	cmd.AddCommand(peersCmd)
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+	return cmd
+}
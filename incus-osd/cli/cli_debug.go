@@ -1,11 +1,17 @@
 package cli
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lxc/incus/v6/shared/api"
 	cli "github.com/lxc/incus/v6/shared/cmd"
 	"github.com/spf13/cobra"
 )
@@ -21,6 +27,14 @@ func (c *cmdAdminOSDebug) command() *cobra.Command {
 	cmd.Short = "Debug IncusOS systems"
 	cmd.Long = cli.FormatSection("Description", "Debug IncusOS systems")
 
+	// Audit.
+	auditCmd := cmdAdminOSDebugAudit{os: c.os}
+	cmd.AddCommand(auditCmd.command())
+
+	// Crash dumps.
+	crashDumpCmd := cmdAdminOSDebugCrashDump{os: c.os}
+	cmd.AddCommand(crashDumpCmd.command())
+
 	// Log.
 	logCmd := cmdAdminOSDebugLog{os: c.os}
 	cmd.AddCommand(logCmd.command())
@@ -32,13 +46,165 @@ func (c *cmdAdminOSDebug) command() *cobra.Command {
 	return cmd
 }
 
+// Audit.
+type cmdAdminOSDebugAudit struct {
+	os *cmdAdminOS
+
+	flagMethod   string
+	flagEndpoint string
+	flagSince    string
+	flagEntries  string
+}
+
+func (c *cmdAdminOSDebugAudit) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("audit")
+	cmd.Short = "Get audit log"
+
+	cmd.Long = cli.FormatSection("Description", "Get the audit log of API calls which may have modified the system")
+	if c.os.args.SupportsTarget {
+		cmd.Flags().StringVar(&c.os.flagTarget, "target", "", "Cluster member name``")
+	}
+
+	cmd.Flags().StringVarP(&c.flagMethod, "method", "m", "", "Request method``")
+	cmd.Flags().StringVarP(&c.flagEndpoint, "endpoint", "e", "", "Endpoint prefix``")
+	cmd.Flags().StringVarP(&c.flagSince, "since", "s", "", "Only show entries since the provided time (RFC3339)``")
+	cmd.Flags().StringVarP(&c.flagEntries, "entries", "n", "", "Number of entries``")
+
+	cmd.RunE = c.run
+
+	return cmd
+}
+
+func (c *cmdAdminOSDebugAudit) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := cli.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote.
+	remote := ""
+	if len(args) > 0 {
+		remote, _ = parseRemote(args[0])
+	}
+
+	// Prepare the URL.
+	u, err := url.Parse("/os/1.0/debug/audit")
+	if err != nil {
+		return err
+	}
+
+	values := u.Query()
+	if c.os.flagTarget != "" {
+		values.Set("target", c.os.flagTarget)
+	}
+
+	if c.flagMethod != "" {
+		values.Set("method", c.flagMethod)
+	}
+
+	if c.flagEndpoint != "" {
+		values.Set("endpoint", c.flagEndpoint)
+	}
+
+	if c.flagSince != "" {
+		values.Set("since", c.flagSince)
+	}
+
+	if c.flagEntries != "" {
+		values.Set("entries", c.flagEntries)
+	}
+
+	u.RawQuery = values.Encode()
+
+	// Get the audit log.
+	resp, _, err := doQuery(c.os.args.DoHTTP, remote, "GET", u.String(), nil, nil, "")
+	if err != nil {
+		return err
+	}
+
+	var data []struct {
+		Time       time.Time `json:"time"`
+		Source     string    `json:"source"`
+		Identity   string    `json:"identity"`
+		Method     string    `json:"method"`
+		Endpoint   string    `json:"endpoint"`
+		StatusCode int       `json:"status_code"`
+	}
+
+	err = resp.MetadataAsStruct(&data)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range data {
+		_, _ = fmt.Printf("[%s] %s %s (%d) by %s %s\n", entry.Time.Local().Format(dateLayoutSecond), entry.Method, entry.Endpoint, entry.StatusCode, entry.Source, entry.Identity) //nolint:forbidigo
+	}
+
+	return nil
+}
+
+// Crash dumps.
+type cmdAdminOSDebugCrashDump struct {
+	os *cmdAdminOS
+}
+
+func (c *cmdAdminOSDebugCrashDump) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("crash-dump")
+	cmd.Short = "Manage kernel crash dumps"
+	cmd.Long = cli.FormatSection("Description", "Manage the kernel crash dumps captured by the kdump service")
+
+	// Delete.
+	deleteCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "delete",
+		description: "Delete a kernel crash dump",
+		endpoint:    "debug/kdump",
+		entity:      "dump",
+		confirm:     "delete the kernel crash dump",
+	}
+	cmd.AddCommand(deleteCmd.command())
+
+	// Download.
+	downloadCmd := cmdGenericRun{
+		os:            c.os,
+		action:        "download",
+		description:   "Download a kernel crash dump",
+		endpoint:      "debug/kdump",
+		entity:        "dump",
+		hasFileOutput: true,
+	}
+	cmd.AddCommand(downloadCmd.command())
+
+	// List.
+	listCmd := cmdGenericList{os: c.os, entity: "kernel crash dumps", endpoint: "debug/kdump"}
+	cmd.AddCommand(listCmd.command())
+
+	// Show.
+	showCmd := cmdGenericShow{os: c.os, entity: "kernel crash dump", entityShort: "dump", endpoint: "debug/kdump"}
+	cmd.AddCommand(showCmd.command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+	cmd.Args = cobra.NoArgs
+	cmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+	return cmd
+}
+
 // Log.
 type cmdAdminOSDebugLog struct {
 	os *cmdAdminOS
 
-	flagUnit    string
-	flagBoot    string
-	flagEntries string
+	flagUnit     string
+	flagKernel   bool
+	flagPriority string
+	flagSince    string
+	flagUntil    string
+	flagBoot     string
+	flagEntries  string
+	flagFollow   bool
 }
 
 func (c *cmdAdminOSDebugLog) command() *cobra.Command {
@@ -52,8 +218,13 @@ func (c *cmdAdminOSDebugLog) command() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&c.flagUnit, "unit", "u", "", "Unit name``")
+	cmd.Flags().BoolVarP(&c.flagKernel, "kernel", "k", false, "Only show kernel messages")
+	cmd.Flags().StringVarP(&c.flagPriority, "priority", "p", "", "Priority or range of priorities, such as \"err\" or \"warning..err\"``")
+	cmd.Flags().StringVar(&c.flagSince, "since", "", "Only show entries since the provided time (RFC3339)``")
+	cmd.Flags().StringVar(&c.flagUntil, "until", "", "Only show entries until the provided time (RFC3339)``")
 	cmd.Flags().StringVarP(&c.flagBoot, "boot", "b", "", "Boot number``")
 	cmd.Flags().StringVarP(&c.flagEntries, "entries", "n", "", "Number of entries``")
+	cmd.Flags().BoolVarP(&c.flagFollow, "follow", "f", false, "Follow new entries as they get logged")
 
 	cmd.RunE = c.run
 
@@ -88,6 +259,22 @@ func (c *cmdAdminOSDebugLog) run(cmd *cobra.Command, args []string) error {
 		values.Set("unit", c.flagUnit)
 	}
 
+	if c.flagKernel {
+		values.Set("kernel", "1")
+	}
+
+	if c.flagPriority != "" {
+		values.Set("priority", c.flagPriority)
+	}
+
+	if c.flagSince != "" {
+		values.Set("since", c.flagSince)
+	}
+
+	if c.flagUntil != "" {
+		values.Set("until", c.flagUntil)
+	}
+
 	if c.flagBoot != "" {
 		values.Set("boot", c.flagBoot)
 	}
@@ -96,6 +283,13 @@ func (c *cmdAdminOSDebugLog) run(cmd *cobra.Command, args []string) error {
 		values.Set("entries", c.flagEntries)
 	}
 
+	if c.flagFollow {
+		values.Set("follow", "1")
+		u.RawQuery = values.Encode()
+
+		return c.follow(remote, u.String())
+	}
+
 	u.RawQuery = values.Encode()
 
 	// Get the log.
@@ -112,33 +306,88 @@ func (c *cmdAdminOSDebugLog) run(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, line := range data {
-		// Get and parse the timestamp.
-		timeStr, ok := line["__REALTIME_TIMESTAMP"].(string)
-		if !ok {
-			continue
-		}
+		printLogEntry(line)
+	}
 
-		timeInt, err := strconv.ParseInt(timeStr, 10, 64)
+	return nil
+}
+
+// follow prints the journal entries streamed by the server until interrupted.
+func (c *cmdAdminOSDebugLog) follow(remote string, path string) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.os.args.DoHTTP(remote, req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	// Report errors, which are returned as regular JSON responses.
+	if resp.StatusCode != http.StatusOK {
+		response := api.Response{}
+
+		err = json.NewDecoder(resp.Body).Decode(&response)
 		if err != nil {
-			continue
+			return fmt.Errorf("failed to fetch %s: %s", resp.Request.URL.String(), resp.Status)
 		}
 
-		ts := time.UnixMicro(timeInt)
+		return api.StatusErrorf(resp.StatusCode, "%v", response.Error)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1024*1024)
 
-		// Get the section identifier.
-		section, ok := line["SYSLOG_IDENTIFIER"].(string)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
 		if !ok {
 			continue
 		}
 
-		// Get the message itself.
-		message, ok := line["MESSAGE"].(string)
-		if !ok {
+		line := map[string]any{}
+
+		err := json.Unmarshal([]byte(data), &line)
+		if err != nil {
 			continue
 		}
 
-		_, _ = fmt.Printf("[%s] %s: %s\n", ts.Format(dateLayoutSecond), section, message) //nolint:forbidigo
+		printLogEntry(line)
 	}
 
-	return nil
+	return scanner.Err()
+}
+
+// printLogEntry prints a single journal entry.
+func printLogEntry(line map[string]any) {
+	// Get and parse the timestamp.
+	timeStr, ok := line["__REALTIME_TIMESTAMP"].(string)
+	if !ok {
+		return
+	}
+
+	timeInt, err := strconv.ParseInt(timeStr, 10, 64)
+	if err != nil {
+		return
+	}
+
+	ts := time.UnixMicro(timeInt)
+
+	// Get the section identifier.
+	section, ok := line["SYSLOG_IDENTIFIER"].(string)
+	if !ok {
+		return
+	}
+
+	// Get the message itself.
+	message, ok := line["MESSAGE"].(string)
+	if !ok {
+		return
+	}
+
+	_, _ = fmt.Printf("[%s] %s: %s\n", ts.Format(dateLayoutSecond), section, message) //nolint:forbidigo
 }
@@ -25,10 +25,29 @@ func (c *cmdAdminOS) command() *cobra.Command {
 	applicationCmd := cmdAdminOSApplication{os: c}
 	cmd.AddCommand(applicationCmd.command())
 
+	// Cluster.
+	clusterCmd := cmdAdminOSCluster{os: c}
+	cmd.AddCommand(clusterCmd.command())
+
 	// Debug.
 	debugCmd := cmdAdminOSDebug{os: c}
 	cmd.AddCommand(debugCmd.command())
 
+	// Health.
+	healthCmd := &cobra.Command{}
+	healthCmd.Use = cli.Usage("health")
+	healthCmd.Short = "Detailed system health"
+	healthCmd.Long = cli.FormatSection("Description", "Detailed system health")
+
+	healthShowCmd := cmdGenericShow{os: c, endpoint: "health"}
+	healthCmd.AddCommand(healthShowCmd.command())
+
+	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+	healthCmd.Args = cobra.NoArgs
+	healthCmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+	cmd.AddCommand(healthCmd)
+
 	// Services.
 	serviceCmd := cmdAdminOSService{os: c}
 	cmd.AddCommand(serviceCmd.command())
@@ -1,8 +1,14 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
 	cli "github.com/lxc/incus/v6/shared/cmd"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 // IncusOS service command.
@@ -35,13 +41,180 @@ func (c *cmdAdminOSService) command() *cobra.Command {
 	}
 	cmd.AddCommand(resetCmd.command())
 
+	// Restart.
+	restartCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "restart",
+		description: "Restart the service",
+		endpoint:    "services",
+		entity:      "service",
+		confirm:     "restart the service",
+	}
+	cmd.AddCommand(restartCmd.command())
+
+	// Log.
+	logCmd := cmdAdminOSServiceLog{os: c.os}
+	cmd.AddCommand(logCmd.command())
+
 	// Show.
 	showCmd := cmdGenericShow{os: c.os, entity: "service", entityShort: "service", endpoint: "services"}
 	cmd.AddCommand(showCmd.command())
 
+	// Status.
+	statusCmd := cmdAdminOSServiceStatus{os: c.os}
+	cmd.AddCommand(statusCmd.command())
+
 	// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
 	cmd.Args = cobra.NoArgs
 	cmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
 
 	return cmd
 }
+
+// Log.
+type cmdAdminOSServiceLog struct {
+	os *cmdAdminOS
+
+	flagEntries string
+}
+
+func (c *cmdAdminOSServiceLog) command() *cobra.Command {
+	usage := ""
+	if c.os.args.SupportsRemote {
+		usage = "[<remote>:]"
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("log", usage+"<service>")
+	cmd.Short = "Get the service log"
+	cmd.Long = cli.FormatSection("Description", "Get the recent journal entries of the systemd units used by the service")
+
+	if c.os.args.SupportsTarget {
+		cmd.Flags().StringVar(&c.os.flagTarget, "target", "", "Cluster member name``")
+	}
+
+	cmd.Flags().StringVarP(&c.flagEntries, "entries", "n", "", "Number of entries``")
+
+	cmd.RunE = c.run
+
+	return cmd
+}
+
+func (c *cmdAdminOSServiceLog) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := cli.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote.
+	remote, resource := parseRemote(args[0])
+	if resource == "" {
+		return errors.New("missing service name")
+	}
+
+	// Prepare the URL.
+	u, err := url.Parse("/os/1.0/services/" + resource + "/log")
+	if err != nil {
+		return err
+	}
+
+	values := u.Query()
+	if c.os.flagTarget != "" {
+		values.Set("target", c.os.flagTarget)
+	}
+
+	if c.flagEntries != "" {
+		values.Set("entries", c.flagEntries)
+	}
+
+	u.RawQuery = values.Encode()
+
+	// Get the log.
+	resp, _, err := doQuery(c.os.args.DoHTTP, remote, "GET", u.String(), nil, nil, "")
+	if err != nil {
+		return err
+	}
+
+	var entries []struct {
+		Time    time.Time `json:"time"`
+		Unit    string    `json:"unit"`
+		Message string    `json:"message"`
+	}
+
+	err = resp.MetadataAsStruct(&entries)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, _ = fmt.Printf("%s %s: %s\n", entry.Time.Local().Format(time.DateTime), entry.Unit, entry.Message) //nolint:forbidigo
+	}
+
+	return nil
+}
+
+// Status.
+type cmdAdminOSServiceStatus struct {
+	os *cmdAdminOS
+}
+
+func (c *cmdAdminOSServiceStatus) command() *cobra.Command {
+	usage := ""
+	if c.os.args.SupportsRemote {
+		usage = "[<remote>:]"
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("status", usage+"<service>")
+	cmd.Short = "Show the service status"
+	cmd.Long = cli.FormatSection("Description", "Show the runtime status of the service and the reason of its most recent failure")
+
+	if c.os.args.SupportsTarget {
+		cmd.Flags().StringVar(&c.os.flagTarget, "target", "", "Cluster member name``")
+	}
+
+	cmd.RunE = c.run
+
+	return cmd
+}
+
+func (c *cmdAdminOSServiceStatus) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := cli.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote.
+	remote, resource := parseRemote(args[0])
+	if resource == "" {
+		return errors.New("missing service name")
+	}
+
+	apiURL := "/os/1.0/services/" + resource + "/status"
+	if c.os.flagTarget != "" {
+		apiURL += "?target=" + c.os.flagTarget
+	}
+
+	resp, _, err := doQuery(c.os.args.DoHTTP, remote, "GET", apiURL, nil, nil, "")
+	if err != nil {
+		return err
+	}
+
+	var rawData any
+
+	err = resp.MetadataAsStruct(&rawData)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(rawData)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Printf("%s", data) //nolint:forbidigo
+
+	return nil
+}
@@ -1,6 +1,11 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
 	cli "github.com/lxc/incus/v6/shared/cmd"
 	"github.com/spf13/cobra"
 )
@@ -16,6 +21,28 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 	cmd.Short = "Manage IncusOS system details"
 	cmd.Long = cli.FormatSection("Description", "Manage IncusOS system details")
 
+	// Apply profile.
+	applyProfileCmd := cmdGenericRun{
+		os:           c.os,
+		action:       "apply-profile",
+		description:  "Apply a node profile (JSON) in a single transaction",
+		endpoint:     "system",
+		hasFileInput: true,
+	}
+	cmd.AddCommand(applyProfileCmd.command())
+
+	// Apply seed.
+	applySeedCmd := cmdGenericRun{
+		os:          c.os,
+		action:      "apply-seed",
+		description: "Re-apply the network, provider and applications seed",
+		endpoint:    "system",
+		hasData:     true,
+		defaultData: "{}",
+		hasOutput:   true,
+	}
+	cmd.AddCommand(applySeedCmd.command())
+
 	// Backup.
 	backupCmd := cmdGenericRun{
 		os:            c.os,
@@ -28,6 +55,18 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 	}
 	cmd.AddCommand(backupCmd.command())
 
+	// Export configuration.
+	exportConfigCmd := cmdGenericRun{
+		os:            c.os,
+		action:        "export-config",
+		description:   "Export the system configuration (YAML)",
+		endpoint:      "system",
+		hasData:       true,
+		defaultData:   "{}",
+		hasFileOutput: true,
+	}
+	cmd.AddCommand(exportConfigCmd.command())
+
 	// Factory reset.
 	factoryResetCmd := cmdGenericRun{
 		os:          c.os,
@@ -36,10 +75,27 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 		endpoint:    "system",
 		hasData:     true,
 		defaultData: "{}",
+		hasOutput:   true,
 		confirm:     "factory-reset the system",
 	}
 	cmd.AddCommand(factoryResetCmd.command())
 
+	// History.
+	historyCmd := cmdAdminOSSystemHistory{os: c.os}
+	cmd.AddCommand(historyCmd.command())
+
+	// Import configuration.
+	importConfigCmd := cmdGenericRun{
+		os:           c.os,
+		action:       "import-config",
+		description:  "Import a system configuration (YAML or JSON)",
+		endpoint:     "system",
+		hasFileInput: true,
+		hasOutput:    true,
+		confirm:      "apply the provided configuration to the system",
+	}
+	cmd.AddCommand(importConfigCmd.command())
+
 	// Power off.
 	poweroffCmd := cmdGenericRun{
 		os:          c.os,
@@ -87,6 +143,11 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 	}
 
 	subCommands := []subCommand{
+		{
+			name:        "dns",
+			description: "DNS providers and dynamic records",
+			isWritable:  true,
+		},
 		{
 			name:        "logging",
 			description: "System logging",
@@ -96,6 +157,36 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 			name:        "network",
 			description: "Network configuration",
 			isWritable:  true,
+			extraCommands: func() []*cobra.Command {
+				// Local proxy log.
+				proxyLogCmd := &cobra.Command{}
+				proxyLogCmd.Use = cli.Usage("proxy-log")
+				proxyLogCmd.Short = "Local proxy log and per-destination failures"
+				proxyLogCmd.Long = cli.FormatSection("Description", "Local proxy log and per-destination failures")
+
+				proxyLogShowCmd := cmdGenericShow{os: c.os, endpoint: "system/network/proxy-log"}
+				proxyLogCmd.AddCommand(proxyLogShowCmd.command())
+
+				// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+				proxyLogCmd.Args = cobra.NoArgs
+				proxyLogCmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+				// Network reachability test.
+				testCmd := cmdGenericRun{
+					os:          c.os,
+					name:        "test",
+					description: "Test DNS, gateway, NTP and provider reachability",
+					endpoint:    "system/network/test",
+					hasOutput:   true,
+				}
+
+				return []*cobra.Command{proxyLogCmd, testCmd.command()}
+			},
+		},
+		{
+			name:        "notifications",
+			description: "Event notifications",
+			isWritable:  true,
 		},
 		{
 			name:        "provider",
@@ -118,9 +209,141 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 					action:      "tpm-rebind",
 					description: "Rebind the TPM (after using recovery key)",
 					endpoint:    "system/security",
+					extraArgs: []cmdGenericRunArgs{
+						{
+							longFlag:    "dry-run",
+							description: "Only report what would be done, set to true",
+						},
+					},
+					hasOutput: true,
+				}
+
+				// Boot order repair.
+				repairBootOrderCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "repair-boot-order",
+					description: "Restore the IncusOS entry at the front of the EFI boot order",
+					endpoint:    "system/security",
+				}
+
+				// Debug access grants.
+				grantDebugCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "grant-debug",
+					description: "Grant time-limited access to the debug endpoints",
+					endpoint:    "system/security",
+					extraArgs: []cmdGenericRunArgs{
+						{
+							shortFlag:   "t",
+							longFlag:    "duration",
+							description: "How long to grant debug access for, such as 4h",
+						},
+					},
+				}
+
+				revokeDebugCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "revoke-debug",
+					description: "Revoke any time-limited access to the debug endpoints",
+					endpoint:    "system/security",
+				}
+
+				// Recovery keys.
+				addFIDO2TokenCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "add-fido2-token",
+					description: "Enroll a FIDO2 security key to unlock the encrypted volumes",
+					endpoint:    "system/security",
+					hasData:     true,
+				}
+
+				removeFIDO2TokenCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "remove-fido2-token",
+					description: "Remove an enrolled FIDO2 security key",
+					endpoint:    "system/security",
+					hasData:     true,
+					confirm:     "remove the FIDO2 security key",
+				}
+
+				addRecoveryKeyCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "add-recovery-key",
+					description: "Add an encryption recovery key, generating one if not provided",
+					endpoint:    "system/security",
+					hasData:     true,
+				}
+
+				rotateRecoveryKeyCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "rotate-recovery-key",
+					description: "Replace an encryption recovery key, generating the new one if not provided",
+					endpoint:    "system/security",
+					hasData:     true,
+				}
+
+				removeRecoveryKeyCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "remove-recovery-key",
+					description: "Remove an encryption recovery key",
+					endpoint:    "system/security",
+					hasData:     true,
+					confirm:     "remove the recovery key",
+				}
+
+				// Measured boot report export.
+				exportBootReportCmd := cmdGenericRun{
+					os:            c.os,
+					action:        "export-boot-report",
+					description:   "Export a signed measured boot report",
+					endpoint:      "system/security",
+					hasFileOutput: true,
 				}
 
-				return []*cobra.Command{tpmRebindCmd.command()}
+				// PCR values preview.
+				pcrPreviewCmd := &cobra.Command{}
+				pcrPreviewCmd.Use = cli.Usage("pcr-preview")
+				pcrPreviewCmd.Short = "Preview the PCR values on next boot"
+				pcrPreviewCmd.Long = cli.FormatSection("Description", "Preview the PCR values on next boot")
+
+				pcrPreviewShowCmd := cmdGenericShow{os: c.os, endpoint: "system/security/pcr-preview"}
+				pcrPreviewCmd.AddCommand(pcrPreviewShowCmd.command())
+
+				// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+				pcrPreviewCmd.Args = cobra.NoArgs
+				pcrPreviewCmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+				// Secure Boot key change preview.
+				keyChangePreviewCmd := &cobra.Command{}
+				keyChangePreviewCmd.Use = cli.Usage("key-change-preview")
+				keyChangePreviewCmd.Short = "Preview the changes of a Secure Boot key change"
+				keyChangePreviewCmd.Long = cli.FormatSection("Description", "Preview the changes made if the staged update is signed with a different Secure Boot key")
+
+				keyChangePreviewShowCmd := cmdGenericShow{os: c.os, endpoint: "system/security/key-change-preview"}
+				keyChangePreviewCmd.AddCommand(keyChangePreviewShowCmd.command())
+
+				// Workaround for subcommand usage errors. See: https://github.com/spf13/cobra/issues/706.
+				keyChangePreviewCmd.Args = cobra.NoArgs
+				keyChangePreviewCmd.Run = func(cmd *cobra.Command, _ []string) { _ = cmd.Usage() }
+
+				return []*cobra.Command{addFIDO2TokenCmd.command(), addRecoveryKeyCmd.command(), exportBootReportCmd.command(), grantDebugCmd.command(), keyChangePreviewCmd, pcrPreviewCmd, removeFIDO2TokenCmd.command(), removeRecoveryKeyCmd.command(), repairBootOrderCmd.command(), revokeDebugCmd.command(), rotateRecoveryKeyCmd.command(), tpmRebindCmd.command()}
+			},
+		},
+		{
+			name:        "self-check",
+			description: "Nightly self-check report",
+			isWritable:  false,
+			extraCommands: func() []*cobra.Command {
+				// Run the self-check.
+				runSelfCheckCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "run",
+					name:        "run",
+					description: "Run the self-check",
+					endpoint:    "system/self-check",
+				}
+
+				return []*cobra.Command{runSelfCheckCmd.command()}
 			},
 		},
 		{
@@ -188,6 +411,26 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 			description: "Update configuration",
 			isWritable:  true,
 			extraCommands: func() []*cobra.Command {
+				// Apply staged updates.
+				applyUpdatesCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "apply",
+					name:        "apply",
+					description: "Apply staged updates",
+					endpoint:    "system/update",
+					confirm:     "apply the staged updates",
+				}
+
+				// Cancel pending updates.
+				cancelUpdatesCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "cancel",
+					name:        "cancel",
+					description: "Cancel in-progress and staged updates",
+					endpoint:    "system/update",
+					confirm:     "discard the staged updates",
+				}
+
 				// Check updates.
 				checkUpdatesCmd := cmdGenericRun{
 					os:          c.os,
@@ -197,9 +440,33 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 					endpoint:    "system/update",
 				}
 
-				return []*cobra.Command{checkUpdatesCmd.command()}
+				// Download updates.
+				downloadUpdatesCmd := cmdGenericRun{
+					os:          c.os,
+					action:      "download",
+					name:        "download",
+					description: "Download updates without applying them",
+					endpoint:    "system/update",
+				}
+
+				// Import an update bundle.
+				importUpdatesCmd := cmdGenericRun{
+					os:           c.os,
+					action:       "import",
+					name:         "import",
+					description:  "Import an offline update bundle",
+					endpoint:     "system/update",
+					hasFileInput: true,
+				}
+
+				return []*cobra.Command{applyUpdatesCmd.command(), cancelUpdatesCmd.command(), checkUpdatesCmd.command(), downloadUpdatesCmd.command(), importUpdatesCmd.command()}
 			},
 		},
+		{
+			name:        "warnings",
+			description: "System warnings",
+			isWritable:  false,
+		},
 	}
 
 	for _, sub := range subCommands {
@@ -234,3 +501,120 @@ func (c *cmdAdminOSSystem) command() *cobra.Command {
 
 	return cmd
 }
+
+// History.
+type cmdAdminOSSystemHistory struct {
+	os *cmdAdminOS
+
+	flagEndpoint string
+	flagSince    string
+	flagUntil    string
+	flagEntries  string
+}
+
+func (c *cmdAdminOSSystemHistory) command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = cli.Usage("history")
+	cmd.Short = "Get configuration change history"
+
+	cmd.Long = cli.FormatSection("Description", "Get the history of changes made to the system configuration")
+	if c.os.args.SupportsTarget {
+		cmd.Flags().StringVar(&c.os.flagTarget, "target", "", "Cluster member name``")
+	}
+
+	cmd.Flags().StringVarP(&c.flagEndpoint, "endpoint", "e", "", "Endpoint prefix``")
+	cmd.Flags().StringVarP(&c.flagSince, "since", "s", "", "Only show entries since the provided time (RFC3339)``")
+	cmd.Flags().StringVarP(&c.flagUntil, "until", "u", "", "Only show entries until the provided time (RFC3339)``")
+	cmd.Flags().StringVarP(&c.flagEntries, "entries", "n", "", "Number of entries``")
+
+	cmd.RunE = c.run
+
+	return cmd
+}
+
+func (c *cmdAdminOSSystemHistory) run(cmd *cobra.Command, args []string) error {
+	// Quick checks.
+	exit, err := cli.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote.
+	remote := ""
+	if len(args) > 0 {
+		remote, _ = parseRemote(args[0])
+	}
+
+	// Prepare the URL.
+	u, err := url.Parse("/os/1.0/system/history")
+	if err != nil {
+		return err
+	}
+
+	values := u.Query()
+	if c.os.flagTarget != "" {
+		values.Set("target", c.os.flagTarget)
+	}
+
+	if c.flagEndpoint != "" {
+		values.Set("endpoint", c.flagEndpoint)
+	}
+
+	if c.flagSince != "" {
+		values.Set("since", c.flagSince)
+	}
+
+	if c.flagUntil != "" {
+		values.Set("until", c.flagUntil)
+	}
+
+	if c.flagEntries != "" {
+		values.Set("entries", c.flagEntries)
+	}
+
+	u.RawQuery = values.Encode()
+
+	// Get the history.
+	resp, _, err := doQuery(c.os.args.DoHTTP, remote, "GET", u.String(), nil, nil, "")
+	if err != nil {
+		return err
+	}
+
+	var data []struct {
+		Time     time.Time `json:"time"`
+		Source   string    `json:"source"`
+		Identity string    `json:"identity"`
+		Method   string    `json:"method"`
+		Endpoint string    `json:"endpoint"`
+		Changes  []struct {
+			Path string          `json:"path"`
+			Old  json.RawMessage `json:"old"`
+			New  json.RawMessage `json:"new"`
+		} `json:"changes"`
+	}
+
+	err = resp.MetadataAsStruct(&data)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range data {
+		_, _ = fmt.Printf("[%s] %s %s by %s %s\n", entry.Time.Local().Format(dateLayoutSecond), entry.Method, entry.Endpoint, entry.Source, entry.Identity) //nolint:forbidigo
+
+		for _, change := range entry.Changes {
+			oldValue := "(unset)"
+			if len(change.Old) > 0 {
+				oldValue = string(change.Old)
+			}
+
+			newValue := "(unset)"
+			if len(change.New) > 0 {
+				newValue = string(change.New)
+			}
+
+			_, _ = fmt.Printf("  %s: %s -> %s\n", change.Path, oldValue, newValue) //nolint:forbidigo
+		}
+	}
+
+	return nil
+}
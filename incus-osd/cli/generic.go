@@ -252,6 +252,7 @@ type cmdGenericRun struct {
 	defaultData   string
 	hasFileInput  bool
 	hasFileOutput bool
+	hasOutput     bool
 	extraArgs     []cmdGenericRunArgs
 
 	flagData string
@@ -425,11 +426,33 @@ func (c *cmdGenericRun) run(cmd *cobra.Command, args []string) error {
 	}
 
 	// Run the command.
-	_, _, err = doQuery(c.os.args.DoHTTP, remote, "POST", apiURL.String(), inData, outData, "")
+	resp, _, err := doQuery(c.os.args.DoHTTP, remote, "POST", apiURL.String(), inData, outData, "")
 	if err != nil {
 		return err
 	}
 
+	// Show the returned data, if any.
+	if c.hasOutput && resp != nil {
+		var rawData any
+
+		err = resp.MetadataAsStruct(&rawData)
+		if err != nil {
+			return err
+		}
+
+		metadata, ok := rawData.(map[string]any)
+		if rawData == nil || (ok && len(metadata) == 0) {
+			return nil
+		}
+
+		data, err := yaml.Marshal(rawData)
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Printf("%s", data) //nolint:forbidigo
+	}
+
 	return nil
 }
 
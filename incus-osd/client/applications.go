@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetApplicationNames returns the names of the installed applications.
+func (c *Client) GetApplicationNames(ctx context.Context) ([]string, error) {
+	return c.queryNames(ctx, "/1.0/applications")
+}
+
+// GetApplications returns the installed applications.
+func (c *Client) GetApplications(ctx context.Context) ([]api.ApplicationListEntry, error) {
+	ret := []api.ApplicationListEntry{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/applications?recursion=1", nil, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetApplication returns an installed application.
+func (c *Client) GetApplication(ctx context.Context, name string) (*api.Application, error) {
+	ret := &api.Application{}
+
+	err := c.query(ctx, http.MethodGet, applicationPath(name), nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// InstallApplication adds an application, which gets installed during the update check triggered right away.
+func (c *Client) InstallApplication(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, "/1.0/applications", map[string]string{"name": name}, nil)
+}
+
+// StartApplication starts an installed application.
+func (c *Client) StartApplication(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, applicationPath(name)+"/:start", nil, nil)
+}
+
+// StopApplication stops an installed application.
+func (c *Client) StopApplication(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, applicationPath(name)+"/:stop", nil, nil)
+}
+
+// RestartApplication restarts an installed application.
+func (c *Client) RestartApplication(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, applicationPath(name)+"/:restart", nil, nil)
+}
+
+// UpdateApplication applies the staged update of an application, or triggers an update check if none is staged.
+func (c *Client) UpdateApplication(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, applicationPath(name)+"/:update", nil, nil)
+}
+
+// RemoveApplication stops and removes an installed application, along with its local data.
+func (c *Client) RemoveApplication(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, applicationPath(name)+"/:remove", nil, nil)
+}
+
+// applicationPath returns the API path of an application.
+func applicationPath(name string) string {
+	return "/1.0/applications/" + url.PathEscape(name)
+}
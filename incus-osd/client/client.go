@@ -0,0 +1,150 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+// DefaultSocketPath is the path of the incus-osd Unix socket.
+const DefaultSocketPath = "/run/incus-os/unix.socket"
+
+// Client is a client for the incus-osd REST API.
+type Client struct {
+	http    *http.Client
+	baseURL string
+}
+
+// ConnectUnix returns a client talking to incus-osd through its Unix socket, using DefaultSocketPath if path is empty.
+func ConnectUnix(path string) (*Client, error) {
+	if path == "" {
+		path = DefaultSocketPath
+	}
+
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			d := net.Dialer{}
+
+			return d.DialContext(ctx, "unix", path)
+		},
+	}
+
+	return NewClient(&http.Client{Transport: transport}, "http://incus-os"), nil
+}
+
+// ConnectHTTPS returns a client talking to the remote API of incus-osd at the provided address, such as
+// "192.0.2.10:9443". The TLS configuration must provide one of the trusted client certificates.
+func ConnectHTTPS(address string, tlsConfig *tls.Config) (*Client, error) {
+	if tlsConfig == nil || len(tlsConfig.Certificates) == 0 {
+		return nil, fmt.Errorf("a client certificate is required to connect to %q", address)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           http.ProxyFromEnvironment,
+	}
+
+	return NewClient(&http.Client{Transport: transport}, "https://"+address), nil
+}
+
+// NewClient returns a client sending its requests through the provided HTTP client to the given base URL. This can
+// be used to reach incus-osd through a proxy, such as the "/os" prefix of an application's API.
+func NewClient(httpClient *http.Client, baseURL string) *Client {
+	return &Client{
+		http:    httpClient,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// query sends a request to the API, encoding the provided data as JSON, and decodes the response's metadata into out
+// if not nil.
+func (c *Client) query(ctx context.Context, method string, endpoint string, data any, out any) error {
+	var body io.Reader
+
+	if data != nil {
+		buf := &bytes.Buffer{}
+
+		err := json.NewEncoder(buf).Encode(data)
+		if err != nil {
+			return err
+		}
+
+		body = buf
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	response := api.Response{}
+
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return api.StatusErrorf(resp.StatusCode, "%s %s: %s", method, endpoint, resp.Status)
+		}
+
+		return err
+	}
+
+	if response.Type == api.ErrorResponse {
+		return api.StatusErrorf(resp.StatusCode, "%s", response.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return response.MetadataAsStruct(out)
+}
+
+// queryNames returns the names of the entities listed by an endpoint returning their URLs.
+func (c *Client) queryNames(ctx context.Context, endpoint string) ([]string, error) {
+	urls := []string{}
+
+	err := c.query(ctx, http.MethodGet, endpoint, nil, &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(urls))
+
+	for _, entityURL := range urls {
+		u, err := url.Parse(entityURL)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, path.Base(u.Path))
+	}
+
+	return names, nil
+}
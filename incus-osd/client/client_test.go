@@ -0,0 +1,90 @@
+package client_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/client"
+)
+
+func newTestServer(t *testing.T) *client.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/1.0/applications", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"type":        api.SyncResponse,
+				"status_code": http.StatusOK,
+				"metadata":    []string{"/1.0/applications/incus", "/1.0/applications/operations-center"},
+			})
+		case http.MethodPost:
+			app := map[string]string{}
+
+			_ = json.NewDecoder(r.Body).Decode(&app)
+			if app["name"] != "incus" {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"type":       api.ErrorResponse,
+					"error_code": http.StatusBadRequest,
+					"error":      "invalid application",
+				})
+
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"type":        api.SyncResponse,
+				"status_code": http.StatusOK,
+				"metadata":    map[string]any{},
+			})
+		}
+	})
+
+	mux.HandleFunc("/1.0/applications/incus", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"type":        api.SyncResponse,
+			"status_code": http.StatusOK,
+			"metadata":    map[string]any{"state": map[string]any{"version": "202511041601", "running": true}},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return client.NewClient(server.Client(), server.URL+"/")
+}
+
+func TestApplications(t *testing.T) {
+	t.Parallel()
+
+	c := newTestServer(t)
+
+	names, err := c.GetApplicationNames(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []string{"incus", "operations-center"}, names)
+
+	app, err := c.GetApplication(t.Context(), "incus")
+	require.NoError(t, err)
+	require.Equal(t, "202511041601", app.State.Version)
+	require.True(t, app.State.Running)
+
+	err = c.InstallApplication(t.Context(), "incus")
+	require.NoError(t, err)
+
+	// Errors carry the status code returned by the server.
+	err = c.InstallApplication(t.Context(), "foo")
+	require.EqualError(t, err, "invalid application")
+	require.True(t, api.StatusErrorCheck(err, http.StatusBadRequest))
+
+	// Unknown endpoints are reported as such.
+	_, err = c.GetApplication(t.Context(), "foo")
+	require.True(t, api.StatusErrorCheck(err, http.StatusNotFound))
+}
@@ -0,0 +1,3 @@
+// Package client provides a Go client for the incus-osd REST API, reachable either through its local Unix socket or
+// through the remote API.
+package client
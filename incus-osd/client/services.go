@@ -0,0 +1,27 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetServiceNames returns the names of the supported services.
+func (c *Client) GetServiceNames(ctx context.Context) ([]string, error) {
+	return c.queryNames(ctx, "/1.0/services")
+}
+
+// GetService decodes the configuration and state of a service into the provided struct, such as *api.ServiceISCSI.
+func (c *Client) GetService(ctx context.Context, name string, service any) error {
+	return c.query(ctx, http.MethodGet, "/1.0/services/"+url.PathEscape(name), nil, service)
+}
+
+// UpdateService applies a new configuration to a service.
+func (c *Client) UpdateService(ctx context.Context, name string, service any) error {
+	return c.query(ctx, http.MethodPut, "/1.0/services/"+url.PathEscape(name), service, nil)
+}
+
+// ResetService resets a service to its default configuration.
+func (c *Client) ResetService(ctx context.Context, name string) error {
+	return c.query(ctx, http.MethodPost, "/1.0/services/"+url.PathEscape(name)+"/:reset", nil, nil)
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetSystemDNS returns the dynamic DNS configuration and state.
+func (c *Client) GetSystemDNS(ctx context.Context) (*api.SystemDNS, error) {
+	ret := &api.SystemDNS{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/dns", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemDNS applies a new dynamic DNS configuration.
+func (c *Client) UpdateSystemDNS(ctx context.Context, dns api.SystemDNS) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/dns", dns, nil)
+}
+
+// GetSystemLogging returns the logging configuration.
+func (c *Client) GetSystemLogging(ctx context.Context) (*api.SystemLogging, error) {
+	ret := &api.SystemLogging{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/logging", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemLogging applies a new logging configuration.
+func (c *Client) UpdateSystemLogging(ctx context.Context, logging api.SystemLogging) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/logging", logging, nil)
+}
+
+// GetSystemNetwork returns the network configuration and state.
+func (c *Client) GetSystemNetwork(ctx context.Context) (*api.SystemNetwork, error) {
+	ret := &api.SystemNetwork{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/network", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemNetwork applies a new network configuration.
+func (c *Client) UpdateSystemNetwork(ctx context.Context, network api.SystemNetwork) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/network", network, nil)
+}
+
+// TestSystemNetwork runs the network reachability self-test and returns its report.
+func (c *Client) TestSystemNetwork(ctx context.Context) (*api.SystemNetworkTest, error) {
+	ret := &api.SystemNetworkTest{}
+
+	err := c.query(ctx, http.MethodPost, "/1.0/system/network/test", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// GetSystemNotifications returns the notifications configuration.
+func (c *Client) GetSystemNotifications(ctx context.Context) (*api.SystemNotifications, error) {
+	ret := &api.SystemNotifications{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/notifications", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemNotifications applies a new notifications configuration.
+func (c *Client) UpdateSystemNotifications(ctx context.Context, notifications api.SystemNotifications) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/notifications", notifications, nil)
+}
+
+// GetSystemProvider returns the update provider configuration and state.
+func (c *Client) GetSystemProvider(ctx context.Context) (*api.SystemProvider, error) {
+	ret := &api.SystemProvider{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/provider", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemProvider applies a new update provider configuration.
+func (c *Client) UpdateSystemProvider(ctx context.Context, provider api.SystemProvider) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/provider", provider, nil)
+}
+
+// GetSystemSecurity returns the security configuration and state.
+func (c *Client) GetSystemSecurity(ctx context.Context) (*api.SystemSecurity, error) {
+	ret := &api.SystemSecurity{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/security", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemSecurity applies a new security configuration.
+func (c *Client) UpdateSystemSecurity(ctx context.Context, security api.SystemSecurity) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/security", security, nil)
+}
+
+// GetSystemStorage returns the storage configuration and state.
+func (c *Client) GetSystemStorage(ctx context.Context) (*api.SystemStorage, error) {
+	ret := &api.SystemStorage{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/storage", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemStorage applies a new storage configuration.
+func (c *Client) UpdateSystemStorage(ctx context.Context, storage api.SystemStorage) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/storage", storage, nil)
+}
+
+// GetSystemWarnings returns the warnings currently raised by the system.
+func (c *Client) GetSystemWarnings(ctx context.Context) ([]api.SystemWarning, error) {
+	ret := []api.SystemWarning{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/warnings", nil, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// ApplySystemProfile applies a node profile in a single transaction.
+func (c *Client) ApplySystemProfile(ctx context.Context, profile api.SystemProfile) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/:apply-profile", profile, nil)
+}
+
+// Reboot reboots the system.
+func (c *Client) Reboot(ctx context.Context) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/:reboot", nil, nil)
+}
+
+// PowerOff shuts the system down.
+func (c *Client) PowerOff(ctx context.Context) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/:poweroff", nil, nil)
+}
+
+// GetHealth returns the detailed health of the system.
+func (c *Client) GetHealth(ctx context.Context) (*api.HealthReport, error) {
+	ret := &api.HealthReport{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/health", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
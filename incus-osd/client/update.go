@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetSystemUpdate returns the update configuration and state.
+func (c *Client) GetSystemUpdate(ctx context.Context) (*api.SystemUpdate, error) {
+	ret := &api.SystemUpdate{}
+
+	err := c.query(ctx, http.MethodGet, "/1.0/system/update", nil, ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// UpdateSystemUpdate applies a new update configuration.
+func (c *Client) UpdateSystemUpdate(ctx context.Context, update api.SystemUpdate) error {
+	return c.query(ctx, http.MethodPut, "/1.0/system/update", update, nil)
+}
+
+// CheckUpdates triggers an immediate update check, applying updates according to the update configuration.
+func (c *Client) CheckUpdates(ctx context.Context) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/update/:check", nil, nil)
+}
+
+// DownloadUpdates triggers an immediate update check, only staging the updates found.
+func (c *Client) DownloadUpdates(ctx context.Context) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/update/:download", nil, nil)
+}
+
+// ApplyUpdates applies the staged updates.
+func (c *Client) ApplyUpdates(ctx context.Context) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/update/:apply", nil, nil)
+}
+
+// CancelUpdates cancels any update check or download in progress and discards the staged updates.
+func (c *Client) CancelUpdates(ctx context.Context) error {
+	return c.query(ctx, http.MethodPost, "/1.0/system/update/:cancel", nil, nil)
+}
@@ -54,12 +54,17 @@ type apiImagesPost struct {
 
 type apiImagesPostSeeds struct {
 	Applications     *apiseed.Applications     `json:"applications"      yaml:"applications"`
+	Ceph             *apiseed.Ceph             `json:"ceph"              yaml:"ceph"`
 	Incus            *apiseed.Incus            `json:"incus"             yaml:"incus"`
 	Install          *apiseed.Install          `json:"install"           yaml:"install"`
 	MigrationManager *apiseed.MigrationManager `json:"migration-manager" yaml:"migration-manager"` //nolint:tagliatelle
 	OperationsCenter *apiseed.OperationsCenter `json:"operations-center" yaml:"operations-center"` //nolint:tagliatelle
 	Network          *apiseed.Network          `json:"network"           yaml:"network"`
+	Nftables         *apiseed.Nftables         `json:"nftables"          yaml:"nftables"`
+	NTP              *apiseed.NTP              `json:"ntp"               yaml:"ntp"`
 	Provider         *apiseed.Provider         `json:"provider"          yaml:"provider"`
+	Tuning           *apiseed.Tuning           `json:"tuning"            yaml:"tuning"`
+	VRRP             *apiseed.VRRP             `json:"vrrp"              yaml:"vrrp"`
 }
 
 func main() {
@@ -433,6 +438,16 @@ func writeSeed(writer io.Writer, seeds apiImagesPostSeeds) (int, error) {
 		archiveContents = append(archiveContents, []string{"applications.yaml", string(yamlContents)})
 	}
 
+	// Create Ceph yaml contents.
+	if seeds.Ceph != nil {
+		yamlContents, err := yaml.Marshal(seeds.Ceph)
+		if err != nil {
+			return -1, err
+		}
+
+		archiveContents = append(archiveContents, []string{"ceph.yaml", string(yamlContents)})
+	}
+
 	// Create incus yaml contents.
 	if seeds.Incus != nil {
 		yamlContents, err := yaml.Marshal(seeds.Incus)
@@ -483,6 +498,26 @@ func writeSeed(writer io.Writer, seeds apiImagesPostSeeds) (int, error) {
 		archiveContents = append(archiveContents, []string{"network.yaml", string(yamlContents)})
 	}
 
+	// Create nftables yaml contents.
+	if seeds.Nftables != nil {
+		yamlContents, err := yaml.Marshal(seeds.Nftables)
+		if err != nil {
+			return -1, err
+		}
+
+		archiveContents = append(archiveContents, []string{"nftables.yaml", string(yamlContents)})
+	}
+
+	// Create NTP yaml contents.
+	if seeds.NTP != nil {
+		yamlContents, err := yaml.Marshal(seeds.NTP)
+		if err != nil {
+			return -1, err
+		}
+
+		archiveContents = append(archiveContents, []string{"ntp.yaml", string(yamlContents)})
+	}
+
 	// Create provider yaml contents.
 	if seeds.Provider != nil {
 		yamlContents, err := yaml.Marshal(seeds.Provider)
@@ -493,6 +528,26 @@ func writeSeed(writer io.Writer, seeds apiImagesPostSeeds) (int, error) {
 		archiveContents = append(archiveContents, []string{"provider.yaml", string(yamlContents)})
 	}
 
+	// Create tuning yaml contents.
+	if seeds.Tuning != nil {
+		yamlContents, err := yaml.Marshal(seeds.Tuning)
+		if err != nil {
+			return -1, err
+		}
+
+		archiveContents = append(archiveContents, []string{"tuning.yaml", string(yamlContents)})
+	}
+
+	// Create VRRP yaml contents.
+	if seeds.VRRP != nil {
+		yamlContents, err := yaml.Marshal(seeds.VRRP)
+		if err != nil {
+			return -1, err
+		}
+
+		archiveContents = append(archiveContents, []string{"vrrp.yaml", string(yamlContents)})
+	}
+
 	// Put a size counter in place.
 	wc := &writeCounter{}
 
@@ -0,0 +1,55 @@
+// Package main is used for the incus-os command line tool, which manages the local system through the incus-osd socket.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/cli"
+	"github.com/lxc/incus-os/incus-osd/client"
+)
+
+func main() {
+	socketPath := os.Getenv("INCUS_OS_SOCKET")
+	if socketPath == "" {
+		socketPath = client.DefaultSocketPath
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				d := net.Dialer{}
+
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	cmd := cli.NewCommand(&cli.Args{
+		DefaultListFormat: "table",
+		DoHTTP: func(_ string, req *http.Request) (*http.Response, error) {
+			// The commands address the API through the "/os" prefix of the applications, which
+			// isn't used on the local socket.
+			req.URL.Scheme = "http"
+			req.URL.Host = "incus-os"
+			req.URL.Path = strings.TrimPrefix(req.URL.Path, "/os")
+
+			return httpClient.Do(req)
+		},
+	})
+
+	cmd.Use = "incus-os"
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	err := cmd.Execute()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+
+		os.Exit(1)
+	}
+}
@@ -3,10 +3,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -14,17 +18,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lxc/incus/v6/shared/revert"
 	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/lxc/incus/v6/shared/units"
 	"golang.org/x/sys/unix"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/dns"
 	"github.com/lxc/incus-os/incus-osd/internal/install"
+	"github.com/lxc/incus-os/incus-osd/internal/kdump"
 	"github.com/lxc/incus-os/incus-osd/internal/keyring"
+	"github.com/lxc/incus-os/incus-osd/internal/mdns"
+	"github.com/lxc/incus-os/incus-osd/internal/notify"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/proxy"
 	"github.com/lxc/incus-os/incus-osd/internal/recovery"
 	"github.com/lxc/incus-os/incus-osd/internal/rest"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/selfcheck"
 	"github.com/lxc/incus-os/incus-osd/internal/services"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
@@ -36,6 +50,9 @@ import (
 var (
 	varPath = "/var/lib/incus-os/"
 	runPath = "/run/incus-os/"
+
+	// statusPath is a world-readable location where a summary of the system status is exported for applications.
+	statusPath = "/run/incus-os-status.json"
 )
 
 var updateModal *tui.Modal
@@ -128,7 +145,7 @@ func main() {
 
 func run(ctx context.Context, s *state.State, t *tui.TUI) error {
 	// Verify that the system meets minimum requirements for running IncusOS.
-	err := install.CheckSystemRequirements(ctx)
+	err := install.CheckSystemRequirements(ctx, s)
 	if err != nil {
 		modal := t.AddModal(s.OS.Name)
 		modal.Update("System check error: [red]" + err.Error() + "[white]\n" + s.OS.Name + " is unable to run until the problem is resolved.")
@@ -212,6 +229,12 @@ func run(ctx context.Context, s *state.State, t *tui.TUI) error {
 		return err
 	}
 
+	// Start the health probe and expose the API on the management address, now that the network is up.
+	server.UpdateManagementListeners(ctx)
+
+	// Re-apply the seed data from newly attached seed volumes.
+	go seedMonitor(ctx, s, server)
+
 	// Done with all initialization.
 	slog.InfoContext(ctx, "System is ready", "release", s.OS.RunningRelease)
 	s.OS.SuccessfulBoot = true
@@ -237,6 +260,9 @@ func shutdown(ctx context.Context, s *state.State, t *tui.TUI) error {
 			return err
 		}
 
+		// Let the application prepare for the shutdown, failures are only logged.
+		_ = applications.RunHook(ctx, s, appName, applications.HookPreShutdown, s.OS.RunningRelease)
+
 		// Stop the application.
 		slog.InfoContext(ctx, "Stopping application", "name", appName, "version", appInfo.State.Version)
 
@@ -265,7 +291,11 @@ func shutdown(ctx context.Context, s *state.State, t *tui.TUI) error {
 		err = srv.Stop(ctx)
 		if err != nil {
 			slog.ErrorContext(ctx, "Failed stopping service", "name", srvName, "err", err)
+
+			continue
 		}
+
+		s.Events.SendLifecycle(api.EventLifecycleServiceStopped, "/1.0/services/"+srvName, nil)
 	}
 
 	return nil
@@ -302,6 +332,18 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		slog.DebugContext(ctx, "Platform keyring entry", "name", key.Description, "key", key.Fingerprint)
 	}
 
+	// When running as the capture kernel following a kernel panic, save the crash dump and reboot.
+	captured, err := kdump.Capture(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to save the kernel crash dump", "err", err)
+	}
+
+	if captured {
+		slog.InfoContext(ctx, "Kernel crash dump captured, rebooting")
+
+		return systemd.SystemReboot(ctx)
+	}
+
 	// If no encryption recovery keys have been defined for the root and swap partitions, generate one before going any further.
 	if len(s.System.Security.Config.EncryptionRecoveryKeys) == 0 {
 		slog.InfoContext(ctx, "Auto-generating encryption recovery key, this may take a few seconds")
@@ -312,6 +354,12 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		}
 	}
 
+	// Unseal the secrets vault, sealing any credential still stored in plaintext.
+	err = secrets.Load(ctx, s)
+	if err != nil {
+		return err
+	}
+
 	// Get the machine ID.
 	machineID, err := os.ReadFile("/etc/machine-id")
 	if err != nil {
@@ -325,6 +373,14 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		slog.WarnContext(ctx, "Booted from backup "+s.OS.Name+" image version "+s.OS.RunningRelease)
 	}
 
+	// Record the geometry of the drive IncusOS is installed on, for later diagnostics.
+	if s.InstallGeometry == "" {
+		err = recordInstallGeometry(s)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to record the system drive geometry", "err", err)
+		}
+	}
+
 	// Check for and run recovery logic if present.
 	err = recovery.CheckRunRecovery(ctx, s)
 	if err != nil {
@@ -348,10 +404,16 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		return err
 	}
 
+	// Have outgoing requests follow proxy configuration changes without needing a restart.
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if ok {
+		defaultTransport.Proxy = proxy.HTTPProxy
+	}
+
 	// Perform network configuration.
 	slog.InfoContext(ctx, "Bringing up the network")
 
-	err = systemd.ApplyNetworkConfiguration(ctx, s, s.System.Network.Config, 30*time.Second, s.OS.SuccessfulBoot, providers.Refresh)
+	err = systemd.ApplyNetworkConfiguration(ctx, s, s.System.Network.Config, 30*time.Second, s.OS.SuccessfulBoot, services.RefreshNetwork)
 	if err != nil {
 		return err
 	}
@@ -362,6 +424,51 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		return err
 	}
 
+	// If there's no network unlock configuration or update trust anchors in the state, attempt to fetch from the seed info.
+	if s.System.Security.Config.NetworkUnlock == nil || len(s.UpdateTrustAnchors.Certificates) == 0 {
+		securitySeed, err := seed.GetSecurity(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if securitySeed != nil {
+			if s.System.Security.Config.NetworkUnlock == nil {
+				s.System.Security.Config.NetworkUnlock = securitySeed.NetworkUnlock
+			}
+
+			if len(s.UpdateTrustAnchors.Certificates) == 0 && len(securitySeed.UpdateTrustAnchors) > 0 {
+				err = providers.ValidateUpdateTrustAnchors(securitySeed.UpdateTrustAnchors)
+				if err != nil {
+					return err
+				}
+
+				s.UpdateTrustAnchors.Certificates = securitySeed.UpdateTrustAnchors
+				s.UpdateTrustAnchors.ReplaceBuiltin = securitySeed.ReplaceUpdateTrustAnchors
+			}
+		}
+	}
+
+	// Bind the encrypted volumes to any configured Tang servers, now that the network is up.
+	err = systemd.ApplyNetworkUnlock(ctx, s)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply network unlock configuration", "err", err)
+	}
+
+	// Check whether the TPM can still unlock the encrypted volumes, rebinding it after a firmware update if allowed.
+	err = checkTPMBindings(ctx, s)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check the TPM bindings", "err", err)
+	}
+
+	// Let event subscribers know about encrypted volumes requiring attention.
+	if !s.System.Security.State.EncryptionRecoveryKeysRetrieved {
+		sendEncryptionWarning(s, "The encryption recovery keys haven't been retrieved yet")
+	}
+
+	if s.PassphraseOnly {
+		sendEncryptionWarning(s, "No TPM is available, the encrypted volumes are only protected by a passphrase")
+	}
+
 	// Get the provider.
 	var provider string
 
@@ -389,6 +496,11 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 			s.System.Provider.Config.Name = provider
 			s.System.Provider.Config.Config = providerConfig
 		}
+
+		err = secrets.SealProviderConfig(&s.System.Provider.Config, s.System.Provider.Config)
+		if err != nil {
+			return err
+		}
 	}
 
 	p, err := providers.Load(ctx, s)
@@ -397,7 +509,85 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 	}
 
 	// Perform an initial blocking check for updates before proceeding.
-	updateChecker(ctx, s, t, p, true, false)
+	updateChecker(ctx, s, t, p, true, false, false)
+
+	// On first boot, attempt to fetch the NTP configuration from the seed info.
+	if !s.OS.SuccessfulBoot && !s.Services.NTP.Config.Enabled {
+		ntpSeed, err := seed.GetNTP(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if ntpSeed != nil {
+			s.Services.NTP.Config = ntpSeed.ServiceNTPConfig
+		}
+	}
+
+	// On first boot, attempt to fetch the firewall configuration from the seed info.
+	if !s.OS.SuccessfulBoot && !s.Services.Nftables.Config.Enabled {
+		nftablesSeed, err := seed.GetNftables(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if nftablesSeed != nil {
+			s.Services.Nftables.Config = nftablesSeed.ServiceNftablesConfig
+		}
+	}
+
+	// On first boot, attempt to fetch the Ceph client configuration from the seed info. The service itself
+	// only gets started once the incus-ceph application is installed.
+	if !s.OS.SuccessfulBoot && !s.Services.Ceph.Config.Enabled {
+		cephSeed, err := seed.GetCeph(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if cephSeed != nil {
+			s.Services.Ceph.Config = cephSeed.ServiceCephConfig
+
+			err = secrets.SealCephConfig(&s.Services.Ceph.Config, s.Services.Ceph.Config)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// On first boot, attempt to fetch the kernel tuning configuration from the seed info.
+	if !s.OS.SuccessfulBoot && !s.Services.Tuning.Config.Enabled {
+		tuningSeed, err := seed.GetTuning(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if tuningSeed != nil {
+			s.Services.Tuning.Config = tuningSeed.ServiceTuningConfig
+		}
+	}
+
+	// On first boot, attempt to fetch the VRRP configuration from the seed info.
+	if !s.OS.SuccessfulBoot && !s.Services.VRRP.Config.Enabled {
+		vrrpSeed, err := seed.GetVRRP(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if vrrpSeed != nil {
+			s.Services.VRRP.Config = vrrpSeed.ServiceVRRPConfig
+		}
+	}
+
+	// On first boot, attempt to fetch the SSH and console access configuration from the seed info.
+	if !s.OS.SuccessfulBoot && !s.Services.SSH.Config.Enabled {
+		sshSeed, err := seed.GetSSH(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if sshSeed != nil {
+			s.Services.SSH.Config = sshSeed.ServiceSSHConfig
+		}
+	}
 
 	// Run services startup actions. This must be done before bringing up any storage pools.
 	for _, srvName := range services.Supported(s) {
@@ -415,7 +605,11 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		err = srv.Start(ctx)
 		if err != nil {
 			slog.ErrorContext(ctx, "Failed starting service", "name", srvName, "err", err)
+
+			continue
 		}
+
+		s.Events.SendLifecycle(api.EventLifecycleServiceStarted, "/1.0/services/"+srvName, nil)
 	}
 
 	// Ensure any locally-defined pools are available.
@@ -427,16 +621,44 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 	}
 
 	// Run application startup actions. Must be done after storage pools are loaded.
-	for appName := range s.Applications {
-		err := startInitializeApplication(ctx, s, appName)
-		if err != nil {
-			return err
-		}
+	err = startInitializeApplications(ctx, s, slices.Collect(maps.Keys(s.Applications)))
+	if err != nil {
+		return err
 	}
 
+	// Monitor the integrity of the system disk.
+	go systemDiskMonitor(ctx, s, t)
+
+	// Monitor the state storage.
+	go stateStorageMonitor(ctx, s, t)
+
+	// Monitor the EFI boot order.
+	go bootOrderMonitor(ctx, s)
+
+	// Monitor for address conflicts on the network.
+	go addressConflictMonitor(ctx, s)
+
+	// Monitor and rotate the application server certificates.
+	go certificateMonitor(ctx, s)
+
+	// Monitor the health of the applications.
+	go applicationHealthMonitor(ctx, s)
+
+	// Keep the dynamic DNS records up to date.
+	go dnsMonitor(ctx, s)
+
+	// Advertise the system and discover its peers on the local network.
+	go peerDiscovery(ctx, s)
+
+	// Periodically export the system status for use by applications.
+	go statusExporter(ctx, s)
+
+	// Run the nightly self-check.
+	go selfCheckScheduler(ctx, s)
+
 	// Run periodic update checks if we have a working provider.
 	if p != nil {
-		go updateChecker(ctx, s, t, p, false, false)
+		go updateChecker(ctx, s, t, p, false, false, false)
 	}
 
 	// Handle registration.
@@ -465,6 +687,8 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 	s.TriggerReboot = make(chan error, 1)
 	s.TriggerShutdown = make(chan error, 1)
 	s.TriggerUpdate = make(chan bool, 1)
+	s.TriggerDownload = make(chan bool, 1)
+	s.TriggerApply = make(chan bool, 1)
 	chSignal := make(chan os.Signal, 1)
 	signal.Notify(chSignal, unix.SIGTERM)
 
@@ -480,7 +704,19 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		case <-s.TriggerShutdown:
 			action = "shutdown"
 		case <-s.TriggerUpdate:
-			updateChecker(ctx, s, t, p, false, true)
+			updateChecker(ctx, s, t, p, false, true, false)
+
+			goto waitSignal
+		case <-s.TriggerDownload:
+			updateChecker(ctx, s, t, p, false, true, true)
+
+			goto waitSignal
+		case <-s.TriggerApply:
+			err := applyStagedUpdates(ctx, s, t)
+			if err != nil {
+				s.System.Update.State.Status = "Failed to apply staged updates"
+				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+			}
 
 			goto waitSignal
 		}
@@ -504,433 +740,1651 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 	return nil
 }
 
-func startInitializeApplication(ctx context.Context, s *state.State, appName string) error {
-	appInfo := s.Applications[appName]
-
-	// Get the application.
-	app, err := applications.Load(ctx, s, appName)
-	if err != nil {
-		return err
-	}
+// systemDiskMonitor periodically checks the integrity of the system disk and reports any degradation.
+func systemDiskMonitor(ctx context.Context, s *state.State, t *tui.TUI) {
+	var modal *tui.Modal
 
-	// Start the application.
-	slog.InfoContext(ctx, "Starting application", "name", appName, "version", appInfo.State.Version)
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
 
-	err = app.Start(ctx, appInfo.State.Version)
-	if err != nil {
-		return err
-	}
+	for {
+		err := checkSystemDiskHealth(ctx, s)
+		if err != nil && modal == nil {
+			slog.ErrorContext(ctx, "System disk integrity is degraded, updates are blocked", "err", err.Error())
 
-	// Run initialization if needed.
-	if !appInfo.State.Initialized {
-		slog.InfoContext(ctx, "Initializing application", "name", appName, "version", appInfo.State.Version)
+			modal = t.AddModal("System Disk")
+			modal.Update("[red]Error[white] System disk integrity is degraded, updates are blocked: " + err.Error())
 
-		err = app.Initialize(ctx)
-		if err != nil {
-			return err
+			notify.Send(ctx, s, api.SystemNotificationsEventSystemDiskError, "System disk integrity is degraded, updates are blocked: "+err.Error())
+		} else if err == nil && modal != nil {
+			modal.Done()
+			modal = nil
 		}
 
-		appInfo.State.Initialized = true
-		s.Applications[appName] = appInfo
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-
-	return nil
 }
 
-func updateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool, isUserRequested bool) { //nolint:revive
-	showModalError := func(msg string, err error) {
-		slog.ErrorContext(ctx, msg, "err", err.Error(), "provider", p.Type())
+// checkSystemDiskHealth refreshes the state of the system disk, returning an error if a corruption was detected.
+// Failing to get the state isn't considered a degradation, in which case the last known state is kept.
+func checkSystemDiskHealth(ctx context.Context, s *state.State) error {
+	volumes, err := systemd.ListVerityVolumes(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to get the state of the dm-verity volumes", "err", err.Error())
+	} else {
+		s.System.Security.State.VerityVolumes = volumes
+	}
 
-		if updateModal == nil {
-			updateModal = t.AddModal(s.OS.Name + " Update")
+	for _, volume := range s.System.Security.State.VerityVolumes {
+		if volume.State == "corrupted" {
+			return fmt.Errorf("dm-verity volume %q is %s", volume.Volume, volume.State)
 		}
-
-		updateModal.Update("[red]Error[white] " + msg + ": " + err.Error() + " (provider: " + p.Type() + ")")
 	}
 
-	for {
-		// If updates are disabled, skip for an hour.
-		if !isUserRequested && s.System.Update.Config.CheckFrequency == "never" {
-			if isStartupCheck {
-				break
-			}
-
-			time.Sleep(time.Hour)
-
-			continue
-		}
+	return nil
+}
 
-		// Sleep at the top of each loop, except if we're performing a startup or manual check.
-		if !isStartupCheck && !isUserRequested {
-			timeSinceCheck := time.Since(s.System.Update.State.LastCheck)
+// stateStorageMonitor periodically checks that the state can be persisted. While its storage is degraded, such
+// as after being remounted read-only following IO errors, the state is only kept in memory and changes through the
+// API are refused, until the storage is successfully remounted read-write.
+func stateStorageMonitor(ctx context.Context, s *state.State, t *tui.TUI) {
+	var modal *tui.Modal
 
-			frequency, err := time.ParseDuration(s.System.Update.Config.CheckFrequency)
-			if err != nil {
-				// Shouldn't be possible, we validate on update.
-				s.System.Update.State.Status = "Failed to parse update frequency"
-				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
 
-				break
-			}
+	for {
+		err := s.CheckStorage()
+		if err != nil {
+			if modal == nil {
+				modal = t.AddModal("State Storage")
+				modal.Update("[red]Error[white] The state storage is unavailable, configuration changes are refused: " + err.Error())
 
-			// If any maintenance windows are defined, limit the time to sleep to be a minimum
-			// of the configured check frequency and the start of the next maintenance window,
-			// whichever is shorter.
-			for _, window := range s.System.Update.Config.MaintenanceWindows {
-				if window.TimeUntilActive() > 0 && window.TimeUntilActive() < frequency {
-					frequency = window.TimeUntilActive()
-				}
+				notify.Send(ctx, s, api.SystemNotificationsEventSystemDiskError, "The state storage is unavailable, configuration changes are refused: "+err.Error())
 			}
 
-			if timeSinceCheck < frequency {
-				// Add one minute to the calculated sleep to protect against an edge case
-				// where we try to do an update check right at the start of a maintenance window.
-				time.Sleep(frequency - timeSinceCheck + 1*time.Minute)
+			err := remountStateStorage(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to remount the state storage", "err", err.Error())
 			}
+		} else if modal != nil {
+			modal.Done()
+			modal = nil
 		}
 
-		// Save when we last performed an update check.
-		s.System.Update.State.LastCheck = time.Now()
-		s.System.Update.State.Status = "Running update check"
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
-		// Check maintenance window, except if we're performing a startup or manual check.
-		if !isStartupCheck && !isUserRequested {
-			// Check that we are within a defined maintenance window.
-			inMaintenanceWindow := len(s.System.Update.Config.MaintenanceWindows) == 0
-			for _, window := range s.System.Update.Config.MaintenanceWindows {
-				if window.IsCurrentlyActive() {
-					inMaintenanceWindow = true
+// remountStateStorage attempts to remount the filesystem holding the state read-write.
+func remountStateStorage(ctx context.Context) error {
+	target, err := subprocess.RunCommandContext(ctx, "findmnt", "--noheadings", "--output", "TARGET", "--target", varPath)
+	if err != nil {
+		return err
+	}
 
-					break
-				}
-			}
+	_, err = subprocess.RunCommandContext(ctx, "mount", "-o", "remount,rw", strings.TrimSpace(target))
 
-			if !inMaintenanceWindow {
-				s.System.Update.State.Status = "Skipping update check outside of maintenance window(s)"
-				slog.InfoContext(ctx, s.System.Update.State.Status)
+	return err
+}
 
-				continue
-			}
-		}
+// addressConflictMonitor periodically checks whether any address of the system is also in use by another host.
+func addressConflictMonitor(ctx context.Context, s *state.State) {
+	var since time.Time
 
-		// If user requested, clear cache.
-		if isUserRequested {
-			err := p.ClearCache(ctx)
-			if err != nil {
-				s.System.Update.State.Status = "Failed to clear provider cache"
-				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+	notified := map[string]bool{}
 
-				break
-			}
-		}
+	for {
+		now := time.Now()
 
-		// Check for and apply any Secure Boot key updates before performing any OS or application updates.
-		err := checkDoSecureBootCertUpdate(ctx, s, t, p, isStartupCheck)
+		conflicts, err := systemd.GetAddressConflicts(ctx, since)
 		if err != nil {
-			s.System.Update.State.Status = "Failed to check for Secure Boot key updates"
-			showModalError(s.System.Update.State.Status, err)
+			slog.WarnContext(ctx, "Failed to check for address conflicts", "err", err)
+		} else {
+			current := map[string]bool{}
 
-			if isStartupCheck || isUserRequested {
-				break
+			for _, conflict := range conflicts {
+				key := conflict.Interface + "/" + conflict.Address
+				current[key] = true
+
+				// Only notify once for as long as the conflict is being reported.
+				if notified[key] {
+					continue
+				}
+
+				slog.WarnContext(ctx, "Address conflict detected", "interface", conflict.Interface, "address", conflict.Address, "hwaddr", conflict.Hwaddr)
+
+				msg := fmt.Sprintf("Address %s of interface %q is in use by another host on the network", conflict.Address, conflict.Interface)
+				if conflict.Hwaddr != "" {
+					msg += " (" + conflict.Hwaddr + ")"
+				}
+
+				notify.Send(ctx, s, api.SystemNotificationsEventIPConflict, msg)
 			}
 
-			continue
+			notified = current
+			since = now
 		}
 
-		// Determine what applications to install.
-		toInstall := []string{"incus"}
+		time.Sleep(time.Minute)
+	}
+}
 
-		if len(s.Applications) == 0 && (isStartupCheck || isUserRequested) {
-			// Assume first start of the daemon.
-			apps, err := seed.GetApplications(ctx)
-			if err != nil && !seed.IsMissing(err) {
-				s.System.Update.State.Status = "Failed to get application list"
-				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+// bootOrderMonitor periodically checks that the IncusOS boot entry is first in the EFI boot order, optionally repairing it.
+func bootOrderMonitor(ctx context.Context, s *state.State) {
+	notified := false
 
-				if isStartupCheck || isUserRequested {
-					break
-				}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
 
-				continue
+	for {
+		bootOrder, err := secureboot.GetBootOrder()
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to check the EFI boot order", "err", err)
+		} else if bootOrder.Drifted {
+			// Only notify once each time the boot order drifts.
+			if !notified {
+				notify.Send(ctx, s, api.SystemNotificationsEventBootOrderDrifted, "The EFI boot order no longer starts with the IncusOS boot entry "+bootOrder.Expected)
+				notified = true
 			}
 
-			if apps != nil {
-				// We have valid seed data.
-				toInstall = []string{}
+			if s.System.Security.Config.AutoRepairBootOrder {
+				slog.InfoContext(ctx, "Repairing the EFI boot order", "expected", bootOrder.Expected)
 
-				for _, app := range apps.Applications {
-					toInstall = append(toInstall, app.Name)
+				err := secureboot.RepairBootOrder(ctx)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to repair the EFI boot order", "err", err)
 				}
+			} else {
+				slog.WarnContext(ctx, "The EFI boot order no longer starts with the IncusOS boot entry", "expected", bootOrder.Expected)
 			}
 		} else {
-			// We have an existing application list.
-			toInstall = []string{}
+			notified = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordInstallGeometry records the sector sizes and NVMe namespace of the drive IncusOS is installed on.
+func recordInstallGeometry(s *state.State) error {
+	systemDrive, err := storage.GetUnderlyingDevice()
+	if err != nil {
+		return err
+	}
+
+	geometry, err := storage.GetDriveGeometry(systemDrive)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(geometry)
+	if err != nil {
+		return err
+	}
+
+	s.InstallGeometry = string(body)
+
+	return nil
+}
+
+// checkTPMBindings records the firmware measurements while the TPM can unlock the encrypted volumes. When it no
+// longer can and the only change since is to the firmware, such as after a BIOS update, the TPM bindings are reset
+// using the first recovery key if automatic rebinding is enabled. This reboots the system.
+func checkTPMBindings(ctx context.Context, s *state.State) error {
+	if s.PassphraseOnly || systemd.TPMUnlockDisabled(s) {
+		return nil
+	}
+
+	canUnlock, err := secureboot.TPMCanUnlock(ctx)
+	if err != nil {
+		return err
+	}
+
+	if canUnlock {
+		measurements, err := secureboot.GetFirmwareMeasurements()
+		if err != nil {
+			return err
+		}
+
+		body, err := json.Marshal(measurements)
+		if err != nil {
+			return err
+		}
+
+		s.FirmwareBaseline = string(body)
+
+		return nil
+	}
+
+	// Without a baseline, there's no way to tell a firmware update apart from other changes.
+	if s.FirmwareBaseline == "" {
+		slog.WarnContext(ctx, "The TPM can't unlock the encrypted volumes, TPM bindings must be reset manually")
+		notify.Send(ctx, s, api.SystemNotificationsEventTPMBindings, "The TPM can't unlock the encrypted volumes")
+		sendEncryptionWarning(s, "The TPM can't unlock the encrypted volumes")
+
+		return nil
+	}
+
+	baseline := secureboot.FirmwareMeasurements{}
+
+	err = json.Unmarshal([]byte(s.FirmwareBaseline), &baseline)
+	if err != nil {
+		return err
+	}
+
+	err = secureboot.CheckFirmwareUpdate(baseline)
+	if err != nil {
+		slog.WarnContext(ctx, "The TPM can't unlock the encrypted volumes and this isn't due to a firmware update, TPM bindings must be reset manually", "reason", err.Error())
+		notify.Send(ctx, s, api.SystemNotificationsEventTPMBindings, "The TPM can't unlock the encrypted volumes and this isn't due to a firmware update: "+err.Error())
+		sendEncryptionWarning(s, "The TPM can't unlock the encrypted volumes and this isn't due to a firmware update: "+err.Error())
+
+		return nil
+	}
+
+	if !s.System.Security.Config.AutoTPMRebind {
+		slog.WarnContext(ctx, "A firmware update prevents the TPM from unlocking the encrypted volumes, TPM bindings must be reset")
+		notify.Send(ctx, s, api.SystemNotificationsEventTPMBindings, "A firmware update prevents the TPM from unlocking the encrypted volumes")
+		sendEncryptionWarning(s, "A firmware update prevents the TPM from unlocking the encrypted volumes")
+
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Resetting TPM bindings after a firmware update")
+	notify.Send(ctx, s, api.SystemNotificationsEventTPMBindings, "Resetting TPM bindings after a firmware update")
+
+	_, err = secureboot.ForceUpdatePCRBindings(ctx, s.OS.Name, s.OS.RunningRelease, s.System.Security.Config.EncryptionRecoveryKeys[0], false)
+
+	return err
+}
+
+// sendEncryptionWarning sends a lifecycle event about the encrypted volumes requiring attention.
+func sendEncryptionWarning(s *state.State, message string) {
+	s.Events.SendLifecycle(api.EventLifecycleEncryptionWarning, "/1.0/system/security", map[string]any{"message": message})
+}
+
+// certificateMonitor periodically checks the server certificates of the primary applications, rotating them ahead of expiry.
+func certificateMonitor(ctx context.Context, s *state.State) {
+	ticker := time.NewTicker(6 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		// Don't interfere with applications being updated, as the certificate replacement restarts them.
+		if s.UpdateMutex.TryLock() {
+			for _, appName := range slices.Sorted(maps.Keys(s.Applications)) {
+				app, err := applications.Load(ctx, s, appName)
+				if err != nil || !app.IsPrimary() {
+					continue
+				}
+
+				err = applications.CheckCertificateRotation(ctx, s, appName, app)
+				if err != nil {
+					slog.WarnContext(ctx, "Failed to check the application server certificate", "name", appName, "err", err)
+				}
+			}
+
+			_ = s.Save()
+
+			s.UpdateMutex.Unlock()
+
+			ticker.Reset(6 * time.Hour)
+		} else {
+			// Try again shortly.
+			ticker.Reset(time.Minute)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applicationHealthMonitor periodically checks the health of the applications, restarting those which fail.
+func applicationHealthMonitor(ctx context.Context, s *state.State) {
+	for {
+		time.Sleep(time.Minute)
+
+		// Don't interfere with applications being updated.
+		if !s.UpdateMutex.TryLock() {
+			continue
+		}
+
+		for _, appName := range slices.Sorted(maps.Keys(s.Applications)) {
+			app, err := applications.Load(ctx, s, appName)
+			if err != nil {
+				continue
+			}
+
+			err = applications.CheckHealth(ctx, s, appName, app)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to check the application health", "name", appName, "err", err)
+			}
+		}
+
+		s.UpdateMutex.Unlock()
+	}
+}
+
+// dnsMonitor periodically points the dynamic DNS records to the system's current addresses.
+func dnsMonitor(ctx context.Context, s *state.State) {
+	for {
+		if len(s.System.DNS.Config.DynamicRecords) > 0 {
+			err := dns.UpdateDynamicRecords(ctx, s)
+			if err != nil {
+				slog.WarnContext(ctx, "Failed to update the dynamic DNS records", "err", err)
+			}
+
+			_ = s.Save()
+		}
+
+		time.Sleep(5 * time.Minute)
+	}
+}
+
+// seedMonitor watches for a user-provided seed volume being attached to the installed system, in which case
+// the network, provider and applications sections it contains are applied. Volumes already attached at boot
+// and install media are ignored.
+func seedMonitor(ctx context.Context, s *state.State, server *rest.Server) {
+	if s.ShouldPerformInstall {
+		return
+	}
+
+	attached := seed.UserSeedPresent()
+
+	for {
+		time.Sleep(5 * time.Second)
+
+		present := seed.UserSeedPresent()
+		if !present || attached {
+			attached = present
+
+			continue
+		}
+
+		attached = true
+
+		_, err := seed.GetInstall()
+		if err == nil || errors.Is(err, io.EOF) {
+			slog.WarnContext(ctx, "Ignoring newly attached seed volume containing an install seed")
+
+			continue
+		}
+
+		slog.InfoContext(ctx, "Applying seed data from newly attached seed volume")
+
+		applied, err := server.ApplySeed(ctx, nil)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to apply seed data from newly attached seed volume", "err", err)
+
+			continue
+		}
+
+		slog.InfoContext(ctx, "Applied seed data from newly attached seed volume", "sections", applied)
+	}
+}
+
+// peerDiscovery answers multicast DNS queries from other IncusOS systems and periodically looks for them.
+func peerDiscovery(ctx context.Context, s *state.State) {
+	go func() {
+		err := mdns.Serve(ctx, s)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to advertise the system over multicast DNS", "err", err)
+		}
+	}()
+
+	for {
+		err := mdns.Discover(ctx, s)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to discover peers over multicast DNS", "err", err)
+		}
+
+		time.Sleep(5 * time.Minute)
+	}
+}
+
+// statusExporter periodically writes a summary of the system status to a well-known location.
+func statusExporter(ctx context.Context, s *state.State) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		err := exportStatus(s)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to export system status", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// selfCheckScheduler runs the self-check every night at 3am, recording its report.
+func selfCheckScheduler(ctx context.Context, s *state.State) {
+	for {
+		now := time.Now()
+
+		next := time.Date(now.Year(), now.Month(), now.Day(), 3, 0, 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		time.Sleep(time.Until(next))
+
+		report, err := selfcheck.Run(ctx, s)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to record the self-check report", "err", err)
+		} else if !report.Passed {
+			slog.WarnContext(ctx, "Nightly self-check failed, see /1.0/system/self-check for details")
+		}
+
+		_ = s.Save()
+	}
+}
+
+// exportStatus atomically writes the system status summary as world-readable JSON.
+func exportStatus(s *state.State) error {
+	body, err := json.Marshal(s.Summary())
+	if err != nil {
+		return err
+	}
+
+	tmpPath := statusPath + ".tmp"
+
+	err = os.WriteFile(tmpPath, body, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// Don't rely on the umask for the permissions.
+	err = os.Chmod(tmpPath, 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, statusPath)
+}
+
+// applicationServices lists the services which only become supported once an application is installed.
+var applicationServices = map[string][]string{
+	"incus-ceph":    {"ceph"},
+	"incus-linstor": {"linstor"},
+}
+
+// startInitializeApplications starts and initializes the listed applications in dependency order. If any
+// of them fails, all the applications which were being initialized for the first time are rolled back.
+func startInitializeApplications(ctx context.Context, s *state.State, appNames []string) error {
+	sortedAppNames, err := applications.SortByDependencies(ctx, s, appNames)
+	if err != nil {
+		return err
+	}
+
+	toStart := []string{}
+	newApps := []string{}
+
+	for _, appName := range sortedAppNames {
+		appInfo, ok := s.Applications[appName]
+		if !ok {
+			return fmt.Errorf("application %q isn't installed", appName)
+		}
+
+		// Don't restart dependencies which are already running.
+		if !slices.Contains(appNames, appName) {
+			app, err := applications.Load(ctx, s, appName)
+			if err != nil {
+				return err
+			}
+
+			if app.IsRunning(ctx) {
+				continue
+			}
+		}
+
+		toStart = append(toStart, appName)
+
+		if !appInfo.State.Initialized {
+			newApps = append(newApps, appName)
+		}
+	}
+
+	// Bring up the services provided by the new applications before anything gets to use them.
+	for _, appName := range newApps {
+		for _, srvName := range applicationServices[appName] {
+			srv, err := services.Load(ctx, s, srvName)
+			if err != nil || !srv.ShouldStart() {
+				continue
+			}
+
+			slog.InfoContext(ctx, "Starting service", "name", srvName)
+
+			err = srv.Start(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed starting service", "name", srvName, "err", err)
+
+				continue
+			}
+
+			s.Events.SendLifecycle(api.EventLifecycleServiceStarted, "/1.0/services/"+srvName, nil)
+		}
+	}
+
+	for _, appName := range toStart {
+		err := startInitializeApplication(ctx, s, appName)
+		if err != nil {
+			if len(newApps) > 0 {
+				rollbackErr := rollbackApplications(ctx, s, newApps)
+				if rollbackErr != nil {
+					slog.ErrorContext(ctx, "Failed to roll back new applications", "err", rollbackErr)
+				}
+			}
+
+			return fmt.Errorf("failed to start application %q: %w", appName, err)
+		}
+	}
+
+	return nil
+}
+
+// rollbackApplications removes newly installed applications, in reverse dependency order.
+// Their local data is kept, as it may predate the failed installation.
+func rollbackApplications(ctx context.Context, s *state.State, appNames []string) error {
+	for _, appName := range slices.Backward(appNames) {
+		slog.WarnContext(ctx, "Rolling back application", "name", appName)
+
+		err := applications.Remove(ctx, s, appName, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	_ = s.Save()
+
+	return systemd.RefreshExtensions(ctx)
+}
+
+func startInitializeApplication(ctx context.Context, s *state.State, appName string) error {
+	appInfo := s.Applications[appName]
+
+	// Get the application.
+	app, err := applications.Load(ctx, s, appName)
+	if err != nil {
+		return err
+	}
+
+	// Have the console step aside for a new primary application.
+	if !appInfo.State.Initialized && appName != "console" && app.IsPrimary() {
+		err = applications.RefreshConsole(ctx, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Start the application.
+	slog.InfoContext(ctx, "Starting application", "name", appName, "version", appInfo.State.Version)
+
+	err = app.Start(ctx, appInfo.State.Version)
+	if err != nil {
+		return err
+	}
+
+	// Run initialization if needed.
+	if !appInfo.State.Initialized {
+		slog.InfoContext(ctx, "Initializing application", "name", appName, "version", appInfo.State.Version)
+
+		err = app.Initialize(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Deliver the application-specific settings.
+		err = app.ApplySettings(ctx, appInfo.Config.Settings)
+		if err != nil {
+			return fmt.Errorf("failed to apply settings of application %q: %w", appName, err)
+		}
+
+		appInfo.State.Initialized = true
+		s.SetApplication(appName, appInfo)
+	}
+
+	return nil
+}
+
+// inMaintenanceWindow returns whether updates can currently be applied, either because a maintenance window is
+// active or because none is defined.
+func inMaintenanceWindow(s *state.State) bool {
+	if len(s.System.Update.Config.MaintenanceWindows) == 0 {
+		return true
+	}
+
+	for _, window := range s.System.Update.Config.MaintenanceWindows {
+		if window.IsCurrentlyActive() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateChecker checks for and applies OS and application updates, either once for startup and manual checks or
+// periodically. If isDownloadRequested is set, updates are only staged, as in download-only mode.
+func updateChecker(parentCtx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool, isUserRequested bool, isDownloadRequested bool) { //nolint:revive
+	ctx := parentCtx
+
+	endOperation := func() {}
+	defer func() { endOperation() }()
+
+	showModalError := func(msg string, err error) {
+		// Don't report cancellations requested through the API as failures.
+		if errors.Is(err, context.Canceled) && parentCtx.Err() == nil {
+			s.System.Update.State.Status = "Update check cancelled"
+			slog.InfoContext(ctx, s.System.Update.State.Status)
+
+			return
+		}
+
+		// Let the user know when the provider will accept requests again.
+		var rateLimitErr *providers.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			s.System.Update.State.Status = msg + " (rate limited until " + rateLimitErr.RetryAt.Format(time.RFC3339) + ")"
+		}
+
+		// Let the user know when downloads are deferred until next month.
+		if errors.Is(err, errMeteredQuotaExceeded) {
+			s.System.Update.State.Status = msg + " (monthly metered quota reached)"
+		}
+
+		// Report the missing disk space in a structured form.
+		var spaceErr *storage.InsufficientSpaceError
+		if errors.As(err, &spaceErr) {
+			s.System.Update.State.InsufficientSpace = &api.SystemUpdateInsufficientSpace{
+				Path:      spaceErr.Path,
+				Required:  spaceErr.Required,
+				Available: spaceErr.Available,
+			}
+		}
+
+		slog.ErrorContext(ctx, msg, "err", err.Error(), "provider", p.Type())
+		notify.Send(ctx, s, api.SystemNotificationsEventUpdateFailed, msg+": "+err.Error())
+
+		if updateModal == nil {
+			updateModal = t.AddModal(s.OS.Name + " Update")
+		}
+
+		updateModal.Update("[red]Error[white] " + msg + ": " + err.Error() + " (provider: " + p.Type() + ")")
+	}
+
+	for {
+		// Complete the previous check before waiting for the next one.
+		endOperation()
+
+		ctx = parentCtx
+
+		// If updates are disabled, skip for an hour.
+		if !isUserRequested && s.System.Update.Config.CheckFrequency == "never" {
+			if isStartupCheck {
+				break
+			}
+
+			time.Sleep(time.Hour)
+
+			continue
+		}
+
+		// Sleep at the top of each loop, except if we're performing a startup or manual check.
+		if !isStartupCheck && !isUserRequested {
+			frequency, err := time.ParseDuration(s.System.Update.Config.CheckFrequency)
+			if err != nil {
+				// Shouldn't be possible, we validate on update.
+				s.System.Update.State.Status = "Failed to parse update frequency"
+				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+
+				break
+			}
+
+			// Sleep in short increments, re-evaluating the remaining time each time, so that changes to the
+			// wall clock, such as the initial NTP synchronization, and to the maintenance windows are accounted for.
+			inWindow := inMaintenanceWindow(s)
+
+			for {
+				wait := frequency - time.Since(s.System.Update.State.LastCheck)
+
+				// If any maintenance windows are defined, limit the time to sleep to be a minimum
+				// of the configured check frequency and the start of the next maintenance window,
+				// whichever is shorter.
+				for _, window := range s.System.Update.Config.MaintenanceWindows {
+					untilActive := window.TimeUntilActive()
+					if untilActive > 0 && untilActive < wait {
+						wait = untilActive
+					}
+				}
+
+				if wait <= 0 {
+					break
+				}
+
+				// Add one minute to the calculated sleep to protect against an edge case
+				// where we try to do an update check right at the start of a maintenance window.
+				time.Sleep(min(wait+1*time.Minute, 15*time.Minute))
+
+				// Check for updates as soon as a maintenance window starts.
+				if !inWindow && inMaintenanceWindow(s) {
+					break
+				}
+
+				inWindow = inMaintenanceWindow(s)
+			}
+		}
+
+		// Allow the check, and any resulting download, to be cancelled through the API.
+		ctx, endOperation = s.StartUpdateOperation(parentCtx)
+
+		// Save when we last performed an update check.
+		s.System.Update.State.LastCheck = time.Now()
+		s.System.Update.State.Status = "Running update check"
+		s.System.Update.State.InsufficientSpace = nil
+
+		// Check maintenance window, except if we're performing a startup or manual check.
+		if !isStartupCheck && !isUserRequested {
+			// Check that we are within a defined maintenance window.
+			if !inMaintenanceWindow(s) {
+				s.System.Update.State.Status = "Skipping update check outside of maintenance window(s)"
+				slog.InfoContext(ctx, s.System.Update.State.Status)
+
+				continue
+			}
+		}
+
+		// If user requested, clear cache.
+		if isUserRequested {
+			err := p.ClearCache(ctx)
+			if err != nil {
+				s.System.Update.State.Status = "Failed to clear provider cache"
+				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+
+				break
+			}
+		}
+
+		// Don't update a system whose disk integrity is degraded.
+		err := checkSystemDiskHealth(ctx, s)
+		if err != nil {
+			s.System.Update.State.Status = "Updates blocked due to degraded system disk"
+			showModalError(s.System.Update.State.Status, err)
+
+			if isStartupCheck || isUserRequested {
+				break
+			}
+
+			continue
+		}
+
+		// Check for and apply any Secure Boot key updates before performing any OS or application updates.
+		err = checkDoSecureBootCertUpdate(ctx, s, t, p, isStartupCheck)
+		if err != nil {
+			s.System.Update.State.Status = "Failed to check for Secure Boot key updates"
+			showModalError(s.System.Update.State.Status, err)
+
+			if isStartupCheck || isUserRequested {
+				break
+			}
+
+			continue
+		}
+
+		// Check for and apply any dbx revocation list update.
+		err = checkDoSecureBootDbxUpdate(ctx, s, t, p)
+		if err != nil {
+			s.System.Update.State.Status = "Failed to check for Secure Boot dbx updates"
+			showModalError(s.System.Update.State.Status, err)
+
+			if isStartupCheck || isUserRequested {
+				break
+			}
+
+			continue
+		}
+
+		// Determine what applications to install.
+		toInstall := []string{"incus"}
+
+		if len(s.Applications) == 0 && (isStartupCheck || isUserRequested) {
+			// Assume first start of the daemon.
+			apps, err := seed.GetApplications(ctx)
+			if err != nil && !seed.IsMissing(err) {
+				s.System.Update.State.Status = "Failed to get application list"
+				slog.ErrorContext(ctx, s.System.Update.State.Status, "err", err.Error())
+
+				if isStartupCheck || isUserRequested {
+					break
+				}
+
+				continue
+			}
+
+			if apps != nil {
+				// We have valid seed data.
+				toInstall = []string{}
+
+				for _, app := range apps.Applications {
+					toInstall = append(toInstall, app.Name)
+
+					// Record any configuration provided for the application.
+					if app.Config != nil {
+						s.SetApplication(app.Name, api.Application{Config: *app.Config})
+					}
+				}
+			}
+		} else {
+			// We have an existing application list.
+			toInstall = []string{}
 
 			for name := range s.Applications {
 				toInstall = append(toInstall, name)
 			}
 		}
 
-		// Verify that each application has its dependencies, if any, included in the list of applications.
+		// Add any missing dependency to the list of applications and order it so that dependencies come first.
+		toInstall, err = applications.SortByDependencies(ctx, s, toInstall)
+		if err != nil {
+			s.System.Update.State.Status = "Failed to check application dependencies"
+			showModalError(s.System.Update.State.Status, err)
+
+			if isStartupCheck || isUserRequested {
+				break
+			}
+
+			continue
+		}
+
+		// Check for application updates, downloading all of them before activating any.
+		appsUpdated := map[string]string{}
+		appsInstalled := []string{}
+
+		for _, appName := range toInstall {
+			isNew := s.Applications[appName].State.Version == ""
+
+			newAppVersion, err := checkDoAppUpdate(ctx, s, t, p, appName, isStartupCheck, s.System.Update.Config.DownloadOnly || isDownloadRequested)
+			if err != nil {
+				s.System.Update.State.Status = "Failed to check for application updates"
+				showModalError(s.System.Update.State.Status, err)
+
+				// Don't leave a partial set of new applications behind.
+				if len(appsInstalled) > 0 {
+					err := rollbackApplications(ctx, s, appsInstalled)
+					if err != nil {
+						slog.ErrorContext(ctx, "Failed to roll back new applications", "err", err)
+					}
+
+					for _, name := range appsInstalled {
+						delete(appsUpdated, name)
+					}
+				}
+
+				break
+			}
+
+			if newAppVersion != "" {
+				appsUpdated[appName] = newAppVersion
+
+				if isNew {
+					appsInstalled = append(appsInstalled, appName)
+				}
+			}
+		}
+
+		// Apply the system extensions.
+		if len(appsUpdated) > 0 {
+			slog.DebugContext(ctx, "Refreshing system extensions")
+
+			err = systemd.RefreshExtensions(ctx)
+			if err != nil {
+				s.System.Update.State.Status = "Failed to refresh system extensions"
+				showModalError(s.System.Update.State.Status, err)
+
+				if isStartupCheck || isUserRequested {
+					break
+				}
+
+				continue
+			}
+		}
+
+		// Check for the latest OS update.
+		newInstalledOSVersion, err := checkDoOSUpdate(ctx, s, t, p, isStartupCheck, s.System.Update.Config.DownloadOnly || isDownloadRequested)
+		if err != nil {
+			s.System.Update.State.Status = "Failed to check for OS updates"
+			showModalError(s.System.Update.State.Status, err)
+
+			if isStartupCheck || isUserRequested {
+				break
+			}
+
+			continue
+		}
+
+		// Notify the applications that they need to update/restart, in dependency order.
+		toStart := []string{}
+
 		for _, appName := range toInstall {
+			appVersion, ok := appsUpdated[appName]
+			if !ok {
+				continue
+			}
+
+			// Get the application.
 			app, err := applications.Load(ctx, s, appName)
 			if err != nil {
-				s.System.Update.State.Status = "Failed to check application dependencies"
+				s.System.Update.State.Status = "Failed to load application"
 				showModalError(s.System.Update.State.Status, err)
 
-				break
+				continue
 			}
 
-			for _, dep := range app.GetDependencies() {
-				if !slices.Contains(toInstall, dep) {
-					toInstall = append(toInstall, dep)
+			notify.Send(ctx, s, api.SystemNotificationsEventUpdateInstalled, "Application "+appName+" has been updated to version "+appVersion)
+
+			// Start/reload the application.
+			if !isStartupCheck {
+				if app.IsRunning(ctx) {
+					slog.InfoContext(ctx, "Reloading application", "name", appName, "version", appVersion)
+
+					start := time.Now()
+
+					err := applications.UpdateWithRollback(ctx, s, appName, appVersion, applications.UpdateHealthTimeout)
+					if err != nil {
+						s.System.Update.State.Status = "Failed to reload application"
+						showModalError(s.System.Update.State.Status, err)
+						notifyRollback(ctx, s, appName, err)
+
+						continue
+					}
+
+					s.RecordUpdateApplyTime(appName, time.Since(start))
+				} else {
+					toStart = append(toStart, appName)
 				}
 			}
 		}
 
-		// Check for application updates.
-		appsUpdated := map[string]string{}
+		// Start any new application, rolling back all the new applications if one fails.
+		err = startInitializeApplications(ctx, s, toStart)
+		if err != nil {
+			s.System.Update.State.Status = "Failed to start application"
+			showModalError(s.System.Update.State.Status, err)
+		}
 
-		for _, appName := range toInstall {
-			newAppVersion, err := checkDoAppUpdate(ctx, s, t, p, appName, isStartupCheck)
+		if newInstalledOSVersion != "" {
+			if updateModal == nil {
+				updateModal = t.AddModal(s.OS.Name + " Update")
+			}
+
+			s.System.Update.State.Status = s.OS.Name + " has been updated to version " + newInstalledOSVersion
+			updateModal.Update(s.OS.Name + " has been updated to version " + newInstalledOSVersion + ".\nPlease reboot the system to finalize update.")
+
+			s.System.Update.State.NeedsReboot = true
+			s.Events.SendLifecycle(api.EventLifecycleRebootPending, "/1.0/system/update", map[string]any{"version": newInstalledOSVersion})
+
+			notify.Send(ctx, s, api.SystemNotificationsEventUpdateInstalled, s.OS.Name+" has been updated to version "+newInstalledOSVersion)
+			notify.Send(ctx, s, api.SystemNotificationsEventRebootRequired, "A reboot is required to finalize the update to "+s.OS.Name+" version "+newInstalledOSVersion)
+		} else if s.HasStagedUpdates() {
+			s.System.Update.State.Status = "Update check completed, staged updates are waiting to be applied"
+		} else {
+			s.System.Update.State.Status = "Update check completed"
+		}
+
+		if isStartupCheck || isUserRequested {
+			// If running a one-time update, we're done.
+			break
+		}
+	}
+}
+
+// checkDoOSUpdate checks for, downloads and applies an OS update, or only stages it if downloadOnly is set.
+func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool, downloadOnly bool) (string, error) { //nolint:revive
+	s.UpdateMutex.Lock()
+	defer s.UpdateMutex.Unlock()
+
+	slog.DebugContext(ctx, "Checking for OS updates")
+
+	if s.System.Update.State.NeedsReboot {
+		slog.DebugContext(ctx, "A reboot of the system is required to finalize a pending update")
+	}
+
+	update, err := p.GetOSUpdate(ctx)
+	recordProviderResult(s, err)
+
+	if err != nil {
+		if errors.Is(err, providers.ErrNoUpdateAvailable) {
+			slog.DebugContext(ctx, "OS update provider doesn't currently have any update")
+
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	s.System.Provider.State.LatestVersion = update.Version()
+
+	// If we're running from the backup image don't attempt to re-update to a broken version.
+	if !s.System.Update.State.NeedsReboot && s.OS.NextRelease != "" && s.OS.RunningRelease != s.OS.NextRelease && s.OS.NextRelease == update.Version() {
+		slog.WarnContext(ctx, "Latest "+s.OS.Name+" image version "+s.OS.NextRelease+" has been identified as problematic, skipping update")
+
+		return "", nil
+	}
+
+	// Skip any update that isn't newer than what we are already running.
+	if s.OS.RunningRelease != update.Version() && !update.IsNewerThan(s.OS.RunningRelease) {
+		return "", errors.New("local " + s.OS.Name + " version (" + s.OS.RunningRelease + ") is newer than available update (" + update.Version() + "); skipping")
+	}
+
+	// Apply the update.
+	if update.Version() != s.OS.RunningRelease && update.Version() != s.OS.NextRelease {
+		// Check if the update has already been staged.
+		if downloadOnly && s.OS.StagedRelease == update.Version() {
+			slog.DebugContext(ctx, "OS update is already staged", "release", update.Version())
+			recordAvailableUpdate(s, p, "os", update.Version(), update.Size(), true)
+
+			return "", nil
+		}
+
+		recordAvailableUpdate(s, p, "os", update.Version(), update.Size(), false)
+
+		defer clearUpdateProgress(s)
+
+		// If configured, confirm the update with an independent provider before going any further.
+		if s.System.Update.Config.VerificationProvider != nil {
+			slog.DebugContext(ctx, "Verifying OS update", "release", update.Version(), "provider", s.System.Update.Config.VerificationProvider.Name)
+			setUpdateProgressPhase(s, api.SystemUpdateProgressPhaseVerifying, "os", update.Version())
+
+			err := providers.VerifyOSUpdate(ctx, s, update, *s.System.Update.Config.VerificationProvider)
 			if err != nil {
-				s.System.Update.State.Status = "Failed to check for application updates"
-				showModalError(s.System.Update.State.Status, err)
+				return "", err
+			}
+		}
 
-				break
+		// Make sure there's enough space to download the update.
+		err = storage.CheckFreeSpace(systemd.SystemUpdatesPath, storage.DecompressedSize(update.Size(), providers.ServesCompressedFiles(p)))
+		if err != nil {
+			return "", err
+		}
+
+		// Make sure the download fits in the metered quota.
+		err = checkMeteredQuota(s, p, update.Size())
+		if err != nil {
+			return "", err
+		}
+
+		// Download the update into place.
+		modal := t.AddModal(s.OS.Name + " Update")
+		defer modal.Done()
+
+		slog.InfoContext(ctx, "Downloading OS update", "release", update.Version())
+		modal.Update("Downloading " + s.OS.Name + " update version " + update.Version())
+
+		err := update.DownloadUpdate(ctx, systemd.SystemUpdatesPath, trackUpdateProgress(s, modal, "os", update.Version(), update.Size()))
+		if err != nil {
+			return "", err
+		}
+
+		recordDataUsage(s, p, update.Size())
+		providers.RecordProvenance(ctx, s, p.Type(), update.GetProvenance)
+
+		// Hide the progress bar.
+		modal.UpdateProgress(0.0)
+
+		// In download-only mode, record the staged release and wait for an explicit request to apply it.
+		if downloadOnly {
+			slog.InfoContext(ctx, "Staged OS update", "release", update.Version())
+			modal.Update(s.OS.Name + " update version " + update.Version() + " has been staged")
+
+			s.OS.StagedRelease = update.Version()
+			_ = s.Save()
+
+			recordAvailableUpdate(s, p, "os", update.Version(), update.Size(), true)
+
+			return "", nil
+		}
+
+		// Apply the update and reboot if first time through loop, otherwise wait for user to reboot system.
+		err = applyOSUpdate(ctx, s, modal, update.Version(), s.System.Update.Config.AutoReboot || isStartupCheck)
+		if err != nil {
+			return "", err
+		}
+
+		return update.Version(), nil
+	} else if isStartupCheck {
+		slog.DebugContext(ctx, "System is already running latest OS release", "release", s.OS.RunningRelease)
+	}
+
+	clearAvailableUpdate(s, "os")
+
+	return "", nil
+}
+
+// applyOSUpdate applies an OS update which has already been downloaded into place.
+func applyOSUpdate(ctx context.Context, s *state.State, modal *tui.Modal, version string, reboot bool) error {
+	// Let the applications prepare for the OS update.
+	err := applications.RunHooks(ctx, s, applications.HookPreOSUpdate, version)
+	if err != nil {
+		return err
+	}
+
+	// Record the release. Need to do it here, since if the system reboots as part of the
+	// update we won't be able to save the state to disk.
+	priorNextRelease := s.OS.NextRelease
+	priorStagedRelease := s.OS.StagedRelease
+	s.OS.NextRelease = version
+	s.OS.StagedRelease = ""
+	_ = s.Save()
+
+	slog.InfoContext(ctx, "Applying OS update", "release", version)
+	modal.Update("Applying " + s.OS.Name + " update version " + version)
+
+	setUpdateProgressPhase(s, api.SystemUpdateProgressPhaseApplying, "os", version)
+	defer clearUpdateProgress(s)
+
+	start := time.Now()
+
+	err = systemd.ApplySystemUpdate(ctx, s.System.Security.Config.EncryptionRecoveryKeys[0], version, reboot)
+	if err != nil {
+		s.OS.NextRelease = priorNextRelease
+		s.OS.StagedRelease = priorStagedRelease
+		_ = s.Save()
+
+		return err
+	}
+
+	s.RecordUpdateApplyTime("os", time.Since(start))
+	clearAvailableUpdate(s, "os")
+	markProvenanceInstalled(ctx, s, "os", version)
+
+	// Record the state of auto-unlocked LUKS devices. With some TPMs this can be slow, so cache the
+	// result after applying an OS update rather than needing to determine it each time a request
+	// arrives via the API.
+	s.System.Security.State.EncryptedVolumes, err = systemd.ListEncryptedVolumes(ctx)
+	if err != nil {
+		s.OS.NextRelease = priorNextRelease
+		s.OS.StagedRelease = priorStagedRelease
+		_ = s.Save()
+
+		return err
+	}
+
+	return nil
+}
+
+// checkDoAppUpdate checks for, downloads and installs an application update, or only stages updates to already
+// installed applications if downloadOnly is set.
+func checkDoAppUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, appName string, isStartupCheck bool, downloadOnly bool) (string, error) { //nolint:revive
+	s.UpdateMutex.Lock()
+	defer s.UpdateMutex.Unlock()
+
+	slog.DebugContext(ctx, "Checking for application updates")
+
+	app, err := p.GetApplication(ctx, appName)
+	recordProviderResult(s, err)
+
+	if err != nil {
+		if errors.Is(err, providers.ErrNoUpdateAvailable) {
+			slog.DebugContext(ctx, "Application update provider doesn't currently have any update")
+
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	// Apply the update.
+	if app.Version() != s.Applications[app.Name()].State.Version {
+		if s.Applications[app.Name()].State.Version != "" && !app.IsNewerThan(s.Applications[app.Name()].State.Version) {
+			// Applications aren't downgraded when moved to an older channel or version, they wait for it to catch up.
+			if s.Applications[app.Name()].Config.UpdateChannel != "" || s.Applications[app.Name()].Config.UpdateVersion != "" {
+				slog.DebugContext(ctx, "Application is newer than its configured channel or version", "application", app.Name(), "release", app.Version())
+
+				return "", nil
 			}
 
-			if newAppVersion != "" {
-				appsUpdated[appName] = newAppVersion
+			return "", errors.New("local application " + app.Name() + " version (" + s.Applications[app.Name()].State.Version + ") is newer than available update (" + app.Version() + "); skipping")
+		}
+
+		// Don't retry an update which was already rolled back.
+		if app.Version() == s.Applications[app.Name()].State.FailedVersion {
+			slog.DebugContext(ctx, "Skipping application update which previously failed", "application", app.Name(), "release", app.Version())
+
+			return "", nil
+		}
+
+		// In download-only mode, updates to already installed applications are staged rather than applied.
+		// Applications which aren't installed yet are always installed right away.
+		stageOnly := downloadOnly && s.Applications[app.Name()].State.Version != ""
+		if stageOnly && s.Applications[app.Name()].State.StagedVersion == app.Version() {
+			slog.DebugContext(ctx, "Application update is already staged", "application", app.Name(), "release", app.Version())
+			recordAvailableUpdate(s, p, app.Name(), app.Version(), app.Size(), true)
+
+			return "", nil
+		}
+
+		recordAvailableUpdate(s, p, app.Name(), app.Version(), app.Size(), false)
+
+		targetPath := systemd.SystemExtensionsPath
+		if stageOnly {
+			targetPath = systemd.SystemExtensionsStagingPath
+		}
+
+		// Make sure there's enough space to download the application.
+		err = storage.CheckFreeSpace(targetPath, storage.DecompressedSize(app.Size(), providers.ServesCompressedFiles(p)))
+		if err != nil {
+			return "", err
+		}
+
+		// Installing a new application is always allowed, but updates must fit in the metered quota.
+		if s.Applications[app.Name()].State.Version != "" {
+			err = checkMeteredQuota(s, p, app.Size())
+			if err != nil {
+				return "", err
+			}
+		}
+
+		reverter := revert.New()
+		defer reverter.Fail()
+
+		// Keep the current version of an installed application, to roll back to if the update fails.
+		if !stageOnly && s.Applications[app.Name()].State.Version != "" {
+			restorePrevious, err := applications.KeepPrevious(app.Name())
+			if err != nil {
+				return "", err
 			}
+
+			reverter.Add(restorePrevious)
+		}
+
+		// Download the application.
+		modal := t.AddModal(s.OS.Name + " Update")
+		defer modal.Done()
+
+		slog.InfoContext(ctx, "Downloading application", "application", app.Name(), "release", app.Version())
+		modal.Update("Downloading application " + app.Name() + " update " + app.Version())
+
+		defer clearUpdateProgress(s)
+
+		err = app.Download(ctx, targetPath, trackUpdateProgress(s, modal, app.Name(), app.Version(), app.Size()))
+		if err != nil {
+			return "", err
+		}
+
+		recordDataUsage(s, p, app.Size())
+		providers.RecordProvenance(ctx, s, p.Type(), app.GetProvenance)
+
+		// Verify the application is signed with a trusted key in the kernel's keyring.
+		setUpdateProgressPhase(s, api.SystemUpdateProgressPhaseVerifying, app.Name(), app.Version())
+
+		err = systemd.VerifyExtensionCertificateFingerprint(ctx, filepath.Join(targetPath, app.Name()+".raw"))
+		if err != nil {
+			return "", err
 		}
 
-		// Apply the system extensions.
-		if len(appsUpdated) > 0 {
-			slog.DebugContext(ctx, "Refreshing system extensions")
+		newAppInfo := s.Applications[app.Name()]
+
+		// Record the staged application and save state to disk.
+		if stageOnly {
+			slog.InfoContext(ctx, "Staged application update", "application", app.Name(), "release", app.Version())
+
+			newAppInfo.State.StagedVersion = app.Version()
+
+			s.SetApplication(app.Name(), newAppInfo)
+			_ = s.Save()
 
-			err = systemd.RefreshExtensions(ctx)
-			if err != nil {
-				s.System.Update.State.Status = "Failed to refresh system extensions"
-				showModalError(s.System.Update.State.Status, err)
+			recordAvailableUpdate(s, p, app.Name(), app.Version(), app.Size(), true)
 
-				if isStartupCheck || isUserRequested {
-					break
-				}
+			reverter.Success()
 
-				continue
-			}
+			return "", nil
 		}
 
-		// Check for the latest OS update.
-		newInstalledOSVersion, err := checkDoOSUpdate(ctx, s, t, p, isStartupCheck)
-		if err != nil {
-			s.System.Update.State.Status = "Failed to check for OS updates"
-			showModalError(s.System.Update.State.Status, err)
-
-			if isStartupCheck || isUserRequested {
-				break
+		// Let the application prepare for the update, before it's activated.
+		if newAppInfo.State.Version != "" {
+			err = applications.RunHook(ctx, s, app.Name(), applications.HookPreUpdate, app.Version())
+			if err != nil {
+				return "", err
 			}
+		}
 
-			continue
+		// Drop any previously staged update.
+		if newAppInfo.State.StagedVersion != "" {
+			_ = os.Remove(filepath.Join(systemd.SystemExtensionsStagingPath, app.Name()+".raw"))
+			newAppInfo.State.StagedVersion = ""
 		}
 
-		// Notify the applications that they need to update/restart.
-		for appName, appVersion := range appsUpdated {
-			// Get the application.
-			app, err := applications.Load(ctx, s, appName)
-			if err != nil {
-				s.System.Update.State.Status = "Failed to load application"
-				showModalError(s.System.Update.State.Status, err)
+		// Record newly installed application and save state to disk.
+		newAppInfo.State.PreviousVersion = newAppInfo.State.Version
+		newAppInfo.State.Version = app.Version()
+		markProvenanceInstalled(ctx, s, app.Name(), app.Version())
 
-				continue
-			}
+		s.SetApplication(app.Name(), newAppInfo)
+		_ = s.Save()
 
-			// Start/reload the application.
-			if !isStartupCheck {
-				if app.IsRunning(ctx) {
-					slog.InfoContext(ctx, "Reloading application", "name", appName, "version", appVersion)
+		clearAvailableUpdate(s, app.Name())
 
-					err := app.Update(ctx, appVersion)
-					if err != nil {
-						s.System.Update.State.Status = "Failed to reload application"
-						showModalError(s.System.Update.State.Status, err)
+		reverter.Success()
 
-						continue
-					}
-				} else {
-					err := startInitializeApplication(ctx, s, appName)
-					if err != nil {
-						s.System.Update.State.Status = "Failed to start application"
-						showModalError(s.System.Update.State.Status, err)
+		return app.Version(), nil
+	} else if isStartupCheck {
+		slog.DebugContext(ctx, "System is already running latest application release", "application", app.Name(), "release", app.Version())
+	}
 
-						continue
-					}
-				}
-			}
-		}
+	clearAvailableUpdate(s, app.Name())
 
-		if newInstalledOSVersion != "" {
-			if updateModal == nil {
-				updateModal = t.AddModal(s.OS.Name + " Update")
-			}
+	return "", nil
+}
 
-			s.System.Update.State.Status = s.OS.Name + " has been updated to version " + newInstalledOSVersion
-			updateModal.Update(s.OS.Name + " has been updated to version " + newInstalledOSVersion + ".\nPlease reboot the system to finalize update.")
+// notifyRollback sends a notification if an application update was rolled back.
+func notifyRollback(ctx context.Context, s *state.State, appName string, err error) {
+	if !errors.Is(err, applications.ErrUpdateRolledBack) {
+		return
+	}
 
-			s.System.Update.State.NeedsReboot = true
-		} else {
-			s.System.Update.State.Status = "Update check completed"
-		}
+	notify.Send(ctx, s, api.SystemNotificationsEventUpdateFailed, "Application "+appName+" failed to update and was rolled back: "+err.Error())
+}
 
-		if isStartupCheck || isUserRequested {
-			// If running a one-time update, we're done.
-			break
+// recordProviderResult updates the provider state following a request to the provider.
+func recordProviderResult(s *state.State, err error) {
+	if err != nil && !errors.Is(err, providers.ErrNoUpdateAvailable) {
+		s.System.Provider.State.LastError = err.Error()
+
+		var rateLimitErr *providers.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			s.System.Provider.State.RateLimitedUntil = rateLimitErr.RetryAt
 		}
+
+		return
 	}
-}
 
-func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool) (string, error) {
-	s.UpdateMutex.Lock()
-	defer s.UpdateMutex.Unlock()
+	s.System.Provider.State.LastCheck = time.Now()
+	s.System.Provider.State.LastError = ""
+	s.System.Provider.State.RateLimitedUntil = time.Time{}
+}
 
-	slog.DebugContext(ctx, "Checking for OS updates")
+// errMeteredQuotaExceeded is returned when a download would exceed the monthly metered quota.
+var errMeteredQuotaExceeded = errors.New("monthly metered quota exceeded")
 
-	if s.System.Update.State.NeedsReboot {
-		slog.DebugContext(ctx, "A reboot of the system is required to finalize a pending update")
+// checkMeteredQuota returns an error if downloading the given amount of data from the provider would exceed the
+// configured monthly quota.
+func checkMeteredQuota(s *state.State, p providers.Provider, size int64) error {
+	if s.System.Update.Config.MeteredQuota == "" {
+		return nil
 	}
 
-	update, err := p.GetOSUpdate(ctx)
+	quota, err := units.ParseByteSizeString(s.System.Update.Config.MeteredQuota)
 	if err != nil {
-		if errors.Is(err, providers.ErrNoUpdateAvailable) {
-			slog.DebugContext(ctx, "OS update provider doesn't currently have any update")
+		return err
+	}
 
-			return "", nil
-		}
+	used := s.MonthlyDataUsage(p.Type())
+	if used+size > quota {
+		return fmt.Errorf("%w: downloading %s would exceed the quota of %s (%s used)", errMeteredQuotaExceeded, units.GetByteSizeStringIEC(size, 2), s.System.Update.Config.MeteredQuota, units.GetByteSizeStringIEC(used, 2))
+	}
 
-		return "", err
+	return nil
+}
+
+// recordDataUsage accounts for data downloaded from a provider, other than the local one.
+func recordDataUsage(s *state.State, p providers.Provider, size int64) {
+	if p.Type() == "local" {
+		return
 	}
 
-	// If we're running from the backup image don't attempt to re-update to a broken version.
-	if !s.System.Update.State.NeedsReboot && s.OS.NextRelease != "" && s.OS.RunningRelease != s.OS.NextRelease && s.OS.NextRelease == update.Version() {
-		slog.WarnContext(ctx, "Latest "+s.OS.Name+" image version "+s.OS.NextRelease+" has been identified as problematic, skipping update")
+	s.RecordDataUsage(p.Type(), size)
+	_ = s.Save()
+}
 
-		return "", nil
+// markProvenanceInstalled records when a downloaded update got applied.
+func markProvenanceInstalled(ctx context.Context, s *state.State, component string, version string) {
+	err := s.MarkProvenanceInstalled(component, version, time.Now())
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record the update provenance", "component", component, "version", version, "err", err.Error())
 	}
+}
 
-	// Skip any update that isn't newer than what we are already running.
-	if s.OS.RunningRelease != update.Version() && !update.IsNewerThan(s.OS.RunningRelease) {
-		return "", errors.New("local " + s.OS.Name + " version (" + s.OS.RunningRelease + ") is newer than available update (" + update.Version() + "); skipping")
+// recordAvailableUpdate records an update which is available but not yet applied, along with estimates of what it
+// will take to download and apply it.
+func recordAvailableUpdate(s *state.State, p providers.Provider, component string, version string, size int64, staged bool) {
+	// Only announce updates which weren't already known.
+	known := slices.ContainsFunc(s.System.Update.State.Available, func(available api.SystemUpdateAvailable) bool {
+		return available.Component == component && available.Version == version
+	})
+
+	if !known {
+		s.Events.SendLifecycle(api.EventLifecycleUpdateAvailable, "/1.0/system/update", map[string]any{"component": component, "version": version, "size": size})
 	}
 
-	// Apply the update.
-	if update.Version() != s.OS.RunningRelease && update.Version() != s.OS.NextRelease {
-		// Download the update into place.
-		modal := t.AddModal(s.OS.Name + " Update")
-		defer modal.Done()
+	clearAvailableUpdate(s, component)
 
-		slog.InfoContext(ctx, "Downloading OS update", "release", update.Version())
-		modal.Update("Downloading " + s.OS.Name + " update version " + update.Version())
+	s.System.Update.State.Available = append(s.System.Update.State.Available, api.SystemUpdateAvailable{
+		Component:    component,
+		Version:      version,
+		Staged:       staged,
+		DownloadSize: size,
+		StagingSize:  storage.RequiredSpace(storage.DecompressedSize(size, providers.ServesCompressedFiles(p))),
+		ApplyTime:    s.UpdateApplyTimes[component],
+	})
+}
 
-		err := update.DownloadUpdate(ctx, systemd.SystemUpdatesPath, modal.UpdateProgress)
-		if err != nil {
-			return "", err
-		}
+// clearAvailableUpdate removes any available update for the given component.
+func clearAvailableUpdate(s *state.State, component string) {
+	s.System.Update.State.Available = slices.DeleteFunc(s.System.Update.State.Available, func(available api.SystemUpdateAvailable) bool {
+		return available.Component == component
+	})
+}
 
-		// Hide the progress bar.
-		modal.UpdateProgress(0.0)
+// trackUpdateProgress records the start of an update download and returns a function reporting its
+// progress both in the TUI and through the update state exposed by the API.
+func trackUpdateProgress(s *state.State, modal *tui.Modal, component string, version string, total int64) func(providers.DownloadProgress) {
+	start := time.Now()
+	files := map[string]int64{}
+	lastPercentage := -1
+
+	s.SetUpdateProgress(&api.SystemUpdateProgress{
+		Phase:      api.SystemUpdateProgressPhaseDownloading,
+		Component:  component,
+		Version:    version,
+		BytesTotal: total,
+	})
+
+	return func(progress providers.DownloadProgress) {
+		modal.UpdateProgress(progress.Progress)
+
+		// Sum up what has been transferred for each of the update's files so far.
+		files[progress.File] = int64(float64(progress.Size) * progress.Progress)
+
+		transferred := int64(0)
+		for _, size := range files {
+			transferred += size
+		}
 
-		// Record the release. Need to do it here, since if the system reboots as part of the
-		// update we won't be able to save the state to disk.
-		priorNextRelease := s.OS.NextRelease
-		s.OS.NextRelease = update.Version()
-		_ = s.Save()
+		newProgress := &api.SystemUpdateProgress{
+			Phase:            api.SystemUpdateProgressPhaseDownloading,
+			Component:        component,
+			Version:          version,
+			File:             progress.File,
+			FilePercentage:   int(progress.Progress * 100),
+			BytesTransferred: transferred,
+			BytesTotal:       total,
+		}
 
-		// Apply the update and reboot if first time through loop, otherwise wait for user to reboot system.
-		slog.InfoContext(ctx, "Applying OS update", "release", update.Version())
-		modal.Update("Applying " + s.OS.Name + " update version " + update.Version())
+		// Estimate the remaining time based on the average transfer rate so far.
+		if transferred > 0 && total > transferred {
+			newProgress.ETA = int64(time.Since(start).Seconds() * float64(total-transferred) / float64(transferred))
+		}
 
-		err = systemd.ApplySystemUpdate(ctx, s.System.Security.Config.EncryptionRecoveryKeys[0], update.Version(), s.System.Update.Config.AutoReboot || isStartupCheck)
-		if err != nil {
-			s.OS.NextRelease = priorNextRelease
-			_ = s.Save()
+		s.SetUpdateProgress(newProgress)
 
-			return "", err
+		// Only send an event when the overall percentage changes, to avoid flooding subscribers.
+		percentage := 0
+		if total > 0 {
+			percentage = int(min(transferred*100/total, 100))
 		}
 
-		// Record the state of auto-unlocked LUKS devices. With some TPMs this can be slow, so cache the
-		// result after applying an OS update rather than needing to determine it each time a request
-		// arrives via the API.
-		s.System.Security.State.EncryptedVolumes, err = systemd.ListEncryptedVolumes(ctx)
-		if err != nil {
-			s.OS.NextRelease = priorNextRelease
-			_ = s.Save()
+		if percentage != lastPercentage {
+			lastPercentage = percentage
 
-			return "", err
+			s.Events.SendLifecycle(api.EventLifecycleUpdateDownloadProgress, "/1.0/system/update", map[string]any{
+				"component":  component,
+				"version":    version,
+				"percentage": percentage,
+				"eta":        newProgress.ETA,
+			})
 		}
+	}
+}
 
-		return update.Version(), nil
-	} else if isStartupCheck {
-		slog.DebugContext(ctx, "System is already running latest OS release", "release", s.OS.RunningRelease)
+// setUpdateProgressPhase updates the phase of an in-progress update.
+func setUpdateProgressPhase(s *state.State, phase api.SystemUpdateProgressPhase, component string, version string) {
+	newProgress := api.SystemUpdateProgress{
+		Component: component,
+		Version:   version,
 	}
 
-	return "", nil
+	// Keep the download details if this is the same update.
+	currentProgress := s.UpdateProgress()
+	if currentProgress != nil && currentProgress.Component == component && currentProgress.Version == version {
+		newProgress = *currentProgress
+		newProgress.ETA = 0
+	}
+
+	newProgress.Phase = phase
+	s.SetUpdateProgress(&newProgress)
+}
+
+// clearUpdateProgress clears the progress once an update has completed or failed.
+func clearUpdateProgress(s *state.State) {
+	s.SetUpdateProgress(nil)
 }
 
-func checkDoAppUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, appName string, isStartupCheck bool) (string, error) {
+// applyStagedUpdates applies any application and OS updates previously downloaded in download-only mode.
+func applyStagedUpdates(ctx context.Context, s *state.State, t *tui.TUI) error {
 	s.UpdateMutex.Lock()
 	defer s.UpdateMutex.Unlock()
 
-	slog.DebugContext(ctx, "Checking for application updates")
-
-	app, err := p.GetApplication(ctx, appName)
+	// Don't update a system whose disk integrity is degraded.
+	err := checkSystemDiskHealth(ctx, s)
 	if err != nil {
-		if errors.Is(err, providers.ErrNoUpdateAvailable) {
-			slog.DebugContext(ctx, "Application update provider doesn't currently have any update")
-
-			return "", nil
-		}
+		s.System.Update.State.Status = "Updates blocked due to degraded system disk"
 
-		return "", err
+		return err
 	}
 
-	// Apply the update.
-	if app.Version() != s.Applications[app.Name()].State.Version {
-		if s.Applications[app.Name()].State.Version != "" && !app.IsNewerThan(s.Applications[app.Name()].State.Version) {
-			return "", errors.New("local application " + app.Name() + " version (" + s.Applications[app.Name()].State.Version + ") is newer than available update (" + app.Version() + "); skipping")
+	s.System.Update.State.Status = "Applying staged updates"
+
+	// Move any staged applications into place.
+	appsUpdated := map[string]string{}
+
+	for appName, appInfo := range s.Applications {
+		if appInfo.State.StagedVersion == "" {
+			continue
 		}
 
-		// Download the application.
-		modal := t.AddModal(s.OS.Name + " Update")
-		defer modal.Done()
+		slog.InfoContext(ctx, "Applying staged application update", "application", appName, "release", appInfo.State.StagedVersion)
 
-		slog.InfoContext(ctx, "Downloading application", "application", app.Name(), "release", app.Version())
-		modal.Update("Downloading application " + app.Name() + " update " + app.Version())
+		err := applications.RunHook(ctx, s, appName, applications.HookPreUpdate, appInfo.State.StagedVersion)
+		if err != nil {
+			return err
+		}
 
-		err = app.Download(ctx, systemd.SystemExtensionsPath, modal.UpdateProgress)
+		_, err = applications.KeepPrevious(appName)
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		// Verify the application is signed with a trusted key in the kernel's keyring.
-		err = systemd.VerifyExtensionCertificateFingerprint(ctx, filepath.Join(systemd.SystemExtensionsPath, app.Name()+".raw"))
+		err = os.Rename(filepath.Join(systemd.SystemExtensionsStagingPath, appName+".raw"), filepath.Join(systemd.SystemExtensionsPath, appName+".raw"))
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		// Record newly installed application and save state to disk.
-		newAppInfo := s.Applications[app.Name()]
-		newAppInfo.State.Version = app.Version()
+		appsUpdated[appName] = appInfo.State.StagedVersion
 
-		s.Applications[app.Name()] = newAppInfo
+		appInfo.State.PreviousVersion = appInfo.State.Version
+		appInfo.State.Version = appInfo.State.StagedVersion
+		appInfo.State.StagedVersion = ""
+		markProvenanceInstalled(ctx, s, appName, appInfo.State.Version)
+		s.SetApplication(appName, appInfo)
 		_ = s.Save()
 
-		return app.Version(), nil
-	} else if isStartupCheck {
-		slog.DebugContext(ctx, "System is already running latest application release", "application", app.Name(), "release", app.Version())
+		clearAvailableUpdate(s, appName)
 	}
 
-	return "", nil
+	// Apply the system extensions and notify the applications.
+	if len(appsUpdated) > 0 {
+		err := systemd.RefreshExtensions(ctx)
+		if err != nil {
+			return err
+		}
+
+		for appName, appVersion := range appsUpdated {
+			app, err := applications.Load(ctx, s, appName)
+			if err != nil {
+				return err
+			}
+
+			if app.IsRunning(ctx) {
+				slog.InfoContext(ctx, "Reloading application", "name", appName, "version", appVersion)
+
+				start := time.Now()
+
+				err = applications.UpdateWithRollback(ctx, s, appName, appVersion, applications.UpdateHealthTimeout)
+				if err == nil {
+					s.RecordUpdateApplyTime(appName, time.Since(start))
+				}
+
+				notifyRollback(ctx, s, appName, err)
+			} else {
+				err = startInitializeApplication(ctx, s, appName)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Apply the staged OS update.
+	if s.OS.StagedRelease != "" {
+		version := s.OS.StagedRelease
+
+		if updateModal == nil {
+			updateModal = t.AddModal(s.OS.Name + " Update")
+		}
+
+		err := applyOSUpdate(ctx, s, updateModal, version, s.System.Update.Config.AutoReboot)
+		if err != nil {
+			return err
+		}
+
+		s.System.Update.State.NeedsReboot = true
+		s.System.Update.State.Status = s.OS.Name + " has been updated to version " + version
+		s.Events.SendLifecycle(api.EventLifecycleRebootPending, "/1.0/system/update", map[string]any{"version": version})
+		updateModal.Update(s.OS.Name + " has been updated to version " + version + ".\nPlease reboot the system to finalize update.")
+
+		return nil
+	}
+
+	s.System.Update.State.Status = "Staged updates applied"
+
+	return nil
 }
 
 func checkDoSecureBootCertUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool) error {
@@ -946,6 +2400,8 @@ func checkDoSecureBootCertUpdate(ctx context.Context, s *state.State, t *tui.TUI
 	}
 
 	update, err := p.GetSecureBootCertUpdate(ctx)
+	recordProviderResult(s, err)
+
 	if err != nil {
 		if errors.Is(err, providers.ErrNoUpdateAvailable) {
 			slog.DebugContext(ctx, "Secure Boot key update provider doesn't currently have any update")
@@ -998,6 +2454,7 @@ func checkDoSecureBootCertUpdate(ctx context.Context, s *state.State, t *tui.TUI
 		// for the user to restart the system before going any further.
 		if needsReboot {
 			s.System.Update.State.NeedsReboot = true
+			s.Events.SendLifecycle(api.EventLifecycleRebootPending, "/1.0/system/update", map[string]any{"secureboot_version": update.Version()})
 
 			if isStartupCheck {
 				slog.InfoContext(ctx, "Automatically rebooting system in five seconds.")
@@ -1029,6 +2486,68 @@ func checkDoSecureBootCertUpdate(ctx context.Context, s *state.State, t *tui.TUI
 	return nil
 }
 
+// checkDoSecureBootDbxUpdate applies any new dbx revocation list update offered by the provider.
+// Unlike Secure Boot key updates, the system isn't rebooted automatically as the new revocations
+// take effect on the next boot anyway.
+func checkDoSecureBootDbxUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider) error {
+	s.UpdateMutex.Lock()
+	defer s.UpdateMutex.Unlock()
+
+	slog.DebugContext(ctx, "Checking for Secure Boot dbx updates")
+
+	// Don't stack a dbx update on top of a pending Secure Boot key update.
+	if s.System.Update.State.NeedsReboot || (s.SecureBoot.Version != "" && !s.SecureBoot.FullyApplied) {
+		return nil
+	}
+
+	update, err := p.GetSecureBootDbxUpdate(ctx)
+	recordProviderResult(s, err)
+
+	if err != nil {
+		if errors.Is(err, providers.ErrNoUpdateAvailable) {
+			slog.DebugContext(ctx, "Secure Boot dbx update provider doesn't currently have any update")
+
+			return nil
+		}
+
+		return err
+	}
+
+	if update.Version() == s.SecureBoot.DbxVersion || (s.SecureBoot.DbxVersion != "" && !update.IsNewerThan(s.SecureBoot.DbxVersion)) {
+		slog.DebugContext(ctx, "System Secure Boot dbx is up to date")
+
+		return nil
+	}
+
+	err = update.Download(ctx, varPath)
+	if err != nil {
+		return err
+	}
+
+	authFilepath := filepath.Join(varPath, update.GetFilename())
+	defer func() { _ = os.Remove(authFilepath) }()
+
+	modal := t.AddModal(s.OS.Name + " EFI Variable Update")
+	defer modal.Done()
+
+	slog.InfoContext(ctx, "Applying Secure Boot dbx update version "+update.Version()+".")
+	modal.Update("Applying Secure Boot dbx update version " + update.Version() + ".")
+
+	applied, err := secureboot.UpdateSecureBootDbx(ctx, authFilepath)
+	if err != nil {
+		return err
+	}
+
+	s.SecureBoot.DbxVersion = update.Version()
+	_ = s.Save()
+
+	if applied {
+		slog.InfoContext(ctx, "Secure Boot dbx update will take effect on next reboot", "release", update.Version())
+	}
+
+	return nil
+}
+
 func setTimezone(ctx context.Context) error {
 	// Get the network seed.
 	config, err := seed.GetNetwork(ctx)
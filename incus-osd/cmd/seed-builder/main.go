@@ -0,0 +1,356 @@
+// Package main is used for the seed builder, which turns a directory of seed files into seed media.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+const (
+	formatTar = "tar"
+	formatFAT = "fat"
+	formatISO = "iso"
+
+	// seedLabel is the label IncusOS looks for on user-provided seed media.
+	seedLabel = "SEED_DATA"
+)
+
+// seedTypes maps the name of each recognized seed file to the structure it's parsed into.
+var seedTypes = map[string]func() any{
+	"applications":      func() any { return &apiseed.Applications{} },
+	"ceph":              func() any { return &apiseed.Ceph{} },
+	"incus":             func() any { return &apiseed.Incus{} },
+	"install":           func() any { return &apiseed.Install{} },
+	"migration-manager": func() any { return &apiseed.MigrationManager{} },
+	"network":           func() any { return &apiseed.Network{} },
+	"nftables":          func() any { return &apiseed.Nftables{} },
+	"ntp":               func() any { return &apiseed.NTP{} },
+	"operations-center": func() any { return &apiseed.OperationsCenter{} },
+	"provider":          func() any { return &apiseed.Provider{} },
+	"security":          func() any { return &apiseed.Security{} },
+	"ssh":               func() any { return &apiseed.SSH{} },
+	"tuning":            func() any { return &apiseed.Tuning{} },
+	"vrrp":              func() any { return &apiseed.VRRP{} },
+}
+
+type cmdBuild struct {
+	flagFormat string
+}
+
+// seedFile is a validated seed file, as named in the source directory.
+type seedFile struct {
+	name    string
+	content []byte
+}
+
+func main() {
+	c := cmdBuild{}
+
+	app := &cobra.Command{}
+	app.Use = "seed-builder <source directory> <output>"
+	app.Short = "Builds IncusOS seed media"
+	app.Long = `Description:
+  Build IncusOS seed media
+
+  This tool validates a directory of JSON or YAML seed files and writes them
+  as a tar archive, to be written to the seed partition of an install image,
+  or as a FAT or ISO image labeled SEED_DATA.
+
+  The format is guessed from the output file extension (.tar, .img or .iso)
+  unless --format is provided.
+`
+	app.Args = cobra.ExactArgs(2)
+	app.RunE = c.run
+	app.SilenceUsage = true
+	app.CompletionOptions = cobra.CompletionOptions{DisableDefaultCmd: true}
+	app.Flags().StringVar(&c.flagFormat, "format", "", "Output format (tar, fat or iso)")
+
+	err := app.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func (c *cmdBuild) run(cmd *cobra.Command, args []string) error {
+	format := c.flagFormat
+	if format == "" {
+		switch filepath.Ext(args[1]) {
+		case ".tar":
+			format = formatTar
+		case ".img":
+			format = formatFAT
+		case ".iso":
+			format = formatISO
+		default:
+			return errors.New("unable to guess the output format, please provide --format")
+		}
+	}
+
+	files, err := readSeeds(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatTar:
+		err = writeTar(args[1], files)
+	case formatFAT:
+		err = writeFAT(cmd.Context(), args[1], files)
+	case formatISO:
+		err = writeISO(cmd.Context(), args[1], files)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d seed files to %q\n", len(files), args[1])
+
+	return nil
+}
+
+// readSeeds reads and validates all the seed files from the source directory.
+func readSeeds(path string) ([]seedFile, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []seedFile{}
+	seen := map[string]string{}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !slices.Contains([]string{".json", ".yaml", ".yml"}, ext) {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ext)
+
+		newSeed, ok := seedTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown seed file %q", entry.Name())
+		}
+
+		// IncusOS would only use one of them.
+		previous, ok := seen[name]
+		if ok {
+			return nil, fmt.Errorf("seed files %q and %q conflict", previous, entry.Name())
+		}
+
+		seen[name] = entry.Name()
+
+		content, err := os.ReadFile(filepath.Join(path, entry.Name())) //nolint:gosec
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateSeed(content, ext, newSeed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed file %q: %w", entry.Name(), err)
+		}
+
+		files = append(files, seedFile{name: entry.Name(), content: content})
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no seed files found in %q", path)
+	}
+
+	return files, nil
+}
+
+// validateSeed checks that each document of the content can be parsed into the seed structure, rejecting
+// unknown fields. Per-host overrides are validated without their "match" criteria.
+func validateSeed(content []byte, ext string, newSeed func() any) error {
+	// An empty file is valid, for example to trigger an install with the default options.
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil
+	}
+
+	var decode func(any) error
+
+	if ext == ".json" {
+		decode = json.NewDecoder(bytes.NewReader(content)).Decode
+	} else {
+		decode = yaml.NewDecoder(bytes.NewReader(content)).Decode
+	}
+
+	for i := 1; ; i++ {
+		doc := map[string]any{}
+
+		err := decode(&doc)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		delete(doc, "match")
+
+		err = validateDocument(doc, ext, newSeed())
+		if err != nil {
+			return fmt.Errorf("document %d: %w", i, err)
+		}
+	}
+}
+
+// validateDocument checks that a single document can be parsed into the seed structure, rejecting unknown fields.
+func validateDocument(doc map[string]any, ext string, target any) error {
+	if ext == ".json" {
+		content, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+
+		return decoder.Decode(target)
+	}
+
+	content, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	return decoder.Decode(target)
+}
+
+// writeTar writes the seed files as a tar archive.
+func writeTar(path string, files []seedFile) error {
+	f, err := os.Create(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name: file.name,
+			Mode: 0o600,
+			Size: int64(len(file.content)),
+		}
+
+		err := tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		_, err = tw.Write(file.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// writeFAT writes the seed files to a FAT image, using mkfs.vfat and mcopy.
+func writeFAT(ctx context.Context, path string, files []seedFile) error {
+	tmpDir, err := stageSeeds(files)
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	// Size the image for the content, with enough room for the file system metadata.
+	size := 0
+	for _, file := range files {
+		size += len(file.content)
+	}
+
+	sizeKiB := max(1024, 2*size/1024+512)
+
+	// mkfs.vfat refuses to overwrite an existing image.
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "mkfs.vfat", "-n", seedLabel, "-C", path, fmt.Sprintf("%d", sizeKiB))
+	if err != nil {
+		return fmt.Errorf("failed to create FAT image: %w", err)
+	}
+
+	for _, file := range files {
+		_, err = subprocess.RunCommandContext(ctx, "mcopy", "-i", path, filepath.Join(tmpDir, file.name), "::"+file.name)
+		if err != nil {
+			return fmt.Errorf("failed to copy %q to FAT image: %w", file.name, err)
+		}
+	}
+
+	return nil
+}
+
+// writeISO writes the seed files to an ISO image, using xorriso.
+func writeISO(ctx context.Context, path string, files []seedFile) error {
+	tmpDir, err := stageSeeds(files)
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "xorriso", "-as", "mkisofs", "-V", seedLabel, "-J", "-r", "-o", path, tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to create ISO image: %w", err)
+	}
+
+	return nil
+}
+
+// stageSeeds writes the seed files to a temporary directory, to be copied into an image.
+func stageSeeds(files []seedFile) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "incus-os-seed")
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		err := os.WriteFile(filepath.Join(tmpDir, file.name), file.content, 0o600)
+		if err != nil {
+			_ = os.RemoveAll(tmpDir)
+
+			return "", err
+		}
+	}
+
+	return tmpDir, nil
+}
@@ -50,6 +50,11 @@ func (*common) GetDependencies() []string {
 	return nil
 }
 
+// HealthCheck checks whether the application is working properly.
+func (*common) HealthCheck(_ context.Context) error {
+	return ErrHealthCheckNotSupported
+}
+
 // Initialize runs first time initialization.
 func (*common) Initialize(_ context.Context) error {
 	return nil
@@ -60,6 +65,11 @@ func (*common) Restart(_ context.Context, _ string) error {
 	return nil
 }
 
+// SetCertificate replaces the server certificate for the application.
+func (*common) SetCertificate(_ context.Context, _ []byte, _ []byte) error {
+	return errors.New("not supported")
+}
+
 // Start runs startup action.
 func (*common) Start(_ context.Context, _ string) error {
 	return nil
@@ -179,6 +189,43 @@ func doRequest(ctx context.Context, socket string, url string, method string, bo
 	return ret, nil
 }
 
+// Common helper to replace the server certificate and key of an application, then restart its unit(s).
+func replaceServerCertificate(ctx context.Context, dir string, restartUnits []string, cert []byte, key []byte) error {
+	// Validate the keypair before touching anything.
+	_, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return err
+	}
+
+	// Write the new files next to the existing ones.
+	for filename, content := range map[string][]byte{"server.crt": cert, "server.key": key} {
+		err = os.WriteFile(filepath.Join(dir, filename+".new"), content, 0o600)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Stop the application while the files are swapped, so it never loads a mismatched keypair.
+	err = systemd.StopUnit(ctx, restartUnits...)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range []string{"server.key", "server.crt"} {
+		path := filepath.Join(dir, filename)
+
+		err = os.Rename(path+".new", path)
+		if err != nil {
+			// Bring the application back up, even if the certificate couldn't be fully replaced.
+			_ = systemd.StartUnit(ctx, restartUnits...)
+
+			return err
+		}
+	}
+
+	return systemd.StartUnit(ctx, restartUnits...)
+}
+
 // Comment helper to compute the SHA256 fingerprint of a PEM-encoded certificate.
 func getCertificateFingerprint(certificate string) (string, error) {
 	certBlock, _ := pem.Decode([]byte(certificate))
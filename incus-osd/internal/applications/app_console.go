@@ -0,0 +1,267 @@
+package applications
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	incustls "github.com/lxc/incus/v6/shared/tls"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+const (
+	consoleDataPath = "/var/lib/console/"
+	consoleRunPath  = "/run/console/"
+	consoleUnit     = "console.service"
+)
+
+type console struct {
+	common
+}
+
+// getOtherPrimary returns the name of the installed primary application, other than the console.
+func getOtherPrimary(ctx context.Context, s *state.State) string {
+	for appName := range s.Applications {
+		if appName == "console" {
+			continue
+		}
+
+		app, err := Load(ctx, s, appName)
+		if err == nil && app.IsPrimary() {
+			return appName
+		}
+	}
+
+	return ""
+}
+
+// writeConfig generates the server certificate if missing, then writes the environment file read by the unit.
+// When another primary application is installed, the console runs alongside it on a separate port and
+// relies on it for network access to IncusOS. Otherwise, it acts as the primary application.
+func (a *console) writeConfig(ctx context.Context) error {
+	err := os.MkdirAll(consoleDataPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(filepath.Join(consoleDataPath, "server.crt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		certPEM, keyPEM, err := incustls.GenerateMemCert(false, true)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(consoleDataPath, "server.key"), keyPEM, 0o600)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(consoleDataPath, "server.crt"), certPEM, 0o600)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(consoleRunPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	env := "CONSOLE_MODE=primary\nCONSOLE_LISTEN=[::]:8443\n"
+
+	primary := getOtherPrimary(ctx, a.state)
+	if primary != "" {
+		env = "CONSOLE_MODE=secondary\nCONSOLE_LISTEN=[::]:8444\nCONSOLE_PRIMARY=" + primary + "\n"
+	}
+
+	return os.WriteFile(filepath.Join(consoleRunPath, "console.env"), []byte(env), 0o600)
+}
+
+// Start starts the systemd unit.
+func (a *console) Start(ctx context.Context, _ string) error {
+	err := a.writeConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	return systemd.EnableUnit(ctx, true, consoleUnit)
+}
+
+// Stop stops the systemd unit.
+func (*console) Stop(ctx context.Context, _ string) error {
+	return systemd.StopUnit(ctx, consoleUnit)
+}
+
+// Restart restarts the systemd unit.
+func (a *console) Restart(ctx context.Context, _ string) error {
+	err := a.writeConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	return systemd.RestartUnit(ctx, consoleUnit)
+}
+
+// Update triggers restart after an application update.
+func (a *console) Update(ctx context.Context, version string) error {
+	// Reload the systemd daemon to pickup any service definition changes.
+	err := systemd.ReloadDaemon(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.Restart(ctx, version)
+}
+
+// Initialize runs first time initialization.
+func (*console) Initialize(ctx context.Context) error {
+	// Wait for the console to begin accepting connections.
+	count := 0
+
+	for {
+		_, err := doRequest(ctx, filepath.Join(consoleRunPath, "unix.socket"), "http://localhost/1.0", http.MethodGet, nil)
+		if err == nil {
+			return nil
+		}
+
+		count++
+
+		if count > 10 {
+			return errors.New("failed to connect to the console via local socket")
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// IsRunning reports if the application is currently running.
+func (*console) IsRunning(ctx context.Context) bool {
+	return systemd.IsActive(ctx, consoleUnit)
+}
+
+// HealthCheck checks that the console is running and its API responds.
+func (*console) HealthCheck(ctx context.Context) error {
+	return checkAPIHealth(ctx, filepath.Join(consoleRunPath, "unix.socket"), consoleUnit)
+}
+
+// IsPrimary reports if the application is a primary application, which is only the case when no
+// other primary application is installed.
+func (a *console) IsPrimary() bool {
+	return getOtherPrimary(context.Background(), a.state) == ""
+}
+
+// GetCertificate returns the keypair for the server certificate.
+func (*console) GetCertificate() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(consoleDataPath, "server.crt"), filepath.Join(consoleDataPath, "server.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+// SetCertificate replaces the server certificate and restarts the application.
+func (*console) SetCertificate(ctx context.Context, cert []byte, key []byte) error {
+	return replaceServerCertificate(ctx, consoleDataPath, []string{consoleUnit}, cert, key)
+}
+
+// AddTrustedCertificate adds a new trusted client certificate to the console.
+func (*console) AddTrustedCertificate(ctx context.Context, name string, cert string) error {
+	// Validate the certificate.
+	_, err := getCertificateFingerprint(cert)
+	if err != nil {
+		return err
+	}
+
+	trustedPath := filepath.Join(consoleDataPath, "trusted")
+
+	err = os.MkdirAll(trustedPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	certFile := filepath.Join(trustedPath, filepath.Base(name)+".crt")
+
+	_, err = os.Stat(certFile)
+	if err == nil {
+		return fmt.Errorf("client certificate %q is already trusted", name)
+	}
+
+	err = os.WriteFile(certFile, []byte(cert), 0o600)
+	if err != nil {
+		return err
+	}
+
+	// The console only reads its trusted certificates on startup.
+	if !systemd.IsActive(ctx, consoleUnit) {
+		return nil
+	}
+
+	return systemd.RestartUnit(ctx, consoleUnit)
+}
+
+// FactoryReset performs a full factory reset of the application.
+func (a *console) FactoryReset(ctx context.Context) error {
+	err := a.Stop(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	err = a.WipeLocalData()
+	if err != nil {
+		return err
+	}
+
+	err = a.Start(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	return a.Initialize(ctx)
+}
+
+// WipeLocalData removes local data created by the application.
+func (*console) WipeLocalData() error {
+	return os.RemoveAll(consoleDataPath)
+}
+
+// GetBackup returns a tar archive backup of the application's configuration and/or state.
+func (*console) GetBackup(archive io.Writer, _ bool) error {
+	return createTarArchive(consoleDataPath, nil, archive)
+}
+
+// RestoreBackup restores a tar archive backup of the application's configuration and/or state.
+func (*console) RestoreBackup(ctx context.Context, archive io.Reader) error {
+	return extractTarArchive(ctx, consoleDataPath, []string{consoleUnit}, archive)
+}
+
+// RefreshConsole restarts the console, if it's running, so it switches between acting as the primary
+// application or running alongside another one. This must be called before starting a new primary
+// application and after removing one.
+func RefreshConsole(ctx context.Context, s *state.State) error {
+	_, ok := s.Applications["console"]
+	if !ok {
+		return nil
+	}
+
+	app := &console{common: common{state: s}}
+	if !app.IsRunning(ctx) {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Restarting the console to update its mode")
+
+	return app.Restart(ctx, s.Applications["console"].State.Version)
+}
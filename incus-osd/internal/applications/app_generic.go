@@ -0,0 +1,398 @@
+package applications
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// manifestsPath is where applications ship their manifest, once their system extension is merged.
+var manifestsPath = "/usr/lib/incus-osd/applications/"
+
+// applicationNameRegexp matches the valid names of generic applications.
+var applicationNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// manifest describes how IncusOS manages an application which doesn't have any specific logic.
+type manifest struct {
+	Primary      bool     `yaml:"primary"`
+	Dependencies []string `yaml:"dependencies"`
+	Units        []string `yaml:"units"`  // Systemd units started, stopped and restarted with the application.
+	Socket       string   `yaml:"socket"` // Local socket which must accept connections once the application is up.
+
+	// Directory holding the application's server.crt and server.key.
+	CertificatePath string `yaml:"certificate_path"`
+
+	// Paths removed when wiping the application's local data.
+	DataPaths []string `yaml:"data_paths"`
+
+	// File the application-specific settings are written to, as a JSON object.
+	SettingsFile string `yaml:"settings_file"`
+
+	Backup *manifestBackup `yaml:"backup"`
+
+	Hooks *api.ApplicationHooks `yaml:"hooks"`
+}
+
+// manifestBackup describes what to include in the backup of an application.
+type manifestBackup struct {
+	Path    string   `yaml:"path"`
+	Exclude []string `yaml:"exclude"` // Globs, relative to the path, left out of partial backups.
+}
+
+// loadManifest reads and validates the manifest of the named application.
+func loadManifest(name string) (*manifest, error) {
+	content, err := os.ReadFile(filepath.Join(manifestsPath, name+".yaml")) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+
+	err = yaml.Unmarshal(content, m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest for application %q: %w", name, err)
+	}
+
+	if len(m.Units) == 0 {
+		return nil, fmt.Errorf("manifest for application %q doesn't list any unit", name)
+	}
+
+	for _, unit := range m.Units {
+		if unit == "" || strings.Contains(unit, "/") {
+			return nil, fmt.Errorf("invalid unit %q in manifest for application %q", unit, name)
+		}
+	}
+
+	if slices.Contains(m.Dependencies, name) {
+		return nil, fmt.Errorf("application %q can't depend on itself", name)
+	}
+
+	paths := slices.Clone(m.DataPaths)
+	paths = append(paths, m.Socket, m.CertificatePath, m.SettingsFile)
+
+	if m.Backup != nil {
+		paths = append(paths, m.Backup.Path)
+	}
+
+	for _, path := range paths {
+		if path != "" && (!filepath.IsAbs(path) || filepath.Clean(path) == "/") {
+			return nil, fmt.Errorf("invalid path %q in manifest for application %q", path, name)
+		}
+	}
+
+	err = ValidateHooks(m.Hooks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hooks in manifest for application %q: %w", name, err)
+	}
+
+	return m, nil
+}
+
+// loadGeneric returns an application driven by its manifest. Applications which haven't been
+// downloaded yet don't have a manifest available, in which case an empty one is used.
+func loadGeneric(s *state.State, name string) (Application, error) {
+	if !applicationNameRegexp.MatchString(name) {
+		return nil, errors.New("unknown application")
+	}
+
+	m, err := loadManifest(name)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		if s.Applications[name].State.Version != "" {
+			return nil, fmt.Errorf("missing manifest for application %q", name)
+		}
+
+		m = &manifest{}
+	}
+
+	return &generic{common: common{state: s}, manifest: m}, nil
+}
+
+type generic struct {
+	common
+
+	manifest *manifest
+}
+
+// Start starts the systemd units.
+func (a *generic) Start(ctx context.Context, _ string) error {
+	if len(a.manifest.Units) == 0 {
+		return nil
+	}
+
+	return systemd.EnableUnit(ctx, true, a.manifest.Units...)
+}
+
+// Stop stops the systemd units.
+func (a *generic) Stop(ctx context.Context, _ string) error {
+	if len(a.manifest.Units) == 0 {
+		return nil
+	}
+
+	return systemd.StopUnit(ctx, a.manifest.Units...)
+}
+
+// Restart restarts the systemd units.
+func (a *generic) Restart(ctx context.Context, _ string) error {
+	if len(a.manifest.Units) == 0 {
+		return nil
+	}
+
+	return systemd.RestartUnit(ctx, a.manifest.Units...)
+}
+
+// Update triggers restart after an application update.
+func (a *generic) Update(ctx context.Context, version string) error {
+	// Reload the systemd daemon to pickup any service definition changes.
+	err := systemd.ReloadDaemon(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.Restart(ctx, version)
+}
+
+// Initialize runs first time initialization.
+func (a *generic) Initialize(ctx context.Context) error {
+	if a.manifest.Socket == "" {
+		return nil
+	}
+
+	// Wait for the application to begin accepting connections.
+	count := 0
+
+	for {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "unix", a.manifest.Socket)
+		if err == nil {
+			_ = conn.Close()
+
+			return nil
+		}
+
+		count++
+
+		if count > 10 {
+			return fmt.Errorf("failed to connect to application via %q", a.manifest.Socket)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// IsRunning reports if the application is currently running.
+func (a *generic) IsRunning(ctx context.Context) bool {
+	if len(a.manifest.Units) == 0 {
+		return false
+	}
+
+	for _, unit := range a.manifest.Units {
+		if !systemd.IsActive(ctx, unit) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ApplySettings writes the settings to the file declared in the manifest and restarts the application.
+func (a *generic) ApplySettings(ctx context.Context, settings map[string]string) error {
+	if a.manifest.SettingsFile == "" {
+		return a.common.ApplySettings(ctx, settings)
+	}
+
+	if settings == nil {
+		settings = map[string]string{}
+	}
+
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	// Only restart the application when its settings changed.
+	current, err := os.ReadFile(a.manifest.SettingsFile)
+	if err == nil && bytes.Equal(current, content) {
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(a.manifest.SettingsFile), 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(a.manifest.SettingsFile, content, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if !a.IsRunning(ctx) {
+		return nil
+	}
+
+	return a.Restart(ctx, "")
+}
+
+// HealthCheck checks that the units are active and the socket, if any, accepts connections.
+func (a *generic) HealthCheck(ctx context.Context) error {
+	if len(a.manifest.Units) == 0 {
+		return ErrHealthCheckNotSupported
+	}
+
+	for _, unit := range a.manifest.Units {
+		if !systemd.IsActive(ctx, unit) {
+			return fmt.Errorf("unit %q isn't active", unit)
+		}
+	}
+
+	if a.manifest.Socket != "" {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "unix", a.manifest.Socket)
+		if err != nil {
+			return fmt.Errorf("socket isn't accepting connections: %w", err)
+		}
+
+		_ = conn.Close()
+	}
+
+	return nil
+}
+
+// Hooks returns the hooks declared in the manifest.
+func (a *generic) Hooks() *api.ApplicationHooks {
+	return a.manifest.Hooks
+}
+
+// IsPrimary reports if the application is a primary application.
+func (a *generic) IsPrimary() bool {
+	return a.manifest.Primary
+}
+
+// GetDependencies returns a list of other applications this application depends on.
+func (a *generic) GetDependencies() []string {
+	return a.manifest.Dependencies
+}
+
+// GetCertificate returns the keypair for the server certificate.
+func (a *generic) GetCertificate() (*tls.Certificate, error) {
+	if a.manifest.CertificatePath == "" {
+		return nil, errors.New("not supported")
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(a.manifest.CertificatePath, "server.crt"), filepath.Join(a.manifest.CertificatePath, "server.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+// SetCertificate replaces the server certificate and restarts the application.
+func (a *generic) SetCertificate(ctx context.Context, cert []byte, key []byte) error {
+	if a.manifest.CertificatePath == "" {
+		return errors.New("not supported")
+	}
+
+	return replaceServerCertificate(ctx, a.manifest.CertificatePath, a.manifest.Units, cert, key)
+}
+
+// WipeLocalData removes local data created by the application.
+func (a *generic) WipeLocalData() error {
+	if len(a.manifest.DataPaths) == 0 {
+		return errors.New("not supported")
+	}
+
+	for _, path := range a.manifest.DataPaths {
+		err := os.RemoveAll(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FactoryReset performs a full factory reset of the application.
+func (a *generic) FactoryReset(ctx context.Context) error {
+	if len(a.manifest.DataPaths) == 0 {
+		return errors.New("not supported")
+	}
+
+	// Stop the application.
+	err := a.Stop(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// Wipe local configuration.
+	err = a.WipeLocalData()
+	if err != nil {
+		return err
+	}
+
+	// Start the application.
+	err = a.Start(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	// Perform first start initialization.
+	return a.Initialize(ctx)
+}
+
+// GetBackup returns a tar archive backup of the application's configuration and/or state.
+func (a *generic) GetBackup(archive io.Writer, complete bool) error {
+	if a.manifest.Backup == nil || a.manifest.Backup.Path == "" {
+		return errors.New("not supported")
+	}
+
+	root := strings.TrimSuffix(a.manifest.Backup.Path, "/") + "/"
+
+	if complete {
+		return createTarArchive(root, nil, archive)
+	}
+
+	// Expand the globs into the paths to exclude.
+	excludePaths := []string{}
+
+	for _, pattern := range a.manifest.Backup.Exclude {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			excludePaths = append(excludePaths, strings.TrimPrefix(match, root))
+		}
+	}
+
+	return createTarArchive(root, excludePaths, archive)
+}
+
+// RestoreBackup restores a tar archive backup of the application's configuration and/or state.
+func (a *generic) RestoreBackup(ctx context.Context, archive io.Reader) error {
+	if a.manifest.Backup == nil || a.manifest.Backup.Path == "" {
+		return errors.New("not supported")
+	}
+
+	return extractTarArchive(ctx, strings.TrimSuffix(a.manifest.Backup.Path, "/")+"/", a.manifest.Units, archive)
+}
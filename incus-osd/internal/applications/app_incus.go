@@ -3,12 +3,14 @@ package applications
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	incusclient "github.com/lxc/incus/v6/client"
 	incusapi "github.com/lxc/incus/v6/shared/api"
+	"gopkg.in/yaml.v3"
 
 	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
@@ -136,11 +138,44 @@ func (a *incus) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// ApplySettings applies the "preseed" setting, an Incus preseed document.
+func (*incus) ApplySettings(_ context.Context, settings map[string]string) error {
+	for key := range settings {
+		if key != "preseed" {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	if settings["preseed"] == "" {
+		return nil
+	}
+
+	preseed := incusapi.InitPreseed{}
+
+	err := yaml.Unmarshal([]byte(settings["preseed"]), &preseed)
+	if err != nil {
+		return fmt.Errorf("invalid setting %q: %w", "preseed", err)
+	}
+
+	// Connect to Incus.
+	c, err := incusclient.ConnectIncusUnix("", nil)
+	if err != nil {
+		return err
+	}
+
+	return c.ApplyServerPreseed(preseed)
+}
+
 // IsRunning reports if the application is currently running.
 func (*incus) IsRunning(ctx context.Context) bool {
 	return systemd.IsActive(ctx, "incus.service")
 }
 
+// HealthCheck checks that Incus is running and its API responds.
+func (*incus) HealthCheck(ctx context.Context) error {
+	return checkAPIHealth(ctx, "/var/lib/incus/unix.socket", "incus.service")
+}
+
 // IsPrimary reports if the application is a primary application.
 func (*incus) IsPrimary() bool {
 	return true
@@ -168,13 +203,34 @@ func (*incus) GetCertificate() (*tls.Certificate, error) {
 	return &cert, nil
 }
 
+// SetCertificate replaces the server certificate and restarts Incus.
+func (*incus) SetCertificate(ctx context.Context, cert []byte, key []byte) error {
+	// Connect to Incus.
+	c, err := incusclient.ConnectIncusUnix("", nil)
+	if err != nil {
+		return err
+	}
+
+	server, _, err := c.GetServer()
+	if err != nil {
+		return err
+	}
+
+	// Clustered servers use a shared certificate which must be managed through Incus itself.
+	if server.Environment.ServerClustered {
+		return errors.New("the certificate of a clustered Incus server must be replaced through the Incus cluster certificate API")
+	}
+
+	return replaceServerCertificate(ctx, "/var/lib/incus", []string{"incus.service"}, cert, key)
+}
+
 // GetDependencies returns a list of other applications this application depends on.
 func (*incus) GetDependencies() []string {
 	return nil
 }
 
 // AddTrustedCertificate adds a new trusted certificate to the application.
-func (*incus) AddTrustedCertificate(_ context.Context, name string, cert string) error {
+func (a *incus) AddTrustedCertificate(_ context.Context, name string, cert string) error {
 	// Connect to Incus.
 	c, err := incusclient.ConnectIncusUnix("", nil)
 	if err != nil {
@@ -189,7 +245,7 @@ func (*incus) AddTrustedCertificate(_ context.Context, name string, cert string)
 
 	_, ok := conf.Config["core.https_address"]
 	if !ok {
-		conf.Config["core.https_address"] = ":8443"
+		conf.Config["core.https_address"] = a.state.ListenAddress("8443")
 
 		err = c.UpdateServer(conf.Writable(), etag)
 		if err != nil {
@@ -260,7 +316,7 @@ func (*incus) RestoreBackup(ctx context.Context, archive io.Reader) error {
 	return extractTarArchive(ctx, "/var/lib/incus/", []string{"incus-startup.service", "incus.socket", "incus.service", "incus-lxcfs.service"}, archive)
 }
 
-func (*incus) applyDefaults(ctx context.Context, c incusclient.InstanceServer) error {
+func (a *incus) applyDefaults(ctx context.Context, c incusclient.InstanceServer) error {
 	// Get server configuration.
 	serverConfig, serverConfigEtag, err := c.GetServer()
 	if err != nil {
@@ -367,7 +423,7 @@ func (*incus) applyDefaults(ctx context.Context, c incusclient.InstanceServer) e
 	// Listen on the network by default.
 	_, ok := serverConfig.Config["core.https_address"]
 	if !ok {
-		serverConfig.Config["core.https_address"] = ":8443"
+		serverConfig.Config["core.https_address"] = a.state.ListenAddress("8443")
 	}
 
 	// Apply default profile changes.
@@ -189,6 +189,11 @@ func (*migrationManager) GetCertificate() (*tls.Certificate, error) {
 	return &cert, nil
 }
 
+// SetCertificate replaces the server certificate and restarts the application.
+func (*migrationManager) SetCertificate(ctx context.Context, cert []byte, key []byte) error {
+	return replaceServerCertificate(ctx, "/var/lib/migration-manager", []string{"migration-manager.service"}, cert, key)
+}
+
 // GetDependencies returns a list of other applications this application depends on.
 func (*migrationManager) GetDependencies() []string {
 	return nil
@@ -238,6 +243,16 @@ func doMMRequest(ctx context.Context, url string, method string, body []byte) ([
 	return doRequest(ctx, "/run/migration-manager/unix.socket", url, method, body)
 }
 
+// ApplySettings sends the settings to the matching Migration Manager API endpoints.
+func (*migrationManager) ApplySettings(ctx context.Context, settings map[string]string) error {
+	return applyAPISettings(ctx, "/run/migration-manager/unix.socket", migrationManagerSettings, settings)
+}
+
+// HealthCheck checks that Migration Manager is running and its API responds.
+func (*migrationManager) HealthCheck(ctx context.Context) error {
+	return checkAPIHealth(ctx, "/run/migration-manager/unix.socket", "migration-manager.service")
+}
+
 // IsPrimary reports if the application is a primary application.
 func (*migrationManager) IsPrimary() bool {
 	return true
@@ -204,6 +204,11 @@ func (*operationsCenter) GetCertificate() (*tls.Certificate, error) {
 	return &cert, nil
 }
 
+// SetCertificate replaces the server certificate and restarts the application.
+func (*operationsCenter) SetCertificate(ctx context.Context, cert []byte, key []byte) error {
+	return replaceServerCertificate(ctx, "/var/lib/operations-center", []string{"operations-center.service"}, cert, key)
+}
+
 // GetDependencies returns a list of other applications this application depends on.
 func (*operationsCenter) GetDependencies() []string {
 	return nil
@@ -253,6 +258,16 @@ func doOCRequest(ctx context.Context, url string, method string, body []byte) ([
 	return doRequest(ctx, "/run/operations-center/unix.socket", url, method, body)
 }
 
+// ApplySettings sends the settings to the matching Operations Center API endpoints.
+func (*operationsCenter) ApplySettings(ctx context.Context, settings map[string]string) error {
+	return applyAPISettings(ctx, "/run/operations-center/unix.socket", operationsCenterSettings, settings)
+}
+
+// HealthCheck checks that Operations Center is running and its API responds.
+func (*operationsCenter) HealthCheck(ctx context.Context) error {
+	return checkAPIHealth(ctx, "/run/operations-center/unix.socket", "operations-center.service")
+}
+
 // IsPrimary reports if the application is a primary application.
 func (*operationsCenter) IsPrimary() bool {
 	return true
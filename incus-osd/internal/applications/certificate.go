@@ -0,0 +1,222 @@
+package applications
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	incustls "github.com/lxc/incus/v6/shared/tls"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/notify"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// certificateRotationThreshold is how long before its expiry a server certificate gets rotated.
+const certificateRotationThreshold = 30 * 24 * time.Hour
+
+// certificateOverlapWindow is how long both the old and new fingerprints are exposed, first while
+// the new certificate is staged and then once it has replaced the old one.
+const certificateOverlapWindow = 7 * 24 * time.Hour
+
+// certificateStagingPath is where certificates are staged ahead of their rotation.
+var certificateStagingPath = "/var/lib/incus-os/"
+
+// CheckCertificateRotation refreshes the state of the application's server certificate, rotating
+// self-signed certificates ahead of their expiry. Certificates managed by the application itself,
+// such as those issued through ACME, are only tracked.
+func CheckCertificateRotation(ctx context.Context, s *state.State, name string, app Application) error {
+	keypair, err := app.GetCertificate()
+	if err != nil {
+		return err
+	}
+
+	cert, err := getLeafCertificate(keypair)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fingerprint := getRawCertificateFingerprint(cert)
+	rotation := s.Certificates[name]
+
+	// Detect certificates replaced outside of our control, for example renewed through ACME.
+	if rotation.Fingerprint != "" && rotation.Fingerprint != fingerprint {
+		slog.InfoContext(ctx, "Application server certificate was replaced", "name", name, "old", rotation.Fingerprint, "new", fingerprint)
+		notify.Send(ctx, s, api.SystemNotificationsEventCertificateRotation, fmt.Sprintf("The server certificate of application %q was replaced, new fingerprint is %s (was %s)", name, fingerprint, rotation.Fingerprint))
+
+		removeStagedCertificate(name)
+
+		rotation = state.CertificateRotation{
+			PreviousFingerprint: rotation.Fingerprint,
+			Rotated:             now.Unix(),
+		}
+	}
+
+	rotation.Fingerprint = fingerprint
+
+	// Stop exposing the old fingerprint once the overlap window has passed.
+	if rotation.PreviousFingerprint != "" && now.After(time.Unix(rotation.Rotated, 0).Add(certificateOverlapWindow)) {
+		rotation.PreviousFingerprint = ""
+	}
+
+	if cert.NotAfter.Sub(now) < certificateRotationThreshold {
+		if !isSelfSigned(cert) {
+			// We can't rotate certificates we didn't issue, so just let the user know once.
+			if !rotation.ExpiryNotified {
+				slog.WarnContext(ctx, "Application server certificate is about to expire", "name", name, "expiry", cert.NotAfter)
+				notify.Send(ctx, s, api.SystemNotificationsEventCertificateRotation, fmt.Sprintf("The server certificate of application %q expires on %s and isn't self-signed, so it must be renewed manually", name, cert.NotAfter.Format(time.RFC3339)))
+
+				rotation.ExpiryNotified = true
+			}
+		} else if rotation.NextFingerprint == "" {
+			rotation, err = stageCertificate(ctx, s, name, rotation, now)
+			if err != nil {
+				return err
+			}
+		} else if now.After(time.Unix(rotation.NextStaged, 0).Add(certificateOverlapWindow)) || cert.NotAfter.Sub(now) < 24*time.Hour {
+			rotation, err = applyStagedCertificate(ctx, s, name, app, rotation, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	s.Certificates[name] = rotation
+
+	// Expose the certificate state through the application.
+	appInfo := s.Applications[name]
+	appInfo.State.Certificate = &api.ApplicationCertificate{
+		Fingerprint:         rotation.Fingerprint,
+		NextFingerprint:     rotation.NextFingerprint,
+		PreviousFingerprint: rotation.PreviousFingerprint,
+	}
+
+	// The expiry is only known for the certificate we just loaded.
+	if rotation.Fingerprint == fingerprint {
+		appInfo.State.Certificate.Expiry = &cert.NotAfter
+	}
+
+	s.SetApplication(name, appInfo)
+
+	return nil
+}
+
+// stageCertificate generates a new self-signed certificate which will replace the current one at
+// the end of the overlap window.
+func stageCertificate(ctx context.Context, s *state.State, name string, rotation state.CertificateRotation, now time.Time) (state.CertificateRotation, error) {
+	certPEM, keyPEM, err := incustls.GenerateMemCert(false, true)
+	if err != nil {
+		return rotation, err
+	}
+
+	certFile, keyFile := getStagedCertificatePaths(name)
+
+	err = os.WriteFile(keyFile, keyPEM, 0o600)
+	if err != nil {
+		return rotation, err
+	}
+
+	err = os.WriteFile(certFile, certPEM, 0o600)
+	if err != nil {
+		return rotation, err
+	}
+
+	nextFingerprint, err := getCertificateFingerprint(string(certPEM))
+	if err != nil {
+		return rotation, err
+	}
+
+	rotation.NextFingerprint = nextFingerprint
+	rotation.NextStaged = now.Unix()
+
+	slog.InfoContext(ctx, "Staged a new application server certificate", "name", name, "fingerprint", nextFingerprint)
+	notify.Send(ctx, s, api.SystemNotificationsEventCertificateRotation, fmt.Sprintf("The server certificate of application %q is nearing expiry and will be replaced on %s, new fingerprint is %s", name, now.Add(certificateOverlapWindow).Format(time.RFC3339), nextFingerprint))
+
+	return rotation, nil
+}
+
+// applyStagedCertificate replaces the application's server certificate with the staged one.
+func applyStagedCertificate(ctx context.Context, s *state.State, name string, app Application, rotation state.CertificateRotation, now time.Time) (state.CertificateRotation, error) {
+	certFile, keyFile := getStagedCertificatePaths(name)
+
+	certPEM, err := os.ReadFile(certFile) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// The staged certificate is gone, start over.
+			rotation.NextFingerprint = ""
+			rotation.NextStaged = 0
+
+			return rotation, nil
+		}
+
+		return rotation, err
+	}
+
+	keyPEM, err := os.ReadFile(keyFile) //nolint:gosec
+	if err != nil {
+		return rotation, err
+	}
+
+	err = app.SetCertificate(ctx, certPEM, keyPEM)
+	if err != nil {
+		return rotation, err
+	}
+
+	removeStagedCertificate(name)
+
+	slog.InfoContext(ctx, "Rotated application server certificate", "name", name, "old", rotation.Fingerprint, "new", rotation.NextFingerprint)
+	notify.Send(ctx, s, api.SystemNotificationsEventCertificateRotation, fmt.Sprintf("The server certificate of application %q was rotated, new fingerprint is %s (was %s)", name, rotation.NextFingerprint, rotation.Fingerprint))
+
+	return state.CertificateRotation{
+		Fingerprint:         rotation.NextFingerprint,
+		PreviousFingerprint: rotation.Fingerprint,
+		Rotated:             now.Unix(),
+	}, nil
+}
+
+// getStagedCertificatePaths returns the paths of the staged certificate and key for an application.
+func getStagedCertificatePaths(name string) (string, string) {
+	return filepath.Join(certificateStagingPath, name+".next.crt"), filepath.Join(certificateStagingPath, name+".next.key")
+}
+
+// removeStagedCertificate removes any staged certificate for an application.
+func removeStagedCertificate(name string) {
+	certFile, keyFile := getStagedCertificatePaths(name)
+
+	_ = os.Remove(certFile)
+	_ = os.Remove(keyFile)
+}
+
+// getLeafCertificate returns the parsed leaf certificate of a keypair.
+func getLeafCertificate(keypair *tls.Certificate) (*x509.Certificate, error) {
+	if keypair.Leaf != nil {
+		return keypair.Leaf, nil
+	}
+
+	if len(keypair.Certificate) == 0 {
+		return nil, errors.New("keypair doesn't contain a certificate")
+	}
+
+	return x509.ParseCertificate(keypair.Certificate[0])
+}
+
+// getRawCertificateFingerprint returns the SHA256 fingerprint of a parsed certificate.
+func getRawCertificateFingerprint(cert *x509.Certificate) string {
+	rawFp := sha256.Sum256(cert.Raw)
+
+	return hex.EncodeToString(rawFp[:])
+}
+
+// isSelfSigned reports whether the certificate was signed by its own key.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
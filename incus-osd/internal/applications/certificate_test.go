@@ -0,0 +1,167 @@
+package applications
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// fakeCertificateApp is an application whose server certificate is kept in memory.
+type fakeCertificateApp struct {
+	common
+
+	cert []byte
+	key  []byte
+}
+
+func (a *fakeCertificateApp) GetCertificate() (*tls.Certificate, error) {
+	keypair, err := tls.X509KeyPair(a.cert, a.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keypair, nil
+}
+
+func (a *fakeCertificateApp) SetCertificate(_ context.Context, cert []byte, key []byte) error {
+	a.cert = cert
+	a.key = key
+
+	return nil
+}
+
+// generateCertificate returns a PEM encoded certificate and key expiring at notAfter. The certificate is
+// signed by the provided parent, or self-signed if none is provided.
+func generateCertificate(t *testing.T, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) ([]byte, []byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), cert, key
+}
+
+func TestIsSelfSigned(t *testing.T) {
+	t.Parallel()
+
+	_, _, ca, caKey := generateCertificate(t, time.Now().Add(24*time.Hour), nil, nil)
+	_, _, leaf, _ := generateCertificate(t, time.Now().Add(24*time.Hour), ca, caKey)
+
+	require.True(t, isSelfSigned(ca))
+	require.False(t, isSelfSigned(leaf))
+}
+
+func TestCheckCertificateRotation(t *testing.T) { //nolint:paralleltest
+	certificateStagingPath = t.TempDir()
+
+	newState := func() *state.State {
+		return &state.State{
+			Applications: map[string]api.Application{},
+			Certificates: map[string]state.CertificateRotation{},
+		}
+	}
+
+	// Valid certificates are only tracked.
+	s := newState()
+	certPEM, keyPEM, cert, _ := generateCertificate(t, time.Now().Add(365*24*time.Hour), nil, nil)
+	app := &fakeCertificateApp{cert: certPEM, key: keyPEM}
+
+	require.NoError(t, CheckCertificateRotation(t.Context(), s, "test", app))
+	require.Equal(t, state.CertificateRotation{Fingerprint: getRawCertificateFingerprint(cert)}, s.Certificates["test"])
+	require.Equal(t, getRawCertificateFingerprint(cert), s.Applications["test"].State.Certificate.Fingerprint)
+	require.Equal(t, cert.NotAfter, *s.Applications["test"].State.Certificate.Expiry)
+
+	// Certificates replaced outside of our control keep exposing the old fingerprint.
+	newCertPEM, newKeyPEM, newCert, _ := generateCertificate(t, time.Now().Add(365*24*time.Hour), nil, nil)
+	app.cert = newCertPEM
+	app.key = newKeyPEM
+
+	require.NoError(t, CheckCertificateRotation(t.Context(), s, "test", app))
+	require.Equal(t, getRawCertificateFingerprint(newCert), s.Certificates["test"].Fingerprint)
+	require.Equal(t, getRawCertificateFingerprint(cert), s.Certificates["test"].PreviousFingerprint)
+	require.Equal(t, getRawCertificateFingerprint(cert), s.Applications["test"].State.Certificate.PreviousFingerprint)
+
+	// Self-signed certificates nearing expiry get a replacement staged.
+	s = newState()
+	certPEM, keyPEM, cert, _ = generateCertificate(t, time.Now().Add(10*24*time.Hour), nil, nil)
+	app = &fakeCertificateApp{cert: certPEM, key: keyPEM}
+
+	require.NoError(t, CheckCertificateRotation(t.Context(), s, "test", app))
+
+	rotation := s.Certificates["test"]
+	require.Equal(t, getRawCertificateFingerprint(cert), rotation.Fingerprint)
+	require.NotEmpty(t, rotation.NextFingerprint)
+	require.Equal(t, rotation.NextFingerprint, s.Applications["test"].State.Certificate.NextFingerprint)
+
+	stagedCert, stagedKey := getStagedCertificatePaths("test")
+	require.FileExists(t, stagedCert)
+	require.FileExists(t, stagedKey)
+
+	// The staged certificate isn't applied during the overlap window.
+	require.NoError(t, CheckCertificateRotation(t.Context(), s, "test", app))
+	require.Equal(t, certPEM, app.cert)
+
+	// Once the overlap window has passed, the staged certificate replaces the current one.
+	rotation.NextStaged = time.Now().Add(-certificateOverlapWindow - time.Hour).Unix()
+	s.Certificates["test"] = rotation
+
+	require.NoError(t, CheckCertificateRotation(t.Context(), s, "test", app))
+
+	fingerprint, err := getCertificateFingerprint(string(app.cert))
+	require.NoError(t, err)
+	require.Equal(t, rotation.NextFingerprint, fingerprint)
+	require.Equal(t, fingerprint, s.Certificates["test"].Fingerprint)
+	require.Equal(t, getRawCertificateFingerprint(cert), s.Certificates["test"].PreviousFingerprint)
+	require.Empty(t, s.Certificates["test"].NextFingerprint)
+
+	require.NoFileExists(t, stagedCert)
+	require.NoFileExists(t, stagedKey)
+
+	// Certificates which aren't self-signed are never rotated, only reported.
+	s = newState()
+	_, _, ca, caKey := generateCertificate(t, time.Now().Add(365*24*time.Hour), nil, nil)
+	certPEM, keyPEM, _, _ = generateCertificate(t, time.Now().Add(10*24*time.Hour), ca, caKey)
+	app = &fakeCertificateApp{cert: certPEM, key: keyPEM}
+
+	require.NoError(t, CheckCertificateRotation(t.Context(), s, "test", app))
+	require.True(t, s.Certificates["test"].ExpiryNotified)
+	require.Empty(t, s.Certificates["test"].NextFingerprint)
+	require.NoFileExists(t, stagedCert)
+}
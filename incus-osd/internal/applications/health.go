@@ -0,0 +1,159 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// healthCheckTimeout is how long a single health check may take.
+const healthCheckTimeout = 30 * time.Second
+
+// healthRestartBackoff is the delay before the first automatic restart, doubled after each subsequent one.
+const healthRestartBackoff = time.Minute
+
+// healthRestartMaxBackoff caps the delay between automatic restarts.
+const healthRestartMaxBackoff = time.Hour
+
+// healthHistorySize is the number of health events kept for each application.
+const healthHistorySize = 20
+
+var (
+	stoppedApplications   = map[string]bool{}
+	stoppedApplicationsMu sync.Mutex
+)
+
+// SetStopped records whether the application was explicitly stopped, in which case it isn't health
+// checked nor restarted until started again.
+func SetStopped(name string, stopped bool) {
+	stoppedApplicationsMu.Lock()
+	defer stoppedApplicationsMu.Unlock()
+
+	if stopped {
+		stoppedApplications[name] = true
+	} else {
+		delete(stoppedApplications, name)
+	}
+}
+
+// isStopped returns whether the application was explicitly stopped.
+func isStopped(name string) bool {
+	stoppedApplicationsMu.Lock()
+	defer stoppedApplicationsMu.Unlock()
+
+	return stoppedApplications[name]
+}
+
+// CheckHealth runs the application's health check, restarting it with an increasing backoff while it
+// keeps failing. The outcome is recorded in the application's state and sent as lifecycle events.
+func CheckHealth(ctx context.Context, s *state.State, name string, app Application) error {
+	appInfo, ok := s.Applications[name]
+	if !ok || !appInfo.State.Initialized || isStopped(name) || GetOperation(name) != "" {
+		return nil
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	checkErr := app.HealthCheck(checkCtx)
+	if errors.Is(checkErr, ErrHealthCheckNotSupported) {
+		return nil
+	}
+
+	now := time.Now()
+
+	// Work on a copy, as the current health may be concurrently read through the API.
+	health := &api.ApplicationHealth{Status: api.ApplicationHealthStatusHealthy}
+	if appInfo.State.Health != nil {
+		*health = *appInfo.State.Health
+		health.History = slices.Clone(health.History)
+	}
+
+	health.LastCheck = now
+
+	addEvent := func(event api.ApplicationHealthEvent) {
+		event.Time = now
+		health.History = append(health.History, event)
+
+		if len(health.History) > healthHistorySize {
+			health.History = health.History[len(health.History)-healthHistorySize:]
+		}
+	}
+
+	source := "/1.0/applications/" + name
+
+	var err error
+
+	if checkErr == nil {
+		if health.Status == api.ApplicationHealthStatusUnhealthy {
+			slog.InfoContext(ctx, "Application recovered", "name", name)
+			addEvent(api.ApplicationHealthEvent{Status: api.ApplicationHealthStatusHealthy})
+			s.Events.SendLifecycle(api.EventLifecycleApplicationRecovered, source, nil)
+		}
+
+		health.Status = api.ApplicationHealthStatusHealthy
+		health.Message = ""
+		health.Failures = 0
+		health.Restarts = 0
+		health.NextRestart = nil
+	} else {
+		health.Failures++
+		health.Message = checkErr.Error()
+
+		if health.Status != api.ApplicationHealthStatusUnhealthy {
+			slog.WarnContext(ctx, "Application failed its health check", "name", name, "err", checkErr)
+			addEvent(api.ApplicationHealthEvent{Status: api.ApplicationHealthStatusUnhealthy, Message: health.Message})
+			s.Events.SendLifecycle(api.EventLifecycleApplicationUnhealthy, source, map[string]any{"message": health.Message})
+		}
+
+		health.Status = api.ApplicationHealthStatusUnhealthy
+
+		// Restart the application, waiting longer after each attempt.
+		if health.NextRestart == nil || !now.Before(*health.NextRestart) {
+			slog.InfoContext(ctx, "Restarting unhealthy application", "name", name, "restarts", health.Restarts)
+
+			err = app.Restart(ctx, appInfo.State.Version)
+			if err != nil {
+				err = fmt.Errorf("failed to restart application %q: %w", name, err)
+			}
+
+			health.Restarts++
+
+			nextRestart := now.Add(min(healthRestartBackoff<<min(health.Restarts-1, 6), healthRestartMaxBackoff))
+			health.NextRestart = &nextRestart
+
+			addEvent(api.ApplicationHealthEvent{Status: api.ApplicationHealthStatusUnhealthy, Message: health.Message, Restarted: err == nil})
+			s.Events.SendLifecycle(api.EventLifecycleApplicationRestarted, source, map[string]any{"restarts": health.Restarts})
+		}
+	}
+
+	appInfo.State.Health = health
+	s.SetApplication(name, appInfo)
+
+	return err
+}
+
+// checkAPIHealth checks that the units are active and the application's local REST API responds.
+func checkAPIHealth(ctx context.Context, socket string, units ...string) error {
+	for _, unit := range units {
+		if !systemd.IsActive(ctx, unit) {
+			return fmt.Errorf("unit %q isn't active", unit)
+		}
+	}
+
+	_, err := doRequest(ctx, socket, "http://localhost/1.0", http.MethodGet, nil)
+	if err != nil {
+		return fmt.Errorf("API isn't responding: %w", err)
+	}
+
+	return nil
+}
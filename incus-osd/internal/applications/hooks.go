@@ -0,0 +1,174 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// HookType represents the step at which an application hook is run.
+type HookType string
+
+const (
+	// HookPreUpdate is run before a new version of the application is activated. A failure skips the update.
+	HookPreUpdate HookType = "pre-update"
+
+	// HookPostUpdate is run once the updated application is healthy. A failure rolls the update back.
+	HookPostUpdate HookType = "post-update"
+
+	// HookPreOSUpdate is run before an OS update is applied. A failure postpones the OS update.
+	HookPreOSUpdate HookType = "pre-os-update"
+
+	// HookPreShutdown is run before the application is stopped as the system shuts down. A failure is only logged.
+	HookPreShutdown HookType = "pre-shutdown"
+)
+
+// hookDefaultTimeout is how long a hook may run when it doesn't specify a timeout.
+const hookDefaultTimeout = 5 * time.Minute
+
+// Hooks returns the hooks declared by the application.
+func (*common) Hooks() *api.ApplicationHooks {
+	return nil
+}
+
+// ValidateHooks checks the hooks of an application.
+func ValidateHooks(hooks *api.ApplicationHooks) error {
+	if hooks == nil {
+		return nil
+	}
+
+	for hookType, hook := range map[HookType]*api.ApplicationHook{
+		HookPreUpdate:   hooks.PreUpdate,
+		HookPostUpdate:  hooks.PostUpdate,
+		HookPreOSUpdate: hooks.PreOSUpdate,
+		HookPreShutdown: hooks.PreShutdown,
+	} {
+		if hook == nil {
+			continue
+		}
+
+		if len(hook.Command) == 0 || !filepath.IsAbs(hook.Command[0]) {
+			return fmt.Errorf("%s hook must run an absolute path", hookType)
+		}
+
+		if hook.Timeout < 0 {
+			return fmt.Errorf("invalid timeout for %s hook", hookType)
+		}
+
+		if hook.OnFailure != "" && !slices.Contains([]api.ApplicationHookFailurePolicy{api.ApplicationHookFailurePolicyAbort, api.ApplicationHookFailurePolicyIgnore}, hook.OnFailure) {
+			return fmt.Errorf("invalid failure policy %q for %s hook", hook.OnFailure, hookType)
+		}
+	}
+
+	return nil
+}
+
+// getHook returns the hook of the given type, as configured for the application or declared by it.
+func getHook(hooks *api.ApplicationHooks, hookType HookType) *api.ApplicationHook {
+	if hooks == nil {
+		return nil
+	}
+
+	switch hookType {
+	case HookPreUpdate:
+		return hooks.PreUpdate
+	case HookPostUpdate:
+		return hooks.PostUpdate
+	case HookPreOSUpdate:
+		return hooks.PreOSUpdate
+	case HookPreShutdown:
+		return hooks.PreShutdown
+	}
+
+	return nil
+}
+
+// RunHook runs the application's hook of the given type, if any. The version is the one of the application
+// or OS update the hook is run for. An error is only returned if the hook failed and its failure policy is
+// to abort.
+func RunHook(ctx context.Context, s *state.State, name string, hookType HookType, version string) error {
+	appInfo, ok := s.Applications[name]
+	if !ok {
+		return nil
+	}
+
+	// The configured hook takes precedence over the one declared by the application.
+	hook := getHook(appInfo.Config.Hooks, hookType)
+	if hook == nil {
+		app, err := Load(ctx, s, name)
+		if err != nil {
+			return err
+		}
+
+		hook = getHook(app.Hooks(), hookType)
+		if hook == nil {
+			return nil
+		}
+	}
+
+	timeout := hookDefaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	env := append(os.Environ(), "INCUSOS_HOOK="+string(hookType), "INCUSOS_APPLICATION="+name, "INCUSOS_VERSION="+version)
+
+	slog.InfoContext(ctx, "Running application hook", "name", name, "hook", hookType, "command", strings.Join(hook.Command, " "))
+
+	_, _, err := subprocess.RunCommandSplit(hookCtx, env, nil, hook.Command[0], hook.Command[1:]...)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+
+	s.Events.SendLifecycle(api.EventLifecycleApplicationHookFailed, "/1.0/applications/"+name, map[string]any{
+		"hook":    hookType,
+		"version": version,
+		"failure": err.Error(),
+	})
+
+	if hookType == HookPreShutdown || hook.OnFailure == api.ApplicationHookFailurePolicyIgnore {
+		slog.WarnContext(ctx, "Application hook failed", "name", name, "hook", hookType, "err", err)
+
+		return nil
+	}
+
+	return fmt.Errorf("%s hook of application %q failed: %w", hookType, name, err)
+}
+
+// RunHooks runs the hook of the given type for each installed application, stopping at the first one
+// whose failure policy is to abort.
+func RunHooks(ctx context.Context, s *state.State, hookType HookType, version string) error {
+	names := make([]string, 0, len(s.Applications))
+	for name := range s.Applications {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		err := RunHook(ctx, s, name, hookType, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
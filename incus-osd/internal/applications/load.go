@@ -3,6 +3,8 @@ package applications
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
@@ -10,11 +12,17 @@ import (
 // ErrNoPrimary is returned when the system doesn't yet have a primary application.
 var ErrNoPrimary = errors.New("no primary application")
 
-// Load retrieves and returns the application specific logic.
+// ErrHealthCheckNotSupported is returned by applications which can't be health checked.
+var ErrHealthCheckNotSupported = errors.New("health check not supported")
+
+// Load retrieves and returns the application specific logic. Applications without any specific logic
+// are driven by the manifest shipped in their system extension.
 func Load(_ context.Context, s *state.State, name string) (Application, error) {
 	var app Application
 
 	switch name {
+	case "console":
+		app = &console{common: common{state: s}}
 	case "debug":
 		app = &debug{common: common{state: s}}
 	case "incus":
@@ -28,7 +36,7 @@ func Load(_ context.Context, s *state.State, name string) (Application, error) {
 	case "operations-center":
 		app = &operationsCenter{common: common{state: s}}
 	default:
-		return nil, errors.New("unknown application")
+		return loadGeneric(s, name)
 	}
 
 	return app, nil
@@ -49,3 +57,50 @@ func GetPrimary(ctx context.Context, s *state.State) (Application, error) {
 
 	return nil, ErrNoPrimary
 }
+
+// SortByDependencies returns the list of applications, along with any missing dependency, ordered
+// such that each application comes after all the applications it depends on.
+func SortByDependencies(ctx context.Context, s *state.State, names []string) ([]string, error) {
+	sorted := []string{}
+	visiting := map[string]bool{}
+
+	var visit func(name string) error
+
+	visit = func(name string) error {
+		if slices.Contains(sorted, name) {
+			return nil
+		}
+
+		if visiting[name] {
+			return fmt.Errorf("dependency loop detected for application %q", name)
+		}
+
+		app, err := Load(ctx, s, name)
+		if err != nil {
+			return fmt.Errorf("failed to load application %q: %w", name, err)
+		}
+
+		visiting[name] = true
+
+		for _, dep := range app.GetDependencies() {
+			err := visit(dep)
+			if err != nil {
+				return err
+			}
+		}
+
+		visiting[name] = false
+		sorted = append(sorted, name)
+
+		return nil
+	}
+
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,47 @@
+package applications
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestSortByDependencies(t *testing.T) { //nolint:paralleltest
+	manifestsPath = t.TempDir()
+
+	writeManifest := func(name string, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(manifestsPath, name+".yaml"), []byte(content), 0o600))
+	}
+
+	writeManifest("app-a", "units: [app-a.service]\ndependencies: [app-b]\n")
+	writeManifest("app-b", "units: [app-b.service]\ndependencies: [incus-ceph]\n")
+	writeManifest("loop-a", "units: [loop-a.service]\ndependencies: [loop-b]\n")
+	writeManifest("loop-b", "units: [loop-b.service]\ndependencies: [loop-c]\n")
+	writeManifest("loop-c", "units: [loop-c.service]\ndependencies: [loop-a]\n")
+
+	s := &state.State{}
+
+	// Missing dependencies are added ahead of the applications needing them.
+	sorted, err := SortByDependencies(t.Context(), s, []string{"app-a"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"incus", "incus-ceph", "app-b", "app-a"}, sorted)
+
+	// Applications already listed aren't repeated.
+	sorted, err = SortByDependencies(t.Context(), s, []string{"incus-ceph", "incus", "app-b"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"incus", "incus-ceph", "app-b"}, sorted)
+
+	// Dependency loops are detected.
+	_, err = SortByDependencies(t.Context(), s, []string{"app-a", "loop-b"})
+	require.EqualError(t, err, `dependency loop detected for application "loop-b"`)
+
+	// Self-dependencies are rejected when loading the manifest.
+	writeManifest("loop-d", "units: [loop-d.service]\ndependencies: [loop-d]\n")
+
+	_, err = SortByDependencies(t.Context(), s, []string{"loop-d"})
+	require.EqualError(t, err, `failed to load application "loop-d": application "loop-d" can't depend on itself`)
+}
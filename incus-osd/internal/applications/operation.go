@@ -0,0 +1,45 @@
+package applications
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrOperationInProgress is returned when another long running operation is already in progress for the application.
+var ErrOperationInProgress = errors.New("another operation is in progress for the application")
+
+var (
+	runningOperations   = map[string]string{}
+	runningOperationsMu sync.Mutex
+)
+
+// StartOperation records the start of a long running operation, such as a backup or restore, on the
+// application and returns a function to call once it's complete. Applications aren't health checked
+// while an operation is running.
+func StartOperation(name string, operation string) (func(), error) {
+	runningOperationsMu.Lock()
+	defer runningOperationsMu.Unlock()
+
+	current, ok := runningOperations[name]
+	if ok {
+		return nil, fmt.Errorf("%w (%s)", ErrOperationInProgress, current)
+	}
+
+	runningOperations[name] = operation
+
+	return func() {
+		runningOperationsMu.Lock()
+		defer runningOperationsMu.Unlock()
+
+		delete(runningOperations, name)
+	}, nil
+}
+
+// GetOperation returns the long running operation currently in progress for the application, if any.
+func GetOperation(name string) string {
+	runningOperationsMu.Lock()
+	defer runningOperationsMu.Unlock()
+
+	return runningOperations[name]
+}
@@ -0,0 +1,85 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// GetDependents returns the installed applications which depend on the provided one.
+func GetDependents(ctx context.Context, s *state.State, name string) ([]string, error) {
+	dependents := []string{}
+
+	for appName := range s.Applications {
+		if appName == name {
+			continue
+		}
+
+		app, err := Load(ctx, s, appName)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(app.GetDependencies(), name) {
+			dependents = append(dependents, appName)
+		}
+	}
+
+	slices.Sort(dependents)
+
+	return dependents, nil
+}
+
+// Remove stops an installed application, optionally wipes its local data and removes its system extension.
+// The system extensions must then be refreshed through systemd.RefreshExtensions.
+func Remove(ctx context.Context, s *state.State, name string, wipe bool) error {
+	app, err := Load(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	if app.IsRunning(ctx) {
+		err = app.Stop(ctx, s.Applications[name].State.Version)
+		if err != nil {
+			return fmt.Errorf("failed to stop application %q: %w", name, err)
+		}
+	}
+
+	// Not all applications support wiping their data.
+	if wipe {
+		_ = app.WipeLocalData()
+	}
+
+	err = os.Remove(filepath.Join(systemd.SystemExtensionsPath, name+".raw"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(systemd.SystemExtensionsPreviousPath, name+".raw"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	wasPrimary := app.IsPrimary()
+
+	s.DeleteApplication(name)
+	SetStopped(name, false)
+
+	// Let the console take over as the primary application.
+	if wasPrimary && name != "console" {
+		err = RefreshConsole(ctx, s)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to restart the console", "err", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package applications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// settingsEndpoint is the local API endpoint a setting of an application is sent to.
+type settingsEndpoint struct {
+	path   string
+	method string
+}
+
+// ApplySettings delivers application-specific settings to the application.
+func (*common) ApplySettings(_ context.Context, settings map[string]string) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	return errors.New("application doesn't support any setting")
+}
+
+// applyAPISettings sends each setting, a YAML or JSON document, to the matching endpoint of the application's local REST API.
+func applyAPISettings(ctx context.Context, socket string, endpoints map[string]settingsEndpoint, settings map[string]string) error {
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		endpoint, ok := endpoints[key]
+		if !ok {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+
+		// YAML being a superset of JSON, parse the setting as YAML and send it as JSON.
+		var value any
+
+		err := yaml.Unmarshal([]byte(settings[key]), &value)
+		if err != nil {
+			return fmt.Errorf("invalid setting %q: %w", key, err)
+		}
+
+		contentJSON, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("invalid setting %q: %w", key, err)
+		}
+
+		_, err = doRequest(ctx, socket, "http://localhost"+endpoint.path, endpoint.method, contentJSON)
+		if err != nil {
+			return fmt.Errorf("failed to apply setting %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// operationsCenterSettings lists the settings supported by Operations Center.
+var operationsCenterSettings = map[string]settingsEndpoint{
+	"system_certificate": {path: "/1.0/system/certificate", method: http.MethodPost},
+	"system_network":     {path: "/1.0/system/network", method: http.MethodPut},
+	"system_security":    {path: "/1.0/system/security", method: http.MethodPut},
+	"system_updates":     {path: "/1.0/system/updates", method: http.MethodPut},
+}
+
+// migrationManagerSettings lists the settings supported by Migration Manager.
+var migrationManagerSettings = map[string]settingsEndpoint{
+	"system_certificate": {path: "/1.0/system/certificate", method: http.MethodPost},
+	"system_network":     {path: "/1.0/system/network", method: http.MethodPut},
+	"system_security":    {path: "/1.0/system/security", method: http.MethodPut},
+}
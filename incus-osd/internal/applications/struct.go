@@ -4,20 +4,26 @@ import (
 	"context"
 	"crypto/tls"
 	"io"
+
+	"github.com/lxc/incus-os/incus-osd/api"
 )
 
 // Application represents an installed application.
 type Application interface { //nolint:interfacebloat
 	AddTrustedCertificate(ctx context.Context, name string, cert string) error
+	ApplySettings(ctx context.Context, settings map[string]string) error
 	FactoryReset(ctx context.Context) error
 	GetBackup(archive io.Writer, complete bool) error
 	GetCertificate() (*tls.Certificate, error)
 	GetDependencies() []string
+	HealthCheck(ctx context.Context) error
+	Hooks() *api.ApplicationHooks
 	Initialize(ctx context.Context) error
 	IsPrimary() bool
 	IsRunning(ctx context.Context) bool
 	RestoreBackup(ctx context.Context, archive io.Reader) error
 	Restart(ctx context.Context, version string) error
+	SetCertificate(ctx context.Context, cert []byte, key []byte) error
 	Start(ctx context.Context, version string) error
 	Stop(ctx context.Context, version string) error
 	Update(ctx context.Context, version string) error
@@ -0,0 +1,173 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// UpdateHealthTimeout is how long an updated application has to pass its health check before being rolled back.
+const UpdateHealthTimeout = 5 * time.Minute
+
+// updateHealthInterval is the delay between health checks while waiting for an updated application.
+const updateHealthInterval = 5 * time.Second
+
+// ErrUpdateRolledBack is returned when an application update failed and the previous version was restored.
+var ErrUpdateRolledBack = errors.New("application update was rolled back")
+
+// KeepPrevious moves the current system extension of the application aside, before it's replaced by an
+// update. The returned function puts it back in place, in case the update can't be applied.
+func KeepPrevious(name string) (func(), error) {
+	currentPath := filepath.Join(systemd.SystemExtensionsPath, name+".raw")
+	previousPath := filepath.Join(systemd.SystemExtensionsPreviousPath, name+".raw")
+
+	err := os.MkdirAll(systemd.SystemExtensionsPreviousPath, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	// Moving the file keeps any loop device backed by it valid.
+	err = os.Rename(currentPath, previousPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return func() {}, nil
+		}
+
+		return nil, err
+	}
+
+	return func() {
+		_ = os.Rename(previousPath, currentPath)
+	}, nil
+}
+
+// UpdateWithRollback notifies a running application that it was updated, then waits for it to pass its
+// health check. If it doesn't within the timeout, the previous version is restored and ErrUpdateRolledBack
+// is returned.
+func UpdateWithRollback(ctx context.Context, s *state.State, name string, version string, timeout time.Duration) error {
+	// Don't let the health monitor restart the application while it's being updated.
+	done, err := StartOperation(name, "update")
+	if err != nil {
+		return err
+	}
+
+	defer done()
+
+	app, err := Load(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	updateErr := app.Update(ctx, version)
+	if updateErr == nil {
+		updateErr = waitHealthy(ctx, app, timeout)
+	}
+
+	if updateErr == nil {
+		updateErr = app.ApplySettings(ctx, s.Applications[name].Config.Settings)
+		if updateErr != nil {
+			updateErr = fmt.Errorf("failed to apply settings: %w", updateErr)
+		}
+	}
+
+	if updateErr == nil {
+		updateErr = RunHook(ctx, s, name, HookPostUpdate, version)
+	}
+
+	if updateErr == nil {
+		appInfo := s.Applications[name]
+		appInfo.State.FailedVersion = ""
+		s.SetApplication(name, appInfo)
+
+		return nil
+	}
+
+	appInfo := s.Applications[name]
+	previousPath := filepath.Join(systemd.SystemExtensionsPreviousPath, name+".raw")
+
+	_, err = os.Stat(previousPath)
+	if appInfo.State.PreviousVersion == "" || err != nil {
+		return updateErr
+	}
+
+	slog.WarnContext(ctx, "Rolling back application update", "name", name, "version", version, "previous", appInfo.State.PreviousVersion, "err", updateErr)
+
+	// Put the previous system extension back in place.
+	err = os.Rename(previousPath, filepath.Join(systemd.SystemExtensionsPath, name+".raw"))
+	if err != nil {
+		return fmt.Errorf("failed to roll back application %q after %w: %w", name, updateErr, err)
+	}
+
+	err = systemd.RefreshExtensions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to roll back application %q after %w: %w", name, updateErr, err)
+	}
+
+	appInfo.State.Version = appInfo.State.PreviousVersion
+	appInfo.State.PreviousVersion = ""
+	appInfo.State.FailedVersion = version
+	s.SetApplication(name, appInfo)
+	_ = s.Save()
+
+	// The application definition may have changed along with its system extension.
+	app, err = Load(ctx, s, name)
+	if err != nil {
+		return fmt.Errorf("failed to roll back application %q after %w: %w", name, updateErr, err)
+	}
+
+	err = app.Update(ctx, appInfo.State.Version)
+	if err != nil {
+		return fmt.Errorf("failed to roll back application %q after %w: %w", name, updateErr, err)
+	}
+
+	s.Events.SendLifecycle(api.EventLifecycleApplicationRolledBack, "/1.0/applications/"+name, map[string]any{
+		"version":        appInfo.State.Version,
+		"failed_version": version,
+		"failure":        updateErr.Error(),
+	})
+
+	return fmt.Errorf("%w to version %s: %w", ErrUpdateRolledBack, appInfo.State.Version, updateErr)
+}
+
+// waitHealthy waits for the application to pass its health check, falling back to checking whether it's
+// running for applications which don't support health checks.
+func waitHealthy(ctx context.Context, app Application, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := app.HealthCheck(checkCtx)
+		cancel()
+
+		if errors.Is(err, ErrHealthCheckNotSupported) {
+			err = nil
+
+			if !app.IsRunning(ctx) {
+				err = errors.New("application isn't running")
+			}
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(updateHealthInterval).After(deadline) {
+			return fmt.Errorf("application didn't become healthy within %s: %w", timeout, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(updateHealthInterval):
+		}
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
@@ -43,6 +44,17 @@ func GetOSBackup() ([]byte, error) {
 			return nil, errors.New("backup cannot contain directories")
 		}
 
+		// The credentials in the state are sealed to the current system, so decrypt them
+		// for the backup to be restorable elsewhere.
+		if file.Name() == "state.txt" {
+			err := addBackupState(tw, filepath.Join("/var/lib/incus-os/", file.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
 		fd, err := os.Open(filepath.Join("/var/lib/incus-os/", file.Name()))
 		if err != nil {
 			return nil, err
@@ -84,6 +96,39 @@ func GetOSBackup() ([]byte, error) {
 	return ret.Bytes(), nil
 }
 
+// addBackupState adds the state to the backup archive, with all credentials decrypted.
+func addBackupState(tw *tar.Writer, path string) error {
+	s, err := state.LoadOrCreate(path)
+	if err != nil {
+		return err
+	}
+
+	err = secrets.OpenState(s)
+	if err != nil {
+		return err
+	}
+
+	body, err := state.Encode(s)
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name: filepath.Base(path),
+		Mode: 0o600,
+		Size: int64(len(body)),
+	}
+
+	err = tw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(body)
+
+	return err
+}
+
 // ApplyOSBackup processes a backup tar archive from the provided io.Reader and performs
 // an OS-level restore. If specific skip options are supplied, some parts of the backup
 // may be omitted.
@@ -228,12 +273,15 @@ func ApplyOSBackup(ctx context.Context, s *state.State, buf io.Reader, skipOptio
 
 func processNewState(ctx context.Context, oldState **state.State, newState *state.State, skipOptions []string) error {
 	// Sanity checks:
-	// 1. Need to be able to use TPM to change encryption recovery passphrase(s).
+	// 1. Need to be able to use TPM to change encryption recovery passphrase(s), unless
+	//    running without a TPM, in which case the current passphrase(s) are used instead.
 	// 2. At least one recovery passphrase provided.
 	// 3. At least one primary application must be installed.
-	tpmStatus := secureboot.TPMStatus()
-	if tpmStatus != "ok" {
-		return errors.New("TPM status isn't OK: " + tpmStatus)
+	if !(*oldState).PassphraseOnly {
+		tpmStatus := secureboot.TPMStatus()
+		if tpmStatus != "ok" {
+			return errors.New("TPM status isn't OK: " + tpmStatus)
+		}
 	}
 
 	if len(newState.System.Security.Config.EncryptionRecoveryKeys) == 0 {
@@ -291,15 +339,33 @@ func processNewState(ctx context.Context, oldState **state.State, newState *stat
 	// Copy over relevant current state.
 	newState.SecureBoot = (*oldState).SecureBoot
 	newState.OS = (*oldState).OS
+	newState.PassphraseOnly = (*oldState).PassphraseOnly
+	newState.UpdateTrustAnchors = (*oldState).UpdateTrustAnchors
+
+	// Seal the restored credentials to the current system.
+	newState.SecretsKey = (*oldState).SecretsKey
+	newState.SecretsKeyFallback = (*oldState).SecretsKeyFallback
+
+	err := secrets.SealState(newState)
+	if err != nil {
+		return err
+	}
 
 	// Clear any stale state from the new struct.
 	newState.Services.Ceph.State = api.ServiceCephState{}
+	newState.Services.Console.State = api.ServiceConsoleState{}
 	newState.Services.ISCSI.State = api.ServiceISCSIState{}
+	newState.Services.Kdump.State = api.ServiceKdumpState{}
 	newState.Services.LVM.State = api.ServiceLVMState{}
 	newState.Services.Multipath.State = api.ServiceMultipathState{}
+	newState.Services.Nftables.State = api.ServiceNftablesState{}
+	newState.Services.NTP.State = api.ServiceNTPState{}
 	newState.Services.NVME.State = api.ServiceNVMEState{}
 	newState.Services.OVN.State = api.ServiceOVNState{}
+	newState.Services.SSH.State = api.ServiceSSHState{}
+	newState.Services.Tuning.State = api.ServiceTuningState{}
 	newState.Services.USBIP.State = api.ServiceUSBIPState{}
+	newState.Services.VRRP.State = api.ServiceVRRPState{}
 	newState.System.Logging.State = api.SystemLoggingState{}
 	newState.System.Network.State = api.SystemNetworkState{}
 	newState.System.Provider.State = api.SystemProviderState{}
@@ -327,27 +393,10 @@ func processNewState(ctx context.Context, oldState **state.State, newState *stat
 		// As the final step, reset the recovery passphrase(s) based on what's in the new state.
 		// This is done at the end, since we really don't want to try to handle reverting the
 		// new passphrase(s) to the old ones if some other part of the backup restore process failed.
-		luksVolumes, err := util.GetLUKSVolumePartitions()
+		err := resetEncryptionKeys(ctx, *oldState, newState)
 		if err != nil {
 			return err
 		}
-
-		for _, volume := range luksVolumes {
-			err := systemd.WipeAllRecoveryKeys(ctx, volume)
-			if err != nil {
-				return err
-			}
-		}
-
-		newKeys := newState.System.Security.Config.EncryptionRecoveryKeys
-		newState.System.Security.Config.EncryptionRecoveryKeys = []string{}
-
-		for _, key := range newKeys {
-			err := systemd.AddEncryptionKey(ctx, newState, key)
-			if err != nil {
-				return err
-			}
-		}
 	}
 
 	// Make sure we set the expected timezone.
@@ -432,3 +481,62 @@ func installApplication(ctx context.Context, s *state.State, p providers.Provide
 
 	return papp.Version(), nil
 }
+
+// resetEncryptionKeys replaces the encryption recovery keys enrolled in the LUKS volumes with those
+// from the new state.
+func resetEncryptionKeys(ctx context.Context, oldState *state.State, newState *state.State) error {
+	newKeys := newState.System.Security.Config.EncryptionRecoveryKeys
+
+	// Without a TPM, the current keys are needed to authorize changes, so add the new keys
+	// before removing the old ones.
+	if newState.PassphraseOnly {
+		newState.System.Security.Config.EncryptionRecoveryKeys = slices.Clone(oldState.System.Security.Config.EncryptionRecoveryKeys)
+
+		for _, key := range newKeys {
+			if slices.Contains(newState.System.Security.Config.EncryptionRecoveryKeys, key) {
+				continue
+			}
+
+			err := systemd.AddEncryptionKey(ctx, newState, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, key := range oldState.System.Security.Config.EncryptionRecoveryKeys {
+			if slices.Contains(newKeys, key) {
+				continue
+			}
+
+			err := systemd.DeleteEncryptionKey(ctx, newState, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range luksVolumes {
+		err := systemd.WipeAllRecoveryKeys(ctx, volume)
+		if err != nil {
+			return err
+		}
+	}
+
+	newState.System.Security.Config.EncryptionRecoveryKeys = []string{}
+
+	for _, key := range newKeys {
+		err := systemd.AddEncryptionKey(ctx, newState, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,3 @@
+// Package dns contains a number of Provider implementations used to manage records in external DNS zones,
+// both for dynamic DNS and to answer ACME DNS-01 challenges.
+package dns
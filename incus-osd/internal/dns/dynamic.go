@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// defaultTTL is the TTL of dynamic records when not configured.
+const defaultTTL = 300
+
+// UpdateDynamicRecords points the configured dynamic records to the system's current addresses.
+// Records are only updated when the addresses changed or the previous update failed.
+func UpdateDynamicRecords(ctx context.Context, s *state.State) error {
+	previous := map[string]api.SystemDNSDynamicRecordState{}
+	for _, record := range s.System.DNS.State.DynamicRecords {
+		previous[record.Name] = record
+	}
+
+	records := make([]api.SystemDNSDynamicRecordState, 0, len(s.System.DNS.Config.DynamicRecords))
+
+	var errs []error
+
+	for _, record := range s.System.DNS.Config.DynamicRecords {
+		recordState := api.SystemDNSDynamicRecordState{
+			Name: record.Name,
+		}
+
+		err := updateDynamicRecord(ctx, s.System.DNS.Config, record, previous[record.Name], &recordState)
+		if err != nil {
+			recordState.Error = err.Error()
+			errs = append(errs, err)
+		}
+
+		records = append(records, recordState)
+	}
+
+	s.System.DNS.State.DynamicRecords = records
+
+	return errors.Join(errs...)
+}
+
+// updateDynamicRecord updates the A and AAAA records of a single dynamic record.
+func updateDynamicRecord(ctx context.Context, config api.SystemDNSConfig, record api.SystemDNSDynamicRecord, previous api.SystemDNSDynamicRecordState, recordState *api.SystemDNSDynamicRecordState) error {
+	addresses, err := getAddresses(record.Interface)
+	if err != nil {
+		return err
+	}
+
+	recordState.Addresses = addresses
+
+	if previous.LastUpdate != nil && previous.Error == "" && slices.Equal(previous.Addresses, addresses) {
+		recordState.LastUpdate = previous.LastUpdate
+
+		return nil
+	}
+
+	p, err := LoadByName(ctx, config, record.Provider)
+	if err != nil {
+		return err
+	}
+
+	ttl := record.TTL
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+
+	for _, recordType := range []string{"A", "AAAA"} {
+		values := []string{}
+
+		for _, address := range addresses {
+			isIPv4 := net.ParseIP(address).To4() != nil
+			if isIPv4 == (recordType == "A") {
+				values = append(values, address)
+			}
+		}
+
+		if len(values) == 0 {
+			err = p.DeleteRecord(ctx, record.Name, recordType)
+		} else {
+			err = p.SetRecord(ctx, record.Name, recordType, values, ttl)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	recordState.LastUpdate = &now
+
+	return nil
+}
+
+// getAddresses returns the sorted global unicast addresses of the given interface, or of all interfaces if empty.
+func getAddresses(name string) ([]string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []string{}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		if name != "" && iface.Name != name {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+
+			if !slices.Contains(addresses, ipNet.IP.String()) {
+				addresses = append(addresses, ipNet.IP.String())
+			}
+		}
+	}
+
+	slices.Sort(addresses)
+
+	return addresses, nil
+}
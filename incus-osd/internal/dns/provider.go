@@ -0,0 +1,179 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+)
+
+// Provider represents a DNS provider records can be managed through.
+type Provider interface {
+	Name() string
+	Type() string
+	Zone() string
+
+	// SetRecord replaces all the records of the given name and type with the provided values.
+	SetRecord(ctx context.Context, name string, recordType string, values []string, ttl int) error
+
+	// DeleteRecord removes all the records of the given name and type.
+	DeleteRecord(ctx context.Context, name string, recordType string) error
+
+	load(ctx context.Context) error
+}
+
+// Load gets a specific DNS provider and initializes it with its configuration.
+func Load(ctx context.Context, config api.SystemDNSProvider) (Provider, error) {
+	var p Provider
+
+	if config.Zone == "" {
+		return nil, errors.New("DNS provider zone cannot be empty")
+	}
+
+	// Decrypt the credentials.
+	providerConfig, err := secrets.OpenMap(config.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the credentials: %w", err)
+	}
+
+	zone := strings.TrimSuffix(strings.ToLower(config.Zone), ".")
+
+	switch config.Type {
+	case "cloudflare":
+		// Setup the Cloudflare provider.
+		p = &cloudflare{
+			name:   config.Name,
+			zone:   zone,
+			config: providerConfig,
+		}
+
+	case "rfc2136":
+		// Setup the RFC2136 provider.
+		p = &rfc2136{
+			name:   config.Name,
+			zone:   zone,
+			config: providerConfig,
+		}
+
+	case "route53":
+		// Setup the Route53 provider.
+		p = &route53{
+			name:   config.Name,
+			zone:   zone,
+			config: providerConfig,
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown DNS provider type %q", config.Type)
+	}
+
+	err = p.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// LoadByName gets the DNS provider with the given name from the configuration.
+func LoadByName(ctx context.Context, config api.SystemDNSConfig, name string) (Provider, error) {
+	for _, provider := range config.Providers {
+		if provider.Name == name {
+			return Load(ctx, provider)
+		}
+	}
+
+	return nil, fmt.Errorf("unknown DNS provider %q", name)
+}
+
+// ValidateConfig checks that all providers can be loaded and that all dynamic records refer to known providers.
+func ValidateConfig(ctx context.Context, config api.SystemDNSConfig) error {
+	providers := map[string]Provider{}
+
+	for _, provider := range config.Providers {
+		if provider.Name == "" {
+			return errors.New("DNS provider name cannot be empty")
+		}
+
+		_, ok := providers[provider.Name]
+		if ok {
+			return fmt.Errorf("duplicate DNS provider %q", provider.Name)
+		}
+
+		p, err := Load(ctx, provider)
+		if err != nil {
+			return fmt.Errorf("invalid DNS provider %q: %w", provider.Name, err)
+		}
+
+		providers[provider.Name] = p
+	}
+
+	names := []string{}
+
+	for _, record := range config.DynamicRecords {
+		p, ok := providers[record.Provider]
+		if !ok {
+			return fmt.Errorf("dynamic record refers to unknown DNS provider %q", record.Provider)
+		}
+
+		if !inZone(record.Name, p.Zone()) {
+			return fmt.Errorf("dynamic record %q isn't in zone %q", record.Name, p.Zone())
+		}
+
+		if slices.Contains(names, record.Name) {
+			return fmt.Errorf("duplicate dynamic record %q", record.Name)
+		}
+
+		if record.TTL < 0 {
+			return fmt.Errorf("invalid TTL for dynamic record %q", record.Name)
+		}
+
+		names = append(names, record.Name)
+	}
+
+	return nil
+}
+
+// SetChallenge publishes the TXT record answering an ACME DNS-01 challenge for the given domain.
+func SetChallenge(ctx context.Context, p Provider, domain string, keyAuthorization string) error {
+	return p.SetRecord(ctx, challengeRecordName(domain), "TXT", []string{challengeRecordValue(keyAuthorization)}, 60)
+}
+
+// ClearChallenge removes the TXT record answering an ACME DNS-01 challenge for the given domain.
+func ClearChallenge(ctx context.Context, p Provider, domain string) error {
+	return p.DeleteRecord(ctx, challengeRecordName(domain), "TXT")
+}
+
+// challengeRecordName returns the name of the TXT record used for ACME DNS-01 challenges.
+func challengeRecordName(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(strings.TrimSuffix(domain, "."), "*.")
+}
+
+// challengeRecordValue returns the value of the TXT record used for ACME DNS-01 challenges.
+func challengeRecordValue(keyAuthorization string) string {
+	digest := sha256.Sum256([]byte(keyAuthorization))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// inZone returns whether a record name is part of the zone.
+func inZone(name string, zone string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// checkRecordType ensures the record type is one supported by all providers.
+func checkRecordType(recordType string) error {
+	if !slices.Contains([]string{"A", "AAAA", "TXT"}, recordType) {
+		return fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// cloudflareAPI is the base URL of the Cloudflare API.
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+type cloudflare struct {
+	name   string
+	zone   string
+	config map[string]string
+
+	token string
+}
+
+// cloudflareResponse represents the envelope of all Cloudflare API responses.
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+func (p *cloudflare) Name() string {
+	return p.name
+}
+
+func (*cloudflare) Type() string {
+	return "cloudflare"
+}
+
+func (p *cloudflare) Zone() string {
+	return p.zone
+}
+
+func (p *cloudflare) SetRecord(ctx context.Context, name string, recordType string, values []string, ttl int) error {
+	err := checkRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	zoneID, err := p.getZoneID(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = p.deleteRecords(ctx, zoneID, name, recordType)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		record := map[string]any{
+			"type":    recordType,
+			"name":    name,
+			"content": value,
+			"ttl":     ttl,
+		}
+
+		err = p.request(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", record, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *cloudflare) DeleteRecord(ctx context.Context, name string, recordType string) error {
+	err := checkRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	zoneID, err := p.getZoneID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.deleteRecords(ctx, zoneID, name, recordType)
+}
+
+func (p *cloudflare) load(_ context.Context) error {
+	p.token = p.config["api_token"]
+	if p.token == "" {
+		return errors.New("cloudflare API token must be provided")
+	}
+
+	return nil
+}
+
+// getZoneID returns the Cloudflare identifier of the zone.
+func (p *cloudflare) getZoneID(ctx context.Context) (string, error) {
+	zones := []struct {
+		ID string `json:"id"`
+	}{}
+
+	err := p.request(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(p.zone), nil, &zones)
+	if err != nil {
+		return "", err
+	}
+
+	if len(zones) != 1 {
+		return "", fmt.Errorf("cloudflare zone %q not found", p.zone)
+	}
+
+	return zones[0].ID, nil
+}
+
+// deleteRecords removes all the records of the given name and type from the zone.
+func (p *cloudflare) deleteRecords(ctx context.Context, zoneID string, name string, recordType string) error {
+	records := []struct {
+		ID string `json:"id"`
+	}{}
+
+	err := p.request(ctx, http.MethodGet, "/zones/"+zoneID+"/dns_records?type="+url.QueryEscape(recordType)+"&name="+url.QueryEscape(strings.TrimSuffix(name, ".")), nil, &records)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		err = p.request(ctx, http.MethodDelete, "/zones/"+zoneID+"/dns_records/"+record.ID, nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// request sends a request to the Cloudflare API, decoding the result into the target if provided.
+func (p *cloudflare) request(ctx context.Context, method string, path string, data any, target any) error {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DNSRequest)
+	defer cancel()
+
+	var body io.Reader
+
+	if data != nil {
+		content, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	cfResp := cloudflareResponse{}
+
+	err = json.NewDecoder(resp.Body).Decode(&cfResp)
+	if err != nil {
+		return fmt.Errorf("cloudflare returned an invalid response with status %q: %w", resp.Status, err)
+	}
+
+	if !cfResp.Success {
+		if len(cfResp.Errors) > 0 {
+			return fmt.Errorf("cloudflare request failed: %s (%d)", cfResp.Errors[0].Message, cfResp.Errors[0].Code)
+		}
+
+		return fmt.Errorf("cloudflare request failed with status %q", resp.Status)
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	return json.Unmarshal(cfResp.Result, target)
+}
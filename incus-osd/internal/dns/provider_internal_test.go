@@ -0,0 +1,189 @@
+package dns
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Test the record used to answer ACME DNS-01 challenges.
+func TestChallengeRecord(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "_acme-challenge.example.com", challengeRecordName("example.com."))
+	require.Equal(t, "_acme-challenge.example.com", challengeRecordName("*.example.com"))
+	require.Equal(t, "47DEQpj8HBSa-_TImW-5JCeuQeRkm5NMpJWZG3hSuFU", challengeRecordValue(""))
+}
+
+// Test validation of the DNS configuration.
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	config := api.SystemDNSConfig{
+		Providers: []api.SystemDNSProvider{
+			{Name: "cf", Type: "cloudflare", Zone: "example.com", Config: map[string]string{"api_token": "secret"}},
+			{Name: "ns", Type: "rfc2136", Zone: "lab.example.com", Config: map[string]string{"server": "192.0.2.53"}},
+		},
+		DynamicRecords: []api.SystemDNSDynamicRecord{{Provider: "cf", Name: "server01.example.com"}},
+	}
+
+	require.NoError(t, ValidateConfig(t.Context(), config))
+
+	// Record outside of the provider's zone.
+	config.DynamicRecords = []api.SystemDNSDynamicRecord{{Provider: "ns", Name: "server01.example.com"}}
+	require.Error(t, ValidateConfig(t.Context(), config))
+
+	// Unknown provider.
+	config.DynamicRecords = []api.SystemDNSDynamicRecord{{Provider: "other", Name: "server01.example.com"}}
+	require.Error(t, ValidateConfig(t.Context(), config))
+
+	// Missing credentials.
+	config.Providers[0].Config = nil
+	config.DynamicRecords = nil
+	require.Error(t, ValidateConfig(t.Context(), config))
+}
+
+// Test the DNS wire format encoding used for RFC2136 updates.
+func TestWireFormat(t *testing.T) {
+	t.Parallel()
+
+	name, err := appendName(nil, "example.com.")
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x07example\x03com\x00"), name)
+
+	_, err = appendName(nil, "example..com")
+	require.Error(t, err)
+
+	data, err := encodeRecordData("A", "192.0.2.1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{192, 0, 2, 1}, data)
+
+	_, err = encodeRecordData("A", "2001:db8::1")
+	require.Error(t, err)
+
+	data, err = encodeRecordData("AAAA", "2001:db8::1")
+	require.NoError(t, err)
+	require.Len(t, data, 16)
+
+	data, err = encodeRecordData("TXT", strings.Repeat("a", 300))
+	require.NoError(t, err)
+	require.Len(t, data, 302)
+	require.Equal(t, byte(255), data[0])
+	require.Equal(t, byte(45), data[256])
+}
+
+// signTestResponse builds a response to an RFC2136 update, signed with TSIG using the provided secret.
+func signTestResponse(t *testing.T, request []byte, requestMAC []byte, secret []byte, signedAt time.Time, tsigError uint16) []byte {
+	t.Helper()
+
+	zone, err := appendName(nil, "example.com")
+	require.NoError(t, err)
+
+	keyName, err := appendName(nil, "update-key")
+	require.NoError(t, err)
+
+	algorithm, err := appendName(nil, "hmac-sha256")
+	require.NoError(t, err)
+
+	// Header with the QR bit set, followed by the zone section.
+	resp := append([]byte{}, request[0:2]...)
+	resp = binary.BigEndian.AppendUint16(resp, 0x8000|dnsOpcodeUpdate<<11)
+	resp = binary.BigEndian.AppendUint16(resp, 1)
+	resp = binary.BigEndian.AppendUint16(resp, 0)
+	resp = binary.BigEndian.AppendUint16(resp, 0)
+	resp = binary.BigEndian.AppendUint16(resp, 0)
+	resp = append(resp, zone...)
+	resp = binary.BigEndian.AppendUint16(resp, dnsTypeSOA)
+	resp = binary.BigEndian.AppendUint16(resp, dnsClassIN)
+
+	timeSigned := binary.BigEndian.AppendUint64(nil, uint64(signedAt.Unix()))[2:] //nolint:gosec
+
+	signature := []byte{}
+
+	if tsigError == 0 {
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC)))) //nolint:gosec
+		_, _ = mac.Write(requestMAC)
+		_, _ = mac.Write(resp)
+		_, _ = mac.Write(keyName)
+		_, _ = mac.Write([]byte{0x00, 0xff, 0x00, 0x00, 0x00, 0x00})
+		_, _ = mac.Write(algorithm)
+		_, _ = mac.Write(timeSigned)
+		_, _ = mac.Write([]byte{0x01, 0x2c, 0x00, 0x00, 0x00, 0x00})
+		signature = mac.Sum(nil)
+	}
+
+	data := append([]byte{}, algorithm...)
+	data = append(data, timeSigned...)
+	data = binary.BigEndian.AppendUint16(data, 300)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(signature))) //nolint:gosec
+	data = append(data, signature...)
+	data = append(data, request[0:2]...)
+	data = binary.BigEndian.AppendUint16(data, tsigError)
+	data = binary.BigEndian.AppendUint16(data, 0)
+
+	resp = append(resp, keyName...)
+	resp = binary.BigEndian.AppendUint16(resp, dnsTypeTSIG)
+	resp = binary.BigEndian.AppendUint16(resp, dnsClassANY)
+	resp = binary.BigEndian.AppendUint32(resp, 0)
+	resp = binary.BigEndian.AppendUint16(resp, uint16(len(data))) //nolint:gosec
+	resp = append(resp, data...)
+
+	binary.BigEndian.PutUint16(resp[10:12], 1)
+
+	return resp
+}
+
+// Test the verification of TSIG signed responses to RFC2136 updates.
+func TestVerifyResponse(t *testing.T) {
+	t.Parallel()
+
+	p := &rfc2136{
+		zone:          "example.com",
+		tsigKeyName:   "Update-Key",
+		tsigSecret:    []byte("secret"),
+		tsigAlgorithm: "hmac-sha256",
+	}
+
+	now := time.Now()
+
+	request, requestMAC, err := p.buildUpdate([]dnsRecord{{name: "server01.example.com", recordType: dnsRecordTypes["A"], class: dnsClassANY}}, now)
+	require.NoError(t, err)
+	require.Len(t, requestMAC, sha256.Size)
+
+	// Valid response.
+	resp := signTestResponse(t, request, requestMAC, p.tsigSecret, now, 0)
+	require.NoError(t, p.verifyResponse(resp, requestMAC, now))
+
+	// Response to another request.
+	require.EqualError(t, p.verifyResponse(resp, make([]byte, sha256.Size), now), "invalid TSIG signature on DNS server response")
+
+	// Unsigned response.
+	unsigned := append([]byte{}, resp[:12+len("\x07example\x03com\x00")+4]...)
+	binary.BigEndian.PutUint16(unsigned[10:12], 0)
+	require.EqualError(t, p.verifyResponse(unsigned, requestMAC, now), "DNS server response isn't signed")
+
+	// Tampered response.
+	tampered := append([]byte{}, resp...)
+	tampered[3] |= 0x05
+	require.EqualError(t, p.verifyResponse(tampered, requestMAC, now), "invalid TSIG signature on DNS server response")
+
+	// Response signed with another key.
+	resp = signTestResponse(t, request, requestMAC, []byte("other"), now, 0)
+	require.EqualError(t, p.verifyResponse(resp, requestMAC, now), "invalid TSIG signature on DNS server response")
+
+	// Response signed outside of the allowed time window.
+	resp = signTestResponse(t, request, requestMAC, p.tsigSecret, now.Add(-time.Hour), 0)
+	require.EqualError(t, p.verifyResponse(resp, requestMAC, now), "DNS server response was signed outside of the allowed time window")
+
+	// Request rejected by the server.
+	resp = signTestResponse(t, request, requestMAC, p.tsigSecret, now, 16)
+	require.EqualError(t, p.verifyResponse(resp, requestMAC, now), "DNS server rejected the TSIG signature: BADSIG")
+}
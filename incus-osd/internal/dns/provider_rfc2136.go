@@ -0,0 +1,562 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// DNS constants used when building RFC2136 update messages.
+const (
+	dnsOpcodeUpdate = 5
+	dnsClassIN      = 1
+	dnsClassANY     = 255
+	dnsTypeSOA      = 6
+	dnsTypeTSIG     = 250
+	dnsTSIGFudge    = 300
+)
+
+// tsigErrors maps the TSIG error codes to their name.
+var tsigErrors = map[uint16]string{
+	16: "BADSIG",
+	17: "BADKEY",
+	18: "BADTIME",
+	22: "BADTRUNC",
+}
+
+// dnsRecordTypes maps the supported record types to their numeric value.
+var dnsRecordTypes = map[string]uint16{
+	"A":    1,
+	"TXT":  16,
+	"AAAA": 28,
+}
+
+// dnsRcodes maps the DNS response codes to their name.
+var dnsRcodes = map[int]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// tsigAlgorithms maps the supported TSIG algorithms to their hash function.
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-sha256": sha256.New,
+	"hmac-sha512": sha512.New,
+}
+
+type rfc2136 struct {
+	name   string
+	zone   string
+	config map[string]string
+
+	server        string
+	tsigKeyName   string
+	tsigSecret    []byte
+	tsigAlgorithm string
+}
+
+// dnsRecord represents a resource record in an RFC2136 update message.
+type dnsRecord struct {
+	name       string
+	recordType uint16
+	class      uint16
+	ttl        uint32
+	data       []byte
+}
+
+func (p *rfc2136) Name() string {
+	return p.name
+}
+
+func (*rfc2136) Type() string {
+	return "rfc2136"
+}
+
+func (p *rfc2136) Zone() string {
+	return p.zone
+}
+
+func (p *rfc2136) SetRecord(ctx context.Context, name string, recordType string, values []string, ttl int) error {
+	err := checkRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	// Replace the whole record set in a single update.
+	updates := []dnsRecord{{name: name, recordType: dnsRecordTypes[recordType], class: dnsClassANY}}
+
+	for _, value := range values {
+		data, err := encodeRecordData(recordType, value)
+		if err != nil {
+			return err
+		}
+
+		updates = append(updates, dnsRecord{name: name, recordType: dnsRecordTypes[recordType], class: dnsClassIN, ttl: uint32(ttl), data: data}) //nolint:gosec
+	}
+
+	return p.update(ctx, updates)
+}
+
+func (p *rfc2136) DeleteRecord(ctx context.Context, name string, recordType string) error {
+	err := checkRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	return p.update(ctx, []dnsRecord{{name: name, recordType: dnsRecordTypes[recordType], class: dnsClassANY}})
+}
+
+func (p *rfc2136) load(_ context.Context) error {
+	p.server = p.config["server"]
+	if p.server == "" {
+		return errors.New("rfc2136 server must be provided")
+	}
+
+	_, _, err := net.SplitHostPort(p.server)
+	if err != nil {
+		p.server = net.JoinHostPort(strings.Trim(p.server, "[]"), "53")
+	}
+
+	p.tsigKeyName = p.config["tsig_key_name"]
+	if p.tsigKeyName == "" {
+		// Unauthenticated updates, only accepted by servers allowing them by address.
+		return nil
+	}
+
+	p.tsigAlgorithm = p.config["tsig_algorithm"]
+	if p.tsigAlgorithm == "" {
+		p.tsigAlgorithm = "hmac-sha256"
+	}
+
+	_, ok := tsigAlgorithms[p.tsigAlgorithm]
+	if !ok {
+		return fmt.Errorf("unsupported TSIG algorithm %q", p.tsigAlgorithm)
+	}
+
+	p.tsigSecret, err = base64.StdEncoding.DecodeString(p.config["tsig_secret"])
+	if err != nil {
+		return fmt.Errorf("invalid TSIG secret: %w", err)
+	}
+
+	if len(p.tsigSecret) == 0 {
+		return errors.New("rfc2136 TSIG secret must be provided along with the key name")
+	}
+
+	return nil
+}
+
+// update sends a DNS UPDATE message to the server over TCP and checks its response code.
+func (p *rfc2136) update(ctx context.Context, updates []dnsRecord) error {
+	msg, requestMAC, err := p.buildUpdate(updates, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DNSRequest)
+	defer cancel()
+
+	dialer := net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "tcp", p.server)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if ok {
+		err = conn.SetDeadline(deadline)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Messages sent over TCP are prefixed with their length.
+	_, err = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...)) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	length := make([]byte, 2)
+
+	_, err = io.ReadFull(conn, length)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]byte, binary.BigEndian.Uint16(length))
+
+	_, err = io.ReadFull(conn, resp)
+	if err != nil {
+		return err
+	}
+
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp[0:2]) != binary.BigEndian.Uint16(msg[0:2]) {
+		return errors.New("invalid response from DNS server")
+	}
+
+	// Make sure the response comes from a server holding the key before trusting it.
+	if p.tsigKeyName != "" {
+		err = p.verifyResponse(resp, requestMAC, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	rcode := int(resp[3] & 0x0f)
+	if rcode != 0 {
+		name, ok := dnsRcodes[rcode]
+		if !ok {
+			name = fmt.Sprintf("rcode %d", rcode)
+		}
+
+		return fmt.Errorf("DNS update of zone %q was rejected: %s", p.zone, name)
+	}
+
+	return nil
+}
+
+// buildUpdate builds a DNS UPDATE message for the zone, signing it with TSIG when a key is configured.
+// The MAC of the request is returned along with it, as the response's signature covers it.
+func (p *rfc2136) buildUpdate(updates []dnsRecord, now time.Time) ([]byte, []byte, error) {
+	id := make([]byte, 2)
+
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Header.
+	msg := append([]byte{}, id...)
+	msg = binary.BigEndian.AppendUint16(msg, dnsOpcodeUpdate<<11)
+	msg = binary.BigEndian.AppendUint16(msg, 1)
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(updates))) //nolint:gosec
+	msg = binary.BigEndian.AppendUint16(msg, 0)
+
+	// Zone section.
+	msg, err = appendName(msg, p.zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeSOA)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+
+	// Update section.
+	for _, record := range updates {
+		msg, err = appendRecord(msg, record)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if p.tsigKeyName == "" {
+		return msg, nil, nil
+	}
+
+	return p.signMessage(msg, now)
+}
+
+// signMessage appends a TSIG record to the message as described in RFC8945, returning the signed
+// message and its MAC.
+func (p *rfc2136) signMessage(msg []byte, now time.Time) ([]byte, []byte, error) {
+	keyName, algorithm, err := p.tsigNames()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeSigned := binary.BigEndian.AppendUint64(nil, uint64(now.Unix()))[2:] //nolint:gosec
+
+	// The MAC covers the message followed by the TSIG variables.
+	mac := hmac.New(tsigAlgorithms[p.tsigAlgorithm], p.tsigSecret)
+	_, _ = mac.Write(msg)
+	_, _ = mac.Write(tsigVariables(keyName, algorithm, timeSigned, dnsTSIGFudge, 0, nil))
+
+	signature := mac.Sum(nil)
+
+	data := append([]byte{}, algorithm...)
+	data = append(data, timeSigned...)
+	data = binary.BigEndian.AppendUint16(data, dnsTSIGFudge)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(signature))) //nolint:gosec
+	data = append(data, signature...)
+	data = append(data, msg[0:2]...)
+	data = binary.BigEndian.AppendUint16(data, 0)
+	data = binary.BigEndian.AppendUint16(data, 0)
+
+	signed := append([]byte{}, msg...)
+	signed = append(signed, keyName...)
+	signed = binary.BigEndian.AppendUint16(signed, dnsTypeTSIG)
+	signed = binary.BigEndian.AppendUint16(signed, dnsClassANY)
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(data))) //nolint:gosec
+	signed = append(signed, data...)
+
+	// Account for the TSIG record in the additional section.
+	binary.BigEndian.PutUint16(signed[10:12], binary.BigEndian.Uint16(msg[10:12])+1)
+
+	return signed, signature, nil
+}
+
+// verifyResponse checks the TSIG record of a response to a request with the provided MAC, as described
+// in RFC8945. Unsigned responses are rejected.
+func (p *rfc2136) verifyResponse(resp []byte, requestMAC []byte, now time.Time) error {
+	keyName, algorithm, err := p.tsigNames()
+	if err != nil {
+		return err
+	}
+
+	invalid := errors.New("invalid TSIG record in DNS server response")
+
+	// The TSIG record must be the last record of the additional section.
+	additional := binary.BigEndian.Uint16(resp[10:12])
+	if additional == 0 {
+		return errors.New("DNS server response isn't signed")
+	}
+
+	offset := 12
+
+	for range binary.BigEndian.Uint16(resp[4:6]) {
+		offset, err = skipName(resp, offset)
+		if err != nil || offset+4 > len(resp) {
+			return invalid
+		}
+
+		offset += 4
+	}
+
+	records := int(binary.BigEndian.Uint16(resp[6:8])) + int(binary.BigEndian.Uint16(resp[8:10])) + int(additional) - 1
+
+	for range records {
+		offset, err = skipName(resp, offset)
+		if err != nil || offset+10 > len(resp) {
+			return invalid
+		}
+
+		offset += 10 + int(binary.BigEndian.Uint16(resp[offset+8:offset+10]))
+		if offset > len(resp) {
+			return invalid
+		}
+	}
+
+	// The names of the TSIG record are never compressed.
+	tsigStart := offset
+
+	offset, err = skipName(resp, offset)
+	if err != nil || offset+10 > len(resp) || !strings.EqualFold(string(resp[tsigStart:offset]), string(keyName)) {
+		return errors.New("DNS server response isn't signed with the configured TSIG key")
+	}
+
+	if binary.BigEndian.Uint16(resp[offset:offset+2]) != dnsTypeTSIG || binary.BigEndian.Uint16(resp[offset+2:offset+4]) != dnsClassANY {
+		return errors.New("DNS server response isn't signed")
+	}
+
+	data := resp[offset+10:]
+	if len(data) != int(binary.BigEndian.Uint16(resp[offset+8:offset+10])) {
+		return invalid
+	}
+
+	// Parse the record data.
+	if len(data) < len(algorithm)+10 || !strings.EqualFold(string(data[:len(algorithm)]), string(algorithm)) {
+		return invalid
+	}
+
+	data = data[len(algorithm):]
+	timeSigned := data[0:6]
+	fudge := binary.BigEndian.Uint16(data[6:8])
+	macSize := int(binary.BigEndian.Uint16(data[8:10]))
+
+	if len(data) < 10+macSize+6 {
+		return invalid
+	}
+
+	signature := data[10 : 10+macSize]
+	originalID := data[10+macSize : 12+macSize]
+	tsigError := binary.BigEndian.Uint16(data[12+macSize : 14+macSize])
+	otherSize := int(binary.BigEndian.Uint16(data[14+macSize : 16+macSize]))
+
+	if len(data) != 16+macSize+otherSize {
+		return invalid
+	}
+
+	other := data[16+macSize:]
+
+	if tsigError != 0 {
+		name, ok := tsigErrors[tsigError]
+		if !ok {
+			name = fmt.Sprintf("error %d", tsigError)
+		}
+
+		return fmt.Errorf("DNS server rejected the TSIG signature: %s", name)
+	}
+
+	// The MAC covers the request's MAC, the response without its TSIG record and the TSIG variables.
+	unsigned := append([]byte{}, resp[:tsigStart]...)
+	copy(unsigned[0:2], originalID)
+	binary.BigEndian.PutUint16(unsigned[10:12], additional-1)
+
+	mac := hmac.New(tsigAlgorithms[p.tsigAlgorithm], p.tsigSecret)
+	_, _ = mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC)))) //nolint:gosec
+	_, _ = mac.Write(requestMAC)
+	_, _ = mac.Write(unsigned)
+	_, _ = mac.Write(tsigVariables(keyName, algorithm, timeSigned, fudge, tsigError, other))
+
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return errors.New("invalid TSIG signature on DNS server response")
+	}
+
+	signedAt := time.Unix(int64(binary.BigEndian.Uint64(append([]byte{0, 0}, timeSigned...))), 0) //nolint:gosec
+	if now.Sub(signedAt).Abs() > time.Duration(fudge)*time.Second {
+		return errors.New("DNS server response was signed outside of the allowed time window")
+	}
+
+	return nil
+}
+
+// tsigNames returns the wire format of the TSIG key and algorithm names.
+func (p *rfc2136) tsigNames() ([]byte, []byte, error) {
+	keyName, err := appendName(nil, strings.ToLower(p.tsigKeyName))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	algorithm, err := appendName(nil, p.tsigAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keyName, algorithm, nil
+}
+
+// tsigVariables returns the TSIG variables covered by the MAC of a message.
+func tsigVariables(keyName []byte, algorithm []byte, timeSigned []byte, fudge uint16, tsigError uint16, other []byte) []byte {
+	variables := append([]byte{}, keyName...)
+	variables = binary.BigEndian.AppendUint16(variables, dnsClassANY)
+	variables = binary.BigEndian.AppendUint32(variables, 0)
+	variables = append(variables, algorithm...)
+	variables = append(variables, timeSigned...)
+	variables = binary.BigEndian.AppendUint16(variables, fudge)
+	variables = binary.BigEndian.AppendUint16(variables, tsigError)
+	variables = binary.BigEndian.AppendUint16(variables, uint16(len(other))) //nolint:gosec
+
+	return append(variables, other...)
+}
+
+// appendRecord appends a resource record in wire format.
+func appendRecord(msg []byte, record dnsRecord) ([]byte, error) {
+	msg, err := appendName(msg, record.name)
+	if err != nil {
+		return nil, err
+	}
+
+	msg = binary.BigEndian.AppendUint16(msg, record.recordType)
+	msg = binary.BigEndian.AppendUint16(msg, record.class)
+	msg = binary.BigEndian.AppendUint32(msg, record.ttl)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(record.data))) //nolint:gosec
+	msg = append(msg, record.data...)
+
+	return msg, nil
+}
+
+// appendName appends an uncompressed domain name in wire format.
+func appendName(msg []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+
+	if name != "" {
+		for label := range strings.SplitSeq(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid domain name %q", name)
+			}
+
+			msg = append(msg, byte(len(label)))
+			msg = append(msg, label...)
+		}
+	}
+
+	return append(msg, 0), nil
+}
+
+// skipName returns the offset following a possibly compressed domain name in a message.
+func skipName(msg []byte, offset int) (int, error) {
+	for offset < len(msg) {
+		length := int(msg[offset])
+
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			// Compression pointer, ending the name.
+			if offset+2 > len(msg) {
+				return 0, errors.New("truncated domain name")
+			}
+
+			return offset + 2, nil
+		case length > 63:
+			return 0, errors.New("invalid domain name label")
+		}
+
+		offset += 1 + length
+	}
+
+	return 0, errors.New("truncated domain name")
+}
+
+// encodeRecordData returns the wire format of a record's value.
+func encodeRecordData(recordType string, value string) ([]byte, error) {
+	switch recordType {
+	case "A", "AAAA":
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if recordType == "A" && !addr.Is4() {
+			return nil, fmt.Errorf("%q isn't an IPv4 address", value)
+		}
+
+		if recordType == "AAAA" && (!addr.Is6() || addr.Is4In6()) {
+			return nil, fmt.Errorf("%q isn't an IPv6 address", value)
+		}
+
+		return addr.AsSlice(), nil
+
+	case "TXT":
+		// TXT records are made of strings of at most 255 bytes.
+		data := []byte{}
+
+		for len(value) > 255 {
+			data = append(data, 255)
+			data = append(data, value[:255]...)
+			value = value[255:]
+		}
+
+		data = append(data, byte(len(value)))
+		data = append(data, value...)
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("unsupported record type %q", recordType)
+}
@@ -0,0 +1,258 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// route53Host is the endpoint of the Route53 API, which is global and signed for the us-east-1 region.
+const route53Host = "route53.amazonaws.com"
+
+type route53 struct {
+	name   string
+	zone   string
+	config map[string]string
+
+	accessKeyID     string
+	secretAccessKey string
+	hostedZoneID    string
+}
+
+// route53RecordSet represents a resource record set in Route53 API requests and responses.
+type route53RecordSet struct {
+	Name            string   `xml:"Name"`
+	Type            string   `xml:"Type"`
+	TTL             int      `xml:"TTL"`
+	ResourceRecords []string `xml:"ResourceRecords>ResourceRecord>Value"`
+}
+
+// route53ChangeRequest represents a ChangeResourceRecordSets request.
+type route53ChangeRequest struct {
+	XMLName xml.Name `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Changes []struct {
+		Action            string           `xml:"Action"`
+		ResourceRecordSet route53RecordSet `xml:"ResourceRecordSet"`
+	} `xml:"ChangeBatch>Changes>Change"`
+}
+
+func (p *route53) Name() string {
+	return p.name
+}
+
+func (*route53) Type() string {
+	return "route53"
+}
+
+func (p *route53) Zone() string {
+	return p.zone
+}
+
+func (p *route53) SetRecord(ctx context.Context, name string, recordType string, values []string, ttl int) error {
+	err := checkRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	recordSet := route53RecordSet{
+		Name: fqdn(name),
+		Type: recordType,
+		TTL:  ttl,
+	}
+
+	for _, value := range values {
+		if recordType == "TXT" {
+			value = `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+		}
+
+		recordSet.ResourceRecords = append(recordSet.ResourceRecords, value)
+	}
+
+	return p.change(ctx, "UPSERT", recordSet)
+}
+
+func (p *route53) DeleteRecord(ctx context.Context, name string, recordType string) error {
+	err := checkRecordType(recordType)
+	if err != nil {
+		return err
+	}
+
+	// Route53 requires the current values to delete a record set.
+	query := url.Values{}
+	query.Set("maxitems", "1")
+	query.Set("name", fqdn(name))
+	query.Set("type", recordType)
+
+	content, err := p.request(ctx, http.MethodGet, "/2013-04-01/hostedzone/"+p.hostedZoneID+"/rrset", query, nil)
+	if err != nil {
+		return err
+	}
+
+	resp := struct {
+		ResourceRecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}{}
+
+	err = xml.Unmarshal(content, &resp)
+	if err != nil {
+		return err
+	}
+
+	if len(resp.ResourceRecordSets) == 0 {
+		return nil
+	}
+
+	recordSet := resp.ResourceRecordSets[0]
+	if !strings.EqualFold(recordSet.Name, fqdn(name)) || recordSet.Type != recordType {
+		return nil
+	}
+
+	return p.change(ctx, "DELETE", recordSet)
+}
+
+func (p *route53) load(_ context.Context) error {
+	p.accessKeyID = p.config["access_key_id"]
+	p.secretAccessKey = p.config["secret_access_key"]
+	p.hostedZoneID = strings.TrimPrefix(p.config["hosted_zone_id"], "/hostedzone/")
+
+	if p.accessKeyID == "" || p.secretAccessKey == "" {
+		return errors.New("route53 access key ID and secret access key must be provided")
+	}
+
+	if p.hostedZoneID == "" {
+		return errors.New("route53 hosted zone ID must be provided")
+	}
+
+	return nil
+}
+
+// change applies a single change to a record set.
+func (p *route53) change(ctx context.Context, action string, recordSet route53RecordSet) error {
+	req := route53ChangeRequest{}
+	req.Changes = append(req.Changes, struct {
+		Action            string           `xml:"Action"`
+		ResourceRecordSet route53RecordSet `xml:"ResourceRecordSet"`
+	}{Action: action, ResourceRecordSet: recordSet})
+
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.request(ctx, http.MethodPost, "/2013-04-01/hostedzone/"+p.hostedZoneID+"/rrset", nil, append([]byte(xml.Header), body...))
+
+	return err
+}
+
+// request sends a signed request to the Route53 API and returns the response body.
+func (p *route53) request(ctx context.Context, method string, path string, query url.Values, body []byte) ([]byte, error) {
+	ctx, cancel := timeout.WithTimeout(ctx, timeout.DNSRequest)
+	defer cancel()
+
+	rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	reqURL := "https://" + route53Host + path
+	if rawQuery != "" {
+		reqURL += "?" + rawQuery
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+
+	signAWSRequest(req, path, rawQuery, body, p.accessKeyID, p.secretAccessKey, time.Now())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := struct {
+			Message string `xml:"Error>Message"`
+		}{}
+
+		_ = xml.Unmarshal(content, &apiErr)
+		if apiErr.Message != "" {
+			return nil, fmt.Errorf("route53 request failed: %s", apiErr.Message)
+		}
+
+		return nil, fmt.Errorf("route53 request failed with status %q", resp.Status)
+	}
+
+	return content, nil
+}
+
+// signAWSRequest adds an AWS Signature Version 4 to a Route53 request.
+func signAWSRequest(req *http.Request, path string, rawQuery string, body []byte, accessKeyID string, secretAccessKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + route53Host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + amzDate + "\n"
+	canonicalRequest := strings.Join([]string{req.Method, path, rawQuery, canonicalHeaders, signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/us-east-1/route53/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, "us-east-1", "route53", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// sha256Hex returns the hex-encoded SHA256 digest of the data.
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+
+	return hex.EncodeToString(digest[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data with the given key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// fqdn returns the name with a trailing dot.
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	return name + "."
+}
@@ -0,0 +1,3 @@
+// Package events distributes system events to subscribers, with a bounded queue per subscriber so that a
+// slow subscriber can neither block event producers nor grow memory usage without bound.
+package events
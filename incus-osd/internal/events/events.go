@@ -0,0 +1,245 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// DefaultQueueSize is the number of events queued for a subscriber when not otherwise specified.
+const DefaultQueueSize = 256
+
+// MaxQueueSize is the largest number of events which can be queued for a single subscriber.
+const MaxQueueSize = 4096
+
+// ErrSlowSubscriber is returned to a subscriber using the disconnect drop policy once its queue overflowed.
+var ErrSlowSubscriber = errors.New("subscriber disconnected for not keeping up with events")
+
+// ErrClosed is returned to a subscriber once it has been closed.
+var ErrClosed = errors.New("subscriber is closed")
+
+// Server distributes events to all its subscribers.
+type Server struct {
+	mu          sync.Mutex
+	subscribers []*Subscriber
+}
+
+// Subscriber receives events from a Server through a bounded queue.
+type Subscriber struct {
+	server *Server
+
+	types     []api.EventType
+	policy    api.EventDropPolicy
+	connected time.Time
+
+	mu           sync.Mutex
+	queue        chan api.Event
+	dropped      uint64 // Since the last lag event.
+	droppedTotal uint64
+	overflowed   bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer returns a new event server without any subscriber.
+func NewServer() *Server {
+	return &Server{}
+}
+
+// Subscribe registers a new subscriber for the provided event types, or all events if empty.
+// A queue size of zero selects the default size and an empty policy drops the oldest events.
+func (s *Server) Subscribe(types []api.EventType, queueSize int, policy api.EventDropPolicy) (*Subscriber, error) {
+	for _, eventType := range types {
+		if !slices.Contains(api.EventTypes, eventType) {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+
+	if queueSize == 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	if queueSize < 0 || queueSize > MaxQueueSize {
+		return nil, fmt.Errorf("invalid queue size %d, must be between 1 and %d", queueSize, MaxQueueSize)
+	}
+
+	if policy == "" {
+		policy = api.EventDropPolicyOldest
+	}
+
+	if policy != api.EventDropPolicyOldest && policy != api.EventDropPolicyNewest && policy != api.EventDropPolicyDisconnect {
+		return nil, fmt.Errorf("unknown drop policy %q", policy)
+	}
+
+	sub := &Subscriber{
+		server:    s,
+		types:     types,
+		policy:    policy,
+		connected: time.Now(),
+		queue:     make(chan api.Event, queueSize),
+		done:      make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, sub)
+	s.mu.Unlock()
+
+	return sub, nil
+}
+
+// Send queues an event for all interested subscribers. It never blocks, subscribers which can't
+// keep up have events dropped according to their policy.
+func (s *Server) Send(eventType api.EventType, metadata any) {
+	if s == nil {
+		return
+	}
+
+	event := api.Event{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Metadata:  metadata,
+	}
+
+	s.mu.Lock()
+	subscribers := slices.Clone(s.subscribers)
+	s.mu.Unlock()
+
+	for _, sub := range subscribers {
+		if len(sub.types) > 0 && !slices.Contains(sub.types, eventType) {
+			continue
+		}
+
+		sub.enqueue(event)
+	}
+}
+
+// SendLifecycle queues a lifecycle event for all interested subscribers.
+func (s *Server) SendLifecycle(action api.EventLifecycleAction, source string, details map[string]any) {
+	s.Send(api.EventTypeLifecycle, api.EventLifecycle{
+		Action:  action,
+		Source:  source,
+		Context: details,
+	})
+}
+
+// Subscribers returns information about all the current subscribers.
+func (s *Server) Subscribers() []api.EventSubscriber {
+	s.mu.Lock()
+	subscribers := slices.Clone(s.subscribers)
+	s.mu.Unlock()
+
+	ret := make([]api.EventSubscriber, 0, len(subscribers))
+
+	for _, sub := range subscribers {
+		sub.mu.Lock()
+		ret = append(ret, api.EventSubscriber{
+			Types:      sub.types,
+			DropPolicy: sub.policy,
+			QueueSize:  cap(sub.queue),
+			Queued:     len(sub.queue),
+			Dropped:    sub.droppedTotal,
+			Connected:  sub.connected,
+		})
+		sub.mu.Unlock()
+	}
+
+	return ret
+}
+
+// Next returns the next event for the subscriber, waiting for one to be available. If events were
+// dropped since the previous call, a lag event reporting how many is returned first.
+func (sub *Subscriber) Next(ctx context.Context) (api.Event, error) {
+	sub.mu.Lock()
+	dropped := sub.dropped
+	sub.dropped = 0
+	overflowed := sub.overflowed
+	sub.mu.Unlock()
+
+	if overflowed {
+		return api.Event{}, ErrSlowSubscriber
+	}
+
+	if dropped > 0 {
+		return api.Event{
+			Type:      api.EventTypeLag,
+			Timestamp: time.Now().UTC(),
+			Metadata:  api.EventLag{Dropped: dropped},
+		}, nil
+	}
+
+	select {
+	case event := <-sub.queue:
+		return event, nil
+	case <-sub.done:
+		sub.mu.Lock()
+		overflowed = sub.overflowed
+		sub.mu.Unlock()
+
+		if overflowed {
+			return api.Event{}, ErrSlowSubscriber
+		}
+
+		return api.Event{}, ErrClosed
+	case <-ctx.Done():
+		return api.Event{}, ctx.Err()
+	}
+}
+
+// Close unregisters the subscriber.
+func (sub *Subscriber) Close() {
+	sub.closeOnce.Do(func() {
+		close(sub.done)
+
+		sub.server.mu.Lock()
+		sub.server.subscribers = slices.DeleteFunc(sub.server.subscribers, func(entry *Subscriber) bool {
+			return entry == sub
+		})
+		sub.server.mu.Unlock()
+	})
+}
+
+// enqueue adds an event to the subscriber's queue, applying the drop policy if it's full.
+func (sub *Subscriber) enqueue(event api.Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.overflowed {
+		return
+	}
+
+	select {
+	case sub.queue <- event:
+		return
+	default:
+	}
+
+	switch sub.policy {
+	case api.EventDropPolicyDisconnect:
+		sub.overflowed = true
+		sub.droppedTotal++
+		sub.Close()
+
+		return
+	case api.EventDropPolicyOldest:
+		// Make room by dropping the oldest event, unless the subscriber caught up in the meantime.
+		select {
+		case <-sub.queue:
+		default:
+			sub.queue <- event
+
+			return
+		}
+
+		sub.queue <- event
+	case api.EventDropPolicyNewest:
+	}
+
+	sub.dropped++
+	sub.droppedTotal++
+}
@@ -0,0 +1,154 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/events"
+)
+
+func TestSubscribeValidation(t *testing.T) {
+	t.Parallel()
+
+	s := events.NewServer()
+
+	_, err := s.Subscribe([]api.EventType{"unknown"}, 0, "")
+	require.Error(t, err)
+
+	_, err = s.Subscribe(nil, events.MaxQueueSize+1, "")
+	require.Error(t, err)
+
+	_, err = s.Subscribe(nil, 0, "drop-everything")
+	require.Error(t, err)
+
+	sub, err := s.Subscribe(nil, 0, "")
+	require.NoError(t, err)
+
+	subscribers := s.Subscribers()
+	require.Len(t, subscribers, 1)
+	require.Equal(t, events.DefaultQueueSize, subscribers[0].QueueSize)
+	require.Equal(t, api.EventDropPolicyOldest, subscribers[0].DropPolicy)
+
+	sub.Close()
+	require.Empty(t, s.Subscribers())
+}
+
+func TestDropOldest(t *testing.T) {
+	t.Parallel()
+
+	s := events.NewServer()
+
+	sub, err := s.Subscribe(nil, 2, api.EventDropPolicyOldest)
+	require.NoError(t, err)
+
+	defer sub.Close()
+
+	for i := range 5 {
+		s.Send(api.EventTypeNotification, i)
+	}
+
+	// Three events were dropped, which is reported first.
+	event, err := sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, api.EventTypeLag, event.Type)
+	require.Equal(t, api.EventLag{Dropped: 3}, event.Metadata)
+
+	// Followed by the two most recent events.
+	event, err = sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, event.Metadata)
+
+	event, err = sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, event.Metadata)
+
+	require.Equal(t, uint64(3), s.Subscribers()[0].Dropped)
+}
+
+func TestDropNewest(t *testing.T) {
+	t.Parallel()
+
+	s := events.NewServer()
+
+	sub, err := s.Subscribe(nil, 2, api.EventDropPolicyNewest)
+	require.NoError(t, err)
+
+	defer sub.Close()
+
+	for i := range 5 {
+		s.Send(api.EventTypeNotification, i)
+	}
+
+	event, err := sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, api.EventTypeLag, event.Type)
+
+	event, err = sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, event.Metadata)
+
+	event, err = sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, event.Metadata)
+}
+
+func TestDisconnect(t *testing.T) {
+	t.Parallel()
+
+	s := events.NewServer()
+
+	sub, err := s.Subscribe(nil, 1, api.EventDropPolicyDisconnect)
+	require.NoError(t, err)
+
+	s.Send(api.EventTypeNotification, 0)
+	s.Send(api.EventTypeNotification, 1)
+
+	_, err = sub.Next(context.Background())
+	require.ErrorIs(t, err, events.ErrSlowSubscriber)
+	require.Empty(t, s.Subscribers())
+}
+
+func TestFilterAndWait(t *testing.T) {
+	t.Parallel()
+
+	s := events.NewServer()
+
+	sub, err := s.Subscribe([]api.EventType{api.EventTypeNotification}, 0, "")
+	require.NoError(t, err)
+
+	defer sub.Close()
+
+	// Events of other types aren't queued.
+	s.Send(api.EventTypeLag, nil)
+	require.Equal(t, 0, s.Subscribers()[0].Queued)
+
+	// Waiting for an event is bounded by the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = sub.Next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSendLifecycle(t *testing.T) {
+	t.Parallel()
+
+	s := events.NewServer()
+
+	sub, err := s.Subscribe([]api.EventType{api.EventTypeLifecycle}, 0, "")
+	require.NoError(t, err)
+
+	defer sub.Close()
+
+	s.Send(api.EventTypeNotification, nil)
+	s.SendLifecycle(api.EventLifecycleServiceStarted, "/1.0/services/iscsi", nil)
+
+	event, err := sub.Next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, api.EventTypeLifecycle, event.Type)
+	require.Equal(t, api.EventLifecycle{Action: api.EventLifecycleServiceStarted, Source: "/1.0/services/iscsi"}, event.Metadata)
+}
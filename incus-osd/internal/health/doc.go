@@ -0,0 +1,3 @@
+// Package health aggregates the detailed health checks of the system, covering the state storage, TPM,
+// services, disk space, time synchronization and update provider.
+package health
@@ -0,0 +1,176 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+const (
+	// minFreeSpaceInGiB is the free space below which the system disk is considered critically low.
+	minFreeSpaceInGiB = 5.0
+
+	// lowFreeSpaceInGiB is the free space below which a warning is reported for the system disk.
+	lowFreeSpaceInGiB = 10.0
+)
+
+// check is a single health check, returning the severity of its outcome and a message describing the issue it found.
+type check struct {
+	name string
+	run  func(ctx context.Context, s *state.State) (api.HealthSeverity, string)
+}
+
+var checks = []check{
+	{name: "state", run: checkState},
+	{name: "tpm", run: checkTPM},
+	{name: "services", run: checkServices},
+	{name: "disk", run: checkDisk},
+	{name: "verity", run: checkVerity},
+	{name: "time", run: checkTime},
+	{name: "provider", run: checkProvider},
+}
+
+// severityOrder lists the severities from the least to the most severe.
+var severityOrder = []api.HealthSeverity{api.HealthSeverityOK, api.HealthSeverityWarning, api.HealthSeverityCritical}
+
+// Run performs all the health checks and returns the aggregated report.
+func Run(ctx context.Context, s *state.State) api.HealthReport {
+	report := api.HealthReport{
+		Status:   api.HealthStatusOK,
+		Severity: api.HealthSeverityOK,
+		Checks:   []api.HealthCheck{},
+	}
+
+	for _, c := range checks {
+		severity, message := c.run(ctx, s)
+
+		result := api.HealthCheck{Name: c.name, Severity: severity, Message: message}
+
+		if slices.Index(severityOrder, result.Severity) > slices.Index(severityOrder, report.Severity) {
+			report.Severity = result.Severity
+		}
+
+		report.Checks = append(report.Checks, result)
+	}
+
+	if report.Severity == api.HealthSeverityCritical {
+		report.Status = api.HealthStatusDegraded
+	}
+
+	return report
+}
+
+// checkState verifies that the state storage is writable.
+func checkState(_ context.Context, s *state.State) (api.HealthSeverity, string) {
+	err := s.CheckStorage()
+	if err != nil {
+		return api.HealthSeverityCritical, "state storage is degraded: " + err.Error()
+	}
+
+	return api.HealthSeverityOK, ""
+}
+
+// checkTPM verifies that the TPM can unlock the encrypted volumes.
+func checkTPM(_ context.Context, s *state.State) (api.HealthSeverity, string) {
+	if s.PassphraseOnly {
+		return api.HealthSeverityWarning, "no TPM is available, the encrypted volumes are only protected by a passphrase"
+	}
+
+	tpmStatus := secureboot.TPMStatus()
+	if tpmStatus != "ok" {
+		return api.HealthSeverityCritical, fmt.Sprintf("TPM status is %q", tpmStatus)
+	}
+
+	return api.HealthSeverityOK, ""
+}
+
+// checkServices verifies that all the applications started and that no systemd unit failed.
+func checkServices(ctx context.Context, s *state.State) (api.HealthSeverity, string) {
+	for _, appName := range slices.Sorted(maps.Keys(s.Applications)) {
+		if !s.Applications[appName].State.Initialized {
+			return api.HealthSeverityCritical, fmt.Sprintf("application %q didn't start", appName)
+		}
+	}
+
+	output, err := timeout.RunCommand(ctx, "systemctl", "list-units", "--state=failed", "--plain", "--no-legend")
+	if err != nil {
+		return api.HealthSeverityWarning, err.Error()
+	}
+
+	failedUnits := []string{}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			failedUnits = append(failedUnits, fields[0])
+		}
+	}
+
+	if len(failedUnits) > 0 {
+		return api.HealthSeverityWarning, "failed units: " + strings.Join(failedUnits, ", ")
+	}
+
+	return api.HealthSeverityOK, ""
+}
+
+// checkDisk verifies that enough space is left on the system disk.
+func checkDisk(_ context.Context, _ *state.State) (api.HealthSeverity, string) {
+	freeSpace, err := storage.GetFreeSpaceInGiB("/")
+	if err != nil {
+		return api.HealthSeverityCritical, err.Error()
+	}
+
+	if freeSpace < minFreeSpaceInGiB {
+		return api.HealthSeverityCritical, fmt.Sprintf("only %.02fGiB free space available in /", freeSpace)
+	}
+
+	if freeSpace < lowFreeSpaceInGiB {
+		return api.HealthSeverityWarning, fmt.Sprintf("only %.02fGiB free space available in /", freeSpace)
+	}
+
+	return api.HealthSeverityOK, ""
+}
+
+// checkVerity verifies that the integrity checks of the system disk didn't detect any corruption.
+func checkVerity(_ context.Context, s *state.State) (api.HealthSeverity, string) {
+	for _, volume := range s.System.Security.State.VerityVolumes {
+		if volume.State == "corrupted" {
+			return api.HealthSeverityCritical, fmt.Sprintf("dm-verity volume %q is corrupted", volume.Volume)
+		}
+	}
+
+	return api.HealthSeverityOK, ""
+}
+
+// checkTime verifies that the system clock is synchronized.
+func checkTime(ctx context.Context, _ *state.State) (api.HealthSeverity, string) {
+	synchronized, err := systemd.IsTimeSynchronized(ctx)
+	if err != nil {
+		return api.HealthSeverityWarning, err.Error()
+	}
+
+	if !synchronized {
+		return api.HealthSeverityWarning, "system clock isn't synchronized"
+	}
+
+	return api.HealthSeverityOK, ""
+}
+
+// checkProvider verifies that the last request to the update provider succeeded. The provider isn't
+// contacted, so the check stays cheap enough for frequent polling.
+func checkProvider(_ context.Context, s *state.State) (api.HealthSeverity, string) {
+	if s.System.Provider.State.LastError != "" {
+		return api.HealthSeverityWarning, "last provider request failed: " + s.System.Provider.State.LastError
+	}
+
+	return api.HealthSeverityOK, ""
+}
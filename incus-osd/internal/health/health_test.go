@@ -0,0 +1,32 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestCheckVerity(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+
+	severity, _ := checkVerity(t.Context(), s)
+	require.Equal(t, api.HealthSeverityOK, severity)
+
+	// Volumes not verified yet aren't reported here.
+	s.System.Security.State.VerityVolumes = []api.SystemSecurityVerityVolume{{Volume: "usr", State: "unknown"}}
+
+	severity, _ = checkVerity(t.Context(), s)
+	require.Equal(t, api.HealthSeverityOK, severity)
+
+	// Corrupted volumes degrade the system.
+	s.System.Security.State.VerityVolumes = []api.SystemSecurityVerityVolume{{Volume: "root", State: "verified"}, {Volume: "usr", State: "corrupted"}}
+
+	severity, message := checkVerity(t.Context(), s)
+	require.Equal(t, api.HealthSeverityCritical, severity)
+	require.Equal(t, `dm-verity volume "usr" is corrupted`, message)
+}
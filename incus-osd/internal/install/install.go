@@ -17,12 +17,16 @@ import (
 
 	"github.com/lxc/incus/v6/shared/osarch"
 	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/lxc/incus/v6/shared/units"
 	"golang.org/x/sys/unix"
 
 	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 	"github.com/lxc/incus-os/incus-osd/internal/tui"
 )
 
@@ -38,8 +42,11 @@ var cdromMappedDevice = "/dev/mapper/sr0"
 
 var cdromRegex = regexp.MustCompile(`^/dev/sr(\d+)`)
 
+// minLocalDataSize matches the minimum size of the local data partition created by systemd-repart.
+const minLocalDataSize = 10 * 1024 * 1024 * 1024
+
 // CheckSystemRequirements verifies that the system meets the minimum requirements for running IncusOS.
-func CheckSystemRequirements(ctx context.Context) error {
+func CheckSystemRequirements(ctx context.Context, s *state.State) error {
 	// Check if Secure Boot is enabled.
 	output, err := subprocess.RunCommandContext(ctx, "bootctl", "status")
 	if err != nil {
@@ -48,9 +55,10 @@ func CheckSystemRequirements(ctx context.Context) error {
 		return errors.New("Secure Boot is not enabled") //nolint:staticcheck
 	}
 
-	// Check if a TPM device is present and working.
-	_, err = subprocess.RunCommandContext(ctx, "tpm2_selftest")
-	if err != nil {
+	// Check if a TPM device is present and working. Systems without one are only supported when
+	// explicitly installed that way, in which case the encrypted volumes are protected by a passphrase.
+	tpmAvailable := secureboot.TPMAvailable(ctx)
+	if !tpmAvailable && !s.PassphraseOnly && !installAllowsMissingTPM() {
 		return errors.New("no working TPM device found")
 	}
 
@@ -113,6 +121,30 @@ func CheckSystemRequirements(ctx context.Context) error {
 			return fmt.Errorf("target device '%s' is too small (%0.2fGiB), must be at least 50GiB", targetDevice, float64(targetDeviceSize)/(1024.0*1024.0*1024.0))
 		}
 
+		// Verify the requested local data partition fits alongside the system partitions.
+		if config.LocalDataSize != "" {
+			localDataSize, err := units.ParseByteSizeString(config.LocalDataSize)
+			if err != nil {
+				return fmt.Errorf("invalid local data size '%s': %w", config.LocalDataSize, err)
+			}
+
+			if localDataSize < minLocalDataSize {
+				return fmt.Errorf("local data size '%s' is too small, must be at least 10GiB", config.LocalDataSize)
+			}
+
+			if int64(targetDeviceSize)-localDataSize < 40*1024*1024*1024 {
+				return fmt.Errorf("local data size '%s' is too large for target device '%s' (%0.2fGiB), at least 40GiB must be left for the system", config.LocalDataSize, targetDevice, float64(targetDeviceSize)/(1024.0*1024.0*1024.0))
+			}
+		}
+
+		// Without a TPM, a passphrase must be provided to protect the encrypted volumes.
+		if !tpmAvailable {
+			err := systemd.ValidateEncryptionKey(config.EncryptionPassphrase)
+			if err != nil {
+				return errors.New("invalid encryption passphrase for install without a TPM: " + err.Error())
+			}
+		}
+
 		// If an applications seed is present, ensure at least one application is defined.
 		apps, _ := seed.GetApplications(ctx)
 		if apps != nil {
@@ -133,6 +165,16 @@ func ShouldPerformInstall() bool {
 	return err == nil
 }
 
+// installAllowsMissingTPM checks whether the install seed allows installing on a system without a TPM.
+func installAllowsMissingTPM() bool {
+	config, err := seed.GetInstall()
+	if err != nil {
+		return false
+	}
+
+	return config.AllowMissingTPM
+}
+
 // NewInstall returns a new Install object with its configuration, if any, populated from the seed partition.
 func NewInstall(t *tui.TUI) (*Install, error) {
 	ret := &Install{
@@ -176,6 +218,15 @@ func (i *Install) DoInstall(ctx context.Context, osName string) error {
 		return err
 	}
 
+	if i.config.WipeOtherDisks {
+		err = wipeOtherDisks(ctx, modal, targets, targetDevice)
+		if err != nil {
+			modal.Update("[red]Error: " + err.Error())
+
+			return err
+		}
+	}
+
 	slog.InfoContext(ctx, "Installing "+osName, "source", sourceDevice, "target", targetDevice)
 	modal.Update(fmt.Sprintf("Installing "+osName+" from %s to %s.", sourceDevice, targetDevice))
 
@@ -247,7 +298,7 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 	// Get NVME drives first.
 	nvmeTargets := storage.LsblkOutput{}
 
-	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-N", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE")
+	output, err := timeout.RunCommand(ctx, "lsblk", "-N", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE,SERIAL,WWN,RM")
 	if err != nil {
 		return []storage.BlockDevices{}, err
 	}
@@ -262,7 +313,7 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 	// Get SCSI drives second.
 	scsiTargets := storage.LsblkOutput{}
 
-	output, err = subprocess.RunCommandContext(ctx, "lsblk", "-S", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE")
+	output, err = timeout.RunCommand(ctx, "lsblk", "-S", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE,SERIAL,WWN,RM")
 	if err != nil {
 		return []storage.BlockDevices{}, err
 	}
@@ -277,7 +328,7 @@ func getAllTargets(ctx context.Context, sourceDevice string) ([]storage.BlockDev
 	// Get virtual drives last.
 	virtualTargets := storage.LsblkOutput{}
 
-	output, err = subprocess.RunCommandContext(ctx, "lsblk", "-v", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE")
+	output, err = timeout.RunCommand(ctx, "lsblk", "-v", "-iJnpb", "-e", "1,2", "-o", "KNAME,ID_LINK,SIZE,SERIAL,WWN,RM")
 	if err != nil {
 		return []storage.BlockDevices{}, err
 	}
@@ -322,8 +373,17 @@ func getTargetDevice(potentialTargets []storage.BlockDevices, seedTarget *apisee
 		return "", -1, errors.New("no target configuration provided, and didn't find exactly one install device")
 	}
 
+	minSize, maxSize, err := getTargetSizeLimits(seedTarget)
+	if err != nil {
+		return "", -1, err
+	}
+
 	// Loop through all disks, selecting the first one that matches the Target configuration.
 	for _, device := range potentialTargets {
+		if seedTarget != nil && !targetMatchesDevice(seedTarget, device, minSize, maxSize) {
+			continue
+		}
+
 		// First, check for a simple substring match.
 		if seedTarget == nil || strings.Contains(device.ID, seedTarget.ID) {
 			return device.KName, device.Size, nil
@@ -343,9 +403,137 @@ func getTargetDevice(potentialTargets []storage.BlockDevices, seedTarget *apisee
 		return "", -1, errors.New("unable to determine target device")
 	}
 
+	if seedTarget.ID == "" {
+		return "", -1, errors.New("no target device matched the provided criteria")
+	}
+
 	return "", -1, errors.New("no target device matched '" + seedTarget.ID + "'")
 }
 
+// getTargetSizeLimits parses the minimum and maximum target device sizes in bytes, zero meaning no limit.
+func getTargetSizeLimits(seedTarget *apiseed.InstallTarget) (int64, int64, error) {
+	if seedTarget == nil {
+		return 0, 0, nil
+	}
+
+	var minSize, maxSize int64
+
+	var err error
+
+	if seedTarget.MinSize != "" {
+		minSize, err = units.ParseByteSizeString(seedTarget.MinSize)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid target minimum size '%s': %w", seedTarget.MinSize, err)
+		}
+	}
+
+	if seedTarget.MaxSize != "" {
+		maxSize, err = units.ParseByteSizeString(seedTarget.MaxSize)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid target maximum size '%s': %w", seedTarget.MaxSize, err)
+		}
+	}
+
+	return minSize, maxSize, nil
+}
+
+// targetMatchesDevice checks the device against the Target criteria other than the ID, which must all match.
+func targetMatchesDevice(seedTarget *apiseed.InstallTarget, device storage.BlockDevices, minSize int64, maxSize int64) bool {
+	// Skip any NVMe namespace other than the requested one.
+	if seedTarget.NVMeNamespace != 0 && storage.GetNVMeNamespace(device.KName) != seedTarget.NVMeNamespace {
+		return false
+	}
+
+	if seedTarget.Serial != "" && strings.TrimSpace(device.Serial) != seedTarget.Serial {
+		return false
+	}
+
+	// lsblk reports the WWN with a "0x" prefix, which is commonly omitted.
+	if seedTarget.WWN != "" && !strings.EqualFold(strings.TrimPrefix(device.WWN, "0x"), strings.TrimPrefix(seedTarget.WWN, "0x")) {
+		return false
+	}
+
+	if seedTarget.Path != "" {
+		path, err := filepath.EvalSymlinks(seedTarget.Path)
+		if err != nil || path != device.KName {
+			return false
+		}
+	}
+
+	if minSize > 0 && int64(device.Size) < minSize {
+		return false
+	}
+
+	if maxSize > 0 && int64(device.Size) > maxSize {
+		return false
+	}
+
+	return true
+}
+
+// wipeOtherDisks wipes the partition tables and signatures of all the potential targets other than the
+// target device. Removable devices and devices holding user-provided seed data are left untouched.
+func wipeOtherDisks(ctx context.Context, modal *tui.Modal, potentialTargets []storage.BlockDevices, targetDevice string) error {
+	for _, device := range potentialTargets {
+		if device.KName == targetDevice || device.RM || holdsSeedData(device.KName) {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Wiping disk", "device", device.KName)
+		modal.Update("Wiping disk " + device.KName + ".")
+
+		_, err := timeout.RunCommand(ctx, "wipefs", "-a", device.KName)
+		if err != nil {
+			return err
+		}
+
+		// Don't check return status, since sgdisk always returns an error if there's a mismatch
+		// between the primary and backup GPT tables.
+		_, _ = timeout.RunCommand(ctx, "sgdisk", "-Z", device.KName)
+	}
+
+	return nil
+}
+
+// holdsSeedData checks if one of the device's partitions holds user-provided seed data.
+func holdsSeedData(device string) bool {
+	for _, link := range []string{"/dev/disk/by-partlabel/SEED_DATA", "/dev/disk/by-label/SEED_DATA", "/dev/disk/by-label/CIDATA", "/dev/disk/by-label/cidata"} {
+		partition, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			continue
+		}
+
+		if partition == device {
+			return true
+		}
+
+		suffix, ok := strings.CutPrefix(partition, device+GetPartitionPrefix(device))
+		if ok && suffix != "" && strings.Trim(suffix, "0123456789") == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createLocalDataPartition creates the local data partition at the end of the target device, so
+// systemd-repart can't grow it and still has room to create the swap and root partitions before it.
+func createLocalDataPartition(ctx context.Context, targetDevice string, alignment string, size string) error {
+	sizeBytes, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return err
+	}
+
+	_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", fmt.Sprintf("11:-%dKiB:0", sizeBytes/1024), "-t", "11:8300", "-c", "11:local-data", targetDevice)
+	if err != nil {
+		return err
+	}
+
+	_, err = timeout.RunCommand(ctx, "udevadm", "settle")
+
+	return err
+}
+
 // performInstall performs the steps to install incus-osd from the given target to the source device.
 func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDevice string, targetDevice string, sourceIsReadonly bool) error {
 	// Get architecture name.
@@ -359,7 +547,7 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	}
 
 	// Check if the target device already has a partition table.
-	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-v", targetDevice)
+	output, err := timeout.RunCommand(ctx, "sgdisk", "-v", targetDevice)
 	if err != nil {
 		// If the device has no main partition table, but does have a backup, assume it's been
 		// partially wiped with something like `dd if=/dev/zero of=/dev/sda ...` and proceed with install.
@@ -381,12 +569,12 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	if i.config.ForceInstall {
 		// Don't check return status, since sgdisk always returns an error if there's a mismatch
 		// between the main and backup GPT tables.
-		_, _ = subprocess.RunCommandContext(ctx, "sgdisk", "-Z", targetDevice)
+		_, _ = timeout.RunCommand(ctx, "sgdisk", "-Z", targetDevice)
 	}
 
 	// Before starting the install, run blkdiscard to fully wipe the target device. blkdiscard may
 	// not work for all devices, so don't check its return status.
-	_, _ = subprocess.RunCommandContext(ctx, "blkdiscard", "-f", targetDevice)
+	_, _ = timeout.RunCommand(ctx, "blkdiscard", "-f", targetDevice)
 
 	// Turn off swap and unmount /boot.
 	_, err = subprocess.RunCommandContext(ctx, "swapoff", "-a")
@@ -408,7 +596,24 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		actualSourceDevice = cdromDevice
 	}
 
-	output, err = subprocess.RunCommandContext(ctx, "sgdisk", "-i", "9", actualSourceDevice)
+	// Get the geometry of both devices, so the partition layout can be converted when their sector sizes
+	// differ, such as when installing to a 4K native drive.
+	sourceGeometry, err := storage.GetDriveGeometry(actualSourceDevice)
+	if err != nil {
+		return err
+	}
+
+	targetGeometry, err := storage.GetDriveGeometry(targetDevice)
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Target device geometry", "device", targetDevice, "logical_block_size", targetGeometry.LogicalBlockSize, "physical_block_size", targetGeometry.PhysicalBlockSize, "nvme_namespace", targetGeometry.NVMeNamespace)
+
+	// Align partitions on 1MiB, whatever the sector size.
+	alignment := strconv.Itoa(max(1, 1024*1024/targetGeometry.LogicalBlockSize))
+
+	output, err = timeout.RunCommand(ctx, "sgdisk", "-i", "9", actualSourceDevice)
 	if err != nil {
 		return err
 	}
@@ -417,7 +622,7 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		// Delete auto-created partitions from source device before proceeding with the install, so we can
 		// re-use the installer media on other systems.
 		for i := 9; i <= 11; i++ {
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-d", strconv.Itoa(i), sourceDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-d", strconv.Itoa(i), sourceDevice)
 			if err != nil {
 				return err
 			}
@@ -427,7 +632,7 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	// Number of partitions to copy.
 	numPartitionsToCopy := 8
 
-	output, err = subprocess.RunCommandContext(ctx, "sgdisk", "-i", "8", actualSourceDevice)
+	output, err = timeout.RunCommand(ctx, "sgdisk", "-i", "8", actualSourceDevice)
 	if err != nil {
 		return err
 	}
@@ -440,7 +645,7 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 
 	// Copy partition definitions.
 	for idx := 1; idx <= numPartitionsToCopy; idx++ {
-		err := copyPartitionDefinition(ctx, actualSourceDevice, targetDevice, idx)
+		err := copyPartitionDefinition(ctx, actualSourceDevice, targetDevice, idx, sourceGeometry.LogicalBlockSize, targetGeometry.LogicalBlockSize, alignment)
 		if err != nil {
 			return err
 		}
@@ -453,33 +658,33 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	if numPartitionsToCopy == 5 {
 		switch archName {
 		case "aarch64":
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "6::+16KiB", "-t", "6:8375", "-c", "6:_empty", targetDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", "6::+16KiB", "-t", "6:8375", "-c", "6:_empty", targetDevice)
 			if err != nil {
 				return err
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "7::+100MiB", "-t", "7:831B", "-c", "7:_empty", targetDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", "7::+100MiB", "-t", "7:831B", "-c", "7:_empty", targetDevice)
 			if err != nil {
 				return err
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "8::+1GiB", "-t", "8:8316", "-c", "8:_empty", targetDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", "8::+1GiB", "-t", "8:8316", "-c", "8:_empty", targetDevice)
 			if err != nil {
 				return err
 			}
 
 		case "x86_64":
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "6::+16KiB", "-t", "6:8385", "-c", "6:_empty", targetDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", "6::+16KiB", "-t", "6:8385", "-c", "6:_empty", targetDevice)
 			if err != nil {
 				return err
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "7::+100MiB", "-t", "7:8319", "-c", "7:_empty", targetDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", "7::+100MiB", "-t", "7:8319", "-c", "7:_empty", targetDevice)
 			if err != nil {
 				return err
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "8::+1GiB", "-t", "8:8314", "-c", "8:_empty", targetDevice)
+			_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", "8::+1GiB", "-t", "8:8314", "-c", "8:_empty", targetDevice)
 			if err != nil {
 				return err
 			}
@@ -497,7 +702,7 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 	// from the source.
 	modal.Update("Copying ESP partition data.")
 
-	_, err = subprocess.RunCommandContext(ctx, "mkfs.vfat", "-n", "ESP", targetDevice+targetPartitionPrefix+"1")
+	_, err = subprocess.RunCommandContext(ctx, "mkfs.vfat", "-S", strconv.Itoa(targetGeometry.LogicalBlockSize), "-n", "ESP", targetDevice+targetPartitionPrefix+"1")
 	if err != nil {
 		return err
 	}
@@ -546,6 +751,27 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 		}
 	}
 
+	// Without a TPM, systemd-repart can't create the encrypted volumes on first boot, so create
+	// them now, protected by the provided passphrase.
+	if i.config.AllowMissingTPM && !secureboot.TPMAvailable(ctx) {
+		modal.Update("Creating passphrase-protected encrypted volumes.")
+
+		err = createPassphraseVolumes(ctx, archName, targetDevice, targetPartitionPrefix, i.config.EncryptionPassphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	// systemd-repart grows the local data partition to fill the disk, so create it now when a size is requested.
+	if i.config.LocalDataSize != "" {
+		modal.Update("Creating local data partition.")
+
+		err = createLocalDataPartition(ctx, targetDevice, alignment, i.config.LocalDataSize)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Remove the install seed from the target device, and copy any external user-provided seeds.
 	err = seed.CleanupPostInstall(ctx, fmt.Sprintf("%s%s2", targetDevice, targetPartitionPrefix))
 	if err != nil {
@@ -575,10 +801,10 @@ func (i *Install) performInstall(ctx context.Context, modal *tui.Modal, sourceDe
 
 // Copy partition definitions to target device. We can't just do a `sgdisk -R target source`
 // because the install media may have a different sector size than the target device (for example,
-// if the installer is running from a CDROM).
-func copyPartitionDefinition(ctx context.Context, src string, tgt string, partitionIndex int) error {
+// if the installer is running from a CDROM or installing to a 4K native drive).
+func copyPartitionDefinition(ctx context.Context, src string, tgt string, partitionIndex int, srcSectorSize int, tgtSectorSize int, alignment string) error {
 	// Get source partition information.
-	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-i", strconv.Itoa(partitionIndex), src)
+	output, err := timeout.RunCommand(ctx, "sgdisk", "-i", strconv.Itoa(partitionIndex), src)
 	if err != nil {
 		return err
 	}
@@ -591,14 +817,21 @@ func copyPartitionDefinition(ctx context.Context, src string, tgt string, partit
 	partitionTypeRegex := regexp.MustCompile(`Partition GUID code: .+ \((.+)\)`)
 	partitionGUIDRegex := regexp.MustCompile(`Partition unique GUID: (.+)`)
 	partitionNameRegex := regexp.MustCompile(`Partition name: '(.+)'`)
-	partitionSizeRegex := regexp.MustCompile(`Partition size: \d+ sectors \((.+)\)`)
+	partitionSizeRegex := regexp.MustCompile(`Partition size: (\d+) sectors`)
 
 	var partitionHexCode string
 
 	partitionType := partitionTypeRegex.FindStringSubmatch(output)[1]
 	partitionGUID := partitionGUIDRegex.FindStringSubmatch(output)[1]
 	partitionName := partitionNameRegex.FindStringSubmatch(output)[1]
-	partitionSize := strings.ReplaceAll(partitionSizeRegex.FindStringSubmatch(output)[1], " ", "")
+
+	partitionSectors, err := strconv.ParseInt(partitionSizeRegex.FindStringSubmatch(output)[1], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	// Convert the partition size to the target's sector size, rounding up.
+	partitionSize := strconv.FormatInt(targetPartitionSectors(partitionSectors, srcSectorSize, tgtSectorSize), 10)
 
 	switch partitionType {
 	case "EFI system partition":
@@ -624,11 +857,18 @@ func copyPartitionDefinition(ctx context.Context, src string, tgt string, partit
 	}
 
 	// Create the partition on the target device.
-	_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", strconv.Itoa(partitionIndex)+"::+"+partitionSize, "-u", strconv.Itoa(partitionIndex)+":"+partitionGUID, "-t", strconv.Itoa(partitionIndex)+":"+partitionHexCode, "-c", strconv.Itoa(partitionIndex)+":"+partitionName, tgt)
+	_, err = timeout.RunCommand(ctx, "sgdisk", "-a", alignment, "-n", strconv.Itoa(partitionIndex)+"::+"+partitionSize, "-u", strconv.Itoa(partitionIndex)+":"+partitionGUID, "-t", strconv.Itoa(partitionIndex)+":"+partitionHexCode, "-c", strconv.Itoa(partitionIndex)+":"+partitionName, tgt)
 
 	return err
 }
 
+// targetPartitionSectors converts a number of sectors between sector sizes, rounding up.
+func targetPartitionSectors(sectors int64, srcSectorSize int, tgtSectorSize int) int64 {
+	size := sectors * int64(srcSectorSize)
+
+	return (size + int64(tgtSectorSize) - 1) / int64(tgtSectorSize)
+}
+
 func doCopy(ctx context.Context, modal *tui.Modal, sourceDevice string, sourcePartitionPrefix string, targetDevice string, targetPartitionPrefix string, partitionIndex int, numPartitionsToCopy int) error {
 	sourcePartition, err := os.OpenFile(fmt.Sprintf("%s%s%d", sourceDevice, sourcePartitionPrefix, partitionIndex), os.O_RDONLY, 0o0600)
 	if err != nil {
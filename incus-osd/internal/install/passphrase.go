@@ -0,0 +1,139 @@
+package install
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// createPassphraseVolumes pre-creates the encrypted swap and root partitions, protected by the
+// provided passphrase rather than the TPM. systemd-repart leaves existing partitions alone on
+// first boot, and systemd-cryptsetup prompts for the passphrase when unlocking them.
+//
+// An initial state is written to the new root volume, so the daemon knows about the passphrase
+// and runs without a TPM.
+func createPassphraseVolumes(ctx context.Context, archName string, targetDevice string, targetPartitionPrefix string, passphrase string) error {
+	rootType := "8304"
+	rootLabel := "root-x86-64"
+
+	if archName == "aarch64" {
+		rootType = "8305"
+		rootLabel = "root-arm64"
+	}
+
+	// Create the partitions, matching the layout produced by systemd-repart.
+	_, err := timeout.RunCommand(ctx, "sgdisk", "-n", "9::+4GiB", "-t", "9:8200", "-c", "9:swap", targetDevice)
+	if err != nil {
+		return err
+	}
+
+	_, err = timeout.RunCommand(ctx, "sgdisk", "-n", "10::+25GiB", "-t", "10:"+rootType, "-c", "10:"+rootLabel, targetDevice)
+	if err != nil {
+		return err
+	}
+
+	_, err = timeout.RunCommand(ctx, "udevadm", "settle")
+	if err != nil {
+		return err
+	}
+
+	// cryptsetup reads the passphrase from a file.
+	keyFile, err := os.CreateTemp("/run", "incus-osd-luks-")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(keyFile.Name())
+
+	_, err = keyFile.WriteString(passphrase)
+	if err != nil {
+		_ = keyFile.Close()
+
+		return err
+	}
+
+	err = keyFile.Close()
+	if err != nil {
+		return err
+	}
+
+	// Format the swap volume.
+	swapMapper, err := formatLUKSVolume(ctx, targetDevice+targetPartitionPrefix+"9", "install-swap", keyFile.Name())
+	if err != nil {
+		return err
+	}
+
+	defer func() { _, _ = timeout.RunCommand(ctx, "cryptsetup", "luksClose", "install-swap") }()
+
+	_, err = timeout.RunCommand(ctx, "mkswap", swapMapper)
+	if err != nil {
+		return err
+	}
+
+	// Format the root volume.
+	rootMapper, err := formatLUKSVolume(ctx, targetDevice+targetPartitionPrefix+"10", "install-root", keyFile.Name())
+	if err != nil {
+		return err
+	}
+
+	defer func() { _, _ = timeout.RunCommand(ctx, "cryptsetup", "luksClose", "install-root") }()
+
+	_, err = timeout.RunCommand(ctx, "mkfs.ext4", "-q", rootMapper)
+	if err != nil {
+		return err
+	}
+
+	// Write the initial state.
+	err = os.MkdirAll("/tmp/targetRoot", 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = unix.Mount(rootMapper, "/tmp/targetRoot", "ext4", 0, "")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = unix.Unmount("/tmp/targetRoot", 0) }()
+
+	statePath := "/tmp/targetRoot/var/lib/incus-os/state.txt"
+
+	err = os.MkdirAll(filepath.Dir(statePath), 0o700)
+	if err != nil {
+		return err
+	}
+
+	s, err := state.LoadOrCreate(statePath)
+	if err != nil {
+		return err
+	}
+
+	s.PassphraseOnly = true
+	s.System.Security.Config.EncryptionRecoveryKeys = []string{passphrase}
+
+	// The passphrase was chosen by the user, so it's already known to them.
+	s.System.Security.State.EncryptionRecoveryKeysRetrieved = true
+
+	return s.Save()
+}
+
+// formatLUKSVolume formats a partition as a LUKS volume unlocked by the provided key file, then
+// opens it under the provided name. Returns the path to the opened volume.
+func formatLUKSVolume(ctx context.Context, partition string, name string, keyFile string) (string, error) {
+	_, err := timeout.RunCommand(ctx, "cryptsetup", "luksFormat", "--type", "luks2", "--batch-mode", "--key-file", keyFile, partition)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = timeout.RunCommand(ctx, "cryptsetup", "luksOpen", "--key-file", keyFile, partition, name)
+	if err != nil {
+		return "", err
+	}
+
+	return "/dev/mapper/" + name, nil
+}
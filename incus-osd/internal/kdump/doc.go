@@ -0,0 +1,3 @@
+// Package kdump loads the crash capture kernel, saves the kernel crash dumps it captures and manages
+// the saved dumps.
+package kdump
@@ -0,0 +1,188 @@
+package kdump
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// DumpsPath is where the captured kernel crash dumps are saved, one directory per crash.
+var DumpsPath = "/var/crash/"
+
+// dumpNameLayout is the time layout used to name the crash dump directories.
+const dumpNameLayout = "20060102-150405"
+
+var dumpNameRegex = regexp.MustCompile(`^\d{8}-\d{6}$`)
+
+// ErrDumpNotFound is returned when the requested crash dump doesn't exist.
+var ErrDumpNotFound = errors.New("crash dump not found")
+
+// Capture saves the crash dump of the previous kernel when running as the capture kernel. It returns
+// whether a crash dump was found, in which case the system should be rebooted.
+func Capture(ctx context.Context) (bool, error) {
+	_, err := os.Stat("/proc/vmcore")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	dumpPath := filepath.Join(DumpsPath, time.Now().UTC().Format(dumpNameLayout))
+
+	err = os.MkdirAll(dumpPath, 0o700)
+	if err != nil {
+		return true, err
+	}
+
+	// Save the kernel log on its own first, since it's the most useful part and the least likely to fail.
+	_, err = timeout.RunCommand(ctx, "makedumpfile", "--dump-dmesg", "/proc/vmcore", filepath.Join(dumpPath, "dmesg.txt"))
+	if err != nil {
+		return true, err
+	}
+
+	// Save a compressed dump, excluding free, cache and user pages.
+	_, err = timeout.RunCommand(ctx, "makedumpfile", "-l", "-d", "31", "/proc/vmcore", filepath.Join(dumpPath, "vmcore"))
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// List returns the names of the saved crash dumps, oldest first.
+func List() ([]string, error) {
+	entries, err := os.ReadDir(DumpsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !dumpNameRegex.MatchString(entry.Name()) {
+			continue
+		}
+
+		names = append(names, entry.Name())
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// Get returns the details of a saved crash dump.
+func Get(name string) (*api.DebugCrashDump, error) {
+	if !dumpNameRegex.MatchString(name) {
+		return nil, ErrDumpNotFound
+	}
+
+	entries, err := os.ReadDir(filepath.Join(DumpsPath, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrDumpNotFound
+		}
+
+		return nil, err
+	}
+
+	dumpTime, err := time.Parse(dumpNameLayout, name)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &api.DebugCrashDump{
+		Name:  name,
+		Time:  dumpTime,
+		Files: []string{},
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		dump.Files = append(dump.Files, entry.Name())
+		dump.Size += info.Size()
+	}
+
+	return dump, nil
+}
+
+// Delete removes a saved crash dump.
+func Delete(name string) error {
+	_, err := Get(name)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(DumpsPath, name))
+}
+
+// WriteArchive writes a tar archive of the files of a saved crash dump.
+func WriteArchive(w io.Writer, name string) error {
+	dump, err := Get(name)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	for _, file := range dump.Files {
+		err := addArchiveFile(tw, filepath.Join(DumpsPath, name, file), filepath.Join(name, file))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// addArchiveFile adds a single file to the tar archive.
+func addArchiveFile(tw *tar.Writer, path string, name string) error {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+
+	return err
+}
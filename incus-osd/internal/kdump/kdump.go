@@ -0,0 +1,181 @@
+package kdump
+
+import (
+	"bytes"
+	"context"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cavaliergopher/cpio"
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// kernelPath holds the capture kernel and initrd extracted from the running image.
+const kernelPath = "/run/incus-os/kdump/"
+
+// captureArgs are appended to the kernel command line of the capture kernel.
+const captureArgs = "irqpoll nr_cpus=1 reset_devices"
+
+// ErrNoReservedMemory is returned when loading the capture kernel without any crash kernel memory reserved.
+var ErrNoReservedMemory = errors.New("no memory is reserved for the crash kernel")
+
+// ReservedMemory returns the amount of memory reserved for the capture kernel, in bytes.
+func ReservedMemory() (int64, error) {
+	content, err := os.ReadFile("/sys/kernel/kexec_crash_size")
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// IsLoaded returns whether a capture kernel is currently loaded.
+func IsLoaded() bool {
+	content, err := os.ReadFile("/sys/kernel/kexec_crash_loaded")
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(content)) == "1"
+}
+
+// Load loads the running kernel as the capture kernel, to be started on kernel panic.
+//
+// The capture kernel boots the regular system, which can't unlock its encrypted volumes through the
+// TPM since the PCRs already hold the measurements of the crashed boot. The recovery key is therefore
+// passed through an additional initrd, in memory reserved for the crash kernel.
+func Load(ctx context.Context, s *state.State) error {
+	reserved, err := ReservedMemory()
+	if err != nil {
+		return err
+	}
+
+	if reserved == 0 {
+		return ErrNoReservedMemory
+	}
+
+	if len(s.System.Security.Config.EncryptionRecoveryKeys) == 0 {
+		return errors.New("an encryption recovery key is required to capture crash dumps")
+	}
+
+	// Extract the kernel, initrd and command line from the running image.
+	sections, err := getUKISections(fmt.Sprintf("/boot/EFI/Linux/%s_%s.efi", s.OS.Name, s.OS.RunningRelease), ".linux", ".initrd", ".cmdline")
+	if err != nil {
+		return err
+	}
+
+	keys, err := getKeysArchive(s.System.Security.Config.EncryptionRecoveryKeys[0])
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(kernelPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(kernelPath, "vmlinuz"), sections[".linux"], 0o600)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(kernelPath, "initrd"), append(sections[".initrd"], keys...), 0o600)
+	if err != nil {
+		return err
+	}
+
+	cmdline := strings.TrimSpace(strings.TrimRight(string(sections[".cmdline"]), "\x00")) + " " + captureArgs
+
+	// Load the capture kernel. Signature verification of kexec_file_load is required with Secure Boot.
+	_, err = subprocess.RunCommandContext(ctx, "kexec", "-s", "-p", filepath.Join(kernelPath, "vmlinuz"), "--initrd="+filepath.Join(kernelPath, "initrd"), "--append="+cmdline)
+	if err != nil {
+		return err
+	}
+
+	// The kernel keeps its own copy, so don't leave the recovery key around.
+	return os.RemoveAll(kernelPath)
+}
+
+// Unload unloads the capture kernel.
+func Unload(ctx context.Context) error {
+	if !IsLoaded() {
+		return nil
+	}
+
+	_, err := subprocess.RunCommandContext(ctx, "kexec", "-s", "-p", "-u")
+
+	return err
+}
+
+// getUKISections returns the content of the requested sections of a UKI.
+func getUKISections(ukiFile string, names ...string) (map[string][]byte, error) {
+	peFile, err := pe.Open(ukiFile)
+	if err != nil {
+		return nil, err
+	}
+
+	defer peFile.Close()
+
+	ret := make(map[string][]byte, len(names))
+
+	for _, name := range names {
+		section := peFile.Section(name)
+		if section == nil {
+			return nil, fmt.Errorf("missing section %s in %s", name, ukiFile)
+		}
+
+		data, err := section.Data()
+		if err != nil {
+			return nil, err
+		}
+
+		if section.VirtualSize > 0 && int(section.VirtualSize) < len(data) {
+			data = data[:section.VirtualSize]
+		}
+
+		ret[name] = data
+	}
+
+	return ret, nil
+}
+
+// getKeysArchive returns a cpio archive providing the key used by systemd-cryptsetup to unlock the
+// root and swap volumes.
+func getKeysArchive(key string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := cpio.NewWriter(&buf)
+
+	for _, dir := range []string{"etc", "etc/cryptsetup-keys.d"} {
+		err := w.WriteHeader(&cpio.Header{Name: dir, Mode: cpio.TypeDir | 0o700})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, volume := range []string{"root", "swap"} {
+		err := w.WriteHeader(&cpio.Header{Name: "etc/cryptsetup-keys.d/" + volume + ".key", Mode: cpio.TypeReg | 0o600, Size: int64(len(key))})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = w.Write([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
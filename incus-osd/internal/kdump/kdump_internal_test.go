@@ -0,0 +1,44 @@
+package kdump
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/cavaliergopher/cpio"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysArchive(t *testing.T) {
+	t.Parallel()
+
+	archive, err := getKeysArchive("recovery-key")
+	require.NoError(t, err)
+
+	r := cpio.NewReader(bytes.NewReader(archive))
+	files := map[string]string{}
+
+	for {
+		hdr, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+
+		if hdr.Mode.IsDir() {
+			continue
+		}
+
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+
+		files[hdr.Name] = string(content)
+	}
+
+	require.Equal(t, map[string]string{
+		"etc/cryptsetup-keys.d/root.key": "recovery-key",
+		"etc/cryptsetup-keys.d/swap.key": "recovery-key",
+	}, files)
+}
@@ -0,0 +1,3 @@
+// Package mdns advertises the system over multicast DNS and discovers the other IncusOS systems on
+// the local network, so they can be found automatically when coordinating updates.
+package mdns
@@ -0,0 +1,450 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// serviceName is the DNS-SD service type advertised by IncusOS systems.
+const serviceName = "_incusos._tcp.local."
+
+// servicePort is the port advertised for the service, on which the primary application serves the API.
+const servicePort = 8443
+
+// recordTTL is the TTL of the advertised records.
+const recordTTL = 120
+
+// discoveryWindow is how long answers to a discovery query are collected for.
+const discoveryWindow = 3 * time.Second
+
+// peerExpiry is how long a peer which stopped answering is kept in the list of peers.
+const peerExpiry = 30 * time.Minute
+
+// mdnsAddress is the IPv4 multicast DNS group.
+var mdnsAddress = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+var (
+	peers      = map[string]api.ClusterPeer{}
+	peersMutex sync.RWMutex
+)
+
+// Peers returns the IncusOS systems recently discovered on the local network, sorted by name.
+func Peers() []api.ClusterPeer {
+	peersMutex.RLock()
+	defer peersMutex.RUnlock()
+
+	ret := []api.ClusterPeer{}
+
+	for _, name := range slices.Sorted(maps.Keys(peers)) {
+		if time.Since(peers[name].LastSeen) > peerExpiry {
+			continue
+		}
+
+		ret = append(ret, peers[name])
+	}
+
+	return ret
+}
+
+// Serve answers multicast DNS queries for the IncusOS service with the system's own records,
+// until the context is cancelled.
+func Serve(ctx context.Context, s *state.State) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsAddress)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, 9000)
+
+	for {
+		n, source, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		var parser dnsmessage.Parser
+
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.Response {
+			continue
+		}
+
+		questions, err := parser.AllQuestions()
+		if err != nil {
+			continue
+		}
+
+		questions = slices.DeleteFunc(questions, func(q dnsmessage.Question) bool {
+			return (q.Type != dnsmessage.TypePTR && q.Type != dnsmessage.TypeALL) || !strings.EqualFold(q.Name.String(), serviceName)
+		})
+
+		if len(questions) == 0 {
+			continue
+		}
+
+		// Queries not sent from the mDNS port come from simple resolvers expecting a unicast answer
+		// echoing the query, as described in RFC 6762 section 6.7.
+		destination := mdnsAddress
+		if source.Port != mdnsAddress.Port {
+			destination = source
+		} else {
+			header.ID = 0
+			questions = nil
+		}
+
+		answer, err := buildAnswer(s, header.ID, questions)
+		if err != nil {
+			return err
+		}
+
+		_, _ = conn.WriteToUDP(answer, destination)
+	}
+}
+
+// Discover queries the local network for other IncusOS systems and records those that answer.
+func Discover(ctx context.Context, s *state.State) error {
+	lc := &net.ListenConfig{}
+
+	packetConn, err := lc.ListenPacket(ctx, "udp4", ":0")
+	if err != nil {
+		return err
+	}
+
+	defer packetConn.Close()
+
+	conn, ok := packetConn.(*net.UDPConn)
+	if !ok {
+		return errors.New("unexpected connection type")
+	}
+
+	query, err := buildQuery()
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.WriteToUDP(query, mdnsAddress)
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(discoveryWindow))
+	if err != nil {
+		return err
+	}
+
+	self := instanceLabel(s)
+	buf := make([]byte, 9000)
+
+	for {
+		n, source, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return nil
+			}
+
+			return err
+		}
+
+		found, err := parseAnswer(buf[:n], source.IP)
+		if err != nil {
+			continue
+		}
+
+		peersMutex.Lock()
+
+		for _, peer := range found {
+			if peer.Name == self {
+				continue
+			}
+
+			peers[peer.Name] = peer
+		}
+
+		peersMutex.Unlock()
+	}
+}
+
+// buildQuery returns a query for the IncusOS service.
+func buildQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+
+	err = builder.StartQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	err = builder.Question(dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	if err != nil {
+		return nil, err
+	}
+
+	return builder.Finish()
+}
+
+// buildAnswer returns an answer advertising the system, along with its version and addresses.
+func buildAnswer(s *state.State, id uint16, questions []dnsmessage.Question) ([]byte, error) {
+	label := instanceLabel(s)
+
+	service, err := dnsmessage.NewName(serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := dnsmessage.NewName(label + "." + serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	host, err := dnsmessage.NewName(label + ".local.")
+	if err != nil {
+		return nil, err
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, Authoritative: true})
+	builder.EnableCompression()
+
+	err = builder.StartQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, question := range questions {
+		err = builder.Question(question)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = builder.StartAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	err = builder.PTRResource(resourceHeader(service), dnsmessage.PTRResource{PTR: instance})
+	if err != nil {
+		return nil, err
+	}
+
+	err = builder.SRVResource(resourceHeader(instance), dnsmessage.SRVResource{Port: servicePort, Target: host})
+	if err != nil {
+		return nil, err
+	}
+
+	txt := []string{"os_name=" + s.OS.Name, "os_version=" + s.OS.RunningRelease}
+	for _, name := range slices.Sorted(maps.Keys(s.Applications)) {
+		txt = append(txt, "app."+name+"="+s.Applications[name].State.Version)
+	}
+
+	err = builder.TXTResource(resourceHeader(instance), dnsmessage.TXTResource{TXT: txt})
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := getAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses {
+		ipv4 := address.To4()
+		if ipv4 != nil {
+			err = builder.AResource(resourceHeader(host), dnsmessage.AResource{A: [4]byte(ipv4)})
+		} else {
+			err = builder.AAAAResource(resourceHeader(host), dnsmessage.AAAAResource{AAAA: [16]byte(address.To16())})
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// parseAnswer extracts the peers advertised in an answer. The source address is used for any
+// peer whose addresses aren't included.
+func parseAnswer(msg []byte, source net.IP) ([]api.ClusterPeer, error) {
+	var parser dnsmessage.Parser
+
+	header, err := parser.Start(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if !header.Response {
+		return nil, errors.New("not an answer")
+	}
+
+	err = parser.SkipAllQuestions()
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := parser.AllAnswers()
+	if err != nil {
+		return nil, err
+	}
+
+	err = parser.SkipAllAuthorities()
+	if err != nil {
+		return nil, err
+	}
+
+	additionals, err := parser.AllAdditionals()
+	if err != nil {
+		return nil, err
+	}
+
+	resources = append(resources, additionals...)
+
+	// Index the records by name.
+	instances := []string{}
+	srvs := map[string]*dnsmessage.SRVResource{}
+	txts := map[string]*dnsmessage.TXTResource{}
+	hosts := map[string][]string{}
+
+	for _, resource := range resources {
+		name := strings.ToLower(resource.Header.Name.String())
+
+		switch body := resource.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name == serviceName {
+				instances = append(instances, strings.ToLower(body.PTR.String()))
+			}
+
+		case *dnsmessage.SRVResource:
+			srvs[name] = body
+
+		case *dnsmessage.TXTResource:
+			txts[name] = body
+
+		case *dnsmessage.AResource:
+			hosts[name] = append(hosts[name], net.IP(body.A[:]).String())
+
+		case *dnsmessage.AAAAResource:
+			hosts[name] = append(hosts[name], net.IP(body.AAAA[:]).String())
+		}
+	}
+
+	ret := []api.ClusterPeer{}
+
+	for _, instance := range instances {
+		label, ok := strings.CutSuffix(instance, "."+serviceName)
+		if !ok {
+			continue
+		}
+
+		peer := api.ClusterPeer{
+			Name:         label,
+			Applications: map[string]string{},
+			LastSeen:     time.Now(),
+		}
+
+		srv, ok := srvs[instance]
+		if ok {
+			peer.Port = int(srv.Port)
+			peer.Addresses = hosts[strings.ToLower(srv.Target.String())]
+		}
+
+		if len(peer.Addresses) == 0 {
+			peer.Addresses = []string{source.String()}
+		}
+
+		txt, ok := txts[instance]
+		if ok {
+			for _, entry := range txt.TXT {
+				key, value, _ := strings.Cut(entry, "=")
+
+				switch key {
+				case "os_name":
+					peer.OSName = value
+				case "os_version":
+					peer.OSVersion = value
+				default:
+					appName, ok := strings.CutPrefix(key, "app.")
+					if ok {
+						peer.Applications[appName] = value
+					}
+				}
+			}
+		}
+
+		ret = append(ret, peer)
+	}
+
+	return ret, nil
+}
+
+// resourceHeader returns the header of an advertised record.
+func resourceHeader(name dnsmessage.Name) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: recordTTL}
+}
+
+// instanceLabel returns the DNS label under which the system is advertised.
+func instanceLabel(s *state.State) string {
+	label := strings.ToLower(strings.ReplaceAll(s.Hostname(), ".", "-"))
+	if len(label) > 63 {
+		label = label[:63]
+	}
+
+	return label
+}
+
+// getAddresses returns the global unicast addresses of the system.
+func getAddresses() ([]net.IP, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := []net.IP{}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !ipNet.IP.IsGlobalUnicast() {
+				continue
+			}
+
+			addresses = append(addresses, ipNet.IP)
+		}
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,44 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestAnswerRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{Applications: map[string]api.Application{}}
+	s.OS.Name = "IncusOS"
+	s.OS.RunningRelease = "202511041601"
+
+	app := api.Application{}
+	app.State.Version = "202511041500"
+	s.Applications["incus"] = app
+
+	answer, err := buildAnswer(s, 1234, nil)
+	require.NoError(t, err)
+
+	peers, err := parseAnswer(answer, net.ParseIP("10.0.0.12"))
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+
+	require.Equal(t, instanceLabel(s), peers[0].Name)
+	require.Equal(t, servicePort, peers[0].Port)
+	require.Equal(t, "IncusOS", peers[0].OSName)
+	require.Equal(t, "202511041601", peers[0].OSVersion)
+	require.Equal(t, map[string]string{"incus": "202511041500"}, peers[0].Applications)
+	require.NotEmpty(t, peers[0].Addresses)
+
+	// Queries aren't answers.
+	query, err := buildQuery()
+	require.NoError(t, err)
+
+	_, err = parseAnswer(query, net.ParseIP("10.0.0.12"))
+	require.Error(t, err)
+}
@@ -0,0 +1,3 @@
+// Package netcheck runs the network reachability self-test, verifying name resolution, gateway
+// reachability, time synchronization and access to the update provider.
+package netcheck
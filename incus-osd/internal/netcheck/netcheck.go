@@ -0,0 +1,176 @@
+package netcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// check is a single network check, returning a short description of what was verified or an error
+// describing why it failed.
+type check struct {
+	name string
+	run  func(ctx context.Context, s *state.State) (string, error)
+}
+
+var checks = []check{
+	{name: "dns", run: checkDNS},
+	{name: "gateway", run: checkGateway},
+	{name: "ntp", run: checkNTP},
+	{name: "provider", run: checkProvider},
+}
+
+// Run performs all the network checks and returns their report.
+func Run(ctx context.Context, s *state.State) api.SystemNetworkTest {
+	report := api.SystemNetworkTest{
+		Time:    time.Now().UTC(),
+		Passed:  true,
+		Results: []api.SystemNetworkTestResult{},
+	}
+
+	for _, c := range checks {
+		result := api.SystemNetworkTestResult{Name: c.name, Passed: true}
+
+		checkCtx, cancel := timeout.WithTimeout(ctx, timeout.NetworkTest)
+
+		message, err := c.run(checkCtx, s)
+		if err != nil {
+			result.Passed = false
+			message = err.Error()
+			report.Passed = false
+		}
+
+		cancel()
+
+		result.Message = message
+		report.Results = append(report.Results, result)
+	}
+
+	return report
+}
+
+// providerURL returns the parsed address of the configured provider, or nil if it doesn't rely on the network.
+func providerURL(s *state.State) (*url.URL, error) {
+	serverURL, err := providers.ServerURL(s.System.Provider.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverURL == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	return url.Parse(serverURL)
+}
+
+// checkDNS verifies that the name of the provider, or of the default image server if the provider doesn't
+// rely on the network, can be resolved.
+func checkDNS(ctx context.Context, s *state.State) (string, error) {
+	u, err := providerURL(s)
+	if err != nil {
+		return "", err
+	}
+
+	if u == nil {
+		u, err = url.Parse("https://images.linuxcontainers.org")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	host := u.Hostname()
+	if net.ParseIP(host) != nil {
+		return "provider is configured by IP address", nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q: %w", host, err)
+	}
+
+	return fmt.Sprintf("%s resolves to %s", host, strings.Join(addresses, ", ")), nil
+}
+
+// checkGateway verifies that every default gateway answers neighbor resolution.
+func checkGateway(ctx context.Context, s *state.State) (string, error) {
+	err := systemd.UpdateNetworkState(ctx, &s.System.Network)
+	if err != nil {
+		return "", err
+	}
+
+	gateways := []string{}
+
+	for _, iface := range slices.Sorted(maps.Keys(s.System.Network.State.Interfaces)) {
+		for _, route := range s.System.Network.State.Interfaces[iface].Routes {
+			if route.To != "default" {
+				continue
+			}
+
+			err := systemd.CheckNeighborReachable(ctx, iface, route.Via)
+			if err != nil {
+				return "", err
+			}
+
+			gateways = append(gateways, route.Via+" ("+iface+")")
+		}
+	}
+
+	if len(gateways) == 0 {
+		return "", errors.New("no default gateway configured")
+	}
+
+	return "reachable gateways: " + strings.Join(gateways, ", "), nil
+}
+
+// checkNTP verifies that the system clock is synchronized.
+func checkNTP(ctx context.Context, _ *state.State) (string, error) {
+	synchronized, err := systemd.IsTimeSynchronized(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if !synchronized {
+		return "", errors.New("system clock isn't synchronized")
+	}
+
+	return "system clock is synchronized", nil
+}
+
+// checkProvider verifies that the provider's server answers HTTP requests, going through the configured proxy.
+func checkProvider(ctx context.Context, s *state.State) (string, error) {
+	u, err := providerURL(s)
+	if err != nil {
+		return "", err
+	}
+
+	if u == nil {
+		return fmt.Sprintf("provider %q doesn't use the network", s.System.Provider.Config.Name), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", u.String(), err)
+	}
+
+	_ = resp.Body.Close()
+
+	return fmt.Sprintf("%s answered with status %d", u.String(), resp.StatusCode), nil
+}
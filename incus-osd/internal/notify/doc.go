@@ -0,0 +1,2 @@
+// Package notify contains a number of Notifier implementations used to send system events to external services.
+package notify
@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// Notifier represents a backend events can be sent to.
+type Notifier interface {
+	Name() string
+	Type() string
+	Notify(ctx context.Context, event api.SystemNotificationsEvent) error
+
+	load(ctx context.Context) error
+}
+
+// Load gets a specific notification backend and initializes it with its configuration.
+func Load(ctx context.Context, config api.SystemNotificationsBackend) (Notifier, error) {
+	var n Notifier
+
+	// Decrypt the credentials.
+	backendConfig, err := secrets.OpenMap(config.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the credentials: %w", err)
+	}
+
+	switch config.Type {
+	case "email":
+		// Setup the email backend.
+		n = &email{
+			name:   config.Name,
+			config: backendConfig,
+		}
+
+	case "webhook":
+		// Setup the webhook backend.
+		n = &webhook{
+			name:   config.Name,
+			config: backendConfig,
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown notification backend type %q", config.Type)
+	}
+
+	err = n.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// ValidateConfig checks that all backends can be loaded and that all routes refer to known events and backends.
+func ValidateConfig(ctx context.Context, config api.SystemNotificationsConfig) error {
+	names := make([]string, 0, len(config.Backends))
+
+	for _, backend := range config.Backends {
+		if backend.Name == "" {
+			return errors.New("notification backend name cannot be empty")
+		}
+
+		if slices.Contains(names, backend.Name) {
+			return fmt.Errorf("duplicate notification backend %q", backend.Name)
+		}
+
+		_, err := Load(ctx, backend)
+		if err != nil {
+			return fmt.Errorf("invalid notification backend %q: %w", backend.Name, err)
+		}
+
+		names = append(names, backend.Name)
+	}
+
+	for _, route := range config.Routes {
+		for _, event := range route.Events {
+			if !slices.Contains(api.SystemNotificationsEventTypes, event) {
+				return fmt.Errorf("unknown notification event %q", event)
+			}
+		}
+
+		if len(route.Backends) == 0 {
+			return errors.New("notification route must have at least one backend")
+		}
+
+		for _, backend := range route.Backends {
+			if !slices.Contains(names, backend) {
+				return fmt.Errorf("notification route refers to unknown backend %q", backend)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Send notifies all the backends routed for the given event type, as well as any API event subscriber.
+// Failures are logged but otherwise ignored.
+func Send(ctx context.Context, s *state.State, eventType api.SystemNotificationsEventType, message string) {
+	config := s.System.Notifications.Config
+
+	event := api.SystemNotificationsEvent{
+		Type:     eventType,
+		Hostname: s.Hostname(),
+		Message:  message,
+	}
+
+	// API event subscribers get all events, independently of the configured routes.
+	s.Events.Send(api.EventTypeNotification, event)
+
+	// Get the list of backends to notify, only notifying each once.
+	backendNames := []string{}
+
+	for _, route := range config.Routes {
+		if len(route.Events) > 0 && !slices.Contains(route.Events, eventType) {
+			continue
+		}
+
+		for _, name := range route.Backends {
+			if !slices.Contains(backendNames, name) {
+				backendNames = append(backendNames, name)
+			}
+		}
+	}
+
+	for _, backend := range config.Backends {
+		if !slices.Contains(backendNames, backend.Name) {
+			continue
+		}
+
+		n, err := Load(ctx, backend)
+		if err == nil {
+			err = n.Notify(ctx, event)
+		}
+
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to send notification", "backend", backend.Name, "event", eventType, "err", err.Error())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+type email struct {
+	name   string
+	config map[string]string
+
+	server string
+	from   string
+	to     []string
+}
+
+func (n *email) Name() string {
+	return n.name
+}
+
+func (*email) Type() string {
+	return "email"
+}
+
+func (n *email) Notify(ctx context.Context, event api.SystemNotificationsEvent) error {
+	host, _, err := net.SplitHostPort(n.server)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the server, making sure a slow server can't block us.
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	conn, err := dialer.DialContext(ctx, "tcp", n.server)
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(time.Minute))
+	if err != nil {
+		_ = conn.Close()
+
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+
+		return err
+	}
+	defer client.Close()
+
+	// Use TLS if offered by the server.
+	ok, _ := client.Extension("STARTTLS")
+	if ok {
+		err = client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return err
+		}
+	}
+
+	if n.config["username"] != "" {
+		err = client.Auth(smtp.PlainAuth("", n.config["username"], n.config["password"], host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(n.from)
+	if err != nil {
+		return err
+	}
+
+	for _, to := range n.to {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: [%s] %s\r\n\r\n%s\r\n", n.from, strings.Join(n.to, ", "), event.Hostname, event.Type, event.Message)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (n *email) load(_ context.Context) error {
+	n.server = n.config["server"]
+	if n.server == "" {
+		return errors.New("email server must be provided")
+	}
+
+	// Default to the submission port.
+	_, _, err := net.SplitHostPort(n.server)
+	if err != nil {
+		n.server = net.JoinHostPort(n.server, "587")
+	}
+
+	n.from = n.config["from"]
+	if n.from == "" {
+		return errors.New("email sender must be provided")
+	}
+
+	for _, to := range strings.Split(n.config["to"], ",") {
+		to = strings.TrimSpace(to)
+		if to != "" {
+			n.to = append(n.to, to)
+		}
+	}
+
+	if len(n.to) == 0 {
+		return errors.New("at least one email recipient must be provided")
+	}
+
+	return nil
+}
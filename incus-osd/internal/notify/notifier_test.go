@@ -0,0 +1,64 @@
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/notify"
+)
+
+// Test validation of the notifications configuration.
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	backends := []api.SystemNotificationsBackend{
+		{
+			Name:   "ops",
+			Type:   "webhook",
+			Config: map[string]string{"url": "https://example.com/hook"},
+		},
+		{
+			Name:   "admins",
+			Type:   "email",
+			Config: map[string]string{"server": "smtp.example.com", "from": "incus@example.com", "to": "a@example.com, b@example.com"},
+		},
+	}
+
+	// Valid configuration.
+	require.NoError(t, notify.ValidateConfig(t.Context(), api.SystemNotificationsConfig{
+		Backends: backends,
+		Routes: []api.SystemNotificationsRoute{
+			{Events: []api.SystemNotificationsEventType{api.SystemNotificationsEventUpdateFailed}, Backends: []string{"ops", "admins"}},
+			{Backends: []string{"admins"}},
+		},
+	}))
+
+	// Unknown backend type.
+	require.Error(t, notify.ValidateConfig(t.Context(), api.SystemNotificationsConfig{
+		Backends: []api.SystemNotificationsBackend{{Name: "chat", Type: "irc"}},
+	}))
+
+	// Missing webhook URL.
+	require.Error(t, notify.ValidateConfig(t.Context(), api.SystemNotificationsConfig{
+		Backends: []api.SystemNotificationsBackend{{Name: "ops", Type: "webhook"}},
+	}))
+
+	// Duplicate backend.
+	require.Error(t, notify.ValidateConfig(t.Context(), api.SystemNotificationsConfig{
+		Backends: append(backends, backends[0]),
+	}))
+
+	// Unknown event.
+	require.Error(t, notify.ValidateConfig(t.Context(), api.SystemNotificationsConfig{
+		Backends: backends,
+		Routes:   []api.SystemNotificationsRoute{{Events: []api.SystemNotificationsEventType{"meteor-strike"}, Backends: []string{"ops"}}},
+	}))
+
+	// Unknown backend in route.
+	require.Error(t, notify.ValidateConfig(t.Context(), api.SystemNotificationsConfig{
+		Backends: backends,
+		Routes:   []api.SystemNotificationsRoute{{Backends: []string{"pager"}}},
+	}))
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+type webhook struct {
+	name   string
+	config map[string]string
+
+	url string
+}
+
+func (n *webhook) Name() string {
+	return n.name
+}
+
+func (*webhook) Type() string {
+	return "webhook"
+}
+
+func (n *webhook) Notify(ctx context.Context, event api.SystemNotificationsEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned unexpected status %q", resp.Status)
+	}
+
+	return nil
+}
+
+func (n *webhook) load(_ context.Context) error {
+	n.url = n.config["url"]
+	if n.url == "" {
+		return errors.New("webhook URL must be provided")
+	}
+
+	u, err := url.Parse(n.url)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported webhook URL scheme %q", u.Scheme)
+	}
+
+	return nil
+}
@@ -0,0 +1,401 @@
+package providers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/osarch"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// UpdateBundle represents an offline update bundle, extracted and verified on local disk.
+type UpdateBundle struct {
+	path   string
+	signer string
+	update *apiupdate.UpdateFull
+}
+
+// LoadUpdateBundle extracts an update bundle into the target path and validates it.
+//
+// The bundle is a tarball containing a signed "update.sjson" along with the files it references,
+// laid out the same way as on the images server. The signature is checked against the update CA
+// of the current provider and the checksum of every included file is validated.
+func LoadUpdateBundle(ctx context.Context, s *state.State, r io.Reader, targetPath string) (*UpdateBundle, error) {
+	// Create the target path.
+	err := os.MkdirAll(targetPath, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract the bundle.
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, err
+		}
+
+		// Only extract regular files.
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Don't allow escaping the target path.
+		if !filepath.IsLocal(hdr.Name) {
+			return nil, fmt.Errorf("invalid file name %q in update bundle", hdr.Name)
+		}
+
+		target := filepath.Join(targetPath, hdr.Name)
+
+		err = os.MkdirAll(filepath.Dir(target), 0o700)
+		if err != nil {
+			return nil, err
+		}
+
+		// #nosec G304
+		fd, err := os.Create(target)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = io.Copy(fd, tr) // #nosec G110
+		_ = fd.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate and parse the signed update metadata.
+	// #nosec G304
+	f, err := os.Open(filepath.Join(targetPath, "update.sjson"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.New("update bundle is missing update.sjson")
+		}
+
+		return nil, err
+	}
+
+	defer f.Close()
+
+	updateCA := s.System.Provider.Config.Config["update_ca"]
+	if updateCA == "" {
+		updateCA = UpdateCA(s)
+	}
+
+	update := &apiupdate.UpdateFull{}
+
+	signer, err := verifySignedJSON(ctx, f, updateCA, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify update bundle signature: %w", err)
+	}
+
+	// Get local architecture.
+	archName, err := osarch.ArchitectureGetLocal()
+	if err != nil {
+		return nil, err
+	}
+
+	update.Files, err = bundleFiles(update.Files, archName, targetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(update.Files) == 0 {
+		return nil, errors.New("update bundle doesn't contain any file for this system")
+	}
+
+	return &UpdateBundle{path: targetPath, signer: signer, update: update}, nil
+}
+
+// bundleFiles returns the files of the update metadata which are for the given architecture, checking that each
+// of them is included in the extracted bundle with the expected checksum.
+func bundleFiles(files []apiupdate.UpdateFile, archName string, targetPath string) ([]apiupdate.UpdateFile, error) {
+	ret := []apiupdate.UpdateFile{}
+
+	for _, file := range files {
+		if file.Architecture != "" && string(file.Architecture) != archName {
+			continue
+		}
+
+		if !filepath.IsLocal(file.Filename) {
+			return nil, fmt.Errorf("invalid file name %q in update metadata", file.Filename)
+		}
+
+		err := checkFileSHA256(filepath.Join(targetPath, file.Filename), file.Sha256)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("update bundle is missing %q", file.Filename)
+			}
+
+			return nil, err
+		}
+
+		ret = append(ret, file)
+	}
+
+	return ret, nil
+}
+
+// Version returns the version of the update bundle.
+func (b *UpdateBundle) Version() string {
+	return b.update.Version
+}
+
+// GetOSUpdate returns the OS update contained in the bundle.
+func (b *UpdateBundle) GetOSUpdate() (OSUpdate, error) {
+	// Check that all the OS update files are present.
+	for _, fileType := range osUpdateFileTypes {
+		found := slices.ContainsFunc(b.update.Files, func(file apiupdate.UpdateFile) bool {
+			return file.Component == apiupdate.UpdateFileComponentOS && file.Type == fileType
+		})
+
+		if !found {
+			return nil, ErrNoUpdateAvailable
+		}
+	}
+
+	return &bundleOSUpdate{bundle: b}, nil
+}
+
+// GetApplication returns the named application contained in the bundle.
+func (b *UpdateBundle) GetApplication(name string) (Application, error) {
+	found := slices.ContainsFunc(b.update.Files, func(file apiupdate.UpdateFile) bool {
+		return string(file.Component) == name
+	})
+
+	if !found {
+		return nil, ErrNoUpdateAvailable
+	}
+
+	return &bundleApplication{bundle: b, name: name}, nil
+}
+
+// extractFile decompresses a file from the bundle into the target path.
+func (b *UpdateBundle) extractFile(file apiupdate.UpdateFile, targetPath string, progressFunc func(float64)) error {
+	// #nosec G304
+	src, err := os.Open(filepath.Join(b.path, file.Filename))
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	// Get the file size.
+	s, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	srcSize := float64(s.Size())
+
+	body, err := gzip.NewReader(src)
+	if err != nil {
+		return errors.New("gzip error reading file: " + err.Error())
+	}
+
+	defer body.Close()
+
+	// #nosec G304
+	dst, err := os.Create(filepath.Join(targetPath, strings.TrimSuffix(filepath.Base(file.Filename), ".gz")))
+	if err != nil {
+		return err
+	}
+
+	defer dst.Close()
+
+	// Copy the content.
+	count := int64(0)
+
+	for {
+		_, err := io.CopyN(dst, body, 4*1024*1024)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+
+		// Update progress every 24MiB.
+		if progressFunc != nil && count%6 == 0 {
+			offset, err := src.Seek(0, io.SeekCurrent)
+			if err == nil {
+				progressFunc(float64(offset) / srcSize)
+			}
+		}
+
+		count++
+	}
+
+	return nil
+}
+
+// checkFileSHA256 validates the SHA256 checksum of the provided file.
+func checkFileSHA256(path string, expectedSHA256 string) error {
+	checksum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	if expectedSHA256 != checksum {
+		return errors.New("sha256 mismatch for file " + filepath.Base(path))
+	}
+
+	return nil
+}
+
+// An application from an update bundle.
+type bundleApplication struct {
+	bundle *UpdateBundle
+
+	name string
+}
+
+func (a *bundleApplication) Name() string {
+	return a.name
+}
+
+func (a *bundleApplication) Version() string {
+	return a.bundle.update.Version
+}
+
+func (a *bundleApplication) IsNewerThan(otherVersion string) bool {
+	return datetimeComparison(a.bundle.update.Version, otherVersion)
+}
+
+func (a *bundleApplication) Size() int64 {
+	size := int64(0)
+
+	for _, file := range a.bundle.update.Files {
+		if string(file.Component) == a.name {
+			size += file.Size
+		}
+	}
+
+	return size
+}
+
+func (a *bundleApplication) GetProvenance(_ context.Context) (api.SystemUpdateProvenance, error) {
+	return updateFilesProvenance(a.name, a.bundle.update.Version, a.bundle.signer, a.bundle.update.Files, "bundle:", func(file apiupdate.UpdateFile) bool {
+		return string(file.Component) == a.name
+	}), nil
+}
+
+func (a *bundleApplication) Download(_ context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
+	// Create the target path.
+	err := os.MkdirAll(targetPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range a.bundle.update.Files {
+		// Only select the desired applications.
+		if string(file.Component) != a.name {
+			continue
+		}
+
+		err = a.bundle.extractFile(file, targetPath, fileProgressFunc(progressFunc, strings.TrimSuffix(filepath.Base(file.Filename), ".gz"), file.Size))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// An OS update from an update bundle.
+type bundleOSUpdate struct {
+	bundle *UpdateBundle
+}
+
+func (o *bundleOSUpdate) Version() string {
+	return o.bundle.update.Version
+}
+
+func (o *bundleOSUpdate) IsNewerThan(otherVersion string) bool {
+	return datetimeComparison(o.bundle.update.Version, otherVersion)
+}
+
+func (o *bundleOSUpdate) Size() int64 {
+	size := int64(0)
+
+	for _, file := range o.bundle.update.Files {
+		if file.Component == apiupdate.UpdateFileComponentOS && slices.Contains(osUpdateFileTypes, file.Type) {
+			size += file.Size
+		}
+	}
+
+	return size
+}
+
+func (o *bundleOSUpdate) GetChecksums(_ context.Context) (map[string]string, error) {
+	checksums := map[string]string{}
+
+	for _, file := range o.bundle.update.Files {
+		// Only select OS updates.
+		if file.Component != apiupdate.UpdateFileComponentOS || !slices.Contains(osUpdateFileTypes, file.Type) {
+			continue
+		}
+
+		checksums[filepath.Base(file.Filename)] = file.Sha256
+	}
+
+	return checksums, nil
+}
+
+func (o *bundleOSUpdate) GetProvenance(_ context.Context) (api.SystemUpdateProvenance, error) {
+	return updateFilesProvenance("os", o.bundle.update.Version, o.bundle.signer, o.bundle.update.Files, "bundle:", func(file apiupdate.UpdateFile) bool {
+		return file.Component == apiupdate.UpdateFileComponentOS && slices.Contains(osUpdateFileTypes, file.Type)
+	}), nil
+}
+
+func (o *bundleOSUpdate) DownloadUpdate(_ context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
+	// Clear the target path.
+	err := os.RemoveAll(targetPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Create the target path.
+	err = os.MkdirAll(targetPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range o.bundle.update.Files {
+		// Only select OS updates.
+		if file.Component != apiupdate.UpdateFileComponentOS || !slices.Contains(osUpdateFileTypes, file.Type) {
+			continue
+		}
+
+		err = o.bundle.extractFile(file, targetPath, fileProgressFunc(progressFunc, strings.TrimSuffix(filepath.Base(file.Filename), ".gz"), file.Size))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (*bundleOSUpdate) DownloadImage(_ context.Context, _ string, _ string, _ func(float64)) (string, error) {
+	// Update bundles only carry the files needed to update an existing system.
+	return "", errors.New("downloading full image not supported from an update bundle")
+}
@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
+)
+
+func TestBundleFiles(t *testing.T) {
+	t.Parallel()
+
+	targetPath := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(targetPath, "incus.raw.gz"), []byte("incus"), 0o600))
+
+	checksum := sha256.Sum256([]byte("incus"))
+
+	files := []apiupdate.UpdateFile{
+		{Filename: "incus.raw.gz", Architecture: apiupdate.UpdateFileArchitecture64BitX86, Sha256: hex.EncodeToString(checksum[:])},
+		{Filename: "incus-arm.raw.gz", Architecture: apiupdate.UpdateFileArchitecture64BitARM},
+	}
+
+	// Files for other architectures are ignored.
+	ret, err := bundleFiles(files, "x86_64", targetPath)
+	require.NoError(t, err)
+	require.Len(t, ret, 1)
+
+	// Missing files and checksum mismatches are rejected.
+	_, err = bundleFiles(files, "aarch64", targetPath)
+	require.EqualError(t, err, `update bundle is missing "incus-arm.raw.gz"`)
+
+	files[0].Sha256 = "0000"
+
+	_, err = bundleFiles(files, "x86_64", targetPath)
+	require.EqualError(t, err, "sha256 mismatch for file incus.raw.gz")
+
+	// As are files outside of the bundle.
+	_, err = bundleFiles([]apiupdate.UpdateFile{{Filename: "../incus.raw.gz"}}, "x86_64", targetPath)
+	require.Error(t, err)
+}
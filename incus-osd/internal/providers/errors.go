@@ -2,6 +2,7 @@ package providers
 
 import (
 	"errors"
+	"time"
 )
 
 // ErrProviderUnavailable is returned when a provider isn't ready for use yet.
@@ -15,3 +16,12 @@ var ErrRegistrationUnsupported = errors.New("registration unsupported")
 
 // ErrDeregistrationUnsupported is returned if the provider doesn't (currently) support deregistration.
 var ErrDeregistrationUnsupported = errors.New("deregistration unsupported")
+
+// RateLimitError is returned when a provider's server is rate limiting requests.
+type RateLimitError struct {
+	RetryAt time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limited by server until " + e.RetryAt.Format(time.RFC3339)
+}
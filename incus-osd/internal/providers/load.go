@@ -5,37 +5,63 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
 // Load gets a specific provider and initializes it with the provider configuration.
 func Load(ctx context.Context, s *state.State) (Provider, error) {
+	return LoadFromConfig(ctx, s, s.System.Provider.Config)
+}
+
+// LoadFromConfig gets a specific provider and initializes it with the provided configuration
+// rather than the one currently recorded in the state.
+func LoadFromConfig(ctx context.Context, s *state.State, config api.SystemProviderConfig) (Provider, error) {
 	var p Provider
 
-	switch s.System.Provider.Config.Name {
+	// Decrypt the credentials.
+	providerConfig, err := secrets.OpenMap(config.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the provider credentials: %w", err)
+	}
+
+	switch config.Name {
 	case "images":
 		// Setup the images provider.
 		p = &images{
-			state: s,
+			state:  s,
+			config: providerConfig,
 		}
 
 	case "local":
 		// Setup the local provider.
 		p = &local{
-			state: s,
+			state:  s,
+			config: providerConfig,
+		}
+
+	case "share":
+		// Setup the network share provider.
+		p = &share{
+			local: local{
+				state:  s,
+				config: providerConfig,
+			},
 		}
 
 	case "operations-center":
 		// Setup the Operations Center provider.
 		p = &operationsCenter{
-			state: s,
+			state:  s,
+			config: providerConfig,
 		}
 
 	default:
-		return nil, fmt.Errorf("unknown provider %q", s.System.Provider.Config.Name)
+		return nil, fmt.Errorf("unknown provider %q", config.Name)
 	}
 
-	err := p.load(ctx)
+	err = p.load(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +69,30 @@ func Load(ctx context.Context, s *state.State) (Provider, error) {
 	return p, nil
 }
 
+// ServerURL returns the address of the server a provider configuration talks to, or an empty string
+// for providers which don't rely on the network.
+func ServerURL(config api.SystemProviderConfig) (string, error) {
+	providerConfig, err := secrets.OpenMap(config.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the provider credentials: %w", err)
+	}
+
+	switch config.Name {
+	case "images":
+		if providerConfig["server_url"] == "" {
+			return imagesDefaultServerURL, nil
+		}
+
+		return providerConfig["server_url"], nil
+
+	case "operations-center":
+		return providerConfig["server_url"], nil
+
+	default:
+		return "", nil
+	}
+}
+
 // Refresh is a hook being called whenever the current provider should be refreshed.
 func Refresh(ctx context.Context, s *state.State) error {
 	if s.System.Provider.Config.Name == "" {
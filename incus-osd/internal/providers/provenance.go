@@ -0,0 +1,53 @@
+package providers
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// RecordProvenance records where the files of a just downloaded update came from, so the origin of the
+// installed artifacts can be audited later on. Failures are only logged, as they shouldn't block updates.
+func RecordProvenance(ctx context.Context, s *state.State, providerType string, getProvenance func(context.Context) (api.SystemUpdateProvenance, error)) {
+	provenance, err := getProvenance(ctx)
+	if err == nil {
+		provenance.Provider = providerType
+		provenance.Downloaded = time.Now().UTC()
+
+		err = s.RecordProvenance(provenance)
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to record the update provenance", "err", err.Error())
+	}
+}
+
+// updateFilesProvenance returns the provenance of the files of an update matching the provided filter, each file's
+// source being its name appended to the base source.
+func updateFilesProvenance(component string, version string, signer string, files []apiupdate.UpdateFile, baseSource string, filter func(apiupdate.UpdateFile) bool) api.SystemUpdateProvenance {
+	ret := api.SystemUpdateProvenance{
+		Component: component,
+		Version:   version,
+		Signer:    signer,
+		Files:     []api.SystemUpdateProvenanceFile{},
+	}
+
+	for _, file := range files {
+		if !filter(file) {
+			continue
+		}
+
+		ret.Files = append(ret.Files, api.SystemUpdateProvenanceFile{
+			Filename: filepath.Base(file.Filename),
+			Source:   baseSource + file.Filename,
+			Sha256:   file.Sha256,
+		})
+	}
+
+	return ret
+}
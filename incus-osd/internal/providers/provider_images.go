@@ -1,12 +1,12 @@
 package providers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
@@ -14,21 +14,28 @@ import (
 	"time"
 
 	"github.com/lxc/incus/v6/shared/osarch"
-	"github.com/lxc/incus/v6/shared/subprocess"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
+// imagesDefaultServerURL is the image server used when none is configured.
+const imagesDefaultServerURL = "https://images.linuxcontainers.org/os"
+
 // The images provider.
 type images struct {
-	state *state.State
+	state  *state.State
+	config map[string]string
 
+	client    *http.Client
 	serverURL string
 	updateCA  string
+	signer    string // Fingerprint of the certificate which signed the last retrieved index.
 
 	lastCheck    time.Time // In system's timezone.
 	latestUpdate *apiupdate.UpdateFull
+	updates      []apiupdate.UpdateFull // All the updates for the local architecture, as of the last check.
 }
 
 func (p *images) ClearCache(_ context.Context) error {
@@ -87,6 +94,36 @@ func (p *images) GetSecureBootCertUpdate(ctx context.Context) (SecureBootCertUpd
 	return &update, nil
 }
 
+func (p *images) GetSecureBootDbxUpdate(ctx context.Context) (SecureBootCertUpdate, error) {
+	// Get latest release.
+	latestUpdate, err := p.checkRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if a dbx update is included.
+	found := false
+
+	for _, file := range latestUpdate.Files {
+		if file.Type == apiupdate.UpdateFileTypeUpdateSecurebootDbx {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		return nil, ErrNoUpdateAvailable
+	}
+
+	update := imagesSecureBootDbxUpdate{
+		provider:     p,
+		latestUpdate: latestUpdate,
+	}
+
+	return &update, nil
+}
+
 func (p *images) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 	// Get latest release.
 	latestUpdate, err := p.checkRelease(ctx)
@@ -125,6 +162,36 @@ func (p *images) GetApplication(ctx context.Context, name string) (Application,
 		return nil, err
 	}
 
+	// Look for a different release if the application tracks its own channel or is pinned.
+	config := p.state.Applications[name].Config
+	if config.UpdateChannel != "" || config.UpdateVersion != "" {
+		// The list of updates isn't persisted across reboots.
+		if p.updates == nil {
+			p.lastCheck = time.Time{}
+
+			_, err = p.checkRelease(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		latestUpdate = nil
+
+		for _, update := range p.updates {
+			if !matchesApplicationConfig(config, update.Version, update.Channels) {
+				continue
+			}
+
+			latestUpdate = &update
+
+			break
+		}
+
+		if latestUpdate == nil {
+			return nil, ErrNoUpdateAvailable
+		}
+	}
+
 	// Check that an application update is included.
 	found := false
 
@@ -152,18 +219,56 @@ func (p *images) GetApplication(ctx context.Context, name string) (Application,
 
 func (p *images) load(_ context.Context) error {
 	// Set up the configuration.
-	p.serverURL = p.state.System.Provider.Config.Config["server_url"]
-	p.updateCA = p.state.System.Provider.Config.Config["update_ca"]
+	p.serverURL = p.config["server_url"]
+	p.updateCA = p.config["update_ca"]
 
 	// Basic validation.
 	if p.serverURL == "" {
-		p.serverURL = "https://images.linuxcontainers.org/os"
-		p.updateCA = LXCUpdateCA
+		p.serverURL = imagesDefaultServerURL
+		p.updateCA = UpdateCA(p.state)
+	} else if p.updateCA == "" {
+		p.updateCA = UpdateCA(p.state)
+	}
+
+	// Authenticate to private image servers if a token is provided.
+	p.client = http.DefaultClient
+
+	if p.config["auth_token"] != "" {
+		serverURL, err := url.Parse(p.serverURL)
+		if err != nil {
+			return err
+		}
+
+		p.client = &http.Client{
+			Transport: &bearerTokenTransport{
+				token: p.config["auth_token"],
+				host:  serverURL.Host,
+				base:  http.DefaultTransport,
+			},
+		}
+	}
+
+	// Restore the last known release, so we don't need to hit the network right after a reboot.
+	cache := p.state.ProviderCache
+	if p.isPrimary() && cache.Source == p.serverURL && cache.Channel == p.state.System.Update.Config.Channel && cache.Release != "" {
+		latestUpdate := &apiupdate.UpdateFull{}
+
+		err := json.Unmarshal([]byte(cache.Release), latestUpdate)
+		if err == nil {
+			p.lastCheck = time.Unix(cache.LastCheck, 0)
+			p.latestUpdate = latestUpdate
+			p.signer = cache.Signer
+		}
 	}
 
 	return nil
 }
 
+// isPrimary returns whether the provider is the system's provider, rather than a verification provider.
+func (p *images) isPrimary() bool {
+	return p.state.System.Provider.Config.Name == "images"
+}
+
 func (p *images) checkRelease(ctx context.Context) (*apiupdate.UpdateFull, error) {
 	// Only talk to image server once an hour.
 	if p.latestUpdate != nil && !p.lastCheck.IsZero() && p.lastCheck.Add(time.Hour).After(time.Now()) {
@@ -182,7 +287,7 @@ func (p *images) checkRelease(ctx context.Context) (*apiupdate.UpdateFull, error
 		return nil, err
 	}
 
-	resp, err := tryRequest(http.DefaultClient, req)
+	resp, err := tryRequest(p.client, req)
 	if err != nil {
 		return nil, err
 	}
@@ -192,44 +297,18 @@ func (p *images) checkRelease(ctx context.Context) (*apiupdate.UpdateFull, error
 		return nil, errors.New("server failed to return expected file")
 	}
 
-	// Write the CA certificate.
-	rootCA, err := os.CreateTemp("", "")
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = fmt.Fprintf(rootCA, "%s", p.updateCA)
-	if err != nil {
-		return nil, err
-	}
-
-	defer func() { _ = os.Remove(rootCA.Name()) }()
-
-	// Validate signed index.
-	verified := bytes.NewBuffer(nil)
-
-	err = subprocess.RunCommandWithFds(ctx, resp.Body, verified, "openssl", "smime", "-verify", "-text", "-CAfile", rootCA.Name())
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the update list.
+	// Validate and parse the signed index.
 	index := &apiupdate.Index{}
 
-	err = json.NewDecoder(bytes.NewReader(verified.Bytes())).Decode(index)
+	signer, err := verifySignedJSON(ctx, resp.Body, p.updateCA, index)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get the latest update for the expected channel.
-	var latestUpdate *apiupdate.UpdateFull
+	// Get the updates for the local architecture.
+	updates := []apiupdate.UpdateFull{}
 
 	for _, update := range index.Updates {
-		// Skip any update targeting the wrong channel(s).
-		if update.Version != p.state.OS.RunningRelease && p.state.System.Update.Config.Channel != "" && !slices.Contains(update.Channels, p.state.System.Update.Config.Channel) {
-			continue
-		}
-
 		// Skip any update with no files.
 		if len(update.Files) == 0 {
 			continue
@@ -253,6 +332,18 @@ func (p *images) checkRelease(ctx context.Context) (*apiupdate.UpdateFull, error
 			continue
 		}
 
+		updates = append(updates, update)
+	}
+
+	// Get the latest update for the expected channel.
+	var latestUpdate *apiupdate.UpdateFull
+
+	for _, update := range updates {
+		// Skip any update targeting the wrong channel(s).
+		if update.Version != p.state.OS.RunningRelease && p.state.System.Update.Config.Channel != "" && !slices.Contains(update.Channels, p.state.System.Update.Config.Channel) {
+			continue
+		}
+
 		latestUpdate = &update
 
 		break
@@ -265,6 +356,21 @@ func (p *images) checkRelease(ctx context.Context) (*apiupdate.UpdateFull, error
 	// Record the release.
 	p.lastCheck = time.Now()
 	p.latestUpdate = latestUpdate
+	p.updates = updates
+	p.signer = signer
+
+	// Persist the release across reboots, unless used as a verification provider as the cache belongs to the
+	// system's provider.
+	body, err := json.Marshal(latestUpdate)
+	if err == nil && p.isPrimary() {
+		p.state.ProviderCache = state.ProviderCache{
+			Source:    p.serverURL,
+			Channel:   p.state.System.Update.Config.Channel,
+			LastCheck: p.lastCheck.Unix(),
+			Release:   string(body),
+			Signer:    signer,
+		}
+	}
 
 	return latestUpdate, nil
 }
@@ -289,7 +395,25 @@ func (a *imagesApplication) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(a.latestUpdate.Version, otherVersion)
 }
 
-func (a *imagesApplication) Download(ctx context.Context, targetPath string, progressFunc func(float64)) error {
+func (a *imagesApplication) Size() int64 {
+	size := int64(0)
+
+	for _, file := range a.latestUpdate.Files {
+		if string(file.Component) == a.name {
+			size += file.Size
+		}
+	}
+
+	return size
+}
+
+func (a *imagesApplication) GetProvenance(_ context.Context) (api.SystemUpdateProvenance, error) {
+	return updateFilesProvenance(a.name, a.latestUpdate.Version, a.provider.signer, a.latestUpdate.Files, a.provider.serverURL+"/"+a.latestUpdate.Version+"/", func(file apiupdate.UpdateFile) bool {
+		return string(file.Component) == a.name
+	}), nil
+}
+
+func (a *imagesApplication) Download(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
 	// Create the target path.
 	err := os.MkdirAll(targetPath, 0o700)
 	if err != nil {
@@ -306,7 +430,7 @@ func (a *imagesApplication) Download(ctx context.Context, targetPath string, pro
 		targetName := strings.TrimSuffix(filepath.Base(file.Filename), ".gz")
 
 		// Download the application.
-		err = downloadAsset(ctx, http.DefaultClient, fileURL, file.Sha256, filepath.Join(targetPath, targetName), progressFunc)
+		err = downloadAsset(ctx, a.provider.client, fileURL, file.Sha256, filepath.Join(targetPath, targetName), fileProgressFunc(progressFunc, targetName, file.Size))
 		if err != nil {
 			return fmt.Errorf("while downloading %s, got error '%s'", fileURL, err.Error())
 		}
@@ -330,7 +454,40 @@ func (o *imagesOSUpdate) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(o.latestUpdate.Version, otherVersion)
 }
 
-func (o *imagesOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(float64)) error {
+func (o *imagesOSUpdate) Size() int64 {
+	size := int64(0)
+
+	for _, file := range o.latestUpdate.Files {
+		if file.Component == apiupdate.UpdateFileComponentOS && slices.Contains(osUpdateFileTypes, file.Type) {
+			size += file.Size
+		}
+	}
+
+	return size
+}
+
+func (o *imagesOSUpdate) GetChecksums(_ context.Context) (map[string]string, error) {
+	checksums := map[string]string{}
+
+	for _, file := range o.latestUpdate.Files {
+		// Only select OS updates.
+		if file.Component != apiupdate.UpdateFileComponentOS || !slices.Contains(osUpdateFileTypes, file.Type) {
+			continue
+		}
+
+		checksums[filepath.Base(file.Filename)] = file.Sha256
+	}
+
+	return checksums, nil
+}
+
+func (o *imagesOSUpdate) GetProvenance(_ context.Context) (api.SystemUpdateProvenance, error) {
+	return updateFilesProvenance("os", o.latestUpdate.Version, o.provider.signer, o.latestUpdate.Files, o.provider.serverURL+"/"+o.latestUpdate.Version+"/", func(file apiupdate.UpdateFile) bool {
+		return file.Component == apiupdate.UpdateFileComponentOS && slices.Contains(osUpdateFileTypes, file.Type)
+	}), nil
+}
+
+func (o *imagesOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
 	// Clear the target path.
 	err := os.RemoveAll(targetPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -345,7 +502,7 @@ func (o *imagesOSUpdate) DownloadUpdate(ctx context.Context, targetPath string,
 
 	for _, file := range o.latestUpdate.Files {
 		// Only select OS updates.
-		if file.Component != apiupdate.UpdateFileComponentOS || !slices.Contains([]apiupdate.UpdateFileType{apiupdate.UpdateFileTypeUpdateEFI, apiupdate.UpdateFileTypeUpdateUsr, apiupdate.UpdateFileTypeUpdateUsrVerity, apiupdate.UpdateFileTypeUpdateUsrVeritySignature}, file.Type) {
+		if file.Component != apiupdate.UpdateFileComponentOS || !slices.Contains(osUpdateFileTypes, file.Type) {
 			continue
 		}
 
@@ -353,7 +510,7 @@ func (o *imagesOSUpdate) DownloadUpdate(ctx context.Context, targetPath string,
 		targetName := strings.TrimSuffix(filepath.Base(file.Filename), ".gz")
 
 		// Download the application.
-		err = downloadAsset(ctx, http.DefaultClient, fileURL, file.Sha256, filepath.Join(targetPath, targetName), progressFunc)
+		err = downloadAsset(ctx, o.provider.client, fileURL, file.Sha256, filepath.Join(targetPath, targetName), fileProgressFunc(progressFunc, targetName, file.Size))
 		if err != nil {
 			return fmt.Errorf("while downloading %s, got error '%s'", fileURL, err.Error())
 		}
@@ -379,7 +536,7 @@ func (o *imagesOSUpdate) DownloadImage(ctx context.Context, imageType string, ta
 		targetName := strings.TrimSuffix(filepath.Base(file.Filename), ".gz")
 
 		// Download the application.
-		err = downloadAsset(ctx, http.DefaultClient, fileURL, file.Sha256, filepath.Join(targetPath, targetName), progressFunc)
+		err = downloadAsset(ctx, o.provider.client, fileURL, file.Sha256, filepath.Join(targetPath, targetName), progressFunc)
 
 		return targetName, err
 	}
@@ -429,7 +586,50 @@ func (o *imagesSecureBootCertUpdate) Download(ctx context.Context, targetPath st
 		fileURL := o.provider.serverURL + "/" + o.latestUpdate.Version + "/" + file.Filename
 
 		// Download the application.
-		err = downloadAsset(ctx, http.DefaultClient, fileURL, file.Sha256, filepath.Join(targetPath, o.GetFilename()), nil)
+		err = downloadAsset(ctx, o.provider.client, fileURL, file.Sha256, filepath.Join(targetPath, o.GetFilename()), nil)
+		if err != nil {
+			return fmt.Errorf("while downloading %s, got error '%s'", fileURL, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Secure Boot dbx revocation list updates from the images provider.
+type imagesSecureBootDbxUpdate struct {
+	provider *images
+
+	latestUpdate *apiupdate.UpdateFull
+}
+
+func (o *imagesSecureBootDbxUpdate) Version() string {
+	return o.latestUpdate.Version
+}
+
+func (o *imagesSecureBootDbxUpdate) GetFilename() string {
+	return "SecureBootDbx_" + o.latestUpdate.Version + ".auth"
+}
+
+func (o *imagesSecureBootDbxUpdate) IsNewerThan(otherVersion string) bool {
+	return datetimeComparison(o.latestUpdate.Version, otherVersion)
+}
+
+func (o *imagesSecureBootDbxUpdate) Download(ctx context.Context, targetPath string) error {
+	// Create the target path.
+	err := os.MkdirAll(targetPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range o.latestUpdate.Files {
+		// Only select the dbx update.
+		if file.Type != apiupdate.UpdateFileTypeUpdateSecurebootDbx {
+			continue
+		}
+
+		fileURL := o.provider.serverURL + "/" + o.latestUpdate.Version + "/" + file.Filename
+
+		err = downloadAsset(ctx, o.provider.client, fileURL, file.Sha256, filepath.Join(targetPath, o.GetFilename()), nil)
 		if err != nil {
 			return fmt.Errorf("while downloading %s, got error '%s'", fileURL, err.Error())
 		}
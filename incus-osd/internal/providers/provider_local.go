@@ -7,16 +7,20 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
 // The Local provider.
 type local struct {
-	state *state.State
+	state  *state.State
+	config map[string]string
 
-	path string
+	path   string
+	origin string // Location reported as the source of the assets, if not the local path.
 
 	releaseAssets  []string
 	releaseVersion string
@@ -79,6 +83,27 @@ func (p *local) GetSecureBootCertUpdate(ctx context.Context) (SecureBootCertUpda
 	return &update, nil
 }
 
+func (p *local) GetSecureBootDbxUpdate(ctx context.Context) (SecureBootCertUpdate, error) {
+	// Get latest release.
+	err := p.checkRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the list of returned assets contains a dbx update for the release version.
+	update := localSecureBootDbxUpdate{
+		provider: p,
+		assets:   p.releaseAssets,
+		version:  p.releaseVersion,
+	}
+
+	if !slices.ContainsFunc(p.releaseAssets, func(asset string) bool { return filepath.Base(asset) == update.GetFilename() }) {
+		return nil, ErrNoUpdateAvailable
+	}
+
+	return &update, nil
+}
+
 func (p *local) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 	// Get latest release.
 	err := p.checkRelease(ctx)
@@ -147,8 +172,13 @@ func (p *local) GetApplication(ctx context.Context, name string) (Application, e
 }
 
 func (p *local) load(_ context.Context) error {
-	// Use a hardcoded path for now.
-	p.path = "/root/updates/"
+	// Set up the configuration.
+	p.path = p.config["path"]
+
+	// Default to the development path.
+	if p.path == "" {
+		p.path = "/root/updates/"
+	}
 
 	return nil
 }
@@ -240,6 +270,35 @@ func (p *local) copyAsset(_ context.Context, name string, targetPath string, pro
 	return nil
 }
 
+// assetProvenance returns the provenance of a local asset.
+func (p *local) assetProvenance(asset string) (api.SystemUpdateProvenanceFile, error) {
+	checksum, err := fileSHA256(asset)
+	if err != nil {
+		return api.SystemUpdateProvenanceFile{}, err
+	}
+
+	source := asset
+	if p.origin != "" {
+		source = p.origin + "/" + filepath.Base(asset)
+	}
+
+	return api.SystemUpdateProvenanceFile{
+		Filename: filepath.Base(asset),
+		Source:   source,
+		Sha256:   checksum,
+	}, nil
+}
+
+// assetSize returns the size of a local asset, or zero if it can't be determined.
+func assetSize(asset string) int64 {
+	fi, err := os.Stat(asset)
+	if err != nil {
+		return 0
+	}
+
+	return fi.Size()
+}
+
 // An application from the Local provider.
 type localApplication struct {
 	provider *local
@@ -261,7 +320,43 @@ func (a *localApplication) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(a.version, otherVersion)
 }
 
-func (a *localApplication) Download(ctx context.Context, targetPath string, progressFunc func(float64)) error {
+func (a *localApplication) Size() int64 {
+	size := int64(0)
+
+	for _, asset := range a.assets {
+		if strings.TrimSuffix(filepath.Base(asset), ".raw") == a.name {
+			size += assetSize(asset)
+		}
+	}
+
+	return size
+}
+
+func (a *localApplication) GetProvenance(_ context.Context) (api.SystemUpdateProvenance, error) {
+	ret := api.SystemUpdateProvenance{
+		Component: a.name,
+		Version:   a.version,
+		Files:     []api.SystemUpdateProvenanceFile{},
+	}
+
+	for _, asset := range a.assets {
+		// Only select the desired applications.
+		if strings.TrimSuffix(filepath.Base(asset), ".raw") != a.name {
+			continue
+		}
+
+		file, err := a.provider.assetProvenance(asset)
+		if err != nil {
+			return ret, err
+		}
+
+		ret.Files = append(ret.Files, file)
+	}
+
+	return ret, nil
+}
+
+func (a *localApplication) Download(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
 	// Create the target path.
 	err := os.MkdirAll(targetPath, 0o700)
 	if err != nil {
@@ -277,7 +372,7 @@ func (a *localApplication) Download(ctx context.Context, targetPath string, prog
 		}
 
 		// Copy the application.
-		err = a.provider.copyAsset(ctx, filepath.Base(asset), targetPath, progressFunc)
+		err = a.provider.copyAsset(ctx, filepath.Base(asset), targetPath, fileProgressFunc(progressFunc, filepath.Base(asset), assetSize(asset)))
 		if err != nil {
 			return err
 		}
@@ -302,7 +397,65 @@ func (o *localOSUpdate) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(o.version, otherVersion)
 }
 
-func (o *localOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(float64)) error {
+func (o *localOSUpdate) Size() int64 {
+	size := int64(0)
+
+	for _, asset := range o.assets {
+		// Only select OS files for the expected version, skipping the full image.
+		if strings.HasPrefix(filepath.Base(asset), "IncusOS_"+o.version) && !strings.HasSuffix(asset, ".raw") {
+			size += assetSize(asset)
+		}
+	}
+
+	return size
+}
+
+func (o *localOSUpdate) GetChecksums(_ context.Context) (map[string]string, error) {
+	checksums := map[string]string{}
+
+	for _, asset := range o.assets {
+		// Only select OS files for the expected version.
+		if !strings.HasPrefix(filepath.Base(asset), "IncusOS_"+o.version) || strings.HasSuffix(asset, ".raw") {
+			continue
+		}
+
+		// Hash the file.
+		checksum, err := fileSHA256(asset)
+		if err != nil {
+			return nil, err
+		}
+
+		checksums[filepath.Base(asset)] = checksum
+	}
+
+	return checksums, nil
+}
+
+func (o *localOSUpdate) GetProvenance(_ context.Context) (api.SystemUpdateProvenance, error) {
+	ret := api.SystemUpdateProvenance{
+		Component: "os",
+		Version:   o.version,
+		Files:     []api.SystemUpdateProvenanceFile{},
+	}
+
+	for _, asset := range o.assets {
+		// Only select OS files for the expected version, skipping the full image.
+		if !strings.HasPrefix(filepath.Base(asset), "IncusOS_"+o.version) || strings.HasSuffix(asset, ".raw") {
+			continue
+		}
+
+		file, err := o.provider.assetProvenance(asset)
+		if err != nil {
+			return ret, err
+		}
+
+		ret.Files = append(ret.Files, file)
+	}
+
+	return ret, nil
+}
+
+func (o *localOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
 	// Clear the path.
 	err := os.RemoveAll(targetPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -333,7 +486,7 @@ func (o *localOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, p
 		}
 
 		// Download the actual update.
-		err = o.provider.copyAsset(ctx, filepath.Base(asset), targetPath, progressFunc)
+		err = o.provider.copyAsset(ctx, filepath.Base(asset), targetPath, fileProgressFunc(progressFunc, filepath.Base(asset), assetSize(asset)))
 		if err != nil {
 			return err
 		}
@@ -383,3 +536,39 @@ func (o *localSecureBootCertUpdate) Download(ctx context.Context, targetPath str
 
 	return nil
 }
+
+// Secure Boot dbx revocation list updates from the Local provider.
+type localSecureBootDbxUpdate struct {
+	provider *local
+
+	assets  []string
+	version string
+}
+
+func (o *localSecureBootDbxUpdate) Version() string {
+	return o.version
+}
+
+func (o *localSecureBootDbxUpdate) GetFilename() string {
+	return "SecureBootDbx_" + o.version + ".auth"
+}
+
+func (o *localSecureBootDbxUpdate) IsNewerThan(otherVersion string) bool {
+	return datetimeComparison(o.version, otherVersion)
+}
+
+func (o *localSecureBootDbxUpdate) Download(ctx context.Context, targetPath string) error {
+	for _, asset := range o.assets {
+		// Only select the dbx update for the expected version.
+		if filepath.Base(asset) != o.GetFilename() {
+			continue
+		}
+
+		err := o.provider.copyAsset(ctx, filepath.Base(asset), targetPath, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/lxc/incus/v6/shared/osarch"
 	incustls "github.com/lxc/incus/v6/shared/tls"
 
+	osapi "github.com/lxc/incus-os/incus-osd/api"
 	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
@@ -49,9 +50,33 @@ type operationsCenterUpdateFile struct {
 	url string
 }
 
+// provenance returns the provenance of the update's files matching the provided filter.
+func (u *operationsCenterUpdate) provenance(component string, filter func(operationsCenterUpdateFile) bool) osapi.SystemUpdateProvenance {
+	ret := osapi.SystemUpdateProvenance{
+		Component: component,
+		Version:   u.Version,
+		Files:     []osapi.SystemUpdateProvenanceFile{},
+	}
+
+	for _, file := range u.Files {
+		if !filter(file) {
+			continue
+		}
+
+		ret.Files = append(ret.Files, osapi.SystemUpdateProvenanceFile{
+			Filename: filepath.Base(file.Filename),
+			Source:   file.url,
+			Sha256:   file.Sha256,
+		})
+	}
+
+	return ret
+}
+
 // The Operations Center provider.
 type operationsCenter struct {
-	state *state.State
+	state  *state.State
+	config map[string]string
 
 	client *http.Client
 
@@ -61,6 +86,7 @@ type operationsCenter struct {
 
 	lastCheck    time.Time // In system's timezone.
 	latestUpdate *operationsCenterUpdate
+	updates      []operationsCenterUpdate // All the updates, as of the last check.
 	releaseMu    sync.Mutex
 }
 
@@ -136,7 +162,7 @@ func (p *operationsCenter) Register(ctx context.Context, _ bool) error {
 	}
 
 	// Register.
-	resp, err := p.apiRequest(ctx, http.MethodPost, "/1.0/provisioning/servers?token="+p.state.System.Provider.Config.Config["server_token"], bytes.NewReader(data))
+	resp, err := p.apiRequest(ctx, http.MethodPost, "/1.0/provisioning/servers?token="+p.serverToken, bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
@@ -203,6 +229,36 @@ func (p *operationsCenter) GetSecureBootCertUpdate(ctx context.Context) (SecureB
 	return &update, nil
 }
 
+func (p *operationsCenter) GetSecureBootDbxUpdate(ctx context.Context) (SecureBootCertUpdate, error) {
+	// Get latest release.
+	latestUpdate, err := p.checkRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if a dbx update is included.
+	found := false
+
+	for _, file := range latestUpdate.Files {
+		if file.Type == string(apiupdate.UpdateFileTypeUpdateSecurebootDbx) {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		return nil, ErrNoUpdateAvailable
+	}
+
+	update := operationsCenterSecureBootDbxUpdate{
+		provider:     p,
+		latestUpdate: latestUpdate,
+	}
+
+	return &update, nil
+}
+
 func (p *operationsCenter) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 	// Get latest release.
 	latestUpdate, err := p.checkRelease(ctx)
@@ -241,6 +297,15 @@ func (p *operationsCenter) GetApplication(ctx context.Context, name string) (App
 		return nil, err
 	}
 
+	// Look for a different release if the application tracks its own channel or is pinned.
+	config := p.state.Applications[name].Config
+	if config.UpdateChannel != "" || config.UpdateVersion != "" {
+		latestUpdate, err = p.checkApplicationRelease(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Check that an application update is included.
 	found := false
 
@@ -260,7 +325,7 @@ func (p *operationsCenter) GetApplication(ctx context.Context, name string) (App
 	app := operationsCenterApplication{
 		provider:     p,
 		name:         name,
-		latestUpdate: p.latestUpdate,
+		latestUpdate: latestUpdate,
 	}
 
 	return &app, nil
@@ -270,9 +335,9 @@ func (p *operationsCenter) load(ctx context.Context) error {
 	p.client = &http.Client{}
 
 	// Set up the configuration.
-	p.serverCertificate = p.state.System.Provider.Config.Config["server_certificate"]
-	p.serverURL = p.state.System.Provider.Config.Config["server_url"]
-	p.serverToken = p.state.System.Provider.Config.Config["server_token"]
+	p.serverCertificate = p.config["server_certificate"]
+	p.serverURL = p.config["server_url"]
+	p.serverToken = p.config["server_token"]
 
 	// Basic validation.
 	if p.serverURL == "" {
@@ -401,7 +466,7 @@ func (p *operationsCenter) apiRequest(ctx context.Context, method string, path s
 			}
 
 			// If successful, commit the change of config.
-			delete(p.state.System.Provider.Config.Config, "server_certificate")
+			delete(p.config, "server_certificate")
 
 			return resp, nil
 		}
@@ -491,41 +556,83 @@ func (p *operationsCenter) checkRelease(ctx context.Context) (*operationsCenterU
 	}
 
 	// Get the file list.
-	apiResp, err = p.apiRequest(ctx, http.MethodGet, "/1.0/provisioning/updates/"+latestUpdate.UUID+"/files", nil)
+	err = p.getUpdateFiles(ctx, latestUpdate, archName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the release.
+	p.lastCheck = time.Now()
+	p.latestUpdate = latestUpdate
+	p.updates = updates
+
+	return latestUpdate, nil
+}
+
+// checkApplicationRelease returns the latest release matching the update channel or pinned version of an application.
+func (p *operationsCenter) checkApplicationRelease(ctx context.Context, config osapi.ApplicationConfig) (*operationsCenterUpdate, error) {
+	// Acquire lock.
+	p.releaseMu.Lock()
+	defer p.releaseMu.Unlock()
+
+	// Get local architecture.
+	archName, err := osarch.ArchitectureGetLocal()
 	if err != nil {
 		return nil, err
 	}
 
+	for _, update := range p.updates {
+		if !matchesApplicationConfig(config, update.Version, update.Channels) {
+			continue
+		}
+
+		if p.latestUpdate != nil && update.UUID == p.latestUpdate.UUID {
+			return p.latestUpdate, nil
+		}
+
+		// Get the file list.
+		err = p.getUpdateFiles(ctx, &update, archName)
+		if err != nil {
+			return nil, err
+		}
+
+		return &update, nil
+	}
+
+	return nil, ErrNoUpdateAvailable
+}
+
+// getUpdateFiles retrieves the files of the update for the local architecture.
+func (p *operationsCenter) getUpdateFiles(ctx context.Context, update *operationsCenterUpdate, archName string) error {
+	apiResp, err := p.apiRequest(ctx, http.MethodGet, "/1.0/provisioning/updates/"+update.UUID+"/files", nil)
+	if err != nil {
+		return err
+	}
+
 	// Parse the file list.
 	files := []operationsCenterUpdateFile{}
 
 	err = apiResp.MetadataAsStruct(&files)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	latestUpdateFiles := []operationsCenterUpdateFile{}
+	update.Files = []operationsCenterUpdateFile{}
 
 	for _, file := range files {
 		if file.Architecture != "" && file.Architecture != archName {
 			continue
 		}
 
-		file.url = p.serverURL + "/1.0/provisioning/updates/" + updates[0].UUID + "/files/" + file.Filename
-		latestUpdateFiles = append(latestUpdateFiles, file)
+		file.url = p.serverURL + "/1.0/provisioning/updates/" + update.UUID + "/files/" + file.Filename
+		update.Files = append(update.Files, file)
 	}
 
-	latestUpdate.Files = latestUpdateFiles
-
-	if len(latestUpdate.Files) == 0 {
-		return nil, ErrNoUpdateAvailable
+	if len(update.Files) == 0 {
+		return ErrNoUpdateAvailable
 	}
 
-	// Record the release.
-	p.lastCheck = time.Now()
-	p.latestUpdate = latestUpdate
-
-	return latestUpdate, nil
+	return nil
 }
 
 // An application from the Operations Center provider.
@@ -548,7 +655,25 @@ func (a *operationsCenterApplication) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(a.latestUpdate.Version, otherVersion)
 }
 
-func (a *operationsCenterApplication) Download(ctx context.Context, targetPath string, progressFunc func(float64)) error {
+func (a *operationsCenterApplication) Size() int64 {
+	size := int64(0)
+
+	for _, file := range a.latestUpdate.Files {
+		if file.Component == a.name {
+			size += file.Size
+		}
+	}
+
+	return size
+}
+
+func (a *operationsCenterApplication) GetProvenance(_ context.Context) (osapi.SystemUpdateProvenance, error) {
+	return a.latestUpdate.provenance(a.name, func(file operationsCenterUpdateFile) bool {
+		return file.Component == a.name
+	}), nil
+}
+
+func (a *operationsCenterApplication) Download(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
 	// Create the target path.
 	err := os.MkdirAll(targetPath, 0o700)
 	if err != nil {
@@ -564,7 +689,7 @@ func (a *operationsCenterApplication) Download(ctx context.Context, targetPath s
 		targetName := strings.TrimSuffix(filepath.Base(file.Filename), ".gz")
 
 		// Download the application.
-		err = downloadAsset(ctx, a.provider.client, file.url, file.Sha256, filepath.Join(targetPath, targetName), progressFunc)
+		err = downloadAsset(ctx, a.provider.client, file.url, file.Sha256, filepath.Join(targetPath, targetName), fileProgressFunc(progressFunc, targetName, file.Size))
 		if err != nil {
 			return fmt.Errorf("while downloading %s, got error '%s'", file.url, err.Error())
 		}
@@ -588,7 +713,40 @@ func (o *operationsCenterOSUpdate) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(o.latestUpdate.Version, otherVersion)
 }
 
-func (o *operationsCenterOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(float64)) error {
+func (o *operationsCenterOSUpdate) Size() int64 {
+	size := int64(0)
+
+	for _, file := range o.latestUpdate.Files {
+		if file.Component == string(apiupdate.UpdateFileComponentOS) && slices.Contains(osUpdateFileTypes, apiupdate.UpdateFileType(file.Type)) {
+			size += file.Size
+		}
+	}
+
+	return size
+}
+
+func (o *operationsCenterOSUpdate) GetChecksums(_ context.Context) (map[string]string, error) {
+	checksums := map[string]string{}
+
+	for _, file := range o.latestUpdate.Files {
+		// Only select OS updates.
+		if file.Component != string(apiupdate.UpdateFileComponentOS) || !slices.Contains(osUpdateFileTypes, apiupdate.UpdateFileType(file.Type)) {
+			continue
+		}
+
+		checksums[filepath.Base(file.Filename)] = file.Sha256
+	}
+
+	return checksums, nil
+}
+
+func (o *operationsCenterOSUpdate) GetProvenance(_ context.Context) (osapi.SystemUpdateProvenance, error) {
+	return o.latestUpdate.provenance("os", func(file operationsCenterUpdateFile) bool {
+		return file.Component == string(apiupdate.UpdateFileComponentOS) && slices.Contains(osUpdateFileTypes, apiupdate.UpdateFileType(file.Type))
+	}), nil
+}
+
+func (o *operationsCenterOSUpdate) DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error {
 	// Clear the target path.
 	err := os.RemoveAll(targetPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -603,14 +761,14 @@ func (o *operationsCenterOSUpdate) DownloadUpdate(ctx context.Context, targetPat
 
 	for _, file := range o.latestUpdate.Files {
 		// Only select OS updates.
-		if file.Component != string(apiupdate.UpdateFileComponentOS) || !slices.Contains([]apiupdate.UpdateFileType{apiupdate.UpdateFileTypeUpdateEFI, apiupdate.UpdateFileTypeUpdateUsr, apiupdate.UpdateFileTypeUpdateUsrVerity, apiupdate.UpdateFileTypeUpdateUsrVeritySignature}, apiupdate.UpdateFileType(file.Type)) {
+		if file.Component != string(apiupdate.UpdateFileComponentOS) || !slices.Contains(osUpdateFileTypes, apiupdate.UpdateFileType(file.Type)) {
 			continue
 		}
 
 		targetName := strings.TrimSuffix(filepath.Base(file.Filename), ".gz")
 
 		// Download the application.
-		err = downloadAsset(ctx, o.provider.client, file.url, file.Sha256, filepath.Join(targetPath, targetName), progressFunc)
+		err = downloadAsset(ctx, o.provider.client, file.url, file.Sha256, filepath.Join(targetPath, targetName), fileProgressFunc(progressFunc, targetName, file.Size))
 		if err != nil {
 			return fmt.Errorf("while downloading %s, got error '%s'", file.url, err.Error())
 		}
@@ -691,3 +849,44 @@ func (o *operationsCenterSecureBootCertUpdate) Download(ctx context.Context, tar
 
 	return nil
 }
+
+// Secure Boot dbx revocation list updates from the Operations Center provider.
+type operationsCenterSecureBootDbxUpdate struct {
+	provider *operationsCenter
+
+	latestUpdate *operationsCenterUpdate
+}
+
+func (o *operationsCenterSecureBootDbxUpdate) Version() string {
+	return o.latestUpdate.Version
+}
+
+func (o *operationsCenterSecureBootDbxUpdate) GetFilename() string {
+	return "SecureBootDbx_" + o.latestUpdate.Version + ".auth"
+}
+
+func (o *operationsCenterSecureBootDbxUpdate) IsNewerThan(otherVersion string) bool {
+	return datetimeComparison(o.latestUpdate.Version, otherVersion)
+}
+
+func (o *operationsCenterSecureBootDbxUpdate) Download(ctx context.Context, targetPath string) error {
+	// Create the target path.
+	err := os.MkdirAll(targetPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range o.latestUpdate.Files {
+		// Only select the dbx update.
+		if file.Type != string(apiupdate.UpdateFileTypeUpdateSecurebootDbx) {
+			continue
+		}
+
+		err = downloadAsset(ctx, o.provider.client, file.url, file.Sha256, filepath.Join(targetPath, o.GetFilename()), nil)
+		if err != nil {
+			return fmt.Errorf("while downloading %s, got error '%s'", file.url, err.Error())
+		}
+	}
+
+	return nil
+}
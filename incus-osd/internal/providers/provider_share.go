@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// shareMountPath is the location under which network shares get mounted.
+var shareMountPath = "/run/incus-os/shares/"
+
+// The network share provider, serving the same layout as the Local provider from an NFS or SMB share.
+type share struct {
+	local
+
+	mountPath string
+}
+
+func (*share) Type() string {
+	return "share"
+}
+
+func (p *share) Deregister(ctx context.Context) error {
+	// Unmount the share, if mounted.
+	if !p.isMounted(ctx) {
+		return nil
+	}
+
+	_, err := subprocess.RunCommandContext(ctx, "umount", p.mountPath)
+
+	return err
+}
+
+func (p *share) load(ctx context.Context) error {
+	// Set up the configuration.
+	source := p.config["source"]
+	if source == "" {
+		return errors.New("no share source provided")
+	}
+
+	var fsType string
+
+	switch p.config["type"] {
+	case "nfs":
+		fsType = "nfs"
+	case "smb":
+		fsType = "cifs"
+	default:
+		return fmt.Errorf("unsupported share type %q", p.config["type"])
+	}
+
+	// Use a distinct mount path for each share, so configuration changes don't get a stale mount.
+	sourceHash := sha256.Sum256([]byte(p.config["type"] + ":" + source))
+	p.mountPath = filepath.Join(shareMountPath, hex.EncodeToString(sourceHash[:])[:12])
+	p.path = filepath.Join(p.mountPath, p.config["path"])
+
+	// Report the files as coming from the share rather than from its mount path.
+	p.origin = strings.TrimSuffix(source, "/")
+	if strings.Trim(p.config["path"], "/") != "" {
+		p.origin += "/" + strings.Trim(p.config["path"], "/")
+	}
+
+	// Check if the share is already mounted.
+	if p.isMounted(ctx) {
+		return nil
+	}
+
+	// Mount the share read-only.
+	err := os.MkdirAll(p.mountPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	options := "ro"
+	if p.config["options"] != "" {
+		options += "," + p.config["options"]
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "mount", "-t", fsType, "-o", options, source, p.mountPath)
+	if err != nil {
+		return fmt.Errorf("failed to mount share %q: %w", source, err)
+	}
+
+	return nil
+}
+
+// isMounted checks whether the share is currently mounted.
+func (p *share) isMounted(ctx context.Context) bool {
+	_, err := subprocess.RunCommandContext(ctx, "mountpoint", "-q", p.mountPath)
+
+	return err == nil
+}
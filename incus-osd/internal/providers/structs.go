@@ -2,7 +2,11 @@ package providers
 
 import (
 	"context"
+	"slices"
 	"strconv"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiupdate "github.com/lxc/incus-os/incus-osd/api/images"
 )
 
 // LXCUpdateCA is used to verify updates.
@@ -20,25 +24,54 @@ dzfuFuN/tMIqY355bBYk3m6/UAIK5Pum/Q==
 -----END CERTIFICATE-----
 `
 
+// osUpdateFileTypes is the list of file types making up an OS update.
+var osUpdateFileTypes = []apiupdate.UpdateFileType{apiupdate.UpdateFileTypeUpdateEFI, apiupdate.UpdateFileTypeUpdateUsr, apiupdate.UpdateFileTypeUpdateUsrVerity, apiupdate.UpdateFileTypeUpdateUsrVeritySignature}
+
+// DownloadProgress represents the progress of downloading one of the files making up an update.
+type DownloadProgress struct {
+	File     string
+	Size     int64
+	Progress float64 // Between 0 and 1.
+}
+
+// fileProgressFunc returns a function reporting the download progress of a single file.
+func fileProgressFunc(progressFunc func(DownloadProgress), file string, size int64) func(float64) {
+	if progressFunc == nil {
+		return nil
+	}
+
+	return func(progress float64) {
+		progressFunc(DownloadProgress{File: file, Size: size, Progress: progress})
+	}
+}
+
 // Application represents an application to be installed on top of IncusOS.
 type Application interface {
 	Name() string
 	Version() string
 	IsNewerThan(otherVersion string) bool
+	Size() int64
+
+	GetProvenance(ctx context.Context) (api.SystemUpdateProvenance, error)
 
-	Download(ctx context.Context, targetPath string, progressFunc func(float64)) error
+	Download(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error
 }
 
 // OSUpdate represents a full OS update.
 type OSUpdate interface {
 	Version() string
 	IsNewerThan(otherVersion string) bool
+	Size() int64
 
-	DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(float64)) error
+	GetChecksums(ctx context.Context) (map[string]string, error)
+	GetProvenance(ctx context.Context) (api.SystemUpdateProvenance, error)
+
+	DownloadUpdate(ctx context.Context, targetPath string, progressFunc func(DownloadProgress)) error
 	DownloadImage(ctx context.Context, imageType string, targetPath string, progressFunc func(float64)) (string, error)
 }
 
-// SecureBootCertUpdate represents a Secure Boot UEFI certificate update (typically a db or dbx addition).
+// SecureBootCertUpdate represents a Secure Boot UEFI certificate update (typically a db or dbx addition)
+// or a dbx revocation list update.
 type SecureBootCertUpdate interface {
 	Version() string
 	IsNewerThan(otherVersion string) bool
@@ -55,6 +88,7 @@ type Provider interface {
 	Type() string
 
 	GetSecureBootCertUpdate(ctx context.Context) (SecureBootCertUpdate, error)
+	GetSecureBootDbxUpdate(ctx context.Context) (SecureBootCertUpdate, error)
 	GetOSUpdate(ctx context.Context) (OSUpdate, error)
 	GetApplication(ctx context.Context, name string) (Application, error)
 
@@ -65,6 +99,12 @@ type Provider interface {
 	load(ctx context.Context) error
 }
 
+// ServesCompressedFiles returns whether the provider's update files are gzip-compressed, their size then only
+// accounting for the compressed data. The local and share providers hold the files already decompressed.
+func ServesCompressedFiles(p Provider) bool {
+	return p.Type() != "local" && p.Type() != "share"
+}
+
 // datetimeComparison takes two strings of the format YYYYMMDDhhmm and returns a boolean
 // indicating if a > b. If either string can't be converted to an int, false is returned.
 func datetimeComparison(a string, b string) bool {
@@ -80,3 +120,16 @@ func datetimeComparison(a string, b string) bool {
 
 	return aInt > bInt
 }
+
+// matchesApplicationConfig returns whether an update matches the update channel or pinned version of an application.
+func matchesApplicationConfig(config api.ApplicationConfig, version string, channels []string) bool {
+	if config.UpdateVersion != "" {
+		return version == config.UpdateVersion
+	}
+
+	if config.UpdateChannel != "" {
+		return slices.Contains(channels, config.UpdateChannel)
+	}
+
+	return true
+}
@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// UpdateCA returns the PEM encoded bundle of certificates trusted to sign the update metadata. This is the
+// built-in LXC update CA, unless replaced by the trust anchors provided by the seed, along with those anchors.
+func UpdateCA(s *state.State) string {
+	anchors := []string{}
+
+	if !s.UpdateTrustAnchors.ReplaceBuiltin {
+		anchors = append(anchors, LXCUpdateCA)
+	}
+
+	for _, anchor := range s.UpdateTrustAnchors.Certificates {
+		anchors = append(anchors, strings.TrimSpace(anchor)+"\n")
+	}
+
+	return strings.Join(anchors, "")
+}
+
+// ValidateUpdateTrustAnchors checks that each of the provided trust anchors is a single PEM encoded certificate.
+func ValidateUpdateTrustAnchors(anchors []string) error {
+	for i, anchor := range anchors {
+		_, err := parseTrustAnchor(anchor)
+		if err != nil {
+			return fmt.Errorf("invalid update trust anchor %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ListUpdateTrustAnchors returns details about the certificates currently trusted to sign the update metadata.
+func ListUpdateTrustAnchors(s *state.State) []api.SystemSecurityTrustAnchor {
+	ret := []api.SystemSecurityTrustAnchor{}
+
+	addAnchor := func(source string, anchor string) {
+		cert, err := parseTrustAnchor(anchor)
+		if err != nil {
+			return
+		}
+
+		rawFp := sha256.Sum256(cert.Raw)
+		ret = append(ret, api.SystemSecurityTrustAnchor{
+			Source:      source,
+			Fingerprint: hex.EncodeToString(rawFp[:]),
+			Subject:     cert.Subject.String(),
+			Issuer:      cert.Issuer.String(),
+		})
+	}
+
+	// A custom image server comes with its own CA.
+	if s.System.Provider.Config.Name == "images" && s.System.Provider.Config.Config["server_url"] != "" && s.System.Provider.Config.Config["update_ca"] != "" {
+		addAnchor("provider", s.System.Provider.Config.Config["update_ca"])
+
+		return ret
+	}
+
+	if !s.UpdateTrustAnchors.ReplaceBuiltin {
+		addAnchor("builtin", LXCUpdateCA)
+	}
+
+	for _, anchor := range s.UpdateTrustAnchors.Certificates {
+		addAnchor("seed", anchor)
+	}
+
+	return ret
+}
+
+// parseTrustAnchor parses a single PEM encoded certificate.
+func parseTrustAnchor(anchor string) (*x509.Certificate, error) {
+	block, rest := pem.Decode([]byte(anchor))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("not a PEM encoded certificate")
+	}
+
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, errors.New("only a single certificate may be provided")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// Test selection of the certificates trusted to sign the update metadata.
+func TestUpdateTrustAnchors(t *testing.T) {
+	t.Parallel()
+
+	// Validation.
+	require.NoError(t, ValidateUpdateTrustAnchors([]string{LXCUpdateCA}))
+	require.Error(t, ValidateUpdateTrustAnchors([]string{"not a certificate"}))
+	require.Error(t, ValidateUpdateTrustAnchors([]string{LXCUpdateCA + LXCUpdateCA}))
+
+	// Built-in CA only.
+	s := &state.State{}
+
+	require.Equal(t, LXCUpdateCA, UpdateCA(s))
+
+	anchors := ListUpdateTrustAnchors(s)
+	require.Len(t, anchors, 1)
+	require.Equal(t, "builtin", anchors[0].Source)
+	require.Equal(t, "CN=Incus OS - Root E1,O=Linux Containers", anchors[0].Subject)
+
+	// Additional anchor from the seed.
+	s.UpdateTrustAnchors.Certificates = []string{LXCUpdateCA}
+
+	require.Equal(t, LXCUpdateCA+LXCUpdateCA, UpdateCA(s))
+	require.Len(t, ListUpdateTrustAnchors(s), 2)
+
+	// Replaced built-in CA.
+	s.UpdateTrustAnchors.ReplaceBuiltin = true
+
+	require.Equal(t, LXCUpdateCA, UpdateCA(s))
+
+	anchors = ListUpdateTrustAnchors(s)
+	require.Len(t, anchors, 1)
+	require.Equal(t, "seed", anchors[0].Source)
+}
@@ -1,18 +1,88 @@
 package providers
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+	incustls "github.com/lxc/incus/v6/shared/tls"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
+// bearerTokenTransport is an http.RoundTripper which adds a bearer token to the requests sent to the
+// given host. Requests to any other host, such as when following a redirect, are sent without it.
+type bearerTokenTransport struct {
+	token string
+	host  string
+	base  http.RoundTripper
+}
+
+func (t *bearerTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(req.URL.Host, t.host) {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+
+	return t.base.RoundTrip(req)
+}
+
+// cancelOnCloseBody cancels the context of a request once its response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+
+	return b.ReadCloser.Close()
+}
+
+// idleWatchdogReader resets an idle watchdog each time data is received.
+type idleWatchdogReader struct {
+	io.Reader
+
+	watchdog *time.Timer
+	timeout  time.Duration
+}
+
+func (r *idleWatchdogReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.watchdog.Reset(r.timeout)
+	}
+
+	return n, err
+}
+
 func downloadAsset(ctx context.Context, client *http.Client, assetURL string, expectedSHA256 string, target string, progressFunc func(float64)) error {
+	// Abort the download if no data is received for too long.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	idleTimeout := timeout.Get(timeout.DownloadIdle)
+
+	watchdog := time.AfterFunc(idleTimeout, cancel)
+	defer watchdog.Stop()
+
 	// Prepare the request.
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
 	if err != nil {
@@ -30,8 +100,8 @@ func downloadAsset(ctx context.Context, client *http.Client, assetURL string, ex
 	// Setup a sha256 hasher.
 	h := sha256.New()
 
-	// Setup the main reader.
-	tr := io.TeeReader(resp.Body, h)
+	// Setup the main reader, keeping the download alive for as long as data is received.
+	tr := io.TeeReader(&idleWatchdogReader{Reader: resp.Body, watchdog: watchdog, timeout: idleTimeout}, h)
 
 	// Setup a gzip reader to decompress during streaming.
 	body, err := gzip.NewReader(tr)
@@ -79,20 +149,197 @@ func downloadAsset(ctx context.Context, client *http.Client, assetURL string, ex
 	return nil
 }
 
-// tryRequest attempts the request multiple times over 5s.
+// maxRetryDelay is the longest delay tryRequest will wait for before retrying a rate limited request.
+const maxRetryDelay = 30 * time.Second
+
+// tryRequest attempts the request up to five times, backing off exponentially between attempts.
+// Rate limited requests are retried once the server allows it, unless that's more than
+// maxRetryDelay away, in which case a RateLimitError is returned.
 func tryRequest(client *http.Client, req *http.Request) (*http.Response, error) {
 	var err error
 
+	delay := time.Second
+
 	for range 5 {
 		var resp *http.Response
 
-		resp, err = client.Do(req)
+		// Bound each attempt, until the caller is done with the response body.
+		ctx, cancel := timeout.WithTimeout(req.Context(), timeout.ProviderRequest)
+
+		resp, err = client.Do(req.WithContext(ctx))
 		if err == nil {
-			return resp, nil
+			retryAt, isRateLimited := rateLimitReset(resp)
+			if !isRateLimited {
+				resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+
+				return resp, nil
+			}
+
+			_ = resp.Body.Close()
+			cancel()
+
+			err = &RateLimitError{RetryAt: retryAt}
+
+			if time.Until(retryAt) > maxRetryDelay {
+				return nil, err
+			}
+
+			delay = max(delay, time.Until(retryAt))
+		} else {
+			cancel()
+		}
+
+		// Rewind the request body, if any.
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, err
+			}
+
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+
+			req.Body = body
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
 		}
 
-		time.Sleep(time.Second)
+		delay *= 2
+	}
+
+	return nil, fmt.Errorf("http request failed after five attempts: %w", err)
+}
+
+// rateLimitReset checks if the response indicates the server is rate limiting requests and if so,
+// returns the time at which requests will be allowed again.
+func rateLimitReset(resp *http.Response) (time.Time, bool) {
+	retryAfter := resp.Header.Get("Retry-After")
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+	case http.StatusServiceUnavailable:
+		if retryAfter == "" {
+			return time.Time{}, false
+		}
+
+	case http.StatusForbidden:
+		// Some servers (GitHub for one) report rate limits with a 403 error.
+		if retryAfter == "" && resp.Header.Get("X-RateLimit-Remaining") != "0" {
+			return time.Time{}, false
+		}
+
+	default:
+		return time.Time{}, false
+	}
+
+	// Retry-After is either a number of seconds or an HTTP date.
+	if retryAfter != "" {
+		seconds, err := strconv.Atoi(retryAfter)
+		if err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second), true
+		}
+
+		retryAt, err := http.ParseTime(retryAfter)
+		if err == nil {
+			return retryAt, true
+		}
+	}
+
+	// X-RateLimit-Reset is a UNIX timestamp.
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err == nil {
+		return time.Unix(reset, 0), true
+	}
+
+	// If the server didn't say, wait a minute.
+	return time.Now().Add(time.Minute), true
+}
+
+// verifySignedJSON validates a signed JSON document against the provided CA and decodes it into target.
+// The fingerprint of the certificate which signed the document is returned.
+func verifySignedJSON(ctx context.Context, r io.Reader, ca string, target any) (string, error) {
+	// Write the CA certificate.
+	rootCA, err := os.CreateTemp("", "")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = os.Remove(rootCA.Name()) }()
+
+	_, err = fmt.Fprintf(rootCA, "%s", ca)
+	if err != nil {
+		return "", err
+	}
+
+	// Get a path for the signer certificate.
+	signer, err := os.CreateTemp("", "")
+	if err != nil {
+		return "", err
+	}
+
+	_ = signer.Close()
+
+	defer func() { _ = os.Remove(signer.Name()) }()
+
+	// Validate the signature.
+	verified := bytes.NewBuffer(nil)
+
+	err = subprocess.RunCommandWithFds(ctx, r, verified, "openssl", "smime", "-verify", "-text", "-CAfile", rootCA.Name(), "-signer", signer.Name())
+	if err != nil {
+		return "", err
+	}
+
+	// Parse the content.
+	err = json.NewDecoder(verified).Decode(target)
+	if err != nil {
+		return "", err
+	}
+
+	return signerFingerprint(signer.Name()), nil
+}
+
+// signerFingerprint returns the fingerprint of the first certificate in a PEM file, or an empty string if none.
+func signerFingerprint(path string) string {
+	// #nosec G304
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return ""
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return ""
+	}
+
+	return incustls.CertFingerprint(cert)
+}
+
+// fileSHA256 returns the SHA256 checksum of the provided file.
+func fileSHA256(path string) (string, error) {
+	// #nosec G304
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
 	}
 
-	return nil, errors.New("http request timed out after five seconds: %s" + err.Error())
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
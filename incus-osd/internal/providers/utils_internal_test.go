@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+func TestRateLimitReset(t *testing.T) {
+	t.Parallel()
+
+	// Not rate limited.
+	_, isRateLimited := rateLimitReset(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	require.False(t, isRateLimited)
+
+	_, isRateLimited = rateLimitReset(&http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}})
+	require.False(t, isRateLimited)
+
+	_, isRateLimited = rateLimitReset(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}})
+	require.False(t, isRateLimited)
+
+	// Retry-After in seconds.
+	retryAt, isRateLimited := rateLimitReset(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"120"}}})
+	require.True(t, isRateLimited)
+	require.WithinDuration(t, time.Now().Add(2*time.Minute), retryAt, 5*time.Second)
+
+	// Retry-After as a date.
+	reset := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	retryAt, isRateLimited = rateLimitReset(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": []string{reset.Format(http.TimeFormat)}}})
+	require.True(t, isRateLimited)
+	require.True(t, reset.Equal(retryAt))
+
+	// Exhausted rate limit.
+	retryAt, isRateLimited = rateLimitReset(&http.Response{StatusCode: http.StatusForbidden, Header: http.Header{"X-Ratelimit-Remaining": []string{"0"}, "X-Ratelimit-Reset": []string{strconv.FormatInt(reset.Unix(), 10)}}})
+	require.True(t, isRateLimited)
+	require.True(t, reset.Equal(retryAt))
+}
+
+func TestBearerTokenTransport(t *testing.T) {
+	t.Parallel()
+
+	// The redirect target records any authorization it receives.
+	authorizations := make(chan string, 2)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations <- r.Header.Get("Authorization")
+		http.Redirect(w, r, target.URL+"/file", http.StatusFound)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &bearerTokenTransport{token: "secret", host: serverURL.Host, base: http.DefaultTransport}}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	// The token is only sent to the configured host.
+	require.Equal(t, "Bearer secret", <-authorizations)
+	require.Empty(t, <-authorizations)
+}
+
+func TestDownloadAssetIdleTimeout(t *testing.T) { //nolint:paralleltest
+	idleTimeout := timeout.Durations[timeout.DownloadIdle]
+	timeout.Durations[timeout.DownloadIdle] = 200 * time.Millisecond
+
+	t.Cleanup(func() { timeout.Durations[timeout.DownloadIdle] = idleTimeout })
+
+	content := &bytes.Buffer{}
+	gz := gzip.NewWriter(content)
+	_, err := gz.Write(bytes.Repeat([]byte("incus-os"), 1024))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	data := content.Bytes()
+	checksum := sha256.Sum256(data)
+
+	// Send the file slowly, taking longer than the idle timeout overall but never pausing for that long.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		for chunk := range slices.Chunk(data, len(data)/10+1) {
+			_, _ = w.Write(chunk)
+			w.(http.Flusher).Flush()
+
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "file")
+
+	err = downloadAsset(t.Context(), http.DefaultClient, server.URL, hex.EncodeToString(checksum[:]), target, nil)
+	require.NoError(t, err)
+
+	written, err := os.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, bytes.Repeat([]byte("incus-os"), 1024), written)
+
+	// A stalled download is aborted.
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data[:len(data)/2])
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+	}))
+	defer stalled.Close()
+
+	err = downloadAsset(t.Context(), http.DefaultClient, stalled.URL, hex.EncodeToString(checksum[:]), target, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// ErrVerificationFailed is returned when an update can't be confirmed by the verification provider.
+var ErrVerificationFailed = errors.New("update verification failed")
+
+// SupportsVerification returns whether the provider can take part in dual-provider verification. The local and
+// share providers only hold the uncompressed update files, so their checksums can never match those published
+// by the other providers for the compressed files.
+func SupportsVerification(name string) bool {
+	return name != "local" && name != "share"
+}
+
+// VerifyOSUpdate checks that the provided OS update is also published, with identical content,
+// by an independent provider defined by the given configuration.
+func VerifyOSUpdate(ctx context.Context, s *state.State, update OSUpdate, config api.SystemProviderConfig) error {
+	if !SupportsVerification(s.System.Provider.Config.Name) || !SupportsVerification(config.Name) {
+		return fmt.Errorf("%w: the local and share providers can't be used for verification", ErrVerificationFailed)
+	}
+
+	// Load the verification provider.
+	p, err := LoadFromConfig(ctx, s, config)
+	if err != nil {
+		return err
+	}
+
+	// Get the update as seen by the verification provider.
+	otherUpdate, err := p.GetOSUpdate(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoUpdateAvailable) {
+			return fmt.Errorf("%w: no update available from the %q provider", ErrVerificationFailed, p.Type())
+		}
+
+		return err
+	}
+
+	if otherUpdate.Version() != update.Version() {
+		return fmt.Errorf("%w: the %q provider offers version %s rather than %s", ErrVerificationFailed, p.Type(), otherUpdate.Version(), update.Version())
+	}
+
+	// Compare the file checksums.
+	checksums, err := update.GetChecksums(ctx)
+	if err != nil {
+		return err
+	}
+
+	otherChecksums, err := otherUpdate.GetChecksums(ctx)
+	if err != nil {
+		return err
+	}
+
+	return compareChecksums(checksums, otherChecksums)
+}
+
+// compareChecksums checks that both lists of files are identical.
+func compareChecksums(checksums map[string]string, otherChecksums map[string]string) error {
+	if len(checksums) == 0 {
+		return fmt.Errorf("%w: no files to compare", ErrVerificationFailed)
+	}
+
+	for _, filename := range slices.Sorted(maps.Keys(checksums)) {
+		otherChecksum, ok := otherChecksums[filename]
+		if !ok {
+			return fmt.Errorf("%w: file %q is missing from the verification provider", ErrVerificationFailed, filename)
+		}
+
+		if otherChecksum != checksums[filename] {
+			return fmt.Errorf("%w: checksum mismatch for file %q", ErrVerificationFailed, filename)
+		}
+	}
+
+	for filename := range otherChecksums {
+		_, ok := checksums[filename]
+		if !ok {
+			return fmt.Errorf("%w: unexpected file %q from the verification provider", ErrVerificationFailed, filename)
+		}
+	}
+
+	return nil
+}
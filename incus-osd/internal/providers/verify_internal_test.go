@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// Test comparison of update checksums between two providers.
+func TestCompareChecksums(t *testing.T) {
+	t.Parallel()
+
+	checksums := map[string]string{
+		"IncusOS_202511041601.efi.gz":            "a9c4e5",
+		"IncusOS_202511041601.usr-x86-64.raw.gz": "72b1f0",
+	}
+
+	// Identical lists.
+	require.NoError(t, compareChecksums(checksums, map[string]string{
+		"IncusOS_202511041601.efi.gz":            "a9c4e5",
+		"IncusOS_202511041601.usr-x86-64.raw.gz": "72b1f0",
+	}))
+
+	// Checksum mismatch.
+	require.ErrorIs(t, compareChecksums(checksums, map[string]string{
+		"IncusOS_202511041601.efi.gz":            "a9c4e5",
+		"IncusOS_202511041601.usr-x86-64.raw.gz": "000000",
+	}), ErrVerificationFailed)
+
+	// Missing file.
+	require.ErrorIs(t, compareChecksums(checksums, map[string]string{
+		"IncusOS_202511041601.efi.gz": "a9c4e5",
+	}), ErrVerificationFailed)
+
+	// Extra file.
+	require.ErrorIs(t, compareChecksums(checksums, map[string]string{
+		"IncusOS_202511041601.efi.gz":            "a9c4e5",
+		"IncusOS_202511041601.usr-x86-64.raw.gz": "72b1f0",
+		"IncusOS_202511041601.usr-x86-64.verity": "1c3d5e",
+	}), ErrVerificationFailed)
+
+	// Nothing to compare.
+	require.ErrorIs(t, compareChecksums(map[string]string{}, map[string]string{}), ErrVerificationFailed)
+}
+
+// Test that the providers which can't be compared with others are rejected.
+func TestVerifyOSUpdateUnsupported(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+	s.System.Provider.Config.Name = "local"
+
+	err := VerifyOSUpdate(t.Context(), s, nil, api.SystemProviderConfig{Name: "images"})
+	require.ErrorIs(t, err, ErrVerificationFailed)
+
+	s.System.Provider.Config.Name = "images"
+
+	err = VerifyOSUpdate(t.Context(), s, nil, api.SystemProviderConfig{Name: "share"})
+	require.ErrorIs(t, err, ErrVerificationFailed)
+}
+
+// Test that the images provider only uses the release cache when it's the system's provider.
+func TestImagesCacheVerification(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+	s.ProviderCache = state.ProviderCache{Source: imagesDefaultServerURL, Release: `{"version": "202511041601"}`}
+
+	// Used as a verification provider.
+	s.System.Provider.Config.Name = "operations-center"
+
+	p := &images{state: s, config: map[string]string{}}
+	require.NoError(t, p.load(t.Context()))
+	require.Nil(t, p.latestUpdate)
+
+	// Used as the system's provider.
+	s.System.Provider.Config.Name = "images"
+
+	p = &images{state: s, config: map[string]string{}}
+	require.NoError(t, p.load(t.Context()))
+	require.NotNil(t, p.latestUpdate)
+	require.Equal(t, "202511041601", p.latestUpdate.Version)
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// maxLogEntries is the number of recent log entries returned by GetLog.
+const maxLogEntries = 100
+
+// logRequestRegex matches the request a kpx log message relates to.
+var logRequestRegex = regexp.MustCompile(`\b(CONNECT|DELETE|GET|HEAD|OPTIONS|PATCH|POST|PUT)\s+(\S+)`)
+
+// logFailureRegex matches kpx log messages reporting a failure, including error status codes returned by the upstream proxy.
+var logFailureRegex = regexp.MustCompile(`(?i)\b(error|errors|fail|failed|failure|refused|denied|timeout|timed out|unreachable|forbidden|unauthorized)\b|\b(407|403|502|503|504)\b`)
+
+// GetLog returns the recent entries of the local proxy's log along with per-destination request and
+// failure counters, both parsed from the kpx journal since the system booted.
+func GetLog(ctx context.Context) (api.SystemNetworkProxyLog, error) {
+	ret := api.SystemNetworkProxyLog{
+		Entries:      []api.SystemNetworkProxyLogEntry{},
+		Destinations: []api.SystemNetworkProxyDestination{},
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "journalctl", "-u", "kpx.service", "-b", "0", "-o", "json", "--no-pager")
+	if err != nil {
+		return ret, err
+	}
+
+	destinations := map[string]*api.SystemNetworkProxyDestination{}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry, ok := parseJournalEntry(line)
+		if !ok {
+			continue
+		}
+
+		ret.Entries = append(ret.Entries, entry)
+
+		if entry.Destination == "" {
+			continue
+		}
+
+		destination, ok := destinations[entry.Destination]
+		if !ok {
+			destination = &api.SystemNetworkProxyDestination{Destination: entry.Destination}
+			destinations[entry.Destination] = destination
+		}
+
+		destination.Requests++
+
+		if entry.Failed {
+			destination.Failures++
+			destination.LastFailure = &entry.Time
+			destination.LastError = entry.Message
+		}
+	}
+
+	// Only return the most recent entries.
+	if len(ret.Entries) > maxLogEntries {
+		ret.Entries = ret.Entries[len(ret.Entries)-maxLogEntries:]
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(destinations)) {
+		ret.Destinations = append(ret.Destinations, *destinations[name])
+	}
+
+	return ret, nil
+}
+
+// parseJournalEntry parses a single JSON journal entry from kpx.
+func parseJournalEntry(line string) (api.SystemNetworkProxyLogEntry, bool) {
+	var fields struct {
+		Timestamp string          `json:"__REALTIME_TIMESTAMP"`
+		Message   json.RawMessage `json:"MESSAGE"`
+	}
+
+	err := json.Unmarshal([]byte(line), &fields)
+	if err != nil {
+		return api.SystemNetworkProxyLogEntry{}, false
+	}
+
+	// Messages which aren't valid UTF-8 are provided as an array of bytes.
+	var message string
+
+	err = json.Unmarshal(fields.Message, &message)
+	if err != nil {
+		var raw []byte
+
+		err = json.Unmarshal(fields.Message, &raw)
+		if err != nil {
+			return api.SystemNetworkProxyLogEntry{}, false
+		}
+
+		message = string(raw)
+	}
+
+	entry := ParseLogMessage(message)
+
+	usec, err := strconv.ParseInt(fields.Timestamp, 10, 64)
+	if err == nil {
+		entry.Time = time.UnixMicro(usec).UTC()
+	}
+
+	return entry, true
+}
+
+// ParseLogMessage extracts the request and failure details from a kpx log message.
+func ParseLogMessage(message string) api.SystemNetworkProxyLogEntry {
+	entry := api.SystemNetworkProxyLogEntry{
+		Message: strings.TrimSpace(message),
+		Failed:  logFailureRegex.MatchString(message),
+	}
+
+	match := logRequestRegex.FindStringSubmatch(message)
+	if match != nil {
+		entry.Method = match[1]
+		entry.Destination = logDestination(match[1], match[2])
+	}
+
+	return entry
+}
+
+// logDestination returns the host and port targeted by a request.
+func logDestination(method string, target string) string {
+	// CONNECT requests target a host and port directly.
+	if method == "CONNECT" {
+		return target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return target
+	}
+
+	if u.Port() != "" {
+		return u.Host
+	}
+
+	if u.Scheme == "https" {
+		return u.Host + ":443"
+	}
+
+	return u.Host + ":80"
+}
@@ -1,20 +1,25 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"maps"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 	"gopkg.in/yaml.v3"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 )
 
 type kpxConfig struct {
@@ -47,6 +52,34 @@ type kpxRule struct {
 	Proxy string `yaml:"proxy"`
 }
 
+// localProxyURL is the address of the local kpx proxy.
+var localProxyURL = &url.URL{Scheme: "http", Host: "localhost:3128"}
+
+// localProxyEnabled tracks whether the local kpx proxy is currently in use.
+var localProxyEnabled atomic.Bool
+
+// HTTPProxy is an http.Transport proxy function sending requests through the local kpx proxy when
+// one is configured. Unlike http.ProxyFromEnvironment, it follows configuration changes made at runtime.
+// Proxy exceptions are handled by kpx itself through direct rules.
+func HTTPProxy(req *http.Request) (*url.URL, error) {
+	if !localProxyEnabled.Load() {
+		return nil, nil //nolint:nilnil
+	}
+
+	// Never proxy local requests.
+	host := req.URL.Hostname()
+	if host == "localhost" {
+		return nil, nil //nolint:nilnil
+	}
+
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return nil, nil //nolint:nilnil
+	}
+
+	return localProxyURL, nil
+}
+
 // StartLocalProxy starts a local kpx proxy with a configuration based off of the
 // contents from the provided SystemNetworkProxy struct.
 func StartLocalProxy(ctx context.Context, proxyConfig *api.SystemNetworkProxy) error {
@@ -55,9 +88,12 @@ func StartLocalProxy(ctx context.Context, proxyConfig *api.SystemNetworkProxy) e
 	if proxyConfig == nil {
 		_ = os.Unsetenv("http_proxy")
 		_ = os.Unsetenv("https_proxy")
+		_ = os.Unsetenv("no_proxy")
 		_ = os.Remove("/etc/environment")
 		_, _ = subprocess.RunCommandContext(ctx, "systemctl", "stop", "kpx.service")
 
+		localProxyEnabled.Store(false)
+
 		return nil
 	}
 
@@ -75,23 +111,46 @@ func StartLocalProxy(ctx context.Context, proxyConfig *api.SystemNetworkProxy) e
 		}
 	}
 
+	// Set the no_proxy environment variable, so clients bypass the local proxy entirely.
+	if len(proxyConfig.NoProxy) > 0 {
+		err = writeAndSetEnvironment("no_proxy", strings.Join(proxyConfig.NoProxy, ","))
+		if err != nil {
+			return err
+		}
+	} else {
+		_ = os.Unsetenv("no_proxy")
+	}
+
 	// Generate the kpx config.
 	yamlConfig, err := GenerateKPXConfig(proxyConfig)
 	if err != nil {
 		return err
 	}
 
+	// Only restart kpx if its configuration changed, to avoid needlessly dropping connections.
+	action := "start"
+
+	oldConfig, err := os.ReadFile("/etc/kpx.yaml")
+	if err != nil || !bytes.Equal(oldConfig, yamlConfig) {
+		action = "restart"
+	}
+
 	// Write file to /etc/kpx.yaml
 	err = os.WriteFile("/etc/kpx.yaml", yamlConfig, 0o644)
 	if err != nil {
 		return err
 	}
 
-	// Start the kpx daemon; can't use the helper method from the systemd package,
+	// (Re)start the kpx daemon; can't use the helper method from the systemd package,
 	// since that causes an import loop.
-	_, err = subprocess.RunCommandContext(ctx, "systemctl", "start", "kpx.service")
+	_, err = subprocess.RunCommandContext(ctx, "systemctl", action, "kpx.service")
+	if err != nil {
+		return err
+	}
 
-	return err
+	localProxyEnabled.Store(true)
+
+	return nil
 }
 
 // GenerateKPXConfig takes a network config struct and generates the kpx yaml configuration.
@@ -187,15 +246,28 @@ func GenerateKPXConfig(proxyConfig *api.SystemNetworkProxy) ([]byte, error) {
 		if server.Auth != "anonymous" {
 			proxy.Credential = credential
 
+			password, err := secrets.Open(server.Password)
+			if err != nil {
+				return nil, err
+			}
+
 			cfg.Credentials[serverKey] = kpxCredential{
 				Login:    server.Username,
-				Password: server.Password,
+				Password: password,
 			}
 		}
 
 		cfg.Proxies[serverKey] = proxy
 	}
 
+	// Generate direct rules for proxy exceptions, ahead of any other rule.
+	for _, noProxy := range proxyConfig.NoProxy {
+		cfg.Rules = append(cfg.Rules, kpxRule{
+			Host:  noProxyToKPXHost(noProxy),
+			Proxy: "direct",
+		})
+	}
+
 	// Generate proxy rules.
 	for _, rule := range proxyConfig.Rules {
 		_, targetExists := cfg.Proxies[rule.Target]
@@ -212,6 +284,24 @@ func GenerateKPXConfig(proxyConfig *api.SystemNetworkProxy) ([]byte, error) {
 	return yaml.Marshal(cfg)
 }
 
+// noProxyToKPXHost converts a no_proxy style entry into a kpx host pattern. As with no_proxy,
+// a domain name also matches all of its subdomains.
+func noProxyToKPXHost(noProxy string) string {
+	noProxy = strings.TrimSpace(noProxy)
+
+	// ".example.org" only matches subdomains.
+	if strings.HasPrefix(noProxy, ".") {
+		return "*" + noProxy
+	}
+
+	// Leave wildcards, addresses and subnets as-is.
+	if strings.Contains(noProxy, "*") || net.ParseIP(noProxy) != nil || strings.Contains(noProxy, "/") {
+		return noProxy
+	}
+
+	return noProxy + "|*." + noProxy
+}
+
 func writeAndSetEnvironment(key string, value string) error {
 	envFile, err := os.OpenFile("/etc/environment", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o0644) //nolint:gosec
 	if err != nil {
@@ -59,6 +59,46 @@ rules:
 	require.YAMLEq(t, string(content), yamlConfig)
 }
 
+func TestNoProxyConfigGeneration(t *testing.T) {
+	t.Parallel()
+
+	networkConfig := api.SystemNetworkProxy{
+		Servers: map[string]api.SystemNetworkProxyServer{
+			"example": {
+				Host: "proxy.example.org",
+				Auth: "anonymous",
+			},
+		},
+		NoProxy: []string{"registry.example.net", ".corp.example.net", "*.internal", "10.0.0.0/8"},
+	}
+
+	yamlConfig := `bind: localhost
+port: 3128
+check: false
+proxies:
+    example:
+        host: proxy.example.org
+        ssl: false
+        type: anonymous
+rules:
+    - host: registry.example.net|*.registry.example.net
+      proxy: direct
+    - host: '*.corp.example.net'
+      proxy: direct
+    - host: '*.internal'
+      proxy: direct
+    - host: 10.0.0.0/8
+      proxy: direct
+    - host: '*'
+      proxy: example
+`
+
+	content, err := proxy.GenerateKPXConfig(&networkConfig)
+
+	require.NoError(t, err)
+	require.YAMLEq(t, string(content), yamlConfig)
+}
+
 func TestConfigGeneration(t *testing.T) {
 	t.Parallel()
 
@@ -224,3 +264,25 @@ func TestConfigGenerationErrors(t *testing.T) {
 	_, err = proxy.GenerateKPXConfig(&networkConfig)
 	require.EqualError(t, err, "no proxy defined for target myproxy")
 }
+
+func TestParseLogMessage(t *testing.T) {
+	t.Parallel()
+
+	entry := proxy.ParseLogMessage("[12] CONNECT images.linuxcontainers.org:443 via proxy corp")
+	require.Equal(t, "CONNECT", entry.Method)
+	require.Equal(t, "images.linuxcontainers.org:443", entry.Destination)
+	require.False(t, entry.Failed)
+
+	entry = proxy.ParseLogMessage("[13] CONNECT images.linuxcontainers.org:443 via proxy corp: 407 Proxy Authentication Required")
+	require.Equal(t, "images.linuxcontainers.org:443", entry.Destination)
+	require.True(t, entry.Failed)
+
+	entry = proxy.ParseLogMessage("[14] GET http://deb.example.com/dists/stable/Release direct")
+	require.Equal(t, "GET", entry.Method)
+	require.Equal(t, "deb.example.com:80", entry.Destination)
+	require.False(t, entry.Failed)
+
+	entry = proxy.ParseLogMessage("Error while connecting to proxy corp: connection refused")
+	require.Empty(t, entry.Destination)
+	require.True(t, entry.Failed)
+}
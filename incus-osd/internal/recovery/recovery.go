@@ -66,7 +66,7 @@ func CheckRunRecovery(ctx context.Context, s *state.State) error {
 	defer unix.Unmount(mountDir, 0)
 
 	// Run the hotfix script, if any.
-	err = runHotfix(ctx, mountDir)
+	err = runHotfix(ctx, s, mountDir)
 	if err != nil {
 		return err
 	}
@@ -88,7 +88,7 @@ func CheckRunRecovery(ctx context.Context, s *state.State) error {
 	return nil
 }
 
-func runHotfix(ctx context.Context, mountDir string) error {
+func runHotfix(ctx context.Context, s *state.State, mountDir string) error {
 	// Check if hotfix.sh.sig exists.
 	_, err := os.Stat(filepath.Join(mountDir, "hotfix.sh.sig"))
 	if err != nil {
@@ -105,7 +105,7 @@ func runHotfix(ctx context.Context, mountDir string) error {
 
 	defer os.Remove(rootCA.Name())
 
-	_, err = fmt.Fprintf(rootCA, "%s", providers.LXCUpdateCA)
+	_, err = fmt.Fprintf(rootCA, "%s", providers.UpdateCA(s))
 	if err != nil {
 		return err
 	}
@@ -174,7 +174,7 @@ func applyUpdate(ctx context.Context, s *state.State, mountDir string, installed
 
 	defer os.Remove(rootCA.Name())
 
-	_, err = fmt.Fprintf(rootCA, providers.LXCUpdateCA)
+	_, err = fmt.Fprintf(rootCA, "%s", providers.UpdateCA(s))
 	if err != nil {
 		return err
 	}
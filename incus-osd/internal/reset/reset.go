@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"time"
 
@@ -16,13 +17,15 @@ import (
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/install"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
-// PerformOSFactoryReset performs an OS-level factory reset.
+// PerformOSFactoryReset performs an OS-level factory reset. If a secure wipe was requested, a report of the
+// wipe of the encrypted volumes is returned.
 // !!! THIS WILL RESULT IN THE DESTRUCTION OF ALL DATA CREATED BY !!!
 // !!! IncusOS, ANY APPLICATIONS, AND ANY ZFS DATASETS CREATED IN !!!
 // !!! THE "local" POOL.                                          !!!
-func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) error {
+func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) (*api.SystemResetWipeReport, error) {
 	// systemd v258 introduced the factory-reset.target, which in
 	// theory should automate the following steps. However, trixie
 	// shipped with systemd v257. Potentially we could use a backported
@@ -38,7 +41,7 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 	// Get the underlying device.
 	underlyingDevice, err := storage.GetUnderlyingDevice()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Verify any provided seed data is valid json.
@@ -46,14 +49,14 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 
 	for seed, seedData := range resetSeed.Seeds {
 		if seedData == nil {
-			return errors.New("seed data for '" + seed + "' is not defined")
+			return nil, errors.New("seed data for '" + seed + "' is not defined")
 		}
 
 		tmp := empty{}
 
 		err := json.Unmarshal(seedData, &tmp)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -67,7 +70,7 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 		// Read any existing seed data and augment it with provided seed update(s), if any.
 		existingSeeds, err := getExistingSeeds(seedPartition)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		for seed, seedData := range resetSeed.Seeds {
@@ -91,7 +94,7 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 	// #nosec G304
 	f, err := os.Create(seedPartition)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
@@ -106,18 +109,18 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 
 		err := tw.WriteHeader(header)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		_, err = tw.Write(seedData)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	err = tw.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Beyond this point, we start making destructive changes to the system.
@@ -129,15 +132,28 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 		// Some systems return errors when trying to clear the TPM. As a workaround,
 		// allow the user to indicate we should accept this error and continue.
 		if !resetSeed.AllowTPMResetFailure {
-			return err
+			return nil, err
 		}
 	}
 
-	// Third, wipe system partitions (swap, root, and local-data).
+	// Third, cryptographically erase the encrypted volumes if requested.
+	var report *api.SystemResetWipeReport
+
+	if resetSeed.SecureWipe {
+		report, err = secureWipeVolumes(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		reportJSON, _ := json.Marshal(report)
+		slog.InfoContext(ctx, "Encrypted volumes securely wiped", "report", string(reportJSON))
+	}
+
+	// Fourth, wipe system partitions (swap, root, and local-data).
 	for _, partitionIndex := range []string{"9", "10", "11"} {
-		_, err := subprocess.RunCommandContext(ctx, "sgdisk", "-d", partitionIndex, underlyingDevice)
+		_, err := timeout.RunCommand(ctx, "sgdisk", "-d", partitionIndex, underlyingDevice)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -152,7 +168,7 @@ func PerformOSFactoryReset(ctx context.Context, resetSeed *api.SystemReset) erro
 		_ = os.WriteFile("/proc/sysrq-trigger", []byte("b"), 0o600)
 	}()
 
-	return nil
+	return report, nil
 }
 
 func getExistingSeeds(seedPartition string) (map[string][]byte, error) {
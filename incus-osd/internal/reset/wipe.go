@@ -0,0 +1,150 @@
+package reset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// defaultLUKSHeaderSize is the size of a LUKS2 header, as created by default.
+const defaultLUKSHeaderSize = 16 * 1024 * 1024
+
+// luksMetadata holds the parts of the LUKS2 JSON metadata needed to wipe a volume.
+type luksMetadata struct {
+	Keyslots map[string]any `json:"keyslots"`
+	Segments map[string]struct {
+		Offset string `json:"offset"`
+	} `json:"segments"`
+}
+
+// secureWipeVolumes cryptographically erases the encrypted volumes of the system drive by destroying all their
+// LUKS keyslots, then overwrites and discards their LUKS headers. As the encryption keys of the storage pools are
+// stored in the root volume, this also makes any encrypted storage pool unrecoverable.
+func secureWipeVolumes(ctx context.Context) (*api.SystemResetWipeReport, error) {
+	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	report := &api.SystemResetWipeReport{
+		Time:     time.Now().UTC(),
+		Hostname: hostname,
+		Volumes:  []api.SystemResetWipeVolume{},
+	}
+
+	for _, volumeName := range slices.Sorted(maps.Keys(luksVolumes)) {
+		volume, err := secureWipeVolume(ctx, volumeName, luksVolumes[volumeName])
+		if err != nil {
+			return nil, fmt.Errorf("failed to wipe encrypted volume %q: %w", volumeName, err)
+		}
+
+		report.Volumes = append(report.Volumes, volume)
+	}
+
+	return report, nil
+}
+
+// secureWipeVolume destroys all the keyslots and the header of a single LUKS volume.
+func secureWipeVolume(ctx context.Context, volumeName string, volumeDev string) (api.SystemResetWipeVolume, error) {
+	ret := api.SystemResetWipeVolume{
+		Name:   volumeName,
+		Device: volumeDev,
+	}
+
+	metadata, err := getLUKSMetadata(ctx, volumeDev)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Keyslots = len(metadata.Keyslots)
+	ret.HeaderSize = defaultLUKSHeaderSize
+
+	segment, ok := metadata.Segments["0"]
+	if ok {
+		offset, err := strconv.ParseInt(segment.Offset, 10, 64)
+		if err == nil && offset > 0 {
+			ret.HeaderSize = offset
+		}
+	}
+
+	// Destroy all the keyslots, making the volume's content unrecoverable.
+	_, err = timeout.RunCommand(ctx, "cryptsetup", "erase", "--batch-mode", volumeDev)
+	if err != nil {
+		return ret, err
+	}
+
+	// Check that no keyslot is left.
+	metadata, err = getLUKSMetadata(ctx, volumeDev)
+	if err != nil {
+		return ret, err
+	}
+
+	if len(metadata.Keyslots) > 0 {
+		return ret, fmt.Errorf("%d keyslots remain after erasing", len(metadata.Keyslots))
+	}
+
+	ret.KeyslotsVerified = true
+
+	// Overwrite the header itself.
+	err = zeroDevice(volumeDev, ret.HeaderSize)
+	if err != nil {
+		return ret, err
+	}
+
+	// Then discard it so no copy is kept by the underlying flash storage, if supported by the device.
+	_, err = timeout.RunCommand(ctx, "blkdiscard", "--force", "--offset", "0", "--length", strconv.FormatInt(ret.HeaderSize, 10), volumeDev)
+	ret.Discarded = err == nil
+
+	return ret, nil
+}
+
+// getLUKSMetadata returns the LUKS2 JSON metadata of a volume.
+func getLUKSMetadata(ctx context.Context, volumeDev string) (*luksMetadata, error) {
+	output, err := timeout.RunCommand(ctx, "cryptsetup", "luksDump", "--dump-json-metadata", volumeDev)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &luksMetadata{}
+
+	err = json.Unmarshal([]byte(output), metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// zeroDevice overwrites the start of a block device with zeros.
+func zeroDevice(device string, size int64) error {
+	// #nosec G304
+	f, err := os.OpenFile(device, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 1024*1024)
+
+	for written := int64(0); written < size; {
+		n, err := f.Write(buf[:min(int64(len(buf)), size-written)])
+		if err != nil {
+			return err
+		}
+
+		written += int64(n)
+	}
+
+	return f.Sync()
+}
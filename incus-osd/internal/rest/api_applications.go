@@ -1,17 +1,24 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
 
 // swagger:operation GET /1.0/applications applications applications_get
@@ -20,9 +27,19 @@ import (
 //
 //	Returns a list of currently installed applications (URLs).
 //
+//	With recursion, returns the name, state and configuration of each installed application instead,
+//	including whether it's currently running.
+//
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: recursion
+//	    description: Return the full application details
+//	    required: false
+//	    type: integer
+//	    example: 1
 //	responses:
 //	  "200":
 //	    description: API endpoints
@@ -67,10 +84,12 @@ import (
 //	    required: true
 //	    schema:
 //	      type: object
-//	      example: {"name": "incus"}
+//	      example: {"name": "incus", "config": {"update_channel": "lts"}}
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
 //	  "404":
 //	    $ref: "#/responses/NotFound"
 //	  "409":
@@ -91,6 +110,18 @@ func (s *Server) apiApplications(w http.ResponseWriter, r *http.Request) {
 
 		slices.Sort(names)
 
+		if r.FormValue("recursion") == "1" {
+			apps := make([]api.ApplicationListEntry, 0, len(names))
+
+			for _, name := range names {
+				apps = append(apps, api.ApplicationListEntry{Name: name, Application: s.getApplication(r.Context(), name)})
+			}
+
+			_ = response.SyncResponse(true, apps).Render(w)
+
+			return
+		}
+
 		endpoint, _ := url.JoinPath(getAPIRoot(r), "applications")
 
 		urls := []string{}
@@ -104,7 +135,8 @@ func (s *Server) apiApplications(w http.ResponseWriter, r *http.Request) {
 
 	case http.MethodPost:
 		type applicationPost struct {
-			Name string `json:"name"`
+			Name   string                `json:"name"`
+			Config api.ApplicationConfig `json:"config"`
 		}
 
 		app := &applicationPost{}
@@ -125,20 +157,14 @@ func (s *Server) apiApplications(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		_, exists := s.state.Applications[app.Name]
-		if exists {
-			_ = response.Conflict(nil).Render(w)
+		err = validateApplicationConfig(app.Config)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
 
 			return
 		}
 
-		// Add the application to the state.
-		s.state.Applications[app.Name] = api.Application{}
-
-		// Trigger a manual update check to install the new application.
-		s.state.TriggerUpdate <- true
-
-		_ = response.EmptySyncResponse.Render(w)
+		s.installApplication(w, r, app.Name, app.Config)
 	default:
 		_ = response.NotImplemented(nil).Render(w)
 
@@ -183,37 +209,206 @@ func (s *Server) apiApplications(w http.ResponseWriter, r *http.Request) {
 //	        metadata:
 //	          type: json
 //	          description: State and configuration for the application
-//	          example: {"state":{"initialized":true,"version":"202511041601"},"config":{}}
+//	          example: {"state":{"initialized":true,"version":"202511041601","running":true},"config":{"update_channel":"lts"}}
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+
+// swagger:operation PUT /1.0/applications/{name} applications applications_put_application
+//
+//	Update application configuration
+//
+//	Updates the configuration of the application, such as the update channel it tracks or the version it's held at.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	  - in: body
+//	    name: configuration
+//	    description: Application configuration
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The application configuration
+//	          example: {"update_channel":"lts"}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
 //	  "404":
 //	    $ref: "#/responses/NotFound"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 func (s *Server) apiApplicationsEndpoint(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if r.Method != http.MethodGet {
+	name := r.PathValue("name")
+
+	// Check if the application is valid.
+	appInfo, ok := s.state.Applications[name]
+	if !ok {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		_ = response.SyncResponseETag(true, s.getApplication(r.Context(), name), appInfo.Config).Render(w)
+	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, appInfo.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
+		newApp := &api.Application{}
+
+		err = json.NewDecoder(r.Body).Decode(newApp)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = validateApplicationConfig(newApp.Config)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Deliver changed settings to the application right away, if it's already running.
+		if appInfo.State.Initialized && !maps.Equal(appInfo.Config.Settings, newApp.Config.Settings) {
+			app, err := applications.Load(r.Context(), s.state, name)
+			if err != nil {
+				_ = response.InternalError(err).Render(w)
+
+				return
+			}
+
+			if app.IsRunning(r.Context()) {
+				err = app.ApplySettings(r.Context(), newApp.Config.Settings)
+				if err != nil {
+					_ = response.BadRequest(fmt.Errorf("failed to apply settings: %w", err)).Render(w)
+
+					return
+				}
+			}
+		}
+
+		// Apply the updated configuration.
+		appInfo.Config = newApp.Config
+		s.state.SetApplication(name, appInfo)
+
+		_ = s.state.Save()
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
 		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+// validateApplicationConfig checks the configuration of an application.
+func validateApplicationConfig(config api.ApplicationConfig) error {
+	if config.UpdateChannel != "" && config.UpdateVersion != "" {
+		return errors.New("an application can't both track an update channel and be held at a version")
+	}
+
+	return applications.ValidateHooks(config.Hooks)
+}
+
+// getApplication returns the state and configuration of an installed application, including whether it's running.
+func (s *Server) getApplication(ctx context.Context, name string) api.Application {
+	appInfo := s.state.Applications[name]
+
+	app, err := applications.Load(ctx, s.state, name)
+	if err == nil {
+		appInfo.State.Running = app.IsRunning(ctx)
+	}
+
+	appInfo.State.Operation = applications.GetOperation(name)
+
+	return appInfo
+}
+
+// installApplication adds a new application to the system, then triggers an update check to install it.
+func (s *Server) installApplication(w http.ResponseWriter, r *http.Request, name string, config api.ApplicationConfig) {
+	_, exists := s.state.Applications[name]
+	if exists {
+		_ = response.Conflict(nil).Render(w)
 
 		return
 	}
 
-	name := r.PathValue("name")
+	_, err := applications.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.BadRequest(fmt.Errorf("invalid application %q: %w", name, err)).Render(w)
 
-	// Check if the application is valid.
-	app, ok := s.state.Applications[name]
-	if !ok {
-		_ = response.NotFound(nil).Render(w)
+		return
+	}
+
+	// Add the application to the state.
+	s.state.SetApplication(name, api.Application{Config: config})
+
+	// Trigger a manual update check to install the new application.
+	s.state.RequestUpdate()
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation POST /1.0/applications/{name}/:install applications applications_post_install
+//
+//	Install an application
+//
+//	Adds the application to the system, then triggers an update check to download, start and initialize it.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+func (s *Server) apiApplicationsInstall(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
 
 		return
 	}
 
-	// Handle the request.
-	_ = response.SyncResponse(true, app).Render(w)
+	s.installApplication(w, r, r.PathValue("name"), api.ApplicationConfig{})
 }
 
-// swagger:operation POST /1.0/applications/{name}/:factory-reset applications applications_post_reset
+// swagger:operation POST /1.0/applications/{name}/:start applications applications_post_start
 //
-//	Perform a factory reset of the application
+//	Start an application
 //
-//	Factory reset the application. This is a DESTRUCTIVE action and will wipe any local application configuration.
+//	Starts the application if it isn't already running.
 //
 //	---
 //	produces:
@@ -231,7 +426,7 @@ func (s *Server) apiApplicationsEndpoint(w http.ResponseWriter, r *http.Request)
 //	    $ref: "#/responses/NotFound"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func (s *Server) apiApplicationsFactoryReset(w http.ResponseWriter, r *http.Request) {
+func (s *Server) apiApplicationsStart(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
@@ -243,7 +438,7 @@ func (s *Server) apiApplicationsFactoryReset(w http.ResponseWriter, r *http.Requ
 	name := r.PathValue("name")
 
 	// Check if the application is valid.
-	_, ok := s.state.Applications[name]
+	appInfo, ok := s.state.Applications[name]
 	if !ok {
 		_ = response.NotFound(nil).Render(w)
 
@@ -258,22 +453,27 @@ func (s *Server) apiApplicationsFactoryReset(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Do the factory reset.
-	err = app.FactoryReset(r.Context())
-	if err != nil {
-		_ = response.InternalError(err).Render(w)
+	if !app.IsRunning(r.Context()) {
+		slog.InfoContext(r.Context(), "Starting application", "name", name, "version", appInfo.State.Version)
 
-		return
+		err = app.Start(r.Context(), appInfo.State.Version)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
 	}
 
+	applications.SetStopped(name, false)
+
 	_ = response.EmptySyncResponse.Render(w)
 }
 
-// swagger:operation POST /1.0/applications/{name}/:restart applications applications_post_restart
+// swagger:operation POST /1.0/applications/{name}/:stop applications applications_post_stop
 //
-//	Restart an application
+//	Stop an application
 //
-//	Triggers a restart of the application.
+//	Stops the application until it's started again or the system is restarted.
 //
 //	---
 //	produces:
@@ -291,7 +491,7 @@ func (s *Server) apiApplicationsFactoryReset(w http.ResponseWriter, r *http.Requ
 //	    $ref: "#/responses/NotFound"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func (s *Server) apiApplicationsRestart(w http.ResponseWriter, r *http.Request) {
+func (s *Server) apiApplicationsStop(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
@@ -303,7 +503,7 @@ func (s *Server) apiApplicationsRestart(w http.ResponseWriter, r *http.Request)
 	name := r.PathValue("name")
 
 	// Check if the application is valid.
-	_, ok := s.state.Applications[name]
+	appInfo, ok := s.state.Applications[name]
 	if !ok {
 		_ = response.NotFound(nil).Render(w)
 
@@ -318,31 +518,87 @@ func (s *Server) apiApplicationsRestart(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Trigger the restart.
-	err = app.Restart(r.Context(), s.state.Applications[name].State.Version)
-	if err != nil {
-		_ = response.InternalError(err).Render(w)
+	// Don't let the health monitor start it again.
+	applications.SetStopped(name, true)
+
+	if app.IsRunning(r.Context()) {
+		slog.InfoContext(r.Context(), "Stopping application", "name", name, "version", appInfo.State.Version)
+
+		err = app.Stop(r.Context(), appInfo.State.Version)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation POST /1.0/applications/{name}/:update applications applications_post_update
+//
+//	Update an application
+//
+//	Applies the staged update of the application, if any, or otherwise triggers an update check.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+func (s *Server) apiApplicationsUpdate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// Check if the application is valid.
+	appInfo, ok := s.state.Applications[name]
+	if !ok {
+		_ = response.NotFound(nil).Render(w)
 
 		return
 	}
 
+	if appInfo.State.StagedVersion != "" {
+		// Trigger applying the staged updates.
+		s.state.RequestApply()
+	} else {
+		// Trigger a manual update check.
+		s.state.RequestUpdate()
+	}
+
 	_ = response.EmptySyncResponse.Render(w)
 }
 
-// swagger:operation POST /1.0/applications/{name}/:backup applications applications_post_backup
+// swagger:operation POST /1.0/applications/{name}/:remove applications applications_post_remove
 //
-//	Generate an application backup
+//	Remove an application
 //
-//	Generate and return a `gzip` compressed tar archive backup for the application.
+//	Stops the application, wipes its local data and removes it from the system. This is a DESTRUCTIVE action.
+//	Applications which other installed applications depend on can't be removed.
 //
-//	A full backup may be quite large depending on what artifacts or updates are locally cached by the application.
+//	The local data can be kept, such as to reinstall the application later on.
 //
 //	---
 //	consumes:
 //	  - application/json
 //	produces:
 //	  - application/json
-//	  - application/gzip
 //	parameters:
 //	  - in: path
 //	    name: name
@@ -351,21 +607,21 @@ func (s *Server) apiApplicationsRestart(w http.ResponseWriter, r *http.Request)
 //	    type: string
 //	  - in: body
 //	    name: configuration
-//	    description: Backup configuration
+//	    description: Removal configuration
 //	    required: false
 //	    schema:
 //	      type: object
-//	      example: {"complete":true}
+//	      example: {"keep_data":true}
 //	responses:
 //	  "200":
-//	    description: gzip'ed tar archive
-//	    schema:
-//	      type: file
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
 //	  "404":
 //	    $ref: "#/responses/NotFound"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
+func (s *Server) apiApplicationsRemove(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
@@ -384,19 +640,24 @@ func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Load the application.
-	app, err := applications.Load(r.Context(), s.state, name)
+	dependents, err := applications.GetDependents(r.Context(), s.state, name)
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
 
-	type backupStruct struct {
-		Complete bool `json:"complete"`
+	if len(dependents) > 0 {
+		_ = response.BadRequest(fmt.Errorf("application %q is required by %s", name, strings.Join(dependents, ", "))).Render(w)
+
+		return
 	}
 
-	config := &backupStruct{}
+	type removeStruct struct {
+		KeepData bool `json:"keep_data"`
+	}
+
+	config := &removeStruct{}
 
 	counter := &countWrapper{ReadCloser: r.Body}
 
@@ -407,44 +668,36 @@ func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Once we begin streaming the tar archive back to the user,
-	// we can no longer return a nice error message if something
-	// goes wrong. So, first generate the archive and dump everything
-	// to /dev/null. If any error is reported, we can return it to the
-	// user. We can't buffer in-memory or on-disk since we don't know
-	// how large the archive might be and we don't want to DOS ourselves.
-	err = app.GetBackup(io.Discard, config.Complete)
+	slog.InfoContext(r.Context(), "Removing application", "name", name, "keep_data", config.KeepData)
+
+	err = applications.Remove(r.Context(), s.state, name, !config.KeepData)
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/gzip")
-
-	// From this point onwards we cannot return any nice errors
-	// to the user, since we will have already begun streaming
-	// the tar archive to them.
+	_ = s.state.Save()
 
-	err = app.GetBackup(w, config.Complete)
+	err = systemd.RefreshExtensions(r.Context())
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
+
+	s.state.Events.SendLifecycle(api.EventLifecycleApplicationRemoved, "/1.0/applications/"+name, nil)
+
+	_ = response.EmptySyncResponse.Render(w)
 }
 
-// swagger:operation POST /1.0/applications/{name}/:restore applications applications_post_restore
-//
-//	Restore an application backup
+// swagger:operation POST /1.0/applications/{name}/:factory-reset applications applications_post_reset
 //
-//	Restore a `gzip` compressed tar archive backup for the application. After a successful restore, the application will be restarted.
+//	Perform a factory reset of the application
 //
-//	Remember to properly set the `Content-Type: application/gzip` HTTP header.
+//	Factory reset the application. This is a DESTRUCTIVE action and will wipe any local application configuration.
 //
 //	---
-//	consumes:
-//	  - application/gzip
 //	produces:
 //	  - application/json
 //	parameters:
@@ -453,12 +706,6 @@ func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
 //	    description: Application name
 //	    required: true
 //	    type: string
-//	  - in: body
-//	    name: gzip tar archive
-//	    description: Application backup to restore
-//	    required: true
-//	    schema:
-//	      type: file
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
@@ -466,7 +713,7 @@ func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
 //	    $ref: "#/responses/NotFound"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
-func (s *Server) apiApplicationsRestore(w http.ResponseWriter, r *http.Request) {
+func (s *Server) apiApplicationsFactoryReset(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method != http.MethodPost {
@@ -478,7 +725,7 @@ func (s *Server) apiApplicationsRestore(w http.ResponseWriter, r *http.Request)
 	name := r.PathValue("name")
 
 	// Check if the application is valid.
-	appInfo, ok := s.state.Applications[name]
+	_, ok := s.state.Applications[name]
 	if !ok {
 		_ = response.NotFound(nil).Render(w)
 
@@ -493,25 +740,439 @@ func (s *Server) apiApplicationsRestore(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Restore the application's backup.
-	err = app.RestoreBackup(r.Context(), r.Body)
+	// Do the factory reset.
+	err = app.FactoryReset(r.Context())
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
 
-	// Record when the application was restored.
-	now := time.Now()
-	appInfo.State.LastRestored = &now
-	s.state.Applications[name] = appInfo
+	_ = response.EmptySyncResponse.Render(w)
+}
 
-	err = s.state.Save()
-	if err != nil {
+// swagger:operation POST /1.0/applications/{name}/:restart applications applications_post_restart
+//
+//	Restart an application
+//
+//	Triggers a restart of the application.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiApplicationsRestart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// Check if the application is valid.
+	_, ok := s.state.Applications[name]
+	if !ok {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	// Load the application.
+	app, err := applications.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	// Trigger the restart.
+	err = app.Restart(r.Context(), s.state.Applications[name].State.Version)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	applications.SetStopped(name, false)
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation GET /1.0/applications/{name}/backup applications applications_get_backup
+//
+//	Download an application backup
+//
+//	Generate and stream a `gzip` compressed tar archive backup for the application.
+//
+//	A full backup may be quite large depending on what artifacts or updates are locally cached by the application.
+//	Progress is reported through `application-backup-progress` lifecycle events.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/gzip
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	  - in: query
+//	    name: complete
+//	    description: Whether to generate a full backup
+//	    required: false
+//	    type: boolean
+//	responses:
+//	  "200":
+//	    description: gzip'ed tar archive
+//	    schema:
+//	      type: file
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation POST /1.0/applications/{name}/backup applications applications_post_backup_restore
+//
+//	Restore an uploaded application backup
+//
+//	Restore a `gzip` compressed tar archive backup for the application. After a successful restore, the application will be restarted.
+//
+//	Remember to properly set the `Content-Type: application/gzip` HTTP header, as well as `Content-Length` for the
+//	`application-restore-progress` lifecycle events to report a percentage.
+//
+//	---
+//	consumes:
+//	  - application/gzip
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	  - in: body
+//	    name: gzip tar archive
+//	    description: Application backup to restore
+//	    required: true
+//	    schema:
+//	      type: file
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiApplicationsBackupEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		complete := false
+
+		if r.FormValue("complete") != "" {
+			var err error
+
+			complete, err = strconv.ParseBool(r.FormValue("complete"))
+			if err != nil {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+		}
+
+		s.backupApplication(w, r, name, complete)
+	case http.MethodPost:
+		s.restoreApplication(w, r, name)
+	default:
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+// swagger:operation POST /1.0/applications/{name}/:backup applications applications_post_backup
+//
+//	Generate an application backup
+//
+//	Generate and return a `gzip` compressed tar archive backup for the application.
+//
+//	A full backup may be quite large depending on what artifacts or updates are locally cached by the application.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	  - application/gzip
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	  - in: body
+//	    name: configuration
+//	    description: Backup configuration
+//	    required: false
+//	    schema:
+//	      type: object
+//	      example: {"complete":true}
+//	responses:
+//	  "200":
+//	    description: gzip'ed tar archive
+//	    schema:
+//	      type: file
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiApplicationsBackup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	type backupStruct struct {
+		Complete bool `json:"complete"`
+	}
+
+	config := &backupStruct{}
+
+	counter := &countWrapper{ReadCloser: r.Body}
+
+	err := json.NewDecoder(counter).Decode(config)
+	if err != nil && counter.n > 0 {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	s.backupApplication(w, r, r.PathValue("name"), config.Complete)
+}
+
+// swagger:operation POST /1.0/applications/{name}/:restore applications applications_post_restore
+//
+//	Restore an application backup
+//
+//	Restore a `gzip` compressed tar archive backup for the application. After a successful restore, the application will be restarted.
+//
+//	Remember to properly set the `Content-Type: application/gzip` HTTP header.
+//
+//	---
+//	consumes:
+//	  - application/gzip
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Application name
+//	    required: true
+//	    type: string
+//	  - in: body
+//	    name: gzip tar archive
+//	    description: Application backup to restore
+//	    required: true
+//	    schema:
+//	      type: file
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "409":
+//	    $ref: "#/responses/Conflict"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiApplicationsRestore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	s.restoreApplication(w, r, r.PathValue("name"))
+}
+
+// backupApplication streams a backup of the application, reporting its progress through lifecycle events.
+func (s *Server) backupApplication(w http.ResponseWriter, r *http.Request, name string, complete bool) {
+	// Check if the application is valid.
+	_, ok := s.state.Applications[name]
+	if !ok {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	// Load the application.
+	app, err := applications.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	// Prevent concurrent backups and restores.
+	done, err := applications.StartOperation(name, "backup")
+	if err != nil {
+		_ = response.Conflict(err).Render(w)
+
+		return
+	}
+
+	defer done()
+
+	// Once we begin streaming the tar archive back to the user,
+	// we can no longer return a nice error message if something
+	// goes wrong. So, first generate the archive and dump everything
+	// to /dev/null. If any error is reported, we can return it to the
+	// user. We can't buffer in-memory or on-disk since we don't know
+	// how large the archive might be and we don't want to DOS ourselves.
+	// The size of this first pass is also used to report progress.
+	counter := &progressWriter{Writer: io.Discard}
+
+	err = app.GetBackup(counter, complete)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	source := "/1.0/applications/" + name + "/backup"
+	total := counter.n
+
+	w.Header().Set("Content-Type", "application/gzip")
+
+	// From this point onwards we cannot return any nice errors
+	// to the user, since we will have already begun streaming
+	// the tar archive to them.
+
+	writer := &progressWriter{Writer: w, progress: s.trackBackupProgress(api.EventLifecycleApplicationBackupProgress, source, total)}
+
+	err = app.GetBackup(writer, complete)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	s.state.Events.SendLifecycle(api.EventLifecycleApplicationBackupCreated, source, map[string]any{"complete": complete, "size": writer.n})
+}
+
+// restoreApplication restores an uploaded backup of the application, reporting its progress through lifecycle events.
+func (s *Server) restoreApplication(w http.ResponseWriter, r *http.Request, name string) {
+	// Check if the application is valid.
+	appInfo, ok := s.state.Applications[name]
+	if !ok {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	// Load the application.
+	app, err := applications.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	// Prevent concurrent backups and restores, as well as restarts by the health monitor.
+	done, err := applications.StartOperation(name, "restore")
+	if err != nil {
+		_ = response.Conflict(err).Render(w)
+
+		return
+	}
+
+	defer done()
+
+	source := "/1.0/applications/" + name + "/backup"
+	reader := &progressReader{Reader: r.Body, progress: s.trackBackupProgress(api.EventLifecycleApplicationRestoreProgress, source, r.ContentLength)}
+
+	// Restore the application's backup.
+	err = app.RestoreBackup(r.Context(), reader)
+	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
 
+	// Record when the application was restored.
+	now := time.Now()
+	appInfo.State.LastRestored = &now
+	s.state.SetApplication(name, appInfo)
+
+	err = s.state.Save()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	s.state.Events.SendLifecycle(api.EventLifecycleApplicationRestored, "/1.0/applications/"+name, nil)
+
 	_ = response.EmptySyncResponse.Render(w)
 }
+
+// trackBackupProgress returns a function sending lifecycle events as a backup is transferred. A
+// percentage is only reported when the total size is known.
+func (s *Server) trackBackupProgress(action api.EventLifecycleAction, source string, total int64) func(int64) {
+	var lastEvent time.Time
+
+	lastPercentage := -1
+
+	return func(transferred int64) {
+		metadata := map[string]any{"bytes": transferred}
+
+		if total > 0 {
+			percentage := int(min(transferred*100/total, 100))
+
+			// Only send an event when the percentage changes, to avoid flooding subscribers.
+			if percentage == lastPercentage {
+				return
+			}
+
+			lastPercentage = percentage
+			metadata["percentage"] = percentage
+		} else {
+			// Otherwise send at most an event per second.
+			if time.Since(lastEvent) < time.Second {
+				return
+			}
+
+			lastEvent = time.Now()
+		}
+
+		s.state.Events.SendLifecycle(action, source, metadata)
+	}
+}
@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/internal/mdns"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// swagger:operation GET /1.0/cluster/peers cluster cluster_get_peers
+//
+//	Get the discovered peers
+//
+//	Returns the other IncusOS systems discovered on the local network through multicast DNS, along with their versions.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: List of peers
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of peers
+//	          example: [{"name":"server02","addresses":["10.0.0.12"],"port":8443,"os_name":"IncusOS","os_version":"202511041601","applications":{"incus":"202511041601"},"last_seen":"2025-11-04T16:07:01Z"}]
+func (*Server) apiClusterPeers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, mdns.Peers()).Render(w)
+}
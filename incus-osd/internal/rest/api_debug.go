@@ -1,12 +1,19 @@
 package rest
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 
@@ -14,6 +21,21 @@ import (
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 )
 
+// withDebugAccess only lets requests through to the debug endpoints if debug access is currently allowed.
+func (s *Server) withDebugAccess(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.state.DebugAccessAllowed() {
+			w.Header().Set("Content-Type", "application/json")
+
+			_ = response.Forbidden(errors.New("debug access is restricted, a time-limited grant is required")).Render(w)
+
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
 // swagger:operation GET /1.0/debug debug debug_get
 //
 //	Get debug endpoints
@@ -49,7 +71,7 @@ import (
 //	          description: List of debug endpoints
 //	          items:
 //	            type: string
-//	          example: ["/1.0/debug/log","/1.0/debug/tui"]
+//	          example: ["/1.0/debug/audit","/1.0/debug/kdump","/1.0/debug/log","/1.0/debug/tui"]
 func (*Server) apiDebug(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -63,7 +85,7 @@ func (*Server) apiDebug(w http.ResponseWriter, r *http.Request) {
 
 	urls := []string{}
 
-	for _, debug := range []string{"log", "tui"} {
+	for _, debug := range []string{"audit", "kdump", "log", "tui"} {
 		debugURL, _ := url.JoinPath(endpoint, debug)
 		urls = append(urls, debugURL)
 	}
@@ -71,15 +93,125 @@ func (*Server) apiDebug(w http.ResponseWriter, r *http.Request) {
 	_ = response.SyncResponse(true, urls).Render(w)
 }
 
+// swagger:operation GET /1.0/debug/audit debug debug_get_audit
+//
+//	Get the audit log
+//
+//	Returns the recorded calls which may have modified the system (POST, PUT, PATCH and DELETE), oldest first, along with
+//	the identity of their caller, a digest of the provided payload and the resulting status code. Entries can be filtered by
+//	method, endpoint prefix, time and number of returned entries.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: method
+//	    description: Limit audit entries to the specified method
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: endpoint
+//	    description: Limit audit entries to endpoints starting with the specified prefix
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: since
+//	    description: Limit audit entries to those recorded after the specified time (RFC3339)
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: entries
+//	    description: Limit audit entries to the specified number of most recent entries
+//	    required: false
+//	    type: integer
+//	responses:
+//	  "200":
+//	    description: Audit log entries
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of audit log entries
+//	          items:
+//	            type: object
+//	          example: [{"time":"2025-11-04T16:12:43.51283Z","source":"local","identity":"uid=0 gid=0 pid=1234 comm=incusd","method":"POST","endpoint":"/1.0/system/:reboot","payload_size":0,"status_code":200,"duration":2}]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiDebugAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	var since time.Time
+
+	if r.FormValue("since") != "" {
+		var err error
+
+		since, err = time.Parse(time.RFC3339, r.FormValue("since"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	limit := 0
+
+	if r.FormValue("entries") != "" {
+		var err error
+
+		limit, err = strconv.Atoi(r.FormValue("entries"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	entries, err := s.getAuditEntries(r.FormValue("method"), r.FormValue("endpoint"), since, limit)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, entries).Render(w)
+}
+
 // swagger:operation GET /1.0/debug/log debug debug_get_log
 //
 //	Get systemd journal entries
 //
-//	Return systemd journal entries, optionally filtering by unit, boot number, and number of returned entries.
+//	Return systemd journal entries, optionally filtering by unit, priority, time range, boot number, and number of returned entries.
+//
+//	When the "follow" parameter is set, the matching entries are instead streamed as server-sent events, each one's data
+//	being the JSON encoded journal entry, and new entries are sent as they get logged until the client goes away.
 //
 //	---
 //	produces:
 //	  - application/json
+//	  - text/event-stream
 //	parameters:
 //	  - in: query
 //	    name: unit
@@ -87,6 +219,26 @@ func (*Server) apiDebug(w http.ResponseWriter, r *http.Request) {
 //	    required: false
 //	    type: string
 //	  - in: query
+//	    name: kernel
+//	    description: Limit journal entries to kernel messages
+//	    required: false
+//	    type: boolean
+//	  - in: query
+//	    name: priority
+//	    description: Limit journal entries to the specified priority or range of priorities, such as "err" or "warning..err"
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: since
+//	    description: Limit journal entries to those logged at or after the specified time (RFC3339)
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: until
+//	    description: Limit journal entries to those logged at or before the specified time (RFC3339)
+//	    required: false
+//	    type: string
+//	  - in: query
 //	    name: boot
 //	    description: Limit journal entries to the specified boot number
 //	    required: false
@@ -96,6 +248,11 @@ func (*Server) apiDebug(w http.ResponseWriter, r *http.Request) {
 //	    description: Limit journal entries to the specified number of entries
 //	    required: false
 //	    type: integer
+//	  - in: query
+//	    name: follow
+//	    description: Stream new journal entries as they get logged
+//	    required: false
+//	    type: boolean
 //	responses:
 //	  "200":
 //	    description: systemd journal entries
@@ -121,43 +278,33 @@ func (*Server) apiDebug(w http.ResponseWriter, r *http.Request) {
 //	          items:
 //	            type: object
 //	          example: [{"MESSAGE":"2025-11-04 16:07:01 INFO System is ready release=202511041601","PRIORITY":"6","SYSLOG_FACILITY":"3","SYSLOG_IDENTIFIER":"incus-osd","_BOOT_ID":"800f36431cb84ddbacbff7fd5539d359","_CAP_EFFECTIVE":"1ffffffffff","_CMDLINE":"/usr/local/bin/incus-osd","_COMM":"incus-osd","_EXE":"/usr/local/bin/incus-osd","_GID":"0","_HOSTNAME":"af94e64e-1993-41b6-8f10-a8eebb828fce","_MACHINE_ID":"af94e64e199341b68f10a8eebb828fce","_PID":"688","_RUNTIME_SCOPE":"system","_SELINUX_CONTEXT":"unconfined\n","_STREAM_ID":"2cad567611724cb0ac38369beeff4921","_SYSTEMD_CGROUP":"/system.slice/incus-osd.service","_SYSTEMD_INVOCATION_ID":"8b2d8aabff73448dafab917f4eaaeacc","_SYSTEMD_SLICE":"system.slice","_SYSTEMD_UNIT":"incus-osd.service","_TRANSPORT":"stdout","_UID":"0","__CURSOR":"s=55e9886cc9024eb7ad4367e9061be6ce;i=7a6;b=800f36431cb84ddbacbff7fd5539d359;m=241064e;t=642c705aba083;x=e88fc1e4f70c128a","__MONOTONIC_TIMESTAMP":"37815886","__REALTIME_TIMESTAMP":"1762272421322883","__SEQNUM":"1958","__SEQNUM_ID":"55e9886cc9024eb7ad4367e9061be6ce"}]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (*Server) apiDebugLog(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
 		_ = response.NotImplemented(nil).Render(w)
 
 		return
 	}
 
-	err := r.ParseForm()
+	journalCmdArgs, follow, err := journalArgs(r)
 	if err != nil {
-		_ = response.InternalError(err).Render(w)
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.BadRequest(err).Render(w)
 
 		return
 	}
 
-	unitName := r.Form.Get("unit")
-	bootNumber := r.Form.Get("boot")
-	numEntries := r.Form.Get("entries")
-
-	journalCmdArgs := []string{"-o", "json"}
-
-	if unitName != "" {
-		journalCmdArgs = append(journalCmdArgs, "-u", unitName)
-	}
+	if follow {
+		streamJournal(w, r, journalCmdArgs)
 
-	if bootNumber != "" {
-		journalCmdArgs = append(journalCmdArgs, "-b", bootNumber)
-	} else {
-		journalCmdArgs = append(journalCmdArgs, "-b", "0")
+		return
 	}
 
-	if numEntries != "" {
-		journalCmdArgs = append(journalCmdArgs, "-n", numEntries)
-	}
+	w.Header().Set("Content-Type", "application/json")
 
 	jsonOutput, err := subprocess.RunCommandContext(r.Context(), "journalctl", journalCmdArgs...)
 	if err != nil {
@@ -188,6 +335,137 @@ func (*Server) apiDebugLog(w http.ResponseWriter, r *http.Request) {
 	_ = response.SyncResponse(true, jsonObj).Render(w)
 }
 
+// journalPriorityRegex matches a journal priority, or range of priorities, as accepted by journalctl.
+var journalPriorityRegex = regexp.MustCompile(`^([0-7]|emerg|alert|crit|err|warning|notice|info|debug)(\.\.([0-7]|emerg|alert|crit|err|warning|notice|info|debug))?$`)
+
+// journalArgs returns the journalctl arguments matching the request's query parameters, along with whether new
+// entries should be followed.
+func journalArgs(r *http.Request) ([]string, bool, error) {
+	journalCmdArgs := []string{"-o", "json"}
+
+	if r.FormValue("unit") != "" {
+		journalCmdArgs = append(journalCmdArgs, "-u", r.FormValue("unit"))
+	}
+
+	if r.FormValue("kernel") != "" {
+		kernel, err := strconv.ParseBool(r.FormValue("kernel"))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid kernel value %q", r.FormValue("kernel"))
+		}
+
+		if kernel {
+			journalCmdArgs = append(journalCmdArgs, "-k")
+		}
+	}
+
+	if r.FormValue("priority") != "" {
+		if !journalPriorityRegex.MatchString(r.FormValue("priority")) {
+			return nil, false, fmt.Errorf("invalid priority %q", r.FormValue("priority"))
+		}
+
+		journalCmdArgs = append(journalCmdArgs, "-p", r.FormValue("priority"))
+	}
+
+	for _, param := range []string{"since", "until"} {
+		if r.FormValue(param) == "" {
+			continue
+		}
+
+		ts, err := time.Parse(time.RFC3339, r.FormValue(param))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid %s time %q", param, r.FormValue(param))
+		}
+
+		journalCmdArgs = append(journalCmdArgs, "--"+param, "@"+strconv.FormatInt(ts.Unix(), 10))
+	}
+
+	if r.FormValue("boot") != "" {
+		journalCmdArgs = append(journalCmdArgs, "-b", r.FormValue("boot"))
+	} else {
+		journalCmdArgs = append(journalCmdArgs, "-b", "0")
+	}
+
+	if r.FormValue("entries") != "" {
+		journalCmdArgs = append(journalCmdArgs, "-n", r.FormValue("entries"))
+	}
+
+	follow := false
+
+	if r.FormValue("follow") != "" {
+		var err error
+
+		follow, err = strconv.ParseBool(r.FormValue("follow"))
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid follow value %q", r.FormValue("follow"))
+		}
+	}
+
+	if follow {
+		if r.FormValue("until") != "" {
+			return nil, false, errors.New("can't follow journal entries with an end time")
+		}
+
+		journalCmdArgs = append(journalCmdArgs, "-f")
+	}
+
+	return journalCmdArgs, follow, nil
+}
+
+// streamJournal streams journal entries as server-sent events until journalctl exits or the client goes away.
+func streamJournal(w http.ResponseWriter, r *http.Request, journalCmdArgs []string) {
+	cmd := exec.CommandContext(r.Context(), "journalctl", journalCmdArgs...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	defer func() { _ = cmd.Wait() }()
+	defer func() { _ = cmd.Cancel() }()
+
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	err = controller.Flush()
+	if err != nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		_, err = fmt.Fprintf(w, "data: %s\n\n", scanner.Bytes())
+		if err != nil {
+			return
+		}
+
+		err = controller.Flush()
+		if err != nil {
+			return
+		}
+	}
+}
+
 // swagger:operation POST /1.0/debug/secureboot/:update debug debug_post_secureboot_update
 //
 //	Apply Secure Boot updates
@@ -0,0 +1,245 @@
+package rest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/lxc/incus-os/incus-osd/internal/kdump"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// swagger:operation GET /1.0/debug/kdump debug debug_get_kdump
+//
+//	Get kernel crash dumps
+//
+//	Returns a list of the kernel crash dumps captured by the kdump service (URLs).
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: API endpoints
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of crash dumps
+//	          items:
+//	            type: string
+//	          example: ["/1.0/debug/kdump/20260412-083012"]
+func (*Server) apiDebugKdump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	names, err := kdump.List()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	endpoint, _ := url.JoinPath(getAPIRoot(r), "debug", "kdump")
+
+	urls := []string{}
+
+	for _, name := range names {
+		dumpURL, _ := url.JoinPath(endpoint, name)
+		urls = append(urls, dumpURL)
+	}
+
+	_ = response.SyncResponse(true, urls).Render(w)
+}
+
+// swagger:operation GET /1.0/debug/kdump/{name} debug debug_get_kdump_dump
+//
+//	Get kernel crash dump details
+//
+//	Returns the time a kernel crash dump was captured at, along with its files and their total size.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Crash dump name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    description: Crash dump details
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Crash dump details
+//	          example: {"name":"20260412-083012","time":"2026-04-12T08:30:12Z","files":["dmesg.txt","vmcore"],"size":183500800}
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+func (*Server) apiDebugKdumpEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	dump, err := kdump.Get(r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, kdump.ErrDumpNotFound) {
+			_ = response.NotFound(err).Render(w)
+
+			return
+		}
+
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, dump).Render(w)
+}
+
+// swagger:operation POST /1.0/debug/kdump/{name}/:download debug debug_post_kdump_download
+//
+//	Download a kernel crash dump
+//
+//	Returns a tar archive of the kernel crash dump, holding the kernel log of the crashed system and its compressed memory dump.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - application/x-tar
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Crash dump name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    description: tar archive
+//	    schema:
+//	      type: file
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (*Server) apiDebugKdumpDownload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// As with application backups, check that the archive can be generated before starting to stream it.
+	err := kdump.WriteArchive(io.Discard, name)
+	if err != nil {
+		if errors.Is(err, kdump.ErrDumpNotFound) {
+			_ = response.NotFound(err).Render(w)
+
+			return
+		}
+
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-tar")
+
+	err = kdump.WriteArchive(w, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+}
+
+// swagger:operation POST /1.0/debug/kdump/{name}/:delete debug debug_post_kdump_delete
+//
+//	Delete a kernel crash dump
+//
+//	Deletes a kernel crash dump from the local disk.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Crash dump name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (*Server) apiDebugKdumpDelete(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	err := kdump.Delete(r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, kdump.ErrDumpNotFound) {
+			_ = response.NotFound(err).Render(w)
+
+			return
+		}
+
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
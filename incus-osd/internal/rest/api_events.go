@@ -0,0 +1,249 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lxc/incus/v6/shared/ws"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/events"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// eventsWriteTimeout bounds how long sending a single event to a subscriber may take.
+const eventsWriteTimeout = 30 * time.Second
+
+// swagger:operation GET /1.0/events events events_get
+//
+//	Get the event subscribers or subscribe to events
+//
+//	Returns information about the connected event subscribers, including their queue usage and dropped events.
+//
+//	When requested as a websocket, the connection is instead upgraded and events are sent over it as JSON objects.
+//	When requested with an "Accept: text/event-stream" header, events are instead streamed as server-sent events,
+//	each one's data being the JSON encoded event. Each subscriber gets its own bounded queue, so a slow subscriber never delays event production. When the
+//	queue is full, events are dropped according to the drop policy and a "lag" event reporting the number of
+//	dropped events is sent once the subscriber catches up.
+//
+//	---
+//	produces:
+//	  - application/json
+//	  - text/event-stream
+//	parameters:
+//	  - in: query
+//	    name: type
+//	    description: Comma separated list of event types to subscribe to, all events if not set
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: queue-size
+//	    description: Number of events queued before dropping any, defaults to 256
+//	    required: false
+//	    type: integer
+//	  - in: query
+//	    name: drop-policy
+//	    description: What to do when the queue is full, one of "drop-oldest" (default), "drop-newest" or "disconnect"
+//	    required: false
+//	    type: string
+//	responses:
+//	  "101":
+//	    description: Switching protocols to websocket
+//	  "200":
+//	    description: Event subscribers
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of event subscribers
+//	          items:
+//	            type: object
+//	          example: [{"types":["notification"],"drop_policy":"drop-oldest","queue_size":256,"queued":0,"dropped":12,"connected":"2025-11-04T16:07:01Z"}]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+func (s *Server) apiEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	isWebsocket := websocket.IsWebSocketUpgrade(r)
+	isEventStream := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+
+	if !isWebsocket && !isEventStream {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.SyncResponse(true, s.state.Events.Subscribers()).Render(w)
+
+		return
+	}
+
+	sub, err := s.subscribeEvents(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	defer sub.Close()
+
+	if isWebsocket {
+		s.sendEventsWebsocket(w, r, sub)
+	} else {
+		s.sendEventsStream(w, r, sub)
+	}
+}
+
+// subscribeEvents subscribes to events according to the request's query parameters.
+func (s *Server) subscribeEvents(r *http.Request) (*events.Subscriber, error) {
+	types := []api.EventType{}
+
+	if r.FormValue("type") != "" {
+		for eventType := range strings.SplitSeq(r.FormValue("type"), ",") {
+			types = append(types, api.EventType(eventType))
+		}
+	}
+
+	queueSize := 0
+
+	if r.FormValue("queue-size") != "" {
+		var err error
+
+		queueSize, err = strconv.Atoi(r.FormValue("queue-size"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.state.Events.Subscribe(types, queueSize, api.EventDropPolicy(r.FormValue("drop-policy")))
+}
+
+// sendEventsWebsocket upgrades the connection to a websocket and sends the subscriber's events over it.
+func (*Server) sendEventsWebsocket(w http.ResponseWriter, r *http.Request, sub *events.Subscriber) {
+	conn, err := ws.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	// Stop sending events once the subscriber goes away.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		defer cancel()
+
+		for {
+			_, _, err := conn.NextReader()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		event, err := sub.Next(ctx)
+		if err != nil {
+			if errors.Is(err, events.ErrSlowSubscriber) {
+				slog.WarnContext(r.Context(), "Disconnecting event subscriber which isn't keeping up")
+
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()), time.Now().Add(time.Second))
+			}
+
+			return
+		}
+
+		logEventLag(r.Context(), event)
+
+		err = conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		if err != nil {
+			return
+		}
+
+		err = conn.WriteJSON(event)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sendEventsStream streams the subscriber's events as server-sent events until the client goes away.
+func (*Server) sendEventsStream(w http.ResponseWriter, r *http.Request, sub *events.Subscriber) {
+	controller := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	err := controller.Flush()
+	if err != nil {
+		return
+	}
+
+	for {
+		event, err := sub.Next(r.Context())
+		if err != nil {
+			if errors.Is(err, events.ErrSlowSubscriber) {
+				slog.WarnContext(r.Context(), "Disconnecting event subscriber which isn't keeping up")
+			}
+
+			return
+		}
+
+		logEventLag(r.Context(), event)
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+
+		err = controller.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		if err != nil {
+			return
+		}
+
+		err = controller.Flush()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// logEventLag logs a warning when events were dropped for a subscriber.
+func logEventLag(ctx context.Context, event api.Event) {
+	lag, ok := event.Metadata.(api.EventLag)
+	if ok {
+		slog.WarnContext(ctx, "Event subscriber is lagging behind, events were dropped", "dropped", lag.Dropped)
+	}
+}
@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/health"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// swagger:operation GET /1.0/health server health_get
+//
+//	Get the detailed health of the system
+//
+//	Returns the overall health of the system along with the outcome of the individual checks covering the state storage,
+//	TPM, services, disk space, time synchronization and update provider. Each check has a severity of "ok", "warning" or
+//	"critical". The status is "degraded", and the response code 503, as soon as one check is critical.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Health report
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Health report
+//	          example: {"status":"ok","severity":"warning","checks":[{"name":"state","severity":"ok"},{"name":"tpm","severity":"ok"},{"name":"services","severity":"ok"},{"name":"disk","severity":"warning","message":"only 7.42GiB free space available in /"},{"name":"time","severity":"ok"},{"name":"provider","severity":"ok"}]}
+//	  "503":
+//	    description: Health report of a degraded system
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        metadata:
+//	          type: json
+//	          description: Health report
+//	          example: {"status":"degraded","severity":"critical","checks":[{"name":"state","severity":"critical","message":"state storage is degraded: /var/lib/incus-os is mounted read-only"},{"name":"tpm","severity":"ok"},{"name":"services","severity":"ok"},{"name":"disk","severity":"ok"},{"name":"time","severity":"ok"},{"name":"provider","severity":"ok"}]}
+func (s *Server) apiHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	report := health.Run(r.Context(), s.state)
+
+	code := http.StatusOK
+	if report.Status != api.HealthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	_ = response.SyncResponseStatus(true, report, code).Render(w)
+}
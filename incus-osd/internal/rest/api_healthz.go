@@ -0,0 +1,227 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/revert"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/health"
+)
+
+// healthzCacheDuration is how long the status served by the health endpoint is reused, so probing the
+// system doesn't let anyone run the health checks at will.
+const healthzCacheDuration = 10 * time.Second
+
+// runHealthChecks performs the health checks, replaced in tests.
+var runHealthChecks = health.Run
+
+// swagger:operation GET /healthz server healthz_get
+//
+//	Get the coarse health of the system
+//
+//	Returns whether the system is healthy, intended for load balancers and monitoring probes.
+//	Beside the local socket, this endpoint can be exposed without authentication on a dedicated
+//	address through the "health_probe_address" security configuration key. It never returns more
+//	than the overall "ok" or "degraded" status of the health checks.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: The system is healthy
+//	    schema:
+//	      type: object
+//	      example: {"status":"ok"}
+//	  "503":
+//	    description: The system is degraded
+//	    schema:
+//	      type: object
+//	      example: {"status":"degraded"}
+func (s *Server) apiHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// Only expose the overall status of the health checks.
+	status := api.Health{Status: s.cachedHealthStatus(r.Context())}
+
+	if status.Status != api.HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// cachedHealthStatus returns the overall status of the health checks, only running them again once the
+// previous result expired. Concurrent requests wait for the same run.
+func (s *Server) cachedHealthStatus(ctx context.Context) api.HealthStatus {
+	s.healthzMutex.Lock()
+	defer s.healthzMutex.Unlock()
+
+	if s.healthzStatus == "" || time.Since(s.healthzCheckedAt) >= healthzCacheDuration {
+		// Don't cache the outcome of checks interrupted by the client going away.
+		s.healthzStatus = runHealthChecks(context.WithoutCancel(ctx), s.state).Status
+		s.healthzCheckedAt = time.Now()
+	}
+
+	return s.healthzStatus
+}
+
+// ValidateHealthProbeAddress checks that the provided health probe address is usable.
+func ValidateHealthProbeAddress(address string) error {
+	if address == "" {
+		return nil
+	}
+
+	_, _, err := net.SplitHostPort(address)
+
+	return err
+}
+
+// ConfigureHealthProbe (re)starts the unauthenticated health probe listener on the provided
+// address, or stops it if the address is empty. Only the health endpoint is served on it. When
+// the address doesn't include a host, the listener is bound to the management address.
+func (s *Server) ConfigureHealthProbe(ctx context.Context, address string) error {
+	s.healthProbeMutex.Lock()
+	defer s.healthProbeMutex.Unlock()
+
+	err := ValidateHealthProbeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	address, err = s.resolveHealthProbeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	if s.healthProbe != nil && s.healthProbeAddress == address {
+		return nil
+	}
+
+	// Stop the existing listener.
+	if s.healthProbe != nil {
+		_ = s.healthProbe.Close()
+
+		s.healthProbe = nil
+		s.healthProbeAddress = ""
+	}
+
+	if address == "" {
+		return nil
+	}
+
+	lc := &net.ListenConfig{}
+
+	listener, err := lc.Listen(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("/healthz", s.apiHealthz)
+
+	server := &http.Server{
+		Handler: router,
+
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.WarnContext(ctx, "Health probe listener failed", "address", address, "err", err.Error())
+		}
+	}()
+
+	s.healthProbe = server
+	s.healthProbeAddress = address
+
+	return nil
+}
+
+// resolveHealthProbeAddress returns the address the health probe listens on, using the management
+// address when the provided one doesn't include a host.
+func (s *Server) resolveHealthProbeAddress(address string) (string, error) {
+	if address == "" {
+		return "", nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+
+	if host != "" {
+		return address, nil
+	}
+
+	mgmtAddr := s.state.ManagementAddress()
+	if mgmtAddr == nil {
+		return "", errors.New("no management address available for the health probe")
+	}
+
+	return net.JoinHostPort(mgmtAddr.String(), port), nil
+}
+
+// configureListeners (re)starts the health probe and remote API listeners for the provided configuration,
+// returning a function restoring the previous listeners.
+func (s *Server) configureListeners(healthProbeAddress string, remoteAPI *api.SystemSecurityRemoteAPI) (func(), error) {
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	previousHealthProbeAddress := s.state.System.Security.Config.HealthProbeAddress
+	previousRemoteAPI := s.state.System.Security.Config.RemoteAPI
+
+	err := s.ConfigureHealthProbe(context.Background(), healthProbeAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	reverter.Add(func() { _ = s.ConfigureHealthProbe(context.Background(), previousHealthProbeAddress) })
+
+	err = s.ConfigureRemoteAPI(context.Background(), remoteAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	reverter.Add(func() { _ = s.ConfigureRemoteAPI(context.Background(), previousRemoteAPI) })
+
+	cleanup := reverter.Clone().Fail
+
+	reverter.Success()
+
+	return cleanup, nil
+}
+
+// UpdateManagementListeners moves the listeners bound to the management address over to its current value,
+// such as after a network change.
+func (s *Server) UpdateManagementListeners(ctx context.Context) {
+	err := s.ConfigureHealthProbe(context.Background(), s.state.System.Security.Config.HealthProbeAddress)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to update the health probe listener", "err", err.Error())
+	}
+
+	err = s.ConfigureRemoteAPI(context.Background(), s.state.System.Security.Config.RemoteAPI)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to update the remote API listener", "err", err.Error())
+	}
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestResolveHealthProbeAddress(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{state: &state.State{}}
+
+	// Disabled.
+	address, err := s.resolveHealthProbeAddress("")
+	require.NoError(t, err)
+	require.Empty(t, address)
+
+	// Explicit hosts are kept as is.
+	address, err = s.resolveHealthProbeAddress("0.0.0.0:8080")
+	require.NoError(t, err)
+	require.Equal(t, "0.0.0.0:8080", address)
+
+	// No management address to default to.
+	_, err = s.resolveHealthProbeAddress(":8080")
+	require.EqualError(t, err, "no management address available for the health probe")
+
+	// Defaults to the management address.
+	s.state.System.Network.State.Interfaces = map[string]api.SystemNetworkInterfaceState{
+		"mgmt": {
+			Addresses: []string{"2001:db8::10", "192.0.2.10"},
+			Roles:     []string{api.SystemNetworkInterfaceRoleManagement},
+		},
+	}
+
+	address, err = s.resolveHealthProbeAddress(":8080")
+	require.NoError(t, err)
+	require.Equal(t, "[2001:db8::10]:8080", address)
+
+	// Invalid address.
+	_, err = s.resolveHealthProbeAddress("8080")
+	require.Error(t, err)
+}
+
+func TestHealthzCache(t *testing.T) { //nolint:paralleltest
+	runs := 0
+	status := api.HealthStatusOK
+
+	runHealthChecks = func(_ context.Context, _ *state.State) api.HealthReport {
+		runs++
+
+		return api.HealthReport{Status: status}
+	}
+
+	s := &Server{state: &state.State{}}
+
+	probe := func() int {
+		w := httptest.NewRecorder()
+		s.apiHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		return w.Code
+	}
+
+	// The checks only run once within the cache duration.
+	require.Equal(t, http.StatusOK, probe())
+
+	status = api.HealthStatusDegraded
+
+	require.Equal(t, http.StatusOK, probe())
+	require.Equal(t, 1, runs)
+
+	// They run again once the previous result expired.
+	s.healthzCheckedAt = time.Now().Add(-healthzCacheDuration)
+
+	require.Equal(t, http.StatusServiceUnavailable, probe())
+	require.Equal(t, 2, runs)
+}
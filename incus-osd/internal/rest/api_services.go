@@ -2,10 +2,13 @@ package rest
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"slices"
+	"strconv"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/services"
 )
@@ -43,7 +46,7 @@ import (
 //	          description: List of services
 //	          items:
 //	            type: string
-//	          example: ["/1.0/services/ceph","/1.0/services/iscsi","/1.0/services/linstor","/1.0/services/lvm","/1.0/services/multipath","/1.0/services/nvme","/1.0/services/ovn","/1.0/services/tailscale","/1.0/services/usbip"]
+//	          example: ["/1.0/services/ceph","/1.0/services/console","/1.0/services/iscsi","/1.0/services/kdump","/1.0/services/linstor","/1.0/services/lvm","/1.0/services/multipath","/1.0/services/nftables","/1.0/services/ntp","/1.0/services/nvme","/1.0/services/ovn","/1.0/services/ssh","/1.0/services/tailscale","/1.0/services/tuning","/1.0/services/usbip","/1.0/services/vrrp"]
 func (s *Server) apiServices(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -145,6 +148,8 @@ func (s *Server) apiServices(w http.ResponseWriter, r *http.Request) {
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "404":
 //	    $ref: "#/responses/NotFound"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
@@ -177,9 +182,38 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		_ = response.SyncResponse(true, resp).Render(w)
+		etag, err := serviceConfigETag(resp)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.SyncResponseETag(true, resp, etag).Render(w)
 
 	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		current, err := srv.Get(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		etag, err := serviceConfigETag(current)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		err = response.EtagCheck(r, etag)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
 		dest := srv.Struct()
 
 		decoder := json.NewDecoder(r.Body)
@@ -191,6 +225,8 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		wasEnabled := srv.ShouldStart()
+
 		err = srv.Update(r.Context(), dest)
 		if err != nil {
 			_ = response.InternalError(err).Render(w)
@@ -198,6 +234,8 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		s.sendServiceLifecycle(name, srv, wasEnabled)
+
 		_ = response.EmptySyncResponse.Render(w)
 	default:
 		_ = response.NotImplemented(nil).Render(w)
@@ -206,6 +244,26 @@ func (s *Server) apiServicesEndpoint(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serviceConfigETag returns the part of a service's state used to compute its ETag, so that only configuration
+// changes invalidate it.
+func serviceConfigETag(resp any) (any, error) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := struct {
+		Config any `json:"config"`
+	}{}
+
+	err = json.Unmarshal(data, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.Config, nil
+}
+
 // swagger:operation POST /1.0/services/{name}/:reset services services_post_reset
 //
 //	Forcefully reset service
@@ -265,3 +323,266 @@ func (s *Server) apiServicesEndpointReset(w http.ResponseWriter, r *http.Request
 		return
 	}
 }
+
+// swagger:operation POST /1.0/services/{name}/:restart services services_post_restart
+//
+//	Restart service
+//
+//	Stops and starts the service again, with its current configuration.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Service name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiServicesEndpointRestart(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// Check if the service is valid.
+	if !slices.Contains(services.Supported(s.state), name) {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	s.configMutex.Lock()
+	defer s.configMutex.Unlock()
+
+	// Load the service.
+	srv, err := services.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	if !srv.ShouldStart() {
+		_ = response.BadRequest(fmt.Errorf("service %q isn't enabled", name)).Render(w)
+
+		return
+	}
+
+	err = srv.Stop(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	err = srv.Start(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation GET /1.0/services/{name}/status services services_get_status
+//
+//	Get the service status
+//
+//	Returns the runtime status of the service, including the state of its systemd units and the reason of their most recent failure.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Service name
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    description: Service status
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Service status
+//	          example: {"status":"failed","units":[{"name":"iscsid.service","active_state":"failed","sub_state":"failed","result":"exit-code","restarts":0,"since":"2025-11-04T16:07:01Z"}],"last_failure":"iscsid.service: iscsid: Can not bind IPC socket"}
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiServicesEndpointStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// Check if the service is valid.
+	if !slices.Contains(services.Supported(s.state), name) {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	// Load the service.
+	srv, err := services.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	status, err := srv.Status(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, status).Render(w)
+}
+
+// swagger:operation GET /1.0/services/{name}/log services services_get_log
+//
+//	Get the service log
+//
+//	Returns the most recent journal entries of the systemd units used by the service since the system booted.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: path
+//	    name: name
+//	    description: Service name
+//	    required: true
+//	    type: string
+//	  - in: query
+//	    name: entries
+//	    description: Number of entries to return (defaults to 100)
+//	    required: false
+//	    type: integer
+//	responses:
+//	  "200":
+//	    description: Service log
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: Journal entries
+//	          items:
+//	            type: object
+//	          example: [{"time":"2025-11-04T16:07:01Z","unit":"iscsid.service","priority":3,"message":"iscsid: Can not bind IPC socket"}]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "404":
+//	    $ref: "#/responses/NotFound"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiServicesEndpointLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	name := r.PathValue("name")
+
+	// Check if the service is valid.
+	if !slices.Contains(services.Supported(s.state), name) {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	entries := 100
+
+	if r.FormValue("entries") != "" {
+		var err error
+
+		entries, err = strconv.Atoi(r.FormValue("entries"))
+		if err != nil || entries <= 0 {
+			_ = response.BadRequest(fmt.Errorf("invalid entries value %q", r.FormValue("entries"))).Render(w)
+
+			return
+		}
+	}
+
+	// Load the service.
+	srv, err := services.Load(r.Context(), s.state, name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	log, err := services.GetLog(r.Context(), srv, entries)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, log).Render(w)
+}
+
+// sendServiceLifecycle lets event subscribers know when an update started or stopped a service.
+func (s *Server) sendServiceLifecycle(name string, srv services.Service, wasEnabled bool) {
+	if !wasEnabled && srv.ShouldStart() {
+		s.state.Events.SendLifecycle(api.EventLifecycleServiceStarted, "/1.0/services/"+name, nil)
+	} else if wasEnabled && !srv.ShouldStart() {
+		s.state.Events.SendLifecycle(api.EventLifecycleServiceStopped, "/1.0/services/"+name, nil)
+	}
+}
@@ -40,7 +40,7 @@ import (
 //	          description: List of system endpoints
 //	          items:
 //	            type: string
-//	          example: ["/1.0/system/logging","/1.0/system/network","/1.0/system/provider","/1.0/system/resources","/1.0/system/security","/1.0/system/storage","/1.0/system/update"]
+//	          example: ["/1.0/system/dns","/1.0/system/history","/1.0/system/logging","/1.0/system/network","/1.0/system/notifications","/1.0/system/provider","/1.0/system/resources","/1.0/system/security","/1.0/system/self-check","/1.0/system/storage","/1.0/system/update","/1.0/system/warnings"]
 func (*Server) apiSystem(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -54,7 +54,7 @@ func (*Server) apiSystem(w http.ResponseWriter, r *http.Request) {
 
 	urls := []string{}
 
-	for _, system := range []string{"logging", "network", "provider", "resources", "security", "storage", "update"} {
+	for _, system := range []string{"dns", "history", "logging", "network", "notifications", "provider", "resources", "security", "self-check", "storage", "update", "warnings"} {
 		systemURL, _ := url.JoinPath(endpoint, system)
 		urls = append(urls, systemURL)
 	}
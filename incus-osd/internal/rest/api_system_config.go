@@ -0,0 +1,671 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/dns"
+	"github.com/lxc/incus-os/incus-osd/internal/notify"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/services"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// errConfigInvalid wraps errors caused by an invalid configuration document.
+var errConfigInvalid = errors.New("invalid configuration")
+
+// swagger:operation POST /1.0/system/:export-config system system_post_export_config
+//
+//	Export the system configuration
+//
+//	Returns the declarative configuration of the system as a single YAML document, holding the network, DNS, logging,
+//	notifications, provider, security, update, services and applications configurations. The document can be imported
+//	back onto the same or another system.
+//
+//	The credentials are exported in plain text, unless redacted. The encryption recovery keys are never exported.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/yaml
+//	parameters:
+//	  - in: body
+//	    name: options
+//	    description: Export options
+//	    required: false
+//	    schema:
+//	      type: object
+//	      example: {"redact_secrets":true}
+//	responses:
+//	  "200":
+//	    description: YAML configuration document
+//	    schema:
+//	      type: file
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemExportConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemConfigExport{}
+
+	counter := &countWrapper{ReadCloser: r.Body}
+
+	err := json.NewDecoder(counter).Decode(req)
+	if err != nil && (!errors.Is(err, io.EOF) || counter.n > 0) {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	config, err := s.exportSystemConfig(req.RedactSecrets)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+
+	_, err = w.Write(content)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+}
+
+// swagger:operation POST /1.0/system/:import-config system system_post_import_config
+//
+//	Import a system configuration
+//
+//	Applies a configuration document, as returned by the export, provided either as YAML or JSON. Sections which aren't
+//	set, as well as those matching the current configuration, are left unchanged. Redacted credentials keep their current
+//	value.
+//
+//	The whole document is validated before any change is made. The network, services, update and applications sections
+//	are then applied as a single transaction, followed by the other sections.
+//
+//	---
+//	consumes:
+//	  - application/yaml
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: config
+//	    description: Configuration document
+//	    required: true
+//	    schema:
+//	      type: object
+//	      example: {"version":"1","logging":{"syslog":{"address":"10.0.0.5","protocol":"udp"}},"services":{"iscsi":{"enabled":true}},"applications":{"incus":{}}}
+//	responses:
+//	  "200":
+//	    description: The applied sections
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: The applied sections
+//	          items:
+//	            type: string
+//	          example: ["logging","services"]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemImportConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// JSON documents are valid YAML, so both are parsed the same way.
+	config := &api.SystemConfig{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(config)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	applied, err := s.importSystemConfig(r.Context(), config)
+	if err != nil {
+		if errors.Is(err, errConfigInvalid) {
+			_ = response.BadRequest(err).Render(w)
+		} else {
+			_ = response.InternalError(err).Render(w)
+		}
+
+		_ = s.state.Save()
+
+		return
+	}
+
+	_ = response.SyncResponse(true, applied).Render(w)
+}
+
+// exportSystemConfig returns the declarative configuration of the system, with its credentials either decrypted or redacted.
+func (s *Server) exportSystemConfig(redact bool) (*api.SystemConfig, error) {
+	// Work on a copy of the configuration, so the credentials can be decrypted without touching the state.
+	data, err := json.Marshal(map[string]any{"applications": s.state.CopyApplications(), "services": s.state.Services, "system": s.state.System})
+	if err != nil {
+		return nil, err
+	}
+
+	configState := &state.State{}
+
+	err = json.Unmarshal(data, configState)
+	if err != nil {
+		return nil, err
+	}
+
+	if redact {
+		configState.System.Network.Config = secrets.RedactNetworkConfig(configState.System.Network.Config)
+		configState.System.Provider.Config = secrets.RedactProviderConfig(configState.System.Provider.Config)
+		configState.System.Notifications.Config = secrets.RedactNotificationsConfig(configState.System.Notifications.Config)
+		configState.System.DNS.Config = secrets.RedactDNSConfig(configState.System.DNS.Config)
+		configState.Services.ISCSI.Config = secrets.RedactISCSIConfig(configState.Services.ISCSI.Config)
+		configState.Services.Ceph.Config = secrets.RedactCephConfig(configState.Services.Ceph.Config)
+		configState.Services.OVN.Config = secrets.RedactOVNConfig(configState.Services.OVN.Config)
+		configState.Services.SSH.Config = secrets.RedactSSHConfig(configState.Services.SSH.Config)
+
+		if configState.System.Update.Config.VerificationProvider != nil {
+			verificationProvider := secrets.RedactProviderConfig(*configState.System.Update.Config.VerificationProvider)
+			configState.System.Update.Config.VerificationProvider = &verificationProvider
+		}
+	} else {
+		err = secrets.OpenState(configState)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config := &api.SystemConfig{
+		Version:       api.SystemConfigVersion,
+		Network:       configState.System.Network.Config,
+		DNS:           &configState.System.DNS.Config,
+		Logging:       &configState.System.Logging.Config,
+		Notifications: &configState.System.Notifications.Config,
+		Provider:      &configState.System.Provider.Config,
+		Security:      &configState.System.Security.Config,
+		Update:        &configState.System.Update.Config,
+		Services:      map[string]map[string]any{},
+		Applications:  map[string]api.ApplicationConfig{},
+	}
+
+	config.Security.EncryptionRecoveryKeys = nil
+
+	// The services are keyed by name in the state, so go through YAML to get each configuration as a generic map
+	// while keeping integers as such.
+	data, err = json.Marshal(configState.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceConfigs := map[string]struct {
+		Config map[string]any `yaml:"config"`
+	}{}
+
+	err = yaml.Unmarshal(data, &serviceConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range services.Supported(s.state) {
+		config.Services[name] = serviceConfigs[name].Config
+	}
+
+	for name, app := range configState.Applications {
+		config.Applications[name] = app.Config
+	}
+
+	return config, nil
+}
+
+// importSystemConfig validates then applies a configuration document, returning the sections which were changed.
+func (s *Server) importSystemConfig(ctx context.Context, config *api.SystemConfig) ([]string, error) {
+	if config.Version != "" && config.Version != api.SystemConfigVersion {
+		return nil, fmt.Errorf("%w: unsupported version %q", errConfigInvalid, config.Version)
+	}
+
+	current, err := s.exportSystemConfig(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate all the changed sections before changing anything.
+	var networkConfig *api.SystemNetworkConfig
+
+	if config.Network != nil && configSectionChanged(config.Network, current.Network) {
+		// Don't allow a new configuration that doesn't define any interfaces, bonds, or vlans.
+		if seed.NetworkConfigHasEmptyDevices(*config.Network) {
+			return nil, fmt.Errorf("%w: network configuration has no devices defined", errConfigInvalid)
+		}
+
+		networkConfig = config.Network
+	}
+
+	serviceConfigs := map[string]any{}
+
+	for name, serviceConfig := range config.Services {
+		if !slices.Contains(services.Supported(s.state), name) {
+			return nil, fmt.Errorf("%w: unsupported service %q", errConfigInvalid, name)
+		}
+
+		if !configSectionChanged(serviceConfig, current.Services[name]) {
+			continue
+		}
+
+		srv, err := services.Load(ctx, s.state, name)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(map[string]any{"config": serviceConfig})
+		if err != nil {
+			return nil, err
+		}
+
+		dest := srv.Struct()
+
+		err = json.Unmarshal(data, dest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid configuration for service %q: %w", errConfigInvalid, name, err)
+		}
+
+		serviceConfigs[name] = dest
+	}
+
+	var updateConfig *api.SystemUpdateConfig
+
+	if config.Update != nil && configSectionChanged(config.Update, current.Update) {
+		updateConfig = config.Update
+
+		err = s.sealUpdateConfig(updateConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.validateUpdateConfig(ctx, *updateConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errConfigInvalid, err)
+		}
+	}
+
+	newApplications := []string{}
+	changedApplications := map[string]api.ApplicationConfig{}
+
+	for _, name := range slices.Sorted(maps.Keys(config.Applications)) {
+		appConfig := config.Applications[name]
+
+		_, err := applications.Load(ctx, s.state, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid application %q: %w", errConfigInvalid, name, err)
+		}
+
+		err = validateApplicationConfig(appConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: application %q: %w", errConfigInvalid, name, err)
+		}
+
+		_, exists := s.state.Applications[name]
+		if !exists {
+			newApplications = append(newApplications, name)
+		}
+
+		if !exists || configSectionChanged(appConfig, current.Applications[name]) {
+			changedApplications[name] = appConfig
+		}
+	}
+
+	var dnsConfig *api.SystemDNSConfig
+
+	if config.DNS != nil && configSectionChanged(config.DNS, current.DNS) {
+		dnsConfig = config.DNS
+
+		err = secrets.SealDNSConfig(dnsConfig, s.state.System.DNS.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		err = dns.ValidateConfig(ctx, *dnsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errConfigInvalid, err)
+		}
+	}
+
+	var loggingConfig *api.SystemLoggingConfig
+
+	if config.Logging != nil && configSectionChanged(config.Logging, current.Logging) {
+		loggingConfig = config.Logging
+	}
+
+	var notificationsConfig *api.SystemNotificationsConfig
+
+	if config.Notifications != nil && configSectionChanged(config.Notifications, current.Notifications) {
+		notificationsConfig = config.Notifications
+
+		err = secrets.SealNotificationsConfig(notificationsConfig, s.state.System.Notifications.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		err = notify.ValidateConfig(ctx, *notificationsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errConfigInvalid, err)
+		}
+	}
+
+	var securityConfig *api.SystemSecurityConfig
+
+	if config.Security != nil {
+		// The encryption recovery keys are specific to each system.
+		config.Security.EncryptionRecoveryKeys = nil
+
+		if configSectionChanged(config.Security, current.Security) {
+			securityConfig = config.Security
+
+			err = systemd.ValidateNetworkUnlock(securityConfig.NetworkUnlock)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errConfigInvalid, err)
+			}
+
+			err = ValidateHealthProbeAddress(securityConfig.HealthProbeAddress)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errConfigInvalid, err)
+			}
+
+			err = ValidateRemoteAPI(securityConfig.RemoteAPI)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errConfigInvalid, err)
+			}
+		}
+	}
+
+	var providerConfig *api.SystemProviderConfig
+
+	if config.Provider != nil && configSectionChanged(config.Provider, current.Provider) {
+		providerConfig = config.Provider
+	}
+
+	// Apply the network, services, update and new applications as a single transaction.
+	slog.InfoContext(ctx, "Importing system configuration")
+
+	applied := []string{}
+
+	err = s.applyProfile(ctx, networkConfig, serviceConfigs, updateConfig, newApplications)
+	if err != nil {
+		return nil, err
+	}
+
+	if networkConfig != nil {
+		applied = append(applied, "network")
+	}
+
+	if len(serviceConfigs) > 0 {
+		applied = append(applied, "services")
+	}
+
+	if updateConfig != nil {
+		applied = append(applied, "update")
+	}
+
+	// Then apply the other sections.
+	for _, name := range slices.Sorted(maps.Keys(changedApplications)) {
+		err = s.applyApplicationConfig(ctx, name, changedApplications[name])
+		if err != nil {
+			return applied, fmt.Errorf("failed to configure application %q: %w", name, err)
+		}
+	}
+
+	if len(changedApplications) > 0 {
+		applied = append(applied, "applications")
+	}
+
+	if dnsConfig != nil {
+		s.state.System.DNS.Config = *dnsConfig
+		s.state.System.DNS.State = api.SystemDNSState{}
+
+		err = dns.UpdateDynamicRecords(ctx, s.state)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to update the dynamic DNS records", "err", err)
+		}
+
+		applied = append(applied, "dns")
+	}
+
+	if loggingConfig != nil {
+		err = systemd.SetSyslog(ctx, loggingConfig.Syslog)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply logging configuration: %w", err)
+		}
+
+		s.state.System.Logging.Config = *loggingConfig
+
+		applied = append(applied, "logging")
+	}
+
+	if notificationsConfig != nil {
+		s.state.System.Notifications.Config = *notificationsConfig
+
+		applied = append(applied, "notifications")
+	}
+
+	if securityConfig != nil {
+		err = s.applySecurityConfig(ctx, *securityConfig)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply security configuration: %w", err)
+		}
+
+		applied = append(applied, "security")
+	} else if networkConfig != nil {
+		// Move the listeners over to the new management address.
+		s.UpdateManagementListeners(ctx)
+	}
+
+	if providerConfig != nil {
+		err = s.applyProvider(ctx, *providerConfig)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply provider configuration: %w", err)
+		}
+
+		applied = append(applied, "provider")
+	}
+
+	_ = s.state.Save()
+
+	// Trigger a manual update check to install the new applications.
+	if len(newApplications) > 0 {
+		s.state.RequestUpdate()
+	}
+
+	s.state.Events.SendLifecycle(api.EventLifecycleSystemConfigImported, "/1.0/system", map[string]any{"sections": applied})
+
+	return applied, nil
+}
+
+// applyApplicationConfig replaces the configuration of an application, delivering changed settings right away if it's running.
+func (s *Server) applyApplicationConfig(ctx context.Context, name string, config api.ApplicationConfig) error {
+	appInfo := s.state.Applications[name]
+
+	if appInfo.State.Initialized && !maps.Equal(appInfo.Config.Settings, config.Settings) {
+		app, err := applications.Load(ctx, s.state, name)
+		if err != nil {
+			return err
+		}
+
+		if app.IsRunning(ctx) {
+			err = app.ApplySettings(ctx, config.Settings)
+			if err != nil {
+				return fmt.Errorf("failed to apply settings: %w", err)
+			}
+		}
+	}
+
+	appInfo.Config = config
+	s.state.SetApplication(name, appInfo)
+
+	return nil
+}
+
+// applySecurityConfig applies the portable security settings, leaving the encryption recovery keys untouched.
+func (s *Server) applySecurityConfig(ctx context.Context, config api.SystemSecurityConfig) error {
+	// Start the listeners first, as they can easily be restored should anything else fail.
+	revertListeners, err := s.configureListeners(config.HealthProbeAddress, config.RemoteAPI)
+	if err != nil {
+		return err
+	}
+
+	// Apply the network unlock binding before any other setting, so a failure leaves them untouched.
+	err = s.applyNetworkUnlock(ctx, config.NetworkUnlock)
+	if err != nil {
+		revertListeners()
+
+		return err
+	}
+
+	s.state.System.Security.Config.HealthProbeAddress = config.HealthProbeAddress
+	s.state.System.Security.Config.RemoteAPI = config.RemoteAPI
+
+	s.state.System.Security.Config.AutoRepairBootOrder = config.AutoRepairBootOrder
+	s.state.System.Security.Config.AutoTPMRebind = config.AutoTPMRebind
+
+	if config.RestrictDebug != s.state.System.Security.Config.RestrictDebug {
+		slog.InfoContext(ctx, "Debug access policy changed", "restricted", config.RestrictDebug)
+	}
+
+	s.state.System.Security.Config.RestrictDebug = config.RestrictDebug
+
+	return nil
+}
+
+// configSectionChanged checks whether an imported configuration section differs from the current one. Redacted
+// credentials are compared as if they held the current value.
+func configSectionChanged(imported any, current any) bool {
+	importedTree, err := configTree(imported)
+	if err != nil {
+		return true
+	}
+
+	currentTree, err := configTree(current)
+	if err != nil {
+		return true
+	}
+
+	importedData, err := json.Marshal(unredactTree(importedTree, currentTree))
+	if err != nil {
+		return true
+	}
+
+	currentData, err := json.Marshal(currentTree)
+	if err != nil {
+		return true
+	}
+
+	return !bytes.Equal(importedData, currentData)
+}
+
+// configTree returns the generic representation of a configuration, as decoded from its JSON encoding.
+func configTree(config any) (any, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree any
+
+	err = json.Unmarshal(data, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// unredactTree replaces the redacted values of an imported configuration with those at the same place in the current one.
+func unredactTree(imported any, current any) any {
+	switch value := imported.(type) {
+	case string:
+		if value == secrets.Redacted {
+			return current
+		}
+
+	case map[string]any:
+		currentMap, _ := current.(map[string]any)
+
+		for k, v := range value {
+			value[k] = unredactTree(v, currentMap[k])
+		}
+
+	case []any:
+		currentList, _ := current.([]any)
+
+		for i, v := range value {
+			if i < len(currentList) {
+				value[i] = unredactTree(v, currentList[i])
+			}
+		}
+	}
+
+	return imported
+}
@@ -0,0 +1,177 @@
+package rest
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/dns"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+)
+
+// swagger:operation GET /1.0/system/dns system system_get_dns
+//
+//	Get DNS providers information
+//
+//	Returns the current DNS providers and dynamic records, along with the state of the dynamic records.
+//	Provider credentials are redacted.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: State and configuration for the DNS providers
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: State and configuration for the DNS providers
+//	          example: {"config":{"providers":[{"name":"cf","type":"cloudflare","zone":"example.com","config":{"api_token":"********"}}],"dynamic_records":[{"provider":"cf","name":"server01.example.com","interface":"","ttl":300}]},"state":{"dynamic_records":[{"name":"server01.example.com","addresses":["192.0.2.10","2001:db8::10"],"last_update":"2025-11-04T16:07:01Z"}]}}
+
+// swagger:operation PUT /1.0/system/dns system system_put_dns
+//
+//	Update DNS providers configuration
+//
+//	Updates the DNS providers and dynamic records. Credentials are encrypted before being stored,
+//	redacted credentials are left unchanged.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: DNS providers configuration
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The DNS providers configuration
+//	          example: {"providers":[{"name":"cf","type":"cloudflare","zone":"example.com","config":{"api_token":"secret"}}],"dynamic_records":[{"provider":"cf","name":"server01.example.com"}]}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/system/dns system system_patch_dns
+//
+//	Partially update system dynamic DNS configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system dynamic DNS configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: DNS providers configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"dynamic_records":[{"provider":"cf","name":"server01.example.com"}]}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemDNS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the current DNS state, without any credentials.
+		_ = response.SyncResponseETag(true, api.SystemDNS{
+			Config: secrets.RedactDNSConfig(s.state.System.DNS.Config),
+			State:  s.state.System.DNS.State,
+		}, s.state.System.DNS.Config).Render(w)
+	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.DNS.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
+		dnsData := &api.SystemDNS{}
+
+		err = json.NewDecoder(r.Body).Decode(dnsData)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Encrypt the credentials.
+		err = secrets.SealDNSConfig(&dnsData.Config, s.state.System.DNS.Config)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Validate the new configuration.
+		err = dns.ValidateConfig(r.Context(), dnsData.Config)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Persist the configuration.
+		s.state.System.DNS.Config = dnsData.Config
+		s.state.System.DNS.State = api.SystemDNSState{}
+
+		// Apply the dynamic records right away.
+		err = dns.UpdateDynamicRecords(r.Context(), s.state)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to update the dynamic DNS records", "err", err)
+		}
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+
+	_ = s.state.Save()
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// swagger:operation GET /1.0/system/history system system_get_history
+//
+//	Get the configuration change history
+//
+//	Returns the changes made to the system configuration through the API, oldest first. Each entry holds the time of the
+//	change, the identity of the caller, the called endpoint and the list of changed values. Credentials are redacted, so
+//	changing them isn't recorded. Entries can be filtered by endpoint prefix, time range and number of returned entries.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: endpoint
+//	    description: Limit history entries to endpoints starting with the specified prefix
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: since
+//	    description: Limit history entries to those recorded after the specified time (RFC3339)
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: until
+//	    description: Limit history entries to those recorded before the specified time (RFC3339)
+//	    required: false
+//	    type: string
+//	  - in: query
+//	    name: entries
+//	    description: Limit history entries to the specified number of most recent entries
+//	    required: false
+//	    type: integer
+//	responses:
+//	  "200":
+//	    description: History entries
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of history entries
+//	          items:
+//	            type: object
+//	          example: [{"time":"2025-11-04T16:12:43.51283Z","source":"local","identity":"uid=0 gid=0 pid=1234 comm=incusd","method":"PATCH","endpoint":"/1.0/system/update","changes":[{"path":"/update/channel","old":"stable","new":"testing"}]}]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	var since time.Time
+
+	if r.FormValue("since") != "" {
+		var err error
+
+		since, err = time.Parse(time.RFC3339, r.FormValue("since"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	var until time.Time
+
+	if r.FormValue("until") != "" {
+		var err error
+
+		until, err = time.Parse(time.RFC3339, r.FormValue("until"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	limit := 0
+
+	if r.FormValue("entries") != "" {
+		var err error
+
+		limit, err = strconv.Atoi(r.FormValue("entries"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	entries, err := s.getHistoryEntries(r.FormValue("endpoint"), since, until, limit)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, entries).Render(w)
+}
@@ -70,6 +70,43 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/system/logging system system_patch_logging
+//
+//	Partially update system logging configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system logging configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Logging configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"syslog":{"address":"192.0.2.10"}}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemLogging(w http.ResponseWriter, r *http.Request) {
@@ -78,11 +115,19 @@ func (s *Server) apiSystemLogging(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// Return the current logging state.
-		_ = response.SyncResponse(true, s.state.System.Logging).Render(w)
+		_ = response.SyncResponseETag(true, s.state.System.Logging, s.state.System.Logging.Config).Render(w)
 	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.Logging.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
 		loggingData := &api.SystemLogging{}
 
-		err := json.NewDecoder(r.Body).Decode(loggingData)
+		err = json.NewDecoder(r.Body).Decode(loggingData)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
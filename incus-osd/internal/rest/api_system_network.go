@@ -8,9 +8,12 @@ import (
 	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
-	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/netcheck"
+	"github.com/lxc/incus-os/incus-osd/internal/proxy"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/services"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
 
@@ -77,6 +80,43 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/system/network system system_patch_network
+//
+//	Partially update system network configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system network configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Network configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"dns":{"nameservers":["192.0.2.53"]}}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
@@ -101,14 +141,25 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			s.state.System.Network.Config.Time.Timezone = "UTC"
 		}
 
-		// Return the current network state.
-		_ = response.SyncResponse(true, s.state.System.Network).Render(w)
+		// Return the current network state, without any credentials.
+		network := s.state.System.Network
+		network.Config = secrets.RedactNetworkConfig(network.Config)
+
+		_ = response.SyncResponseETag(true, network, s.state.System.Network.Config).Render(w)
 	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.Network.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
 		// Replace the existing network configuration.
 		newConfig := &api.SystemNetwork{}
 
 		// Populate the network configuration from request's body.
-		err := json.NewDecoder(r.Body).Decode(newConfig)
+		err = json.NewDecoder(r.Body).Decode(newConfig)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
@@ -124,7 +175,7 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 
 		slog.InfoContext(r.Context(), "Applying new network configuration")
 
-		err = systemd.ApplyNetworkConfiguration(r.Context(), s.state, newConfig.Config, 30*time.Second, false, providers.Refresh)
+		err = systemd.ApplyNetworkConfiguration(r.Context(), s.state, newConfig.Config, 30*time.Second, false, services.RefreshNetwork)
 		if err != nil {
 			slog.ErrorContext(r.Context(), "Failed to update network configuration: "+err.Error())
 			_ = response.InternalError(err).Render(w)
@@ -132,6 +183,9 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Move the listeners over to the new management address.
+		s.UpdateManagementListeners(r.Context())
+
 		_ = response.EmptySyncResponse.Render(w)
 		_ = s.state.Save()
 	default:
@@ -139,3 +193,104 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 		_ = response.NotImplemented(nil).Render(w)
 	}
 }
+
+// swagger:operation GET /1.0/system/network/proxy-log system system_get_network_proxy_log
+//
+//	Get the local proxy log
+//
+//	Returns the recent entries of the local proxy's log, along with request and failure counters for each destination since the system booted.
+//	This helps telling whether update failures are caused by the upstream proxy, such as when it rejects CONNECT requests.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Local proxy log
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Local proxy log
+//	          example: {"entries":[{"time":"2025-11-05T10:12:43Z","method":"CONNECT","destination":"images.linuxcontainers.org:443","failed":true,"message":"CONNECT images.linuxcontainers.org:443: 407 Proxy Authentication Required"}],"destinations":[{"destination":"images.linuxcontainers.org:443","requests":12,"failures":1,"last_failure":"2025-11-05T10:12:43Z","last_error":"CONNECT images.linuxcontainers.org:443: 407 Proxy Authentication Required"}]}
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (*Server) apiSystemNetworkProxyLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	proxyLog, err := proxy.GetLog(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, proxyLog).Render(w)
+}
+
+// swagger:operation POST /1.0/system/network/test system system_post_network_test
+//
+//	Test network reachability
+//
+//	Verifies DNS resolution, default gateway reachability, NTP synchronization and provider reachability
+//	through the configured proxy, returning the outcome of each check.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Network test report
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Network test report
+//	          example: {"time":"2025-11-05T10:12:43Z","passed":false,"results":[{"name":"dns","passed":true,"message":"images.linuxcontainers.org resolves to 2602:fc62:a:1::7, 45.45.148.7"},{"name":"gateway","passed":true,"message":"reachable gateways: 10.234.136.1 (enp5s0)"},{"name":"ntp","passed":true,"message":"system clock is synchronized"},{"name":"provider","passed":false,"message":"failed to reach https://images.linuxcontainers.org/os: proxyconnect tcp: dial tcp 10.0.0.5:3128: connect: connection refused"}]}
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemNetworkTest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, netcheck.Run(r.Context(), s.state)).Render(w)
+}
@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/notify"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+)
+
+// swagger:operation GET /1.0/system/notifications system system_get_notifications
+//
+//	Get notifications information
+//
+//	Returns the current notification backends and routing rules.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: State and configuration for the system notifications
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: State and configuration for the system notifications
+//	          example: {"config":{"backends":[{"name":"ops","type":"webhook","config":{"url":"https://example.com/hook"}}],"routes":[{"events":["update-failed","system-disk-error"],"backends":["ops"]}]},"state":{}}
+
+// swagger:operation PUT /1.0/system/notifications system system_put_notifications
+//
+//	Update system notifications configuration
+//
+//	Updates the notification backends and the rules routing events to them.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Notifications configuration
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The notifications configuration
+//	          example: {"backends":[{"name":"ops","type":"webhook","config":{"url":"https://example.com/hook"}}],"routes":[{"events":["update-failed","system-disk-error"],"backends":["ops"]}]}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/system/notifications system system_patch_notifications
+//
+//	Partially update system notifications configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system notifications configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Notifications configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"routes":[{"events":["update-failed"],"backends":["ops"]}]}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemNotifications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the current notifications state, without any credentials.
+		notifications := s.state.System.Notifications
+		notifications.Config = secrets.RedactNotificationsConfig(notifications.Config)
+
+		_ = response.SyncResponseETag(true, notifications, s.state.System.Notifications.Config).Render(w)
+	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.Notifications.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
+		notificationsData := &api.SystemNotifications{}
+
+		err = json.NewDecoder(r.Body).Decode(notificationsData)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Seal the credentials, keeping any which were left redacted.
+		err = secrets.SealNotificationsConfig(&notificationsData.Config, s.state.System.Notifications.Config)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Validate the new configuration.
+		err = notify.ValidateConfig(r.Context(), notificationsData.Config)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Persist the configuration.
+		s.state.System.Notifications.Config = notificationsData.Config
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+
+	_ = s.state.Save()
+}
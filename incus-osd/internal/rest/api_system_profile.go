@@ -0,0 +1,266 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/revert"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/services"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// swagger:operation POST /1.0/system/:apply-profile system system_post_apply_profile
+//
+//	Apply a node profile
+//
+//	Applies a combined network, proxy, services, update and applications configuration in a single call. The whole profile
+//	is validated before any change is made, then applied as a single transaction: if any part fails to apply, the parts
+//	already applied are reverted to their previous configuration. Sections which aren't set are left unchanged.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: profile
+//	    description: Node profile
+//	    required: true
+//	    schema:
+//	      type: object
+//	      example: {"network":{"interfaces":[{"name":"enp5s0","addresses":["dhcp4","slaac"],"hwaddr":"10:66:6a:e5:f6:0d","roles":["management","cluster"]}]},"proxy":{"servers":{"corporate":{"host":"proxy.example.com:3128","auth":"anonymous"}},"rules":[{"destination":"*","target":"corporate"}]},"services":{"iscsi":{"config":{"enabled":true}}},"update":{"auto_reboot":false,"channel":"stable","check_frequency":"6h"},"applications":["incus"]}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemApplyProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	profile := &api.SystemProfile{}
+
+	err := json.NewDecoder(r.Body).Decode(profile)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Validate the whole profile before changing anything.
+	var networkConfig *api.SystemNetworkConfig
+
+	if profile.Network != nil || profile.Proxy != nil {
+		networkConfig = profile.Network
+		if networkConfig == nil {
+			networkConfig = &api.SystemNetworkConfig{}
+
+			if s.state.System.Network.Config != nil {
+				*networkConfig = *s.state.System.Network.Config
+			}
+		}
+
+		if profile.Proxy != nil {
+			networkConfig.Proxy = profile.Proxy
+		}
+
+		// Don't allow a new configuration that doesn't define any interfaces, bonds, or vlans.
+		if seed.NetworkConfigHasEmptyDevices(*networkConfig) {
+			_ = response.BadRequest(errors.New("network configuration has no devices defined")).Render(w)
+
+			return
+		}
+	}
+
+	serviceConfigs := map[string]any{}
+
+	for name, config := range profile.Services {
+		if !slices.Contains(services.Supported(s.state), name) {
+			_ = response.BadRequest(fmt.Errorf("unsupported service %q", name)).Render(w)
+
+			return
+		}
+
+		srv, err := services.Load(r.Context(), s.state, name)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		dest := srv.Struct()
+
+		err = json.Unmarshal(config, dest)
+		if err != nil {
+			_ = response.BadRequest(fmt.Errorf("invalid configuration for service %q: %w", name, err)).Render(w)
+
+			return
+		}
+
+		serviceConfigs[name] = dest
+	}
+
+	if profile.Update != nil {
+		err = s.sealUpdateConfig(profile.Update)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		err = s.validateUpdateConfig(r.Context(), *profile.Update)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	newApplications := []string{}
+
+	for _, name := range profile.Applications {
+		if name == "" {
+			_ = response.BadRequest(errors.New("missing application name")).Render(w)
+
+			return
+		}
+
+		_, err := applications.Load(r.Context(), s.state, name)
+		if err != nil {
+			_ = response.BadRequest(fmt.Errorf("invalid application %q: %w", name, err)).Render(w)
+
+			return
+		}
+
+		_, exists := s.state.Applications[name]
+		if !exists && !slices.Contains(newApplications, name) {
+			newApplications = append(newApplications, name)
+		}
+	}
+
+	// Apply the profile.
+	slog.InfoContext(r.Context(), "Applying node profile")
+
+	err = s.applyProfile(r.Context(), networkConfig, serviceConfigs, profile.Update, newApplications)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to apply node profile: "+err.Error())
+		_ = response.InternalError(err).Render(w)
+		_ = s.state.Save()
+
+		return
+	}
+
+	if networkConfig != nil {
+		// Move the listeners over to the new management address.
+		s.UpdateManagementListeners(r.Context())
+	}
+
+	// Trigger a manual update check to install the new applications.
+	if len(newApplications) > 0 {
+		s.state.RequestUpdate()
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+	_ = s.state.Save()
+}
+
+// applyProfile applies the validated parts of a node profile, reverting those already applied if any fails.
+func (s *Server) applyProfile(ctx context.Context, networkConfig *api.SystemNetworkConfig, serviceConfigs map[string]any, updateConfig *api.SystemUpdateConfig, newApplications []string) error {
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	// Apply the network configuration first, as services may depend on it.
+	if networkConfig != nil {
+		previousConfig := s.state.System.Network.Config
+		if previousConfig != nil {
+			reverter.Add(func() {
+				err := systemd.ApplyNetworkConfiguration(ctx, s.state, previousConfig, 30*time.Second, false, services.RefreshNetwork)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to restore the previous network configuration", "err", err.Error())
+				}
+			})
+		}
+
+		err := systemd.ApplyNetworkConfiguration(ctx, s.state, networkConfig, 30*time.Second, false, services.RefreshNetwork)
+		if err != nil {
+			return fmt.Errorf("failed to apply network configuration: %w", err)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(serviceConfigs)) {
+		srv, err := services.Load(ctx, s.state, name)
+		if err != nil {
+			return err
+		}
+
+		// Keep a copy of the current configuration to restore it on failure.
+		current, err := srv.Get(ctx)
+		if err != nil {
+			return err
+		}
+
+		previousConfig := srv.Struct()
+
+		data, err := json.Marshal(current)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(data, previousConfig)
+		if err != nil {
+			return err
+		}
+
+		reverter.Add(func() {
+			err := srv.Update(ctx, previousConfig)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to restore the previous service configuration", "service", name, "err", err.Error())
+			}
+		})
+
+		wasEnabled := srv.ShouldStart()
+
+		err = srv.Update(ctx, serviceConfigs[name])
+		if err != nil {
+			return fmt.Errorf("failed to update service %q: %w", name, err)
+		}
+
+		s.sendServiceLifecycle(name, srv, wasEnabled)
+	}
+
+	if updateConfig != nil {
+		previousConfig := s.state.System.Update.Config
+		reverter.Add(func() { s.state.System.Update.Config = previousConfig })
+
+		s.state.System.Update.Config = *updateConfig
+	}
+
+	for _, name := range newApplications {
+		s.state.SetApplication(name, api.Application{})
+		reverter.Add(func() { s.state.DeleteApplication(name) })
+	}
+
+	reverter.Success()
+
+	return nil
+}
@@ -2,12 +2,14 @@ package rest
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 )
 
 // swagger:operation GET /1.0/system/provider system system_get_provider
@@ -41,7 +43,7 @@ import (
 //	        metadata:
 //	          type: json
 //	          description: State and configuration for the system provider
-//	          example: {"config":{"name":"images","config":null},"state":{"registered":false}}
+//	          example: {"config":{"name":"images","config":null},"state":{"registered":false,"last_check":"2025-11-04T16:21:34.929524792Z","latest_version":"202511041601","rate_limited_until":"0001-01-01T00:00:00Z","last_error":""}}
 
 // swagger:operation PUT /1.0/system/provider system system_put_provider
 //
@@ -71,6 +73,43 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/system/provider system system_patch_provider
+//
+//	Partially update system provider configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system provider configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Provider configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"name":"local"}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemProvider(w http.ResponseWriter, r *http.Request) {
@@ -78,21 +117,56 @@ func (s *Server) apiSystemProvider(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		// Return the current system provider state.
-		_ = response.SyncResponse(true, s.state.System.Provider).Render(w)
+		// Return the current system provider state, without any credentials.
+		provider := s.state.System.Provider
+		provider.Config = secrets.RedactProviderConfig(provider.Config)
+
+		_ = response.SyncResponseETag(true, provider, s.state.System.Provider.Config).Render(w)
 	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.Provider.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
 		// Apply a new system provider configuration.
 		newConfig := &api.SystemProvider{}
 		oldConfig := s.state.System.Provider.Config
 
 		// Update the system provider configuration from request's body.
-		err := json.NewDecoder(r.Body).Decode(newConfig)
+		err = json.NewDecoder(r.Body).Decode(newConfig)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
 			return
 		}
 
+		// Keep the provider usable along with the verification provider, if any.
+		verificationProvider := s.state.System.Update.Config.VerificationProvider
+		if verificationProvider != nil {
+			if newConfig.Config.Name == verificationProvider.Name {
+				_ = response.BadRequest(errors.New("provider must differ from the verification provider")).Render(w)
+
+				return
+			}
+
+			if !providers.SupportsVerification(newConfig.Config.Name) {
+				_ = response.BadRequest(errors.New("the local and share providers can't be used for verification")).Render(w)
+
+				return
+			}
+		}
+
+		// Seal the credentials, keeping any which were left redacted.
+		err = secrets.SealProviderConfig(&newConfig.Config, oldConfig)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
 		// Load the current provider and deregister it.
 		p, err := providers.Load(r.Context(), s.state)
 		if err != nil {
@@ -133,7 +207,7 @@ func (s *Server) apiSystemProvider(w http.ResponseWriter, r *http.Request) {
 		// We've successfully registered.
 		slog.InfoContext(r.Context(), "Server registered with the provider")
 
-		s.state.System.Provider.State.Registered = true
+		s.state.System.Provider.State = api.SystemProviderState{Registered: true}
 		_ = s.state.Save()
 
 		_ = response.EmptySyncResponse.Render(w)
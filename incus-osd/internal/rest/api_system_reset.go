@@ -15,6 +15,8 @@ import (
 //
 //	Factory reset the entire system and immediately reboot. This is a DESTRUCTIVE action and will wipe all installed applications, configuration, and the "local" ZFS datapool.
 //
+//	If `secure_wipe` is set, the encrypted volumes are cryptographically erased first and a report of the wipe is returned.
+//
 //	---
 //	produces:
 //	  - application/json
@@ -25,10 +27,30 @@ import (
 //	    required: false
 //	    schema:
 //	      type: object
-//	      example: {"allow_tpm_reset_failure":false,"wipe_existing_seeds":true,"seeds":{"incus":{"apply_defaults":true}}}
+//	      example: {"allow_tpm_reset_failure":false,"wipe_existing_seeds":true,"secure_wipe":true,"seeds":{"incus":{"apply_defaults":true}}}
 //	responses:
 //	  "200":
-//	    $ref: "#/responses/EmptySyncResponse"
+//	    description: Secure wipe report, if requested
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Secure wipe report
+//	          example: {"time":"2025-11-05T10:12:43Z","hostname":"server01","volumes":[{"name":"root","device":"/dev/disk/by-partlabel/root","keyslots":2,"keyslots_verified":true,"header_size":16777216,"discarded":true}]}
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
 //	  "500":
@@ -53,13 +75,19 @@ func (*Server) apiSystemFactoryReset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = reset.PerformOSFactoryReset(r.Context(), resetData)
+	report, err := reset.PerformOSFactoryReset(r.Context(), resetData)
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
 
-	// Will never actually reach here, since the system will auto-reboot.
+	// Return the wipe report, if any, before the system reboots.
+	if report != nil {
+		_ = response.SyncResponse(true, report).Render(w)
+
+		return
+	}
+
 	_ = response.EmptySyncResponse.Render(w)
 }
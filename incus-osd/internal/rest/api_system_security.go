@@ -1,12 +1,19 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"slices"
+	"strconv"
+	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
@@ -44,7 +51,7 @@ import (
 //	        metadata:
 //	          type: json
 //	          description: State and configuration for the system security
-//	          example: {"config":{"encryption_recovery_keys":["fkrjjenn-tbtjbjgh-jtvvchjr-ctienevu-crknfkvi-vjlvblhl-kbneribu-htjtldch"]},"state":{"encryption_recovery_keys_retrieved":true,"encrypted_volumes":[{"volume":"root","state":"unlocked (TPM)"},{"volume":"swap","state":"unlocked (TPM)"}],"secure_boot_enabled":true,"secure_boot_certificates":[{"type":"PK","fingerprint":"26dce4dbb3de2d72bd16ae91a85cfeda84535317d3ee77e0d4b2d65e714cf111","subject":"CN=Incus OS - Secure Boot PK R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"},{"type":"KEK","fingerprint":"9a42866f496834bde7e1b26a862b1e1b6dea7b78b91a948aecfc4e6ef79ea6c1","subject":"CN=Incus OS - Secure Boot KEK R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"},{"type":"db","fingerprint":"21b6f423cf80fe6c436dfea0683460312f276debe2a14285bfdc22da2d00fc20","subject":"CN=Incus OS - Secure Boot 2025 R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"},{"type":"db","fingerprint":"2243c49fcf6f84fe670f100ecafa801389dc207536cb9ca87aa2c062ddebfde5","subject":"CN=Incus OS - Secure Boot 2026 R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"}],"tpm_status":"ok","pool_recovery_keys":{"local":"F7zrtdHEaivKqofZbVFs2EeANyK77DbLi6Z8sqYVhr0="}}}
+//	          example: {"config":{"encryption_recovery_keys":["fkrjjenn-tbtjbjgh-jtvvchjr-ctienevu-crknfkvi-vjlvblhl-kbneribu-htjtldch"]},"state":{"encryption_recovery_keys_retrieved":true,"encrypted_volumes":[{"volume":"root","state":"unlocked (TPM)"},{"volume":"swap","state":"unlocked (TPM)"}],"secure_boot_enabled":true,"secure_boot_certificates":[{"type":"PK","fingerprint":"26dce4dbb3de2d72bd16ae91a85cfeda84535317d3ee77e0d4b2d65e714cf111","subject":"CN=Incus OS - Secure Boot PK R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"},{"type":"KEK","fingerprint":"9a42866f496834bde7e1b26a862b1e1b6dea7b78b91a948aecfc4e6ef79ea6c1","subject":"CN=Incus OS - Secure Boot KEK R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"},{"type":"db","fingerprint":"21b6f423cf80fe6c436dfea0683460312f276debe2a14285bfdc22da2d00fc20","subject":"CN=Incus OS - Secure Boot 2025 R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"},{"type":"db","fingerprint":"2243c49fcf6f84fe670f100ecafa801389dc207536cb9ca87aa2c062ddebfde5","subject":"CN=Incus OS - Secure Boot 2026 R1,O=Linux Containers","issuer":"CN=Incus OS - Secure Boot E1,O=Linux Containers"}],"tpm_status":"ok","passphrase_only":false,"pool_recovery_keys":{"local":"F7zrtdHEaivKqofZbVFs2EeANyK77DbLi6Z8sqYVhr0="},"boot_order":{"entries":["Boot0001: Linux Boot Manager","Boot0000: UEFI Misc Device"],"expected":"Boot0001","drifted":false},"pcr_prediction":{"release":"202511041601","bank":"sha256","pcr4":"3d458cfe55cc03ea1f443f1562beec8df51c75e14a9fcf9a7234a13f198e7969","pcr7":"65caf8dd1e0ea7a6347b635d2b379c93b9a1351edb2afc3ecda3d0ef0d1e3d62","pcr11":"f1e4b1b5e0d4b9d5cba4bd6a2dd1d1c3e2e6f8b6df4d6b8f7d0c6cb67bfd8e11"}}}
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 
@@ -52,7 +59,7 @@ import (
 //
 //	Update system security configuration
 //
-//	Updates list of encryption recovery keys. Keys must be at least 15 characters long,
+//	Updates list of encryption recovery keys, whether the EFI boot order should be automatically repaired and whether debug access is restricted, the address of the unauthenticated health probe and the remote API listener. Keys must be at least 15 characters long,
 //	contain at least one special character, and consist of at least five unique characters.
 //	Some other simple complexity checks are applied, and any key that doesn't pass will
 //	be rejected with an error.
@@ -73,12 +80,49 @@ import (
 //	        config:
 //	          type: object
 //	          description: The security configuration
-//	          example: {"encryption_recovery_keys":["my-super-secret-passphrase"]}
+//	          example: {"encryption_recovery_keys":["my-super-secret-passphrase"],"auto_repair_boot_order":true,"auto_tpm_rebind":false,"restrict_debug":true,"health_probe_address":":8080","remote_api":{"port":9443,"trusted_certificates":[{"name":"operations-center","certificate":"-----BEGIN CERTIFICATE-----\nMIIBxjCCAUygAwIBAgIQ...\n-----END CERTIFICATE-----\n"}]}}
 //	responses:
 //	  "200":
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation PATCH /1.0/system/security system system_patch_security
+//
+//	Partially update system security configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system security configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Security configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"restrict_debug":true}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
@@ -88,9 +132,6 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		var err error
 
-		// Mark that the keys have been retrieved via the API.
-		s.state.System.Security.State.EncryptionRecoveryKeysRetrieved = true
-
 		// s.state.System.Security.State.EncryptedVolumes is pre-cached, because
 		// getting the state of the LUKS volumes can be slow.
 
@@ -106,7 +147,13 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 		s.state.System.Security.State.SecureBootCertificates = secureboot.ListCertificates()
 
 		// Get TPM status.
-		s.state.System.Security.State.TPMStatus = secureboot.TPMStatus()
+		s.state.System.Security.State.PassphraseOnly = s.state.PassphraseOnly
+
+		if s.state.PassphraseOnly {
+			s.state.System.Security.State.TPMStatus = "unavailable"
+		} else {
+			s.state.System.Security.State.TPMStatus = secureboot.TPMStatus()
+		}
 
 		// Get zpool encryption keys.
 		s.state.System.Security.State.PoolRecoveryKeys, err = zfs.GetZpoolEncryptionKeys()
@@ -116,15 +163,69 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Get dm-verity volume status, keeping the last known one if it can't be retrieved.
+		verityVolumes, err := systemd.ListVerityVolumes(r.Context())
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to get the state of the dm-verity volumes", "err", err.Error())
+		} else {
+			s.state.System.Security.State.VerityVolumes = verityVolumes
+		}
+
+		// Get the certificates trusted to sign the update metadata.
+		s.state.System.Security.State.UpdateTrustAnchors = providers.ListUpdateTrustAnchors(s.state)
+
+		// Get the EFI boot order.
+		bootOrder, err := secureboot.GetBootOrder()
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to get the EFI boot order", "err", err.Error())
+		} else {
+			s.state.System.Security.State.BootOrder = bootOrder
+		}
+
+		// Predict the PCR values for the next boot, so TPM bindings can be checked ahead of a reboot.
+		nextRelease := s.nextBootRelease()
+
+		prediction, err := secureboot.PredictPCRValues(fmt.Sprintf("/boot/EFI/Linux/%s_%s.efi", s.state.OS.Name, nextRelease))
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to predict the PCR values", "release", nextRelease, "err", err.Error())
+
+			prediction.Error = err.Error()
+		}
+
+		prediction.Release = nextRelease
+		s.state.System.Security.State.PCRPrediction = &prediction
+
+		// Report any active debug access grant.
+		s.state.System.Security.State.DebugAccessExpiry = nil
+
+		if s.state.System.Security.Config.RestrictDebug && s.state.DebugAccessAllowed() {
+			expiry := time.Unix(s.state.DebugAccessExpiry, 0)
+			s.state.System.Security.State.DebugAccessExpiry = &expiry
+		}
+
+		// Compute the warnings before marking the keys as retrieved, so this response still reports them.
+		warnings := s.state.EntityWarnings("/1.0/system/security")
+
+		// Mark that the keys have been retrieved via the API.
+		s.state.System.Security.State.EncryptionRecoveryKeysRetrieved = true
+
 		// Return the current system security state.
-		_ = response.SyncResponse(true, s.state.System.Security).Render(w)
+		_ = response.SyncResponseWarningsETag(true, s.state.System.Security, warnings, s.state.System.Security.Config).Render(w)
 	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.Security.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
 		// Update the list of encryption recovery keys.
 		securityStruct := &api.SystemSecurity{}
 
 		counter := &countWrapper{ReadCloser: r.Body}
 
-		err := json.NewDecoder(counter).Decode(securityStruct)
+		deprecations, err := decodeConfigRequest(counter, "/1.0/system/security", securityStruct)
 		if err != nil && counter.n > 0 {
 			_ = response.BadRequest(err).Render(w)
 
@@ -137,6 +238,48 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		err = systemd.ValidateNetworkUnlock(securityStruct.Config.NetworkUnlock)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = ValidateHealthProbeAddress(securityStruct.Config.HealthProbeAddress)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = ValidateRemoteAPI(securityStruct.Config.RemoteAPI)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Start the listeners first, as they can easily be restored should anything else fail.
+		revertListeners, err := s.configureListeners(securityStruct.Config.HealthProbeAddress, securityStruct.Config.RemoteAPI)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Update the network unlock binding before any other setting, so a failure leaves them untouched.
+		err = s.applyNetworkUnlock(r.Context(), securityStruct.Config.NetworkUnlock)
+		if err != nil {
+			revertListeners()
+
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Security.Config.HealthProbeAddress = securityStruct.Config.HealthProbeAddress
+		s.state.System.Security.Config.RemoteAPI = securityStruct.Config.RemoteAPI
+
 		// Add any new encryption keys.
 		for _, newKey := range securityStruct.Config.EncryptionRecoveryKeys {
 			if !slices.Contains(s.state.System.Security.Config.EncryptionRecoveryKeys, newKey) {
@@ -161,7 +304,27 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		_ = response.EmptySyncResponse.Render(w)
+		// Update the boot order policy.
+		s.state.System.Security.Config.AutoRepairBootOrder = securityStruct.Config.AutoRepairBootOrder
+
+		// Update the TPM rebinding policy.
+		s.state.System.Security.Config.AutoTPMRebind = securityStruct.Config.AutoTPMRebind
+
+		// Update the debug access policy.
+		if securityStruct.Config.RestrictDebug != s.state.System.Security.Config.RestrictDebug {
+			slog.InfoContext(r.Context(), "Debug access policy changed", "restricted", securityStruct.Config.RestrictDebug)
+		}
+
+		s.state.System.Security.Config.RestrictDebug = securityStruct.Config.RestrictDebug
+
+		s.state.System.Security.State.EncryptedVolumes, err = systemd.ListEncryptedVolumes(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.SyncResponseWarnings(true, map[string]any{}, append(s.state.EntityWarnings("/1.0/system/security"), deprecations...)).Render(w)
 	default:
 		// If none of the supported methods, return NotImplemented.
 		_ = response.NotImplemented(nil).Render(w)
@@ -170,18 +333,67 @@ func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
 	_ = s.state.Save()
 }
 
+// applyNetworkUnlock binds the encrypted volumes according to the provided network unlock configuration,
+// keeping the previous configuration if that fails.
+func (s *Server) applyNetworkUnlock(ctx context.Context, config *api.SystemSecurityNetworkUnlock) error {
+	previous := s.state.System.Security.Config.NetworkUnlock
+
+	s.state.System.Security.Config.NetworkUnlock = config
+
+	err := systemd.ApplyNetworkUnlock(ctx, s.state)
+	if err != nil {
+		s.state.System.Security.Config.NetworkUnlock = previous
+
+		return err
+	}
+
+	return nil
+}
+
 // swagger:operation POST /1.0/system/security/:tpm-rebind system system_post_security_tpm_rebind
 //
 //	Reset TPM bindings
 //
 //	Forcibly resets TPM encryption bindings; intended only for use if it was required to enter a recovery passphrase to boot the system.
 //
+//	When run as a dry run, nothing is written to the TPM and the system isn't rebooted. Instead, the volumes which would be
+//	re-enrolled, the PCR7 value they would be bound to, the detected Secure Boot certificates and any reason the reset would
+//	be refused are returned.
+//
 //	---
 //	produces:
 //	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: dry-run
+//	    description: Only report what would be done
+//	    type: boolean
+//	    example: true
 //	responses:
 //	  "200":
-//	    $ref: "#/responses/EmptySyncResponse"
+//	    description: TPM rebinding plan
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: TPM rebinding plan
+//	          example: {"operation":"tpm-rebind","bank":"sha256","pcr7":"65caf8dd1e0ea7a6347b635d2b379c93b9a1351edb2afc3ecda3d0ef0d1e3d62","signing_certificate":{"type":"db","fingerprint":"26d1df5e3d8b8ae6fc2ee2a4fdc2e2b4b8c6a4cd0c3bd8c9e0d3e34c8a1b7e02","subject":"CN=IncusOS - Secure Boot 2025 R1,O=Linux Containers","issuer":"CN=IncusOS - Secure Boot E1,O=Linux Containers"},"certificates":[],"volumes":[{"name":"root","device":"/dev/disk/by-partlabel/root-x86-64"},{"name":"swap","device":"/dev/disk/by-partlabel/swap"}],"reboot":true,"blockers":[]}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
 //	  "500":
 //	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemSecurityTPMRebind(w http.ResponseWriter, r *http.Request) {
@@ -193,13 +405,794 @@ func (s *Server) apiSystemSecurityTPMRebind(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err := secureboot.ForceUpdatePCRBindings(r.Context(), s.state.OS.Name, s.state.OS.RunningRelease, s.state.System.Security.Config.EncryptionRecoveryKeys[0])
+	if systemd.TPMUnlockDisabled(s.state) {
+		_ = response.BadRequest(errors.New("TPM unlocking is disabled")).Render(w)
+
+		return
+	}
+
+	if s.state.PassphraseOnly {
+		_ = response.BadRequest(errors.New("no TPM is available")).Render(w)
+
+		return
+	}
+
+	dryRun := false
+
+	if r.FormValue("dry-run") != "" {
+		var err error
+
+		dryRun, err = strconv.ParseBool(r.FormValue("dry-run"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	plan, err := secureboot.ForceUpdatePCRBindings(r.Context(), s.state.OS.Name, s.state.OS.RunningRelease, s.state.System.Security.Config.EncryptionRecoveryKeys[0], dryRun)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, plan).Render(w)
+
+	if !dryRun {
+		_ = s.state.Save()
+	}
+}
+
+// swagger:operation POST /1.0/system/security/:repair-boot-order system system_post_security_repair_boot_order
+//
+//	Repair the EFI boot order
+//
+//	Moves the IncusOS boot entry back to the front of the EFI boot order, for example after the firmware or another operating system changed it.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityRepairBootOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	err := secureboot.RepairBootOrder(r.Context())
 	if err != nil {
 		_ = response.InternalError(err).Render(w)
 
 		return
 	}
 
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation POST /1.0/system/security/:grant-debug system system_post_security_grant_debug
+//
+//	Grant time-limited debug access
+//
+//	Allows use of the debug endpoints for a limited time when debug access is restricted. The grant is automatically revoked once it expires.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: duration
+//	    description: How long to grant debug access for (defaults to 1h, at most 7 days)
+//	    type: string
+//	    example: 4h
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+func (s *Server) apiSystemSecurityGrantDebug(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	duration := time.Hour
+
+	if r.FormValue("duration") != "" {
+		var err error
+
+		duration, err = time.ParseDuration(r.FormValue("duration"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+	}
+
+	if duration <= 0 || duration > 7*24*time.Hour {
+		_ = response.BadRequest(errors.New("debug access can only be granted for up to 7 days")).Render(w)
+
+		return
+	}
+
+	expiry := time.Now().Add(duration)
+	s.state.DebugAccessExpiry = expiry.Unix()
+
+	slog.WarnContext(r.Context(), "Debug access granted", "until", expiry.UTC().Format(time.RFC3339))
+
+	_ = response.EmptySyncResponse.Render(w)
+	_ = s.state.Save()
+}
+
+// swagger:operation POST /1.0/system/security/:revoke-debug system system_post_security_revoke_debug
+//
+//	Revoke debug access
+//
+//	Immediately revokes any active time-limited debug access grant.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+func (s *Server) apiSystemSecurityRevokeDebug(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	if s.state.DebugAccessExpiry != 0 {
+		slog.WarnContext(r.Context(), "Debug access revoked")
+	}
+
+	s.state.DebugAccessExpiry = 0
+
 	_ = response.EmptySyncResponse.Render(w)
 	_ = s.state.Save()
 }
+
+// swagger:operation POST /1.0/system/security/:add-recovery-key system system_post_security_add_recovery_key
+//
+//	Add an encryption recovery key
+//
+//	Enrolls an additional recovery key for the encrypted volumes, for example as a second emergency passphrase.
+//	A new key is generated if none is provided.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: recovery_key
+//	    description: Recovery key to add
+//	    required: false
+//	    schema:
+//	      type: object
+//	      example: {"key":"my-Secure-emergency-passphrase!"}
+//	responses:
+//	  "200":
+//	    description: The new recovery key
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: The new recovery key
+//	          example: {"key":"fkrjjenn-tbtjbjgh-jtvvchjj-ivghvnlg-edvuhdrh-gfjjgbke-nhibelnc-ijkjrtcc"}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityAddRecoveryKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req, err := decodeRecoveryKeyRequest(r)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if req.Key == "" {
+		err = systemd.GenerateRecoveryKey(r.Context(), s.state)
+		if err == nil {
+			req.Key = s.state.System.Security.Config.EncryptionRecoveryKeys[len(s.state.System.Security.Config.EncryptionRecoveryKeys)-1]
+		}
+	} else {
+		err = systemd.AddEncryptionKey(r.Context(), s.state, req.Key)
+	}
+
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Encryption recovery key added")
+
+	// The key is returned to the caller.
+	s.state.System.Security.State.EncryptionRecoveryKeysRetrieved = true
+
+	_ = response.SyncResponse(true, api.SystemSecurityRecoveryKey{Key: req.Key}).Render(w)
+	_ = s.state.Save()
+}
+
+// swagger:operation POST /1.0/system/security/:rotate-recovery-key system system_post_security_rotate_recovery_key
+//
+//	Rotate an encryption recovery key
+//
+//	Replaces an existing recovery key with a new one, which is generated if not provided.
+//	The new key is enrolled before the existing one is removed.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: recovery_key
+//	    description: Recovery key to rotate and its optional replacement
+//	    required: true
+//	    schema:
+//	      type: object
+//	      example: {"key":"fkrjjenn-tbtjbjgh-jtvvchjj-ivghvnlg-edvuhdrh-gfjjgbke-nhibelnc-ijkjrtcc"}
+//	responses:
+//	  "200":
+//	    description: The new recovery key
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: The new recovery key
+//	          example: {"key":"fkrjjenn-tbtjbjgh-jtvvchjj-ivghvnlg-edvuhdrh-gfjjgbke-nhibelnc-ijkjrtcc"}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityRotateRecoveryKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req, err := decodeRecoveryKeyRequest(r)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if req.Key == "" {
+		_ = response.BadRequest(errors.New("no encryption key provided")).Render(w)
+
+		return
+	}
+
+	if !slices.Contains(s.state.System.Security.Config.EncryptionRecoveryKeys, req.Key) {
+		_ = response.BadRequest(errors.New("provided encryption key is not enrolled")).Render(w)
+
+		return
+	}
+
+	newKey, err := systemd.RotateEncryptionKey(r.Context(), s.state, req.Key, req.NewKey)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+		_ = s.state.Save()
+
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Encryption recovery key rotated")
+
+	// The key is returned to the caller.
+	s.state.System.Security.State.EncryptionRecoveryKeysRetrieved = true
+
+	_ = response.SyncResponse(true, api.SystemSecurityRecoveryKey{Key: newKey}).Render(w)
+	_ = s.state.Save()
+}
+
+// swagger:operation POST /1.0/system/security/:remove-recovery-key system system_post_security_remove_recovery_key
+//
+//	Remove an encryption recovery key
+//
+//	Removes a recovery key from the encrypted volumes. The last remaining key can't be removed.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: recovery_key
+//	    description: Recovery key to remove
+//	    required: true
+//	    schema:
+//	      type: object
+//	      example: {"key":"my-Secure-emergency-passphrase!"}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityRemoveRecoveryKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req, err := decodeRecoveryKeyRequest(r)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if !slices.Contains(s.state.System.Security.Config.EncryptionRecoveryKeys, req.Key) {
+		_ = response.BadRequest(errors.New("provided encryption key is not enrolled")).Render(w)
+
+		return
+	}
+
+	if len(s.state.System.Security.Config.EncryptionRecoveryKeys) == 1 {
+		_ = response.BadRequest(errors.New("cannot remove only existing recovery key")).Render(w)
+
+		return
+	}
+
+	err = systemd.DeleteEncryptionKey(r.Context(), s.state, req.Key)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+		_ = s.state.Save()
+
+		return
+	}
+
+	slog.InfoContext(r.Context(), "Encryption recovery key removed")
+
+	_ = response.EmptySyncResponse.Render(w)
+	_ = s.state.Save()
+}
+
+// swagger:operation POST /1.0/system/security/:add-fido2-token system system_post_security_add_fido2_token
+//
+//	Enroll a FIDO2 security key
+//
+//	Enrolls a FIDO2 security key as an additional way to unlock the encrypted volumes. The request only
+//	completes once presence has been confirmed on the security key, once for each encrypted volume.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: fido2_token
+//	    description: Name of the security key and optional device and PIN
+//	    required: true
+//	    schema:
+//	      type: object
+//	      example: {"name":"yubikey-1","device":"/dev/hidraw0","pin":"123456"}
+//	responses:
+//	  "200":
+//	    description: The enrolled security key
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: The enrolled security key
+//	          example: {"name":"yubikey-1","slots":{"root":3,"swap":3}}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityAddFIDO2Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := api.SystemSecurityFIDO2TokenEnroll{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if req.Name == "" {
+		_ = response.BadRequest(errors.New("no FIDO2 token name provided")).Render(w)
+
+		return
+	}
+
+	token, err := systemd.EnrollFIDO2Token(r.Context(), s.state, req)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	slog.InfoContext(r.Context(), "FIDO2 token enrolled", "name", token.Name)
+
+	_ = response.SyncResponse(true, token).Render(w)
+	_ = s.state.Save()
+}
+
+// swagger:operation POST /1.0/system/security/:remove-fido2-token system system_post_security_remove_fido2_token
+//
+//	Remove a FIDO2 security key
+//
+//	Removes an enrolled FIDO2 security key from the encrypted volumes.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: fido2_token
+//	    description: Name of the security key to remove
+//	    required: true
+//	    schema:
+//	      type: object
+//	      example: {"name":"yubikey-1"}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityRemoveFIDO2Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := api.SystemSecurityFIDO2Token{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	if !slices.ContainsFunc(s.state.System.Security.State.FIDO2Tokens, func(entry api.SystemSecurityFIDO2Token) bool { return entry.Name == req.Name }) {
+		_ = response.BadRequest(fmt.Errorf("FIDO2 token %q isn't enrolled", req.Name)).Render(w)
+
+		return
+	}
+
+	err = systemd.RemoveFIDO2Token(r.Context(), s.state, req.Name)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	slog.InfoContext(r.Context(), "FIDO2 token removed", "name", req.Name)
+
+	_ = response.EmptySyncResponse.Render(w)
+	_ = s.state.Save()
+}
+
+// decodeRecoveryKeyRequest parses the optional body of the recovery key endpoints.
+func decodeRecoveryKeyRequest(r *http.Request) (*api.SystemSecurityRecoveryKey, error) {
+	req := &api.SystemSecurityRecoveryKey{}
+
+	counter := &countWrapper{ReadCloser: r.Body}
+
+	err := json.NewDecoder(counter).Decode(req)
+	if err != nil && counter.n > 0 {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// swagger:operation GET /1.0/system/security/pcr-preview system system_get_security_pcr_preview
+//
+//	Preview the PCR values on next boot
+//
+//	Returns the current PCR4, PCR7 and PCR11 values along with those expected when next booting, once any pending change to the
+//	EFI variables or the boot image is measured. Also reports whether the TPM won't unlock the encrypted volumes on next boot,
+//	meaning a recovery key will be required.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: PCR values preview
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: PCR values preview
+//	          example: {"release":"202511041601","bank":"sha256","pcrs":[{"index":4,"current":"3d458cfe55cc03ea1f443f1562beec8df51c75e14a9fcf9a7234a13f198e7969","next":"3d458cfe55cc03ea1f443f1562beec8df51c75e14a9fcf9a7234a13f198e7969","bound":false},{"index":7,"current":"65caf8dd1e0ea7a6347b635d2b379c93b9a1351edb2afc3ecda3d0ef0d1e3d62","next":"65caf8dd1e0ea7a6347b635d2b379c93b9a1351edb2afc3ecda3d0ef0d1e3d62","bound":true},{"index":11,"next":"f1e4b1b5e0d4b9d5cba4bd6a2dd1d1c3e2e6f8b6df4d6b8f7d0c6cb67bfd8e11","bound":false}],"recovery_required":false}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityPCRPreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	if systemd.TPMUnlockDisabled(s.state) {
+		_ = response.BadRequest(errors.New("TPM unlocking is disabled")).Render(w)
+
+		return
+	}
+
+	if s.state.PassphraseOnly {
+		_ = response.BadRequest(errors.New("no TPM is available")).Render(w)
+
+		return
+	}
+
+	nextRelease := s.nextBootRelease()
+
+	preview, err := secureboot.PreviewPCRValues(r.Context(), fmt.Sprintf("/boot/EFI/Linux/%s_%s.efi", s.state.OS.Name, nextRelease))
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	preview.Release = nextRelease
+
+	_ = response.SyncResponse(true, preview).Render(w)
+}
+
+// swagger:operation GET /1.0/system/security/key-change-preview system system_get_security_key_change_preview
+//
+//	Preview a Secure Boot key change
+//
+//	Returns the changes which would be made when applying the staged OS update, should it be signed with a different
+//	Secure Boot key: the volumes which would be re-enrolled, the PCR7 value they would be bound to, the detected Secure
+//	Boot certificates and any reason the change would be refused. Nothing is written to the TPM or the EFI partition.
+//	If the staged update is signed with the current key, no plan is returned.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Secure Boot key change plan
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Secure Boot key change plan
+//	          example: {"operation":"secure-boot-key-change","bank":"sha256","pcr7":"9b2c0ad5d1ef3c1b2e6b6f4d0d3c74e0b3f0c3b7a2cd1c0f8e6f04d3b2a1c9e8","signing_certificate":{"type":"db","fingerprint":"7f0e2b9c4d9a1e3c5b8d6a2f4e0c9b1d3a5f7e9c2b4d6f8a0c1e3b5d7f9a2c4e","subject":"CN=IncusOS - Secure Boot 2026 R1,O=Linux Containers","issuer":"CN=IncusOS - Secure Boot E1,O=Linux Containers"},"certificates":[],"volumes":[{"name":"root","device":"/dev/disk/by-partlabel/root-x86-64"},{"name":"swap","device":"/dev/disk/by-partlabel/swap"}],"reboot":false,"blockers":[]}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityKeyChangePreview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	if s.state.OS.StagedRelease == "" {
+		_ = response.BadRequest(errors.New("no OS update is currently staged")).Render(w)
+
+		return
+	}
+
+	plan, err := systemd.PlanSecureBootKeyChange(r.Context(), s.state.OS.StagedRelease)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, plan).Render(w)
+}
+
+// nextBootRelease returns the OS release the system will boot into next.
+func (s *Server) nextBootRelease() string {
+	if s.state.OS.NextRelease != "" {
+		return s.state.OS.NextRelease
+	}
+
+	return s.state.OS.RunningRelease
+}
+
+// swagger:operation POST /1.0/system/security/:export-boot-report system system_post_security_export_boot_report
+//
+//	Export a signed measured boot report
+//
+//	Returns a single JSON document holding a measured boot report, containing the TCG event log, the current PCR values,
+//	the running UKI version and the Secure Boot certificate chain, signed with the key of the primary application's server certificate.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Signed measured boot report
+//	    schema:
+//	      type: object
+//	      example: {"report":"{\"time\":\"2025-11-05T10:12:43Z\",\"hostname\":\"server01\",\"os_name\":\"IncusOS\",\"os_version\":\"202511041601\",...}","signature_algorithm":"ECDSA-SHA256","signature":"MEUCIQDx...","certificate":"-----BEGIN CERTIFICATE-----\n..."}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSecurityExportBootReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	if s.state.PassphraseOnly {
+		_ = response.BadRequest(errors.New("no TPM is available")).Render(w)
+
+		return
+	}
+
+	// Get the key to sign the report with.
+	app, err := applications.GetPrimary(r.Context(), s.state)
+	if err != nil {
+		_ = response.BadRequest(fmt.Errorf("no certificate available to sign the report: %w", err)).Render(w)
+
+		return
+	}
+
+	keypair, err := app.GetCertificate()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	// Gather and sign the report.
+	report, err := secureboot.GetMeasuredBootReport(fmt.Sprintf("/boot/EFI/Linux/%s_%s.efi", s.state.OS.Name, s.state.OS.RunningRelease))
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	report.Time = time.Now().UTC()
+	report.Hostname = s.state.Hostname()
+	report.OSName = s.state.OS.Name
+	report.OSVersion = s.state.OS.RunningRelease
+
+	signedReport, err := secureboot.SignMeasuredBootReport(report, keypair)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(signedReport)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+}
@@ -0,0 +1,284 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/revert"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/seed"
+	"github.com/lxc/incus-os/incus-osd/internal/services"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// errSeedInvalid wraps errors caused by invalid seed data or an invalid request.
+var errSeedInvalid = errors.New("invalid seed")
+
+// swagger:operation POST /1.0/system/:apply-seed system system_post_apply_seed
+//
+//	Re-apply the seed
+//
+//	Re-applies the selected sections of the seed data on an installed system, reading them from an attached
+//	user-provided seed volume if any, otherwise from the seed partition. Sections missing from the seed data
+//	are skipped. The network configuration is reverted if a later section fails to apply.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: seed
+//	    description: Seed sections to apply, defaulting to network, provider and applications
+//	    required: false
+//	    schema:
+//	      type: object
+//	      example: {"sections":["network","applications"]}
+//	responses:
+//	  "200":
+//	    description: The applied sections
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: The applied sections
+//	          items:
+//	            type: string
+//	          example: ["network","applications"]
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemApplySeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemSeedApply{}
+
+	counter := &countWrapper{ReadCloser: r.Body}
+
+	err := json.NewDecoder(counter).Decode(req)
+	if err != nil && (!errors.Is(err, io.EOF) || counter.n > 0) {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	applied, err := s.ApplySeed(r.Context(), req.Sections)
+	if err != nil {
+		if errors.Is(err, errSeedInvalid) {
+			_ = response.BadRequest(err).Render(w)
+		} else {
+			_ = response.InternalError(err).Render(w)
+		}
+
+		return
+	}
+
+	_ = response.SyncResponse(true, applied).Render(w)
+}
+
+// ApplySeed re-applies the selected sections of the seed data, returning those which were found and applied.
+func (s *Server) ApplySeed(ctx context.Context, sections []string) ([]string, error) {
+	supported := []string{api.SystemSeedSectionNetwork, api.SystemSeedSectionProvider, api.SystemSeedSectionApplications}
+
+	if len(sections) == 0 {
+		sections = supported
+	}
+
+	for _, section := range sections {
+		if !slices.Contains(supported, section) {
+			return nil, fmt.Errorf("%w: unsupported section %q", errSeedInvalid, section)
+		}
+	}
+
+	// Read and validate all the sections before changing anything.
+	var networkConfig *api.SystemNetworkConfig
+
+	if slices.Contains(sections, api.SystemSeedSectionNetwork) {
+		config, err := seed.GetNetworkSeed(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return nil, fmt.Errorf("%w: network: %w", errSeedInvalid, err)
+		}
+
+		if config != nil {
+			if seed.NetworkConfigHasEmptyDevices(*config) {
+				return nil, fmt.Errorf("%w: network configuration has no devices defined", errSeedInvalid)
+			}
+
+			networkConfig = config
+		}
+	}
+
+	var providerSeed *apiseed.Provider
+
+	if slices.Contains(sections, api.SystemSeedSectionProvider) {
+		config, err := seed.GetProvider(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return nil, fmt.Errorf("%w: provider: %w", errSeedInvalid, err)
+		}
+
+		providerSeed = config
+	}
+
+	var newApplications []apiseed.Application
+
+	if slices.Contains(sections, api.SystemSeedSectionApplications) {
+		apps, err := seed.GetApplications(ctx)
+		if err != nil && !seed.IsMissing(err) {
+			return nil, fmt.Errorf("%w: applications: %w", errSeedInvalid, err)
+		}
+
+		if apps != nil {
+			for _, app := range apps.Applications {
+				_, err := applications.Load(ctx, s.state, app.Name)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid application %q: %w", errSeedInvalid, app.Name, err)
+				}
+
+				// Applications which are already installed are left as they are.
+				_, exists := s.state.Applications[app.Name]
+				if !exists {
+					newApplications = append(newApplications, app)
+				}
+			}
+		}
+	}
+
+	// Apply the seed.
+	slog.InfoContext(ctx, "Applying seed data", "sections", sections)
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	applied := []string{}
+
+	if networkConfig != nil {
+		previousConfig := s.state.System.Network.Config
+		if previousConfig != nil {
+			reverter.Add(func() {
+				err := systemd.ApplyNetworkConfiguration(ctx, s.state, previousConfig, 30*time.Second, false, services.RefreshNetwork)
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to restore the previous network configuration", "err", err.Error())
+				}
+			})
+		}
+
+		err := systemd.ApplyNetworkConfiguration(ctx, s.state, networkConfig, 30*time.Second, false, services.RefreshNetwork)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply network configuration: %w", err)
+		}
+
+		applied = append(applied, api.SystemSeedSectionNetwork)
+	}
+
+	if providerSeed != nil {
+		err := s.applyProvider(ctx, api.SystemProviderConfig{Name: providerSeed.Name, Config: providerSeed.Config})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply provider configuration: %w", err)
+		}
+
+		applied = append(applied, api.SystemSeedSectionProvider)
+	}
+
+	for _, app := range newApplications {
+		appInfo := api.Application{}
+		if app.Config != nil {
+			appInfo.Config = *app.Config
+		}
+
+		s.state.SetApplication(app.Name, appInfo)
+	}
+
+	if len(newApplications) > 0 {
+		applied = append(applied, api.SystemSeedSectionApplications)
+	}
+
+	reverter.Success()
+
+	_ = s.state.Save()
+
+	if networkConfig != nil {
+		// Move the listeners over to the new management address.
+		s.UpdateManagementListeners(ctx)
+	}
+
+	// Trigger a manual update check to install the new applications.
+	if len(newApplications) > 0 {
+		s.state.RequestUpdate()
+	}
+
+	s.state.Events.SendLifecycle(api.EventLifecycleSystemSeedApplied, "/1.0/system", map[string]any{"sections": applied})
+
+	return applied, nil
+}
+
+// applyProvider switches to a new provider configuration, deregistering from the current provider.
+func (s *Server) applyProvider(ctx context.Context, newConfig api.SystemProviderConfig) error {
+	oldConfig := s.state.System.Provider.Config
+
+	err := secrets.SealProviderConfig(&newConfig, oldConfig)
+	if err != nil {
+		return err
+	}
+
+	p, err := providers.Load(ctx, s.state)
+	if err != nil {
+		return err
+	}
+
+	err = p.Deregister(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.state.System.Provider.Config = newConfig
+
+	p, err = providers.Load(ctx, s.state)
+	if err == nil {
+		err = p.Register(ctx, false)
+	}
+
+	if err != nil {
+		s.state.System.Provider.Config = oldConfig
+
+		return err
+	}
+
+	s.state.System.Provider.State = api.SystemProviderState{Registered: true}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/selfcheck"
+)
+
+// swagger:operation GET /1.0/system/self-check system system_get_self_check
+//
+//	Get the self-check report
+//
+//	Returns the report of the last nightly self-check, covering the security posture, storage health, connectivity and boot state,
+//	along with the pass/fail history of the previous runs.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Self-check report
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Self-check report and history
+//	          example: {"last_report":{"time":"2025-11-05T03:00:00Z","passed":false,"results":[{"name":"security","passed":true},{"name":"storage","passed":false,"message":"storage pool \"local\" is DEGRADED"},{"name":"connectivity","passed":true},{"name":"boot","passed":true}]},"history":[{"time":"2025-11-04T03:00:00Z","passed":true},{"time":"2025-11-05T03:00:00Z","passed":false,"failed":["storage"]}]}
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSelfCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	report, err := s.state.SelfCheckReport()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, report).Render(w)
+}
+
+// swagger:operation POST /1.0/system/self-check/:run system system_post_self_check_run
+//
+//	Run the self-check
+//
+//	Immediately runs the self-check, returning and recording its report.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: Self-check report
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: Self-check report
+//	          example: {"time":"2025-11-05T10:12:43Z","passed":true,"results":[{"name":"security","passed":true},{"name":"storage","passed":true},{"name":"connectivity","passed":true},{"name":"boot","passed":true}]}
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemSelfCheckRun(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	report, err := selfcheck.Run(r.Context(), s.state)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = s.state.Save()
+
+	_ = response.SyncResponse(true, report).Render(w)
+}
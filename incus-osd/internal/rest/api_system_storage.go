@@ -91,6 +91,16 @@ func (s *Server) apiSystemStorage(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Add the geometry of the system drive recorded on first boot.
+		if s.state.InstallGeometry != "" {
+			geometry := &api.SystemStorageDriveGeometry{}
+
+			err = json.Unmarshal([]byte(s.state.InstallGeometry), geometry)
+			if err == nil {
+				ret.State.InstallGeometry = geometry
+			}
+		}
+
 		// Return the current system storage state.
 		_ = response.SyncResponse(true, ret).Render(w)
 	case http.MethodPut:
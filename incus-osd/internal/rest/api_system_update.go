@@ -1,13 +1,30 @@
 package rest
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/lxc/incus/v6/shared/units"
+
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
 
 // swagger:operation GET /1.0/system/update system system_get_update
@@ -41,7 +58,7 @@ import (
 //	        metadata:
 //	          type: json
 //	          description: State and configuration for the system update
-//	          example: {"config":{"auto_reboot":false,"channel":"stable","check_frequency":"6h"},"state":{"last_check":"2025-11-04T16:21:34.929524792Z","status":"Update check completed","needs_reboot":false}}
+//	          example: {"config":{"auto_reboot":false,"channel":"stable","check_frequency":"6h","download_only":false},"state":{"last_check":"2025-11-04T16:21:34.929524792Z","status":"Downloading OS update","needs_reboot":false,"current_release":"202511031407","in_progress":true,"data_usage":[{"month":"2025-11","provider":"images","bytes":524288000}],"progress":{"phase":"downloading","component":"os","version":"202511041601","file":"IncusOS_202511041601.efi","file_percentage":42,"bytes_transferred":220200960,"bytes_total":524288000,"eta":35},"available":[{"component":"os","version":"202511041601","staged":false,"download_size":524288000,"staging_size":710410240,"apply_time":42}],"provenance":[{"component":"incus","version":"202511031407","provider":"images","signer":"4c4d3c1e7b9a1a3b2c9e2ed3a8f1b9d4e7f0c6a2b5d8e1f4a7c0b3d6e9f2a5c8","files":[{"filename":"incus.raw.gz","source":"https://images.linuxcontainers.org/os/202511031407/x86_64/incus.raw.gz","sha256":"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}],"downloaded":"2025-11-03T14:21:02Z","installed":"2025-11-03T14:21:40Z"}]}}
 
 // swagger:operation PUT /1.0/system/update system system_put_update
 //
@@ -71,59 +88,115 @@ import (
 //	    $ref: "#/responses/EmptySyncResponse"
 //	  "400":
 //	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+
+// swagger:operation PATCH /1.0/system/update system system_patch_update
+//
+//	Partially update system update configuration
+//
+//	Merges the provided JSON merge patch (RFC 7386) into the current system update configuration. Objects are merged
+//	recursively, keys set to null are removed and any other value, including lists, replaces the current one.
+//
+//	---
+//	consumes:
+//	  - application/json
+//	  - application/merge-patch+json
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: body
+//	    name: configuration
+//	    description: Update configuration patch
+//	    required: true
+//	    schema:
+//	      type: object
+//	      properties:
+//	        config:
+//	          type: object
+//	          description: The configuration changes
+//	          example: {"channel":"testing"}
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "412":
+//	    $ref: "#/responses/PreconditionFailed"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
 func (s *Server) apiSystemUpdate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	switch r.Method {
 	case http.MethodGet:
 		// Return the current system update state.
-		_ = response.SyncResponse(true, s.state.System.Update).Render(w)
+		update := s.state.System.Update
+		update.State.StagedRelease = s.state.OS.StagedRelease
+		update.State.StagedApplications = s.state.StagedApplications()
+		update.State.CurrentRelease = s.state.OS.RunningRelease
+		update.State.InProgress = s.state.UpdateInProgress()
+		update.State.Progress = s.state.UpdateProgress()
+		update.State.DataUsage = s.state.DataUsage
+
+		if s.nextBootRelease() != s.state.OS.RunningRelease {
+			update.State.NextRelease = s.nextBootRelease()
+		}
+
+		provenance, err := s.state.ProvenanceRecords()
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		update.State.Provenance = provenance
+
+		if update.Config.VerificationProvider != nil {
+			verificationProvider := secrets.RedactProviderConfig(*update.Config.VerificationProvider)
+			update.Config.VerificationProvider = &verificationProvider
+		}
+
+		_ = response.SyncResponseWarningsETag(true, update, s.state.EntityWarnings("/1.0/system/update"), s.state.System.Update.Config).Render(w)
 	case http.MethodPut:
+		// Refuse the change if the configuration was modified since the client last read it.
+		err := response.EtagCheck(r, s.state.System.Update.Config)
+		if err != nil {
+			_ = response.PreconditionFailed(err).Render(w)
+
+			return
+		}
+
 		// Apply a new system update configuration.
 		newConfig := &api.SystemUpdate{}
 
 		// Update the system update configuration from request's body.
-		err := json.NewDecoder(r.Body).Decode(newConfig)
+		deprecations, err := decodeConfigRequest(r.Body, "/1.0/system/update", newConfig)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
 
 			return
 		}
 
-		// Basic validation.
-		for _, mw := range newConfig.Config.MaintenanceWindows {
-			// To simplify logic, we don't allow a week-long migration window
-			// to start and end on the same day.
-			if mw.StartDayOfWeek != api.NONE && mw.StartDayOfWeek == mw.EndDayOfWeek {
-				if mw.EndHour*60+mw.EndMinute < mw.StartHour*60+mw.StartMinute {
-					_ = response.BadRequest(errors.New("invalid migration window: end time is before start time")).Render(w)
-
-					return
-				}
-			}
-
-			// If either StartDayOfWeek or EndDayOfWeek is specified, the other must be too.
-			if (mw.StartDayOfWeek == api.NONE && mw.EndDayOfWeek != api.NONE) || (mw.StartDayOfWeek != api.NONE && mw.EndDayOfWeek == api.NONE) {
-				_ = response.BadRequest(errors.New("invalid migration window: both StartDayOfWeek and EndDayOfWeek must be provided")).Render(w)
+		// Seal the verification provider's credentials, keeping any which were left redacted.
+		err = s.sealUpdateConfig(&newConfig.Config)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
 
-				return
-			}
+			return
 		}
 
-		// Check the update frequency is valid.
-		if newConfig.Config.CheckFrequency != "never" {
-			_, err = time.ParseDuration(newConfig.Config.CheckFrequency)
-			if err != nil {
-				_ = response.BadRequest(errors.New("invalid update check frequency")).Render(w)
+		err = s.validateUpdateConfig(r.Context(), newConfig.Config)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
 
-				return
-			}
+			return
 		}
 
 		// Apply the updated configuration.
 		s.state.System.Update.Config = newConfig.Config
 
-		_ = response.EmptySyncResponse.Render(w)
+		_ = response.SyncResponseWarnings(true, map[string]any{}, append(s.state.EntityWarnings("/1.0/system/update"), deprecations...)).Render(w)
 
 		_ = s.state.Save()
 	default:
@@ -132,6 +205,65 @@ func (s *Server) apiSystemUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// sealUpdateConfig seals the credentials of the verification provider, keeping any which were left redacted.
+func (s *Server) sealUpdateConfig(config *api.SystemUpdateConfig) error {
+	if config.VerificationProvider == nil {
+		return nil
+	}
+
+	currentVerificationProvider := api.SystemProviderConfig{}
+	if s.state.System.Update.Config.VerificationProvider != nil {
+		currentVerificationProvider = *s.state.System.Update.Config.VerificationProvider
+	}
+
+	return secrets.SealProviderConfig(config.VerificationProvider, currentVerificationProvider)
+}
+
+// validateUpdateConfig checks that a new system update configuration is valid.
+func (s *Server) validateUpdateConfig(ctx context.Context, config api.SystemUpdateConfig) error {
+	// Basic validation.
+	for _, mw := range config.MaintenanceWindows {
+		err := mw.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check the update frequency is valid.
+	if config.CheckFrequency != "never" {
+		_, err := time.ParseDuration(config.CheckFrequency)
+		if err != nil {
+			return errors.New("invalid update check frequency")
+		}
+	}
+
+	// Check the metered quota is valid.
+	if config.MeteredQuota != "" {
+		quota, err := units.ParseByteSizeString(config.MeteredQuota)
+		if err != nil || quota <= 0 {
+			return errors.New("invalid metered quota")
+		}
+	}
+
+	// Check the verification provider is valid.
+	if config.VerificationProvider != nil {
+		if config.VerificationProvider.Name == s.state.System.Provider.Config.Name {
+			return errors.New("verification provider must differ from the current provider")
+		}
+
+		if !providers.SupportsVerification(config.VerificationProvider.Name) || !providers.SupportsVerification(s.state.System.Provider.Config.Name) {
+			return errors.New("the local and share providers can't be used for verification")
+		}
+
+		_, err := providers.LoadFromConfig(ctx, s.state, *config.VerificationProvider)
+		if err != nil {
+			return fmt.Errorf("invalid verification provider: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // swagger:operation POST /1.0/system/update/:check system system_post_update_check
 //
 //	Trigger update check
@@ -154,7 +286,709 @@ func (s *Server) apiSystemUpdateCheck(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Trigger a manual update check.
-	s.state.TriggerUpdate <- true
+	s.state.RequestUpdate()
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation POST /1.0/system/update/:download system system_post_update_download
+//
+//	Download updates
+//
+//	Triggers an immediate system update check, staging any OS and application updates found without applying them,
+//	as in download-only mode. Applications which aren't installed yet are installed right away.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+func (s *Server) apiSystemUpdateDownload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Trigger a manual update check, only staging the updates.
+	s.state.RequestDownload()
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// swagger:operation POST /1.0/system/update/:cancel system system_post_update_cancel
+//
+//	Cancel pending updates
+//
+//	Cancels any update check or download currently in progress and discards the OS and application updates which
+//	have been staged but not yet applied. Updates which have already been applied and only wait for a reboot aren't affected.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemUpdateCancel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	cancelled := s.state.CancelUpdateOperations()
+
+	stagedApplications := s.state.StagedApplications()
+	if !cancelled && s.state.OS.StagedRelease == "" && len(stagedApplications) == 0 {
+		_ = response.BadRequest(errors.New("no update in progress or staged")).Render(w)
+
+		return
+	}
+
+	// Wait for any cancelled download to stop before discarding the staged updates.
+	s.state.UpdateMutex.Lock()
+	defer s.state.UpdateMutex.Unlock()
+
+	if s.state.OS.StagedRelease != "" {
+		slog.InfoContext(r.Context(), "Discarding staged OS update", "release", s.state.OS.StagedRelease)
+
+		err := systemd.RemoveSystemUpdate(s.state.OS.StagedRelease)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.OS.StagedRelease = ""
+		s.state.SetUpdateStaged("os", false)
+	}
+
+	for name := range stagedApplications {
+		slog.InfoContext(r.Context(), "Discarding staged application update", "application", name, "release", stagedApplications[name])
+
+		err := os.Remove(filepath.Join(systemd.SystemExtensionsStagingPath, name+".raw"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = response.InternalError(err).Render(w)
+			_ = s.state.Save()
+
+			return
+		}
+
+		app := s.state.Applications[name]
+		app.State.StagedVersion = ""
+		s.state.SetApplication(name, app)
+
+		s.state.SetUpdateStaged(name, false)
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+	_ = s.state.Save()
+}
+
+// swagger:operation POST /1.0/system/update/:apply system system_post_update_apply
+//
+//	Apply staged updates
+//
+//	Applies any OS and application updates previously downloaded while in download-only mode.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+func (s *Server) apiSystemUpdateApply(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Check that there's something to apply.
+	if !s.state.HasStagedUpdates() {
+		_ = response.BadRequest(errors.New("no staged updates to apply")).Render(w)
+
+		return
+	}
+
+	// Trigger applying the staged updates, unless already requested.
+	s.state.RequestApply()
 
 	_ = response.EmptySyncResponse.Render(w)
 }
+
+// swagger:operation POST /1.0/system/update/:import system system_post_update_import
+//
+//	Import an update bundle
+//
+//	Imports an offline update bundle and stages the OS and application updates it contains.
+//	The bundle is either provided as the request body or was previously sent through the upload endpoint.
+//	The staged updates can then be applied through the apply endpoint.
+//
+//	---
+//	consumes:
+//	  - application/x-tar
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: upload
+//	    description: Identifier of a completed chunked upload to import instead of the request body
+//	    type: string
+//	  - in: query
+//	    name: sha256
+//	    description: SHA256 checksum of the whole bundle, required when importing a chunked upload
+//	    type: string
+//	  - in: body
+//	    name: bundle
+//	    description: Update bundle tarball
+//	    required: false
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemUpdateImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// The bundle can take much longer to receive than the server's read timeout allows.
+	disableReadTimeout(w)
+
+	// Read the bundle from a previous chunked upload if requested.
+	body := io.Reader(r.Body)
+	uploadPath := ""
+
+	if r.FormValue("upload") != "" {
+		var err error
+
+		uploadPath, err = getUploadPath(r.FormValue("upload"))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		if r.FormValue("sha256") == "" {
+			_ = response.BadRequest(errors.New("missing upload checksum")).Render(w)
+
+			return
+		}
+
+		// Prevent any chunk from being written while the upload is imported.
+		unlock := s.lockUpload(r.FormValue("upload"))
+		defer unlock()
+
+		f, err := os.Open(uploadPath) //nolint:gosec
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		defer f.Close()
+
+		// Check the assembled upload against the checksum of the whole bundle.
+		hash := sha256.New()
+
+		_, err = io.Copy(hash, f)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		if hex.EncodeToString(hash.Sum(nil)) != strings.ToLower(r.FormValue("sha256")) {
+			_ = response.BadRequest(errors.New("upload checksum mismatch")).Render(w)
+
+			return
+		}
+
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		body = f
+	}
+
+	// Extract and verify the bundle next to the other update paths. This is done before taking the update lock,
+	// so receiving a large bundle doesn't hold up the update checks.
+	bundlePath, err := os.MkdirTemp(filepath.Dir(systemd.SystemUpdatesPath), "incus-os-import-")
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	defer func() { _ = os.RemoveAll(bundlePath) }()
+
+	bundle, err := providers.LoadUpdateBundle(r.Context(), s.state, body, bundlePath)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	s.state.UpdateMutex.Lock()
+	defer s.state.UpdateMutex.Unlock()
+
+	staged := false
+
+	// Stage the OS update.
+	osUpdate, err := bundle.GetOSUpdate()
+	if err == nil && osUpdate.IsNewerThan(s.state.OS.RunningRelease) && osUpdate.Version() != s.state.OS.NextRelease {
+		slog.InfoContext(r.Context(), "Staging OS update from bundle", "release", osUpdate.Version())
+
+		err = osUpdate.DownloadUpdate(r.Context(), systemd.SystemUpdatesPath, nil)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		providers.RecordProvenance(r.Context(), s.state, "bundle", osUpdate.GetProvenance)
+
+		s.state.OS.StagedRelease = osUpdate.Version()
+		staged = true
+	}
+
+	// Stage updates for the installed applications.
+	for appName, appInfo := range s.state.Applications {
+		app, err := bundle.GetApplication(appName)
+		if err != nil || !app.IsNewerThan(appInfo.State.Version) {
+			continue
+		}
+
+		slog.InfoContext(r.Context(), "Staging application update from bundle", "application", appName, "release", app.Version())
+
+		err = app.Download(r.Context(), systemd.SystemExtensionsStagingPath, nil)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Verify the application is signed with a trusted key in the kernel's keyring.
+		err = systemd.VerifyExtensionCertificateFingerprint(r.Context(), filepath.Join(systemd.SystemExtensionsStagingPath, appName+".raw"))
+		if err != nil {
+			_ = os.Remove(filepath.Join(systemd.SystemExtensionsStagingPath, appName+".raw"))
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		providers.RecordProvenance(r.Context(), s.state, "bundle", app.GetProvenance)
+
+		appInfo.State.StagedVersion = app.Version()
+		s.state.SetApplication(appName, appInfo)
+		staged = true
+	}
+
+	_ = s.state.Save()
+
+	if !staged {
+		_ = response.BadRequest(errors.New("update bundle doesn't contain any newer update")).Render(w)
+
+		return
+	}
+
+	// The uploaded bundle isn't needed anymore.
+	if uploadPath != "" {
+		_ = os.Remove(uploadPath)
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+// disableReadTimeout lifts the server's read timeout for a request streaming a large body.
+func disableReadTimeout(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+}
+
+// uploadIDRegex restricts the identifiers of chunked uploads to safe file names.
+var uploadIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// maxUploadChunkSize is the largest chunk accepted by the upload endpoint.
+const maxUploadChunkSize = 64 * 1024 * 1024
+
+// uploadExpiry is how long a chunked upload can go without receiving any data before being discarded.
+const uploadExpiry = 24 * time.Hour
+
+// uploadsPath is where the data received for chunked uploads is kept.
+var uploadsPath = filepath.Dir(systemd.SystemUpdatesPath)
+
+// uploadLock serializes the requests touching a given chunked upload.
+type uploadLock struct {
+	sync.Mutex
+
+	users int
+}
+
+// swagger:operation GET /1.0/system/update/:upload system system_get_update_upload
+//
+//	Get the state of a chunked upload
+//
+//	Returns how much of an update bundle has been received so far, allowing an interrupted upload to be resumed.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: id
+//	    description: Upload identifier
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    description: State of the upload
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: State of the upload
+//	          example: {"id":"bundle-202511041601","size":134217728}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+
+// swagger:operation POST /1.0/system/update/:upload system system_post_update_upload
+//
+//	Upload a chunk of an update bundle
+//
+//	Writes a chunk of an update bundle at the provided offset. The offset can't be past the amount of data
+//	received so far, but may be lower to retry a chunk, in which case any data after it is discarded.
+//	Each chunk is verified against its SHA256 checksum before being written.
+//	Once complete, the bundle can be imported through the import endpoint.
+//
+//	---
+//	consumes:
+//	  - application/octet-stream
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: id
+//	    description: Upload identifier
+//	    required: true
+//	    type: string
+//	  - in: query
+//	    name: offset
+//	    description: Offset of the chunk in the bundle
+//	    required: true
+//	    type: integer
+//	  - in: query
+//	    name: sha256
+//	    description: SHA256 checksum of the chunk
+//	    required: true
+//	    type: string
+//	  - in: body
+//	    name: chunk
+//	    description: Chunk of the update bundle, up to 64MiB
+//	    required: true
+//	responses:
+//	  "200":
+//	    description: State of the upload
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: json
+//	          description: State of the upload
+//	          example: {"id":"bundle-202511041601","size":201326592}
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+
+// swagger:operation DELETE /1.0/system/update/:upload system system_delete_update_upload
+//
+//	Discard a chunked upload
+//
+//	Removes any data received for the upload.
+//
+//	---
+//	produces:
+//	  - application/json
+//	parameters:
+//	  - in: query
+//	    name: id
+//	    description: Upload identifier
+//	    required: true
+//	    type: string
+//	responses:
+//	  "200":
+//	    $ref: "#/responses/EmptySyncResponse"
+//	  "400":
+//	    $ref: "#/responses/BadRequest"
+//	  "500":
+//	    $ref: "#/responses/InternalServerError"
+func (s *Server) apiSystemUpdateUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id := r.FormValue("id")
+
+	uploadPath, err := getUploadPath(id)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		unlock := s.lockUpload(id)
+		defer unlock()
+
+		size, err := getUploadSize(uploadPath)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.SyncResponse(true, api.SystemUpdateUpload{ID: id, Size: size}).Render(w)
+	case http.MethodPost:
+		offset, err := strconv.ParseInt(r.FormValue("offset"), 10, 64)
+		if err != nil {
+			_ = response.BadRequest(fmt.Errorf("invalid offset: %w", err)).Render(w)
+
+			return
+		}
+
+		if r.FormValue("sha256") == "" {
+			_ = response.BadRequest(errors.New("missing chunk checksum")).Render(w)
+
+			return
+		}
+
+		// Read and verify the whole chunk before taking the upload's lock, so a slow client only holds up itself.
+		disableReadTimeout(w)
+
+		chunk, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadChunkSize))
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		checksum := sha256.Sum256(chunk)
+		if hex.EncodeToString(checksum[:]) != strings.ToLower(r.FormValue("sha256")) {
+			_ = response.BadRequest(errors.New("chunk checksum mismatch")).Render(w)
+
+			return
+		}
+
+		unlock := s.lockUpload(id)
+		defer unlock()
+
+		size, err := getUploadSize(uploadPath)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		if offset < 0 || offset > size {
+			_ = response.BadRequest(fmt.Errorf("invalid offset %d, %d bytes received so far", offset, size)).Render(w)
+
+			return
+		}
+
+		err = storage.CheckFreeSpace(uploadPath, int64(len(chunk)))
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		err = writeUploadChunk(uploadPath, offset, chunk)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.SyncResponse(true, api.SystemUpdateUpload{ID: id, Size: offset + int64(len(chunk))}).Render(w)
+	case http.MethodDelete:
+		unlock := s.lockUpload(id)
+		defer unlock()
+
+		err := os.Remove(uploadPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+// getUploadPath returns the path holding the data received for a chunked upload.
+func getUploadPath(id string) (string, error) {
+	if !uploadIDRegex.MatchString(id) {
+		return "", fmt.Errorf("invalid upload identifier %q", id)
+	}
+
+	return filepath.Join(uploadsPath, "incus-os-upload-"+id), nil
+}
+
+// lockUpload takes the lock of a chunked upload, returning the function releasing it.
+func (s *Server) lockUpload(id string) func() {
+	s.uploadsMutex.Lock()
+
+	if s.uploads == nil {
+		s.uploads = map[string]*uploadLock{}
+	}
+
+	lock, ok := s.uploads[id]
+	if !ok {
+		lock = &uploadLock{}
+		s.uploads[id] = lock
+	}
+
+	lock.users++
+	s.uploadsMutex.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		s.uploadsMutex.Lock()
+		defer s.uploadsMutex.Unlock()
+
+		// Forget about the lock once nobody is using it anymore.
+		lock.users--
+		if lock.users == 0 {
+			delete(s.uploads, id)
+		}
+	}
+}
+
+// uploadCleanup periodically discards the chunked uploads which stopped receiving data.
+func (s *Server) uploadCleanup(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		s.removeStaleUploads(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// removeStaleUploads removes the chunked uploads which haven't received any data for longer than uploadExpiry.
+func (s *Server) removeStaleUploads(ctx context.Context) {
+	uploadPaths, err := filepath.Glob(filepath.Join(uploadsPath, "incus-os-upload-*"))
+	if err != nil {
+		return
+	}
+
+	for _, uploadPath := range uploadPaths {
+		id := strings.TrimPrefix(filepath.Base(uploadPath), "incus-os-upload-")
+
+		unlock := s.lockUpload(id)
+
+		info, err := os.Stat(uploadPath)
+		if err == nil && time.Since(info.ModTime()) > uploadExpiry {
+			slog.InfoContext(ctx, "Removing stale chunked upload", "id", id)
+
+			_ = os.Remove(uploadPath)
+		}
+
+		unlock()
+	}
+}
+
+// getUploadSize returns the number of bytes received so far for a chunked upload.
+func getUploadSize(uploadPath string) (int64, error) {
+	info, err := os.Stat(uploadPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// writeUploadChunk writes a chunk at the given offset, discarding any data previously received after it.
+func writeUploadChunk(uploadPath string, offset int64, chunk []byte) error {
+	f, err := os.OpenFile(uploadPath, os.O_WRONLY|os.O_CREATE, 0o600) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	err = f.Truncate(offset)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt(chunk, offset)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
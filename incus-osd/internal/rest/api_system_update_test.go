@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+func TestRemoveStaleUploads(t *testing.T) { //nolint:paralleltest
+	uploadsPath = t.TempDir()
+
+	s := &Server{}
+
+	staleUpload, err := getUploadPath("stale")
+	require.NoError(t, err)
+
+	activeUpload, err := getUploadPath("active")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(staleUpload, []byte("stale"), 0o600))
+	require.NoError(t, os.WriteFile(activeUpload, []byte("active"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(uploadsPath, "unrelated"), []byte("unrelated"), 0o600))
+
+	old := time.Now().Add(-uploadExpiry - time.Hour)
+	require.NoError(t, os.Chtimes(staleUpload, old, old))
+	require.NoError(t, os.Chtimes(filepath.Join(uploadsPath, "unrelated"), old, old))
+
+	s.removeStaleUploads(t.Context())
+
+	require.NoFileExists(t, staleUpload)
+	require.FileExists(t, activeUpload)
+	require.FileExists(t, filepath.Join(uploadsPath, "unrelated"))
+
+	// The per-upload locks are released once unused.
+	require.Empty(t, s.uploads)
+}
+
+func TestLockUpload(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{}
+
+	unlockFirst := s.lockUpload("first")
+
+	// Other uploads aren't held up.
+	unlockSecond := s.lockUpload("second")
+	unlockSecond()
+
+	// Requests for the same upload wait for the lock to be released.
+	locked := make(chan struct{})
+
+	go func() {
+		unlock := s.lockUpload("first")
+		close(locked)
+		unlock()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("upload lock was taken twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlockFirst()
+	<-locked
+
+	require.Eventually(t, func() bool {
+		s.uploadsMutex.Lock()
+		defer s.uploadsMutex.Unlock()
+
+		return len(s.uploads) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// slowBody returns a request body sending the data in small pieces over the given duration.
+func slowBody(data []byte, duration time.Duration) io.Reader {
+	reader, writer := io.Pipe()
+
+	go func() {
+		pieces := 10
+		size := (len(data) + pieces - 1) / pieces
+
+		for len(data) > 0 {
+			time.Sleep(duration / time.Duration(pieces))
+
+			n := min(size, len(data))
+
+			_, err := writer.Write(data[:n])
+			if err != nil {
+				return
+			}
+
+			data = data[n:]
+		}
+
+		_ = writer.Close()
+	}()
+
+	return reader
+}
+
+func TestUpdateImportSlowBody(t *testing.T) { //nolint:paralleltest
+	systemd.SystemUpdatesPath = filepath.Join(t.TempDir(), "updates")
+
+	s := &Server{state: &state.State{}}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(s.apiSystemUpdateImport))
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Start()
+
+	defer server.Close()
+
+	// A bundle without any update metadata, only rejected once fully received.
+	var bundle bytes.Buffer
+
+	tw := tar.NewWriter(&bundle)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "unrelated", Mode: 0o600, Size: 4096}))
+	_, err := tw.Write(make([]byte, 4096))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL, slowBody(bundle.Bytes(), 500*time.Millisecond))
+	require.NoError(t, err)
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "update bundle is missing update.sjson")
+}
+
+func TestUpdateUploadSlowBody(t *testing.T) { //nolint:paralleltest
+	uploadsPath = t.TempDir()
+
+	s := &Server{}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(s.apiSystemUpdateUpload))
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Start()
+
+	defer server.Close()
+
+	chunk := bytes.Repeat([]byte("chunk"), 1024)
+	checksum := sha256.Sum256(chunk)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+"?id=slow&offset=0&sha256="+hex.EncodeToString(checksum[:]), slowBody(chunk, 500*time.Millisecond))
+	require.NoError(t, err)
+
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	uploadPath, err := getUploadPath("slow")
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(uploadPath) //nolint:gosec
+	require.NoError(t, err)
+	require.Equal(t, chunk, content)
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// deprecatedConfigFields lists, for each endpoint, the deprecated configuration fields which are still
+// accepted along with the field replacing them.
+var deprecatedConfigFields = map[string]map[string]string{
+	"/1.0/system/security": {"recovery_keys": "encryption_recovery_keys"},
+	"/1.0/system/update":   {"update_frequency": "check_frequency"},
+}
+
+// swagger:operation GET /1.0/system/warnings system system_get_warnings
+//
+//	Get system warnings
+//
+//	Returns the list of current warnings about the system's configuration or state, such as insecure settings or pending required actions.
+//
+//	---
+//	produces:
+//	  - application/json
+//	responses:
+//	  "200":
+//	    description: List of warnings
+//	    schema:
+//	      type: object
+//	      description: Sync response
+//	      properties:
+//	        type:
+//	          description: Response type
+//	          example: sync
+//	          type: string
+//	        status:
+//	          type: string
+//	          description: Status description
+//	          example: Success
+//	        status_code:
+//	          type: integer
+//	          description: Status code
+//	          example: 200
+//	        metadata:
+//	          type: array
+//	          description: List of warnings
+//	          example: [{"type":"action-required","entity":"/1.0/system/update","message":"A reboot is required to finalize an update"}]
+func (s *Server) apiSystemWarnings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, s.state.Warnings()).Render(w)
+}
+
+// decodeConfigRequest decodes a request body holding a "config" object into target, replacing any deprecated
+// configuration field of the endpoint by its current name. A deprecation warning is returned for each of those.
+// If both a deprecated field and its replacement are set, the replacement wins.
+func decodeConfigRequest(body io.Reader, entity string, target any) ([]api.SystemWarning, error) {
+	request := map[string]json.RawMessage{}
+
+	err := json.NewDecoder(body).Decode(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := []api.SystemWarning{}
+
+	config := map[string]json.RawMessage{}
+
+	if request["config"] != nil {
+		err = json.Unmarshal(request["config"], &config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fields := deprecatedConfigFields[entity]
+
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		value, ok := config[field]
+		if !ok {
+			continue
+		}
+
+		_, ok = config[fields[field]]
+		if !ok {
+			config[fields[field]] = value
+		}
+
+		delete(config, field)
+
+		warnings = append(warnings, api.SystemWarning{
+			Type:    api.SystemWarningTypeDeprecated,
+			Entity:  entity,
+			Message: fmt.Sprintf("The %q configuration field is deprecated, use %q instead", field, fields[field]),
+		})
+	}
+
+	if len(warnings) > 0 {
+		request["config"], err = json.Marshal(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return warnings, nil
+}
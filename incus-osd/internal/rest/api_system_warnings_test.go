@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestDecodeConfigRequest(t *testing.T) {
+	t.Parallel()
+
+	// Current field names are decoded as-is.
+	update := &api.SystemUpdate{}
+
+	warnings, err := decodeConfigRequest(strings.NewReader(`{"config": {"check_frequency": "12h", "channel": "stable"}}`), "/1.0/system/update", update)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.Equal(t, "12h", update.Config.CheckFrequency)
+	require.Equal(t, "stable", update.Config.Channel)
+
+	// Deprecated fields are renamed and reported.
+	update = &api.SystemUpdate{}
+
+	warnings, err = decodeConfigRequest(strings.NewReader(`{"config": {"update_frequency": "12h", "channel": "stable"}}`), "/1.0/system/update", update)
+	require.NoError(t, err)
+	require.Equal(t, []api.SystemWarning{{Type: api.SystemWarningTypeDeprecated, Entity: "/1.0/system/update", Message: `The "update_frequency" configuration field is deprecated, use "check_frequency" instead`}}, warnings)
+	require.Equal(t, "12h", update.Config.CheckFrequency)
+	require.Equal(t, "stable", update.Config.Channel)
+
+	// The current field wins over the deprecated one.
+	update = &api.SystemUpdate{}
+
+	warnings, err = decodeConfigRequest(strings.NewReader(`{"config": {"update_frequency": "12h", "check_frequency": "6h"}}`), "/1.0/system/update", update)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Equal(t, "6h", update.Config.CheckFrequency)
+
+	// Deprecated fields only apply to their own endpoint.
+	security := &api.SystemSecurity{}
+
+	warnings, err = decodeConfigRequest(strings.NewReader(`{"config": {"recovery_keys": ["key"], "update_frequency": "12h"}}`), "/1.0/system/security", security)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Equal(t, []string{"key"}, security.Config.EncryptionRecoveryKeys)
+
+	// Requests without a configuration are left alone.
+	security = &api.SystemSecurity{}
+
+	warnings, err = decodeConfigRequest(strings.NewReader(`{"config": null}`), "/1.0/system/security", security)
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+
+	// Invalid requests are rejected.
+	_, err = decodeConfigRequest(strings.NewReader(`{"config": []}`), "/1.0/system/security", security)
+	require.Error(t, err)
+
+	_, err = decodeConfigRequest(strings.NewReader(``), "/1.0/system/security", security)
+	require.Error(t, err)
+}
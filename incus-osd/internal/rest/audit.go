@@ -0,0 +1,257 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	incustls "github.com/lxc/incus/v6/shared/tls"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// auditLogPath is where the audit log of mutating API calls is kept, one JSON entry per line.
+const auditLogPath = "/var/lib/incus-os/audit.log"
+
+// auditLogMaxSize is the size above which the audit log is rotated, keeping a single previous file.
+const auditLogMaxSize = 4 * 1024 * 1024
+
+// peerCredentialsKey is the context key holding the credentials of the process connected to the local socket.
+type peerCredentialsKey struct{}
+
+// withPeerCredentials records the credentials of the process connected to the local socket in the connection's context.
+func withPeerCredentials(ctx context.Context, c net.Conn) context.Context {
+	unixConn, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return ctx
+	}
+
+	var ucred *unix.Ucred
+
+	ctrlErr := rawConn.Control(func(fd uintptr) {
+		ucred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if ctrlErr != nil || err != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, peerCredentialsKey{}, ucred)
+}
+
+// withAuditLog records every call which may modify the system, along with the identity of the caller, a digest of
+// the provided payload and the resulting status code.
+func (s *Server) withAuditLog(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			handler.ServeHTTP(w, r)
+
+			return
+		}
+
+		start := time.Now()
+
+		body := &digestWrapper{ReadCloser: r.Body, hash: sha256.New()}
+		r.Body = body
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		handler.ServeHTTP(recorder, r)
+
+		// Consume whatever the handler didn't read so the digest covers the full payload.
+		_, _ = io.Copy(io.Discard, body)
+
+		source, identity := s.callerIdentity(r)
+
+		entry := api.DebugAuditEntry{
+			Time:        start.UTC(),
+			Source:      source,
+			Identity:    identity,
+			Method:      r.Method,
+			Endpoint:    r.URL.Path,
+			PayloadSize: body.n,
+			StatusCode:  recorder.status,
+			Duration:    time.Since(start).Milliseconds(),
+		}
+
+		if body.n > 0 {
+			entry.PayloadSha256 = hex.EncodeToString(body.hash.Sum(nil))
+		}
+
+		err := s.recordAuditEntry(entry)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to record API call in the audit log", "method", r.Method, "endpoint", r.URL.Path, "err", err.Error())
+		}
+	})
+}
+
+// callerIdentity returns the source of a request and the identity of its caller.
+func (s *Server) callerIdentity(r *http.Request) (string, string) {
+	if r.TLS != nil {
+		if len(r.TLS.PeerCertificates) == 0 {
+			return "remote", "unknown"
+		}
+
+		cert := r.TLS.PeerCertificates[0]
+		fingerprint := incustls.CertFingerprint(cert)
+
+		name := s.trustedCertificateName(cert)
+		if name == "" {
+			return "remote", "certificate=" + fingerprint
+		}
+
+		return "remote", fmt.Sprintf("certificate=%s name=%s", fingerprint, name)
+	}
+
+	ucred, ok := r.Context().Value(peerCredentialsKey{}).(*unix.Ucred)
+	if !ok {
+		return "local", "unknown"
+	}
+
+	identity := fmt.Sprintf("uid=%d gid=%d pid=%d", ucred.Uid, ucred.Gid, ucred.Pid)
+
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", ucred.Pid))
+	if err == nil {
+		identity += " comm=" + strings.TrimSpace(string(comm))
+	}
+
+	return "local", identity
+}
+
+// recordAuditEntry appends an entry to the audit log, rotating it if it grew too large.
+func (s *Server) recordAuditEntry(entry api.DebugAuditEntry) error {
+	s.auditMutex.Lock()
+	defer s.auditMutex.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(auditLogPath)
+	if err == nil && info.Size()+int64(len(data)) > auditLogMaxSize {
+		err = os.Rename(auditLogPath, auditLogPath+".1")
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(auditLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// getAuditEntries returns the audit log entries matching the provided filters, oldest first. An empty method or
+// endpoint prefix, or a zero time, matches all entries. If limit is positive, only the most recent entries are returned.
+func (s *Server) getAuditEntries(method string, endpoint string, since time.Time, limit int) ([]api.DebugAuditEntry, error) {
+	s.auditMutex.Lock()
+	defer s.auditMutex.Unlock()
+
+	ret := []api.DebugAuditEntry{}
+
+	for _, path := range []string{auditLogPath + ".1", auditLogPath} {
+		f, err := os.Open(path) //nolint:gosec
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry := api.DebugAuditEntry{}
+
+			err := json.Unmarshal(scanner.Bytes(), &entry)
+			if err != nil {
+				continue
+			}
+
+			if method != "" && !strings.EqualFold(entry.Method, method) {
+				continue
+			}
+
+			if endpoint != "" && !strings.HasPrefix(entry.Endpoint, endpoint) {
+				continue
+			}
+
+			if !since.IsZero() && entry.Time.Before(since) {
+				continue
+			}
+
+			ret = append(ret, entry)
+		}
+
+		err = scanner.Err()
+		_ = f.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limit > 0 && len(ret) > limit {
+		ret = ret[len(ret)-limit:]
+	}
+
+	return ret, nil
+}
+
+// digestWrapper computes the digest and size of a request body as it's read.
+type digestWrapper struct {
+	io.ReadCloser
+
+	hash hash.Hash
+	n    int
+}
+
+func (w *digestWrapper) Read(p []byte) (int, error) {
+	n, err := w.ReadCloser.Read(p)
+	w.n += n
+	_, _ = w.hash.Write(p[:n])
+
+	return n, err
+}
+
+// statusRecorder keeps track of the status code returned to the client.
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.status = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
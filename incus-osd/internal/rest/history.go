@@ -0,0 +1,245 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// historyLogPath is where the history of configuration changes is kept, one JSON entry per line.
+const historyLogPath = "/var/lib/incus-os/history.log"
+
+// historyLogMaxSize is the size above which the history is rotated, keeping a single previous file.
+const historyLogMaxSize = 4 * 1024 * 1024
+
+// historyPointerEscaper escapes the keys making up a JSON pointer, as per RFC 6901.
+var historyPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// historySnapshot returns the current configuration, with its credentials redacted, in its generic form.
+func (s *Server) historySnapshot() (any, error) {
+	config, err := s.exportSystemConfig(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return configTree(config)
+}
+
+// withHistory serializes the calls which may modify the configuration through an endpoint, recording the resulting
+// changes in the history. The configuration is only compared before and after the call while holding the lock, so
+// the changes of concurrent calls can't be attributed to the wrong one.
+func (s *Server) withHistory(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			handler(w, r)
+
+			return
+		}
+
+		s.configMutex.Lock()
+		defer s.configMutex.Unlock()
+
+		start := time.Now()
+
+		// Keep a copy of the configuration to record the changes made by the call.
+		before, err := s.historySnapshot()
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to snapshot the configuration for the change history", "err", err.Error())
+
+			handler(w, r)
+
+			return
+		}
+
+		handler(w, r)
+
+		err = s.recordHistoryChanges(r, start, before)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Failed to record configuration changes in the history", "method", r.Method, "endpoint", r.URL.Path, "err", err.Error())
+		}
+	}
+}
+
+// recordHistoryChanges compares the current configuration to a snapshot taken before an API call, recording the
+// differences in the history, if any.
+func (s *Server) recordHistoryChanges(r *http.Request, start time.Time, before any) error {
+	after, err := s.historySnapshot()
+	if err != nil {
+		return err
+	}
+
+	changes := diffConfig("", before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	source, identity := s.callerIdentity(r)
+
+	entry := api.SystemHistoryEntry{
+		Time:     start.UTC(),
+		Source:   source,
+		Identity: identity,
+		Method:   r.Method,
+		Endpoint: r.URL.Path,
+		Changes:  changes,
+	}
+
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(historyLogPath)
+	if err == nil && info.Size()+int64(len(data)) > historyLogMaxSize {
+		err = os.Rename(historyLogPath, historyLogPath+".1")
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(historyLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// getHistoryEntries returns the history entries matching the provided filters, oldest first. An empty endpoint prefix,
+// or a zero time, matches all entries. If limit is positive, only the most recent entries are returned.
+func (s *Server) getHistoryEntries(endpoint string, since time.Time, until time.Time, limit int) ([]api.SystemHistoryEntry, error) {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+
+	ret := []api.SystemHistoryEntry{}
+
+	for _, path := range []string{historyLogPath + ".1", historyLogPath} {
+		f, err := os.Open(path) //nolint:gosec
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		// Entries holding large configuration values can exceed the default line limit.
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, historyLogMaxSize)
+
+		for scanner.Scan() {
+			entry := api.SystemHistoryEntry{}
+
+			err := json.Unmarshal(scanner.Bytes(), &entry)
+			if err != nil {
+				continue
+			}
+
+			if endpoint != "" && !strings.HasPrefix(entry.Endpoint, endpoint) {
+				continue
+			}
+
+			if !since.IsZero() && entry.Time.Before(since) {
+				continue
+			}
+
+			if !until.IsZero() && entry.Time.After(until) {
+				continue
+			}
+
+			ret = append(ret, entry)
+		}
+
+		err = scanner.Err()
+		_ = f.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limit > 0 && len(ret) > limit {
+		ret = ret[len(ret)-limit:]
+	}
+
+	return ret, nil
+}
+
+// diffConfig returns the differences between two configurations in their generic form. Maps are compared key by key
+// and lists of the same length entry by entry, while any other change is reported as a whole.
+func diffConfig(path string, before any, after any) []api.SystemHistoryChange {
+	changes := []api.SystemHistoryChange{}
+
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+
+	if beforeIsMap && afterIsMap {
+		keys := slices.Collect(maps.Keys(beforeMap))
+		for key := range afterMap {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			keyPath := path + "/" + historyPointerEscaper.Replace(key)
+
+			beforeValue, inBefore := beforeMap[key]
+			afterValue, inAfter := afterMap[key]
+
+			switch {
+			case !inBefore:
+				changes = append(changes, api.SystemHistoryChange{Path: keyPath, New: afterValue})
+			case !inAfter:
+				changes = append(changes, api.SystemHistoryChange{Path: keyPath, Old: beforeValue})
+			default:
+				changes = append(changes, diffConfig(keyPath, beforeValue, afterValue)...)
+			}
+		}
+
+		return changes
+	}
+
+	beforeList, beforeIsList := before.([]any)
+	afterList, afterIsList := after.([]any)
+
+	if beforeIsList && afterIsList && len(beforeList) == len(afterList) {
+		for i := range beforeList {
+			changes = append(changes, diffConfig(path+"/"+strconv.Itoa(i), beforeList[i], afterList[i])...)
+		}
+
+		return changes
+	}
+
+	beforeData, err := json.Marshal(before)
+	if err == nil {
+		afterData, err := json.Marshal(after)
+		if err == nil && bytes.Equal(beforeData, afterData) {
+			return changes
+		}
+	}
+
+	return append(changes, api.SystemHistoryChange{Path: path, Old: before, New: after})
+}
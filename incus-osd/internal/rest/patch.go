@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// withMergePatch adds support for JSON merge patches (RFC 7386) to a system configuration endpoint. The patch is
+// merged into the endpoint's current configuration and the result is then applied through PUT, as a
+// full replacement. Changes are serialized through withHistory, so that concurrent writers don't overwrite
+// each other's changes.
+func (s *Server) withMergePatch(handler http.HandlerFunc) http.HandlerFunc {
+	return s.withHistory(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut && r.Method != http.MethodPatch {
+			handler(w, r)
+
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			handler(w, r)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		patch := map[string]any{}
+
+		err := json.NewDecoder(r.Body).Decode(&patch)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		for key := range patch {
+			if key != "config" {
+				_ = response.BadRequest(fmt.Errorf("only the configuration can be patched, got %q", key)).Render(w)
+
+				return
+			}
+		}
+
+		current, err := s.getCurrentConfig(r.URL.Path)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		data, err := json.Marshal(map[string]any{"config": mergePatch(current, patch["config"])})
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Apply the merged configuration.
+		putReq := r.Clone(r.Context())
+		putReq.Method = http.MethodPut
+		putReq.Body = io.NopCloser(bytes.NewReader(data))
+		putReq.ContentLength = int64(len(data))
+
+		handler(w, putReq)
+	})
+}
+
+// getCurrentConfig returns the current configuration of a system configuration endpoint, in its JSON form. It's
+// built from the state the same way as the endpoint's GET response, without going through the handler itself, as
+// that may have side effects such as marking the encryption recovery keys as retrieved.
+func (s *Server) getCurrentConfig(path string) (any, error) {
+	config, err := s.exportSystemConfig(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var current any
+
+	switch path {
+	case "/1.0/system/dns":
+		current = config.DNS
+	case "/1.0/system/logging":
+		current = config.Logging
+	case "/1.0/system/network":
+		current = config.Network
+	case "/1.0/system/notifications":
+		current = config.Notifications
+	case "/1.0/system/provider":
+		current = config.Provider
+	case "/1.0/system/security":
+		// The encryption recovery keys are left out of exports, but must be part of the replacement configuration.
+		config.Security.EncryptionRecoveryKeys = s.state.System.Security.Config.EncryptionRecoveryKeys
+		current = config.Security
+	case "/1.0/system/update":
+		current = config.Update
+	default:
+		return nil, fmt.Errorf("configuration of %q can't be patched", path)
+	}
+
+	// Convert the configuration to its generic JSON form.
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret any
+
+	err = json.Unmarshal(data, &ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// mergePatch applies a JSON merge patch to a JSON document. Objects are merged recursively, null values remove the
+// matching key and any other value, including arrays, replaces the existing one.
+func mergePatch(target any, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+
+			continue
+		}
+
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		result string
+	}{
+		{
+			name:   "null deletion",
+			target: `{"a":"b","c":"d"}`,
+			patch:  `{"a":null}`,
+			result: `{"c":"d"}`,
+		},
+		{
+			name:   "nested merge",
+			target: `{"a":{"b":"c","d":{"e":"f","g":"h"}},"i":"j"}`,
+			patch:  `{"a":{"d":{"e":"x","k":"l"}}}`,
+			result: `{"a":{"b":"c","d":{"e":"x","g":"h","k":"l"}},"i":"j"}`,
+		},
+		{
+			name:   "nested null deletion",
+			target: `{"a":{"b":"c","d":"e"}}`,
+			patch:  `{"a":{"b":null}}`,
+			result: `{"a":{"d":"e"}}`,
+		},
+		{
+			name:   "array replacement",
+			target: `{"a":[{"b":"c"},{"d":"e"}],"f":"g"}`,
+			patch:  `{"a":[{"x":"y"}]}`,
+			result: `{"a":[{"x":"y"}],"f":"g"}`,
+		},
+		{
+			name:   "object replacing a value",
+			target: `{"a":"b"}`,
+			patch:  `{"a":{"c":"d"}}`,
+			result: `{"a":{"c":"d"}}`,
+		},
+		{
+			name:   "value replacing an object",
+			target: `{"a":{"b":"c"}}`,
+			patch:  `{"a":false}`,
+			result: `{"a":false}`,
+		},
+		{
+			name:   "empty target",
+			target: `null`,
+			patch:  `{"a":"b","c":null}`,
+			result: `{"a":"b"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var target, patch any
+
+			require.NoError(t, json.Unmarshal([]byte(tt.target), &target))
+			require.NoError(t, json.Unmarshal([]byte(tt.patch), &patch))
+
+			result, err := json.Marshal(mergePatch(target, patch))
+			require.NoError(t, err)
+			require.JSONEq(t, tt.result, string(result))
+		})
+	}
+}
+
+func TestGetCurrentConfig(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{state: &state.State{}}
+	s.state.System.Security.Config.EncryptionRecoveryKeys = []string{"recovery-key"}
+	s.state.System.Security.Config.AutoTPMRebind = true
+
+	// The security configuration includes the recovery keys, without marking them as retrieved.
+	current, err := s.getCurrentConfig("/1.0/system/security")
+	require.NoError(t, err)
+
+	config, ok := current.(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, []any{"recovery-key"}, config["encryption_recovery_keys"])
+	require.Equal(t, true, config["auto_tpm_rebind"])
+	require.False(t, s.state.System.Security.State.EncryptionRecoveryKeysRetrieved)
+
+	// The state isn't modified.
+	require.Equal(t, []string{"recovery-key"}, s.state.System.Security.Config.EncryptionRecoveryKeys)
+
+	// Other endpoints can't be patched.
+	_, err = s.getCurrentConfig("/1.0/system/storage")
+	require.Error(t, err)
+}
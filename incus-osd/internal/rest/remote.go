@@ -0,0 +1,254 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	incustls "github.com/lxc/incus/v6/shared/tls"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+// defaultRemoteAPIPort is the port the remote API listens on when none is configured.
+const defaultRemoteAPIPort = 9443
+
+// remoteAPICertificateRenewal is how long before its expiry the remote API server certificate gets regenerated.
+const remoteAPICertificateRenewal = 30 * 24 * time.Hour
+
+// remoteAPICertificatePaths returns the paths of the remote API server certificate and key.
+func remoteAPICertificatePaths() (string, string) {
+	return "/var/lib/incus-os/api.crt", "/var/lib/incus-os/api.key"
+}
+
+// ValidateRemoteAPI checks that the provided remote API configuration is usable.
+func ValidateRemoteAPI(config *api.SystemSecurityRemoteAPI) error {
+	if config == nil {
+		return nil
+	}
+
+	if config.Port < 0 || config.Port > 65535 {
+		return fmt.Errorf("invalid remote API port %d", config.Port)
+	}
+
+	if len(config.TrustedCertificates) == 0 {
+		return errors.New("at least one trusted certificate must be provided for the remote API")
+	}
+
+	names := map[string]bool{}
+
+	for _, trusted := range config.TrustedCertificates {
+		if trusted.Name == "" {
+			return errors.New("trusted certificates must have a name")
+		}
+
+		if names[trusted.Name] {
+			return fmt.Errorf("duplicate trusted certificate %q", trusted.Name)
+		}
+
+		names[trusted.Name] = true
+
+		_, err := parseTrustedCertificate(trusted.Certificate)
+		if err != nil {
+			return fmt.Errorf("invalid trusted certificate %q: %w", trusted.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ConfigureRemoteAPI (re)starts the HTTPS listener exposing the REST API on the management address, or stops
+// it if the configuration is nil. Clients must present one of the configured trusted certificates.
+func (s *Server) ConfigureRemoteAPI(ctx context.Context, config *api.SystemSecurityRemoteAPI) error {
+	s.remoteAPIMutex.Lock()
+	defer s.remoteAPIMutex.Unlock()
+
+	err := ValidateRemoteAPI(config)
+	if err != nil {
+		return err
+	}
+
+	address := ""
+
+	if config != nil {
+		mgmtAddr := s.state.ManagementAddress()
+		if mgmtAddr == nil {
+			return errors.New("no management address available for the remote API")
+		}
+
+		port := config.Port
+		if port == 0 {
+			port = defaultRemoteAPIPort
+		}
+
+		address = net.JoinHostPort(mgmtAddr.String(), strconv.Itoa(port))
+	}
+
+	if s.remoteAPI != nil && s.remoteAPIAddress == address {
+		return nil
+	}
+
+	// Stop the existing listener.
+	if s.remoteAPI != nil {
+		_ = s.remoteAPI.Close()
+
+		s.remoteAPI = nil
+		s.remoteAPIAddress = ""
+		s.state.System.Security.State.RemoteAPI = nil
+	}
+
+	if address == "" {
+		return nil
+	}
+
+	keypair, err := getRemoteAPICertificate(ctx)
+	if err != nil {
+		return err
+	}
+
+	lc := &net.ListenConfig{}
+
+	listener, err := lc.Listen(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	// Any client certificate is accepted during the handshake, as trusted certificates are
+	// usually self-signed. They are then checked against the trusted list on each request.
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{*keypair},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MinVersion:   tls.VersionTLS13,
+	}
+
+	server := &http.Server{
+		Handler: s.withTrustedCertificate(s.newRouter()),
+
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 0,
+	}
+
+	go func() {
+		err := server.Serve(tls.NewListener(listener, tlsConfig))
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.WarnContext(ctx, "Remote API listener failed", "address", address, "err", err.Error())
+		}
+	}()
+
+	s.remoteAPI = server
+	s.remoteAPIAddress = address
+	s.state.System.Security.State.RemoteAPI = &api.SystemSecurityRemoteAPIState{
+		Address:     address,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: keypair.Leaf.Raw})),
+		Fingerprint: incustls.CertFingerprint(keypair.Leaf),
+	}
+
+	slog.InfoContext(ctx, "Remote API listening", "address", address, "fingerprint", s.state.System.Security.State.RemoteAPI.Fingerprint)
+
+	return nil
+}
+
+// withTrustedCertificate only lets through requests authenticated with one of the trusted client certificates.
+func (s *Server) withTrustedCertificate(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 || !s.isTrustedCertificate(r.TLS.PeerCertificates[0]) {
+			w.Header().Set("Content-Type", "application/json")
+
+			_ = response.Forbidden(nil).Render(w)
+
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// isTrustedCertificate checks whether a client certificate is currently valid and part of the trusted list.
+func (s *Server) isTrustedCertificate(cert *x509.Certificate) bool {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return false
+	}
+
+	return s.trustedCertificateName(cert) != ""
+}
+
+// trustedCertificateName returns the name of the trusted certificate matching a client certificate, if any.
+func (s *Server) trustedCertificateName(cert *x509.Certificate) string {
+	config := s.state.System.Security.Config.RemoteAPI
+	if config == nil {
+		return ""
+	}
+
+	fingerprint := incustls.CertFingerprint(cert)
+
+	for _, trusted := range config.TrustedCertificates {
+		trustedCert, err := parseTrustedCertificate(trusted.Certificate)
+		if err != nil {
+			continue
+		}
+
+		if incustls.CertFingerprint(trustedCert) == fingerprint {
+			return trusted.Name
+		}
+	}
+
+	return ""
+}
+
+// parseTrustedCertificate parses a PEM encoded trusted certificate.
+func parseTrustedCertificate(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// getRemoteAPICertificate returns the remote API server certificate, generating a new self-signed one if
+// missing or about to expire.
+func getRemoteAPICertificate(ctx context.Context) (*tls.Certificate, error) {
+	certFile, keyFile := remoteAPICertificatePaths()
+
+	keypair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil && keypair.Leaf != nil && time.Until(keypair.Leaf.NotAfter) > remoteAPICertificateRenewal {
+		return &keypair, nil
+	}
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.WarnContext(ctx, "Failed to load the remote API server certificate, generating a new one", "err", err.Error())
+	}
+
+	certPEM, keyPEM, err := incustls.GenerateMemCert(false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(keyFile, keyPEM, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(certFile, certPEM, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	keypair, err = tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keypair, nil
+}
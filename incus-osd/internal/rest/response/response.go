@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/lxc/incus/v6/shared/api"
+
+	osapi "github.com/lxc/incus-os/incus-osd/api"
 )
 
 // Response represents an API response.
@@ -28,6 +30,7 @@ type syncResponse struct {
 	headers   map[string]string
 	plaintext bool
 	compress  bool
+	warnings  []osapi.SystemWarning
 }
 
 // EmptySyncResponse represents an empty syncResponse.
@@ -60,6 +63,21 @@ func SyncResponseHeaders(success bool, metadata any, headers map[string]string)
 	return &syncResponse{success: success, metadata: metadata, headers: headers}
 }
 
+// SyncResponseWarnings returns a new syncResponse carrying a list of warnings.
+func SyncResponseWarnings(success bool, metadata any, warnings []osapi.SystemWarning) Response {
+	return &syncResponse{success: success, metadata: metadata, warnings: warnings}
+}
+
+// SyncResponseWarningsETag returns a new syncResponse carrying a list of warnings and an etag.
+func SyncResponseWarningsETag(success bool, metadata any, warnings []osapi.SystemWarning, etag any) Response {
+	return &syncResponse{success: success, metadata: metadata, warnings: warnings, etag: etag}
+}
+
+// SyncResponseStatus returns a new syncResponse with a custom HTTP status code.
+func SyncResponseStatus(success bool, metadata any, code int) Response {
+	return &syncResponse{success: success, metadata: metadata, code: code}
+}
+
 // SyncResponsePlain return a new syncResponse with plaintext.
 func SyncResponsePlain(success bool, compress bool, metadata string) Response {
 	return &syncResponse{success: success, metadata: metadata, plaintext: true, compress: compress}
@@ -162,6 +180,15 @@ func (r *syncResponse) Render(w http.ResponseWriter) error {
 	enc := json.NewEncoder(w)
 	enc.SetEscapeHTML(false)
 
+	// Include any warnings alongside the regular response fields.
+	if len(r.warnings) > 0 {
+		return enc.Encode(struct {
+			api.ResponseRaw
+
+			Warnings []osapi.SystemWarning `json:"warnings"`
+		}{ResponseRaw: resp, Warnings: r.warnings})
+	}
+
 	err := enc.Encode(resp)
 	if err != nil {
 		return err
@@ -72,3 +72,21 @@ type swaggerInternalServerError struct {
 		ErrorCode int `json:"error_code"`
 	}
 }
+
+// Precondition failed
+//
+// swagger:response PreconditionFailed
+type swaggerPreconditionFailed struct {
+	// Precondition failed
+	// in: body
+	Body struct {
+		// Example: error
+		Type string `json:"type"`
+
+		// Example: precondition failed
+		Error string `json:"error"`
+
+		// Example: 412
+		ErrorCode int `json:"error_code"`
+	}
+}
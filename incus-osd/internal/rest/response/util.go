@@ -4,6 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 )
 
 // etagHash hashes the provided data and returns the sha256.
@@ -17,3 +20,26 @@ func etagHash(data any) (string, error) {
 
 	return hex.EncodeToString(etag.Sum(nil)), nil
 }
+
+// EtagCheck validates the hash of the current state with the hash provided by the client in the If-Match
+// header. Requests without an If-Match header, or with a wildcard one, are always accepted.
+func EtagCheck(r *http.Request, data any) error {
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if match == "" || match == "*" {
+		return nil
+	}
+
+	hash, err := etagHash(data)
+	if err != nil {
+		return err
+	}
+
+	for candidate := range strings.SplitSeq(match, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if strings.Trim(candidate, "\"") == hash {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("ETag doesn't match: %s vs %s", hash, match)
+}
@@ -2,19 +2,54 @@ package rest
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
+// degradedAllowedEndpoints lists the endpoints which can still be used to modify the system while the state
+// storage is degraded, as they don't need to persist any change.
+var degradedAllowedEndpoints = []string{
+	"/1.0/system/:poweroff",
+	"/1.0/system/:reboot",
+	"/1.0/system/network/test",
+	"/1.0/system/self-check/:run",
+}
+
 // Server holds the internal state of the REST API server.
 type Server struct {
 	socketPath string
 	state      *state.State
+
+	uploadsMutex sync.Mutex
+	uploads      map[string]*uploadLock
+
+	healthProbeMutex   sync.Mutex
+	healthProbe        *http.Server
+	healthProbeAddress string
+
+	healthzMutex     sync.Mutex
+	healthzStatus    api.HealthStatus
+	healthzCheckedAt time.Time
+
+	remoteAPIMutex   sync.Mutex
+	remoteAPI        *http.Server
+	remoteAPIAddress string
+
+	auditMutex sync.Mutex
+
+	historyMutex sync.Mutex
+
+	configMutex sync.Mutex
 }
 
 // NewServer returns a REST API server object.
@@ -45,52 +80,125 @@ func (s *Server) Serve(ctx context.Context) error {
 		return err
 	}
 
-	// Setup routing.
+	// Discard the chunked uploads which were abandoned.
+	go s.uploadCleanup(ctx)
+
+	// Setup server.
+	server := &http.Server{
+		Handler:     s.newRouter(),
+		ConnContext: withPeerCredentials,
+
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 0,
+	}
+
+	return server.Serve(listener)
+}
+
+// newRouter returns the handler serving the REST API.
+func (s *Server) newRouter() http.Handler {
 	router := http.NewServeMux()
 
 	router.HandleFunc("/", s.apiRoot)
+	router.HandleFunc("/healthz", s.apiHealthz)
 	router.HandleFunc("/1.0", s.apiRoot10)
-	router.HandleFunc("/1.0/applications", s.apiApplications)
-	router.HandleFunc("/1.0/applications/{name}", s.apiApplicationsEndpoint)
+	router.HandleFunc("/1.0/applications", s.withHistory(s.apiApplications))
+	router.HandleFunc("/1.0/applications/{name}", s.withHistory(s.apiApplicationsEndpoint))
+	router.HandleFunc("/1.0/applications/{name}/backup", s.apiApplicationsBackupEndpoint)
 	router.HandleFunc("/1.0/applications/{name}/:backup", s.apiApplicationsBackup)
-	router.HandleFunc("/1.0/applications/{name}/:factory-reset", s.apiApplicationsFactoryReset)
+	router.HandleFunc("/1.0/applications/{name}/:factory-reset", s.withHistory(s.apiApplicationsFactoryReset))
+	router.HandleFunc("/1.0/applications/{name}/:install", s.withHistory(s.apiApplicationsInstall))
+	router.HandleFunc("/1.0/applications/{name}/:remove", s.withHistory(s.apiApplicationsRemove))
 	router.HandleFunc("/1.0/applications/{name}/:restart", s.apiApplicationsRestart)
-	router.HandleFunc("/1.0/applications/{name}/:restore", s.apiApplicationsRestore)
-	router.HandleFunc("/1.0/debug", s.apiDebug)
-	router.HandleFunc("/1.0/debug/log", s.apiDebugLog)
-	router.HandleFunc("/1.0/debug/secureboot/:update", s.apiDebugSecureBootUpdate)
-	router.HandleFunc("/1.0/debug/tui/:write-message", s.apiDebugTUI)
+	router.HandleFunc("/1.0/applications/{name}/:restore", s.withHistory(s.apiApplicationsRestore))
+	router.HandleFunc("/1.0/applications/{name}/:start", s.apiApplicationsStart)
+	router.HandleFunc("/1.0/applications/{name}/:stop", s.apiApplicationsStop)
+	router.HandleFunc("/1.0/applications/{name}/:update", s.apiApplicationsUpdate)
+	router.HandleFunc("/1.0/cluster/peers", s.apiClusterPeers)
+	router.HandleFunc("/1.0/debug", s.withDebugAccess(s.apiDebug))
+	router.HandleFunc("/1.0/debug/audit", s.withDebugAccess(s.apiDebugAudit))
+	router.HandleFunc("/1.0/debug/kdump", s.withDebugAccess(s.apiDebugKdump))
+	router.HandleFunc("/1.0/debug/kdump/{name}", s.withDebugAccess(s.apiDebugKdumpEndpoint))
+	router.HandleFunc("/1.0/debug/kdump/{name}/:delete", s.withDebugAccess(s.apiDebugKdumpDelete))
+	router.HandleFunc("/1.0/debug/kdump/{name}/:download", s.withDebugAccess(s.apiDebugKdumpDownload))
+	router.HandleFunc("/1.0/debug/log", s.withDebugAccess(s.apiDebugLog))
+	router.HandleFunc("/1.0/debug/secureboot/:update", s.withDebugAccess(s.apiDebugSecureBootUpdate))
+	router.HandleFunc("/1.0/debug/tui/:write-message", s.withDebugAccess(s.apiDebugTUI))
+	router.HandleFunc("/1.0/events", s.apiEvents)
+	router.HandleFunc("/1.0/health", s.apiHealth)
 	router.HandleFunc("/1.0/services", s.apiServices)
-	router.HandleFunc("/1.0/services/{name}", s.apiServicesEndpoint)
-	router.HandleFunc("/1.0/services/{name}/:reset", s.apiServicesEndpointReset)
+	router.HandleFunc("/1.0/services/{name}", s.withHistory(s.apiServicesEndpoint))
+	router.HandleFunc("/1.0/services/{name}/:reset", s.withHistory(s.apiServicesEndpointReset))
+	router.HandleFunc("/1.0/services/{name}/:restart", s.apiServicesEndpointRestart)
+	router.HandleFunc("/1.0/services/{name}/log", s.apiServicesEndpointLog)
+	router.HandleFunc("/1.0/services/{name}/status", s.apiServicesEndpointStatus)
 	router.HandleFunc("/1.0/system", s.apiSystem)
+	router.HandleFunc("/1.0/system/:apply-profile", s.withHistory(s.apiSystemApplyProfile))
+	router.HandleFunc("/1.0/system/:apply-seed", s.withHistory(s.apiSystemApplySeed))
 	router.HandleFunc("/1.0/system/:backup", s.apiSystemBackup)
+	router.HandleFunc("/1.0/system/:export-config", s.apiSystemExportConfig)
 	router.HandleFunc("/1.0/system/:factory-reset", s.apiSystemFactoryReset)
+	router.HandleFunc("/1.0/system/:import-config", s.withHistory(s.apiSystemImportConfig))
 	router.HandleFunc("/1.0/system/:poweroff", s.apiSystemPoweroff)
 	router.HandleFunc("/1.0/system/:reboot", s.apiSystemReboot)
-	router.HandleFunc("/1.0/system/:restore", s.apiSystemRestore)
-	router.HandleFunc("/1.0/system/logging", s.apiSystemLogging)
-	router.HandleFunc("/1.0/system/network", s.apiSystemNetwork)
-	router.HandleFunc("/1.0/system/provider", s.apiSystemProvider)
+	router.HandleFunc("/1.0/system/:restore", s.withHistory(s.apiSystemRestore))
+	router.HandleFunc("/1.0/system/dns", s.withMergePatch(s.apiSystemDNS))
+	router.HandleFunc("/1.0/system/history", s.apiSystemHistory)
+	router.HandleFunc("/1.0/system/logging", s.withMergePatch(s.apiSystemLogging))
+	router.HandleFunc("/1.0/system/network", s.withMergePatch(s.apiSystemNetwork))
+	router.HandleFunc("/1.0/system/network/proxy-log", s.apiSystemNetworkProxyLog)
+	router.HandleFunc("/1.0/system/network/test", s.apiSystemNetworkTest)
+	router.HandleFunc("/1.0/system/notifications", s.withMergePatch(s.apiSystemNotifications))
+	router.HandleFunc("/1.0/system/provider", s.withMergePatch(s.apiSystemProvider))
 	router.HandleFunc("/1.0/system/resources", s.apiSystemResources)
-	router.HandleFunc("/1.0/system/security", s.apiSystemSecurity)
+	router.HandleFunc("/1.0/system/security", s.withMergePatch(s.apiSystemSecurity))
+	router.HandleFunc("/1.0/system/security/:add-fido2-token", s.apiSystemSecurityAddFIDO2Token)
+	router.HandleFunc("/1.0/system/security/:add-recovery-key", s.apiSystemSecurityAddRecoveryKey)
+	router.HandleFunc("/1.0/system/security/:export-boot-report", s.apiSystemSecurityExportBootReport)
+	router.HandleFunc("/1.0/system/security/:grant-debug", s.apiSystemSecurityGrantDebug)
+	router.HandleFunc("/1.0/system/security/:remove-fido2-token", s.apiSystemSecurityRemoveFIDO2Token)
+	router.HandleFunc("/1.0/system/security/:remove-recovery-key", s.apiSystemSecurityRemoveRecoveryKey)
+	router.HandleFunc("/1.0/system/security/:repair-boot-order", s.apiSystemSecurityRepairBootOrder)
+	router.HandleFunc("/1.0/system/security/:revoke-debug", s.apiSystemSecurityRevokeDebug)
+	router.HandleFunc("/1.0/system/security/:rotate-recovery-key", s.apiSystemSecurityRotateRecoveryKey)
 	router.HandleFunc("/1.0/system/security/:tpm-rebind", s.apiSystemSecurityTPMRebind)
+	router.HandleFunc("/1.0/system/security/pcr-preview", s.apiSystemSecurityPCRPreview)
+	router.HandleFunc("/1.0/system/security/key-change-preview", s.apiSystemSecurityKeyChangePreview)
+	router.HandleFunc("/1.0/system/self-check", s.apiSystemSelfCheck)
+	router.HandleFunc("/1.0/system/self-check/:run", s.apiSystemSelfCheckRun)
 	router.HandleFunc("/1.0/system/storage", s.apiSystemStorage)
 	router.HandleFunc("/1.0/system/storage/:create-volume", s.apiSystemStorageCreateVolume)
 	router.HandleFunc("/1.0/system/storage/:delete-pool", s.apiSystemStorageDeletePool)
 	router.HandleFunc("/1.0/system/storage/:delete-volume", s.apiSystemStorageDeleteVolume)
 	router.HandleFunc("/1.0/system/storage/:import-pool", s.apiSystemStorageImportPool)
 	router.HandleFunc("/1.0/system/storage/:wipe-drive", s.apiSystemStorageWipeDrive)
-	router.HandleFunc("/1.0/system/update", s.apiSystemUpdate)
+	router.HandleFunc("/1.0/system/update", s.withMergePatch(s.apiSystemUpdate))
+	router.HandleFunc("/1.0/system/update/:apply", s.apiSystemUpdateApply)
+	router.HandleFunc("/1.0/system/update/:cancel", s.apiSystemUpdateCancel)
 	router.HandleFunc("/1.0/system/update/:check", s.apiSystemUpdateCheck)
+	router.HandleFunc("/1.0/system/update/:download", s.apiSystemUpdateDownload)
+	router.HandleFunc("/1.0/system/update/:import", s.apiSystemUpdateImport)
+	router.HandleFunc("/1.0/system/update/:upload", s.apiSystemUpdateUpload)
+	router.HandleFunc("/1.0/system/warnings", s.apiSystemWarnings)
 
-	// Setup server.
-	server := &http.Server{
-		Handler: router,
+	return s.withAuditLog(s.withStateStorage(router))
+}
 
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 0,
-	}
+// withStateStorage refuses requests which would modify the system while the state storage is degraded, since
+// any change would be lost on restart.
+func (s *Server) withStateStorage(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !slices.Contains(degradedAllowedEndpoints, r.URL.Path) {
+			err := s.state.StorageError()
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
 
-	return server.Serve(listener)
+				_ = response.Unavailable(fmt.Errorf("%w: %w", state.ErrStorageDegraded, err)).Render(w)
+
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r)
+	})
 }
@@ -16,3 +16,41 @@ func (w *countWrapper) Read(p []byte) (int, error) {
 
 	return n, err
 }
+
+// progressReader counts the bytes read, reporting the total so far after each read.
+type progressReader struct {
+	io.Reader
+
+	n        int64
+	progress func(int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+
+	if r.progress != nil && n > 0 {
+		r.progress(r.n)
+	}
+
+	return n, err
+}
+
+// progressWriter counts the bytes written, reporting the total so far after each write.
+type progressWriter struct {
+	io.Writer
+
+	n        int64
+	progress func(int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += int64(n)
+
+	if w.progress != nil && n > 0 {
+		w.progress(w.n)
+	}
+
+	return n, err
+}
@@ -0,0 +1,536 @@
+package secrets
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// providerSecretKeys lists the configuration keys holding credentials, for each update provider.
+var providerSecretKeys = map[string][]string{
+	"images":            {"auth_token"},
+	"operations-center": {"server_token"},
+}
+
+// notificationSecretKeys lists the configuration keys holding credentials, for each notification backend type.
+var notificationSecretKeys = map[string][]string{
+	"email": {"password"},
+}
+
+// dnsSecretKeys lists the configuration keys holding credentials, for each DNS provider type.
+var dnsSecretKeys = map[string][]string{
+	"cloudflare": {"api_token"},
+	"rfc2136":    {"tsig_secret"},
+	"route53":    {"secret_access_key"},
+}
+
+// SealState seals all the credentials stored in the state which are still in plaintext.
+func SealState(s *state.State) error {
+	err := SealNetworkConfig(s.System.Network.Config, s.System.Network.Config)
+	if err != nil {
+		return err
+	}
+
+	err = SealProviderConfig(&s.System.Provider.Config, s.System.Provider.Config)
+	if err != nil {
+		return err
+	}
+
+	if s.System.Update.Config.VerificationProvider != nil {
+		err = SealProviderConfig(s.System.Update.Config.VerificationProvider, *s.System.Update.Config.VerificationProvider)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = SealNotificationsConfig(&s.System.Notifications.Config, s.System.Notifications.Config)
+	if err != nil {
+		return err
+	}
+
+	err = SealISCSIConfig(&s.Services.ISCSI.Config, s.Services.ISCSI.Config)
+	if err != nil {
+		return err
+	}
+
+	err = SealCephConfig(&s.Services.Ceph.Config, s.Services.Ceph.Config)
+	if err != nil {
+		return err
+	}
+
+	err = SealOVNConfig(&s.Services.OVN.Config, s.Services.OVN.Config)
+	if err != nil {
+		return err
+	}
+
+	return SealDNSConfig(&s.System.DNS.Config, s.System.DNS.Config)
+}
+
+// OpenState decrypts all the credentials stored in the state and clears the vault key, so the
+// state can be used on another system.
+func OpenState(s *state.State) error {
+	if s.System.Network.Config != nil && s.System.Network.Config.Proxy != nil {
+		for name, server := range s.System.Network.Config.Proxy.Servers {
+			var err error
+
+			server.Password, err = Open(server.Password)
+			if err != nil {
+				return err
+			}
+
+			s.System.Network.Config.Proxy.Servers[name] = server
+		}
+	}
+
+	if s.System.Network.Config != nil {
+		for _, iface := range s.System.Network.Config.Interfaces {
+			if iface.Auth == nil {
+				continue
+			}
+
+			var err error
+
+			iface.Auth.Password, err = Open(iface.Auth.Password)
+			if err != nil {
+				return err
+			}
+
+			iface.Auth.ClientKey, err = Open(iface.Auth.ClientKey)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var err error
+
+	s.System.Provider.Config.Config, err = OpenMap(s.System.Provider.Config.Config)
+	if err != nil {
+		return err
+	}
+
+	if s.System.Update.Config.VerificationProvider != nil {
+		s.System.Update.Config.VerificationProvider.Config, err = OpenMap(s.System.Update.Config.VerificationProvider.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, backend := range s.System.Notifications.Config.Backends {
+		s.System.Notifications.Config.Backends[i].Config, err = OpenMap(backend.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, provider := range s.System.DNS.Config.Providers {
+		s.System.DNS.Config.Providers[i].Config, err = OpenMap(provider.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, target := range s.Services.ISCSI.Config.Targets {
+		if target.CHAP == nil {
+			continue
+		}
+
+		chap, err := OpenISCSICHAP(*target.CHAP)
+		if err != nil {
+			return err
+		}
+
+		s.Services.ISCSI.Config.Targets[i].CHAP = &chap
+	}
+
+	for _, cluster := range s.Services.Ceph.Config.Clusters {
+		for name, keyring := range cluster.Keyrings {
+			var err error
+
+			keyring.Key, err = Open(keyring.Key)
+			if err != nil {
+				return err
+			}
+
+			cluster.Keyrings[name] = keyring
+		}
+	}
+
+	s.Services.OVN.Config.TLSClientKey, err = Open(s.Services.OVN.Config.TLSClientKey)
+	if err != nil {
+		return err
+	}
+
+	s.SecretsKey = ""
+	s.SecretsKeyFallback = ""
+
+	return nil
+}
+
+// SealNetworkConfig seals the proxy passwords and 802.1X credentials of a network configuration.
+// Redacted values are replaced with those of the same proxy server or interface in the current configuration.
+func SealNetworkConfig(config *api.SystemNetworkConfig, current *api.SystemNetworkConfig) error {
+	if config == nil {
+		return nil
+	}
+
+	currentAuth := map[string]api.SystemNetworkAuth{}
+	if current != nil {
+		for _, iface := range current.Interfaces {
+			if iface.Auth != nil {
+				currentAuth[iface.Name] = *iface.Auth
+			}
+		}
+	}
+
+	for i, iface := range config.Interfaces {
+		if iface.Auth == nil {
+			continue
+		}
+
+		auth := *iface.Auth
+
+		var err error
+
+		auth.Password, err = SealValue(auth.Password, currentAuth[iface.Name].Password)
+		if err != nil {
+			return err
+		}
+
+		auth.ClientKey, err = SealValue(auth.ClientKey, currentAuth[iface.Name].ClientKey)
+		if err != nil {
+			return err
+		}
+
+		config.Interfaces[i].Auth = &auth
+	}
+
+	if config.Proxy == nil || config.Proxy.Servers == nil {
+		return nil
+	}
+
+	currentServers := map[string]api.SystemNetworkProxyServer{}
+	if current != nil && current.Proxy != nil {
+		currentServers = current.Proxy.Servers
+	}
+
+	servers := make(map[string]api.SystemNetworkProxyServer, len(config.Proxy.Servers))
+
+	for name, server := range config.Proxy.Servers {
+		var err error
+
+		server.Password, err = SealValue(server.Password, currentServers[name].Password)
+		if err != nil {
+			return err
+		}
+
+		servers[name] = server
+	}
+
+	config.Proxy.Servers = servers
+
+	return nil
+}
+
+// RedactNetworkConfig returns a copy of the network configuration with the proxy passwords and
+// 802.1X credentials redacted.
+func RedactNetworkConfig(config *api.SystemNetworkConfig) *api.SystemNetworkConfig {
+	if config == nil {
+		return config
+	}
+
+	redacted := *config
+
+	if config.Interfaces != nil {
+		redacted.Interfaces = slices.Clone(config.Interfaces)
+
+		for i, iface := range redacted.Interfaces {
+			if iface.Auth == nil {
+				continue
+			}
+
+			auth := *iface.Auth
+			auth.Password = RedactValue(auth.Password)
+			auth.ClientKey = RedactValue(auth.ClientKey)
+			redacted.Interfaces[i].Auth = &auth
+		}
+	}
+
+	if config.Proxy == nil || config.Proxy.Servers == nil {
+		return &redacted
+	}
+
+	redacted.Proxy = &api.SystemNetworkProxy{}
+	*redacted.Proxy = *config.Proxy
+	redacted.Proxy.Servers = maps.Clone(config.Proxy.Servers)
+
+	for name, server := range redacted.Proxy.Servers {
+		server.Password = RedactValue(server.Password)
+		redacted.Proxy.Servers[name] = server
+	}
+
+	return &redacted
+}
+
+// SealProviderConfig seals the credentials of an update provider configuration. Redacted credentials
+// are replaced with the current ones when the provider is unchanged.
+func SealProviderConfig(config *api.SystemProviderConfig, current api.SystemProviderConfig) error {
+	if current.Name != config.Name {
+		current = api.SystemProviderConfig{}
+	}
+
+	var err error
+
+	config.Config, err = SealMap(config.Config, providerSecretKeys[config.Name], current.Config)
+
+	return err
+}
+
+// RedactProviderConfig returns a copy of the update provider configuration with the credentials redacted.
+func RedactProviderConfig(config api.SystemProviderConfig) api.SystemProviderConfig {
+	config.Config = RedactMap(config.Config, providerSecretKeys[config.Name])
+
+	return config
+}
+
+// SealNotificationsConfig seals the credentials of the notification backends. Redacted credentials
+// are replaced with those of the backend of the same name in the current configuration.
+func SealNotificationsConfig(config *api.SystemNotificationsConfig, current api.SystemNotificationsConfig) error {
+	currentConfigs := map[string]map[string]string{}
+	for _, backend := range current.Backends {
+		currentConfigs[backend.Name] = backend.Config
+	}
+
+	backends := make([]api.SystemNotificationsBackend, 0, len(config.Backends))
+
+	for _, backend := range config.Backends {
+		var err error
+
+		backend.Config, err = SealMap(backend.Config, notificationSecretKeys[backend.Type], currentConfigs[backend.Name])
+		if err != nil {
+			return err
+		}
+
+		backends = append(backends, backend)
+	}
+
+	if config.Backends != nil {
+		config.Backends = backends
+	}
+
+	return nil
+}
+
+// RedactNotificationsConfig returns a copy of the notifications configuration with the credentials redacted.
+func RedactNotificationsConfig(config api.SystemNotificationsConfig) api.SystemNotificationsConfig {
+	if config.Backends == nil {
+		return config
+	}
+
+	backends := make([]api.SystemNotificationsBackend, 0, len(config.Backends))
+
+	for _, backend := range config.Backends {
+		backend.Config = RedactMap(backend.Config, notificationSecretKeys[backend.Type])
+		backends = append(backends, backend)
+	}
+
+	config.Backends = backends
+
+	return config
+}
+
+// SealDNSConfig seals the credentials of the DNS providers. Redacted credentials are replaced with
+// those of the provider of the same name in the current configuration.
+func SealDNSConfig(config *api.SystemDNSConfig, current api.SystemDNSConfig) error {
+	currentConfigs := map[string]map[string]string{}
+	for _, provider := range current.Providers {
+		currentConfigs[provider.Name] = provider.Config
+	}
+
+	providers := make([]api.SystemDNSProvider, 0, len(config.Providers))
+
+	for _, provider := range config.Providers {
+		var err error
+
+		provider.Config, err = SealMap(provider.Config, dnsSecretKeys[provider.Type], currentConfigs[provider.Name])
+		if err != nil {
+			return err
+		}
+
+		providers = append(providers, provider)
+	}
+
+	if config.Providers != nil {
+		config.Providers = providers
+	}
+
+	return nil
+}
+
+// RedactDNSConfig returns a copy of the DNS configuration with the credentials redacted.
+func RedactDNSConfig(config api.SystemDNSConfig) api.SystemDNSConfig {
+	if config.Providers == nil {
+		return config
+	}
+
+	providers := make([]api.SystemDNSProvider, 0, len(config.Providers))
+
+	for _, provider := range config.Providers {
+		provider.Config = RedactMap(provider.Config, dnsSecretKeys[provider.Type])
+		providers = append(providers, provider)
+	}
+
+	config.Providers = providers
+
+	return config
+}
+
+// SealISCSIConfig seals the CHAP passwords of the iSCSI targets. Redacted passwords are replaced with
+// those of the target of the same name in the current configuration.
+func SealISCSIConfig(config *api.ServiceISCSIConfig, current api.ServiceISCSIConfig) error {
+	currentCHAP := map[string]api.ServiceISCSICHAP{}
+	for _, target := range current.Targets {
+		if target.CHAP != nil {
+			currentCHAP[target.Target] = *target.CHAP
+		}
+	}
+
+	for i, target := range config.Targets {
+		if target.CHAP == nil {
+			continue
+		}
+
+		chap := *target.CHAP
+
+		var err error
+
+		chap.Password, err = SealValue(chap.Password, currentCHAP[target.Target].Password)
+		if err != nil {
+			return err
+		}
+
+		chap.MutualPassword, err = SealValue(chap.MutualPassword, currentCHAP[target.Target].MutualPassword)
+		if err != nil {
+			return err
+		}
+
+		config.Targets[i].CHAP = &chap
+	}
+
+	return nil
+}
+
+// RedactISCSIConfig returns a copy of the iSCSI configuration with the CHAP passwords redacted.
+func RedactISCSIConfig(config api.ServiceISCSIConfig) api.ServiceISCSIConfig {
+	if config.Targets == nil {
+		return config
+	}
+
+	targets := make([]api.ServiceISCSITarget, 0, len(config.Targets))
+
+	for _, target := range config.Targets {
+		if target.CHAP != nil {
+			chap := *target.CHAP
+			chap.Password = RedactValue(chap.Password)
+			chap.MutualPassword = RedactValue(chap.MutualPassword)
+			target.CHAP = &chap
+		}
+
+		targets = append(targets, target)
+	}
+
+	config.Targets = targets
+
+	return config
+}
+
+// OpenISCSICHAP returns a copy of the CHAP credentials with the passwords decrypted.
+func OpenISCSICHAP(chap api.ServiceISCSICHAP) (api.ServiceISCSICHAP, error) {
+	var err error
+
+	chap.Password, err = Open(chap.Password)
+	if err != nil {
+		return chap, err
+	}
+
+	chap.MutualPassword, err = Open(chap.MutualPassword)
+	if err != nil {
+		return chap, err
+	}
+
+	return chap, nil
+}
+
+// SealCephConfig seals the keyring keys of the Ceph clusters. Redacted keys are replaced with those
+// of the same keyring in the current configuration.
+func SealCephConfig(config *api.ServiceCephConfig, current api.ServiceCephConfig) error {
+	for clusterName, cluster := range config.Clusters {
+		keyrings := make(map[string]api.ServiceCephKeyring, len(cluster.Keyrings))
+
+		for name, keyring := range cluster.Keyrings {
+			var err error
+
+			keyring.Key, err = SealValue(keyring.Key, current.Clusters[clusterName].Keyrings[name].Key)
+			if err != nil {
+				return err
+			}
+
+			keyrings[name] = keyring
+		}
+
+		cluster.Keyrings = keyrings
+		config.Clusters[clusterName] = cluster
+	}
+
+	return nil
+}
+
+// RedactCephConfig returns a copy of the Ceph configuration with the keyring keys redacted.
+func RedactCephConfig(config api.ServiceCephConfig) api.ServiceCephConfig {
+	if config.Clusters == nil {
+		return config
+	}
+
+	clusters := make(map[string]api.ServiceCephCluster, len(config.Clusters))
+
+	for clusterName, cluster := range config.Clusters {
+		keyrings := make(map[string]api.ServiceCephKeyring, len(cluster.Keyrings))
+
+		for name, keyring := range cluster.Keyrings {
+			keyring.Key = RedactValue(keyring.Key)
+			keyrings[name] = keyring
+		}
+
+		cluster.Keyrings = keyrings
+		clusters[clusterName] = cluster
+	}
+
+	config.Clusters = clusters
+
+	return config
+}
+
+// SealOVNConfig seals the OVN client key. A redacted key is replaced with the current one.
+func SealOVNConfig(config *api.ServiceOVNConfig, current api.ServiceOVNConfig) error {
+	var err error
+
+	config.TLSClientKey, err = SealValue(config.TLSClientKey, current.TLSClientKey)
+
+	return err
+}
+
+// RedactOVNConfig returns a copy of the OVN configuration with the client key redacted.
+func RedactOVNConfig(config api.ServiceOVNConfig) api.ServiceOVNConfig {
+	config.TLSClientKey = RedactValue(config.TLSClientKey)
+
+	return config
+}
+
+// RedactSSHConfig returns a copy of the SSH configuration with the console password hash redacted.
+func RedactSSHConfig(config api.ServiceSSHConfig) api.ServiceSSHConfig {
+	config.ConsolePasswordHash = RedactValue(config.ConsolePasswordHash)
+
+	return config
+}
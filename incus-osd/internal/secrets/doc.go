@@ -0,0 +1,6 @@
+// Package secrets implements a vault sealing credentials, such as proxy passwords, provider tokens
+// or SMTP passwords, before they're written to the persistent state.
+//
+// Values are encrypted with a vault key which is itself stored in the state, sealed through
+// systemd-creds to the TPM and the host key. Sealed values are redacted when retrieved through the API.
+package secrets
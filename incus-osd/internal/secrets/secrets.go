@@ -0,0 +1,317 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// ErrNotLoaded is returned when sealing or opening a value before the vault key is loaded.
+var ErrNotLoaded = errors.New("secrets vault isn't loaded")
+
+// credentialName is the name the vault key is sealed under by systemd-creds.
+const credentialName = "incus-osd-secrets"
+
+// sealedPrefix marks a sealed value.
+const sealedPrefix = "sealed:"
+
+// Redacted is returned in place of a secret when retrieving a configuration through the API.
+const Redacted = "********"
+
+var (
+	key      []byte
+	keyMutex sync.RWMutex
+)
+
+// Load unseals the vault key from the state, generating one on first use, then seals any credential
+// still stored in plaintext in the state.
+func Load(ctx context.Context, s *state.State) error {
+	err := loadKey(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	return SealState(s)
+}
+
+// loadKey unseals or generates the vault key. The key is unsealed from its fallback copy if it can't be
+// unsealed from the TPM, such as after the TPM was cleared, and then sealed to the TPM again.
+func loadKey(ctx context.Context, s *state.State) error {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+
+	resealKey := s.SecretsKey == ""
+
+	if s.SecretsKey != "" {
+		content, err := systemdCreds(ctx, "decrypt", s.SecretsKey, "")
+		if err != nil {
+			if s.SecretsKeyFallback == "" {
+				return err
+			}
+
+			slog.WarnContext(ctx, "Failed to unseal the secrets vault key, using its fallback copy", "err", err.Error())
+
+			content, err = systemdCreds(ctx, "decrypt", s.SecretsKeyFallback, "")
+			if err != nil {
+				return err
+			}
+
+			resealKey = true
+		}
+
+		key, err = hex.DecodeString(strings.TrimSpace(content))
+		if err != nil {
+			return err
+		}
+	} else {
+		slog.InfoContext(ctx, "Generating a new secrets vault key")
+
+		key = make([]byte, 32)
+
+		_, err := rand.Read(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(key) != 32 {
+		key = nil
+
+		return errors.New("invalid secrets vault key length")
+	}
+
+	if resealKey {
+		sealedKey, err := systemdCreds(ctx, "encrypt", hex.EncodeToString(key), "auto")
+		if err != nil {
+			key = nil
+
+			return err
+		}
+
+		s.SecretsKey = sealedKey
+	}
+
+	// Keep a copy of the key sealed to the host key only, which lives on the encrypted root volume.
+	if s.SecretsKeyFallback == "" {
+		sealedKey, err := systemdCreds(ctx, "encrypt", hex.EncodeToString(key), "host")
+		if err != nil {
+			key = nil
+
+			return err
+		}
+
+		s.SecretsKeyFallback = sealedKey
+	}
+
+	return nil
+}
+
+// IsSealed returns whether the value is sealed.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Seal encrypts a value so it can be safely stored in the state. Empty and already sealed values are returned as-is.
+func Seal(value string) (string, error) {
+	if value == "" || IsSealed(value) {
+		return value, nil
+	}
+
+	aead, err := getAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a sealed value. Values which aren't sealed are returned as-is.
+func Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := getAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("sealed value is too short")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// SealValue seals a value provided through the API. A redacted value is replaced with the current one.
+func SealValue(value string, current string) (string, error) {
+	if value == Redacted {
+		value = current
+	}
+
+	return Seal(value)
+}
+
+// RedactValue returns the value to expose through the API in place of a credential.
+func RedactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return Redacted
+}
+
+// SealMap returns a copy of the map with the values of the given keys sealed. Redacted values are
+// replaced with those from the current map.
+func SealMap(values map[string]string, keys []string, current map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	sealed := maps.Clone(values)
+
+	for _, k := range keys {
+		value, ok := sealed[k]
+		if !ok {
+			continue
+		}
+
+		var err error
+
+		sealed[k], err = SealValue(value, current[k])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sealed, nil
+}
+
+// RedactMap returns a copy of the map with the values of the given keys redacted.
+func RedactMap(values map[string]string, keys []string) map[string]string {
+	if values == nil {
+		return nil
+	}
+
+	redacted := maps.Clone(values)
+
+	for _, k := range keys {
+		_, ok := redacted[k]
+		if ok {
+			redacted[k] = RedactValue(redacted[k])
+		}
+	}
+
+	return redacted
+}
+
+// OpenMap returns a copy of the map with all sealed values decrypted.
+func OpenMap(values map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	opened := make(map[string]string, len(values))
+
+	for k, value := range values {
+		plaintext, err := Open(value)
+		if err != nil {
+			return nil, err
+		}
+
+		opened[k] = plaintext
+	}
+
+	return opened, nil
+}
+
+// getAEAD returns the cipher used to seal secrets.
+func getAEAD() (cipher.AEAD, error) {
+	keyMutex.RLock()
+	defer keyMutex.RUnlock()
+
+	if key == nil {
+		return nil, ErrNotLoaded
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// systemdCreds encrypts or decrypts the vault key through systemd-creds, replaced in tests. With the "auto"
+// key type, the key is sealed to the TPM when one is available, along with the host key, but isn't bound
+// to any PCR so it remains usable across OS and firmware updates. With the "host" key type, it's only
+// sealed to the host key.
+var systemdCreds = func(ctx context.Context, action string, input string, withKey string) (string, error) {
+	// systemd-creds reads its input from a file.
+	inputFile, err := os.CreateTemp("/run", "incus-osd-secrets-")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.Remove(inputFile.Name())
+
+	_, err = inputFile.WriteString(input)
+	if err != nil {
+		_ = inputFile.Close()
+
+		return "", err
+	}
+
+	err = inputFile.Close()
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{action, "--name=" + credentialName}
+	if action == "encrypt" {
+		args = append(args, "--with-key="+withKey)
+
+		if withKey == "auto" {
+			args = append(args, "--tpm2-pcrs=")
+		}
+	}
+
+	output, err := timeout.RunCommand(ctx, "systemd-creds", append(args, inputFile.Name(), "-")...)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep the sealed key on a single line in the state.
+	return strings.Join(strings.Fields(output), ""), nil
+}
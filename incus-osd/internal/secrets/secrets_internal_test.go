@@ -0,0 +1,252 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// loadTestKey sets a fixed vault key for the tests.
+func loadTestKey() {
+	keyMutex.Lock()
+	defer keyMutex.Unlock()
+
+	key = make([]byte, 32)
+}
+
+// Test unsealing the vault key from its fallback copy.
+func TestLoadKeyFallback(t *testing.T) { //nolint:paralleltest
+	tpmCleared := false
+
+	oldSystemdCreds := systemdCreds
+	systemdCreds = func(_ context.Context, action string, input string, withKey string) (string, error) {
+		if action == "encrypt" {
+			return withKey + ":" + input, nil
+		}
+
+		if tpmCleared && strings.HasPrefix(input, "auto:") {
+			return "", errors.New("TPM2 seal/unseal failed")
+		}
+
+		_, content, _ := strings.Cut(input, ":")
+
+		return content, nil
+	}
+
+	defer func() { systemdCreds = oldSystemdCreds }()
+
+	// A new key is sealed to the TPM, with a fallback copy sealed to the host key.
+	s := &state.State{}
+
+	err := loadKey(t.Context(), s)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(s.SecretsKey, "auto:"))
+	require.True(t, strings.HasPrefix(s.SecretsKeyFallback, "host:"))
+
+	sealed, err := Seal("my-token")
+	require.NoError(t, err)
+
+	// The fallback copy is used when the key can't be unsealed from the TPM, and the key is sealed again.
+	tpmCleared = true
+	s.SecretsKey = "auto:broken"
+
+	err = loadKey(t.Context(), s)
+	require.NoError(t, err)
+	require.NotEqual(t, "auto:broken", s.SecretsKey)
+
+	plaintext, err := Open(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "my-token", plaintext)
+
+	// Without a fallback copy, the error is reported.
+	s.SecretsKey = "auto:broken"
+	s.SecretsKeyFallback = ""
+
+	err = loadKey(t.Context(), s)
+	require.Error(t, err)
+}
+
+// Test sealing and opening of secrets.
+func TestSealOpen(t *testing.T) {
+	t.Parallel()
+
+	loadTestKey()
+
+	sealed, err := Seal("my-token")
+	require.NoError(t, err)
+	require.True(t, IsSealed(sealed))
+	require.NotContains(t, sealed, "my-token")
+
+	// Sealing twice gives different values.
+	sealedAgain, err := Seal("my-token")
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, sealedAgain)
+
+	// Already sealed and empty values are left alone.
+	resealed, err := Seal(sealed)
+	require.NoError(t, err)
+	require.Equal(t, sealed, resealed)
+
+	empty, err := Seal("")
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	// Opening gives the original value.
+	plaintext, err := Open(sealed)
+	require.NoError(t, err)
+	require.Equal(t, "my-token", plaintext)
+
+	// Values which aren't sealed are returned as-is.
+	plaintext, err = Open("not-sealed")
+	require.NoError(t, err)
+	require.Equal(t, "not-sealed", plaintext)
+
+	// Tampered values are rejected.
+	_, err = Open(sealed[:len(sealed)-4] + "AAAA")
+	require.Error(t, err)
+}
+
+// Test sealing and redaction of the credentials in configurations.
+func TestSealRedactConfig(t *testing.T) {
+	t.Parallel()
+
+	loadTestKey()
+
+	config := api.SystemNotificationsConfig{
+		Backends: []api.SystemNotificationsBackend{
+			{Name: "ops", Type: "webhook", Config: map[string]string{"url": "https://example.com/hook"}},
+			{Name: "admins", Type: "email", Config: map[string]string{"server": "smtp.example.com", "password": "hunter2"}},
+		},
+	}
+
+	err := SealNotificationsConfig(&config, api.SystemNotificationsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/hook", config.Backends[0].Config["url"])
+	require.True(t, IsSealed(config.Backends[1].Config["password"]))
+
+	// Redaction doesn't affect the original configuration.
+	redacted := RedactNotificationsConfig(config)
+	require.Equal(t, Redacted, redacted.Backends[1].Config["password"])
+	require.True(t, IsSealed(config.Backends[1].Config["password"]))
+
+	// Submitting a redacted configuration keeps the current credentials.
+	err = SealNotificationsConfig(&redacted, config)
+	require.NoError(t, err)
+
+	plaintext, err := Open(redacted.Backends[1].Config["password"])
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", plaintext)
+
+	// Redacted proxy passwords are restored the same way.
+	network := &api.SystemNetworkConfig{
+		Proxy: &api.SystemNetworkProxy{
+			Servers: map[string]api.SystemNetworkProxyServer{"corp": {Host: "proxy.example.com", Auth: "basic", Username: "user", Password: "pass"}},
+		},
+	}
+
+	err = SealNetworkConfig(network, nil)
+	require.NoError(t, err)
+
+	redactedNetwork := RedactNetworkConfig(network)
+	require.Equal(t, Redacted, redactedNetwork.Proxy.Servers["corp"].Password)
+	require.NotEqual(t, Redacted, network.Proxy.Servers["corp"].Password)
+
+	err = SealNetworkConfig(redactedNetwork, network)
+	require.NoError(t, err)
+
+	plaintext, err = Open(redactedNetwork.Proxy.Servers["corp"].Password)
+	require.NoError(t, err)
+	require.Equal(t, "pass", plaintext)
+
+	// As are the 802.1X credentials of an interface.
+	network.Interfaces = []api.SystemNetworkInterface{{Name: "eth0", Auth: &api.SystemNetworkAuth{Method: "peap", Identity: "host01", Password: "secret"}}}
+
+	err = SealNetworkConfig(network, nil)
+	require.NoError(t, err)
+
+	redactedNetwork = RedactNetworkConfig(network)
+	require.Equal(t, Redacted, redactedNetwork.Interfaces[0].Auth.Password)
+	require.Empty(t, redactedNetwork.Interfaces[0].Auth.ClientKey)
+	require.NotEqual(t, Redacted, network.Interfaces[0].Auth.Password)
+
+	err = SealNetworkConfig(redactedNetwork, network)
+	require.NoError(t, err)
+
+	plaintext, err = Open(redactedNetwork.Interfaces[0].Auth.Password)
+	require.NoError(t, err)
+	require.Equal(t, "secret", plaintext)
+
+	// As are the CHAP credentials of an iSCSI target.
+	iscsi := api.ServiceISCSIConfig{Targets: []api.ServiceISCSITarget{{Target: "iqn.2004-10.org.example:disk1", Address: "10.0.0.1", CHAP: &api.ServiceISCSICHAP{Username: "host01", Password: "chap-secret"}}}}
+
+	err = SealISCSIConfig(&iscsi, api.ServiceISCSIConfig{})
+	require.NoError(t, err)
+
+	redactedISCSI := RedactISCSIConfig(iscsi)
+	require.Equal(t, Redacted, redactedISCSI.Targets[0].CHAP.Password)
+	require.Empty(t, redactedISCSI.Targets[0].CHAP.MutualPassword)
+	require.True(t, IsSealed(iscsi.Targets[0].CHAP.Password))
+
+	err = SealISCSIConfig(&redactedISCSI, iscsi)
+	require.NoError(t, err)
+
+	chap, err := OpenISCSICHAP(*redactedISCSI.Targets[0].CHAP)
+	require.NoError(t, err)
+	require.Equal(t, "chap-secret", chap.Password)
+
+	// As are the keyring keys of a Ceph cluster.
+	ceph := api.ServiceCephConfig{Clusters: map[string]api.ServiceCephCluster{"ceph": {Keyrings: map[string]api.ServiceCephKeyring{"admin": {Key: "AQBkZXZlbG9wbWVudA=="}}}}}
+
+	err = SealCephConfig(&ceph, api.ServiceCephConfig{})
+	require.NoError(t, err)
+
+	redactedCeph := RedactCephConfig(ceph)
+	require.Equal(t, Redacted, redactedCeph.Clusters["ceph"].Keyrings["admin"].Key)
+	require.True(t, IsSealed(ceph.Clusters["ceph"].Keyrings["admin"].Key))
+
+	err = SealCephConfig(&redactedCeph, ceph)
+	require.NoError(t, err)
+
+	plaintext, err = Open(redactedCeph.Clusters["ceph"].Keyrings["admin"].Key)
+	require.NoError(t, err)
+	require.Equal(t, "AQBkZXZlbG9wbWVudA==", plaintext)
+
+	// Credentials aren't carried over to a different update provider.
+	provider := api.SystemProviderConfig{Name: "images", Config: map[string]string{"auth_token": Redacted}}
+
+	err = SealProviderConfig(&provider, api.SystemProviderConfig{Name: "operations-center", Config: map[string]string{"server_token": "token"}})
+	require.NoError(t, err)
+	require.Empty(t, provider.Config["auth_token"])
+
+	// As is the OVN client key.
+	ovn := api.ServiceOVNConfig{TLSClientCertificate: "cert", TLSClientKey: "client-key"}
+
+	err = SealOVNConfig(&ovn, api.ServiceOVNConfig{})
+	require.NoError(t, err)
+
+	redactedOVN := RedactOVNConfig(ovn)
+	require.Equal(t, Redacted, redactedOVN.TLSClientKey)
+	require.Equal(t, "cert", redactedOVN.TLSClientCertificate)
+	require.True(t, IsSealed(ovn.TLSClientKey))
+
+	err = SealOVNConfig(&redactedOVN, ovn)
+	require.NoError(t, err)
+
+	plaintext, err = Open(redactedOVN.TLSClientKey)
+	require.NoError(t, err)
+	require.Equal(t, "client-key", plaintext)
+
+	// The console password hash is only redacted, as it's already a hash.
+	ssh := api.ServiceSSHConfig{ConsolePasswordHash: "$y$j9T$salt$hash"}
+
+	redactedSSH := RedactSSHConfig(ssh)
+	require.Equal(t, Redacted, redactedSSH.ConsolePasswordHash)
+	require.Equal(t, "$y$j9T$salt$hash", ssh.ConsolePasswordHash)
+	require.Empty(t, RedactSSHConfig(api.ServiceSSHConfig{}).ConsolePasswordHash)
+}
@@ -0,0 +1,251 @@
+package secureboot
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// efiGlobalVariableGUID is the vendor GUID used by all the UEFI boot manager variables.
+const efiGlobalVariableGUID = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// bootEntryRegex matches the names of the individual boot entry EFI variables.
+var bootEntryRegex = regexp.MustCompile(`^Boot[0-9A-F]{4}$`)
+
+// efiLoadOptionPath is the path, relative to the ESP, of the systemd-boot binary used by the IncusOS boot entry.
+const efiLoadOptionPath = `\efi\systemd\systemd-boot`
+
+// GetBootOrder returns the current EFI boot order and whether the IncusOS boot entry is first in it.
+func GetBootOrder() (api.SystemSecurityBootOrder, error) {
+	ret := api.SystemSecurityBootOrder{}
+
+	order, err := readBootOrder()
+	if err != nil {
+		return ret, err
+	}
+
+	expected, err := getExpectedBootEntry(order)
+	if err != nil {
+		return ret, err
+	}
+
+	for _, entry := range order {
+		description, _, err := readBootEntry(entry)
+		if err != nil {
+			// Firmware commonly leaves entries in the boot order after their variable was removed.
+			ret.Entries = append(ret.Entries, fmt.Sprintf("Boot%04X: (stale)", entry))
+
+			continue
+		}
+
+		ret.Entries = append(ret.Entries, fmt.Sprintf("Boot%04X: %s", entry, description))
+	}
+
+	ret.Expected = fmt.Sprintf("Boot%04X", expected)
+	ret.Drifted = len(order) == 0 || order[0] != expected
+
+	return ret, nil
+}
+
+// RepairBootOrder moves the IncusOS boot entry back to the front of the EFI boot order.
+func RepairBootOrder(ctx context.Context) error {
+	order, err := readBootOrder()
+	if err != nil {
+		return err
+	}
+
+	expected, err := getExpectedBootEntry(order)
+	if err != nil {
+		return err
+	}
+
+	if len(order) > 0 && order[0] == expected {
+		return nil
+	}
+
+	// Build the new boot order, keeping all other entries in their current relative order.
+	newOrder := []uint16{expected}
+
+	for _, entry := range order {
+		if entry != expected {
+			newOrder = append(newOrder, entry)
+		}
+	}
+
+	data := make([]byte, 0, 2*len(newOrder))
+	for _, entry := range newOrder {
+		data = binary.LittleEndian.AppendUint16(data, entry)
+	}
+
+	return writeEFIVariable(ctx, "BootOrder", data)
+}
+
+// readBootOrder returns the list of boot entries from the BootOrder EFI variable.
+func readBootOrder() ([]uint16, error) {
+	val, err := readEFIVariable("BootOrder")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(val)%2 != 0 {
+		return nil, errors.New("invalid BootOrder EFI variable length")
+	}
+
+	order := make([]uint16, 0, len(val)/2)
+	for i := 0; i < len(val); i += 2 {
+		order = append(order, binary.LittleEndian.Uint16(val[i:]))
+	}
+
+	return order, nil
+}
+
+// getExpectedBootEntry returns the boot entry that should be first in the boot order.
+//
+// This is the entry pointing to the IncusOS systemd-boot binary if one exists,
+// otherwise the entry the system was booted from.
+func getExpectedBootEntry(order []uint16) (uint16, error) {
+	for _, entry := range order {
+		// Skip stale or unreadable entries, they can't be the IncusOS one.
+		_, path, err := readBootEntry(entry)
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(strings.ToLower(path), efiLoadOptionPath) {
+			return entry, nil
+		}
+	}
+
+	val, err := readEFIVariable("BootCurrent")
+	if err != nil {
+		return 0, err
+	}
+
+	if len(val) != 2 {
+		return 0, errors.New("unable to determine the IncusOS boot entry")
+	}
+
+	current := binary.LittleEndian.Uint16(val)
+	if !slices.Contains(order, current) {
+		return 0, fmt.Errorf("current boot entry Boot%04X isn't in the boot order", current)
+	}
+
+	return current, nil
+}
+
+// readBootEntry returns the description and file path of the specified Boot#### EFI variable.
+func readBootEntry(entry uint16) (string, string, error) {
+	val, err := readEFIVariable(fmt.Sprintf("Boot%04X", entry))
+	if err != nil {
+		return "", "", err
+	}
+
+	return parseLoadOption(val)
+}
+
+// parseLoadOption parses an EFI_LOAD_OPTION, returning its description and file path, if any.
+func parseLoadOption(val []byte) (string, string, error) {
+	// Skip the 32-bit attributes, then get the length of the device path list.
+	if len(val) < 6 {
+		return "", "", errors.New("invalid EFI load option")
+	}
+
+	pathListLength := int(binary.LittleEndian.Uint16(val[4:]))
+	val = val[6:]
+
+	// Get the null-terminated UCS-2 description.
+	description := []uint16{}
+
+	for {
+		if len(val) < 2 {
+			return "", "", errors.New("invalid EFI load option description")
+		}
+
+		c := binary.LittleEndian.Uint16(val)
+		val = val[2:]
+
+		if c == 0 {
+			break
+		}
+
+		description = append(description, c)
+	}
+
+	if len(val) < pathListLength {
+		return "", "", errors.New("invalid EFI load option device path")
+	}
+
+	// Walk the device path nodes, looking for a media file path.
+	val = val[:pathListLength]
+	path := ""
+
+	for len(val) >= 4 {
+		nodeType := val[0]
+		nodeSubType := val[1]
+		nodeLength := int(binary.LittleEndian.Uint16(val[2:]))
+
+		if nodeLength < 4 || nodeLength > len(val) {
+			return "", "", errors.New("invalid EFI device path node")
+		}
+
+		// End of the device path.
+		if nodeType == 0x7f && nodeSubType == 0xff {
+			break
+		}
+
+		// Media file path node.
+		if nodeType == 0x04 && nodeSubType == 0x04 {
+			data := val[4:nodeLength]
+
+			chars := make([]uint16, 0, len(data)/2)
+			for i := 0; i+1 < len(data); i += 2 {
+				c := binary.LittleEndian.Uint16(data[i:])
+				if c == 0 {
+					break
+				}
+
+				chars = append(chars, c)
+			}
+
+			path += string(utf16.Decode(chars))
+		}
+
+		val = val[nodeLength:]
+	}
+
+	return string(utf16.Decode(description)), path, nil
+}
+
+// writeEFIVariable sets the value of a non-authenticated EFI variable.
+func writeEFIVariable(ctx context.Context, variableName string, data []byte) error {
+	filename, err := efiVariableToFilename(variableName)
+	if err != nil {
+		return err
+	}
+
+	// By default, sysfs mounts EFI variables with the immutable attribute set. We need to remove it prior to writing.
+	_, err = os.Stat(filename)
+	if err == nil {
+		_, err = subprocess.RunCommandContext(ctx, "chattr", "-i", filename)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Prefix the value with its attributes (non-volatile, boot service and runtime access);
+	// efivarfs requires the whole variable to be written in a single call.
+	buf := binary.LittleEndian.AppendUint32(nil, 0x07)
+	buf = append(buf, data...)
+
+	return os.WriteFile(filename, buf, 0o644)
+}
@@ -0,0 +1,107 @@
+package secureboot
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ucs2 encodes a string as null-terminated UCS-2, as used in EFI load options.
+func ucs2(s string) []byte {
+	ret := []byte{}
+	for _, c := range utf16.Encode([]rune(s)) {
+		ret = binary.LittleEndian.AppendUint16(ret, c)
+	}
+
+	return binary.LittleEndian.AppendUint16(ret, 0)
+}
+
+// devicePathNode encodes a single EFI device path node.
+func devicePathNode(nodeType byte, nodeSubType byte, data []byte) []byte {
+	ret := []byte{nodeType, nodeSubType}
+	ret = binary.LittleEndian.AppendUint16(ret, uint16(4+len(data))) //nolint:gosec
+
+	return append(ret, data...)
+}
+
+// loadOption encodes an EFI_LOAD_OPTION from its description and device path list.
+func loadOption(description string, pathList []byte) []byte {
+	ret := binary.LittleEndian.AppendUint32(nil, 0x01)
+	ret = binary.LittleEndian.AppendUint16(ret, uint16(len(pathList))) //nolint:gosec
+	ret = append(ret, ucs2(description)...)
+
+	return append(ret, pathList...)
+}
+
+func TestParseLoadOption(t *testing.T) {
+	t.Parallel()
+
+	hardDrive := devicePathNode(0x04, 0x01, make([]byte, 38))
+	filePath := devicePathNode(0x04, 0x04, ucs2(`\EFI\systemd\systemd-bootx64.efi`))
+	end := devicePathNode(0x7f, 0xff, nil)
+
+	tests := []struct {
+		name        string
+		val         []byte
+		description string
+		path        string
+		err         string
+	}{
+		{
+			name:        "systemd-boot entry",
+			val:         loadOption("IncusOS", append(append(append([]byte{}, hardDrive...), filePath...), end...)),
+			description: "IncusOS",
+			path:        `\EFI\systemd\systemd-bootx64.efi`,
+		},
+		{
+			name:        "entry without a file path",
+			val:         loadOption("UEFI PXEv4", append(append([]byte{}, hardDrive...), end...)),
+			description: "UEFI PXEv4",
+		},
+		{
+			name:        "trailing optional data",
+			val:         append(loadOption("Disk", append(append([]byte{}, filePath...), end...)), 0xde, 0xad),
+			description: "Disk",
+			path:        `\EFI\systemd\systemd-bootx64.efi`,
+		},
+		{
+			name: "missing variable",
+			val:  nil,
+			err:  "invalid EFI load option",
+		},
+		{
+			name: "unterminated description",
+			val:  loadOption("Disk", nil)[:8],
+			err:  "invalid EFI load option description",
+		},
+		{
+			name: "truncated device path",
+			val:  loadOption("Disk", filePath)[:len(loadOption("Disk", filePath))-2],
+			err:  "invalid EFI load option device path",
+		},
+		{
+			name: "invalid node length",
+			val:  loadOption("Disk", []byte{0x04, 0x04, 0x02, 0x00}),
+			err:  "invalid EFI device path node",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			description, path, err := parseLoadOption(tt.val)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.description, description)
+			require.Equal(t, tt.path, path)
+		})
+	}
+}
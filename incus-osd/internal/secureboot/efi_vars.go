@@ -4,8 +4,10 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/sha256"
 	"crypto/x509"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
@@ -21,6 +23,7 @@ import (
 	"github.com/google/go-eventlog/tcg"
 	"github.com/lxc/incus/v6/shared/subprocess"
 
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 	"github.com/lxc/incus-os/incus-osd/internal/util"
 )
 
@@ -188,6 +191,56 @@ func applySecureBootUpdates(ctx context.Context, varName string, newCerts map[st
 	return false, nil
 }
 
+// UpdateSecureBootDbx takes a pre-signed (.auth) dbx revocation list update and appends it to the
+// current dbx EFI variable, unless all of its entries are already present. Before applying the update,
+// it's checked to not revoke any of the binaries needed to boot the system, and the LUKS bindings are
+// updated to the PCR7 value expected after the next reboot.
+func UpdateSecureBootDbx(ctx context.Context, authFile string) (bool, error) {
+	certs, hashes, err := readAuthVariableUpdate(authFile)
+	if err != nil {
+		return false, err
+	}
+
+	existingCerts, existingHashes, err := readSignatureList("dbx")
+	if err != nil {
+		return false, err
+	}
+
+	// Check if there's anything new in the update.
+	newEntry := false
+
+	for _, cert := range certs {
+		if !slices.ContainsFunc(existingCerts, func(c x509.Certificate) bool { return c.Equal(&cert) }) {
+			newEntry = true
+
+			break
+		}
+	}
+
+	for _, hash := range hashes {
+		if !slices.ContainsFunc(existingHashes, func(h []byte) bool { return bytes.Equal(h, hash) }) {
+			newEntry = true
+
+			break
+		}
+	}
+
+	if !newEntry {
+		return false, nil
+	}
+
+	slog.InfoContext(ctx, "Appending revocation list update to EFI variable dbx", "certificates", len(certs), "hashes", len(hashes))
+
+	err = appendEFIVarUpdate(ctx, authFile, "dbx")
+	if err != nil {
+		return false, err
+	}
+
+	slog.InfoContext(ctx, "Successfully updated EFI variable")
+
+	return true, nil
+}
+
 // appendEFIVarUpdate takes a pre-signed (.auth) EFI variable update, appends it
 // to the current EFI value, and then updates the expected PCR7 value used to
 // decrypt the root file system and swap at boot.
@@ -207,15 +260,24 @@ func appendEFIVarUpdate(ctx context.Context, efiUpdateFile string, varName strin
 		}
 	}
 
-	// Get and verify the current PCR7 state.
-	eventLog, err := readTMPEventLog()
-	if err != nil {
-		return err
-	}
+	// Get and verify the current PCR7 state. Without a TPM, the encrypted volumes are only
+	// protected by passphrases and there's no binding to update.
+	tpmAvailable := TPMAvailable(ctx)
 
-	err = validateUntrustedTPMEventLog(eventLog)
-	if err != nil {
-		return err
+	var eventLog []tcg.Event
+
+	var bank pcrBank
+
+	if tpmAvailable {
+		eventLog, bank, err = readTMPEventLog()
+		if err != nil {
+			return err
+		}
+
+		err = validateUntrustedTPMEventLog(bank, eventLog)
+		if err != nil {
+			return err
+		}
 	}
 
 	// By default, sysfs mounts EFI variables with the immutable attribute set. We need to remove it prior to appending the update.
@@ -245,22 +307,24 @@ func appendEFIVarUpdate(ctx context.Context, efiUpdateFile string, varName strin
 		return err
 	}
 
+	if !tpmAvailable {
+		return nil
+	}
+
 	// Compute the new expected PCR7 value on next boot.
-	newPCR7, err := computeNewPCR7Value(eventLog)
+	newPCR7, err := computeNewPCR7Value(bank, eventLog)
 	if err != nil {
 		return err
 	}
 
 	// Update the LUKS-encrypted volumes to use the new PCR7 value.
-	newPCR7String := hex.EncodeToString(newPCR7)
-
 	luksVolumes, err := util.GetLUKSVolumePartitions()
 	if err != nil {
 		return err
 	}
 
 	for _, volume := range luksVolumes {
-		_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device=auto", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", "--tpm2-pcrs=7:sha256="+newPCR7String, volume)
+		_, err = timeout.RunCommand(ctx, "systemd-cryptenroll", "--unlock-tpm2-device=auto", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", bank.tpm2PCRsArgument(newPCR7), volume)
 		if err != nil {
 			return err
 		}
@@ -270,75 +334,113 @@ func appendEFIVarUpdate(ctx context.Context, efiUpdateFile string, varName strin
 }
 
 // checkDbxUpdateWouldBrickUKI checks if a proposed dbx update would invalidate a signed UKI
-// currently present on the system, resulting in a bricked boot.
+// currently present on the system, or one of the boot binaries, resulting in a bricked boot.
 func checkDbxUpdateWouldBrickUKI(dbxFilePath string) error {
-	// Get the pending dbx update certificate from signed .auth file.
-	// #nosec G304
-	dbxFile, err := os.Open(dbxFilePath)
+	certs, hashes, err := readAuthVariableUpdate(dbxFilePath)
 	if err != nil {
 		return err
 	}
-	defer dbxFile.Close()
 
-	s, err := dbxFile.Stat()
+	// Check each UKI image.
+	ukis, err := os.ReadDir("/boot/EFI/Linux/")
 	if err != nil {
 		return err
 	}
 
-	// .auth files have a timestamp and AuthInfo header before the .esl content. For our use, skip 1287 bytes
-	// into the .auth file to get actual certificate data.
-	buf := make([]byte, s.Size()-1287)
-
-	readBytes, err := dbxFile.ReadAt(buf, 1287)
-	if err != nil && !errors.Is(err, io.EOF) {
-		return err
-	} else if readBytes != len(buf) {
-		return fmt.Errorf("only read %d of %d expected bytes from EFI variable update '%s'", readBytes, len(buf), dbxFilePath)
+	bootFiles := []string{}
+	for _, uki := range ukis {
+		bootFiles = append(bootFiles, filepath.Join("/boot/EFI/Linux/", uki.Name()))
 	}
 
-	efiVar := tcg.UEFIVariableData{
-		VariableData: buf,
-	}
+	for _, cert := range certs {
+		publicKeyDer, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+		if err != nil {
+			return err
+		}
 
-	certs, _, err := efiVar.SignatureData()
-	if err != nil {
-		return err
-	} else if len(certs) != 1 {
-		return fmt.Errorf("expected exactly one certificate in dbx update, got %d", len(certs))
-	}
+		publicKeyBlock := pem.Block{
+			Type:  "PUBLIC KEY",
+			Bytes: publicKeyDer,
+		}
 
-	publicKeyDer, err := x509.MarshalPKIXPublicKey(certs[0].PublicKey)
-	if err != nil {
-		return err
+		for _, ukiFile := range bootFiles {
+			ukiPubKey, err := getPublicKeyFromUKI(ukiFile)
+			if err != nil {
+				return err
+			}
+
+			if bytes.Equal(pem.EncodeToMemory(&publicKeyBlock), ukiPubKey) {
+				return fmt.Errorf("unable to apply dbx update, since UKI image '%s' is signed by the key which would be revoked", ukiFile)
+			}
+		}
 	}
 
-	publicKeyBlock := pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: publicKeyDer,
+	if len(hashes) == 0 {
+		return nil
 	}
 
-	// Check each UKI image.
-	ukis, err := os.ReadDir("/boot/EFI/Linux/")
+	// Also check the systemd-boot binaries against any revoked image hash.
+	efiFiles, err := getArchEFIFiles()
 	if err != nil {
 		return err
 	}
 
-	for _, uki := range ukis {
-		ukiFile := filepath.Join("/boot/EFI/Linux/", uki.Name())
+	bootFiles = append(bootFiles, efiFiles["bootEFI"], efiFiles["systemdEFI"])
 
-		ukiPubKey, err := getPublicKeyFromUKI(ukiFile)
+	for _, bootFile := range bootFiles {
+		digest, err := computeFileAuthenticodeDigest(bootFile, crypto.SHA256)
 		if err != nil {
 			return err
 		}
 
-		if bytes.Equal(pem.EncodeToMemory(&publicKeyBlock), ukiPubKey) {
-			return fmt.Errorf("unable to apply dbx update, since UKI image '%s' is signed by the key which would be revoked", ukiFile)
+		if slices.ContainsFunc(hashes, func(h []byte) bool { return bytes.Equal(h, digest) }) {
+			return fmt.Errorf("unable to apply dbx update, since '%s' would be revoked", bootFile)
 		}
 	}
 
 	return nil
 }
 
+// readAuthVariableUpdate returns the certificates and hashes contained in a pre-signed (.auth) EFI variable update.
+func readAuthVariableUpdate(authFile string) ([]x509.Certificate, [][]byte, error) {
+	// #nosec G304
+	buf, err := os.ReadFile(authFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// .auth files start with an EFI_VARIABLE_AUTHENTICATION_2 header, made of a 16 byte timestamp
+	// followed by the WIN_CERTIFICATE_UEFI_GUID holding the signature, before the actual .esl content.
+	if len(buf) < 20 {
+		return nil, nil, fmt.Errorf("EFI variable update '%s' is too short", authFile)
+	}
+
+	headerLength := 16 + int(binary.LittleEndian.Uint32(buf[16:]))
+	if headerLength > len(buf) {
+		return nil, nil, fmt.Errorf("invalid authentication header in EFI variable update '%s'", authFile)
+	}
+
+	efiVar := tcg.UEFIVariableData{
+		VariableData: buf[headerLength:],
+	}
+
+	return efiVar.SignatureData()
+}
+
+// readSignatureList returns the certificates and hashes currently in a given EFI variable.
+func readSignatureList(varName string) ([]x509.Certificate, [][]byte, error) {
+	val, err := readEFIVariable(varName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsedVal := tcg.UEFIVariableData{
+		VariableData: val,
+	}
+
+	return parsedVal.SignatureData()
+}
+
 // readEFIVariable returns the current value, if any, of the specified EFI variable.
 func readEFIVariable(variableName string) ([]byte, error) {
 	// Determine which file to open.
@@ -398,7 +500,14 @@ func efiVariableToFilename(variableName string) (string, error) {
 		return "/sys/firmware/efi/efivars/db-d719b2cb-3d3a-4596-a3bc-dad00e67656f", nil
 	case "dbx":
 		return "/sys/firmware/efi/efivars/dbx-d719b2cb-3d3a-4596-a3bc-dad00e67656f", nil
+	case "BootCurrent", "BootOrder":
+		return "/sys/firmware/efi/efivars/" + variableName + "-" + efiGlobalVariableGUID, nil
 	default:
+		// Individual boot entries (Boot####).
+		if bootEntryRegex.MatchString(variableName) {
+			return "/sys/firmware/efi/efivars/" + variableName + "-" + efiGlobalVariableGUID, nil
+		}
+
 		return "", fmt.Errorf("unsupported EFI variable '%s'", variableName)
 	}
 }
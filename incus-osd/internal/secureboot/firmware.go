@@ -0,0 +1,136 @@
+package secureboot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/go-eventlog/tcg"
+
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// FirmwareMeasurements identifies the firmware and Secure Boot configuration measured during boot, so that a
+// firmware update can be told apart from other changes to the measured boot chain.
+type FirmwareMeasurements struct {
+	PCR0                   string   `json:"pcr0"`
+	FirmwareVersion        string   `json:"firmware_version"`
+	SecureBootCertificates []string `json:"secure_boot_certificates"` // Sorted SHA256 fingerprints of the PK, KEK and db certificates.
+}
+
+// TPMCanUnlock returns whether the TPM can unlock all the LUKS volumes in the current state.
+func TPMCanUnlock(ctx context.Context) (bool, error) {
+	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if err != nil {
+		return false, err
+	}
+
+	return tpmCanUnlockVolumes(ctx, luksVolumes), nil
+}
+
+// GetFirmwareMeasurements returns the firmware measurements of the current boot, after validating the
+// TPM event log against the TPM.
+func GetFirmwareMeasurements() (FirmwareMeasurements, error) {
+	ret := FirmwareMeasurements{
+		SecureBootCertificates: []string{},
+	}
+
+	eventLog, bank, err := readTMPEventLog()
+	if err != nil {
+		return ret, err
+	}
+
+	for _, index := range []int{0, 7} {
+		err := validateUntrustedTPMEventLogPCR(bank, eventLog, index)
+		if err != nil {
+			return ret, err
+		}
+	}
+
+	pcr0, err := readPCR(bank, 0)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.PCR0 = hex.EncodeToString(pcr0)
+
+	for _, e := range eventLog {
+		if e.Index == 0 && e.Type == tcg.SCRTMVersion {
+			ret.FirmwareVersion = decodeFirmwareVersion(e.Data)
+
+			break
+		}
+	}
+
+	for _, varName := range []string{"PK", "KEK", "db"} {
+		certs, err := GetCertificatesFromVar(varName)
+		if err != nil {
+			return ret, err
+		}
+
+		for _, cert := range certs {
+			rawFp := sha256.Sum256(cert.Raw)
+			ret.SecureBootCertificates = append(ret.SecureBootCertificates, hex.EncodeToString(rawFp[:]))
+		}
+	}
+
+	slices.Sort(ret.SecureBootCertificates)
+
+	return ret, nil
+}
+
+// CheckFirmwareUpdate checks that the differences between the current boot and the provided baseline are
+// limited to the firmware, as expected after a firmware update, rather than a possible tampering with the
+// rest of the boot chain. An error describing the unexpected change is returned otherwise.
+func CheckFirmwareUpdate(baseline FirmwareMeasurements) error {
+	sbEnabled, err := Enabled()
+	if err != nil {
+		return err
+	} else if !sbEnabled {
+		return errors.New("secure boot is disabled")
+	}
+
+	// Make sure there's no pending Secure Boot policy change.
+	tpmStatus := TPMStatus()
+	if tpmStatus != "ok" {
+		return errors.New(tpmStatus)
+	}
+
+	current, err := GetFirmwareMeasurements()
+	if err != nil {
+		return err
+	}
+
+	if current.PCR0 == baseline.PCR0 && current.FirmwareVersion == baseline.FirmwareVersion {
+		return errors.New("firmware measurements are unchanged")
+	}
+
+	if !slices.Equal(current.SecureBootCertificates, baseline.SecureBootCertificates) {
+		return errors.New("secure boot certificates have changed")
+	}
+
+	return nil
+}
+
+// decodeFirmwareVersion decodes the firmware version recorded in a S-CRTM version event. It's usually a
+// NUL-terminated UCS-2 string, but some firmware record a GUID or other binary value, which is hex encoded.
+func decodeFirmwareVersion(data []byte) string {
+	if len(data) >= 2 && len(data)%2 == 0 {
+		chars := make([]uint16, 0, len(data)/2)
+		for i := 0; i < len(data); i += 2 {
+			chars = append(chars, binary.LittleEndian.Uint16(data[i:]))
+		}
+
+		version := strings.TrimRight(string(utf16.Decode(chars)), "\x00")
+		if version != "" && !strings.ContainsFunc(version, func(r rune) bool { return r < 0x20 || r == 0xfffd }) {
+			return version
+		}
+	}
+
+	return hex.EncodeToString(data)
+}
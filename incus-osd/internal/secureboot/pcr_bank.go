@@ -0,0 +1,52 @@
+package secureboot
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"os"
+	"slices"
+
+	"github.com/google/go-eventlog/register"
+)
+
+// pcrBank represents a TPM PCR bank, identified by its hash algorithm.
+type pcrBank struct {
+	name string
+	hash crypto.Hash
+	alg  register.HashAlg
+}
+
+// pcrBanks lists the supported PCR banks, strongest first.
+var pcrBanks = []pcrBank{
+	{name: "sha384", hash: crypto.SHA384, alg: register.HashSHA384},
+	{name: "sha256", hash: crypto.SHA256, alg: register.HashSHA256},
+}
+
+// selectPCRBank returns the strongest PCR bank which is both active in the TPM and recorded in the event log.
+func selectPCRBank(eventLogAlgs []register.HashAlg) (pcrBank, error) {
+	for _, bank := range pcrBanks {
+		if !slices.Contains(eventLogAlgs, bank.alg) {
+			continue
+		}
+
+		_, err := os.Stat("/sys/class/tpm/tpm0/pcr-" + bank.name)
+		if err != nil {
+			continue
+		}
+
+		return bank, nil
+	}
+
+	return pcrBank{}, errors.New("no supported PCR bank is available, the TPM must have an active SHA256 or SHA384 bank")
+}
+
+// newPCR returns the initial value of a PCR in the bank.
+func (b pcrBank) newPCR() []byte {
+	return make([]byte, b.hash.Size())
+}
+
+// tpm2PCRsArgument returns the systemd-cryptenroll argument binding to the given PCR7 value in the bank.
+func (b pcrBank) tpm2PCRsArgument(pcr7 []byte) string {
+	return "--tpm2-pcrs=7:" + b.name + "=" + hex.EncodeToString(pcr7)
+}
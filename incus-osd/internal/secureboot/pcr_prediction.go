@@ -0,0 +1,400 @@
+package secureboot
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"debug/pe"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/go-eventlog/tcg"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// ukiMeasuredSections is the list of UKI sections measured by systemd-stub into PCR11, in the order they are measured.
+var ukiMeasuredSections = []string{".linux", ".osrel", ".cmdline", ".initrd", ".ucode", ".splash", ".dtb", ".uname", ".sbat", ".pcrpkey", ".profile", ".dtbauto", ".hwids"}
+
+// ukiUnlockPhase is the boot phase measured into PCR11 by systemd-pcrphase at the point the LUKS volumes are unlocked.
+const ukiUnlockPhase = "enter-initrd"
+
+// PredictPCRValues computes the PCR4, PCR7 and PCR11 values expected when next booting into the provided UKI.
+func PredictPCRValues(ukiFile string) (api.SystemSecurityPCRPrediction, error) {
+	ret := api.SystemSecurityPCRPrediction{}
+
+	eventLog, bank, err := readTMPEventLog()
+	if err != nil {
+		return ret, err
+	}
+
+	for _, index := range []int{4, 7} {
+		err = validateUntrustedTPMEventLogPCR(bank, eventLog, index)
+		if err != nil {
+			return ret, err
+		}
+	}
+
+	pcr4, err := computeNewPCR4Value(bank, eventLog, ukiFile)
+	if err != nil {
+		return ret, err
+	}
+
+	pcr7, err := computeNewPCR7Value(bank, eventLog)
+	if err != nil {
+		return ret, err
+	}
+
+	pcr11, err := computeUKIPCR11Value(bank, ukiFile)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Bank = bank.name
+	ret.PCR4 = hex.EncodeToString(pcr4)
+	ret.PCR7 = hex.EncodeToString(pcr7)
+	ret.PCR11 = hex.EncodeToString(pcr11)
+
+	return ret, nil
+}
+
+// PreviewPCRValues reports the current PCR4, PCR7 and PCR11 values along with those expected when next
+// booting into the provided UKI, once any EFI variable changes are measured. As the encrypted volumes are
+// bound to the exact PCR7 value, a change of it while the TPM can still unlock the volumes in the current
+// state means the recovery key will be required on next boot.
+func PreviewPCRValues(ctx context.Context, ukiFile string) (api.SystemSecurityPCRPreview, error) {
+	ret := api.SystemSecurityPCRPreview{}
+
+	prediction, err := PredictPCRValues(ukiFile)
+	if err != nil {
+		return ret, err
+	}
+
+	_, bank, err := readTMPEventLog()
+	if err != nil {
+		return ret, err
+	}
+
+	currentPCR4, err := readPCR(bank, 4)
+	if err != nil {
+		return ret, err
+	}
+
+	currentPCR7, err := readPCR(bank, 7)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Bank = prediction.Bank
+	ret.PCRs = []api.SystemSecurityPCRValue{
+		{Index: 4, Current: hex.EncodeToString(currentPCR4), Next: prediction.PCR4},
+		{Index: 7, Current: hex.EncodeToString(currentPCR7), Next: prediction.PCR7, Bound: true},
+
+		// PCR11 is covered by a policy signed with the UKI signing key rather than bound to its exact value.
+		{Index: 11, Next: prediction.PCR11},
+	}
+
+	// If the TPM can't unlock the volumes in the current state, their binding was already updated for the next boot.
+	if hex.EncodeToString(currentPCR7) != prediction.PCR7 {
+		luksVolumes, err := util.GetLUKSVolumePartitions()
+		if err != nil {
+			return ret, err
+		}
+
+		ret.RecoveryRequired = tpmCanUnlockVolumes(ctx, luksVolumes)
+	}
+
+	return ret, nil
+}
+
+// computeNewPCR4Value will compute the future PCR4 value when booting the provided UKI.
+// IMPORTANT: It is assumed that the provided TPM event log has already been validated.
+func computeNewPCR4Value(bank pcrBank, eventLog []tcg.Event, ukiFile string) ([]byte, error) {
+	actualPCR4Buf := bank.newPCR()
+	seenUKI := false
+
+	for _, e := range eventLog {
+		if e.Index != 4 { // We only care about PCR4.
+			continue
+		}
+
+		digest := e.ReplayedDigest()
+
+		if e.Type == tcg.EFIBootServicesApplication {
+			path, err := getImageLoadPath(e.Data)
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case strings.HasPrefix(strings.ToLower(path), `\efi\linux\`):
+				// The UKI loaded by systemd-boot will be replaced by the new one.
+				digest, err = computeFileAuthenticodeDigest(ukiFile, bank.hash)
+				if err != nil {
+					return nil, err
+				}
+
+				seenUKI = true
+			case path == "" && seenUKI:
+				// The kernel loaded from memory by systemd-stub comes from the new UKI.
+				digest, err = computeUKISectionAuthenticodeDigest(ukiFile, ".linux", bank.hash)
+				if err != nil {
+					return nil, err
+				}
+			case path != "":
+				// Other binaries, such as systemd-boot, are measured as currently present on the ESP.
+				espPath := "/boot" + strings.ReplaceAll(path, `\`, "/")
+
+				_, err = os.Stat(espPath)
+				if err == nil {
+					digest, err = computeFileAuthenticodeDigest(espPath, bank.hash)
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		var err error
+
+		actualPCR4Buf, err = extendPCRValue(bank, actualPCR4Buf, digest, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return actualPCR4Buf, nil
+}
+
+// computeUKIPCR11Value will compute the PCR11 value measured by systemd-stub and systemd-pcrphase
+// when booting the provided UKI, at the point the LUKS volumes are unlocked.
+func computeUKIPCR11Value(bank pcrBank, ukiFile string) ([]byte, error) {
+	peFile, err := pe.Open(ukiFile)
+	if err != nil {
+		return nil, err
+	}
+	defer peFile.Close()
+
+	actualPCR11Buf := bank.newPCR()
+
+	for _, name := range ukiMeasuredSections {
+		section := peFile.Section(name)
+		if section == nil {
+			continue
+		}
+
+		data, err := getSectionData(section)
+		if err != nil {
+			return nil, err
+		}
+
+		// The section name, including its trailing NUL, is measured followed by its contents.
+		actualPCR11Buf, err = extendPCRValue(bank, actualPCR11Buf, append([]byte(name), 0), true)
+		if err != nil {
+			return nil, err
+		}
+
+		actualPCR11Buf, err = extendPCRValue(bank, actualPCR11Buf, data, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return extendPCRValue(bank, actualPCR11Buf, []byte(ukiUnlockPhase), true)
+}
+
+// getImageLoadPath returns the file path from an EFI_IMAGE_LOAD_EVENT, if any.
+func getImageLoadPath(rawBuf []byte) (string, error) {
+	imageLoad, err := tcg.ParseEFIImageLoad(bytes.NewReader(rawBuf))
+	if err != nil {
+		return "", err
+	}
+
+	devicePath, err := imageLoad.DevicePath()
+	if err != nil {
+		return "", err
+	}
+
+	path := ""
+
+	for _, element := range devicePath {
+		// Only consider media file path nodes.
+		if element.Type != tcg.MediaDevice || element.Subtype != 0x04 {
+			continue
+		}
+
+		chars := make([]uint16, 0, len(element.Data)/2)
+		for i := 0; i+1 < len(element.Data); i += 2 {
+			c := binary.LittleEndian.Uint16(element.Data[i:])
+			if c == 0 {
+				break
+			}
+
+			chars = append(chars, c)
+		}
+
+		path += string(utf16.Decode(chars))
+	}
+
+	return path, nil
+}
+
+// getSectionData returns the contents of a PE section, without any trailing padding.
+func getSectionData(section *pe.Section) ([]byte, error) {
+	data, err := section.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	if section.VirtualSize > 0 && int(section.VirtualSize) < len(data) {
+		data = data[:section.VirtualSize]
+	}
+
+	return data, nil
+}
+
+// computeFileAuthenticodeDigest returns the Authenticode digest of the given PE binary.
+func computeFileAuthenticodeDigest(filename string, hash crypto.Hash) ([]byte, error) {
+	// #nosec G304
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return computeAuthenticodeDigest(f, info.Size(), hash)
+}
+
+// computeUKISectionAuthenticodeDigest returns the Authenticode digest of the PE binary
+// embedded in the given section of a UKI.
+func computeUKISectionAuthenticodeDigest(ukiFile string, name string, hash crypto.Hash) ([]byte, error) {
+	peFile, err := pe.Open(ukiFile)
+	if err != nil {
+		return nil, err
+	}
+	defer peFile.Close()
+
+	section := peFile.Section(name)
+	if section == nil {
+		return nil, fmt.Errorf("UKI '%s' doesn't have a %s section", ukiFile, name)
+	}
+
+	data, err := getSectionData(section)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeAuthenticodeDigest(bytes.NewReader(data), int64(len(data)), hash)
+}
+
+// computeAuthenticodeDigest returns the Authenticode digest of a PE binary, which is what the
+// firmware measures into PCR4 when loading an EFI application.
+func computeAuthenticodeDigest(r io.ReaderAt, size int64, hash crypto.Hash) ([]byte, error) {
+	peFile, err := pe.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locate the optional header fields which are excluded from the digest.
+	buf := make([]byte, 4)
+
+	_, err = r.ReadAt(buf, 0x3c)
+	if err != nil {
+		return nil, err
+	}
+
+	optionalHeaderOffset := int64(binary.LittleEndian.Uint32(buf)) + 4 + 20
+	checksumOffset := optionalHeaderOffset + 64
+
+	var sizeOfHeaders int64
+
+	var certTableOffset int64
+
+	var certTable pe.DataDirectory
+
+	switch t := peFile.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		sizeOfHeaders = int64(t.SizeOfHeaders)
+		certTableOffset = optionalHeaderOffset + 96 + 8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+		certTable = t.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+	case *pe.OptionalHeader64:
+		sizeOfHeaders = int64(t.SizeOfHeaders)
+		certTableOffset = optionalHeaderOffset + 112 + 8*pe.IMAGE_DIRECTORY_ENTRY_SECURITY
+		certTable = t.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+	default:
+		return nil, errors.New("PE binary doesn't have an optional header")
+	}
+
+	digest := hash.New()
+
+	hashRange := func(start int64, end int64) error {
+		if start > end || end > size {
+			return errors.New("invalid PE binary layout")
+		}
+
+		_, err := io.Copy(digest, io.NewSectionReader(r, start, end-start))
+
+		return err
+	}
+
+	// Hash the headers, skipping the checksum and certificate table entry.
+	err = hashRange(0, checksumOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hashRange(checksumOffset+4, certTableOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = hashRange(certTableOffset+8, sizeOfHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hash the sections in the order they appear in the file.
+	sections := slices.Clone(peFile.Sections)
+	slices.SortFunc(sections, func(a *pe.Section, b *pe.Section) int {
+		return int(a.Offset) - int(b.Offset)
+	})
+
+	hashed := sizeOfHeaders
+
+	for _, section := range sections {
+		if section.Size == 0 {
+			continue
+		}
+
+		err = hashRange(int64(section.Offset), int64(section.Offset)+int64(section.Size))
+		if err != nil {
+			return nil, err
+		}
+
+		hashed += int64(section.Size)
+	}
+
+	// Hash any trailing data, excluding the certificate table.
+	end := size - int64(certTable.Size)
+	if hashed < end {
+		err = hashRange(hashed, end)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return digest.Sum(nil), nil
+}
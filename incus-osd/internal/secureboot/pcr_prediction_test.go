@@ -0,0 +1,34 @@
+package secureboot
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The test UKI is a minimal EFI binary with .linux, .osrel, .cmdline and .initrd sections added by objcopy.
+// The expected values were computed independently by running, on the section files:
+//
+//	systemd-measure calculate --linux=linux --osrel=osrel --cmdline=cmdline --initrd=initrd --phase=enter-initrd
+func TestComputeUKIPCR11Value(t *testing.T) {
+	t.Parallel()
+
+	expected := map[string]string{
+		"sha256": "272c5016689fb659d9fc40a5ea17f7f6ce8ce54bd392fbcc953be93d397daa26",
+		"sha384": "a5dbbd6888e54f444c5d25ec772b3792adcbd0a471ee3c16398910a10b136452e346350c5bda574c68b6460e4bdec2da",
+	}
+
+	for _, bank := range pcrBanks {
+		t.Run(bank.name, func(t *testing.T) {
+			t.Parallel()
+
+			pcr11, err := computeUKIPCR11Value(bank, "testdata/uki.efi")
+			require.NoError(t, err)
+			require.Equal(t, expected[bank.name], hex.EncodeToString(pcr11))
+		})
+	}
+
+	_, err := computeUKIPCR11Value(pcrBanks[0], "testdata/missing.efi")
+	require.Error(t, err)
+}
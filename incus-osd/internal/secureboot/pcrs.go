@@ -3,8 +3,6 @@ package secureboot
 import (
 	"bytes"
 	"context"
-	"crypto"
-	"crypto/sha256"
 	"crypto/x509"
 	"debug/pe"
 	"encoding/hex"
@@ -19,6 +17,8 @@ import (
 	"github.com/lxc/incus/v6/shared/subprocess"
 	"github.com/smallstep/pkcs7"
 
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 	"github.com/lxc/incus-os/incus-osd/internal/util"
 )
 
@@ -26,101 +26,140 @@ import (
 // the current LUKS TPM bindings with these values. This is DANGEROUS and only intended to be used in
 // a recovery-type situation, such as when the system had to be booted with a recovery passphrase.
 //
-// Immediately after a successful reset, the system will be rebooted.
-func ForceUpdatePCRBindings(ctx context.Context, osName string, osVersion string, luksPassword string) error {
+// Immediately after a successful reset, the system will be rebooted. In dry-run mode, nothing is written to the
+// TPM and the system isn't rebooted; the returned plan instead reports what would be done, along with any reason
+// the reset would be refused.
+func ForceUpdatePCRBindings(ctx context.Context, osName string, osVersion string, luksPassword string, dryRun bool) (*api.SystemSecurityRebindPlan, error) {
+	plan := newRebindPlan("tpm-rebind")
+	plan.Reboot = true
+
+	refuse := func(err error) error {
+		if !dryRun {
+			return err
+		}
+
+		plan.Blockers = append(plan.Blockers, err.Error())
+
+		return nil
+	}
+
 	// First, make sure Secure Boot is enabled so we can have some confidence in the current running system.
 	sbEnabled, err := Enabled()
 	if err != nil {
-		return err
+		return nil, err
 	} else if !sbEnabled {
-		return errors.New("refusing to reset TPM encryption bindings because Secure Boot is disabled")
+		err = refuse(errors.New("refusing to reset TPM encryption bindings because Secure Boot is disabled"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Second, refuse to do anything if the TPM can unlock all LUKS volumes.
 	luksVolumes, err := util.GetLUKSVolumePartitions()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	atLeastOneVolumeNeedsFixing := false
+	plan.Volumes = rebindVolumes(luksVolumes)
 
-	for volumeName, volumeDev := range luksVolumes {
-		_, err = subprocess.RunCommandContext(ctx, "cryptsetup", "luksOpen", "--test-passphrase", volumeDev, volumeName)
+	if tpmCanUnlockVolumes(ctx, luksVolumes) {
+		err = refuse(errors.New("refusing to reset TPM encryption bindings because current state can unlock all volumes"))
 		if err != nil {
-			atLeastOneVolumeNeedsFixing = true
-
-			break
+			return nil, err
 		}
 	}
 
-	if !atLeastOneVolumeNeedsFixing {
-		return errors.New("refusing to reset TPM encryption bindings because current state can unlock all volumes")
-	}
-
 	// WARNING: here be dragons as we're going to be blindly trusting inputs that in theory could be attacker-controlled.
 
 	// Get the current PCR7 value directly from the TPM. Don't bother replaying the event log and computing the value,
 	// since it should be the same.
-	pcr7, err := readPCR7()
+	_, bank, err := readTMPEventLog()
+	if err != nil {
+		return nil, err
+	}
+
+	pcr7, err := readPCR(bank, 7)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	plan.Bank = bank.name
+	plan.PCR7 = hex.EncodeToString(pcr7)
+
 	// Extract the signing certificate from the UKI we're running from.
 	ukiCert, err := getPublicKeyFromUKI(fmt.Sprintf("/boot/EFI/Linux/%s_%s.efi", osName, osVersion))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	plan.SigningCertificate = signingCertificate(ukiCert)
+
+	if dryRun {
+		return plan, nil
 	}
 
 	// Write the UKI's cert to where systemd will pick it up.
 	err = os.WriteFile("/run/systemd/tpm2-pcr-public-key.pem", ukiCert, 0o600)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Finally, we're ready to update the TPM bindings for each LUKS volume.
-	pcr7String := hex.EncodeToString(pcr7)
-
 	for _, volume := range luksVolumes {
-		_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksPassword), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", "--tpm2-pcrs=7:sha256="+pcr7String, volume)
+		_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksPassword), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", bank.tpm2PCRsArgument(pcr7), volume)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Once complete, immediately reboot the system which should then auto-unlock.
 	_, err = subprocess.RunCommandContext(ctx, "systemctl", "reboot")
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// tpmCanUnlockVolumes returns whether the TPM can unlock all the provided LUKS volumes in the current state.
+func tpmCanUnlockVolumes(ctx context.Context, luksVolumes map[string]string) bool {
+	for volumeName, volumeDev := range luksVolumes {
+		_, err := timeout.RunCommand(ctx, "cryptsetup", "luksOpen", "--test-passphrase", volumeDev, volumeName)
+		if err != nil {
+			return false
+		}
 	}
 
-	return nil
+	return true
 }
 
-// readPCR7 returns the current PCR7 value from the TPM.
-func readPCR7() ([]byte, error) {
-	pcr7File, err := os.Open("/sys/class/tpm/tpm0/pcr-sha256/7")
+// readPCR returns the current value of the given PCR in the bank from the TPM.
+func readPCR(bank pcrBank, index int) ([]byte, error) {
+	pcrFilename := fmt.Sprintf("/sys/class/tpm/tpm0/pcr-%s/%d", bank.name, index)
+
+	// #nosec G304
+	pcrFile, err := os.Open(pcrFilename)
 	if err != nil {
 		return nil, err
 	}
-	defer pcr7File.Close()
+	defer pcrFile.Close()
 
-	actualPCR7Buf := make([]byte, 64)
+	actualPCRBuf := make([]byte, 2*bank.hash.Size())
 
-	numBytes, err := io.ReadFull(pcr7File, actualPCR7Buf)
+	numBytes, err := io.ReadFull(pcrFile, actualPCRBuf)
 	if err != nil {
 		return nil, err
-	} else if numBytes != 64 {
-		return nil, fmt.Errorf("only read %d bytes from /sys/class/tpm/tpm0/pcr-sha256/7", numBytes)
+	} else if numBytes != len(actualPCRBuf) {
+		return nil, fmt.Errorf("only read %d bytes from %s", numBytes, pcrFilename)
 	}
 
-	return hex.DecodeString(string(actualPCR7Buf))
+	return hex.DecodeString(string(actualPCRBuf))
 }
 
 // computeNewPCR7Value will compute the future PCR7 value after the KEK, db, and/or dbx EFI variables are updated.
 // IMPORTANT: It is assumed that the provided TPM event log has already been validated.
-func computeNewPCR7Value(eventLog []tcg.Event) ([]byte, error) {
-	actualPCR7Buf := make([]byte, 32)
+func computeNewPCR7Value(bank pcrBank, eventLog []tcg.Event) ([]byte, error) {
+	actualPCR7Buf := bank.newPCR()
 
 	for _, e := range eventLog {
 		if e.Index == 7 { // We only care about PCR7.
@@ -132,7 +171,7 @@ func computeNewPCR7Value(eventLog []tcg.Event) ([]byte, error) {
 					return nil, err
 				}
 
-				actualPCR7Buf, err = extendPCRValue(actualPCR7Buf, buf, true)
+				actualPCR7Buf, err = extendPCRValue(bank, actualPCR7Buf, buf, true)
 				if err != nil {
 					return nil, err
 				}
@@ -147,7 +186,7 @@ func computeNewPCR7Value(eventLog []tcg.Event) ([]byte, error) {
 					return nil, err
 				}
 
-				actualPCR7Buf, err = extendPCRValue(actualPCR7Buf, buf, true)
+				actualPCR7Buf, err = extendPCRValue(bank, actualPCR7Buf, buf, true)
 				if err != nil {
 					return nil, err
 				}
@@ -155,7 +194,7 @@ func computeNewPCR7Value(eventLog []tcg.Event) ([]byte, error) {
 				// For all other types, re-use the existing digest from the event log.
 				var err error
 
-				actualPCR7Buf, err = extendPCRValue(actualPCR7Buf, e.ReplayedDigest(), false)
+				actualPCR7Buf, err = extendPCRValue(bank, actualPCR7Buf, e.ReplayedDigest(), false)
 				if err != nil {
 					return nil, err
 				}
@@ -323,19 +362,21 @@ func extractCertificateFromPE(filename string) (*x509.Certificate, error) {
 	return pkcs.Certificates[0], nil
 }
 
-// extendPCRValue takes an existing pcr and extends it using the provided content.
-func extendPCRValue(pcr []byte, content []byte, computeSHA256 bool) ([]byte, error) {
-	hash := crypto.SHA256.New()
+// extendPCRValue takes an existing pcr and extends it using the provided content, hashed with the
+// bank's algorithm if computeDigest is set.
+func extendPCRValue(bank pcrBank, pcr []byte, content []byte, computeDigest bool) ([]byte, error) {
+	hash := bank.hash.New()
 
 	_, err := hash.Write(pcr)
 	if err != nil {
 		return nil, err
 	}
 
-	if computeSHA256 {
-		sum := sha256.Sum256(content)
+	if computeDigest {
+		contentHash := bank.hash.New()
+		_, _ = contentHash.Write(content)
 
-		_, err := hash.Write(sum[:])
+		_, err := hash.Write(contentHash.Sum(nil))
 		if err != nil {
 			return nil, err
 		}
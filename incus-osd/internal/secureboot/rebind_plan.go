@@ -0,0 +1,56 @@
+package secureboot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// newRebindPlan returns an empty plan for the given TPM re-binding operation, listing the detected Secure Boot certificates.
+func newRebindPlan(operation string) *api.SystemSecurityRebindPlan {
+	return &api.SystemSecurityRebindPlan{
+		Operation:    operation,
+		Certificates: ListCertificates(),
+		Volumes:      []api.SystemSecurityRebindVolume{},
+		Blockers:     []string{},
+	}
+}
+
+// rebindVolumes returns the LUKS volumes which would be re-enrolled, sorted by name.
+func rebindVolumes(luksVolumes map[string]string) []api.SystemSecurityRebindVolume {
+	ret := []api.SystemSecurityRebindVolume{}
+
+	for _, name := range slices.Sorted(maps.Keys(luksVolumes)) {
+		ret = append(ret, api.SystemSecurityRebindVolume{Name: name, Device: luksVolumes[name]})
+	}
+
+	return ret
+}
+
+// signingCertificate returns the Secure Boot db certificate holding the provided PEM encoded public key, if any.
+func signingCertificate(publicKey []byte) *api.SystemSecuritySecureBootCertificate {
+	dbCerts, err := GetCertificatesFromVar("db")
+	if err != nil {
+		return nil
+	}
+
+	for _, cert := range dbCerts {
+		if !certificateMatchesPublicKey(cert, publicKey) {
+			continue
+		}
+
+		rawFp := sha256.Sum256(cert.Raw)
+
+		return &api.SystemSecuritySecureBootCertificate{
+			Type:        "db",
+			Fingerprint: hex.EncodeToString(rawFp[:]),
+			Subject:     cert.Subject.String(),
+			Issuer:      cert.Issuer.String(),
+		}
+	}
+
+	return nil
+}
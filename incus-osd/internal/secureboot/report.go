@@ -0,0 +1,171 @@
+package secureboot
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// GetMeasuredBootReport gathers the evidence of the integrity of the current boot: the TCG event log, the
+// current PCR values, the running UKI and the Secure Boot certificate chain.
+func GetMeasuredBootReport(ukiFile string) (api.SystemSecurityMeasuredBootReport, error) {
+	ret := api.SystemSecurityMeasuredBootReport{
+		SecureBootCertificates: []api.SystemSecurityMeasuredBootCertificate{},
+		PCRs:                   map[string]string{},
+		Events:                 []api.SystemSecurityMeasuredBootEvent{},
+	}
+
+	// Get the running UKI and the certificate it's signed with.
+	ukiSHA256, err := fileSHA256(ukiFile)
+	if err != nil {
+		return ret, err
+	}
+
+	ukiCert, err := extractCertificateFromPE(ukiFile)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.UKISHA256 = ukiSHA256
+	ret.UKICertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ukiCert.Raw}))
+
+	// Get the Secure Boot certificate chain.
+	ret.SecureBootEnabled, err = Enabled()
+	if err != nil {
+		return ret, err
+	}
+
+	for _, varName := range []string{"PK", "KEK", "db"} {
+		certs, err := GetCertificatesFromVar(varName)
+		if err != nil {
+			return ret, err
+		}
+
+		for _, cert := range certs {
+			ret.SecureBootCertificates = append(ret.SecureBootCertificates, api.SystemSecurityMeasuredBootCertificate{
+				Type:        varName,
+				Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			})
+		}
+	}
+
+	// Get the TCG event log.
+	rawEventLog, err := readRawTPMEventLog()
+	if err != nil {
+		return ret, err
+	}
+
+	eventLog, bank, err := parseTPMEventLog(rawEventLog)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.EventLog = base64.StdEncoding.EncodeToString(rawEventLog)
+
+	for _, e := range eventLog {
+		ret.Events = append(ret.Events, api.SystemSecurityMeasuredBootEvent{
+			PCR:    e.Index,
+			Type:   e.Type.String(),
+			Digest: hex.EncodeToString(e.ReplayedDigest()),
+		})
+	}
+
+	// Get the current PCR values.
+	ret.PCRBank = bank.name
+
+	for index := range 24 {
+		pcr, err := readPCR(bank, index)
+		if err != nil {
+			return ret, err
+		}
+
+		ret.PCRs[strconv.Itoa(index)] = hex.EncodeToString(pcr)
+	}
+
+	return ret, nil
+}
+
+// SignMeasuredBootReport signs the JSON encoding of a measured boot report with the provided keypair.
+func SignMeasuredBootReport(report api.SystemSecurityMeasuredBootReport, keypair *tls.Certificate) (api.SystemSecuritySignedMeasuredBootReport, error) {
+	ret := api.SystemSecuritySignedMeasuredBootReport{}
+
+	if len(keypair.Certificate) == 0 {
+		return ret, errors.New("keypair doesn't contain a certificate")
+	}
+
+	signer, ok := keypair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return ret, errors.New("unsupported private key type")
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return ret, err
+	}
+
+	// Ed25519 signs the message itself rather than its digest.
+	digest := sha256.Sum256(body)
+	message := digest[:]
+	opts := crypto.Hash(crypto.SHA256)
+
+	var algorithm x509.SignatureAlgorithm
+
+	switch signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case ed25519.PublicKey:
+		algorithm = x509.PureEd25519
+		message = body
+		opts = crypto.Hash(0)
+	default:
+		return ret, errors.New("unsupported private key type")
+	}
+
+	signature, err := signer.Sign(rand.Reader, message, opts)
+	if err != nil {
+		return ret, err
+	}
+
+	ret.Report = string(body)
+	ret.SignatureAlgorithm = algorithm.String()
+	ret.Signature = base64.StdEncoding.EncodeToString(signature)
+	ret.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: keypair.Certificate[0]}))
+
+	return ret, nil
+}
+
+// fileSHA256 returns the SHA256 checksum of a file.
+func fileSHA256(filename string) (string, error) {
+	// #nosec G304
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
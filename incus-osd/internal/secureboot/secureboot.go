@@ -16,6 +16,7 @@ import (
 	"slices"
 	"time"
 
+	"github.com/google/go-eventlog/tcg"
 	"github.com/lxc/incus/v6/shared/subprocess"
 	"golang.org/x/sys/unix"
 
@@ -44,62 +45,103 @@ func Enabled() (bool, error) {
 //	   must have an alternative way of authenticating the LUKS changes; by
 //	   default rely on the recovery passphrase that's automatically created on
 //	   first boot.
-func HandleSecureBootKeyChange(ctx context.Context, luksPassword string, ukiFile string, usrImageFile string) error {
-	// Pre-checks -- Verify that the TPM event log matches current TPM values.
-	eventLog, err := readTMPEventLog()
-	if err != nil {
-		return err
+//
+// On systems without a TPM, the encrypted volumes are only protected by passphrases, so only the
+// first two steps are performed. In dry-run mode, neither the EFI stub nor the TPM are modified and
+// the returned plan reports what would be done, along with any reason the change would be refused.
+func HandleSecureBootKeyChange(ctx context.Context, luksPassword string, ukiFile string, usrImageFile string, dryRun bool) (*api.SystemSecurityRebindPlan, error) {
+	plan := newRebindPlan("secure-boot-key-change")
+
+	refuse := func(err error) error {
+		if !dryRun {
+			return err
+		}
+
+		plan.Blockers = append(plan.Blockers, err.Error())
+
+		return nil
 	}
 
-	err = validateUntrustedTPMEventLog(eventLog)
-	if err != nil {
-		return err
+	tpmAvailable := TPMAvailable(ctx)
+
+	var eventLog []tcg.Event
+
+	var bank pcrBank
+
+	var err error
+
+	// Pre-checks -- Verify that the TPM event log matches current TPM values.
+	if tpmAvailable {
+		eventLog, bank, err = readTMPEventLog()
+		if err != nil {
+			return nil, err
+		}
+
+		err = refuse(validateUntrustedTPMEventLog(bank, eventLog))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Part 1 -- Verify the new certificate is in db and isn't in dbx.
 	newCert, err := getPublicKeyFromUKI(ukiFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = validatePKICertificate(newCert)
+	plan.SigningCertificate = signingCertificate(newCert)
+
+	err = refuse(validatePKICertificate(newCert))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Part 2 -- Update the systemd-boot EFI stub.
-	err = updateEFIBootStub(ctx, usrImageFile)
-	if err != nil {
-		return err
+	if !dryRun {
+		err = updateEFIBootStub(ctx, usrImageFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !tpmAvailable {
+		return plan, nil
 	}
 
 	// Part 3 -- Compute the new PCR7 value.
-	newPCR7, err := computeNewPCR7Value(eventLog)
+	newPCR7, err := computeNewPCR7Value(bank, eventLog)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Part 4 -- Re-enroll the TPM utilizing the new Secure Boot public key.
-	err = os.WriteFile("/run/systemd/tpm2-pcr-public-key.pem", newCert, 0o600)
+	plan.Bank = bank.name
+	plan.PCR7 = hex.EncodeToString(newPCR7)
+
+	luksVolumes, err := util.GetLUKSVolumePartitions()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	newPCR7String := hex.EncodeToString(newPCR7)
+	plan.Volumes = rebindVolumes(luksVolumes)
 
-	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if dryRun {
+		return plan, nil
+	}
+
+	// Part 4 -- Re-enroll the TPM utilizing the new Secure Boot public key.
+	err = os.WriteFile("/run/systemd/tpm2-pcr-public-key.pem", newCert, 0o600)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for _, volume := range luksVolumes {
-		_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksPassword), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", "--tpm2-pcrs=7:sha256="+newPCR7String, volume)
+		_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+luksPassword), nil, "systemd-cryptenroll", "--tpm2-device=auto", "--wipe-slot=tpm2", "--tpm2-pcrlock=", bank.tpm2PCRsArgument(newPCR7), volume)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return plan, nil
 }
 
 // UKIHasDifferentSecureBootCertificate returns a boolean indicating if a provided UKI is signed
@@ -159,17 +201,7 @@ func ListCertificates() []api.SystemSecuritySecureBootCertificate {
 // it's just another easy check to help ensure we only install valid UKIs.)
 func validatePKICertificate(cert []byte) error {
 	certEqualityFunc := func(c x509.Certificate) bool {
-		publicKeyDer, err := x509.MarshalPKIXPublicKey(c.PublicKey)
-		if err != nil {
-			return false
-		}
-
-		publicKeyBlock := pem.Block{
-			Type:  "PUBLIC KEY",
-			Bytes: publicKeyDer,
-		}
-
-		return bytes.Equal(pem.EncodeToMemory(&publicKeyBlock), cert)
+		return certificateMatchesPublicKey(c, cert)
 	}
 
 	dbCerts, err := GetCertificatesFromVar("db")
@@ -201,6 +233,21 @@ func validatePKICertificate(cert []byte) error {
 	return nil
 }
 
+// certificateMatchesPublicKey checks whether a certificate holds the provided PEM encoded public key.
+func certificateMatchesPublicKey(c x509.Certificate, publicKey []byte) bool {
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(c.PublicKey)
+	if err != nil {
+		return false
+	}
+
+	publicKeyBlock := pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDer,
+	}
+
+	return bytes.Equal(pem.EncodeToMemory(&publicKeyBlock), publicKey)
+}
+
 // getPublicKeyFromUKI extracts the public key from a UKI image.
 func getPublicKeyFromUKI(ukiFile string) ([]byte, error) {
 	peFile, err := pe.Open(ukiFile)
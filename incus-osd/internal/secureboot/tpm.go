@@ -2,32 +2,41 @@ package secureboot
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 
-	"github.com/google/go-eventlog/register"
 	"github.com/google/go-eventlog/tcg"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
+// TPMAvailable returns whether a working TPM device is present.
+func TPMAvailable(ctx context.Context) bool {
+	_, err := timeout.RunCommand(ctx, "tpm2_selftest")
+
+	return err == nil
+}
+
 // TPMStatus returns basic information about the status of the TPM.
 func TPMStatus() string {
-	eventLog, err := readTMPEventLog()
+	eventLog, bank, err := readTMPEventLog()
 	if err != nil {
 		return err.Error()
 	}
 
-	err = validateUntrustedTPMEventLog(eventLog)
+	err = validateUntrustedTPMEventLog(bank, eventLog)
 	if err != nil {
 		return err.Error()
 	}
 
-	computedPCR, err := computeNewPCR7Value(eventLog)
+	computedPCR, err := computeNewPCR7Value(bank, eventLog)
 	if err != nil {
 		return err.Error()
 	}
 
-	actualPCR, err := readPCR7()
+	actualPCR, err := readPCR(bank, 7)
 	if err != nil {
 		return err.Error()
 	}
@@ -39,52 +48,75 @@ func TPMStatus() string {
 	return "ok"
 }
 
-// readTMPEventLog reads the raw TPM measurements and returns a parsed array of Events with SHA256 hashes.
-func readTMPEventLog() ([]tcg.Event, error) {
+// readTMPEventLog reads the raw TPM measurements and returns a parsed array of Events with the hashes
+// of the strongest PCR bank available, along with that bank.
+func readTMPEventLog() ([]tcg.Event, pcrBank, error) {
+	buf, err := readRawTPMEventLog()
+	if err != nil {
+		return nil, pcrBank{}, err
+	}
+
+	return parseTPMEventLog(buf)
+}
+
+// readRawTPMEventLog returns the binary TPM measurements, as exposed by the kernel.
+func readRawTPMEventLog() ([]byte, error) {
 	rawLog, err := os.Open("/sys/kernel/security/tpm0/binary_bios_measurements")
 	if err != nil {
 		return nil, err
 	}
 	defer rawLog.Close()
 
-	buf, err := io.ReadAll(rawLog)
+	return io.ReadAll(rawLog)
+}
+
+// parseTPMEventLog parses the binary TPM measurements and returns an array of Events with the hashes
+// of the strongest PCR bank available, along with that bank.
+func parseTPMEventLog(buf []byte) ([]tcg.Event, pcrBank, error) {
+	log, err := tcg.ParseEventLog(buf, tcg.ParseOpts{})
 	if err != nil {
-		return nil, err
+		return nil, pcrBank{}, err
 	}
 
-	log, err := tcg.ParseEventLog(buf, tcg.ParseOpts{})
+	bank, err := selectPCRBank(log.Algs)
 	if err != nil {
-		return nil, err
+		return nil, pcrBank{}, err
 	}
 
-	return log.Events(register.HashSHA256), nil
+	return log.Events(bank.alg), bank, nil
 }
 
 // validateUntrustedTPMEventLog takes an untrusted TPM event log and verifies if its values
 // match what is currently reported by the TPM.
-func validateUntrustedTPMEventLog(eventLog []tcg.Event) error {
+func validateUntrustedTPMEventLog(bank pcrBank, eventLog []tcg.Event) error {
+	return validateUntrustedTPMEventLogPCR(bank, eventLog, 7)
+}
+
+// validateUntrustedTPMEventLogPCR takes an untrusted TPM event log and verifies if its values
+// for the given PCR match what is currently reported by the TPM.
+func validateUntrustedTPMEventLogPCR(bank pcrBank, eventLog []tcg.Event, index int) error {
 	var err error
 
-	// Playback the log and compute the resulting PCR7 value.
-	untrustedPCR7Digest := make([]byte, 32)
+	// Playback the log and compute the resulting PCR value.
+	untrustedPCRDigest := bank.newPCR()
 
 	for _, e := range eventLog {
-		if e.Index == 7 { // We only care about PCR7.
-			untrustedPCR7Digest, err = extendPCRValue(untrustedPCR7Digest, e.ReplayedDigest(), false)
+		if e.Index == index {
+			untrustedPCRDigest, err = extendPCRValue(bank, untrustedPCRDigest, e.ReplayedDigest(), false)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
-	// Get the current PCR7 value from the TPM.
-	actualPCR7, err := readPCR7()
+	// Get the current PCR value from the TPM.
+	actualPCR, err := readPCR(bank, index)
 	if err != nil {
 		return err
 	}
 
-	if !bytes.Equal(actualPCR7, untrustedPCR7Digest) {
-		return fmt.Errorf("computed PCR7 (%x) doesn't match actual value (%x)", untrustedPCR7Digest, actualPCR7)
+	if !bytes.Equal(actualPCR, untrustedPCRDigest) {
+		return fmt.Errorf("computed PCR%d (%x) doesn't match actual value (%x)", index, untrustedPCRDigest, actualPCR)
 	}
 
 	return nil
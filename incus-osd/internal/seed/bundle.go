@@ -0,0 +1,189 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// hostHwaddrs returns the MAC addresses of all the network interfaces, replaced in tests.
+var hostHwaddrs = func() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []string{}
+
+	for _, iface := range ifaces {
+		if len(iface.HardwareAddr) > 0 {
+			ret = append(ret, iface.HardwareAddr.String())
+		}
+	}
+
+	return ret, nil
+}
+
+// selectFileContents handles seed files made of several documents, a default configuration followed by
+// per-host overrides. Documents without a "match" key always apply, while the others only apply to the
+// matching hosts. The applicable documents are merged in order. Single document files are returned as-is.
+func selectFileContents(content []byte, isJSON bool) ([]byte, error) {
+	docs, err := splitDocuments(content, isJSON)
+	if err != nil {
+		// Let the usual decoding report errors in the first document, once the variables are expanded.
+		if len(docs) == 0 {
+			return content, nil
+		}
+
+		return nil, err
+	}
+
+	if len(docs) <= 1 {
+		return content, nil
+	}
+
+	merged := map[string]any{}
+
+	for i, doc := range docs {
+		match, hasMatch := doc["match"]
+		if hasMatch {
+			matched, err := hostMatches(match)
+			if err != nil {
+				return nil, fmt.Errorf("invalid match in seed document %d: %w", i+1, err)
+			}
+
+			if !matched {
+				continue
+			}
+
+			delete(doc, "match")
+		}
+
+		mergeDocuments(merged, doc)
+	}
+
+	if isJSON {
+		return json.Marshal(merged)
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// splitDocuments parses each document of a seed file, either a stream of JSON objects or YAML documents.
+// On error, the documents parsed so far are returned along with it.
+func splitDocuments(content []byte, isJSON bool) ([]map[string]any, error) {
+	docs := []map[string]any{}
+
+	var decode func(any) error
+
+	if isJSON {
+		decode = json.NewDecoder(bytes.NewReader(content)).Decode
+	} else {
+		decode = yaml.NewDecoder(bytes.NewReader(content)).Decode
+	}
+
+	for {
+		doc := map[string]any{}
+
+		err := decode(&doc)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+
+			return docs, err
+		}
+
+		docs = append(docs, doc)
+	}
+}
+
+// mergeDocuments recursively merges the source into the destination. Maps are merged, while any other value
+// from the source, including lists, replaces the one in the destination.
+func mergeDocuments(dst map[string]any, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]any)
+		dstMap, dstIsMap := dst[key].(map[string]any)
+
+		if srcIsMap && dstIsMap {
+			mergeDocuments(dstMap, srcMap)
+
+			continue
+		}
+
+		dst[key] = value
+	}
+}
+
+// hostMatches checks whether the system matches all the criteria of an override. Each criterion is either a
+// single value or a list of values, one of which must match.
+func hostMatches(match any) (bool, error) {
+	criteria, ok := match.(map[string]any)
+	if !ok {
+		return false, errors.New("expected a map of criteria")
+	}
+
+	for key, value := range criteria {
+		values, err := matchValues(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid %q: %w", key, err)
+		}
+
+		switch key {
+		case "serial_number":
+			// Systems whose firmware doesn't report a serial number never match.
+			serial, _ := templateDMI(nil, "product_serial")
+			if serial == "" || !slices.Contains(values, serial) {
+				return false, nil
+			}
+
+		case "mac":
+			hwaddrs, err := hostHwaddrs()
+			if err != nil {
+				return false, err
+			}
+
+			if !slices.ContainsFunc(values, func(value string) bool {
+				return slices.ContainsFunc(hwaddrs, func(hwaddr string) bool { return strings.EqualFold(value, hwaddr) })
+			}) {
+				return false, nil
+			}
+
+		default:
+			return false, fmt.Errorf("unknown criterion %q", key)
+		}
+	}
+
+	return true, nil
+}
+
+// matchValues returns the values of a criterion, provided either as a single value or a list of values.
+// Numbers are accepted too, as serial numbers are often left unquoted in YAML.
+func matchValues(value any) ([]string, error) {
+	switch v := value.(type) {
+	case string, int, float64:
+		return []string{fmt.Sprint(v)}, nil
+	case []any:
+		ret := make([]string, 0, len(v))
+
+		for _, entry := range v {
+			switch e := entry.(type) {
+			case string, int, float64:
+				ret = append(ret, fmt.Sprint(e))
+			default:
+				return nil, errors.New("expected a list of values")
+			}
+		}
+
+		return ret, nil
+	default:
+		return nil, errors.New("expected a value or a list of values")
+	}
+}
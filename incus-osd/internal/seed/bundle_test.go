@@ -0,0 +1,79 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+const testBundleYAML = `version: "1"
+dns:
+  hostname: default
+  domain: example.com
+time:
+  timezone: UTC
+---
+match:
+  serial_number: [ABC123, ABC124]
+dns:
+  hostname: "node-{{ serial_number }}"
+---
+match:
+  mac: 10:66:6A:01:02:03
+time:
+  timezone: Europe/Paris
+---
+match:
+  serial_number: 42
+dns:
+  hostname: other
+`
+
+func TestSelectFileContents(t *testing.T) { //nolint:paralleltest
+	dmiPath = t.TempDir()
+	hostHwaddrs = func() ([]string, error) { return []string{"10:66:6a:01:02:03"}, nil }
+
+	require.NoError(t, os.WriteFile(filepath.Join(dmiPath, "product_serial"), []byte("ABC124\n"), 0o600))
+
+	// Single document files are left untouched.
+	content, err := selectFileContents([]byte("dns:\n  hostname: {{ serial_number }}\n"), false)
+	require.NoError(t, err)
+	require.Equal(t, "dns:\n  hostname: {{ serial_number }}\n", string(content))
+
+	// The matching overrides are merged over the default configuration.
+	var network apiseed.Network
+
+	err = decodeFileContents(strings.NewReader(testBundleYAML), false, &network)
+	require.NoError(t, err)
+	require.Equal(t, "1", network.Version)
+	require.Equal(t, &api.SystemNetworkDNS{Hostname: "node-ABC124", Domain: "example.com"}, network.DNS)
+	require.Equal(t, "Europe/Paris", network.Time.Timezone)
+
+	// JSON files can hold a stream of objects.
+	network = apiseed.Network{}
+
+	err = decodeFileContents(strings.NewReader(`{"dns": {"hostname": "default"}}
+{"match": {"mac": "10:66:6a:01:02:04"}, "dns": {"hostname": "other"}}`), true, &network)
+	require.NoError(t, err)
+	require.Equal(t, "default", network.DNS.Hostname)
+
+	// Invalid criteria are reported.
+	_, err = selectFileContents([]byte("dns: {}\n---\nmatch:\n  hostname: foo\n"), false)
+	require.ErrorContains(t, err, "unknown criterion")
+
+	_, err = selectFileContents([]byte("dns: {}\n---\nmatch: foo\n"), false)
+	require.Error(t, err)
+
+	_, err = selectFileContents([]byte("dns: {}\n---\nmatch:\n  mac: [{}]\n"), false)
+	require.Error(t, err)
+
+	// Errors in later documents are reported.
+	_, err = selectFileContents([]byte("dns: {}\n---\n: [\n"), false)
+	require.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetCeph extracts the Ceph client configuration from the seed data.
+func GetCeph(_ context.Context) (*apiseed.Ceph, error) {
+	// Get the Ceph configuration.
+	var config apiseed.Ceph
+
+	err := parseFileContents(getSeedPath(), "ceph", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,438 @@
+package seed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v3"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// cloudInit holds the supported subset of a cloud-init NoCloud datasource.
+type cloudInit struct {
+	MetaData      cloudInitMetaData
+	UserData      cloudInitUserData
+	NetworkConfig *cloudInitNetwork
+}
+
+// cloudInitMetaData represents the meta-data file.
+type cloudInitMetaData struct {
+	InstanceID    string `yaml:"instance-id"`
+	LocalHostname string `yaml:"local-hostname"`
+	PublicKeys    any    `yaml:"public-keys"` // Either a single key, a list of keys or a map of keys.
+}
+
+// cloudInitUserData represents a "#cloud-config" user-data file.
+type cloudInitUserData struct {
+	Hostname          string   `yaml:"hostname"`
+	FQDN              string   `yaml:"fqdn"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys"`
+}
+
+// cloudInitNetwork represents a version 2 network-config file.
+type cloudInitNetwork struct {
+	Version   int                                 `yaml:"version"`
+	Ethernets map[string]cloudInitNetworkEthernet `yaml:"ethernets"`
+	Bonds     map[string]cloudInitNetworkBond     `yaml:"bonds"`
+	VLANs     map[string]cloudInitNetworkVLAN     `yaml:"vlans"`
+}
+
+// cloudInitNetworkDevice holds the addressing common to all device types.
+type cloudInitNetworkDevice struct {
+	DHCP4       bool                         `yaml:"dhcp4"`
+	DHCP6       bool                         `yaml:"dhcp6"`
+	Addresses   []string                     `yaml:"addresses"`
+	Gateway4    string                       `yaml:"gateway4"`
+	Gateway6    string                       `yaml:"gateway6"`
+	Routes      []cloudInitNetworkRoute      `yaml:"routes"`
+	Nameservers *cloudInitNetworkNameservers `yaml:"nameservers"`
+	MTU         int                          `yaml:"mtu"`
+}
+
+type cloudInitNetworkEthernet struct {
+	cloudInitNetworkDevice `yaml:",inline"`
+
+	Match *struct {
+		MACAddress string `yaml:"macaddress"`
+		Name       string `yaml:"name"`
+	} `yaml:"match"`
+	SetName string `yaml:"set-name"`
+}
+
+type cloudInitNetworkBond struct {
+	cloudInitNetworkDevice `yaml:",inline"`
+
+	Interfaces []string `yaml:"interfaces"`
+	Parameters struct {
+		Mode               string `yaml:"mode"`
+		LACPRate           string `yaml:"lacp-rate"`
+		TransmitHashPolicy string `yaml:"transmit-hash-policy"`
+		Primary            string `yaml:"primary"`
+	} `yaml:"parameters"`
+}
+
+type cloudInitNetworkVLAN struct {
+	cloudInitNetworkDevice `yaml:",inline"`
+
+	ID   int    `yaml:"id"`
+	Link string `yaml:"link"`
+}
+
+type cloudInitNetworkRoute struct {
+	To  string `yaml:"to"`
+	Via string `yaml:"via"`
+}
+
+type cloudInitNetworkNameservers struct {
+	Addresses []string `yaml:"addresses"`
+	Search    []string `yaml:"search"`
+}
+
+// getCloudInitPath returns the path to a cloud-init NoCloud volume, identified by its "cidata" label.
+func getCloudInitPath() string {
+	for _, label := range []string{"CIDATA", "cidata"} {
+		_, err := os.Stat("/dev/disk/by-label/" + label)
+		if err == nil {
+			return "/dev/disk/by-label/" + label
+		}
+	}
+
+	return ""
+}
+
+// getCloudInit reads the cloud-init NoCloud datasource, if present.
+func getCloudInit() (*cloudInit, error) {
+	partition := getCloudInitPath()
+	if partition == "" {
+		return nil, ErrNoSeedPartition
+	}
+
+	// Mount the cloud-init volume.
+	mountDir, err := os.MkdirTemp("", "incus-os-cidata")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(mountDir)
+
+	// Try to mount as vfat.
+	err = unix.Mount(partition, mountDir, "vfat", 0, "ro")
+	if err != nil {
+		// Try to mount as iso9660.
+		err = unix.Mount(partition, mountDir, "iso9660", 0, "ro")
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer unix.Unmount(mountDir, 0)
+
+	return parseCloudInit(os.DirFS(mountDir))
+}
+
+// parseCloudInit parses the meta-data, user-data and network-config files of a NoCloud datasource.
+func parseCloudInit(fsys fs.FS) (*cloudInit, error) {
+	ret := &cloudInit{}
+
+	// The meta-data file is required to identify a NoCloud datasource.
+	content, err := fs.ReadFile(fsys, "meta-data")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNoSeedData
+		}
+
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(content, &ret.MetaData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloud-init meta-data: %w", err)
+	}
+
+	// Only cloud-config user-data is supported, scripts and other formats are ignored.
+	content, err = fs.ReadFile(fsys, "user-data")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(content, []byte("#cloud-config")) {
+		err = yaml.Unmarshal(content, &ret.UserData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloud-init user-data: %w", err)
+		}
+	}
+
+	content, err = fs.ReadFile(fsys, "network-config")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ret, nil
+		}
+
+		return nil, err
+	}
+
+	// The network configuration may or may not be wrapped in a "network" key.
+	var wrapped struct {
+		Network *cloudInitNetwork `yaml:"network"`
+	}
+
+	err = yaml.Unmarshal(content, &wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cloud-init network-config: %w", err)
+	}
+
+	ret.NetworkConfig = wrapped.Network
+	if ret.NetworkConfig == nil {
+		ret.NetworkConfig = &cloudInitNetwork{}
+
+		err = yaml.Unmarshal(content, ret.NetworkConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloud-init network-config: %w", err)
+		}
+	}
+
+	if ret.NetworkConfig.Version != 2 {
+		return nil, fmt.Errorf("unsupported cloud-init network-config version %d, only version 2 is supported", ret.NetworkConfig.Version)
+	}
+
+	return ret, nil
+}
+
+// Hostname returns the hostname and domain, from the user-data or the meta-data.
+func (c *cloudInit) Hostname() (string, string) {
+	if c.UserData.FQDN != "" {
+		hostname, domain, _ := strings.Cut(c.UserData.FQDN, ".")
+
+		return hostname, domain
+	}
+
+	hostname := c.UserData.Hostname
+	if hostname == "" {
+		hostname = c.MetaData.LocalHostname
+	}
+
+	hostname, domain, _ := strings.Cut(hostname, ".")
+
+	return hostname, domain
+}
+
+// SSHKeys returns the SSH public keys, from the user-data and the meta-data.
+func (c *cloudInit) SSHKeys() []string {
+	keys := slices.Clone(c.UserData.SSHAuthorizedKeys)
+
+	switch publicKeys := c.MetaData.PublicKeys.(type) {
+	case string:
+		keys = append(keys, publicKeys)
+	case []any:
+		for _, key := range publicKeys {
+			value, ok := key.(string)
+			if ok {
+				keys = append(keys, value)
+			}
+		}
+
+	case map[string]any:
+		for _, name := range slices.Sorted(maps.Keys(publicKeys)) {
+			value, ok := publicKeys[name].(string)
+			if ok {
+				keys = append(keys, value)
+			}
+		}
+
+	default:
+	}
+
+	return keys
+}
+
+// SystemNetworkConfig converts the network-config into an IncusOS network configuration, using lookupHwaddr
+// to find the MAC address of interfaces which aren't matched by MAC address.
+func (c *cloudInit) SystemNetworkConfig(lookupHwaddr func(name string) (string, error)) (*api.SystemNetworkConfig, error) {
+	if c.NetworkConfig == nil {
+		return nil, ErrNoSeedSection
+	}
+
+	ret := &api.SystemNetworkConfig{}
+
+	// Record the MAC address of each ethernet device, as needed to match both interfaces and bond members.
+	hwaddrs := map[string]string{}
+
+	for _, name := range slices.Sorted(maps.Keys(c.NetworkConfig.Ethernets)) {
+		ethernet := c.NetworkConfig.Ethernets[name]
+
+		hwaddr := ""
+		if ethernet.Match != nil {
+			hwaddr = ethernet.Match.MACAddress
+		}
+
+		if hwaddr == "" {
+			lookupName := name
+			if ethernet.Match != nil && ethernet.Match.Name != "" {
+				lookupName = ethernet.Match.Name
+			}
+
+			var err error
+
+			hwaddr, err = lookupHwaddr(lookupName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find cloud-init ethernet device %q: %w", name, err)
+			}
+		}
+
+		hwaddrs[name] = hwaddr
+	}
+
+	// Bond members are only configured as part of their bond.
+	members := map[string]bool{}
+
+	for _, name := range slices.Sorted(maps.Keys(c.NetworkConfig.Bonds)) {
+		bond := c.NetworkConfig.Bonds[name]
+
+		mode := bond.Parameters.Mode
+		if mode == "" {
+			mode = "balance-rr"
+		}
+
+		apiBond := api.SystemNetworkBond{
+			Name:       name,
+			Mode:       mode,
+			LACPRate:   bond.Parameters.LACPRate,
+			HashPolicy: bond.Parameters.TransmitHashPolicy,
+			MTU:        bond.MTU,
+		}
+
+		for _, member := range bond.Interfaces {
+			hwaddr, ok := hwaddrs[member]
+			if !ok {
+				return nil, fmt.Errorf("cloud-init bond %q references unknown ethernet device %q", name, member)
+			}
+
+			members[member] = true
+			apiBond.Members = append(apiBond.Members, hwaddr)
+		}
+
+		if bond.Parameters.Primary != "" {
+			if !slices.Contains(bond.Interfaces, bond.Parameters.Primary) {
+				return nil, fmt.Errorf("cloud-init bond %q primary %q isn't one of its interfaces", name, bond.Parameters.Primary)
+			}
+
+			apiBond.Primary = hwaddrs[bond.Parameters.Primary]
+		}
+
+		apiBond.Addresses, apiBond.Routes = bond.convert(ret)
+		ret.Bonds = append(ret.Bonds, apiBond)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.NetworkConfig.Ethernets)) {
+		if members[name] {
+			continue
+		}
+
+		ethernet := c.NetworkConfig.Ethernets[name]
+
+		iface := api.SystemNetworkInterface{
+			Name:   name,
+			MTU:    ethernet.MTU,
+			Hwaddr: hwaddrs[name],
+		}
+
+		if ethernet.SetName != "" {
+			iface.Name = ethernet.SetName
+		}
+
+		iface.Addresses, iface.Routes = ethernet.convert(ret)
+		ret.Interfaces = append(ret.Interfaces, iface)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.NetworkConfig.VLANs)) {
+		vlan := c.NetworkConfig.VLANs[name]
+
+		apiVLAN := api.SystemNetworkVLAN{
+			Name:   name,
+			Parent: vlan.Link,
+			ID:     vlan.ID,
+			MTU:    vlan.MTU,
+		}
+
+		apiVLAN.Addresses, apiVLAN.Routes = vlan.convert(ret)
+		ret.VLANs = append(ret.VLANs, apiVLAN)
+	}
+
+	return ret, nil
+}
+
+// convert returns the addresses and routes of the device, adding its nameservers to the global DNS configuration.
+func (d *cloudInitNetworkDevice) convert(config *api.SystemNetworkConfig) ([]string, []api.SystemNetworkRoute) {
+	var addresses []string
+
+	if d.DHCP4 {
+		addresses = append(addresses, "dhcp4")
+	}
+
+	if d.DHCP6 {
+		addresses = append(addresses, "dhcp6", "slaac")
+	}
+
+	addresses = append(addresses, d.Addresses...)
+
+	var routes []api.SystemNetworkRoute
+
+	if d.Gateway4 != "" {
+		routes = append(routes, api.SystemNetworkRoute{To: "0.0.0.0/0", Via: d.Gateway4})
+	}
+
+	if d.Gateway6 != "" {
+		routes = append(routes, api.SystemNetworkRoute{To: "::/0", Via: d.Gateway6})
+	}
+
+	for _, route := range d.Routes {
+		to := route.To
+		if to == "default" {
+			to = "0.0.0.0/0"
+
+			if strings.Contains(route.Via, ":") {
+				to = "::/0"
+			}
+		}
+
+		routes = append(routes, api.SystemNetworkRoute{To: to, Via: route.Via})
+	}
+
+	if d.Nameservers != nil {
+		if config.DNS == nil {
+			config.DNS = &api.SystemNetworkDNS{}
+		}
+
+		for _, nameserver := range d.Nameservers.Addresses {
+			if !slices.Contains(config.DNS.Nameservers, nameserver) {
+				config.DNS.Nameservers = append(config.DNS.Nameservers, nameserver)
+			}
+		}
+
+		for _, search := range d.Nameservers.Search {
+			if !slices.Contains(config.DNS.SearchDomains, search) {
+				config.DNS.SearchDomains = append(config.DNS.SearchDomains, search)
+			}
+		}
+	}
+
+	return addresses, routes
+}
+
+// lookupHwaddr returns the MAC address of a local network interface.
+func lookupHwaddr(name string) (string, error) {
+	iface, err := net.InterfaceByName(filepath.Base(name))
+	if err != nil {
+		return "", err
+	}
+
+	return iface.HardwareAddr.String(), nil
+}
@@ -0,0 +1,128 @@
+package seed
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestParseCloudInit(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseCloudInit(fstest.MapFS{})
+	require.ErrorIs(t, err, ErrNoSeedData)
+
+	ci, err := parseCloudInit(fstest.MapFS{
+		"meta-data": {Data: []byte("instance-id: iid-1\nlocal-hostname: node01\npublic-keys:\n  b: ssh-ed25519 BBBB\n  a: ssh-ed25519 AAAA\n")},
+		"user-data": {Data: []byte("#cloud-config\nfqdn: server01.example.org\nssh_authorized_keys:\n  - ssh-ed25519 CCCC\n")},
+	})
+	require.NoError(t, err)
+	require.Nil(t, ci.NetworkConfig)
+
+	hostname, domain := ci.Hostname()
+	require.Equal(t, "server01", hostname)
+	require.Equal(t, "example.org", domain)
+	require.Equal(t, []string{"ssh-ed25519 CCCC", "ssh-ed25519 AAAA", "ssh-ed25519 BBBB"}, ci.SSHKeys())
+
+	// Scripts are ignored.
+	ci, err = parseCloudInit(fstest.MapFS{
+		"meta-data": {Data: []byte("local-hostname: node01\n")},
+		"user-data": {Data: []byte("#!/bin/sh\necho hello\n")},
+	})
+	require.NoError(t, err)
+
+	hostname, domain = ci.Hostname()
+	require.Equal(t, "node01", hostname)
+	require.Empty(t, domain)
+
+	_, err = parseCloudInit(fstest.MapFS{
+		"meta-data":      {Data: []byte("instance-id: iid-1\n")},
+		"network-config": {Data: []byte("version: 1\nconfig: []\n")},
+	})
+	require.Error(t, err)
+}
+
+func TestCloudInitSystemNetworkConfig(t *testing.T) {
+	t.Parallel()
+
+	ci, err := parseCloudInit(fstest.MapFS{
+		"meta-data": {Data: []byte("instance-id: iid-1\n")},
+		"network-config": {Data: []byte(`network:
+  version: 2
+  ethernets:
+    eno1:
+      match:
+        macaddress: "00:11:22:33:44:55"
+      set-name: uplink
+      addresses: [192.0.2.10/24]
+      gateway4: 192.0.2.1
+      nameservers:
+        addresses: [192.0.2.53]
+        search: [example.org]
+    eno2: {}
+    eno3: {}
+  bonds:
+    bond0:
+      interfaces: [eno2, eno3]
+      parameters:
+        mode: 802.3ad
+        lacp-rate: fast
+        transmit-hash-policy: layer3+4
+      dhcp4: true
+      dhcp6: true
+  vlans:
+    vlan100:
+      id: 100
+      link: bond0
+      addresses: [2001:db8::10/64]
+      routes:
+        - to: default
+          via: 2001:db8::1
+`)},
+	})
+	require.NoError(t, err)
+
+	hwaddrs := map[string]string{"eno2": "00:11:22:33:44:66", "eno3": "00:11:22:33:44:77"}
+
+	config, err := ci.SystemNetworkConfig(func(name string) (string, error) {
+		hwaddr, ok := hwaddrs[name]
+		if !ok {
+			return "", errors.New("not found")
+		}
+
+		return hwaddr, nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []api.SystemNetworkInterface{{
+		Name:      "uplink",
+		Hwaddr:    "00:11:22:33:44:55",
+		Addresses: []string{"192.0.2.10/24"},
+		Routes:    []api.SystemNetworkRoute{{To: "0.0.0.0/0", Via: "192.0.2.1"}},
+	}}, config.Interfaces)
+
+	require.Len(t, config.Bonds, 1)
+	require.Equal(t, "802.3ad", config.Bonds[0].Mode)
+	require.Equal(t, "fast", config.Bonds[0].LACPRate)
+	require.Equal(t, "layer3+4", config.Bonds[0].HashPolicy)
+	require.Equal(t, []string{"00:11:22:33:44:66", "00:11:22:33:44:77"}, config.Bonds[0].Members)
+	require.Equal(t, []string{"dhcp4", "dhcp6", "slaac"}, config.Bonds[0].Addresses)
+
+	require.Len(t, config.VLANs, 1)
+	require.Equal(t, "bond0", config.VLANs[0].Parent)
+	require.Equal(t, 100, config.VLANs[0].ID)
+	require.Equal(t, []api.SystemNetworkRoute{{To: "::/0", Via: "2001:db8::1"}}, config.VLANs[0].Routes)
+
+	require.Equal(t, []string{"192.0.2.53"}, config.DNS.Nameservers)
+	require.Equal(t, []string{"example.org"}, config.DNS.SearchDomains)
+
+	// Unknown devices are reported.
+	_, err = ci.SystemNetworkConfig(func(_ string) (string, error) {
+		return "", errors.New("not found")
+	})
+	require.Error(t, err)
+}
@@ -14,19 +14,52 @@ func GetNetwork(_ context.Context) (*api.SystemNetworkConfig, error) {
 	// Get the network configuration.
 	var config apiseed.Network
 
-	err := parseFileContents(getSeedPath(), "network", &config)
+	// A cloud-init NoCloud datasource may provide the network configuration and hostname.
+	ci, err := getCloudInit()
+	if err != nil && !IsMissing(err) {
+		return nil, err
+	}
+
+	err = parseFileContents(getSeedPath(), "network", &config)
 	if err != nil {
 		if !IsMissing(err) {
 			return nil, err
 		}
 
-		// No seed network available; return a minimal default.
-		defaultNetwork, err := getDefaultNetworkConfig()
-		if err != nil {
-			return nil, err
+		// No seed network available; use the cloud-init one, or return a minimal default.
+		var network *api.SystemNetworkConfig
+
+		if ci != nil {
+			network, err = ci.SystemNetworkConfig(lookupHwaddr)
+			if err != nil && !IsMissing(err) {
+				return nil, err
+			}
+		}
+
+		if network == nil {
+			network, err = getDefaultNetworkConfig()
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		return defaultNetwork, nil
+		config.SystemNetworkConfig = *network
+	}
+
+	// Use the cloud-init hostname, unless one is configured.
+	if ci != nil && (config.DNS == nil || config.DNS.Hostname == "") {
+		hostname, domain := ci.Hostname()
+		if hostname != "" {
+			if config.DNS == nil {
+				config.DNS = &api.SystemNetworkDNS{}
+			}
+
+			config.DNS.Hostname = hostname
+
+			if config.DNS.Domain == "" {
+				config.DNS.Domain = domain
+			}
+		}
 	}
 
 	// If no interfaces, bonds, or vlans are defined, add a minimal default configuration for the interfaces.
@@ -51,6 +84,18 @@ func GetNetwork(_ context.Context) (*api.SystemNetworkConfig, error) {
 	return &config.SystemNetworkConfig, nil
 }
 
+// GetNetworkSeed returns the network configuration from the seed data, without applying any default.
+func GetNetworkSeed(_ context.Context) (*api.SystemNetworkConfig, error) {
+	var config apiseed.Network
+
+	err := parseFileContents(getSeedPath(), "network", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config.SystemNetworkConfig, nil
+}
+
 // NetworkConfigHasEmptyDevices checks if any device (interface, bond, or vlan) is defined in the given config.
 func NetworkConfigHasEmptyDevices(networkCfg api.SystemNetworkConfig) bool {
 	return len(networkCfg.Interfaces) == 0 && len(networkCfg.Bonds) == 0 && len(networkCfg.VLANs) == 0
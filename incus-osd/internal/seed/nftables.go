@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetNftables extracts the nftables firewall service configuration from the seed data.
+func GetNftables(_ context.Context) (*apiseed.Nftables, error) {
+	// Get the firewall configuration.
+	var config apiseed.Nftables
+
+	err := parseFileContents(getSeedPath(), "nftables", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
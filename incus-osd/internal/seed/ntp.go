@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetNTP extracts the NTP service configuration from the seed data.
+func GetNTP(_ context.Context) (*apiseed.NTP, error) {
+	// Get the NTP configuration.
+	var config apiseed.NTP
+
+	err := parseFileContents(getSeedPath(), "ntp", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
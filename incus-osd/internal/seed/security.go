@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetSecurity extracts the security configuration from the seed data.
+func GetSecurity(_ context.Context) (*apiseed.Security, error) {
+	// Get the security configuration.
+	var config apiseed.Security
+
+	err := parseFileContents(getSeedPath(), "security", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
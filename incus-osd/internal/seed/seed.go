@@ -28,8 +28,8 @@ func IsMissing(e error) bool {
 	return false
 }
 
-// CleanupPostInstall will remove the seed install from the target partition and copy any
-// external user-provided seeds.
+// CleanupPostInstall will remove the seed install from the target partition, copy any
+// external user-provided seeds and mark the target partition as installed.
 func CleanupPostInstall(ctx context.Context, targetSeedPartition string) error {
 	// Remove the install configuration file, if present, from the target seed partition.
 	for _, filename := range []string{"install.json", "install.yaml", "install.yml"} {
@@ -96,9 +96,31 @@ func CleanupPostInstall(ctx context.Context, targetSeedPartition string) error {
 		}
 	}
 
+	// Mark the target seed partition as installed, so an install seed provided through SMBIOS is ignored.
+	markerDir, err := os.MkdirTemp("", "incus-os-seed")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(markerDir)
+
+	err = os.WriteFile(filepath.Join(markerDir, installedMarker+".json"), []byte("{}\n"), 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "tar", "-f", targetSeedPartition, "-C", markerDir, "--append", "--add-file", installedMarker+".json")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// UserSeedPresent checks whether an external user-provided seed partition is attached.
+func UserSeedPresent() bool {
+	return getSeedPath() != "/dev/disk/by-partlabel/seed-data"
+}
+
 // getSeedPath defines the path to the expected seed configuration. It will first search for any
 // disk with a "SEED_DATA" label, which would be externally provided by the user. If not found,
 // defaults to the "seed-data" partition that exists on install media.
@@ -131,7 +153,43 @@ func parseFileContents(partition string, filename string, target any) error {
 	}
 
 	// Fallback to seed data from install media.
-	return parseFileContentsFromRawTar(partition, filename, target)
+	err = parseFileContentsFromRawTar(partition, filename, target)
+	if err == nil || !IsMissing(err) {
+		return err
+	}
+
+	// Finally, check for seed data provided through SMBIOS OEM strings.
+	smbiosErr := parseFileContentsFromSMBIOS(filename, target)
+	if smbiosErr == nil || !IsMissing(smbiosErr) {
+		return smbiosErr
+	}
+
+	return err
+}
+
+// decodeFileContents selects the documents applying to this system from a seed file, expands its template
+// variables, then decodes it into the target.
+func decodeFileContents(r io.Reader, isJSON bool, target any) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	content, err = selectFileContents(content, isJSON)
+	if err != nil {
+		return err
+	}
+
+	content, err = expandTemplate(content, isJSON)
+	if err != nil {
+		return err
+	}
+
+	if isJSON {
+		return json.NewDecoder(bytes.NewReader(content)).Decode(target)
+	}
+
+	return yaml.NewDecoder(bytes.NewReader(content)).Decode(target)
 }
 
 // parseFileContentsFromUserPartition searches for a given file in the user-provided seed partition and returns its contents as a byte array if found.
@@ -169,14 +227,7 @@ func parseFileContentsFromUserPartition(partition string, filename string, targe
 			}
 			defer f.Close() //nolint:revive
 
-			decoder := json.NewDecoder(f)
-
-			err = decoder.Decode(target)
-			if err != nil {
-				return err
-			}
-
-			return nil
+			return decodeFileContents(f, true, target)
 
 		case filename + ".yaml":
 			f, err := os.Open(filepath.Join(mountDir, filename+".yaml")) //nolint:gosec
@@ -185,14 +236,7 @@ func parseFileContentsFromUserPartition(partition string, filename string, targe
 			}
 			defer f.Close() //nolint:revive
 
-			decoder := yaml.NewDecoder(f)
-
-			err = decoder.Decode(target)
-			if err != nil {
-				return err
-			}
-
-			return nil
+			return decodeFileContents(f, false, target)
 
 		case filename + ".yml":
 			f, err := os.Open(filepath.Join(mountDir, filename+".yml")) //nolint:gosec
@@ -201,14 +245,7 @@ func parseFileContentsFromUserPartition(partition string, filename string, targe
 			}
 			defer f.Close() //nolint:revive
 
-			decoder := yaml.NewDecoder(f)
-
-			err = decoder.Decode(target)
-			if err != nil {
-				return err
-			}
-
-			return nil
+			return decodeFileContents(f, false, target)
 
 		default:
 		}
@@ -266,24 +303,10 @@ func parseFileContentsFromRawTar(partition string, filename string, target any)
 		// Check if expected file.
 		switch hdr.Name {
 		case filename + ".json":
-			decoder := json.NewDecoder(tr)
-
-			err = decoder.Decode(target)
-			if err != nil {
-				return err
-			}
-
-			return nil
+			return decodeFileContents(tr, true, target)
 
 		case filename + ".yaml", filename + ".yml":
-			decoder := yaml.NewDecoder(tr)
-
-			err = decoder.Decode(target)
-			if err != nil {
-				return err
-			}
-
-			return nil
+			return decodeFileContents(tr, false, target)
 
 		default:
 		}
@@ -0,0 +1,156 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// smbiosEntriesPath is where the kernel exposes the raw SMBIOS OEM strings (type 11) structures.
+var smbiosEntriesPath = "/sys/firmware/dmi/entries"
+
+// installedMarker is appended to the seed partition of the installed system, so an install seed
+// still provided through SMBIOS doesn't trigger a new install on every boot.
+const installedMarker = "installed"
+
+const (
+	// smbiosSeedPrefix prefixes OEM strings holding a seed file as plain JSON or YAML.
+	smbiosSeedPrefix = "io.incus-os.seed:"
+
+	// smbiosSeedBase64Prefix prefixes OEM strings holding a base64 encoded seed file.
+	smbiosSeedBase64Prefix = "io.incus-os.seed.base64:"
+)
+
+// parseFileContentsFromSMBIOS searches for a given file in the SMBIOS OEM strings, as set by hypervisors
+// or bare-metal providers, and decodes it into the target if found.
+func parseFileContentsFromSMBIOS(filename string, target any) error {
+	// Don't trigger another install once installed.
+	if filename == "install" {
+		err := parseFileContentsFromRawTar("/dev/disk/by-partlabel/seed-data", installedMarker, &map[string]any{})
+		if err == nil {
+			return ErrNoSeedSection
+		}
+	}
+
+	oemStrings, err := getSMBIOSOEMStrings()
+	if err != nil {
+		return err
+	}
+
+	content, err := getSMBIOSSeed(oemStrings, filename)
+	if err != nil {
+		return err
+	}
+
+	// JSON is valid YAML, but the JSON decoder is used where possible to follow the JSON field names.
+	return decodeFileContents(bytes.NewReader(content), bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")), target)
+}
+
+// getSMBIOSSeed returns the content of the seed file from the list of OEM strings. Later strings take precedence.
+func getSMBIOSSeed(oemStrings []string, filename string) ([]byte, error) {
+	var content []byte
+
+	found := false
+
+	for _, entry := range oemStrings {
+		var value string
+
+		encoded := false
+
+		if strings.HasPrefix(entry, smbiosSeedBase64Prefix) {
+			value = strings.TrimPrefix(entry, smbiosSeedBase64Prefix)
+			encoded = true
+		} else if strings.HasPrefix(entry, smbiosSeedPrefix) {
+			value = strings.TrimPrefix(entry, smbiosSeedPrefix)
+		} else {
+			continue
+		}
+
+		name, value, ok := strings.Cut(value, "=")
+		if !ok || name != filename {
+			continue
+		}
+
+		if encoded {
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 encoded SMBIOS seed for %q: %w", filename, err)
+			}
+
+			content = decoded
+		} else {
+			content = []byte(value)
+		}
+
+		found = true
+	}
+
+	if !found {
+		return nil, ErrNoSeedSection
+	}
+
+	return content, nil
+}
+
+// getSMBIOSOEMStrings returns all the OEM strings from the SMBIOS type 11 structures.
+func getSMBIOSOEMStrings() ([]string, error) {
+	entries, err := filepath.Glob(filepath.Join(smbiosEntriesPath, "11-*", "raw"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoSeedData
+	}
+
+	ret := []string{}
+
+	for _, entry := range entries {
+		raw, err := os.ReadFile(entry) //nolint:gosec
+		if err != nil {
+			return nil, err
+		}
+
+		oemStrings, err := parseSMBIOSOEMStrings(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMBIOS structure %q: %w", entry, err)
+		}
+
+		ret = append(ret, oemStrings...)
+	}
+
+	return ret, nil
+}
+
+// parseSMBIOSOEMStrings parses a raw SMBIOS type 11 structure. Its formatted area is made of the type,
+// length, handle and string count, followed by the NULL terminated strings and a final NULL byte.
+func parseSMBIOSOEMStrings(raw []byte) ([]string, error) {
+	if len(raw) < 5 || raw[0] != 11 {
+		return nil, errors.New("not an OEM strings structure")
+	}
+
+	length := int(raw[1])
+	if length < 5 || length > len(raw) {
+		return nil, errors.New("invalid structure length")
+	}
+
+	count := int(raw[4])
+	ret := make([]string, 0, count)
+
+	remaining := raw[length:]
+	for range count {
+		end := bytes.IndexByte(remaining, 0)
+		if end < 0 {
+			return nil, errors.New("unterminated string")
+		}
+
+		ret = append(ret, string(remaining[:end]))
+		remaining = remaining[end+1:]
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,75 @@
+package seed
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+func TestParseSMBIOSOEMStrings(t *testing.T) {
+	t.Parallel()
+
+	raw := []byte{11, 5, 0x2a, 0x00, 2}
+	raw = append(raw, []byte("io.incus-os.seed:provider={\"name\": \"local\"}\x00other\x00\x00")...)
+
+	oemStrings, err := parseSMBIOSOEMStrings(raw)
+	require.NoError(t, err)
+	require.Equal(t, []string{"io.incus-os.seed:provider={\"name\": \"local\"}", "other"}, oemStrings)
+
+	_, err = parseSMBIOSOEMStrings([]byte{1, 5, 0, 0, 1, 0, 0})
+	require.Error(t, err)
+
+	_, err = parseSMBIOSOEMStrings([]byte{11, 5, 0, 0, 1, 'a'})
+	require.Error(t, err)
+}
+
+func TestGetSMBIOSSeed(t *testing.T) {
+	t.Parallel()
+
+	oemStrings := []string{
+		"io.systemd.credential:foo=bar",
+		"io.incus-os.seed:applications=applications: [{name: foo}]",
+		"io.incus-os.seed.base64:applications=" + base64.StdEncoding.EncodeToString([]byte("applications:\n  - name: bar\n")),
+	}
+
+	content, err := getSMBIOSSeed(oemStrings, "applications")
+	require.NoError(t, err)
+	require.Equal(t, "applications:\n  - name: bar\n", string(content))
+
+	_, err = getSMBIOSSeed(oemStrings, "network")
+	require.ErrorIs(t, err, ErrNoSeedSection)
+
+	_, err = getSMBIOSSeed([]string{"io.incus-os.seed.base64:network=!"}, "network")
+	require.Error(t, err)
+}
+
+func TestParseFileContentsFromSMBIOS(t *testing.T) { //nolint:paralleltest
+	smbiosEntriesPath = t.TempDir()
+
+	var apps apiseed.Applications
+
+	err := parseFileContentsFromSMBIOS("applications", &apps)
+	require.ErrorIs(t, err, ErrNoSeedData)
+
+	raw := []byte{11, 5, 0x2a, 0x00, 1}
+	raw = append(raw, []byte("io.incus-os.seed:applications={\"version\": \"1\", \"applications\": [{\"name\": \"incus\"}]}\x00\x00")...)
+
+	err = os.MkdirAll(filepath.Join(smbiosEntriesPath, "11-0"), 0o700)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(smbiosEntriesPath, "11-0", "raw"), raw, 0o600)
+	require.NoError(t, err)
+
+	err = parseFileContentsFromSMBIOS("applications", &apps)
+	require.NoError(t, err)
+	require.Len(t, apps.Applications, 1)
+	require.Equal(t, "incus", apps.Applications[0].Name)
+
+	err = parseFileContentsFromSMBIOS("network", &apps)
+	require.ErrorIs(t, err, ErrNoSeedSection)
+}
@@ -0,0 +1,37 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetSSH extracts the SSH service configuration from the seed data.
+// If no SSH seed is found, the SSH service is enabled with the keys from a cloud-init NoCloud datasource, if any.
+func GetSSH(_ context.Context) (*apiseed.SSH, error) {
+	// Get the SSH configuration.
+	var config apiseed.SSH
+
+	err := parseFileContents(getSeedPath(), "ssh", &config)
+	if err == nil {
+		return &config, nil
+	} else if !IsMissing(err) {
+		return nil, err
+	}
+
+	// No SSH seed available; use the keys from cloud-init.
+	ci, err := getCloudInit()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := ci.SSHKeys()
+	if len(keys) == 0 {
+		return nil, ErrNoSeedSection
+	}
+
+	config.Enabled = true
+	config.AuthorizedKeys = keys
+
+	return &config, nil
+}
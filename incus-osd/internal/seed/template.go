@@ -0,0 +1,249 @@
+package seed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dmiPath is where the kernel exposes the SMBIOS system information.
+var dmiPath = "/sys/class/dmi/id"
+
+// networkdLeasesPath is where systemd-networkd stores the DHCP leases.
+var networkdLeasesPath = "/run/systemd/netif/leases"
+
+// templateHwaddr returns the MAC address of a network interface, replaced in tests.
+var templateHwaddr = lookupHwaddr
+
+// templateRegexp matches the variables in seed files, such as "{{ serial_number }}" or "{{ mac eth0 }}".
+var templateRegexp = regexp.MustCompile(`\{\{\s*([a-z_]+)((?:\s+[^\s{}]+)*)\s*\}\}`)
+
+// templateVariables maps each supported variable to the function resolving it from its arguments.
+var templateVariables = map[string]func(args []string) (string, error){
+	"dhcp_hostname": templateDHCPHostname,
+	"mac":           templateMAC,
+	"serial_number": func(args []string) (string, error) { return templateDMI(args, "product_serial") },
+	"uuid":          func(args []string) (string, error) { return templateDMI(args, "product_uuid") },
+}
+
+// expandTemplate replaces the variables in the string values of a seed file with their runtime value. Values
+// are expanded after parsing the file, so they can't alter its structure, and unknown variables are left
+// untouched as they may be meant for another tool, such as cloud-init.
+func expandTemplate(content []byte, isJSON bool) ([]byte, error) {
+	if !templateRegexp.Match(content) {
+		return content, nil
+	}
+
+	if isJSON {
+		var tree any
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+
+		err := decoder.Decode(&tree)
+		if err != nil {
+			// Let the usual decoding report the error.
+			return content, nil //nolint:nilerr
+		}
+
+		tree, err = expandJSONValue(tree)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(tree)
+	}
+
+	var node yaml.Node
+
+	err := yaml.Unmarshal(content, &node)
+	if err != nil {
+		// Let the usual decoding report the error.
+		return content, nil //nolint:nilerr
+	}
+
+	err = expandYAMLNode(&node)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(&node)
+}
+
+// expandJSONValue expands the variables in the string values of a decoded JSON document, leaving its keys untouched.
+func expandJSONValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return expandString(v)
+
+	case map[string]any:
+		for key, entry := range v {
+			expanded, err := expandJSONValue(entry)
+			if err != nil {
+				return nil, err
+			}
+
+			v[key] = expanded
+		}
+
+	case []any:
+		for i, entry := range v {
+			expanded, err := expandJSONValue(entry)
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = expanded
+		}
+	}
+
+	return value, nil
+}
+
+// expandYAMLNode expands the variables in the string values of a YAML node, leaving its keys untouched.
+func expandYAMLNode(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" {
+			return nil
+		}
+
+		value, err := expandString(node.Value)
+		if err != nil {
+			return err
+		}
+
+		// Keep the value a string, whatever it now looks like.
+		node.Value = value
+		node.Tag = "!!str"
+
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			err := expandYAMLNode(node.Content[i])
+			if err != nil {
+				return err
+			}
+		}
+
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			err := expandYAMLNode(child)
+			if err != nil {
+				return err
+			}
+		}
+
+	case yaml.AliasNode:
+	}
+
+	return nil
+}
+
+// expandString replaces the known variables in a string value with their runtime value.
+func expandString(value string) (string, error) {
+	var templateErr error
+
+	ret := templateRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		if templateErr != nil {
+			return match
+		}
+
+		submatches := templateRegexp.FindStringSubmatch(match)
+		name := submatches[1]
+		args := strings.Fields(submatches[2])
+
+		resolve, ok := templateVariables[name]
+		if !ok {
+			return match
+		}
+
+		resolved, err := resolve(args)
+		if err != nil {
+			templateErr = fmt.Errorf("failed to resolve seed variable %q: %w", name, err)
+
+			return match
+		}
+
+		return resolved
+	})
+
+	if templateErr != nil {
+		return "", templateErr
+	}
+
+	return ret, nil
+}
+
+// templateDMI returns the value of a SMBIOS system information field.
+func templateDMI(args []string, field string) (string, error) {
+	if len(args) != 0 {
+		return "", errors.New("no argument expected")
+	}
+
+	content, err := os.ReadFile(filepath.Join(dmiPath, field)) //nolint:gosec
+	if err != nil {
+		return "", err
+	}
+
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return "", fmt.Errorf("no %s reported by the firmware", field)
+	}
+
+	return value, nil
+}
+
+// templateMAC returns the MAC address of the network interface provided as argument.
+func templateMAC(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("expected a network interface name")
+	}
+
+	hwaddr, err := templateHwaddr(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	if hwaddr == "" {
+		return "", fmt.Errorf("network interface %q has no MAC address", args[0])
+	}
+
+	return hwaddr, nil
+}
+
+// templateDHCPHostname returns the hostname provided by a DHCP server, which is only available once the network is up.
+func templateDHCPHostname(args []string) (string, error) {
+	if len(args) != 0 {
+		return "", errors.New("no argument expected")
+	}
+
+	entries, err := os.ReadDir(networkdLeasesPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(networkdLeasesPath, entry.Name())) //nolint:gosec
+		if err != nil {
+			return "", err
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			hostname, ok := strings.CutPrefix(scanner.Text(), "HOSTNAME=")
+			if ok && hostname != "" {
+				return hostname, nil
+			}
+		}
+	}
+
+	return "", errors.New("no hostname provided by DHCP")
+}
@@ -0,0 +1,111 @@
+package seed
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+func TestExpandTemplate(t *testing.T) { //nolint:paralleltest
+	dmiPath = t.TempDir()
+	networkdLeasesPath = t.TempDir()
+	templateHwaddr = func(name string) (string, error) {
+		if name != "eth0" {
+			return "", errors.New("no such network interface")
+		}
+
+		return "10:66:6a:01:02:03", nil
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dmiPath, "product_serial"), []byte("ABC123\n"), 0o600))
+
+	// Content without variables is left untouched.
+	content, err := expandTemplate([]byte("hostname: server01\n"), false)
+	require.NoError(t, err)
+	require.Equal(t, "hostname: server01\n", string(content))
+
+	content, err = expandTemplate([]byte("hostname: node-{{ serial_number }}\nmac: \"{{mac eth0}}\"\n"), false)
+	require.NoError(t, err)
+	require.Equal(t, "hostname: node-ABC123\nmac: \"10:66:6a:01:02:03\"\n", string(content))
+
+	content, err = expandTemplate([]byte(`{"hostname": "node-{{ serial_number }}", "port": 8443}`), true)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"hostname": "node-ABC123", "port": 8443}`, string(content))
+
+	// Unknown variables are left for other tools, such as cloud-init.
+	content, err = expandTemplate([]byte("user-data: \"hostname: {{ ds.meta_data.hostname }} {{ foo }}\"\n"), false)
+	require.NoError(t, err)
+	require.Equal(t, "user-data: \"hostname: {{ ds.meta_data.hostname }} {{ foo }}\"\n", string(content))
+
+	// Unresolved variables and invalid arguments are reported.
+	_, err = expandTemplate([]byte("value: \"{{ uuid }}\""), false)
+	require.Error(t, err)
+
+	_, err = expandTemplate([]byte("value: \"{{ mac eth1 }}\""), false)
+	require.Error(t, err)
+
+	_, err = expandTemplate([]byte(`{"value": "{{ mac }}"}`), true)
+	require.Error(t, err)
+
+	_, err = expandTemplate([]byte("value: \"{{ serial_number eth0 }}\""), false)
+	require.Error(t, err)
+
+	// The DHCP hostname is only available once a lease was obtained.
+	_, err = expandTemplate([]byte("value: \"{{ dhcp_hostname }}\""), false)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(networkdLeasesPath, "2"), []byte("ADDRESS=10.0.0.2\nHOSTNAME=rack1-node4\n"), 0o600))
+
+	content, err = expandTemplate([]byte("value: \"{{ dhcp_hostname }}.example.com\""), false)
+	require.NoError(t, err)
+	require.Equal(t, "value: \"rack1-node4.example.com\"\n", string(content))
+}
+
+func TestExpandTemplateEscaping(t *testing.T) { //nolint:paralleltest
+	dmiPath = t.TempDir()
+
+	// Values are inserted as strings, whatever they contain.
+	require.NoError(t, os.WriteFile(filepath.Join(dmiPath, "product_serial"), []byte("A\"B: c\n- d\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dmiPath, "product_uuid"), []byte("1234\n"), 0o600))
+
+	var config map[string]any
+
+	content, err := expandTemplate([]byte("hostname: \"{{ serial_number }}\"\nid: \"{{ uuid }}\"\n"), false)
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(content, &config))
+	require.Equal(t, map[string]any{"hostname": "A\"B: c\n- d", "id": "1234"}, config)
+
+	config = nil
+
+	content, err = expandTemplate([]byte(`{"hostname": "{{ serial_number }}"}`), true)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, &config))
+	require.Equal(t, map[string]any{"hostname": "A\"B: c\n- d"}, config)
+}
+
+func TestParseFileContentsFromSMBIOSTemplate(t *testing.T) { //nolint:paralleltest
+	dmiPath = t.TempDir()
+	smbiosEntriesPath = t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dmiPath, "product_uuid"), []byte("4c4c4544-0042\n"), 0o600))
+
+	raw := []byte{11, 5, 0x2a, 0x00, 1}
+	raw = append(raw, []byte("io.incus-os.seed:applications={\"applications\": [{\"name\": \"app-{{ uuid }}\"}]}\x00\x00")...)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(smbiosEntriesPath, "11-0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(smbiosEntriesPath, "11-0", "raw"), raw, 0o600))
+
+	var apps apiseed.Applications
+
+	err := parseFileContentsFromSMBIOS("applications", &apps)
+	require.NoError(t, err)
+	require.Len(t, apps.Applications, 1)
+	require.Equal(t, "app-4c4c4544-0042", apps.Applications[0].Name)
+}
@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetTuning extracts the kernel tuning configuration from the seed data.
+func GetTuning(_ context.Context) (*apiseed.Tuning, error) {
+	// Get the tuning configuration.
+	var config apiseed.Tuning
+
+	err := parseFileContents(getSeedPath(), "tuning", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,20 @@
+package seed
+
+import (
+	"context"
+
+	apiseed "github.com/lxc/incus-os/incus-osd/api/seed"
+)
+
+// GetVRRP extracts the VRRP service configuration from the seed data.
+func GetVRRP(_ context.Context) (*apiseed.VRRP, error) {
+	// Get the VRRP configuration.
+	var config apiseed.VRRP
+
+	err := parseFileContents(getSeedPath(), "vrrp", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,3 @@
+// Package selfcheck runs the periodic self-checks covering the security posture, storage health,
+// connectivity and boot state of the system, recording their outcome to track health drift.
+package selfcheck
@@ -0,0 +1,180 @@
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// minFreeSpaceInGiB is the free space below which the system disk is considered unhealthy.
+const minFreeSpaceInGiB = 5.0
+
+// runMutex prevents concurrent self-check runs.
+var runMutex sync.Mutex
+
+// check is a single self-check, returning an error describing why it failed.
+type check struct {
+	name string
+	run  func(ctx context.Context, s *state.State) error
+}
+
+var checks = []check{
+	{name: "security", run: checkSecurity},
+	{name: "storage", run: checkStorage},
+	{name: "connectivity", run: checkConnectivity},
+	{name: "boot", run: checkBoot},
+}
+
+// Run performs all the self-checks and records the resulting report in the state.
+func Run(ctx context.Context, s *state.State) (api.SystemSelfCheckReport, error) {
+	runMutex.Lock()
+	defer runMutex.Unlock()
+
+	report := api.SystemSelfCheckReport{
+		Time:    time.Now().UTC(),
+		Passed:  true,
+		Results: []api.SystemSelfCheckResult{},
+	}
+
+	for _, c := range checks {
+		result := api.SystemSelfCheckResult{Name: c.name, Passed: true}
+
+		err := c.run(ctx, s)
+		if err != nil {
+			result.Passed = false
+			result.Message = err.Error()
+			report.Passed = false
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	return report, s.RecordSelfCheck(report)
+}
+
+// checkSecurity verifies that Secure Boot is enabled, that the TPM event log is consistent and
+// that the system disk integrity is verified.
+func checkSecurity(ctx context.Context, s *state.State) error {
+	enabled, err := secureboot.Enabled()
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return errors.New("secure boot is disabled")
+	}
+
+	if s.PassphraseOnly {
+		return errors.New("no TPM is available, the encrypted volumes are only protected by a passphrase")
+	}
+
+	tpmStatus := secureboot.TPMStatus()
+	if tpmStatus != "ok" {
+		return fmt.Errorf("TPM status is %q", tpmStatus)
+	}
+
+	volumes, err := systemd.ListVerityVolumes(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		if volume.State != "verified" {
+			return fmt.Errorf("dm-verity volume %q is %s", volume.Volume, volume.State)
+		}
+	}
+
+	return nil
+}
+
+// checkStorage verifies that all the storage pools are online, that no drive fails its SMART
+// self-assessment and that enough space is left on the system disk.
+func checkStorage(ctx context.Context, _ *state.State) error {
+	info, err := storage.GetStorageInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, pool := range info.State.Pools {
+		if pool.State != "ONLINE" {
+			return fmt.Errorf("storage pool %q is %s", pool.Name, pool.State)
+		}
+	}
+
+	for _, drive := range info.State.Drives {
+		if drive.SMART != nil && drive.SMART.Enabled && !drive.SMART.Passed {
+			return fmt.Errorf("drive %q failed its SMART self-assessment", drive.ID)
+		}
+	}
+
+	freeSpace, err := storage.GetFreeSpaceInGiB("/")
+	if err != nil {
+		return err
+	}
+
+	if freeSpace < minFreeSpaceInGiB {
+		return fmt.Errorf("only %.02fGiB free space available in /", freeSpace)
+	}
+
+	return nil
+}
+
+// checkConnectivity verifies that the network is online.
+func checkConnectivity(ctx context.Context, _ *state.State) error {
+	output, err := timeout.RunCommand(ctx, "networkctl", "status")
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(output, "Online state: online") {
+		return errors.New("network isn't online")
+	}
+
+	return nil
+}
+
+// checkBoot verifies that the system booted its expected release, that no systemd unit failed
+// and that all the applications started.
+func checkBoot(ctx context.Context, s *state.State) error {
+	if s.OS.NextRelease != "" && s.OS.RunningRelease != s.OS.NextRelease {
+		return fmt.Errorf("booted from backup %s image version %s", s.OS.Name, s.OS.RunningRelease)
+	}
+
+	output, err := timeout.RunCommand(ctx, "systemctl", "list-units", "--state=failed", "--plain", "--no-legend")
+	if err != nil {
+		return err
+	}
+
+	failedUnits := []string{}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			failedUnits = append(failedUnits, fields[0])
+		}
+	}
+
+	if len(failedUnits) > 0 {
+		return fmt.Errorf("failed units: %s", strings.Join(failedUnits, ", "))
+	}
+
+	for _, appName := range slices.Sorted(maps.Keys(s.Applications)) {
+		if !s.Applications[appName].State.Initialized {
+			return fmt.Errorf("application %q didn't start", appName)
+		}
+	}
+
+	return nil
+}
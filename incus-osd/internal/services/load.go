@@ -3,15 +3,17 @@ package services
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"slices"
 
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
 // Supported returns the list of all valid services for this system.
 // The list is sorted in recommended startup order to handle service dependencies.
 func Supported(s *state.State) []string {
-	services := []string{"ceph", "iscsi", "linstor", "nvme", "multipath", "lvm", "ovn", "tailscale", "usbip"}
+	services := []string{"console", "ntp", "tuning", "nftables", "ceph", "iscsi", "kdump", "linstor", "nvme", "multipath", "lvm", "ovn", "ssh", "tailscale", "usbip", "vrrp"}
 	supported := make([]string, 0, len(services))
 
 	for _, service := range services {
@@ -51,25 +53,52 @@ func loadByName(s *state.State, name string) (Service, error) {
 	switch name {
 	case "ceph":
 		srv = &Ceph{state: s}
+	case "console":
+		srv = &Console{state: s}
 	case "iscsi":
 		srv = &ISCSI{state: s}
+	case "kdump":
+		srv = &Kdump{state: s}
 	case "linstor":
 		srv = &Linstor{state: s}
 	case "lvm":
 		srv = &LVM{state: s}
 	case "multipath":
 		srv = &Multipath{state: s}
+	case "nftables":
+		srv = &Nftables{state: s}
+	case "ntp":
+		srv = &NTP{state: s}
 	case "nvme":
 		srv = &NVME{state: s}
 	case "ovn":
 		srv = &OVN{state: s}
+	case "ssh":
+		srv = &SSH{state: s}
 	case "tailscale":
 		srv = &Tailscale{state: s}
+	case "tuning":
+		srv = &Tuning{state: s}
 	case "usbip":
 		srv = &USBIP{state: s}
+	case "vrrp":
+		srv = &VRRP{state: s}
 	default:
 		return nil, errors.New("unknown service")
 	}
 
 	return srv, nil
 }
+
+// RefreshNetwork is a hook being called whenever a network configuration was applied. It reloads the
+// firewall policy if the management interfaces changed, then refreshes the provider registration.
+func RefreshNetwork(ctx context.Context, s *state.State) error {
+	nftables := &Nftables{state: s}
+
+	err := nftables.Refresh(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to reload the firewall policy", "err", err)
+	}
+
+	return providers.Refresh(ctx, s)
+}
@@ -2,16 +2,26 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/google/uuid"
+
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
+// cephNameRegexp matches the valid cluster and keyring names.
+var cephNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
 // Ceph represents the system Ceph service.
 type Ceph struct {
 	common
@@ -26,7 +36,11 @@ func (n *Ceph) Get(_ context.Context) (any, error) {
 		n.state.Services.Ceph.Config.Clusters = map[string]api.ServiceCephCluster{}
 	}
 
-	return n.state.Services.Ceph, nil
+	// Never return the keyring keys.
+	ret := n.state.Services.Ceph
+	ret.Config = secrets.RedactCephConfig(ret.Config)
+
+	return ret, nil
 }
 
 // Update updates the service configuration.
@@ -36,11 +50,21 @@ func (n *Ceph) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceCeph", req)
 	}
 
+	err := validateCephConfig(newState.Config)
+	if err != nil {
+		return err
+	}
+
+	err = secrets.SealCephConfig(&newState.Config, n.state.Services.Ceph.Config)
+	if err != nil {
+		return err
+	}
+
 	// Save the state on return.
 	defer n.state.Save()
 
 	// Disable the service.
-	err := n.Stop(ctx)
+	err = n.Stop(ctx)
 	if err != nil {
 		return err
 	}
@@ -106,14 +130,22 @@ mon_host = %s
 			return err
 		}
 
+		// Apply the messenger v2 connection mode.
+		if cluster.MessengerMode != "" {
+			_, err = fmt.Fprintf(rw, "ms_client_mode = %s\nms_mon_client_mode = %s\n", cluster.MessengerMode, cluster.MessengerMode)
+			if err != nil {
+				return err
+			}
+		}
+
 		if len(cluster.ClientConfig) > 0 {
 			_, err = fmt.Fprint(rw, "\n[client]\n")
 			if err != nil {
 				return err
 			}
 
-			for k, v := range cluster.ClientConfig {
-				_, err = fmt.Fprintf(rw, "%s = %s\n", k, v)
+			for _, k := range slices.Sorted(maps.Keys(cluster.ClientConfig)) {
+				_, err = fmt.Fprintf(rw, "%s = %s\n", k, cluster.ClientConfig[k])
 				if err != nil {
 					return err
 				}
@@ -124,6 +156,11 @@ mon_host = %s
 	}
 
 	writeKeyring := func(clusterName string, keyringName string, keyring api.ServiceCephKeyring) error {
+		key, err := secrets.Open(keyring.Key)
+		if err != nil {
+			return err
+		}
+
 		// Generate the keyring file.
 		rw, err := os.Create(filepath.Join("/etc/ceph", clusterName+".client."+keyringName+".keyring")) //nolint:gosec
 		if err != nil {
@@ -139,7 +176,7 @@ mon_host = %s
 
 		_, err = fmt.Fprintf(rw, `[client.%s]
 key = %s
-`, keyringName, keyring.Key)
+`, keyringName, key)
 		if err != nil {
 			return err
 		}
@@ -165,6 +202,11 @@ key = %s
 	return nil
 }
 
+// Status returns the runtime status of the service.
+func (n *Ceph) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *Ceph) ShouldStart() bool {
 	return n.state.Services.Ceph.Config.Enabled
@@ -182,3 +224,54 @@ func (n *Ceph) Supported() bool {
 
 	return ok
 }
+
+// validateCephConfig checks the cluster definitions, as their names and content end up in files under /etc/ceph.
+func validateCephConfig(config api.ServiceCephConfig) error {
+	for clusterName, cluster := range config.Clusters {
+		if !cephNameRegexp.MatchString(clusterName) {
+			return fmt.Errorf("invalid Ceph cluster name %q", clusterName)
+		}
+
+		err := uuid.Validate(cluster.FSID)
+		if err != nil {
+			return fmt.Errorf("invalid FSID for Ceph cluster %q: %w", clusterName, err)
+		}
+
+		if len(cluster.Monitors) == 0 {
+			return fmt.Errorf("no monitors provided for Ceph cluster %q", clusterName)
+		}
+
+		for _, monitor := range cluster.Monitors {
+			if monitor == "" || strings.ContainsAny(monitor, ", \n") {
+				return fmt.Errorf("invalid monitor %q for Ceph cluster %q", monitor, clusterName)
+			}
+		}
+
+		if cluster.MessengerMode != "" && cluster.MessengerMode != "crc" && cluster.MessengerMode != "secure" {
+			return fmt.Errorf("invalid messenger mode %q for Ceph cluster %q", cluster.MessengerMode, clusterName)
+		}
+
+		for keyringName, keyring := range cluster.Keyrings {
+			if !cephNameRegexp.MatchString(keyringName) {
+				return fmt.Errorf("invalid keyring name %q for Ceph cluster %q", keyringName, clusterName)
+			}
+
+			if keyring.Key == secrets.Redacted || secrets.IsSealed(keyring.Key) {
+				continue
+			}
+
+			_, err := base64.StdEncoding.DecodeString(keyring.Key)
+			if err != nil {
+				return fmt.Errorf("invalid key for keyring %q of Ceph cluster %q", keyringName, clusterName)
+			}
+		}
+
+		for k, v := range cluster.ClientConfig {
+			if strings.ContainsAny(k+v, "\n=") {
+				return fmt.Errorf("invalid client configuration key %q for Ceph cluster %q", k, clusterName)
+			}
+		}
+	}
+
+	return nil
+}
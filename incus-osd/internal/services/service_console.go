@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// consoleDeviceRegexp matches the serial devices which may be configured.
+var consoleDeviceRegexp = regexp.MustCompile(`^tty(S|AMA|USB|ACM)[0-9]+$`)
+
+// consoleBaudRates is the list of supported serial baud rates.
+var consoleBaudRates = []int{9600, 19200, 38400, 57600, 115200}
+
+// Console represents the system serial console service.
+type Console struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Console) Get(_ context.Context) (any, error) {
+	consoles, err := getKernelConsoles()
+	if err != nil {
+		return nil, err
+	}
+
+	n.state.Services.Console.State.KernelConsoles = consoles
+
+	return n.state.Services.Console, nil
+}
+
+// Update updates the service configuration.
+func (n *Console) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceConsole)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceConsole", req)
+	}
+
+	if newState.Config.Enabled {
+		if !consoleDeviceRegexp.MatchString(newState.Config.Device) {
+			return fmt.Errorf("invalid serial device %q", newState.Config.Device)
+		}
+
+		_, err := os.Stat("/dev/" + newState.Config.Device)
+		if err != nil {
+			return fmt.Errorf("serial device %q doesn't exist", newState.Config.Device)
+		}
+
+		if newState.Config.BaudRate != 0 && !slices.Contains(consoleBaudRates, newState.Config.BaudRate) {
+			return fmt.Errorf("unsupported baud rate %d", newState.Config.BaudRate)
+		}
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Update the configuration.
+	n.state.Services.Console.Config = newState.Config
+
+	// Enable the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the service.
+func (*Console) Stop(_ context.Context) error {
+	return nil
+}
+
+// Start starts the service.
+func (n *Console) Start(ctx context.Context) error {
+	if !n.state.Services.Console.Config.Enabled {
+		return nil
+	}
+
+	if n.state.Services.Console.Config.Device == "" {
+		return errors.New("no serial device configured")
+	}
+
+	baudRate := n.state.Services.Console.Config.BaudRate
+	if baudRate == 0 {
+		baudRate = 115200
+	}
+
+	// Configure the line settings of the serial device.
+	_, err := subprocess.RunCommandContext(ctx, "stty", "-F", "/dev/"+n.state.Services.Console.Config.Device, strconv.Itoa(baudRate), "cs8", "-parenb", "-cstopb")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Status returns the runtime status of the service.
+func (n *Console) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Console) ShouldStart() bool {
+	return n.state.Services.Console.Config.Enabled
+}
+
+// Struct returns the API struct for the serial console service.
+func (*Console) Struct() any {
+	return &api.ServiceConsole{}
+}
+
+// getKernelConsoles returns the list of devices currently used by the kernel as consoles.
+func getKernelConsoles() ([]string, error) {
+	content, err := os.ReadFile("/proc/consoles")
+	if err != nil {
+		return nil, err
+	}
+
+	consoles := []string{}
+
+	for line := range strings.SplitSeq(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		consoles = append(consoles, fields[0])
+	}
+
+	return consoles, nil
+}
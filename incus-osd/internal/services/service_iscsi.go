@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
@@ -23,12 +25,14 @@ type ISCSI struct {
 }
 
 // Get returns the current service state.
-func (n *ISCSI) Get(_ context.Context) (any, error) {
+func (n *ISCSI) Get(ctx context.Context) (any, error) {
 	// Initialize target list if missing.
 	if n.state.Services.ISCSI.Config.Targets == nil {
 		n.state.Services.ISCSI.Config.Targets = []api.ServiceISCSITarget{}
 	}
 
+	n.state.Services.ISCSI.State.Sessions = []api.ServiceISCSISession{}
+
 	// Get runtime details if enabled.
 	if n.state.Services.ISCSI.Config.Enabled {
 		// Retrieve host ID.
@@ -38,9 +42,19 @@ func (n *ISCSI) Get(_ context.Context) (any, error) {
 		}
 
 		n.state.Services.ISCSI.State.InitiatorName = strings.TrimPrefix(strings.TrimSpace(string(initiatorName)), "InitiatorName=")
+
+		// Retrieve the active sessions.
+		n.state.Services.ISCSI.State.Sessions, err = getISCSISessions(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return n.state.Services.ISCSI, nil
+	// Never return the CHAP credentials.
+	ret := n.state.Services.ISCSI
+	ret.Config = secrets.RedactISCSIConfig(ret.Config)
+
+	return ret, nil
 }
 
 // Update updates the service configuration.
@@ -50,15 +64,45 @@ func (n *ISCSI) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceISCSI", req)
 	}
 
-	// Save the state on return.
-	defer n.state.Save()
+	err := validateISCSIConfig(newState.Config)
+	if err != nil {
+		return err
+	}
 
-	// Disable the service.
-	err := n.Stop(ctx)
+	err = secrets.SealISCSIConfig(&newState.Config, n.state.Services.ISCSI.Config)
 	if err != nil {
 		return err
 	}
 
+	// Save the state on return.
+	defer n.state.Save()
+
+	if !newState.Config.Enabled {
+		// Disable the service.
+		err := n.Stop(ctx)
+		if err != nil {
+			return err
+		}
+	} else if n.state.Services.ISCSI.Config.Enabled {
+		// Only logout from the targets which were removed, changed or should now be logged out.
+		newTargets := map[string]api.ServiceISCSITarget{}
+		for _, target := range newState.Config.Targets {
+			newTargets[target.Target] = target
+		}
+
+		for _, target := range n.state.Services.ISCSI.Config.Targets {
+			newTarget, ok := newTargets[target.Target]
+			if ok && !newTarget.Logout && reflect.DeepEqual(target, newTarget) {
+				continue
+			}
+
+			err := logoutISCSITarget(ctx, target)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	// Update the configuration.
 	n.state.Services.ISCSI.Config = newState.Config
 
@@ -79,18 +123,7 @@ func (n *ISCSI) Stop(ctx context.Context) error {
 
 	// Disconnect from the targets.
 	for _, target := range n.state.Services.ISCSI.Config.Targets {
-		// Determine portal address.
-		portal := target.Address
-		if strings.Contains(portal, ":") {
-			portal = "[" + portal + "]"
-		}
-
-		if target.Port > 0 {
-			portal = fmt.Sprintf("%s:%d", portal, target.Port)
-		}
-
-		// Logout from the target.
-		_, err := subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "node", "-T", target.Target, "-p", portal, "--logout")
+		err := logoutISCSITarget(ctx, target)
 		if err != nil {
 			return err
 		}
@@ -166,32 +199,156 @@ func (n *ISCSI) Start(ctx context.Context) error {
 		return err
 	}
 
+	// Get the existing sessions.
+	sessions, err := getISCSISessions(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Connect to the targets.
 	for _, target := range n.state.Services.ISCSI.Config.Targets {
-		// Determine portal address.
-		portal := target.Address
-		if strings.Contains(portal, ":") {
-			portal = "[" + portal + "]"
+		if target.Logout {
+			continue
 		}
 
-		if target.Port > 0 {
-			portal = fmt.Sprintf("%s:%d", portal, target.Port)
+		err := loginISCSITarget(ctx, target, sessions)
+		if err != nil {
+			return fmt.Errorf("failed to login to %q: %w", target.Target, err)
 		}
+	}
 
-		// Discover the targets.
-		for range 10 {
-			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "discovery", "-t", "sendtargets", "-p", portal)
-			if err == nil {
-				break
+	return nil
+}
+
+// Status returns the runtime status of the service.
+func (n *ISCSI) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "iscsid.service")
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *ISCSI) ShouldStart() bool {
+	return n.state.Services.ISCSI.Config.Enabled
+}
+
+// Struct returns the API struct for the ISCSI service.
+func (*ISCSI) Struct() any {
+	return &api.ServiceISCSI{}
+}
+
+// validateISCSIConfig checks the targets and their credentials.
+func validateISCSIConfig(config api.ServiceISCSIConfig) error {
+	targets := map[string]bool{}
+
+	for _, target := range config.Targets {
+		if target.Target == "" || target.Address == "" {
+			return errors.New("iSCSI targets require a name and an address")
+		}
+
+		if targets[target.Target] {
+			return fmt.Errorf("duplicate iSCSI target %q", target.Target)
+		}
+
+		targets[target.Target] = true
+
+		for _, portal := range target.Portals {
+			if portal.Address == "" {
+				return fmt.Errorf("missing portal address for target %q", target.Target)
 			}
+		}
+
+		if target.CHAP == nil {
+			continue
+		}
+
+		if target.CHAP.Username == "" || target.CHAP.Password == "" {
+			return fmt.Errorf("CHAP requires both a username and a password for target %q", target.Target)
+		}
+
+		if (target.CHAP.MutualUsername == "") != (target.CHAP.MutualPassword == "") {
+			return fmt.Errorf("mutual CHAP requires both a username and a password for target %q", target.Target)
+		}
+	}
+
+	return nil
+}
+
+// getISCSIPortals returns the portal address of each portal of the target, including the default port.
+func getISCSIPortals(target api.ServiceISCSITarget) []string {
+	portals := append([]api.ServiceISCSIPortal{{Address: target.Address, Port: target.Port}}, target.Portals...)
+	ret := make([]string, 0, len(portals))
 
-			time.Sleep(500 * time.Millisecond)
+	for _, portal := range portals {
+		address := portal.Address
+		if strings.Contains(address, ":") {
+			address = "[" + address + "]"
 		}
 
+		port := portal.Port
+		if port <= 0 {
+			port = 3260
+		}
+
+		ret = append(ret, fmt.Sprintf("%s:%d", address, port))
+	}
+
+	return ret
+}
+
+// hasISCSISession returns true if a session exists for the target through the portal.
+func hasISCSISession(sessions []api.ServiceISCSISession, target string, portal string) bool {
+	for _, session := range sessions {
+		if session.Target == target && session.Portal == portal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loginISCSITarget discovers the target through each of its portals and logs into it.
+func loginISCSITarget(ctx context.Context, target api.ServiceISCSITarget, sessions []api.ServiceISCSISession) error {
+	var chap *api.ServiceISCSICHAP
+
+	if target.CHAP != nil {
+		opened, err := secrets.OpenISCSICHAP(*target.CHAP)
+		if err != nil {
+			return err
+		}
+
+		chap = &opened
+	}
+
+	for _, portal := range getISCSIPortals(target) {
+		if hasISCSISession(sessions, target.Target, portal) {
+			continue
+		}
+
+		// Discover the targets.
+		err := discoverISCSIPortal(ctx, portal, chap)
 		if err != nil {
 			return err
 		}
 
+		// Configure the credentials.
+		if chap != nil {
+			settings := [][]string{
+				{"node.session.auth.authmethod", "CHAP"},
+				{"node.session.auth.username", chap.Username},
+				{"node.session.auth.password", chap.Password},
+			}
+
+			if chap.MutualUsername != "" {
+				settings = append(settings, []string{"node.session.auth.username_in", chap.MutualUsername}, []string{"node.session.auth.password_in", chap.MutualPassword})
+			}
+
+			for _, setting := range settings {
+				_, err := subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "node", "-T", target.Target, "-p", portal, "-o", "update", "-n", setting[0], "-v", setting[1])
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 		// Login to the target.
 		_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "node", "-T", target.Target, "-p", portal, "--login")
 		if err != nil {
@@ -202,12 +359,121 @@ func (n *ISCSI) Start(ctx context.Context) error {
 	return nil
 }
 
-// ShouldStart returns true if the service should be started on boot.
-func (n *ISCSI) ShouldStart() bool {
-	return n.state.Services.ISCSI.Config.Enabled
+// discoverISCSIPortal runs a SendTargets discovery against the portal, retrying while the network comes up.
+func discoverISCSIPortal(ctx context.Context, portal string, chap *api.ServiceISCSICHAP) error {
+	var err error
+
+	if chap != nil {
+		// Record the discovery credentials.
+		_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "discoverydb", "-t", "sendtargets", "-p", portal, "-o", "new")
+		if err != nil {
+			return err
+		}
+
+		settings := [][]string{
+			{"discovery.sendtargets.auth.authmethod", "CHAP"},
+			{"discovery.sendtargets.auth.username", chap.Username},
+			{"discovery.sendtargets.auth.password", chap.Password},
+		}
+
+		if chap.MutualUsername != "" {
+			settings = append(settings, []string{"discovery.sendtargets.auth.username_in", chap.MutualUsername}, []string{"discovery.sendtargets.auth.password_in", chap.MutualPassword})
+		}
+
+		for _, setting := range settings {
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "discoverydb", "-t", "sendtargets", "-p", portal, "-o", "update", "-n", setting[0], "-v", setting[1])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for range 10 {
+		if chap != nil {
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "discoverydb", "-t", "sendtargets", "-p", portal, "--discover")
+		} else {
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "discovery", "-t", "sendtargets", "-p", portal)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return err
 }
 
-// Struct returns the API struct for the ISCSI service.
-func (*ISCSI) Struct() any {
-	return &api.ServiceISCSI{}
+// logoutISCSITarget logs out of all the active sessions of the target.
+func logoutISCSITarget(ctx context.Context, target api.ServiceISCSITarget) error {
+	sessions, err := getISCSISessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Target != target.Target {
+			continue
+		}
+
+		_, err := subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "node", "-T", target.Target, "-p", session.Portal, "--logout")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getISCSISessions returns the list of active ISCSI sessions.
+func getISCSISessions(ctx context.Context) ([]api.ServiceISCSISession, error) {
+	if !systemd.IsActive(ctx, "iscsid") {
+		return []api.ServiceISCSISession{}, nil
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "session", "-P", "1")
+	if err != nil {
+		if strings.Contains(err.Error(), "No active sessions") {
+			return []api.ServiceISCSISession{}, nil
+		}
+
+		return nil, err
+	}
+
+	return parseISCSISessions(output), nil
+}
+
+// parseISCSISessions parses the output of "iscsiadm -m session -P 1".
+func parseISCSISessions(output string) []api.ServiceISCSISession {
+	sessions := []api.ServiceISCSISession{}
+	target := ""
+
+	for line := range strings.SplitSeq(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		value, ok := strings.CutPrefix(line, "Target: ")
+		if ok {
+			target, _, _ = strings.Cut(value, " ")
+
+			continue
+		}
+
+		value, ok = strings.CutPrefix(line, "Current Portal: ")
+		if ok {
+			// Strip the target portal group tag.
+			portal, _, _ := strings.Cut(value, ",")
+
+			sessions = append(sessions, api.ServiceISCSISession{Target: target, Portal: portal})
+
+			continue
+		}
+
+		value, ok = strings.CutPrefix(line, "iSCSI Session State: ")
+		if ok && len(sessions) > 0 {
+			sessions[len(sessions)-1].State = strings.ToLower(value)
+		}
+	}
+
+	return sessions
 }
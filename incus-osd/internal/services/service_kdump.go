@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/kdump"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// Kdump represents the system kdump service.
+type Kdump struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Kdump) Get(_ context.Context) (any, error) {
+	reserved, err := kdump.ReservedMemory()
+	if err != nil {
+		return nil, err
+	}
+
+	n.state.Services.Kdump.State = api.ServiceKdumpState{
+		Loaded:         kdump.IsLoaded(),
+		ReservedMemory: reserved,
+	}
+
+	return n.state.Services.Kdump, nil
+}
+
+// Update updates the service configuration.
+func (n *Kdump) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceKdump)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceKdump", req)
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Disable the service if requested.
+	if n.state.Services.Kdump.Config.Enabled && !newState.Config.Enabled {
+		err := n.Stop(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the configuration.
+	n.state.Services.Kdump.Config = newState.Config
+
+	// Enable the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the service.
+func (n *Kdump) Stop(ctx context.Context) error {
+	if !n.state.Services.Kdump.Config.Enabled {
+		return nil
+	}
+
+	return kdump.Unload(ctx)
+}
+
+// Start starts the service.
+func (n *Kdump) Start(ctx context.Context) error {
+	if !n.state.Services.Kdump.Config.Enabled {
+		return nil
+	}
+
+	return kdump.Load(ctx, n.state)
+}
+
+// Status returns the runtime status of the service.
+func (n *Kdump) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Kdump) ShouldStart() bool {
+	return n.state.Services.Kdump.Config.Enabled
+}
+
+// Struct returns the API struct for the kdump service.
+func (*Kdump) Struct() any {
+	return &api.ServiceKdump{}
+}
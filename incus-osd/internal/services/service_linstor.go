@@ -204,6 +204,11 @@ func (n *Linstor) Start(ctx context.Context) error {
 	return nil
 }
 
+// Status returns the runtime status of the service.
+func (n *Linstor) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "linstor-satellite.service")
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *Linstor) ShouldStart() bool {
 	return n.state.Services.Linstor.Config.Enabled
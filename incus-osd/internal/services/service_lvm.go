@@ -184,6 +184,11 @@ func (n *LVM) Reset(ctx context.Context) error {
 	return n.Start(ctx)
 }
 
+// Status returns the runtime status of the service.
+func (n *LVM) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "lvmlockd.service", "sanlock.service", "wdmd.service")
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *LVM) ShouldStart() bool {
 	return n.state.Services.LVM.Config.Enabled
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +34,22 @@ func (n *Multipath) Get(ctx context.Context) (any, error) {
 		n.state.Services.Multipath.Config.WWNs = []string{}
 	}
 
+	if n.state.Services.Multipath.Config.Vendors == nil {
+		n.state.Services.Multipath.Config.Vendors = []api.ServiceMultipathVendor{}
+	}
+
+	if n.state.Services.Multipath.Config.Settings == nil {
+		n.state.Services.Multipath.Config.Settings = map[string]api.ServiceMultipathSettings{}
+	}
+
+	if n.state.Services.Multipath.Config.Blacklist.WWNs == nil {
+		n.state.Services.Multipath.Config.Blacklist.WWNs = []string{}
+	}
+
+	if n.state.Services.Multipath.Config.Blacklist.Devices == nil {
+		n.state.Services.Multipath.Config.Blacklist.Devices = []api.ServiceMultipathBlacklistDevice{}
+	}
+
 	// Get runtime details if enabled.
 	if !n.state.Services.Multipath.Config.Enabled {
 		return n.state.Services.Multipath, nil
@@ -134,6 +152,13 @@ func (n *Multipath) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceMultipath", req)
 	}
 
+	if newState.Config.Enabled {
+		err := validateMultipathConfig(newState.Config)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Save the state on return.
 	defer n.state.Save()
 
@@ -223,12 +248,29 @@ func (n *Multipath) Start(ctx context.Context) error {
 		return err
 	}
 
+	// Generate the vendor, WWN and blacklist configuration.
+	err = os.MkdirAll("/etc/multipath/conf.d", 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile("/etc/multipath/conf.d/incus-os.conf", []byte(generateMultipathConfig(n.state.Services.Multipath.Config)), 0o600)
+	if err != nil {
+		return err
+	}
+
 	// Ensure the service is running.
 	err = systemd.StartUnit(ctx, "multipathd.service")
 	if err != nil {
 		return err
 	}
 
+	// Have the daemon pick up the configuration changes.
+	_, err = subprocess.RunCommandContext(ctx, "multipathd", "reconfigure")
+	if err != nil {
+		return err
+	}
+
 	// Reload the multipath configuration.
 	_, err = subprocess.RunCommandContext(ctx, "multipath", "-r")
 	if err != nil {
@@ -238,6 +280,11 @@ func (n *Multipath) Start(ctx context.Context) error {
 	return nil
 }
 
+// Status returns the runtime status of the service.
+func (n *Multipath) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "multipathd.service")
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *Multipath) ShouldStart() bool {
 	return n.state.Services.Multipath.Config.Enabled
@@ -247,3 +294,169 @@ func (n *Multipath) ShouldStart() bool {
 func (*Multipath) Struct() any {
 	return &api.ServiceMultipath{}
 }
+
+// multipathPathSelectors lists the supported path selectors.
+var multipathPathSelectors = []string{"round-robin 0", "queue-length 0", "service-time 0", "historical-service-time 0"}
+
+// multipathPathGroupingPolicies lists the supported path grouping policies.
+var multipathPathGroupingPolicies = []string{"failover", "multibus", "group_by_serial", "group_by_prio", "group_by_node_name"}
+
+// validateMultipathSettings checks a set of multipath settings.
+func validateMultipathSettings(settings api.ServiceMultipathSettings) error {
+	if settings.PathSelector != "" && !slices.Contains(multipathPathSelectors, settings.PathSelector) {
+		return fmt.Errorf("invalid path selector %q", settings.PathSelector)
+	}
+
+	if settings.PathGroupingPolicy != "" && !slices.Contains(multipathPathGroupingPolicies, settings.PathGroupingPolicy) {
+		return fmt.Errorf("invalid path grouping policy %q", settings.PathGroupingPolicy)
+	}
+
+	if settings.NoPathRetry != "" && settings.NoPathRetry != "queue" && settings.NoPathRetry != "fail" {
+		retries, err := strconv.Atoi(settings.NoPathRetry)
+		if err != nil || retries <= 0 {
+			return fmt.Errorf("invalid no_path_retry value %q", settings.NoPathRetry)
+		}
+	}
+
+	return nil
+}
+
+// validateMultipathConfig checks the multipath settings and device matches.
+func validateMultipathConfig(config api.ServiceMultipathConfig) error {
+	err := validateMultipathSettings(config.Defaults)
+	if err != nil {
+		return err
+	}
+
+	for _, vendor := range config.Vendors {
+		if vendor.Vendor == "" {
+			return errors.New("multipath vendor settings require a vendor")
+		}
+
+		if strings.ContainsAny(vendor.Vendor+vendor.Product, "\"\n") {
+			return fmt.Errorf("invalid multipath vendor %q", vendor.Vendor)
+		}
+
+		err := validateMultipathSettings(vendor.ServiceMultipathSettings)
+		if err != nil {
+			return fmt.Errorf("vendor %q: %w", vendor.Vendor, err)
+		}
+	}
+
+	for wwn, settings := range config.Settings {
+		if !slices.Contains(config.WWNs, wwn) {
+			return fmt.Errorf("settings provided for unknown WWN %q", wwn)
+		}
+
+		err := validateMultipathSettings(settings)
+		if err != nil {
+			return fmt.Errorf("WWN %q: %w", wwn, err)
+		}
+	}
+
+	for _, wwn := range config.Blacklist.WWNs {
+		if slices.Contains(config.WWNs, wwn) {
+			return fmt.Errorf("WWN %q can't be both configured and blacklisted", wwn)
+		}
+	}
+
+	for _, device := range config.Blacklist.Devices {
+		if device.Vendor == "" {
+			return errors.New("blacklisted devices require a vendor")
+		}
+
+		if strings.ContainsAny(device.Vendor+device.Product, "\"\n") {
+			return fmt.Errorf("invalid blacklisted vendor %q", device.Vendor)
+		}
+	}
+
+	for _, wwn := range append(slices.Clone(config.WWNs), config.Blacklist.WWNs...) {
+		if strings.ContainsAny(wwn, "\"\n ") {
+			return fmt.Errorf("invalid WWN %q", wwn)
+		}
+	}
+
+	return nil
+}
+
+// writeMultipathSettings writes the non-default settings into a multipath.conf section.
+func writeMultipathSettings(sb *strings.Builder, indent string, settings api.ServiceMultipathSettings) {
+	if settings.PathSelector != "" {
+		fmt.Fprintf(sb, "%spath_selector \"%s\"\n", indent, settings.PathSelector)
+	}
+
+	if settings.PathGroupingPolicy != "" {
+		fmt.Fprintf(sb, "%spath_grouping_policy %s\n", indent, settings.PathGroupingPolicy)
+	}
+
+	if settings.NoPathRetry != "" {
+		fmt.Fprintf(sb, "%sno_path_retry %s\n", indent, settings.NoPathRetry)
+	}
+}
+
+// generateMultipathConfig returns the multipath.conf snippet holding the defaults, per-vendor and per-WWN settings as well as the blacklist.
+func generateMultipathConfig(config api.ServiceMultipathConfig) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Generated by IncusOS\n")
+
+	sb.WriteString("defaults {\n")
+	writeMultipathSettings(&sb, "\t", config.Defaults)
+	sb.WriteString("}\n")
+
+	if len(config.Blacklist.WWNs) > 0 || len(config.Blacklist.Devices) > 0 {
+		sb.WriteString("blacklist {\n")
+
+		for _, wwn := range config.Blacklist.WWNs {
+			fmt.Fprintf(&sb, "\twwid \"%s\"\n", strings.TrimPrefix(wwn, "0x"))
+		}
+
+		for _, device := range config.Blacklist.Devices {
+			sb.WriteString("\tdevice {\n")
+			fmt.Fprintf(&sb, "\t\tvendor \"%s\"\n", device.Vendor)
+
+			if device.Product != "" {
+				fmt.Fprintf(&sb, "\t\tproduct \"%s\"\n", device.Product)
+			}
+
+			sb.WriteString("\t}\n")
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	if len(config.Vendors) > 0 {
+		sb.WriteString("devices {\n")
+
+		for _, vendor := range config.Vendors {
+			sb.WriteString("\tdevice {\n")
+			fmt.Fprintf(&sb, "\t\tvendor \"%s\"\n", vendor.Vendor)
+
+			product := vendor.Product
+			if product == "" {
+				product = ".*"
+			}
+
+			fmt.Fprintf(&sb, "\t\tproduct \"%s\"\n", product)
+			writeMultipathSettings(&sb, "\t\t", vendor.ServiceMultipathSettings)
+			sb.WriteString("\t}\n")
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	if len(config.Settings) > 0 {
+		sb.WriteString("multipaths {\n")
+
+		for _, wwn := range slices.Sorted(maps.Keys(config.Settings)) {
+			sb.WriteString("\tmultipath {\n")
+			fmt.Fprintf(&sb, "\t\twwid \"%s\"\n", strings.TrimPrefix(wwn, "0x"))
+			writeMultipathSettings(&sb, "\t\t", config.Settings[wwn])
+			sb.WriteString("\t}\n")
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
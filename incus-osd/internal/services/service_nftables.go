@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// nftablesTable is the nftables table holding the IncusOS firewall policy. It's kept separate from the
+// tables managed by Incus for its instances.
+const nftablesTable = "incus-os"
+
+// nftablesRulesetPath is where the generated ruleset is written before being loaded.
+const nftablesRulesetPath = "/run/incus-os/nftables.nft"
+
+// Nftables represents the system nftables firewall service.
+type Nftables struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Nftables) Get(ctx context.Context) (any, error) {
+	// Initialize the lists if missing.
+	if n.state.Services.Nftables.Config.ManagementNetworks == nil {
+		n.state.Services.Nftables.Config.ManagementNetworks = []string{}
+	}
+
+	if n.state.Services.Nftables.Config.TCPPorts == nil {
+		n.state.Services.Nftables.Config.TCPPorts = []int{}
+	}
+
+	if n.state.Services.Nftables.Config.UDPPorts == nil {
+		n.state.Services.Nftables.Config.UDPPorts = []int{}
+	}
+
+	// Check whether the policy is loaded.
+	_, err := subprocess.RunCommandContext(ctx, "nft", "list", "table", "inet", nftablesTable)
+
+	n.state.Services.Nftables.State.Active = err == nil
+	if !n.state.Services.Nftables.State.Active {
+		n.state.Services.Nftables.State.Interfaces = []string{}
+	}
+
+	return n.state.Services.Nftables, nil
+}
+
+// Update updates the service configuration.
+func (n *Nftables) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceNftables)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceNftables", req)
+	}
+
+	err := validateNftablesConfig(newState.Config)
+	if err != nil {
+		return err
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Disable the service if requested.
+	if n.state.Services.Nftables.Config.Enabled && !newState.Config.Enabled {
+		err := n.Stop(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the configuration.
+	n.state.Services.Nftables.Config = newState.Config
+
+	// Enable or reconfigure the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the service, removing the firewall policy.
+func (n *Nftables) Stop(ctx context.Context) error {
+	if !n.state.Services.Nftables.Config.Enabled {
+		return nil
+	}
+
+	_, err := subprocess.RunCommandContext(ctx, "nft", "delete", "table", "inet", nftablesTable)
+	if err != nil && !strings.Contains(err.Error(), "No such file or directory") {
+		return err
+	}
+
+	n.state.Services.Nftables.State = api.ServiceNftablesState{Interfaces: []string{}}
+
+	return nil
+}
+
+// Start starts the service, (re)loading the firewall policy.
+func (n *Nftables) Start(ctx context.Context) error {
+	if !n.state.Services.Nftables.Config.Enabled {
+		return nil
+	}
+
+	interfaces := n.managementInterfaces()
+	if len(interfaces) == 0 {
+		return errors.New("no management interface found")
+	}
+
+	err := os.MkdirAll("/run/incus-os", 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(nftablesRulesetPath, []byte(generateNftablesRuleset(n.state.Services.Nftables.Config, interfaces)), 0o600)
+	if err != nil {
+		return err
+	}
+
+	// Load the ruleset, atomically replacing any previous version of the table.
+	_, err = subprocess.RunCommandContext(ctx, "nft", "-f", nftablesRulesetPath)
+	if err != nil {
+		return err
+	}
+
+	n.state.Services.Nftables.State = api.ServiceNftablesState{Active: true, Interfaces: interfaces}
+
+	return nil
+}
+
+// Refresh reloads the firewall policy if the management interfaces changed since it was loaded, so a
+// new management interface isn't left unfiltered.
+func (n *Nftables) Refresh(ctx context.Context) error {
+	if !n.state.Services.Nftables.Config.Enabled {
+		return nil
+	}
+
+	if slices.Equal(n.managementInterfaces(), n.state.Services.Nftables.State.Interfaces) {
+		return nil
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	return n.Start(ctx)
+}
+
+// Status returns the runtime status of the service.
+func (n *Nftables) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Nftables) ShouldStart() bool {
+	return n.state.Services.Nftables.Config.Enabled
+}
+
+// Struct returns the API struct for the nftables service.
+func (*Nftables) Struct() any {
+	return &api.ServiceNftables{}
+}
+
+// managementInterfaces returns the names of the network devices carrying the management role. As the addresses
+// of bridged interfaces may be held by their internal port, both names are included.
+func (n *Nftables) managementInterfaces() []string {
+	interfaces := []string{}
+
+	for _, name := range slices.Sorted(maps.Keys(n.state.System.Network.State.Interfaces)) {
+		if slices.Contains(n.state.System.Network.State.Interfaces[name].Roles, api.SystemNetworkInterfaceRoleManagement) {
+			interfaces = append(interfaces, name, "_v"+name)
+		}
+	}
+
+	return interfaces
+}
+
+// validateNftablesConfig checks that the firewall configuration is usable.
+func validateNftablesConfig(config api.ServiceNftablesConfig) error {
+	if !slices.Contains([]string{"", "drop", "accept"}, config.DefaultPolicy) {
+		return fmt.Errorf("invalid default policy %q", config.DefaultPolicy)
+	}
+
+	for _, network := range config.ManagementNetworks {
+		_, _, err := net.ParseCIDR(network)
+		if err != nil {
+			return fmt.Errorf("invalid management network %q: %w", network, err)
+		}
+	}
+
+	for _, port := range slices.Concat(config.TCPPorts, config.UDPPorts) {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %d", port)
+		}
+	}
+
+	return nil
+}
+
+// generateNftablesRuleset generates the firewall policy applied to the traffic reaching the system through its
+// management interfaces. Traffic from other interfaces, including the ones used by Incus instances, isn't filtered.
+func generateNftablesRuleset(config api.ServiceNftablesConfig, interfaces []string) string {
+	policy := config.DefaultPolicy
+	if policy == "" {
+		policy = "drop"
+	}
+
+	quoted := make([]string, 0, len(interfaces))
+	for _, iface := range interfaces {
+		quoted = append(quoted, strconv.Quote(iface))
+	}
+
+	var sb strings.Builder
+
+	// Declaring then deleting the table makes loading the ruleset work whether it already exists or not.
+	fmt.Fprintf(&sb, "table inet %s {}\ndelete table inet %s\n\n", nftablesTable, nftablesTable)
+	fmt.Fprintf(&sb, "table inet %s {\n", nftablesTable)
+	sb.WriteString("\tchain input {\n")
+	fmt.Fprintf(&sb, "\t\ttype filter hook input priority filter; policy %s;\n\n", policy)
+	fmt.Fprintf(&sb, "\t\tiifname != { %s } accept\n", strings.Join(quoted, ", "))
+	sb.WriteString("\t\tct state established,related accept\n")
+	sb.WriteString("\t\tct state invalid drop\n")
+	sb.WriteString("\t\tmeta l4proto { icmp, ipv6-icmp, vrrp } accept\n")
+	sb.WriteString("\t\tudp dport { 68, 546 } accept\n")
+
+	ipv4 := []string{}
+	ipv6 := []string{}
+
+	for _, network := range config.ManagementNetworks {
+		ip, _, _ := net.ParseCIDR(network)
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, network)
+		} else {
+			ipv6 = append(ipv6, network)
+		}
+	}
+
+	for _, proto := range []string{"tcp", "udp"} {
+		ports := config.TCPPorts
+		if proto == "udp" {
+			ports = config.UDPPorts
+		}
+
+		if len(ports) == 0 {
+			continue
+		}
+
+		portList := make([]string, 0, len(ports))
+		for _, port := range ports {
+			portList = append(portList, strconv.Itoa(port))
+		}
+
+		match := fmt.Sprintf("%s dport { %s } accept", proto, strings.Join(portList, ", "))
+
+		if len(config.ManagementNetworks) == 0 {
+			fmt.Fprintf(&sb, "\t\t%s\n", match)
+
+			continue
+		}
+
+		if len(ipv4) > 0 {
+			fmt.Fprintf(&sb, "\t\tip saddr { %s } %s\n", strings.Join(ipv4, ", "), match)
+		}
+
+		if len(ipv6) > 0 {
+			fmt.Fprintf(&sb, "\t\tip6 saddr { %s } %s\n", strings.Join(ipv6, ", "), match)
+		}
+	}
+
+	sb.WriteString("\t}\n}\n")
+
+	return sb.String()
+}
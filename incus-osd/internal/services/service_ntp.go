@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// NTP represents the system NTP time synchronization service.
+type NTP struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *NTP) Get(ctx context.Context) (any, error) {
+	// Initialize the server lists if missing.
+	if n.state.Services.NTP.Config.Servers == nil {
+		n.state.Services.NTP.Config.Servers = []string{}
+	}
+
+	if n.state.Services.NTP.Config.FallbackServers == nil {
+		n.state.Services.NTP.Config.FallbackServers = []string{}
+	}
+
+	// Get the synchronization status, which applies whether the service is enabled or not.
+	synchronized, err := systemd.IsTimeSynchronized(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	serverName, serverAddress, err := systemd.GetTimesyncServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	n.state.Services.NTP.State = api.ServiceNTPState{
+		Synchronized:  synchronized,
+		ServerName:    serverName,
+		ServerAddress: serverAddress,
+	}
+
+	return n.state.Services.NTP, nil
+}
+
+// Update updates the service configuration.
+func (n *NTP) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceNTP)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceNTP", req)
+	}
+
+	if newState.Config.Enabled && len(newState.Config.Servers) == 0 && len(newState.Config.FallbackServers) == 0 {
+		return errors.New("at least one NTP server must be provided")
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Disable the service if requested.
+	if n.state.Services.NTP.Config.Enabled && !newState.Config.Enabled {
+		err := n.Stop(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the configuration.
+	n.state.Services.NTP.Config = newState.Config
+
+	// Enable or reconfigure the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the service, reverting to the time servers from the network configuration.
+func (n *NTP) Stop(ctx context.Context) error {
+	if !n.state.Services.NTP.Config.Enabled {
+		return nil
+	}
+
+	// Remove the configuration.
+	err := os.Remove(n.configPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// Restart the time synchronization.
+	return systemd.RestartUnit(ctx, "systemd-timesyncd")
+}
+
+// Start starts the service.
+func (n *NTP) Start(ctx context.Context) error {
+	if !n.state.Services.NTP.Config.Enabled {
+		return nil
+	}
+
+	// Create the configuration directory if missing.
+	err := os.MkdirAll(systemd.SystemdTimesyncDropinPath, 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Generate the configuration, overriding the time servers from the network configuration.
+	config := "[Time]\n"
+
+	if len(n.state.Services.NTP.Config.Servers) > 0 {
+		config += "NTP=" + strings.Join(n.state.Services.NTP.Config.Servers, " ") + "\n"
+	}
+
+	if len(n.state.Services.NTP.Config.FallbackServers) > 0 {
+		config += "FallbackNTP=" + strings.Join(n.state.Services.NTP.Config.FallbackServers, " ") + "\n"
+	}
+
+	err = os.WriteFile(n.configPath(), []byte(config), 0o644)
+	if err != nil {
+		return err
+	}
+
+	// Restart the time synchronization.
+	return systemd.RestartUnit(ctx, "systemd-timesyncd")
+}
+
+// Status returns the runtime status of the service.
+func (n *NTP) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "systemd-timesyncd.service")
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *NTP) ShouldStart() bool {
+	return n.state.Services.NTP.Config.Enabled
+}
+
+// Struct returns the API struct for the NTP service.
+func (*NTP) Struct() any {
+	return &api.ServiceNTP{}
+}
+
+// configPath returns the path of the systemd-timesyncd configuration override.
+func (*NTP) configPath() string {
+	return filepath.Join(systemd.SystemdTimesyncDropinPath, "ntp.conf")
+}
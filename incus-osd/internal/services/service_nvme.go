@@ -208,6 +208,11 @@ func (n *NVME) Start(ctx context.Context) error {
 	return nil
 }
 
+// Status returns the runtime status of the service.
+func (n *NVME) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *NVME) ShouldStart() bool {
 	return n.state.Services.NVME.Config.Enabled
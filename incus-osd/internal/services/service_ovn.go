@@ -2,12 +2,21 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
@@ -39,7 +48,37 @@ type OVN struct {
 
 // Get returns the current service state.
 func (n *OVN) Get(_ context.Context) (any, error) {
-	return n.state.Services.OVN, nil
+	n.state.Services.OVN.State = api.ServiceOVNState{}
+
+	// Report the certificate details, to know when they need rotating.
+	if n.state.Services.OVN.Config.TLSClientCertificate != "" {
+		certBlock, _ := pem.Decode([]byte(n.state.Services.OVN.Config.TLSClientCertificate))
+		if certBlock != nil {
+			cert, err := x509.ParseCertificate(certBlock.Bytes)
+			if err == nil {
+				fingerprint := sha256.Sum256(cert.Raw)
+				n.state.Services.OVN.State.TLSClientCertificateFingerprint = hex.EncodeToString(fingerprint[:])
+				n.state.Services.OVN.State.TLSClientCertificateExpiry = &cert.NotAfter
+			}
+		}
+	}
+
+	if n.state.Services.OVN.Config.TLSCACertificate != "" {
+		caCerts, err := parseOVNCertificates(n.state.Services.OVN.Config.TLSCACertificate)
+		if err == nil {
+			for _, caCert := range caCerts {
+				if n.state.Services.OVN.State.TLSCACertificateExpiry == nil || caCert.NotAfter.Before(*n.state.Services.OVN.State.TLSCACertificateExpiry) {
+					n.state.Services.OVN.State.TLSCACertificateExpiry = &caCert.NotAfter
+				}
+			}
+		}
+	}
+
+	// Never return the client key.
+	ret := n.state.Services.OVN
+	ret.Config = secrets.RedactOVNConfig(ret.Config)
+
+	return ret, nil
 }
 
 // Update updates the service configuration.
@@ -49,9 +88,27 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceOVN", req)
 	}
 
+	// Seal the client key first, so a redacted key is validated as the current one.
+	err := secrets.SealOVNConfig(&newState.Config, n.state.Services.OVN.Config)
+	if err != nil {
+		return err
+	}
+
+	if newState.Config.Enabled {
+		err := validateOVNTLS(newState.Config)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Save the state on return.
 	defer n.state.Save()
 
+	// Only rotate the certificates if nothing else changed, to avoid reconfiguring OVS.
+	if n.state.Services.OVN.Config.Enabled && newState.Config.Enabled && isOVNTLSRotation(n.state.Services.OVN.Config, newState.Config) {
+		return n.rotateTLS(ctx, newState.Config)
+	}
+
 	// Disable the service if requested.
 	if n.state.Services.OVN.Config.Enabled && !newState.Config.Enabled {
 		err := n.Stop(ctx)
@@ -72,7 +129,7 @@ func (n *OVN) Update(ctx context.Context, req any) error {
 	}
 
 	// Configure the service.
-	err := n.configure(ctx)
+	err = n.configure(ctx)
 	if err != nil {
 		return err
 	}
@@ -111,6 +168,11 @@ func (n *OVN) Start(ctx context.Context) error {
 	return n.configure(ctx)
 }
 
+// Status returns the runtime status of the service.
+func (n *OVN) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "ovsdb-server.service", "ovs-vswitchd.service", "ovn-controller.service")
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *OVN) ShouldStart() bool {
 	return n.state.Services.OVN.Config.Enabled
@@ -132,60 +194,212 @@ func (n *OVN) configure(ctx context.Context) error {
 	args = append(args, "external_ids:ovn-encap-ip="+n.state.Services.OVN.Config.TunnelAddress)
 	args = append(args, fmt.Sprintf("external_ids:ovn-is-interconn=%v", n.state.Services.OVN.Config.ICChassis))
 
-	_, err := subprocess.RunCommand("ovs-vsctl", args...)
+	_, err := subprocess.RunCommandContext(ctx, "ovs-vsctl", args...)
 	if err != nil {
 		return err
 	}
 
 	// Write the OVN certificates (if provided).
-	err = os.MkdirAll("/run/ovn", 0o700)
+	err = writeOVNTLS(n.state.Services.OVN.Config)
 	if err != nil {
 		return err
 	}
 
+	// Generate the systemd unit.
 	if n.state.Services.OVN.Config.TLSClientCertificate != "" {
-		err = os.WriteFile("/run/ovn/client.crt", []byte(n.state.Services.OVN.Config.TLSClientCertificate), 0o600)
+		err = os.WriteFile("/run/systemd/system/ovn-controller.service", []byte(ovnSystemdTLS), 0o600)
 		if err != nil {
 			return err
 		}
-	}
-
-	if n.state.Services.OVN.Config.TLSClientKey != "" {
-		err = os.WriteFile("/run/ovn/client.key", []byte(n.state.Services.OVN.Config.TLSClientKey), 0o600)
+	} else {
+		err = os.WriteFile("/run/systemd/system/ovn-controller.service", []byte(ovnSystemd), 0o600)
 		if err != nil {
 			return err
 		}
 	}
 
-	if n.state.Services.OVN.Config.TLSCACertificate != "" {
-		err = os.WriteFile("/run/ovn/ca.crt", []byte(n.state.Services.OVN.Config.TLSCACertificate), 0o600)
-		if err != nil {
-			return err
+	err = systemd.ReloadDaemon(ctx)
+	if err != nil {
+		return err
+	}
+
+	// (Re)start the OVN controller.
+	err = systemd.RestartUnit(ctx, "ovn-controller.service")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// rotateTLS replaces the OVN certificates and restarts the OVN controller, going back to the previous
+// certificates if the controller fails to start with the new ones.
+func (n *OVN) rotateTLS(ctx context.Context, config api.ServiceOVNConfig) error {
+	previousConfig := n.state.Services.OVN.Config
+
+	err := writeOVNTLS(config)
+	if err != nil {
+		return err
+	}
+
+	err = systemd.RestartUnit(ctx, "ovn-controller.service")
+	if err == nil {
+		// Give the controller a moment to load the certificates.
+		time.Sleep(2 * time.Second)
+
+		if systemd.IsActive(ctx, "ovn-controller.service") {
+			n.state.Services.OVN.Config = config
+
+			return nil
 		}
+
+		err = errors.New("OVN controller failed to start with the new certificates")
 	}
 
-	// Generate the systemd unit.
-	if n.state.Services.OVN.Config.TLSClientCertificate != "" {
-		err = os.WriteFile("/run/systemd/system/ovn-controller.service", []byte(ovnSystemdTLS), 0o600)
+	// Restore the previous certificates.
+	restoreErr := writeOVNTLS(previousConfig)
+	if restoreErr == nil {
+		restoreErr = systemd.RestartUnit(ctx, "ovn-controller.service")
+	}
+
+	if restoreErr != nil {
+		slog.ErrorContext(ctx, "Failed to restore the previous OVN certificates", "err", restoreErr.Error())
+	}
+
+	return err
+}
+
+// isOVNTLSRotation returns true if the only difference between both configurations is the TLS material.
+func isOVNTLSRotation(current api.ServiceOVNConfig, config api.ServiceOVNConfig) bool {
+	if current.TLSClientCertificate == "" || config.TLSClientCertificate == "" {
+		return false
+	}
+
+	if current == config {
+		return false
+	}
+
+	current.TLSClientCertificate = config.TLSClientCertificate
+	current.TLSClientKey = config.TLSClientKey
+	current.TLSCACertificate = config.TLSCACertificate
+
+	return current == config
+}
+
+// writeOVNTLS writes the OVN certificates and key (if provided), replacing the existing files atomically.
+func writeOVNTLS(config api.ServiceOVNConfig) error {
+	err := os.MkdirAll("/run/ovn", 0o700)
+	if err != nil {
+		return err
+	}
+
+	clientKey, err := secrets.Open(config.TLSClientKey)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"/run/ovn/client.crt": config.TLSClientCertificate,
+		"/run/ovn/client.key": clientKey,
+		"/run/ovn/ca.crt":     config.TLSCACertificate,
+	}
+
+	for path, content := range files {
+		if content == "" {
+			continue
+		}
+
+		err := os.WriteFile(path+".new", []byte(content), 0o600)
 		if err != nil {
 			return err
 		}
-	} else {
-		err = os.WriteFile("/run/systemd/system/ovn-controller.service", []byte(ovnSystemd), 0o600)
+
+		err = os.Rename(path+".new", path)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = systemd.ReloadDaemon(ctx)
+	return nil
+}
+
+// parseOVNCertificates parses all the certificates of a PEM bundle.
+func parseOVNCertificates(bundle string) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := []byte(bundle)
+
+	for {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+
+	return certs, nil
+}
+
+// validateOVNTLS checks that the client certificate matches its key and is signed by the CA, so that
+// rotating the certificates doesn't leave the OVN controller unable to connect.
+func validateOVNTLS(config api.ServiceOVNConfig) error {
+	if config.TLSClientCertificate == "" && config.TLSClientKey == "" && config.TLSCACertificate == "" {
+		return nil
+	}
+
+	if config.TLSClientCertificate == "" || config.TLSClientKey == "" || config.TLSCACertificate == "" {
+		return errors.New("the OVN client certificate, key and CA certificate must be provided together")
+	}
+
+	clientKey, err := secrets.Open(config.TLSClientKey)
 	if err != nil {
 		return err
 	}
 
-	// (Re)start the OVN controller.
-	err = systemd.RestartUnit(ctx, "ovn-controller.service")
+	_, err = tls.X509KeyPair([]byte(config.TLSClientCertificate), []byte(clientKey))
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid OVN client certificate or key: %w", err)
+	}
+
+	certs, err := parseOVNCertificates(config.TLSClientCertificate)
+	if err != nil {
+		return fmt.Errorf("invalid OVN client certificate: %w", err)
+	}
+
+	caCerts, err := parseOVNCertificates(config.TLSCACertificate)
+	if err != nil {
+		return fmt.Errorf("invalid OVN CA certificate: %w", err)
+	}
+
+	// The CA may be a bundle holding both the current and the next CA during a rotation.
+	roots := x509.NewCertPool()
+	for _, caCert := range caCerts {
+		roots.AddCert(caCert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err != nil {
+		return fmt.Errorf("OVN client certificate isn't signed by the provided CA: %w", err)
 	}
 
 	return nil
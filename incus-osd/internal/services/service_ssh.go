@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+const (
+	sshDataPath    = "/var/lib/incus-os/ssh/"
+	sshRunPath     = "/run/incus-os/ssh/"
+	sshUnit        = "incus-osd-sshd.service"
+	sshConsoleUnit = "getty@tty2.service"
+	shadowPath     = "/etc/shadow"
+)
+
+// SSH represents the system SSH service, along with the local console recovery login.
+type SSH struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *SSH) Get(_ context.Context) (any, error) {
+	// Initialize the key list if missing.
+	if n.state.Services.SSH.Config.AuthorizedKeys == nil {
+		n.state.Services.SSH.Config.AuthorizedKeys = []string{}
+	}
+
+	// The host keys are only generated once the service is first enabled.
+	n.state.Services.SSH.State.HostKeyFingerprints = []string{}
+
+	content, err := os.ReadFile(filepath.Join(sshDataPath, "ssh_host_ed25519_key.pub"))
+	if err == nil {
+		key, _, _, _, err := ssh.ParseAuthorizedKey(content)
+		if err != nil {
+			return nil, err
+		}
+
+		n.state.Services.SSH.State.HostKeyFingerprints = append(n.state.Services.SSH.State.HostKeyFingerprints, ssh.FingerprintSHA256(key))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// Never return the console password hash, as it would allow an offline attack on the root password.
+	ret := n.state.Services.SSH
+	ret.Config = secrets.RedactSSHConfig(ret.Config)
+
+	return ret, nil
+}
+
+// Update updates the service configuration.
+func (n *SSH) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceSSH)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceSSH", req)
+	}
+
+	// A redacted console password hash keeps the current one.
+	if newState.Config.ConsolePasswordHash == secrets.Redacted {
+		newState.Config.ConsolePasswordHash = n.state.Services.SSH.Config.ConsolePasswordHash
+	}
+
+	err := validateSSHConfig(newState.Config)
+	if err != nil {
+		return err
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Stop the service, so it can be reconfigured.
+	err = n.Stop(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Update the configuration.
+	n.state.Services.SSH.Config = newState.Config
+
+	// Enable or reconfigure the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the SSH server and the console login, and locks the root password.
+func (n *SSH) Stop(ctx context.Context) error {
+	if !n.ShouldStart() {
+		return nil
+	}
+
+	err := systemd.StopUnit(ctx, sshUnit, sshConsoleUnit)
+	if err != nil {
+		return err
+	}
+
+	return setRootPasswordHash("!")
+}
+
+// Start starts the SSH server and the console login, as configured.
+func (n *SSH) Start(ctx context.Context) error {
+	if n.state.Services.SSH.Config.ConsolePasswordHash != "" {
+		err := setRootPasswordHash(n.state.Services.SSH.Config.ConsolePasswordHash)
+		if err != nil {
+			return err
+		}
+
+		err = systemd.StartUnit(ctx, sshConsoleUnit)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !n.state.Services.SSH.Config.Enabled {
+		return nil
+	}
+
+	err := n.writeConfig()
+	if err != nil {
+		return err
+	}
+
+	return systemd.StartUnit(ctx, sshUnit)
+}
+
+// Status returns the runtime status of the service.
+func (n *SSH) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	units := []string{}
+
+	if n.state.Services.SSH.Config.Enabled {
+		units = append(units, sshUnit)
+	}
+
+	if n.state.Services.SSH.Config.ConsolePasswordHash != "" {
+		units = append(units, sshConsoleUnit)
+	}
+
+	return getUnitsStatus(ctx, n.ShouldStart(), units...)
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *SSH) ShouldStart() bool {
+	return n.state.Services.SSH.Config.Enabled || n.state.Services.SSH.Config.ConsolePasswordHash != ""
+}
+
+// Struct returns the API struct for the SSH service.
+func (*SSH) Struct() any {
+	return &api.ServiceSSH{}
+}
+
+// writeConfig generates the host key if missing, then writes the SSH server configuration and authorized keys.
+func (n *SSH) writeConfig() error {
+	err := os.MkdirAll(sshDataPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	hostKeyPath := filepath.Join(sshDataPath, "ssh_host_ed25519_key")
+
+	_, err = os.Stat(hostKeyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		err = generateSSHHostKey(hostKeyPath)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(sshRunPath, 0o700)
+	if err != nil {
+		return err
+	}
+
+	authorizedKeys := strings.Join(n.state.Services.SSH.Config.AuthorizedKeys, "\n") + "\n"
+
+	err = os.WriteFile(filepath.Join(sshRunPath, "authorized_keys"), []byte(authorizedKeys), 0o600)
+	if err != nil {
+		return err
+	}
+
+	port := n.state.Services.SSH.Config.Port
+	if port == 0 {
+		port = 22
+	}
+
+	config := "Port " + strconv.Itoa(port) + "\n"
+	config += "HostKey " + hostKeyPath + "\n"
+	config += "AuthorizedKeysFile " + filepath.Join(sshRunPath, "authorized_keys") + "\n"
+	config += "PermitRootLogin prohibit-password\n"
+	config += "PasswordAuthentication no\n"
+	config += "KbdInteractiveAuthentication no\n"
+	config += "UsePAM no\n"
+	config += "PrintMotd no\n"
+	config += "Subsystem sftp internal-sftp\n"
+
+	return os.WriteFile(filepath.Join(sshRunPath, "sshd_config"), []byte(config), 0o600)
+}
+
+// validateSSHConfig checks the port, authorized keys and console password hash.
+func validateSSHConfig(config api.ServiceSSHConfig) error {
+	if config.Port < 0 || config.Port > 65535 {
+		return fmt.Errorf("invalid SSH port %d", config.Port)
+	}
+
+	if config.Enabled && len(config.AuthorizedKeys) == 0 {
+		return errors.New("at least one authorized key must be provided")
+	}
+
+	for _, key := range config.AuthorizedKeys {
+		if strings.ContainsAny(key, "\r\n") {
+			return errors.New("authorized keys must be provided one per entry")
+		}
+
+		_, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return fmt.Errorf("invalid authorized key %q: %w", key, err)
+		}
+	}
+
+	// Only accept a hash, in the "$id$..." format from crypt(3), and never a plain text password.
+	hash := config.ConsolePasswordHash
+	if hash != "" && (!strings.HasPrefix(hash, "$") || strings.ContainsAny(hash, ": \t\r\n")) {
+		return errors.New("invalid console password hash, expected a crypt(3) hash such as produced by \"mkpasswd\"")
+	}
+
+	return nil
+}
+
+// generateSSHHostKey generates a new ED25519 host key, along with its public key.
+func generateSSHHostKey(path string) error {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	block, err := ssh.MarshalPrivateKey(privKey, "")
+	if err != nil {
+		return err
+	}
+
+	sshPubKey, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, pem.EncodeToMemory(block), 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path+".pub", ssh.MarshalAuthorizedKey(sshPubKey), 0o644) //nolint:gosec
+}
+
+// setRootPasswordHash replaces the root password hash in /etc/shadow.
+func setRootPasswordHash(hash string) error {
+	content, err := os.ReadFile(shadowPath)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		fields := strings.Split(line, ":")
+		if len(fields) < 2 || fields[0] != "root" {
+			continue
+		}
+
+		if fields[1] == hash {
+			return nil
+		}
+
+		fields[1] = hash
+		lines[i] = strings.Join(fields, ":")
+
+		return writeFileAtomic(shadowPath, []byte(strings.Join(lines, "\n")), 0o640)
+	}
+
+	return errors.New("no root entry found in " + shadowPath)
+}
+
+// writeFileAtomic replaces a file through a synced temporary file in the same directory, so a
+// crash never leaves it partially written.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(f.Name()) }()
+	defer f.Close()
+
+	_, err = f.Write(content)
+	if err != nil {
+		return err
+	}
+
+	err = f.Chmod(mode)
+	if err != nil {
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	// Sync the directory, so the rename itself is persisted.
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -99,6 +99,11 @@ func (n *Tailscale) Start(ctx context.Context) error {
 	return nil
 }
 
+// Status returns the runtime status of the service.
+func (n *Tailscale) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "tailscale.service")
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *Tailscale) ShouldStart() bool {
 	return n.state.Services.Tailscale.Config.Enabled
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// tunable describes a supported kernel tunable.
+type tunable struct {
+	name  string
+	path  string
+	min   int
+	max   int
+	value func(cfg api.ServiceTuningConfig) int
+}
+
+// tunables is the curated list of kernel tunables exposed by the tuning service.
+var tunables = []tunable{
+	{
+		name:  "vm.nr_hugepages",
+		path:  "/proc/sys/vm/nr_hugepages",
+		min:   1,
+		max:   1048576,
+		value: func(cfg api.ServiceTuningConfig) int { return cfg.Hugepages },
+	},
+	{
+		name:  "vm.nr_hugepages_1g",
+		path:  "/sys/kernel/mm/hugepages/hugepages-1048576kB/nr_hugepages",
+		min:   1,
+		max:   4096,
+		value: func(cfg api.ServiceTuningConfig) int { return cfg.Hugepages1G },
+	},
+	{
+		name:  "vm.swappiness",
+		path:  "/proc/sys/vm/swappiness",
+		min:   1,
+		max:   200,
+		value: func(cfg api.ServiceTuningConfig) int { return cfg.Swappiness },
+	},
+	{
+		name:  "net.core.netdev_budget",
+		path:  "/proc/sys/net/core/netdev_budget",
+		min:   50,
+		max:   100000,
+		value: func(cfg api.ServiceTuningConfig) int { return cfg.NetdevBudget },
+	},
+	{
+		name:  "net.core.netdev_max_backlog",
+		path:  "/proc/sys/net/core/netdev_max_backlog",
+		min:   100,
+		max:   10000000,
+		value: func(cfg api.ServiceTuningConfig) int { return cfg.NetdevMaxBacklog },
+	},
+	{
+		name:  "net.netfilter.nf_conntrack_max",
+		path:  "/proc/sys/net/netfilter/nf_conntrack_max",
+		min:   1024,
+		max:   67108864,
+		value: func(cfg api.ServiceTuningConfig) int { return cfg.ConntrackMax },
+	},
+}
+
+// tuningDefaults records the kernel value of each tunable before it was first changed, so it can be restored.
+var (
+	tuningDefaults   = map[string]int{}
+	tuningDefaultsMu sync.Mutex
+)
+
+// Tuning represents the system kernel tuning service.
+type Tuning struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Tuning) Get(_ context.Context) (any, error) {
+	n.state.Services.Tuning.State.Values = map[string]int{}
+
+	for _, t := range tunables {
+		value, err := readTunable(t.path)
+		if err != nil {
+			continue
+		}
+
+		n.state.Services.Tuning.State.Values[t.name] = value
+	}
+
+	return n.state.Services.Tuning, nil
+}
+
+// Update updates the service configuration.
+func (n *Tuning) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceTuning)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceTuning", req)
+	}
+
+	if newState.Config.Enabled {
+		err := validateTuningConfig(newState.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Restore the kernel defaults before applying the new configuration.
+	err := n.Stop(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Update the configuration.
+	n.state.Services.Tuning.Config = newState.Config
+
+	// Enable the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the service.
+func (n *Tuning) Stop(_ context.Context) error {
+	if !n.state.Services.Tuning.Config.Enabled {
+		return nil
+	}
+
+	tuningDefaultsMu.Lock()
+	defer tuningDefaultsMu.Unlock()
+
+	for _, t := range tunables {
+		value, ok := tuningDefaults[t.name]
+		if !ok {
+			continue
+		}
+
+		err := writeTunable(t.path, value)
+		if err != nil {
+			return fmt.Errorf("failed to restore %s: %w", t.name, err)
+		}
+
+		delete(tuningDefaults, t.name)
+	}
+
+	return nil
+}
+
+// Start starts the service.
+func (n *Tuning) Start(ctx context.Context) error {
+	if !n.state.Services.Tuning.Config.Enabled {
+		return nil
+	}
+
+	// The conntrack tunables only exist once the module is loaded.
+	if n.state.Services.Tuning.Config.ConntrackMax > 0 {
+		_, err := subprocess.RunCommandContext(ctx, "modprobe", "nf_conntrack")
+		if err != nil {
+			return err
+		}
+	}
+
+	tuningDefaultsMu.Lock()
+	defer tuningDefaultsMu.Unlock()
+
+	for _, t := range tunables {
+		value := t.value(n.state.Services.Tuning.Config)
+		if value == 0 {
+			continue
+		}
+
+		// Record the current value so it can be restored later.
+		_, ok := tuningDefaults[t.name]
+		if !ok {
+			current, err := readTunable(t.path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", t.name, err)
+			}
+
+			tuningDefaults[t.name] = current
+		}
+
+		err := writeTunable(t.path, value)
+		if err != nil {
+			return fmt.Errorf("failed to set %s: %w", t.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Status returns the runtime status of the service.
+func (n *Tuning) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Tuning) ShouldStart() bool {
+	return n.state.Services.Tuning.Config.Enabled
+}
+
+// Struct returns the API struct for the tuning service.
+func (*Tuning) Struct() any {
+	return &api.ServiceTuning{}
+}
+
+// validateTuningConfig checks that all configured tunables are within their supported range.
+func validateTuningConfig(cfg api.ServiceTuningConfig) error {
+	for _, t := range tunables {
+		value := t.value(cfg)
+		if value == 0 {
+			continue
+		}
+
+		if value < t.min || value > t.max {
+			return fmt.Errorf("invalid value for %s: must be between %d and %d", t.name, t.min, t.max)
+		}
+	}
+
+	return nil
+}
+
+// readTunable returns the current integer value of a sysctl or sysfs file.
+func readTunable(path string) (int, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0, errors.New("empty value")
+	}
+
+	return strconv.Atoi(fields[0])
+}
+
+// writeTunable sets the integer value of a sysctl or sysfs file.
+func writeTunable(path string, value int) error {
+	return os.WriteFile(path, []byte(strconv.Itoa(value)+"\n"), 0o644) //nolint:gosec
+}
@@ -4,13 +4,22 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
 
+// usbDevicesPath is the sysfs directory listing the USB devices.
+const usbDevicesPath = "/sys/bus/usb/devices/"
+
 // USBIP represents the system USBIP service.
 type USBIP struct {
 	common
@@ -19,12 +28,34 @@ type USBIP struct {
 }
 
 // Get returns the current service state.
-func (n *USBIP) Get(_ context.Context) (any, error) {
+func (n *USBIP) Get(ctx context.Context) (any, error) {
 	// Initialize target list if missing.
 	if n.state.Services.USBIP.Config.Targets == nil {
 		n.state.Services.USBIP.Config.Targets = []api.ServiceUSBIPTarget{}
 	}
 
+	if n.state.Services.USBIP.Config.Exports == nil {
+		n.state.Services.USBIP.Config.Exports = []string{}
+	}
+
+	// List the local devices.
+	devices, err := getUSBDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	n.state.Services.USBIP.State.Devices = devices
+
+	// List the remote clients.
+	n.state.Services.USBIP.State.Clients = []string{}
+
+	if systemd.IsActive(ctx, "usbipd") {
+		n.state.Services.USBIP.State.Clients, err = getUSBIPClients(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return n.state.Services.USBIP, nil
 }
 
@@ -35,13 +66,45 @@ func (n *USBIP) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceUSBIP", req)
 	}
 
+	// Check that the exported devices exist.
+	for _, busID := range newState.Config.Exports {
+		if busID == "" || strings.ContainsAny(busID, "/:") {
+			return fmt.Errorf("invalid USB bus ID %q", busID)
+		}
+
+		_, err := os.Stat(filepath.Join(usbDevicesPath, busID))
+		if err != nil {
+			return fmt.Errorf("USB device %q doesn't exist", busID)
+		}
+	}
+
 	// Save the state on return.
 	defer n.state.Save()
 
+	// Stop exporting the devices which were removed.
+	for _, busID := range n.state.Services.USBIP.Config.Exports {
+		if slices.Contains(newState.Config.Exports, busID) {
+			continue
+		}
+
+		err := unbindUSBIPDevice(ctx, busID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Stop the server if nothing is exported anymore.
+	if len(n.state.Services.USBIP.Config.Exports) > 0 && len(newState.Config.Exports) == 0 {
+		err := systemd.StopUnit(ctx, "usbipd")
+		if err != nil {
+			return err
+		}
+	}
+
 	// Update the configuration.
 	n.state.Services.USBIP.Config = newState.Config
 
-	// Attach the devices.
+	// Attach and export the devices.
 	err := n.Start(ctx)
 	if err != nil {
 		return err
@@ -52,6 +115,26 @@ func (n *USBIP) Update(ctx context.Context, req any) error {
 
 // Start starts the service.
 func (n *USBIP) Start(ctx context.Context) error {
+	// Export the local devices.
+	if len(n.state.Services.USBIP.Config.Exports) > 0 {
+		_, err := subprocess.RunCommandContext(ctx, "modprobe", "usbip-host")
+		if err != nil {
+			return err
+		}
+
+		err = systemd.StartUnit(ctx, "usbipd")
+		if err != nil {
+			return err
+		}
+
+		for _, busID := range n.state.Services.USBIP.Config.Exports {
+			err := bindUSBIPDevice(ctx, busID)
+			if err != nil {
+				slog.WarnContext(ctx, "Unable to export USBIP device", "busid", busID, "err", err)
+			}
+		}
+	}
+
 	// If nothing to be attached, we're done.
 	if len(n.state.Services.USBIP.Config.Targets) == 0 {
 		return nil
@@ -75,12 +158,128 @@ func (n *USBIP) Start(ctx context.Context) error {
 	return nil
 }
 
+// Status returns the runtime status of the service.
+func (n *USBIP) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	// The server only runs when devices are exported.
+	if len(n.state.Services.USBIP.Config.Exports) > 0 {
+		return getUnitsStatus(ctx, n.ShouldStart(), "usbipd.service")
+	}
+
+	return getUnitsStatus(ctx, n.ShouldStart())
+}
+
 // ShouldStart returns true if the service should be started on boot.
 func (n *USBIP) ShouldStart() bool {
-	return len(n.state.Services.USBIP.Config.Targets) > 0
+	return len(n.state.Services.USBIP.Config.Targets) > 0 || len(n.state.Services.USBIP.Config.Exports) > 0
 }
 
 // Struct returns the API struct for the USBIP service.
 func (*USBIP) Struct() any {
 	return &api.ServiceUSBIP{}
 }
+
+// isUSBIPExported returns true if the device is currently bound to the usbip-host driver.
+func isUSBIPExported(busID string) bool {
+	driver, err := os.Readlink(filepath.Join(usbDevicesPath, busID, "driver"))
+	if err != nil {
+		return false
+	}
+
+	return filepath.Base(driver) == "usbip-host"
+}
+
+// bindUSBIPDevice binds the device to the usbip-host driver, making it available to remote clients.
+func bindUSBIPDevice(ctx context.Context, busID string) error {
+	if isUSBIPExported(busID) {
+		return nil
+	}
+
+	_, err := subprocess.RunCommandContext(ctx, "usbip", "bind", "-b", busID)
+
+	return err
+}
+
+// unbindUSBIPDevice returns the device to its local driver.
+func unbindUSBIPDevice(ctx context.Context, busID string) error {
+	if !isUSBIPExported(busID) {
+		return nil
+	}
+
+	_, err := subprocess.RunCommandContext(ctx, "usbip", "unbind", "-b", busID)
+
+	return err
+}
+
+// getUSBDevices returns the list of local USB devices, excluding root hubs and interfaces.
+func getUSBDevices() ([]api.ServiceUSBIPDevice, error) {
+	entries, err := os.ReadDir(usbDevicesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []api.ServiceUSBIPDevice{}
+
+	for _, entry := range entries {
+		busID := entry.Name()
+		if strings.HasPrefix(busID, "usb") || strings.Contains(busID, ":") {
+			continue
+		}
+
+		readValue := func(name string) string {
+			content, err := os.ReadFile(filepath.Join(usbDevicesPath, busID, name)) //nolint:gosec
+			if err != nil {
+				return ""
+			}
+
+			return strings.TrimSpace(string(content))
+		}
+
+		device := api.ServiceUSBIPDevice{
+			BusID:        busID,
+			VendorID:     readValue("idVendor"),
+			ProductID:    readValue("idProduct"),
+			Manufacturer: readValue("manufacturer"),
+			Product:      readValue("product"),
+			Exported:     isUSBIPExported(busID),
+		}
+
+		// A status of 2 (SDEV_ST_USED) indicates a client is attached.
+		if device.Exported {
+			device.InUse = readValue("usbip_status") == "2"
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+// getUSBIPClients returns the addresses of the remote clients connected to the USBIP server.
+func getUSBIPClients(ctx context.Context) ([]string, error) {
+	output, err := subprocess.RunCommandContext(ctx, "ss", "-Htn", "state", "established", "( sport = :3240 )")
+	if err != nil {
+		return nil, err
+	}
+
+	clients := []string{}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(fields[3])
+		if err != nil {
+			continue
+		}
+
+		host = strings.Trim(host, "[]")
+
+		if !slices.Contains(clients, host) {
+			clients = append(clients, host)
+		}
+	}
+
+	return clients, nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// keepalivedConfigPath is the keepalived configuration file.
+const keepalivedConfigPath = "/etc/keepalived/keepalived.conf"
+
+// VRRP represents the system VRRP service, providing floating addresses through keepalived.
+type VRRP struct {
+	common
+
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *VRRP) Get(ctx context.Context) (any, error) {
+	// Initialize the lists if missing.
+	if n.state.Services.VRRP.Config.Addresses == nil {
+		n.state.Services.VRRP.Config.Addresses = []string{}
+	}
+
+	if n.state.Services.VRRP.Config.Peers == nil {
+		n.state.Services.VRRP.Config.Peers = []string{}
+	}
+
+	// Determine whether this host currently holds the floating addresses.
+	n.state.Services.VRRP.State.Role = ""
+
+	if n.state.Services.VRRP.Config.Enabled && systemd.IsActive(ctx, "keepalived") {
+		addresses, err := systemd.GetIPAddresses(ctx, n.state.Services.VRRP.Config.Interface)
+		if err != nil {
+			return nil, err
+		}
+
+		n.state.Services.VRRP.State.Role = "backup"
+
+		for _, address := range n.state.Services.VRRP.Config.Addresses {
+			ip, _, _ := net.ParseCIDR(address)
+			if ip != nil && slices.Contains(addresses, ip.String()) {
+				n.state.Services.VRRP.State.Role = "master"
+
+				break
+			}
+		}
+	}
+
+	return n.state.Services.VRRP, nil
+}
+
+// Update updates the service configuration.
+func (n *VRRP) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceVRRP)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceVRRP", req)
+	}
+
+	if newState.Config.Enabled {
+		err := n.validateConfig(newState.Config)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the state on return.
+	defer n.state.Save()
+
+	// Disable the service if requested.
+	if n.state.Services.VRRP.Config.Enabled && !newState.Config.Enabled {
+		err := n.Stop(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the configuration.
+	n.state.Services.VRRP.Config = newState.Config
+
+	// Enable or reconfigure the service if requested.
+	return n.Start(ctx)
+}
+
+// Stop stops the service.
+func (n *VRRP) Stop(ctx context.Context) error {
+	if !n.state.Services.VRRP.Config.Enabled {
+		return nil
+	}
+
+	// Stop keepalived, releasing the floating addresses.
+	err := systemd.StopUnit(ctx, "keepalived")
+	if err != nil {
+		return err
+	}
+
+	n.state.Services.VRRP.State = api.ServiceVRRPState{}
+
+	return nil
+}
+
+// Start starts the service.
+func (n *VRRP) Start(ctx context.Context) error {
+	if !n.state.Services.VRRP.Config.Enabled {
+		return nil
+	}
+
+	// Create the configuration directory if missing.
+	err := os.MkdirAll("/etc/keepalived", 0o700)
+	if err != nil {
+		return err
+	}
+
+	config := generateKeepalivedConfig(n.state.Services.VRRP.Config, systemd.GetAddressDevice(n.state.Services.VRRP.Config.Interface))
+
+	err = os.WriteFile(keepalivedConfigPath, []byte(config), 0o600)
+	if err != nil {
+		return err
+	}
+
+	// Restart keepalived to apply the configuration.
+	return systemd.RestartUnit(ctx, "keepalived")
+}
+
+// Status returns the runtime status of the service.
+func (n *VRRP) Status(ctx context.Context) (*api.ServiceStatus, error) {
+	return getUnitsStatus(ctx, n.ShouldStart(), "keepalived.service")
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *VRRP) ShouldStart() bool {
+	return n.state.Services.VRRP.Config.Enabled
+}
+
+// Struct returns the API struct for the VRRP service.
+func (*VRRP) Struct() any {
+	return &api.ServiceVRRP{}
+}
+
+// validateConfig checks that the VRRP configuration is usable on this system.
+func (n *VRRP) validateConfig(config api.ServiceVRRPConfig) error {
+	_, ok := n.state.System.Network.State.Interfaces[config.Interface]
+	if !ok {
+		return fmt.Errorf("unknown interface %q", config.Interface)
+	}
+
+	if config.VirtualRouterID < 1 || config.VirtualRouterID > 255 {
+		return errors.New("virtual router ID must be between 1 and 255")
+	}
+
+	if config.Priority < 1 || config.Priority > 254 {
+		return errors.New("priority must be between 1 and 254")
+	}
+
+	if len(config.Addresses) == 0 {
+		return errors.New("at least one floating address must be provided")
+	}
+
+	var ipv4 bool
+
+	for i, address := range config.Addresses {
+		ip, _, err := net.ParseCIDR(address)
+		if err != nil {
+			return fmt.Errorf("invalid floating address %q: %w", address, err)
+		}
+
+		// A VRRP instance can only carry a single address family.
+		if i == 0 {
+			ipv4 = ip.To4() != nil
+		} else if ipv4 != (ip.To4() != nil) {
+			return errors.New("floating addresses must all be of the same address family")
+		}
+	}
+
+	for _, peer := range config.Peers {
+		ip := net.ParseIP(peer)
+		if ip == nil {
+			return fmt.Errorf("invalid peer address %q", peer)
+		}
+
+		if ipv4 != (ip.To4() != nil) {
+			return errors.New("peer addresses must be of the same address family as the floating addresses")
+		}
+	}
+
+	return nil
+}
+
+// generateKeepalivedConfig generates the keepalived configuration for a single VRRP instance on the given device.
+func generateKeepalivedConfig(config api.ServiceVRRPConfig, device string) string {
+	var sb strings.Builder
+
+	sb.WriteString("global_defs {\n")
+	sb.WriteString("\tenable_script_security\n")
+	sb.WriteString("}\n\n")
+
+	sb.WriteString("vrrp_instance incus-os {\n")
+	sb.WriteString("\tstate BACKUP\n")
+	fmt.Fprintf(&sb, "\tinterface %s\n", device)
+	fmt.Fprintf(&sb, "\tvirtual_router_id %d\n", config.VirtualRouterID)
+	fmt.Fprintf(&sb, "\tpriority %d\n", config.Priority)
+	sb.WriteString("\tadvert_int 1\n")
+
+	if len(config.Peers) > 0 {
+		sb.WriteString("\tunicast_peer {\n")
+
+		for _, peer := range config.Peers {
+			fmt.Fprintf(&sb, "\t\t%s\n", peer)
+		}
+
+		sb.WriteString("\t}\n")
+	}
+
+	sb.WriteString("\tvirtual_ipaddress {\n")
+
+	for _, address := range config.Addresses {
+		fmt.Fprintf(&sb, "\t\t%s dev %s\n", address, device)
+	}
+
+	sb.WriteString("\t}\n")
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
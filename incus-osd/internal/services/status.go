@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// getUnitsStatus returns the status of a service based on the state of its systemd units.
+func getUnitsStatus(ctx context.Context, enabled bool, units ...string) (*api.ServiceStatus, error) {
+	ret := &api.ServiceStatus{
+		Status: "stopped",
+		Units:  []api.ServiceUnitStatus{},
+	}
+
+	active := 0
+	failed := false
+
+	for _, unit := range units {
+		properties, err := systemd.GetUnitProperties(ctx, unit, "ActiveState", "SubState", "Result", "NRestarts", "StateChangeTimestamp")
+		if err != nil {
+			return nil, err
+		}
+
+		unitStatus := api.ServiceUnitStatus{
+			Name:        unit,
+			ActiveState: properties["ActiveState"],
+			SubState:    properties["SubState"],
+			Result:      properties["Result"],
+		}
+
+		unitStatus.Restarts, _ = strconv.Atoi(properties["NRestarts"])
+
+		since, err := strconv.ParseInt(strings.TrimPrefix(properties["StateChangeTimestamp"], "@"), 10, 64)
+		if err == nil && since > 0 {
+			ts := time.Unix(since, 0).UTC()
+			unitStatus.Since = &ts
+		}
+
+		switch unitStatus.ActiveState {
+		case "active", "reloading":
+			active++
+		case "failed":
+			failed = true
+		}
+
+		// Record the reason of the most recent failure.
+		if unitStatus.Result != "" && unitStatus.Result != "success" {
+			ret.LastFailure = unit + ": " + unitStatus.Result
+
+			message, err := subprocess.RunCommandContext(ctx, "journalctl", "-u", unit, "-b", "0", "-p", "err", "-n", "1", "-o", "cat", "--no-pager")
+			if err == nil && strings.TrimSpace(message) != "" {
+				ret.LastFailure = unit + ": " + strings.TrimSpace(message)
+			}
+		}
+
+		ret.Units = append(ret.Units, unitStatus)
+	}
+
+	switch {
+	case failed:
+		ret.Status = "failed"
+	case !enabled:
+		ret.Status = "stopped"
+	case active == len(units):
+		ret.Status = "running"
+	default:
+		ret.Status = "degraded"
+	}
+
+	return ret, nil
+}
+
+// GetLog returns the most recent journal entries of the systemd units used by the service, since the system booted.
+func GetLog(ctx context.Context, srv Service, entries int) ([]api.ServiceLogEntry, error) {
+	ret := []api.ServiceLogEntry{}
+
+	status, err := srv.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(status.Units) == 0 {
+		return ret, nil
+	}
+
+	args := []string{"-b", "0", "-o", "json", "--no-pager", "-n", strconv.Itoa(entries)}
+	for _, unit := range status.Units {
+		args = append(args, "-u", unit.Name)
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "journalctl", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		var fields struct {
+			Timestamp string          `json:"__REALTIME_TIMESTAMP"`
+			Unit      string          `json:"_SYSTEMD_UNIT"`
+			Priority  string          `json:"PRIORITY"`
+			Message   json.RawMessage `json:"MESSAGE"`
+		}
+
+		err := json.Unmarshal([]byte(line), &fields)
+		if err != nil {
+			continue
+		}
+
+		// Messages which aren't valid UTF-8 are provided as an array of bytes.
+		var message string
+
+		err = json.Unmarshal(fields.Message, &message)
+		if err != nil {
+			var raw []byte
+
+			err = json.Unmarshal(fields.Message, &raw)
+			if err != nil {
+				continue
+			}
+
+			message = string(raw)
+		}
+
+		entry := api.ServiceLogEntry{
+			Unit:    fields.Unit,
+			Message: message,
+		}
+
+		entry.Priority, _ = strconv.Atoi(fields.Priority)
+
+		usec, err := strconv.ParseInt(fields.Timestamp, 10, 64)
+		if err == nil {
+			entry.Time = time.UnixMicro(usec).UTC()
+		}
+
+		ret = append(ret, entry)
+	}
+
+	return ret, nil
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"errors"
+
+	"github.com/lxc/incus-os/incus-osd/api"
 )
 
 // Service represents a system service.
@@ -11,6 +13,7 @@ type Service interface {
 	ShouldStart() bool
 	Reset(ctx context.Context) error
 	Start(ctx context.Context) error
+	Status(ctx context.Context) (*api.ServiceStatus, error)
 	Stop(ctx context.Context) error
 	Struct() any
 	Supported() bool
@@ -1,14 +1,23 @@
 package state
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/events"
 )
 
 var currentStateVersion = 6
 
+// ErrStorageDegraded is returned when the state can't be persisted because its storage is read-only or failing.
+var ErrStorageDegraded = errors.New("the state storage is unavailable, changes can't be persisted")
+
 // LoadOrCreate parses the on-disk state file and returns a State struct.
 // If no file exists, a new empty one is created.
 func LoadOrCreate(path string) (*State, error) {
@@ -18,6 +27,9 @@ func LoadOrCreate(path string) (*State, error) {
 		StateVersion: currentStateVersion,
 
 		Applications: map[string]api.Application{},
+		Certificates: map[string]CertificateRotation{},
+
+		Events: events.NewServer(),
 	}
 
 	body, err := os.ReadFile(s.path)
@@ -55,19 +67,89 @@ func (s *State) Save() error {
 		return nil
 	}
 
+	s.applicationsMutex.RLock()
 	body, err := Encode(s)
+	s.applicationsMutex.RUnlock()
+
 	if err != nil {
 		return err
 	}
 
 	err = os.WriteFile(s.path, body, 0o600)
 	if err != nil {
+		if isStorageError(err) {
+			s.setStorageError(err)
+
+			return fmt.Errorf("%w: %w", ErrStorageDegraded, err)
+		}
+
 		return err
 	}
 
+	s.setStorageError(nil)
+
 	return nil
 }
 
+// StorageError returns the error which caused the state storage to be considered degraded, if any. While
+// degraded, the state is only kept in memory.
+func (s *State) StorageError() error {
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+
+	return s.storageError
+}
+
+// CheckStorage checks whether the state storage is still writable, marking it as degraded if it was remounted
+// read-only. While degraded, it attempts to persist the state again to detect a recovery.
+func (s *State) CheckStorage() error {
+	if s.StorageError() != nil {
+		err := s.Save()
+		if err != nil && !errors.Is(err, ErrStorageDegraded) {
+			return err
+		}
+
+		return s.StorageError()
+	}
+
+	var stat unix.Statfs_t
+
+	err := unix.Statfs(filepath.Dir(s.path), &stat)
+	if err != nil {
+		s.setStorageError(err)
+
+		return err
+	}
+
+	if stat.Flags&unix.ST_RDONLY != 0 {
+		err := fmt.Errorf("%s is mounted read-only", filepath.Dir(s.path))
+		s.setStorageError(err)
+
+		return err
+	}
+
+	return nil
+}
+
+// setStorageError records the current state storage error, clearing it if nil.
+func (s *State) setStorageError(err error) {
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+
+	if err != nil && s.storageError == nil {
+		slog.Error("State storage is degraded, changes are only kept in memory", "err", err.Error())
+	} else if err == nil && s.storageError != nil {
+		slog.Info("State storage has recovered")
+	}
+
+	s.storageError = err
+}
+
+// isStorageError returns whether the error is due to the underlying storage, rather than the state itself.
+func isStorageError(err error) bool {
+	return errors.Is(err, unix.EROFS) || errors.Is(err, unix.EIO) || errors.Is(err, unix.ENOSPC) || errors.Is(err, unix.EDQUOT)
+}
+
 // initialize sets default values for a new state file.
 func (s *State) initialize() error {
 	// Use the default update channel.
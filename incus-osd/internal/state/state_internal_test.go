@@ -1,9 +1,11 @@
 package state
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
 )
 
 // Make sure that we have correctly bumped the schema version.
@@ -12,3 +14,22 @@ func TestSchemaVersion(t *testing.T) {
 
 	require.Equal(t, len(upgrades), currentStateVersion)
 }
+
+// Make sure that a degraded state storage is reported and cleared once the state is saved again.
+func TestStorageError(t *testing.T) {
+	t.Parallel()
+
+	s, err := LoadOrCreate(filepath.Join(t.TempDir(), "state.txt"))
+	require.NoError(t, err)
+	require.NoError(t, s.StorageError())
+	require.NoError(t, s.CheckStorage())
+
+	s.setStorageError(unix.EROFS)
+	require.ErrorIs(t, s.StorageError(), unix.EROFS)
+	require.Len(t, s.EntityWarnings("/1.0/system"), 1)
+
+	// The storage is writable again.
+	require.NoError(t, s.CheckStorage())
+	require.NoError(t, s.StorageError())
+	require.Empty(t, s.EntityWarnings("/1.0/system"))
+}
@@ -1,11 +1,15 @@
 package state_test
 
 import (
+	"context"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
@@ -288,3 +292,370 @@ func TestUpgradeFuncs(t *testing.T) {
 	require.Equal(t, "dhcp4", s.System.Network.Config.Interfaces[0].Addresses[0])
 	require.Equal(t, "dhcp6", s.System.Network.Config.Interfaces[0].Addresses[1])
 }
+
+func TestDataUsage(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+
+	require.Equal(t, int64(0), s.MonthlyDataUsage("images"))
+
+	s.RecordDataUsage("images", 100)
+	s.RecordDataUsage("images", 50)
+	s.RecordDataUsage("operations-center", 10)
+
+	require.Equal(t, int64(150), s.MonthlyDataUsage("images"))
+	require.Equal(t, int64(10), s.MonthlyDataUsage("operations-center"))
+	require.Len(t, s.DataUsage, 2)
+
+	// Entries older than a year are dropped.
+	s.DataUsage = append(s.DataUsage, api.SystemUpdateDataUsage{Month: "2000-01", Provider: "images", Bytes: 1000})
+	s.RecordDataUsage("images", 1)
+
+	require.Len(t, s.DataUsage, 2)
+	require.Equal(t, int64(151), s.MonthlyDataUsage("images"))
+}
+
+func TestSelfCheck(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+
+	report, err := s.SelfCheckReport()
+	require.NoError(t, err)
+	require.Nil(t, report.LastReport)
+	require.Empty(t, report.History)
+
+	for i := range 40 {
+		err := s.RecordSelfCheck(api.SystemSelfCheckReport{
+			Time:   time.Unix(int64(i), 0),
+			Passed: i%2 == 0,
+			Results: []api.SystemSelfCheckResult{
+				{Name: "security", Passed: true},
+				{Name: "storage", Passed: i%2 == 0, Message: "pool is degraded"},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	// The history survives a round trip through the state file.
+	content, err := state.Encode(&s)
+	require.NoError(t, err)
+
+	reloaded := state.State{}
+	err = state.Decode(content, nil, &reloaded)
+	require.NoError(t, err)
+
+	report, err = reloaded.SelfCheckReport()
+	require.NoError(t, err)
+	require.NotNil(t, report.LastReport)
+	require.False(t, report.LastReport.Passed)
+	require.Len(t, report.LastReport.Results, 2)
+	require.Len(t, report.History, 30)
+	require.Equal(t, int64(10), report.History[0].Time.Unix())
+	require.True(t, report.History[0].Passed)
+	require.False(t, report.History[29].Passed)
+	require.Equal(t, []string{"storage"}, report.History[29].Failed)
+}
+
+func TestDebugAccessAllowed(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+
+	// Unrestricted by default.
+	require.True(t, s.DebugAccessAllowed())
+
+	// Restricted without a grant.
+	s.System.Security.Config.RestrictDebug = true
+	require.False(t, s.DebugAccessAllowed())
+
+	// Active grant.
+	s.DebugAccessExpiry = time.Now().Add(time.Hour).Unix()
+	require.True(t, s.DebugAccessAllowed())
+
+	// Expired grant.
+	s.DebugAccessExpiry = time.Now().Add(-time.Minute).Unix()
+	require.False(t, s.DebugAccessAllowed())
+}
+
+func TestSummary(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{Applications: map[string]api.Application{}}
+	s.OS.Name = "IncusOS"
+	s.OS.RunningRelease = "202601010000"
+	s.OS.NextRelease = "202601010000"
+
+	summary := s.Summary()
+	require.Equal(t, "IncusOS", summary.OSName)
+	require.Equal(t, "202601010000", summary.OSVersion)
+	require.Empty(t, summary.OSNextVersion)
+	require.Empty(t, summary.Applications)
+	require.False(t, summary.PendingUpdates)
+
+	// Staged application updates are pending.
+	app := api.Application{}
+	app.State.Version = "202601010000"
+	app.State.StagedVersion = "202601020000"
+	s.SetApplication("incus", app)
+
+	summary = s.Summary()
+	require.Equal(t, map[string]string{"incus": "202601010000"}, summary.Applications)
+	require.True(t, summary.PendingUpdates)
+
+	// As is an OS update waiting for a reboot.
+	app.State.StagedVersion = ""
+	s.SetApplication("incus", app)
+	s.OS.NextRelease = "202601020000"
+	s.System.Update.State.NeedsReboot = true
+
+	summary = s.Summary()
+	require.Equal(t, "202601020000", summary.OSNextVersion)
+	require.True(t, summary.PendingUpdates)
+
+	// And an update which was found but not downloaded yet.
+	s.OS.NextRelease = "202601010000"
+	s.System.Update.State.NeedsReboot = false
+	s.System.Update.State.Available = []api.SystemUpdateAvailable{{Component: "os", Version: "202601030000"}}
+
+	summary = s.Summary()
+	require.True(t, summary.PendingUpdates)
+}
+
+func TestSummaryConcurrentApplications(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{Applications: map[string]api.Application{}}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := range 1000 {
+			s.SetApplication("app"+strconv.Itoa(i%10), api.Application{})
+			s.DeleteApplication("app" + strconv.Itoa((i+5)%10))
+		}
+	}()
+
+	for range 1000 {
+		_ = s.Summary()
+		_ = s.CopyApplications()
+	}
+
+	<-done
+}
+
+func TestWarnings(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{Applications: map[string]api.Application{}}
+	s.System.Security.State.EncryptionRecoveryKeysRetrieved = true
+	s.System.Update.Config.CheckFrequency = "6h"
+
+	// No warnings by default.
+	require.Empty(t, s.Warnings())
+
+	// Each condition is reported against its endpoint.
+	s.System.Security.State.EncryptionRecoveryKeysRetrieved = false
+	s.System.Security.State.VerityVolumes = []api.SystemSecurityVerityVolume{{Volume: "usr", State: "verified"}, {Volume: "usr-a", State: "corrupted"}}
+	s.System.Update.Config.CheckFrequency = "never"
+	s.OS.StagedRelease = "202601020000"
+
+	app := api.Application{}
+	app.State.StagedVersion = "202601020000"
+	s.Applications["incus"] = app
+
+	require.Equal(t, []api.SystemWarning{
+		{Type: api.SystemWarningTypeActionRequired, Entity: "/1.0/system/security", Message: "The encryption recovery keys haven't been retrieved yet"},
+		{Type: api.SystemWarningTypeInsecure, Entity: "/1.0/system/security", Message: "The dm-verity volume usr-a is corrupted"},
+		{Type: api.SystemWarningTypeInsecure, Entity: "/1.0/system/update", Message: "Automatic update checks are disabled, security updates won't be applied"},
+		{Type: api.SystemWarningTypeActionRequired, Entity: "/1.0/system/update", Message: "OS update 202601020000 is staged and waiting to be applied"},
+		{Type: api.SystemWarningTypeActionRequired, Entity: "/1.0/system/update", Message: "Application incus update 202601020000 is staged and waiting to be applied"},
+	}, s.Warnings())
+
+	// Warnings can be filtered by endpoint.
+	require.Len(t, s.EntityWarnings("/1.0/system/security"), 2)
+	require.Len(t, s.EntityWarnings("/1.0/system/update"), 3)
+	require.Empty(t, s.EntityWarnings("/1.0/system"))
+
+	// Debug access is only reported while a grant is active.
+	s.System.Security.Config.RestrictDebug = true
+	require.Len(t, s.EntityWarnings("/1.0/system/security"), 2)
+
+	s.DebugAccessExpiry = time.Now().Add(time.Hour).Unix()
+	require.Len(t, s.EntityWarnings("/1.0/system/security"), 3)
+}
+
+func TestUpdateProgress(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+	require.Nil(t, s.UpdateProgress())
+
+	progress := &api.SystemUpdateProgress{Phase: api.SystemUpdateProgressPhaseDownloading, Component: "os", Version: "202601020000"}
+	s.SetUpdateProgress(progress)
+
+	// The recorded progress is a copy, not affected by later changes of the caller.
+	progress.Phase = api.SystemUpdateProgressPhaseApplying
+	require.Equal(t, api.SystemUpdateProgressPhaseDownloading, s.UpdateProgress().Phase)
+
+	// As is the returned one.
+	s.UpdateProgress().Phase = api.SystemUpdateProgressPhaseVerifying
+	require.Equal(t, api.SystemUpdateProgressPhaseDownloading, s.UpdateProgress().Phase)
+
+	// Concurrent updates and reads are safe.
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := range 100 {
+			s.SetUpdateProgress(&api.SystemUpdateProgress{Phase: api.SystemUpdateProgressPhaseDownloading, BytesTransferred: int64(i)})
+		}
+	}()
+
+	for range 100 {
+		_ = s.UpdateProgress()
+	}
+
+	<-done
+
+	s.SetUpdateProgress(nil)
+	require.Nil(t, s.UpdateProgress())
+}
+
+func TestUpdateApplyTime(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+
+	// First update is recorded as is.
+	s.RecordUpdateApplyTime("os", 60*time.Second)
+	require.Equal(t, int64(60), s.UpdateApplyTimes["os"])
+
+	// Subsequent updates are averaged.
+	s.RecordUpdateApplyTime("os", 120*time.Second)
+	require.Equal(t, int64(90), s.UpdateApplyTimes["os"])
+
+	// Components are tracked separately.
+	s.RecordUpdateApplyTime("incus", 10*time.Second)
+	require.Equal(t, int64(10), s.UpdateApplyTimes["incus"])
+	require.Equal(t, int64(90), s.UpdateApplyTimes["os"])
+}
+
+func TestProvenance(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+
+	records, err := s.ProvenanceRecords()
+	require.NoError(t, err)
+	require.Empty(t, records)
+
+	downloaded := time.Date(2025, 11, 4, 16, 0, 0, 0, time.UTC)
+
+	for i := range 60 {
+		err = s.RecordProvenance(api.SystemUpdateProvenance{Component: "incus", Version: strconv.Itoa(i), Provider: "images", Downloaded: downloaded})
+		require.NoError(t, err)
+	}
+
+	// Downloading the same version again replaces its record.
+	err = s.RecordProvenance(api.SystemUpdateProvenance{Component: "incus", Version: "59", Provider: "local", Downloaded: downloaded})
+	require.NoError(t, err)
+
+	err = s.MarkProvenanceInstalled("incus", "59", downloaded.Add(time.Hour))
+	require.NoError(t, err)
+
+	records, err = s.ProvenanceRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 50)
+	require.Equal(t, "10", records[0].Version)
+	require.Nil(t, records[0].Installed)
+	require.Equal(t, "local", records[49].Provider)
+	require.NotNil(t, records[49].Installed)
+	require.Equal(t, downloaded.Add(time.Hour), *records[49].Installed)
+}
+
+func TestUpdateOperations(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{}
+
+	require.False(t, s.UpdateInProgress())
+	require.False(t, s.CancelUpdateOperations())
+
+	// Completed operations are no longer tracked.
+	ctx, done := s.StartUpdateOperation(t.Context())
+	require.True(t, s.UpdateInProgress())
+
+	done()
+	require.Error(t, ctx.Err())
+	require.False(t, s.UpdateInProgress())
+
+	// Operations in progress get cancelled.
+	ctx, done = s.StartUpdateOperation(t.Context())
+	defer done()
+
+	require.True(t, s.CancelUpdateOperations())
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+	require.False(t, s.UpdateInProgress())
+}
+
+func TestStagedUpdates(t *testing.T) {
+	t.Parallel()
+
+	s := state.State{
+		Applications:    map[string]api.Application{"incus": {}},
+		TriggerApply:    make(chan bool, 1),
+		TriggerUpdate:   make(chan bool, 1),
+		TriggerDownload: make(chan bool, 1),
+	}
+
+	s.System.Update.State.Available = []api.SystemUpdateAvailable{{Component: "os", Version: "202601010000"}, {Component: "incus", Version: "202601010000"}}
+
+	require.False(t, s.HasStagedUpdates())
+	require.Empty(t, s.StagedApplications())
+
+	// Download-only updates are staged.
+	s.OS.StagedRelease = "202601010000"
+	s.SetUpdateStaged("os", true)
+
+	app := s.Applications["incus"]
+	app.State.StagedVersion = "202601010000"
+	s.Applications["incus"] = app
+	s.SetUpdateStaged("incus", true)
+
+	require.True(t, s.HasStagedUpdates())
+	require.Equal(t, map[string]string{"incus": "202601010000"}, s.StagedApplications())
+	require.True(t, s.System.Update.State.Available[0].Staged)
+	require.True(t, s.System.Update.State.Available[1].Staged)
+
+	// Applying them is only requested once, without blocking.
+	require.True(t, s.RequestApply())
+	require.False(t, s.RequestApply())
+
+	<-s.TriggerApply
+
+	require.True(t, s.RequestApply())
+
+	// Discarding the OS update leaves the application update staged.
+	s.OS.StagedRelease = ""
+	s.SetUpdateStaged("os", false)
+
+	require.True(t, s.HasStagedUpdates())
+	require.False(t, s.System.Update.State.Available[0].Staged)
+	require.True(t, s.System.Update.State.Available[1].Staged)
+
+	app.State.StagedVersion = ""
+	s.SetApplication("incus", app)
+
+	require.False(t, s.HasStagedUpdates())
+
+	// Update checks and downloads are requested the same way.
+	require.True(t, s.RequestUpdate())
+	require.False(t, s.RequestUpdate())
+	require.True(t, s.RequestDownload())
+	require.False(t, s.RequestDownload())
+}
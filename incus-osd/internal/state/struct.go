@@ -1,19 +1,25 @@
 package state
 
 import (
+	"context"
+	"encoding/json"
+	"maps"
 	"net"
 	"os"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/events"
 )
 
 // SecureBoot represents the current state of Secure Boot key updates applied to the system.
 type SecureBoot struct {
 	Version      string `json:"version"`
 	FullyApplied bool   `json:"fully_applied"`
+	DbxVersion   string `json:"dbx_version"` // Version of the last applied dbx revocation list update.
 }
 
 // OS represents the current OS image state.
@@ -21,13 +27,60 @@ type OS struct {
 	Name           string `json:"name"`
 	RunningRelease string `json:"running_release"`
 	NextRelease    string `json:"next_release"`
+	StagedRelease  string `json:"staged_release"`
 	SuccessfulBoot bool   `jsno:"successful_boot"`
 }
 
+// ProviderCache represents the last release metadata retrieved from the provider, kept across reboots.
+type ProviderCache struct {
+	Source    string `json:"source"`
+	Channel   string `json:"channel"`
+	LastCheck int64  `json:"last_check"` // Unix timestamp.
+	Release   string `json:"release"`    // JSON encoded.
+	Signer    string `json:"signer"`     // Fingerprint of the certificate which signed the release metadata.
+}
+
+// CertificateRotation tracks the rotation of an application's server certificate.
+type CertificateRotation struct {
+	Fingerprint         string `json:"fingerprint"`          // Certificate currently in use.
+	NextFingerprint     string `json:"next_fingerprint"`     // Staged replacement certificate.
+	NextStaged          int64  `json:"next_staged"`          // Unix timestamp.
+	PreviousFingerprint string `json:"previous_fingerprint"` // Replaced certificate, kept during the overlap window.
+	Rotated             int64  `json:"rotated"`              // Unix timestamp.
+	ExpiryNotified      bool   `json:"expiry_notified"`      // Set once a notification about an upcoming expiry has been sent.
+}
+
+// UpdateTrustAnchors holds the certificates trusted to sign the update metadata, as provided by the seed.
+type UpdateTrustAnchors struct {
+	Certificates   []string `json:"certificates"`    // PEM encoded.
+	ReplaceBuiltin bool     `json:"replace_builtin"` // Don't trust the built-in update signing CA.
+}
+
+// maxProvenanceRecords is the number of downloaded updates whose origin is kept.
+const maxProvenanceRecords = 50
+
+// maxSelfCheckHistory is the number of past self-check runs kept in the history.
+const maxSelfCheckHistory = 30
+
+// SelfCheckRun records the outcome of a past self-check run.
+type SelfCheckRun struct {
+	Time   int64    `json:"time"`   // Unix timestamp.
+	Failed []string `json:"failed"` // Names of the failed checks.
+}
+
+// SelfCheck holds the report of the last self-check run, along with the outcome of the previous runs.
+type SelfCheck struct {
+	Report  string         `json:"report"` // JSON encoded.
+	History []SelfCheckRun `json:"history"`
+}
+
 // State represents the on-disk persistent state.
 type State struct {
 	path string
 
+	storageMutex sync.Mutex
+	storageError error
+
 	StateVersion       int      `json:"-"`
 	UnrecognizedFields []string `json:"-"`
 
@@ -35,10 +88,27 @@ type State struct {
 
 	UpdateMutex sync.Mutex `json:"-"`
 
+	// Guards the applications map against concurrent changes, see SetApplication.
+	applicationsMutex sync.RWMutex
+
+	// Update checks and downloads currently in progress, which can be cancelled.
+	updateOperationsMutex sync.Mutex
+	updateOperations      map[int]context.CancelFunc
+	updateOperationsNext  int
+
+	// Progress of the update currently being downloaded or applied.
+	updateProgressMutex sync.Mutex
+	updateProgress      *api.SystemUpdateProgress
+
 	// Triggers for daemon actions.
 	TriggerReboot   chan error `json:"-"`
 	TriggerShutdown chan error `json:"-"`
 	TriggerUpdate   chan bool  `json:"-"`
+	TriggerDownload chan bool  `json:"-"`
+	TriggerApply    chan bool  `json:"-"`
+
+	// Distribution of events to API subscribers.
+	Events *events.Server `json:"-"`
 
 	SecureBoot SecureBoot `json:"secure_boot"`
 
@@ -46,24 +116,61 @@ type State struct {
 
 	OS OS `json:"os"`
 
+	DataUsage []api.SystemUpdateDataUsage `json:"data_usage"`
+
+	UpdateApplyTimes map[string]int64 `json:"update_apply_times"` // Average number of seconds taken to apply an update, by component.
+
+	ProviderCache ProviderCache `json:"provider_cache"`
+
+	DebugAccessExpiry int64 `json:"debug_access_expiry"` // Unix timestamp.
+
+	NetworkUnlockBinding string `json:"network_unlock_binding"` // JSON encoded network unlock configuration currently bound to the encrypted volumes.
+
+	Provenance string `json:"provenance"` // JSON encoded list of the origin of the recently downloaded OS and application updates.
+
+	InstallGeometry string `json:"install_geometry"` // JSON encoded geometry of the drive IncusOS is installed on, recorded on first boot.
+
+	FirmwareBaseline string `json:"firmware_baseline"` // JSON encoded firmware measurements from the last boot where the TPM could unlock the encrypted volumes.
+
+	SecretsKey string `json:"secrets_key"` // Key of the secrets vault, sealed to the TPM and host key through systemd-creds.
+
+	SecretsKeyFallback string `json:"secrets_key_fallback"` // Copy of the secrets vault key sealed to the host key only, used if it can't be unsealed from the TPM.
+
+	PassphraseOnly bool `json:"passphrase_only"` // Set on systems installed without a TPM, whose encrypted volumes are only protected by passphrases.
+
+	UpdateTrustAnchors UpdateTrustAnchors `json:"update_trust_anchors"`
+
+	Certificates map[string]CertificateRotation `json:"certificates"`
+
+	SelfCheck SelfCheck `json:"self_check"`
+
 	Services struct {
 		Ceph      api.ServiceCeph      `json:"ceph"`
+		Console   api.ServiceConsole   `json:"console"`
 		ISCSI     api.ServiceISCSI     `json:"iscsi"`
+		Kdump     api.ServiceKdump     `json:"kdump"`
 		Linstor   api.ServiceLinstor   `json:"linstor"`
 		LVM       api.ServiceLVM       `json:"lvm"`
 		Multipath api.ServiceMultipath `json:"multipath"`
+		Nftables  api.ServiceNftables  `json:"nftables"`
+		NTP       api.ServiceNTP       `json:"ntp"`
 		NVME      api.ServiceNVME      `json:"nvme"`
 		OVN       api.ServiceOVN       `json:"ovn"`
+		SSH       api.ServiceSSH       `json:"ssh"`
 		Tailscale api.ServiceTailscale `json:"tailscale"`
+		Tuning    api.ServiceTuning    `json:"tuning"`
 		USBIP     api.ServiceUSBIP     `json:"usbip"`
+		VRRP      api.ServiceVRRP      `json:"vrrp"`
 	} `json:"services"`
 
 	System struct {
-		Logging  api.SystemLogging  `json:"logging"`
-		Network  api.SystemNetwork  `json:"network"`
-		Provider api.SystemProvider `json:"provider"`
-		Security api.SystemSecurity `json:"security"`
-		Update   api.SystemUpdate   `json:"update"`
+		DNS           api.SystemDNS           `json:"dns"`
+		Logging       api.SystemLogging       `json:"logging"`
+		Network       api.SystemNetwork       `json:"network"`
+		Notifications api.SystemNotifications `json:"notifications"`
+		Provider      api.SystemProvider      `json:"provider"`
+		Security      api.SystemSecurity      `json:"security"`
+		Update        api.SystemUpdate        `json:"update"`
 	} `json:"system"`
 }
 
@@ -97,6 +204,467 @@ func (s *State) Hostname() string {
 	return s.OS.Name
 }
 
+// Summary returns a summary of the current system status suitable for consumption by applications.
+func (s *State) Summary() api.StatusSummary {
+	summary := api.StatusSummary{
+		OSName:          s.OS.Name,
+		OSVersion:       s.OS.RunningRelease,
+		OSStagedVersion: s.OS.StagedRelease,
+		Applications:    map[string]string{},
+		NeedsReboot:     s.System.Update.State.NeedsReboot,
+		UpdateStatus:    s.System.Update.State.Status,
+		LastUpdateCheck: s.System.Update.State.LastCheck,
+		GeneratedAt:     time.Now(),
+	}
+
+	if s.OS.NextRelease != s.OS.RunningRelease {
+		summary.OSNextVersion = s.OS.NextRelease
+	}
+
+	// Updates found by the last check are pending, whether or not they were downloaded yet.
+	summary.PendingUpdates = summary.NeedsReboot || summary.OSStagedVersion != "" || len(s.System.Update.State.Available) > 0
+
+	s.applicationsMutex.RLock()
+	defer s.applicationsMutex.RUnlock()
+
+	for name, app := range s.Applications {
+		summary.Applications[name] = app.State.Version
+
+		if app.State.StagedVersion != "" {
+			summary.PendingUpdates = true
+		}
+	}
+
+	return summary
+}
+
+// SetApplication adds or replaces an application. The applications map must only be modified through it
+// and DeleteApplication, so it can be read safely from the background tasks.
+func (s *State) SetApplication(name string, app api.Application) {
+	s.applicationsMutex.Lock()
+	defer s.applicationsMutex.Unlock()
+
+	s.Applications[name] = app
+}
+
+// CopyApplications returns a copy of the applications map, which is safe to go through while it's being modified.
+func (s *State) CopyApplications() map[string]api.Application {
+	s.applicationsMutex.RLock()
+	defer s.applicationsMutex.RUnlock()
+
+	return maps.Clone(s.Applications)
+}
+
+// DeleteApplication removes an application.
+func (s *State) DeleteApplication(name string) {
+	s.applicationsMutex.Lock()
+	defer s.applicationsMutex.Unlock()
+
+	delete(s.Applications, name)
+}
+
+// Warnings returns the list of current warnings about the system's configuration or state.
+func (s *State) Warnings() []api.SystemWarning {
+	warnings := []api.SystemWarning{}
+
+	addWarning := func(warningType api.SystemWarningType, entity string, message string) {
+		warnings = append(warnings, api.SystemWarning{Type: warningType, Entity: entity, Message: message})
+	}
+
+	// System.
+	if s.StorageError() != nil {
+		addWarning(api.SystemWarningTypeActionRequired, "/1.0/system", "The state storage is unavailable, configuration changes are refused until it recovers")
+	}
+
+	// Security.
+	if !s.System.Security.State.EncryptionRecoveryKeysRetrieved {
+		addWarning(api.SystemWarningTypeActionRequired, "/1.0/system/security", "The encryption recovery keys haven't been retrieved yet")
+	}
+
+	if s.PassphraseOnly {
+		addWarning(api.SystemWarningTypeInsecure, "/1.0/system/security", "No TPM is available, the encrypted volumes are only protected by a passphrase")
+	}
+
+	if s.System.Security.State.BootOrder.Drifted {
+		addWarning(api.SystemWarningTypeActionRequired, "/1.0/system/security", "The EFI boot order doesn't start with the IncusOS boot entry")
+	}
+
+	if s.System.Security.Config.RestrictDebug && s.DebugAccessAllowed() {
+		addWarning(api.SystemWarningTypeInsecure, "/1.0/system/security", "Debug access has been granted until "+time.Unix(s.DebugAccessExpiry, 0).UTC().Format(time.RFC3339))
+	}
+
+	for _, volume := range s.System.Security.State.VerityVolumes {
+		if volume.State != "verified" {
+			addWarning(api.SystemWarningTypeInsecure, "/1.0/system/security", "The dm-verity volume "+volume.Volume+" is "+volume.State)
+		}
+	}
+
+	// Updates.
+	if s.System.Update.Config.CheckFrequency == "never" {
+		addWarning(api.SystemWarningTypeInsecure, "/1.0/system/update", "Automatic update checks are disabled, security updates won't be applied")
+	}
+
+	if s.System.Update.State.NeedsReboot {
+		addWarning(api.SystemWarningTypeActionRequired, "/1.0/system/update", "A reboot is required to finalize an update")
+	}
+
+	if s.OS.StagedRelease != "" {
+		addWarning(api.SystemWarningTypeActionRequired, "/1.0/system/update", "OS update "+s.OS.StagedRelease+" is staged and waiting to be applied")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.Applications)) {
+		if s.Applications[name].State.StagedVersion != "" {
+			addWarning(api.SystemWarningTypeActionRequired, "/1.0/system/update", "Application "+name+" update "+s.Applications[name].State.StagedVersion+" is staged and waiting to be applied")
+		}
+	}
+
+	return warnings
+}
+
+// EntityWarnings returns the list of current warnings related to the given API endpoint.
+func (s *State) EntityWarnings(entity string) []api.SystemWarning {
+	warnings := []api.SystemWarning{}
+
+	for _, warning := range s.Warnings() {
+		if warning.Entity == entity {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	return warnings
+}
+
+// DebugAccessAllowed returns true if the debug endpoints can currently be used, either because they
+// aren't restricted or because a time-limited grant is active.
+func (s *State) DebugAccessAllowed() bool {
+	return !s.System.Security.Config.RestrictDebug || time.Now().Unix() < s.DebugAccessExpiry
+}
+
+// MonthlyDataUsage returns the amount of data downloaded from the named provider during the current month.
+func (s *State) MonthlyDataUsage(provider string) int64 {
+	month := time.Now().Format("2006-01")
+
+	for _, usage := range s.DataUsage {
+		if usage.Month == month && usage.Provider == provider {
+			return usage.Bytes
+		}
+	}
+
+	return 0
+}
+
+// RecordDataUsage accounts for data downloaded from the named provider, only keeping the last year of history.
+func (s *State) RecordDataUsage(provider string, size int64) {
+	now := time.Now()
+	month := now.Format("2006-01")
+	oldestMonth := now.AddDate(-1, 0, 0).Format("2006-01")
+
+	found := false
+	newUsage := []api.SystemUpdateDataUsage{}
+
+	for _, usage := range s.DataUsage {
+		// Drop old entries.
+		if usage.Month <= oldestMonth {
+			continue
+		}
+
+		if usage.Month == month && usage.Provider == provider {
+			usage.Bytes += size
+			found = true
+		}
+
+		newUsage = append(newUsage, usage)
+	}
+
+	if !found {
+		newUsage = append(newUsage, api.SystemUpdateDataUsage{Month: month, Provider: provider, Bytes: size})
+	}
+
+	s.DataUsage = newUsage
+}
+
+// RecordUpdateApplyTime accounts for the time taken to apply an update to the given component, weighting
+// recent updates more heavily.
+func (s *State) RecordUpdateApplyTime(component string, duration time.Duration) {
+	if s.UpdateApplyTimes == nil {
+		s.UpdateApplyTimes = map[string]int64{}
+	}
+
+	seconds := int64(duration.Seconds())
+
+	previous, ok := s.UpdateApplyTimes[component]
+	if ok {
+		seconds = (previous + seconds) / 2
+	}
+
+	s.UpdateApplyTimes[component] = seconds
+}
+
+// StartUpdateOperation returns a context for an update check or download which is cancelled by
+// CancelUpdateOperations, along with a function to call once the operation is complete.
+func (s *State) StartUpdateOperation(ctx context.Context) (context.Context, func()) {
+	s.updateOperationsMutex.Lock()
+	defer s.updateOperationsMutex.Unlock()
+
+	if s.updateOperations == nil {
+		s.updateOperations = map[int]context.CancelFunc{}
+	}
+
+	opCtx, cancel := context.WithCancel(ctx)
+
+	id := s.updateOperationsNext
+	s.updateOperationsNext++
+	s.updateOperations[id] = cancel
+
+	return opCtx, func() {
+		s.updateOperationsMutex.Lock()
+		defer s.updateOperationsMutex.Unlock()
+
+		delete(s.updateOperations, id)
+		cancel()
+	}
+}
+
+// UpdateInProgress returns whether an update check or download is currently in progress.
+func (s *State) UpdateInProgress() bool {
+	s.updateOperationsMutex.Lock()
+	defer s.updateOperationsMutex.Unlock()
+
+	return len(s.updateOperations) > 0
+}
+
+// CancelUpdateOperations cancels all the update checks and downloads currently in progress, returning
+// whether there were any.
+func (s *State) CancelUpdateOperations() bool {
+	s.updateOperationsMutex.Lock()
+	defer s.updateOperationsMutex.Unlock()
+
+	cancelled := len(s.updateOperations) > 0
+
+	for id, cancel := range s.updateOperations {
+		cancel()
+		delete(s.updateOperations, id)
+	}
+
+	return cancelled
+}
+
+// UpdateProgress returns a copy of the progress of the update currently being downloaded or applied, or nil
+// if there's none.
+func (s *State) UpdateProgress() *api.SystemUpdateProgress {
+	s.updateProgressMutex.Lock()
+	defer s.updateProgressMutex.Unlock()
+
+	if s.updateProgress == nil {
+		return nil
+	}
+
+	progress := *s.updateProgress
+
+	return &progress
+}
+
+// SetUpdateProgress records the progress of the update currently being downloaded or applied. A nil value
+// clears it.
+func (s *State) SetUpdateProgress(progress *api.SystemUpdateProgress) {
+	s.updateProgressMutex.Lock()
+	defer s.updateProgressMutex.Unlock()
+
+	if progress == nil {
+		s.updateProgress = nil
+
+		return
+	}
+
+	newProgress := *progress
+	s.updateProgress = &newProgress
+}
+
+// StagedApplications returns the application updates which have been downloaded but not yet applied.
+func (s *State) StagedApplications() map[string]string {
+	ret := map[string]string{}
+
+	for name, app := range s.Applications {
+		if app.State.StagedVersion != "" {
+			ret[name] = app.State.StagedVersion
+		}
+	}
+
+	return ret
+}
+
+// HasStagedUpdates returns true if any OS or application update has been downloaded but not yet applied.
+func (s *State) HasStagedUpdates() bool {
+	return s.OS.StagedRelease != "" || len(s.StagedApplications()) > 0
+}
+
+// SetUpdateStaged flags whether the available update of a component has been downloaded.
+func (s *State) SetUpdateStaged(component string, staged bool) {
+	for i, available := range s.System.Update.State.Available {
+		if available.Component == component {
+			s.System.Update.State.Available[i].Staged = staged
+		}
+	}
+}
+
+// RequestUpdate asks for an update check without waiting for it, returning false if one is already pending.
+func (s *State) RequestUpdate() bool {
+	return trigger(s.TriggerUpdate)
+}
+
+// RequestDownload asks for the available updates to be downloaded without waiting for it, returning false if a
+// request is already pending.
+func (s *State) RequestDownload() bool {
+	return trigger(s.TriggerDownload)
+}
+
+// RequestApply asks for the staged updates to be applied without waiting for it, returning false if a
+// request is already pending.
+func (s *State) RequestApply() bool {
+	return trigger(s.TriggerApply)
+}
+
+// trigger sends a request to a daemon action handler, unless one is already pending.
+func trigger(ch chan bool) bool {
+	select {
+	case ch <- true:
+		return true
+	default:
+		return false
+	}
+}
+
+// ProvenanceRecords returns the origin of the recently downloaded OS and application updates, most recent last.
+func (s *State) ProvenanceRecords() ([]api.SystemUpdateProvenance, error) {
+	records := []api.SystemUpdateProvenance{}
+
+	if s.Provenance == "" {
+		return records, nil
+	}
+
+	err := json.Unmarshal([]byte(s.Provenance), &records)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// RecordProvenance records the origin of a downloaded update, replacing any previous record for the same version.
+func (s *State) RecordProvenance(record api.SystemUpdateProvenance) error {
+	records, err := s.ProvenanceRecords()
+	if err != nil {
+		return err
+	}
+
+	records = slices.DeleteFunc(records, func(entry api.SystemUpdateProvenance) bool {
+		return entry.Component == record.Component && entry.Version == record.Version
+	})
+
+	records = append(records, record)
+
+	// Drop the oldest entries.
+	if len(records) > maxProvenanceRecords {
+		records = records[len(records)-maxProvenanceRecords:]
+	}
+
+	return s.setProvenanceRecords(records)
+}
+
+// MarkProvenanceInstalled records when a downloaded update got applied.
+func (s *State) MarkProvenanceInstalled(component string, version string, installed time.Time) error {
+	records, err := s.ProvenanceRecords()
+	if err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		if record.Component == component && record.Version == version {
+			installed = installed.UTC()
+			records[i].Installed = &installed
+		}
+	}
+
+	return s.setProvenanceRecords(records)
+}
+
+// setProvenanceRecords stores the origin of the recently downloaded updates.
+func (s *State) setProvenanceRecords(records []api.SystemUpdateProvenance) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	s.Provenance = string(body)
+
+	return nil
+}
+
+// RecordSelfCheck stores the report of a self-check run, adding its outcome to the history.
+func (s *State) RecordSelfCheck(report api.SystemSelfCheckReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	run := SelfCheckRun{Time: report.Time.Unix(), Failed: []string{}}
+
+	for _, result := range report.Results {
+		if !result.Passed {
+			run.Failed = append(run.Failed, result.Name)
+		}
+	}
+
+	s.SelfCheck.Report = string(body)
+	s.SelfCheck.History = append(s.SelfCheck.History, run)
+
+	// Drop the oldest entries.
+	if len(s.SelfCheck.History) > maxSelfCheckHistory {
+		s.SelfCheck.History = s.SelfCheck.History[len(s.SelfCheck.History)-maxSelfCheckHistory:]
+	}
+
+	return nil
+}
+
+// SelfCheckReport returns the report of the last self-check run, along with the outcome of the previous runs.
+func (s *State) SelfCheckReport() (api.SystemSelfCheck, error) {
+	ret := api.SystemSelfCheck{History: []api.SystemSelfCheckHistoryEntry{}}
+
+	if s.SelfCheck.Report != "" {
+		report := api.SystemSelfCheckReport{}
+
+		err := json.Unmarshal([]byte(s.SelfCheck.Report), &report)
+		if err != nil {
+			return ret, err
+		}
+
+		ret.LastReport = &report
+	}
+
+	for _, run := range s.SelfCheck.History {
+		ret.History = append(ret.History, api.SystemSelfCheckHistoryEntry{
+			Time:   time.Unix(run.Time, 0).UTC(),
+			Passed: len(run.Failed) == 0,
+			Failed: run.Failed,
+		})
+	}
+
+	return ret, nil
+}
+
+// ListenAddress returns the address on which management services should listen for the given port.
+// This is all addresses, unless the management plane is dedicated to a single network device.
+func (s *State) ListenAddress(port string) string {
+	if s.System.Network.Config != nil && s.System.Network.Config.DedicatedManagement {
+		mgmtAddr := s.ManagementAddress()
+		if mgmtAddr != nil {
+			return net.JoinHostPort(mgmtAddr.String(), port)
+		}
+	}
+
+	return ":" + port
+}
+
 // ManagementAddress returns the preferred IP address at which to reach this server for management purposes.
 // A nil value is returned if none could be found.
 func (s *State) ManagementAddress() net.IP {
@@ -3,13 +3,14 @@ package storage
 // Originally copied from https://github.com/lxc/incus/blob/main/internal/linux/discard.go
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 
-	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
 // IsBlockdev checks if the provided file is a block device.
@@ -22,7 +23,7 @@ func IsBlockdev(fm os.FileMode) bool {
 // For blocks, it will attempt a variety of discard options, validating the result with marker files and eventually fallback to full zero-ing.
 //
 // An offset can be specified to only reset a part of a device.
-func ClearBlock(blockPath string, blockOffset int64) error {
+func ClearBlock(ctx context.Context, blockPath string, blockOffset int64) error {
 	// Open the block device for checking.
 	fd, err := os.OpenFile(blockPath, os.O_RDWR, 0o644) //nolint:gosec
 	if err != nil {
@@ -163,7 +164,7 @@ func ClearBlock(blockPath string, blockOffset int64) error {
 	_ = fd.Close()
 
 	// Attempt a secure discard run.
-	_, err = subprocess.RunCommand("blkdiscard", "-f", "-o", strconv.FormatInt(blockOffset, 10), "-s", blockPath)
+	_, err = timeout.RunCommand(ctx, "blkdiscard", "-f", "-o", strconv.FormatInt(blockOffset, 10), "-s", blockPath)
 	if err == nil {
 		// Check if the markers are gone.
 		fd, err := os.Open(blockPath) //nolint:gosec
@@ -188,7 +189,7 @@ func ClearBlock(blockPath string, blockOffset int64) error {
 	}
 
 	// Attempt a regular discard run.
-	_, err = subprocess.RunCommand("blkdiscard", "-f", "-o", strconv.FormatInt(blockOffset, 10), blockPath)
+	_, err = timeout.RunCommand(ctx, "blkdiscard", "-f", "-o", strconv.FormatInt(blockOffset, 10), blockPath)
 	if err == nil {
 		// Check if the markers are gone.
 		fd, err := os.Open(blockPath) //nolint:gosec
@@ -213,7 +214,7 @@ func ClearBlock(blockPath string, blockOffset int64) error {
 	}
 
 	// Attempt device zero-ing.
-	_, err = subprocess.RunCommand("blkdiscard", "-f", "-o", strconv.FormatInt(blockOffset, 10), "-z", blockPath)
+	_, err = timeout.RunCommand(ctx, "blkdiscard", "-f", "-o", strconv.FormatInt(blockOffset, 10), "-z", blockPath)
 	if err == nil {
 		// Check if the markers are gone.
 		fd, err := os.Open(blockPath) //nolint:gosec
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// nvmeNamespaceRegex matches the namespace index in the name of an NVMe block device.
+var nvmeNamespaceRegex = regexp.MustCompile(`nvme\d+n(\d+)$`)
+
+// GetDriveGeometry returns the sector sizes of a drive and, for NVMe drives, the ID of its namespace.
+func GetDriveGeometry(device string) (api.SystemStorageDriveGeometry, error) {
+	ret := api.SystemStorageDriveGeometry{
+		Device: device,
+	}
+
+	sysPath := filepath.Join("/sys/class/block", filepath.Base(device))
+
+	var err error
+
+	ret.LogicalBlockSize, err = readSysfsInt(filepath.Join(sysPath, "queue", "logical_block_size"))
+	if err != nil {
+		return ret, err
+	}
+
+	ret.PhysicalBlockSize, err = readSysfsInt(filepath.Join(sysPath, "queue", "physical_block_size"))
+	if err != nil {
+		return ret, err
+	}
+
+	// Prefer the namespace ID reported by the kernel, which may differ from the device name.
+	nsid, err := readSysfsInt(filepath.Join(sysPath, "nsid"))
+	if err == nil {
+		ret.NVMeNamespace = nsid
+	} else {
+		ret.NVMeNamespace = GetNVMeNamespace(device)
+	}
+
+	return ret, nil
+}
+
+// GetNVMeNamespace returns the namespace index from the name of an NVMe block device, or zero for other devices.
+func GetNVMeNamespace(device string) int {
+	match := nvmeNamespaceRegex.FindStringSubmatch(device)
+	if match == nil {
+		return 0
+	}
+
+	nsid, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+
+	return nsid
+}
+
+// readSysfsInt reads an integer value from a sysfs file.
+func readSysfsInt(path string) (int, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
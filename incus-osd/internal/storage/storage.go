@@ -15,14 +15,17 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
 // BlockDevices stores specific fields for each device reported by `lsblk`.
 type BlockDevices struct {
-	KName string `json:"kname"`
-	ID    string `json:"id-link"` //nolint:tagliatelle
-	Size  int    `json:"size"`
-	RM    bool   `json:"rm"`
+	KName  string `json:"kname"`
+	ID     string `json:"id-link"` //nolint:tagliatelle
+	Size   int    `json:"size"`
+	RM     bool   `json:"rm"`
+	Serial string `json:"serial"`
+	WWN    string `json:"wwn"`
 }
 
 // LsblkOutput stores the output of running `lsblk -J ...`.
@@ -181,6 +184,69 @@ func GetFreeSpaceInGiB(path string) (float64, error) {
 	return float64(s.Bsize*int64(s.Bfree)) / 1024.0 / 1024.0 / 1024.0, nil //nolint:gosec
 }
 
+// InsufficientSpaceError is returned when a filesystem doesn't have enough free space for an operation.
+type InsufficientSpaceError struct {
+	Path      string
+	Required  int64
+	Available int64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("insufficient free space in %s: %d bytes required, %d bytes available", e.Path, e.Required, e.Available)
+}
+
+// updateExpansionFactor is the assumed upper bound of the ratio between the decompressed and compressed size of
+// update files. Update files are gzip-compressed, but their metadata only records the compressed size.
+const updateExpansionFactor = 4
+
+// DecompressedSize returns an estimate of the disk space taken by update files once decompressed, based on
+// their compressed size. The size of files which aren't compressed is returned as-is.
+func DecompressedSize(size int64, compressed bool) int64 {
+	if !compressed {
+		return size
+	}
+
+	return size * updateExpansionFactor
+}
+
+// RequiredSpace returns the free space needed to download the requested number of bytes, including a safety
+// margin of 10% and 128MiB.
+func RequiredSpace(size int64) int64 {
+	return size + size/10 + 128*1024*1024
+}
+
+// CheckFreeSpace verifies that the filesystem holding the given path has room for the requested number of bytes,
+// plus a safety margin. The path doesn't need to exist yet, in which case its closest existing parent is checked.
+func CheckFreeSpace(path string, size int64) error {
+	required := RequiredSpace(size)
+
+	// Find the closest existing parent.
+	checkPath := path
+
+	for {
+		_, err := os.Stat(checkPath)
+		if err == nil || checkPath == filepath.Dir(checkPath) {
+			break
+		}
+
+		checkPath = filepath.Dir(checkPath)
+	}
+
+	var s unix.Statfs_t
+
+	err := unix.Statfs(checkPath, &s)
+	if err != nil {
+		return err
+	}
+
+	available := s.Bsize * int64(s.Bavail) //nolint:gosec
+	if available < required {
+		return &InsufficientSpaceError{Path: path, Required: required, Available: available}
+	}
+
+	return nil
+}
+
 // DeviceToID takes a device path like /dev/sda and determines its "by-id" mapping, for example /dev/disk/by-id/scsi-0QEMU_QEMU_HARDDISK_incus_root.
 func DeviceToID(ctx context.Context, device string) (string, error) {
 	if device == "" {
@@ -192,7 +258,7 @@ func DeviceToID(ctx context.Context, device string) (string, error) {
 		return device, nil
 	}
 
-	output, err := subprocess.RunCommandContext(ctx, "udevadm", "info", "-q", "symlink", device)
+	output, err := timeout.RunCommand(ctx, "udevadm", "info", "-q", "symlink", device)
 	if err != nil {
 		return "", err
 	}
@@ -214,14 +280,14 @@ func DeviceToID(ctx context.Context, device string) (string, error) {
 
 // PoolExists checks if a given ZFS pool exists.
 func PoolExists(ctx context.Context, zpoolName string) bool {
-	_, err := subprocess.RunCommandContext(ctx, "zpool", "status", zpoolName)
+	_, err := timeout.RunCommand(ctx, "zpool", "status", zpoolName)
 
 	return err == nil
 }
 
 // DatasetExists checks if a given ZFS dataset exists.
 func DatasetExists(ctx context.Context, datasetName string) bool {
-	_, err := subprocess.RunCommandContext(ctx, "zfs", "list", datasetName)
+	_, err := timeout.RunCommand(ctx, "zfs", "list", datasetName)
 
 	return err == nil
 }
@@ -229,7 +295,7 @@ func DatasetExists(ctx context.Context, datasetName string) bool {
 // GetZpoolMembers returns an instantiated SystemStoragePool struct for the specified storage pool.
 // Logically it makes more sense for this to be in the zfs package, but that would cause an import loop.
 func GetZpoolMembers(ctx context.Context, zpoolName string) (api.SystemStoragePool, error) {
-	output, err := subprocess.RunCommandContext(ctx, "zpool", "status", zpoolName, "-jp", "--json-int")
+	output, err := timeout.RunCommand(ctx, "zpool", "status", zpoolName, "-jp", "--json-int")
 	if err != nil {
 		return api.SystemStoragePool{}, err
 	}
@@ -246,7 +312,7 @@ func getZpoolMembersHelper(ctx context.Context, rawJSONContent []byte, zpoolName
 	}
 
 	// Get the encryption key status.
-	zfsGetOutput, err := subprocess.RunCommandContext(ctx, "zfs", "get", "keystatus", zpoolName, "-j")
+	zfsGetOutput, err := timeout.RunCommand(ctx, "zfs", "get", "keystatus", zpoolName, "-j")
 	if err != nil {
 		return api.SystemStoragePool{}, err
 	}
@@ -337,7 +403,7 @@ func getZpoolMembersHelper(ctx context.Context, rawJSONContent []byte, zpoolName
 	}
 
 	// Get ZFS datasets and fill in volumes.
-	zfsListOutput, err := subprocess.RunCommandContext(ctx, "zfs", "list", "-r", "-d1", zpoolName, "-o", "name,quota,used,incusos:use", "-j", "--json-int")
+	zfsListOutput, err := timeout.RunCommand(ctx, "zfs", "list", "-r", "-d1", zpoolName, "-o", "name,quota,used,incusos:use", "-j", "--json-int")
 	if err != nil {
 		return api.SystemStoragePool{}, err
 	}
@@ -417,7 +483,7 @@ func GetStorageInfo(ctx context.Context) (api.SystemStorage, error) {
 	}
 
 	// Get the status of the zpool(s).
-	zpoolOutput, err := subprocess.RunCommandContext(ctx, "zpool", "status", "-jp", "--json-int")
+	zpoolOutput, err := timeout.RunCommand(ctx, "zpool", "status", "-jp", "--json-int")
 	if err != nil {
 		return ret, err
 	}
@@ -442,7 +508,7 @@ func GetStorageInfo(ctx context.Context) (api.SystemStorage, error) {
 	// Get a list of all local drives.
 	// Note that while we can get the VENDOR field from lsblk, it seems to return generic values like "ATA" which isn't useful.
 	// Exclude devices with major numbers 1 (RAM disk), 2 (floppy disks), 7 (loopback), 230 (zvols)
-	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-JMpdb", "-e", "1,2,7,230", "-o", "KNAME,SIZE,RM")
+	output, err := timeout.RunCommand(ctx, "lsblk", "-JMpdb", "-e", "1,2,7,230", "-o", "KNAME,SIZE,RM")
 	if err != nil {
 		return ret, err
 	}
@@ -673,7 +739,7 @@ func WipeDrive(ctx context.Context, drive string) error {
 			}
 
 			// Wipe the drive.
-			return ClearBlock(drive, 0)
+			return ClearBlock(ctx, drive, 0)
 		}
 	}
 
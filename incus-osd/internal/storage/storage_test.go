@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestDecompressedSize(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, int64(0), DecompressedSize(0, true))
+	require.Equal(t, int64(400*1024*1024), DecompressedSize(100*1024*1024, true))
+
+	// Files which aren't compressed aren't expanded.
+	require.Equal(t, int64(100*1024*1024), DecompressedSize(100*1024*1024, false))
+
+	// The safety margin applies on top of the decompressed size.
+	require.Equal(t, int64(440*1024*1024+128*1024*1024), RequiredSpace(DecompressedSize(100*1024*1024, true)))
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	var s unix.Statfs_t
+
+	err := unix.Statfs(tmpDir, &s)
+	require.NoError(t, err)
+
+	available := s.Bsize * int64(s.Bavail) //nolint:gosec
+	if available < 512*1024*1024 {
+		t.Skip("Not enough free space to run the test")
+	}
+
+	// The path doesn't need to exist yet.
+	path := filepath.Join(tmpDir, "missing", "update")
+
+	err = CheckFreeSpace(path, 0)
+	require.NoError(t, err)
+
+	// A compressed size which would fit is rejected once accounting for decompression.
+	size := (available - 128*1024*1024) / 2
+
+	err = CheckFreeSpace(path, size)
+	require.NoError(t, err)
+
+	err = CheckFreeSpace(path, DecompressedSize(size, true))
+
+	var spaceErr *InsufficientSpaceError
+
+	require.ErrorAs(t, err, &spaceErr)
+	require.Equal(t, path, spaceErr.Path)
+	require.Equal(t, RequiredSpace(DecompressedSize(size, true)), spaceErr.Required)
+}
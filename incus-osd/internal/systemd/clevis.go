@@ -0,0 +1,253 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// ErrTPMUnlockDisabled is returned when managing encryption keys, which requires unlocking through the TPM, while it is disabled.
+var ErrTPMUnlockDisabled = errors.New("encryption keys can't be managed while TPM unlocking is disabled")
+
+// clevisSlotRegex matches the LUKS slots bound by clevis to Tang servers, as listed by "clevis luks list".
+var clevisSlotRegex = regexp.MustCompile(`^([0-9]+): (sss|tang) `)
+
+// ValidateNetworkUnlock checks that the provided network unlock configuration is usable.
+func ValidateNetworkUnlock(config *api.SystemSecurityNetworkUnlock) error {
+	if config == nil {
+		return nil
+	}
+
+	// The initrd has no network access, so the Tang servers can't unlock the encrypted volumes at boot
+	// and the TPM must remain available.
+	if config.TPM == "disabled" {
+		return errors.New("TPM unlocking can't be disabled, as the Tang servers can't be reached during boot")
+	}
+
+	if config.TPM != "" && config.TPM != "fallback" {
+		return fmt.Errorf("invalid TPM mode %q, must be \"fallback\"", config.TPM)
+	}
+
+	if len(config.TangServers) == 0 {
+		return nil
+	}
+
+	if config.Threshold < 0 || config.Threshold > len(config.TangServers) {
+		return fmt.Errorf("invalid threshold %d, must be between 1 and the number of Tang servers", config.Threshold)
+	}
+
+	for _, server := range config.TangServers {
+		u, err := url.Parse(server.URL)
+		if err != nil {
+			return fmt.Errorf("invalid Tang server URL %q: %w", server.URL, err)
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid Tang server URL %q", server.URL)
+		}
+	}
+
+	return nil
+}
+
+// TPMUnlockDisabled returns whether the encrypted volumes have been configured to only unlock through Tang servers.
+func TPMUnlockDisabled(s *state.State) bool {
+	config := s.System.Security.Config.NetworkUnlock
+
+	return config != nil && len(config.TangServers) > 0 && config.TPM == "disabled"
+}
+
+// ApplyNetworkUnlock binds the root and swap LUKS volumes to the configured Tang servers, replacing
+// any existing binding, and removes or restores the TPM key slot depending on the configured TPM mode.
+// Nothing is done if the configuration is already applied.
+func ApplyNetworkUnlock(ctx context.Context, s *state.State) error {
+	config := s.System.Security.Config.NetworkUnlock
+
+	err := ValidateNetworkUnlock(config)
+	if err != nil {
+		return err
+	}
+
+	binding := ""
+
+	if config != nil && len(config.TangServers) > 0 {
+		content, err := json.Marshal(config)
+		if err != nil {
+			return err
+		}
+
+		binding = string(content)
+	}
+
+	if binding == s.NetworkUnlockBinding {
+		return nil
+	}
+
+	if len(s.System.Security.Config.EncryptionRecoveryKeys) == 0 {
+		return errors.New("no encryption recovery key available")
+	}
+
+	// Determine whether the TPM key slot was previously removed.
+	previous := &api.SystemSecurityNetworkUnlock{}
+
+	if s.NetworkUnlockBinding != "" {
+		err = json.Unmarshal([]byte(s.NetworkUnlockBinding), previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if err != nil {
+		return err
+	}
+
+	// clevis reads the existing passphrase from a file.
+	keyFile, err := os.CreateTemp("/run", "incus-osd-luks-")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(keyFile.Name())
+
+	_, err = keyFile.WriteString(s.System.Security.Config.EncryptionRecoveryKeys[0])
+	if err != nil {
+		_ = keyFile.Close()
+
+		return err
+	}
+
+	err = keyFile.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range luksVolumes {
+		err = unbindTang(ctx, volume)
+		if err != nil {
+			return err
+		}
+
+		if binding == "" {
+			continue
+		}
+
+		err = bindTang(ctx, volume, keyFile.Name(), config)
+		if err != nil {
+			return err
+		}
+
+		if config.TPM == "disabled" && previous.TPM != "disabled" {
+			_, _, err = timeout.RunCommandSplit(ctx, append(os.Environ(), "PASSWORD="+s.System.Security.Config.EncryptionRecoveryKeys[0]), "systemd-cryptenroll", "--wipe-slot=tpm2", volume)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Re-enroll the TPM if it was previously disabled.
+	if previous.TPM == "disabled" && (binding == "" || config.TPM != "disabled") && !s.PassphraseOnly {
+		_, err = secureboot.ForceUpdatePCRBindings(ctx, s.OS.Name, s.OS.RunningRelease, s.System.Security.Config.EncryptionRecoveryKeys[0], false)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.NetworkUnlockBinding = binding
+
+	return nil
+}
+
+// IsTangBound returns whether the provided LUKS volume is bound to any Tang server.
+func IsTangBound(ctx context.Context, volume string) (bool, error) {
+	slots, err := getTangSlots(ctx, volume)
+	if err != nil {
+		return false, err
+	}
+
+	return len(slots) > 0, nil
+}
+
+// bindTang binds a LUKS volume to the configured Tang servers through a Shamir secret sharing pin.
+func bindTang(ctx context.Context, volume string, keyFile string, config *api.SystemSecurityNetworkUnlock) error {
+	type tangPin struct {
+		URL        string `json:"url"`
+		Thumbprint string `json:"thp,omitempty"`
+	}
+
+	pins := make([]tangPin, 0, len(config.TangServers))
+	for _, server := range config.TangServers {
+		pins = append(pins, tangPin{URL: server.URL, Thumbprint: server.Thumbprint})
+	}
+
+	threshold := config.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+
+	pinConfig, err := json.Marshal(map[string]any{
+		"t":    threshold,
+		"pins": map[string]any{"tang": pins},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = timeout.RunCommand(ctx, "clevis", "luks", "bind", "-y", "-k", keyFile, "-d", volume, "sss", string(pinConfig))
+
+	return err
+}
+
+// unbindTang removes any Tang binding from a LUKS volume.
+func unbindTang(ctx context.Context, volume string) error {
+	slots, err := getTangSlots(ctx, volume)
+	if err != nil {
+		return err
+	}
+
+	for _, slot := range slots {
+		_, err = timeout.RunCommand(ctx, "clevis", "luks", "unbind", "-f", "-d", volume, "-s", slot)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTangSlots returns the LUKS slots of a volume which are bound by clevis to Tang servers.
+func getTangSlots(ctx context.Context, volume string) ([]string, error) {
+	output, err := timeout.RunCommand(ctx, "clevis", "luks", "list", "-d", volume)
+	if err != nil {
+		// clevis fails when no slot is bound.
+		if strings.Contains(err.Error(), "No slots") || strings.Contains(err.Error(), "No key slots") {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	slots := []string{}
+
+	for _, line := range strings.Split(output, "\n") {
+		match := clevisSlotRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		slots = append(slots, match[1])
+	}
+
+	return slots, nil
+}
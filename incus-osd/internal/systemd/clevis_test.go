@@ -0,0 +1,35 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestValidateNetworkUnlock(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateNetworkUnlock(nil))
+	require.NoError(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{}))
+
+	servers := []api.SystemSecurityTangServer{{URL: "http://tang1.example.com"}, {URL: "https://tang2.example.com:7500"}}
+
+	require.NoError(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: servers}))
+	require.NoError(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: servers, Threshold: 2, TPM: "fallback"}))
+
+	// TPM can't be disabled, as the Tang servers aren't reachable at boot.
+	require.EqualError(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: servers, TPM: "disabled"}), "TPM unlocking can't be disabled, as the Tang servers can't be reached during boot")
+	require.Error(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TPM: "disabled"}))
+
+	// Invalid TPM mode.
+	require.Error(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: servers, TPM: "required"}))
+
+	// Threshold higher than the number of servers.
+	require.Error(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: servers, Threshold: 3}))
+
+	// Invalid URLs.
+	require.Error(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: []api.SystemSecurityTangServer{{URL: "tang.example.com"}}}))
+	require.Error(t, ValidateNetworkUnlock(&api.SystemSecurityNetworkUnlock{TangServers: []api.SystemSecurityTangServer{{URL: "ftp://tang.example.com"}}}))
+}
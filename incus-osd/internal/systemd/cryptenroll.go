@@ -7,18 +7,58 @@ import (
 	"slices"
 	"strings"
 
-	"github.com/lxc/incus/v6/shared/subprocess"
 	"github.com/muesli/crunchy"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 	"github.com/lxc/incus-os/incus-osd/internal/util"
 )
 
+// ErrNoEncryptionKey is returned when changing the encryption keys of a system without a TPM, which
+// requires an existing key to authorize the change.
+var ErrNoEncryptionKey = errors.New("no encryption recovery key available")
+
+// ValidateEncryptionKey checks that a user-specified encryption key is complex enough.
+func ValidateEncryptionKey(key string) error {
+	validator := crunchy.NewValidatorWithOpts(crunchy.Options{
+		MinLength:         15,
+		MustContainDigit:  false, // systemd-cryptenroll generates recovery passphrases that don't contain any digits.
+		MustContainSymbol: true,
+		CheckHIBP:         false,
+	})
+
+	return validator.Check(key)
+}
+
+// getUnlockArgs returns the arguments and environment used to authorize systemd-cryptenroll to change
+// the LUKS volumes. This relies on the TPM, or on the first recovery key for systems without a TPM.
+func getUnlockArgs(s *state.State) ([]string, []string, error) {
+	if !s.PassphraseOnly {
+		return []string{"--unlock-tpm2-device", "auto"}, os.Environ(), nil
+	}
+
+	if len(s.System.Security.Config.EncryptionRecoveryKeys) == 0 {
+		return nil, nil, ErrNoEncryptionKey
+	}
+
+	return []string{}, append(os.Environ(), "PASSWORD="+s.System.Security.Config.EncryptionRecoveryKeys[0]), nil
+}
+
 // GenerateRecoveryKey utilizes systemd-cryptenroll to generate a recovery key for the
-// root and swap LUKS volumes. Depends on an existing tpm2-backed key being enrolled and accessible.
+// root and swap LUKS volumes. Depends on an existing tpm2-backed key being enrolled and accessible,
+// or on systems without a TPM, an existing recovery key.
 func GenerateRecoveryKey(ctx context.Context, s *state.State) error {
+	if TPMUnlockDisabled(s) {
+		return ErrTPMUnlockDisabled
+	}
+
+	unlockArgs, env, err := getUnlockArgs(s)
+	if err != nil {
+		return err
+	}
+
 	// Get the underlying LUKS partitions.
 	luksVolumes, err := util.GetLUKSVolumePartitions()
 	if err != nil {
@@ -26,7 +66,7 @@ func GenerateRecoveryKey(ctx context.Context, s *state.State) error {
 	}
 
 	// First, generate a recovery key for the root volume.
-	recoveryPassword, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--recovery-key", luksVolumes["root"])
+	recoveryPassword, _, err := timeout.RunCommandSplit(ctx, env, "systemd-cryptenroll", append(unlockArgs, "--recovery-key", luksVolumes["root"])...)
 	if err != nil {
 		return err
 	}
@@ -34,7 +74,7 @@ func GenerateRecoveryKey(ctx context.Context, s *state.State) error {
 	recoveryPassword = strings.TrimSuffix(recoveryPassword, "\n")
 
 	// Second, set the same recovery key for the swap volume. Need to pass to systemd-cryptenroll via NEWPASSWORD environment variable.
-	_, _, err = subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+recoveryPassword), nil, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--password", luksVolumes["swap"])
+	_, _, err = timeout.RunCommandSplit(ctx, append(env, "NEWPASSWORD="+recoveryPassword), "systemd-cryptenroll", append(unlockArgs, "--password", luksVolumes["swap"])...)
 	if err != nil {
 		return err
 	}
@@ -47,20 +87,23 @@ func GenerateRecoveryKey(ctx context.Context, s *state.State) error {
 }
 
 // AddEncryptionKey utilizes systemd-cryptenroll to add a user-specified key for the
-// root and swap LUKS volumes. Depends on an existing tpm2-backed key being enrolled and accessible.
+// root and swap LUKS volumes. Depends on an existing tpm2-backed key being enrolled and accessible,
+// or on systems without a TPM, an existing recovery key.
 func AddEncryptionKey(ctx context.Context, s *state.State, key string) error {
+	if TPMUnlockDisabled(s) {
+		return ErrTPMUnlockDisabled
+	}
+
 	if slices.Contains(s.System.Security.Config.EncryptionRecoveryKeys, key) {
 		return errors.New("provided encryption key is already enrolled")
 	}
 
-	validator := crunchy.NewValidatorWithOpts(crunchy.Options{
-		MinLength:         15,
-		MustContainDigit:  false, // systemd-cryptenroll generates recovery passphrases that don't contain any digits.
-		MustContainSymbol: true,
-		CheckHIBP:         false,
-	})
+	err := ValidateEncryptionKey(key)
+	if err != nil {
+		return err
+	}
 
-	err := validator.Check(key)
+	unlockArgs, env, err := getUnlockArgs(s)
 	if err != nil {
 		return err
 	}
@@ -73,7 +116,7 @@ func AddEncryptionKey(ctx context.Context, s *state.State, key string) error {
 
 	// Add the new encryption password. Need to pass to systemd-cryptenroll via NEWPASSWORD environment variable.
 	for _, volume := range luksVolumes {
-		_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+key), nil, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--password", volume)
+		_, _, err := timeout.RunCommandSplit(ctx, append(env, "NEWPASSWORD="+key), "systemd-cryptenroll", append(unlockArgs, "--password", volume)...)
 		if err != nil {
 			return err
 		}
@@ -87,8 +130,13 @@ func AddEncryptionKey(ctx context.Context, s *state.State, key string) error {
 // DeleteEncryptionKey utilizes systemd-cryptenroll to remove a user-specified key from the
 // root and swap LUKS volumes. Depends on an existing tpm2-backed key being enrolled and accessible.
 // Due to systemd-cryptenroll only being able to wipe slots by index or type, we must first
-// remove all recovery and password slots, then re-add any remaining keys.
+// remove all recovery and password slots, then re-add any remaining keys. On systems without
+// a TPM, the other keys are needed to unlock the volumes, so only the slot of the removed key is wiped.
 func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error {
+	if TPMUnlockDisabled(s) {
+		return ErrTPMUnlockDisabled
+	}
+
 	if !slices.Contains(s.System.Security.Config.EncryptionRecoveryKeys, key) {
 		return errors.New("provided encryption key is not enrolled")
 	}
@@ -103,6 +151,19 @@ func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error
 		return err
 	}
 
+	if s.PassphraseOnly {
+		for _, volume := range luksVolumes {
+			err := removeLUKSKey(ctx, volume, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		s.System.Security.Config.EncryptionRecoveryKeys = slices.DeleteFunc(s.System.Security.Config.EncryptionRecoveryKeys, func(entry string) bool { return entry == key })
+
+		return nil
+	}
+
 	// First, wipe all recovery and password slots.
 	for _, volume := range luksVolumes {
 		err := WipeAllRecoveryKeys(ctx, volume)
@@ -129,9 +190,70 @@ func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error
 	return nil
 }
 
+// RotateEncryptionKey replaces an existing key for the root and swap LUKS volumes with a new one,
+// which is generated if not provided. The new key is enrolled before the old one is removed, so a
+// failure never leaves the volumes without a working key. Returns the new key.
+func RotateEncryptionKey(ctx context.Context, s *state.State, oldKey string, newKey string) (string, error) {
+	if !slices.Contains(s.System.Security.Config.EncryptionRecoveryKeys, oldKey) {
+		return "", errors.New("provided encryption key is not enrolled")
+	}
+
+	if newKey == oldKey {
+		return "", errors.New("new encryption key must differ from the existing one")
+	}
+
+	if newKey == "" {
+		err := GenerateRecoveryKey(ctx, s)
+		if err != nil {
+			return "", err
+		}
+
+		newKey = s.System.Security.Config.EncryptionRecoveryKeys[len(s.System.Security.Config.EncryptionRecoveryKeys)-1]
+	} else {
+		err := AddEncryptionKey(ctx, s, newKey)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := DeleteEncryptionKey(ctx, s, oldKey)
+	if err != nil {
+		return "", err
+	}
+
+	return newKey, nil
+}
+
 // WipeAllRecoveryKeys will wipe all recovery and password key slots for the provided volume.
 func WipeAllRecoveryKeys(ctx context.Context, volume string) error {
-	_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", "recovery,password", volume)
+	_, err := timeout.RunCommand(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", "recovery,password", volume)
+
+	return err
+}
+
+// removeLUKSKey removes the key slot unlocked by the provided key from a LUKS volume.
+func removeLUKSKey(ctx context.Context, volume string, key string) error {
+	// cryptsetup reads the key to remove from a file.
+	keyFile, err := os.CreateTemp("/run", "incus-osd-luks-")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(keyFile.Name())
+
+	_, err = keyFile.WriteString(key)
+	if err != nil {
+		_ = keyFile.Close()
+
+		return err
+	}
+
+	err = keyFile.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = timeout.RunCommand(ctx, "cryptsetup", "luksRemoveKey", volume, keyFile.Name())
 
 	return err
 }
@@ -148,7 +270,7 @@ func ListEncryptedVolumes(ctx context.Context) ([]api.SystemSecurityEncryptedVol
 
 	for volumeName, volumeDev := range luksVolumes {
 		// First, check if the volume is mapped, and therefore unlocked.
-		_, err := subprocess.RunCommandContext(ctx, "dmsetup", "info", volumeName)
+		_, err := timeout.RunCommand(ctx, "dmsetup", "info", volumeName)
 		if err != nil {
 			ret = append(ret, api.SystemSecurityEncryptedVolume{
 				Volume: volumeName,
@@ -161,7 +283,7 @@ func ListEncryptedVolumes(ctx context.Context) ([]api.SystemSecurityEncryptedVol
 		// Second, test if we can auto-unlock with the current TPM state.
 		// Ideally we wouldn't have to depend on cryptsetup, but systemd-cryptenroll (and friends) don't
 		// seem to have an equivalent of "--test-passphrase".
-		_, err = subprocess.RunCommandContext(ctx, "cryptsetup", "luksOpen", "--test-passphrase", volumeDev, volumeName)
+		_, err = timeout.RunCommand(ctx, "cryptsetup", "luksOpen", "--test-passphrase", volumeDev, volumeName)
 		if err != nil {
 			// Do we have a PCR mismatch on the TPM? If so, assume we can unlock with the TPM upon reboot.
 			if secureboot.TPMStatus() == secureboot.TPMPCRMismatch {
@@ -186,5 +308,10 @@ func ListEncryptedVolumes(ctx context.Context) ([]api.SystemSecurityEncryptedVol
 		})
 	}
 
+	// Report any network-bound unlocking; failures are ignored as clevis may not be available.
+	for i, volume := range ret {
+		ret[i].TangBound, _ = IsTangBound(ctx, luksVolumes[volume.Volume])
+	}
+
 	return ret, nil
 }
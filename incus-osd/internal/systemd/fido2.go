@@ -0,0 +1,168 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+	"github.com/lxc/incus-os/incus-osd/internal/util"
+)
+
+// fido2SlotRegex matches the key slot reported by systemd-cryptenroll after enrolling a FIDO2 token.
+var fido2SlotRegex = regexp.MustCompile(`key slot ([0-9]+)`)
+
+// fido2DeviceRegex matches the hidraw devices FIDO2 security keys are exposed as.
+var fido2DeviceRegex = regexp.MustCompile(`^/dev/hidraw[0-9]+$`)
+
+// EnrollFIDO2Token utilizes systemd-cryptenroll to enroll a FIDO2 security key for the root and swap
+// LUKS volumes. The existing recovery key is used to authorize the enrollment, which requires the user
+// to confirm their presence on the security key once for each volume.
+func EnrollFIDO2Token(ctx context.Context, s *state.State, req api.SystemSecurityFIDO2TokenEnroll) (api.SystemSecurityFIDO2Token, error) {
+	token := api.SystemSecurityFIDO2Token{
+		Name:  req.Name,
+		Slots: map[string]int{},
+	}
+
+	if req.Name == "" {
+		return token, errors.New("FIDO2 token name cannot be empty")
+	}
+
+	if slices.ContainsFunc(s.System.Security.State.FIDO2Tokens, func(entry api.SystemSecurityFIDO2Token) bool { return entry.Name == req.Name }) {
+		return token, fmt.Errorf("FIDO2 token %q is already enrolled", req.Name)
+	}
+
+	device := req.Device
+	if device == "" {
+		device = "auto"
+	} else if !fido2DeviceRegex.MatchString(device) {
+		return token, fmt.Errorf("invalid FIDO2 device %q", device)
+	}
+
+	if len(s.System.Security.Config.EncryptionRecoveryKeys) == 0 {
+		return token, errors.New("no encryption recovery key available")
+	}
+
+	// Get the underlying LUKS partitions.
+	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if err != nil {
+		return token, err
+	}
+
+	env := append(os.Environ(), "PASSWORD="+s.System.Security.Config.EncryptionRecoveryKeys[0])
+
+	withPIN := "no"
+	if req.PIN != "" {
+		withPIN = "yes"
+		env = append(env, "PIN="+req.PIN)
+	}
+
+	for volumeName, volume := range luksVolumes {
+		stdout, stderr, err := timeout.RunCommandSplit(ctx, env, "systemd-cryptenroll", "--fido2-device="+device, "--fido2-with-client-pin="+withPIN, "--fido2-with-user-presence=yes", volume)
+		if err != nil {
+			// Don't leave a partial enrollment behind.
+			enrolledVolumes := map[string]string{}
+			for name := range token.Slots {
+				enrolledVolumes[name] = luksVolumes[name]
+			}
+
+			_ = wipeFIDO2Slots(ctx, s, enrolledVolumes, token)
+
+			return token, err
+		}
+
+		match := fido2SlotRegex.FindStringSubmatch(stdout + stderr)
+		if match == nil {
+			return token, fmt.Errorf("unable to determine the key slot of the FIDO2 token enrolled in volume %q", volumeName)
+		}
+
+		slot, err := strconv.Atoi(match[1])
+		if err != nil {
+			return token, err
+		}
+
+		token.Slots[volumeName] = slot
+	}
+
+	s.System.Security.State.FIDO2Tokens = append(s.System.Security.State.FIDO2Tokens, token)
+
+	return token, nil
+}
+
+// RemoveFIDO2Token utilizes systemd-cryptenroll to remove an enrolled FIDO2 security key from the
+// root and swap LUKS volumes.
+func RemoveFIDO2Token(ctx context.Context, s *state.State, name string) error {
+	index := slices.IndexFunc(s.System.Security.State.FIDO2Tokens, func(entry api.SystemSecurityFIDO2Token) bool { return entry.Name == name })
+	if index < 0 {
+		return fmt.Errorf("FIDO2 token %q isn't enrolled", name)
+	}
+
+	// Get the underlying LUKS partitions.
+	luksVolumes, err := util.GetLUKSVolumePartitions()
+	if err != nil {
+		return err
+	}
+
+	err = wipeFIDO2Slots(ctx, s, luksVolumes, s.System.Security.State.FIDO2Tokens[index])
+	if err != nil {
+		return err
+	}
+
+	s.System.Security.State.FIDO2Tokens = slices.Delete(s.System.Security.State.FIDO2Tokens, index, index+1)
+
+	return nil
+}
+
+// wipeFIDO2Slots wipes the key slots used by a FIDO2 token in the provided volumes.
+func wipeFIDO2Slots(ctx context.Context, s *state.State, volumes map[string]string, token api.SystemSecurityFIDO2Token) error {
+	env := append(os.Environ(), "PASSWORD="+s.System.Security.Config.EncryptionRecoveryKeys[0])
+
+	for volumeName, volume := range volumes {
+		// Zero values aren't persisted in the state file, so a token using slot 0 has no entry for
+		// that volume once reloaded. Confirm that slot 0 actually holds a FIDO2 token before wiping it.
+		slot := token.Slots[volumeName]
+		if slot == 0 {
+			isFIDO2, err := isFIDO2Slot(ctx, volume, slot)
+			if err != nil {
+				return err
+			}
+
+			if !isFIDO2 {
+				continue
+			}
+		}
+
+		_, _, err := timeout.RunCommandSplit(ctx, env, "systemd-cryptenroll", "--wipe-slot="+strconv.Itoa(slot), volume)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isFIDO2Slot returns whether the given key slot of a LUKS volume holds a FIDO2 token.
+func isFIDO2Slot(ctx context.Context, volume string, slot int) (bool, error) {
+	output, err := timeout.RunCommand(ctx, "systemd-cryptenroll", volume)
+	if err != nil {
+		return false, err
+	}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != strconv.Itoa(slot) {
+			continue
+		}
+
+		return fields[1] == "fido2", nil
+	}
+
+	return false, nil
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/proxy"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
@@ -65,6 +66,12 @@ func ApplyNetworkConfiguration(ctx context.Context, s *state.State, networkCfg *
 	// the new devices are properly renamed by udev.
 	expectedNewPhysicalDevices := getExpectedNewPhysicalDevices(ctx, networkCfg)
 
+	// Seal the proxy credentials, keeping any which were left redacted.
+	err = secrets.SealNetworkConfig(networkCfg, s.System.Network.Config)
+	if err != nil {
+		return err
+	}
+
 	// Update the state before (re)generating networking configuration.
 	s.System.Network.Config = networkCfg
 
@@ -95,6 +102,12 @@ func ApplyNetworkConfiguration(ctx context.Context, s *state.State, networkCfg *
 		return err
 	}
 
+	// Start 802.1X authentication, so that switch ports requiring it let DHCP through.
+	err = applyPortAuthentication(ctx, networkCfg)
+	if err != nil {
+		return err
+	}
+
 	// Restart networking after new config files have been generated.
 	err = RestartUnit(ctx, "systemd-networkd")
 	if err != nil {
@@ -198,6 +211,11 @@ func ValidateNetworkConfiguration(networkCfg *api.SystemNetworkConfig, requireVa
 		return err
 	}
 
+	err = validateDedicatedManagement(networkCfg)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -236,10 +254,13 @@ func UpdateNetworkState(ctx context.Context, n *api.SystemNetwork) error {
 		for _, m := range b.Members {
 			mName := "_p" + strings.ToLower(strings.ReplaceAll(m, ":", ""))
 
-			members[mName], err = getInterfaceState(ctx, "bond_member", mName, m, "", nil)
+			mState, err := getInterfaceState(ctx, "bond_member", mName, m, "", nil)
 			if err != nil {
 				return err
 			}
+
+			mState.Bond = getBondMemberState(mName)
+			members[mName] = mState
 		}
 
 		bState, err := getInterfaceState(ctx, "bond", b.Name, b.Hwaddr, "", members)
@@ -247,6 +268,7 @@ func UpdateNetworkState(ctx context.Context, n *api.SystemNetwork) error {
 			return err
 		}
 
+		bState.Bond = getBondState("_b" + b.Name)
 		bState.Roles = b.Roles
 		rolesFound = append(rolesFound, b.Roles...)
 		n.State.Interfaces[b.Name] = bState
@@ -472,6 +494,12 @@ func getInterfaceState(ctx context.Context, ifaceType string, iface string, hwad
 		}
 	}
 
+	// Get the 802.1X authentication state of an interface.
+	var auth string
+	if ifaceType == "interface" {
+		auth = getPortAuthenticationState(ctx, underlyingDevice)
+	}
+
 	// Get LACP info for a bond member.
 	var lacp *api.SystemNetworkLACPState
 	if ifaceType == "bond_member" {
@@ -497,6 +525,7 @@ func getInterfaceState(ctx context.Context, ifaceType string, iface string, hwad
 		},
 		LLDP:    lldp,
 		LACP:    lacp,
+		Auth:    auth,
 		Members: members,
 	}, nil
 }
@@ -514,6 +543,11 @@ func resolveBridge(iface string) string {
 	return "_v" + iface
 }
 
+// GetAddressDevice returns the name of the device holding the addresses of a configured interface, bond or VLAN.
+func GetAddressDevice(iface string) string {
+	return resolveBridge(iface)
+}
+
 // GetIPAddresses returns any non-link-local address for an interface.
 func GetIPAddresses(ctx context.Context, iface string) ([]string, error) {
 	ipAddressRegex := regexp.MustCompile(`inet6? (.+)/\d+ `)
@@ -911,16 +945,26 @@ Name=_i%s
 		}
 
 		// Bond.
-		ret = append(ret, networkdConfigFile{
-			Name: fmt.Sprintf("11-_b%s.netdev", b.Name),
-			Contents: fmt.Sprintf(`[NetDev]
+		cfgString := fmt.Sprintf(`[NetDev]
 Name=_b%s
 Kind=bond
 %s
 
 [Bond]
 Mode=%s
-`, b.Name, mtuString, b.Mode),
+`, b.Name, mtuString, b.Mode)
+
+		if b.LACPRate != "" {
+			cfgString += "LACPTransmitRate=" + b.LACPRate + "\n"
+		}
+
+		if b.HashPolicy != "" {
+			cfgString += "TransmitHashPolicy=" + b.HashPolicy + "\n"
+		}
+
+		ret = append(ret, networkdConfigFile{
+			Name:     fmt.Sprintf("11-_b%s.netdev", b.Name),
+			Contents: cfgString,
 		})
 
 		// Bridge.
@@ -1002,12 +1046,16 @@ UseMTU=true
 [Network]
 %s`, i.Name, generateLinkSectionContents(i.Addresses, i.RequiredForOnline), generateNetworkSectionContents(i.Name, networkCfg.VLANs, networkCfg.DNS, networkCfg.Time))
 
-		cfgString += processAddresses(i.Addresses)
+		cfgString += processAddresses(i.Addresses, detectAddressConflicts(networkCfg, i.Roles))
 
 		if len(i.Routes) > 0 {
-			cfgString += processRoutes(i.Routes)
+			cfgString += processRoutes(i.Routes, managementRouteTable(networkCfg.DedicatedManagement, i.Roles))
 		}
 
+		cfgString += processNeighbors(i.Neighbors)
+
+		cfgString += generateDedicatedManagementContents(networkCfg.DedicatedManagement, i.Roles)
+
 		ret = append(ret, networkdConfigFile{
 			Name:     fmt.Sprintf("20-_v%s.network", i.Name),
 			Contents: cfgString,
@@ -1086,12 +1134,16 @@ UseMTU=true
 [Network]
 %s`, b.Name, generateLinkSectionContents(b.Addresses, b.RequiredForOnline), generateNetworkSectionContents(b.Name, networkCfg.VLANs, networkCfg.DNS, networkCfg.Time))
 
-		cfgString += processAddresses(b.Addresses)
+		cfgString += processAddresses(b.Addresses, detectAddressConflicts(networkCfg, b.Roles))
 
 		if len(b.Routes) > 0 {
-			cfgString += processRoutes(b.Routes)
+			cfgString += processRoutes(b.Routes, managementRouteTable(networkCfg.DedicatedManagement, b.Roles))
 		}
 
+		cfgString += processNeighbors(b.Neighbors)
+
+		cfgString += generateDedicatedManagementContents(networkCfg.DedicatedManagement, b.Roles)
+
 		ret = append(ret, networkdConfigFile{
 			Name:     fmt.Sprintf("21-_v%s.network", b.Name),
 			Contents: cfgString,
@@ -1154,16 +1206,22 @@ ConfigureWithoutCarrier=yes
 		for index, member := range b.Members {
 			memberStrippedHwaddr := strings.ToLower(strings.ReplaceAll(member, ":", ""))
 
-			ret = append(ret, networkdConfigFile{
-				Name: fmt.Sprintf("21-_b%s-dev%d.network", b.Name, index),
-				Contents: fmt.Sprintf(`[Match]
+			cfgString = fmt.Sprintf(`[Match]
 Name=_p%s
 
 [Network]
 LLDP=%s
 EmitLLDP=%s
 Bond=_b%s
-`, memberStrippedHwaddr, strconv.FormatBool(b.LLDP), strconv.FormatBool(b.LLDP), b.Name),
+`, memberStrippedHwaddr, strconv.FormatBool(b.LLDP), strconv.FormatBool(b.LLDP), b.Name)
+
+			if b.Primary != "" && strings.EqualFold(member, b.Primary) {
+				cfgString += "PrimarySlave=true\n"
+			}
+
+			ret = append(ret, networkdConfigFile{
+				Name:     fmt.Sprintf("21-_b%s-dev%d.network", b.Name, index),
+				Contents: cfgString,
 			})
 		}
 	}
@@ -1184,12 +1242,16 @@ UseMTU=true
 [Network]
 %s`, v.Name, generateLinkSectionContents(v.Addresses, v.RequiredForOnline), generateNetworkSectionContents(v.Name, nil, networkCfg.DNS, networkCfg.Time))
 
-		cfgString += processAddresses(v.Addresses)
+		cfgString += processAddresses(v.Addresses, detectAddressConflicts(networkCfg, v.Roles))
 
 		if len(v.Routes) > 0 {
-			cfgString += processRoutes(v.Routes)
+			cfgString += processRoutes(v.Routes, managementRouteTable(networkCfg.DedicatedManagement, v.Roles))
 		}
 
+		cfgString += processNeighbors(v.Neighbors)
+
+		cfgString += generateDedicatedManagementContents(networkCfg.DedicatedManagement, v.Roles)
+
 		ret = append(ret, networkdConfigFile{
 			Name:     fmt.Sprintf("22-%s.network", v.Name),
 			Contents: cfgString,
@@ -1199,9 +1261,11 @@ UseMTU=true
 	return ret
 }
 
-func processAddresses(addresses []string) string {
+func processAddresses(addresses []string, detectConflicts bool) string {
 	var ret strings.Builder
 
+	staticAddresses := []string{}
+
 	if len(addresses) != 0 {
 		_, _ = ret.WriteString("LinkLocalAddressing=ipv6\n")
 	} else {
@@ -1223,6 +1287,13 @@ func processAddresses(addresses []string) string {
 			acceptIPv6RA = true
 
 		default:
+			// Addresses needing duplicate address detection get their own section.
+			if detectConflicts {
+				staticAddresses = append(staticAddresses, addr)
+
+				continue
+			}
+
 			_, _ = ret.WriteString(fmt.Sprintf("Address=%s\n", addr))
 		}
 	}
@@ -1241,15 +1312,47 @@ func processAddresses(addresses []string) string {
 		_, _ = ret.WriteString("DHCP=ipv6\n")
 	}
 
+	if detectConflicts && hasDHCP4 {
+		_, _ = ret.WriteString("\n[DHCPv4]\nSendDecline=yes\n")
+	}
+
+	for _, addr := range staticAddresses {
+		family := "ipv6"
+		if strings.Contains(addr, ".") {
+			family = "ipv4"
+		}
+
+		_, _ = ret.WriteString(fmt.Sprintf("\n[Address]\nAddress=%s\nDuplicateAddressDetection=%s\n", addr, family))
+	}
+
 	return ret.String()
 }
 
-func processRoutes(routes []api.SystemNetworkRoute) string {
+// detectAddressConflicts returns whether duplicate address detection should be performed for a device.
+func detectAddressConflicts(networkCfg api.SystemNetworkConfig, roles []string) bool {
+	return networkCfg.DetectAddressConflicts && slices.Contains(roles, api.SystemNetworkInterfaceRoleManagement)
+}
+
+func processNeighbors(neighbors []api.SystemNetworkNeighbor) string {
+	var ret strings.Builder
+
+	for _, neighbor := range neighbors {
+		_, _ = ret.WriteString(fmt.Sprintf("\n[Neighbor]\nAddress=%s\nLinkLayerAddress=%s\n", neighbor.Address, neighbor.Hwaddr))
+	}
+
+	return ret.String()
+}
+
+func processRoutes(routes []api.SystemNetworkRoute, table string) string {
 	var ret strings.Builder
 
 	for _, route := range routes {
 		_, _ = ret.WriteString("\n[Route]\n")
 
+		if table != "" {
+			_, _ = ret.WriteString(fmt.Sprintf("Table=%s\n", table))
+		}
+
 		switch route.Via {
 		case "dhcp4":
 			_, _ = ret.WriteString("Gateway=_dhcp4\n")
@@ -1265,6 +1368,59 @@ func processRoutes(routes []api.SystemNetworkRoute) string {
 	return ret.String()
 }
 
+// dedicatedManagementTable is the routing table holding the management device's routes when the
+// management plane is dedicated, and dedicatedManagementPriority the priority of the first of its rules.
+const (
+	dedicatedManagementTable    = 100
+	dedicatedManagementPriority = 1000
+)
+
+// managementRouteTable returns the routing table to use for a device's routes, or an empty string for the main table.
+// When the management plane is dedicated, the management device's routes live in their own table.
+func managementRouteTable(dedicatedManagement bool, roles []string) string {
+	if !dedicatedManagement || !slices.Contains(roles, api.SystemNetworkInterfaceRoleManagement) {
+		return ""
+	}
+
+	return strconv.Itoa(dedicatedManagementTable)
+}
+
+// generateDedicatedManagementContents enforces the dedicated management plane through routing policy.
+//
+// Devices without the management role don't install default routes. The management device's routes are
+// placed in their own table, and two rules make the system consult the main table for everything but
+// default routes, then fall through to the management table. This way the system's own traffic (provider
+// requests, event delivery, replies from the API listener) can only leave through the management device,
+// even if a default route is added to the main table by other means.
+func generateDedicatedManagementContents(dedicatedManagement bool, roles []string) string {
+	if !dedicatedManagement {
+		return ""
+	}
+
+	if !slices.Contains(roles, api.SystemNetworkInterfaceRoleManagement) {
+		return "\n[DHCPv4]\nUseGateway=false\n\n[IPv6AcceptRA]\nUseGateway=false\n"
+	}
+
+	return fmt.Sprintf(`
+[DHCPv4]
+RouteTable=%[1]d
+
+[IPv6AcceptRA]
+RouteTable=%[1]d
+
+[RoutingPolicyRule]
+Family=both
+Table=main
+SuppressPrefixLength=0
+Priority=%[2]d
+
+[RoutingPolicyRule]
+Family=both
+Table=%[1]d
+Priority=%[3]d
+`, dedicatedManagementTable, dedicatedManagementPriority, dedicatedManagementPriority+1)
+}
+
 func generateNetworkSectionContents(name string, vlans []api.SystemNetworkVLAN, dns *api.SystemNetworkDNS, timeCfg *api.SystemNetworkTime) string {
 	var ret strings.Builder
 
@@ -1490,6 +1646,15 @@ func resolveMACs(ctx context.Context, config *api.SystemNetworkConfig) error {
 				config.Bonds[i].Members[j] = hwaddr
 			}
 		}
+
+		if config.Bonds[i].Primary != "" && !hwaddrhRegex.MatchString(config.Bonds[i].Primary) {
+			hwaddr, err := getMacForInterface(ctx, config.Bonds[i].Primary)
+			if err != nil {
+				return fmt.Errorf("bond %d primary failed getting MAC for '%s': %s", i, config.Bonds[i].Primary, err.Error())
+			}
+
+			config.Bonds[i].Primary = hwaddr
+		}
 	}
 
 	return nil
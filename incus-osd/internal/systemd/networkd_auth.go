@@ -0,0 +1,193 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/secrets"
+)
+
+// wpaSupplicantConfigPath is where the per-device wpa_supplicant configuration is written, as
+// expected by the wpa_supplicant-wired@.service unit.
+var wpaSupplicantConfigPath = "/etc/wpa_supplicant/"
+
+// wpaSupplicantControlPath is the wpa_supplicant control socket directory.
+const wpaSupplicantControlPath = "/run/wpa_supplicant"
+
+// applyPortAuthentication configures and (re)starts wpa_supplicant on the physical device of each
+// interface which has 802.1X authentication configured, and stops it on any other device.
+func applyPortAuthentication(ctx context.Context, networkCfg *api.SystemNetworkConfig) error {
+	devices := map[string]*api.SystemNetworkAuth{}
+
+	for _, iface := range networkCfg.Interfaces {
+		if iface.Auth == nil {
+			continue
+		}
+
+		devices["_p"+strings.ToLower(strings.ReplaceAll(iface.Hwaddr, ":", ""))] = iface.Auth
+	}
+
+	// Stop authentication on devices which no longer need it.
+	existing, err := filepath.Glob(filepath.Join(wpaSupplicantConfigPath, "wpa_supplicant-wired-*.conf"))
+	if err != nil {
+		return err
+	}
+
+	for _, configFile := range existing {
+		device := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(configFile), "wpa_supplicant-wired-"), ".conf")
+
+		_, ok := devices[device]
+		if ok {
+			continue
+		}
+
+		err := StopUnit(ctx, "wpa_supplicant-wired@"+device+".service")
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(configFile)
+		if err != nil {
+			return err
+		}
+
+		err = os.RemoveAll(filepath.Join(wpaSupplicantConfigPath, device))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Configure and restart wpa_supplicant for each device.
+	for device, auth := range devices {
+		err := writePortAuthenticationFiles(device, auth)
+		if err != nil {
+			return fmt.Errorf("failed to configure 802.1X authentication on %s: %w", device, err)
+		}
+
+		err = RestartUnit(ctx, "wpa_supplicant-wired@"+device+".service")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePortAuthenticationFiles writes the wpa_supplicant configuration and any certificates or keys it references.
+func writePortAuthenticationFiles(device string, auth *api.SystemNetworkAuth) error {
+	certDir := filepath.Join(wpaSupplicantConfigPath, device)
+
+	err := os.RemoveAll(certDir)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(certDir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	password, err := secrets.Open(auth.Password)
+	if err != nil {
+		return err
+	}
+
+	clientKey, err := secrets.Open(auth.ClientKey)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"ca.crt":     auth.CACertificate,
+		"client.crt": auth.ClientCertificate,
+		"client.key": clientKey,
+	}
+
+	for name, content := range files {
+		if content == "" {
+			continue
+		}
+
+		err := os.WriteFile(filepath.Join(certDir, name), []byte(content), 0o600)
+		if err != nil {
+			return err
+		}
+	}
+
+	opened := *auth
+	opened.Password = password
+	opened.ClientKey = clientKey
+
+	return os.WriteFile(filepath.Join(wpaSupplicantConfigPath, "wpa_supplicant-wired-"+device+".conf"), []byte(generateWPASupplicantConfig(certDir, &opened)), 0o600)
+}
+
+// generateWPASupplicantConfig returns the wired wpa_supplicant configuration for the given 802.1X
+// credentials. Certificates and keys are expected to have been written into certDir.
+func generateWPASupplicantConfig(certDir string, auth *api.SystemNetworkAuth) string {
+	var sb strings.Builder
+
+	sb.WriteString("ctrl_interface=" + wpaSupplicantControlPath + "\n")
+	sb.WriteString("ap_scan=0\n")
+	sb.WriteString("\n")
+	sb.WriteString("network={\n")
+	sb.WriteString("	key_mgmt=IEEE8021X\n")
+	sb.WriteString("	eapol_flags=0\n")
+	sb.WriteString("	identity=" + quoteWPAValue(auth.Identity) + "\n")
+
+	if auth.AnonymousIdentity != "" {
+		sb.WriteString("	anonymous_identity=" + quoteWPAValue(auth.AnonymousIdentity) + "\n")
+	}
+
+	if auth.CACertificate != "" {
+		sb.WriteString("	ca_cert=" + quoteWPAValue(filepath.Join(certDir, "ca.crt")) + "\n")
+	}
+
+	switch auth.Method {
+	case "tls":
+		sb.WriteString("	eap=TLS\n")
+		sb.WriteString("	client_cert=" + quoteWPAValue(filepath.Join(certDir, "client.crt")) + "\n")
+		sb.WriteString("	private_key=" + quoteWPAValue(filepath.Join(certDir, "client.key")) + "\n")
+	case "peap":
+		sb.WriteString("	eap=PEAP\n")
+		sb.WriteString("	password=" + quoteWPAValue(auth.Password) + "\n")
+		sb.WriteString("	phase2=\"auth=MSCHAPV2\"\n")
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// quoteWPAValue returns a quoted wpa_supplicant configuration string.
+func quoteWPAValue(value string) string {
+	return "\"" + value + "\""
+}
+
+// getPortAuthenticationState returns the 802.1X supplicant state of a physical device, or an empty
+// string if authentication isn't configured on it.
+func getPortAuthenticationState(ctx context.Context, device string) string {
+	_, err := os.Stat(filepath.Join(wpaSupplicantConfigPath, "wpa_supplicant-wired-"+device+".conf"))
+	if err != nil {
+		return ""
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "wpa_cli", "-p", wpaSupplicantControlPath, "-i", device, "status")
+	if err != nil {
+		return "unknown"
+	}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		value, ok := strings.CutPrefix(line, "Supplicant PAE state=")
+		if ok {
+			return strings.ToLower(value)
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,85 @@
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// sysClassNetPath is where the kernel exposes the network devices.
+var sysClassNetPath = "/sys/class/net"
+
+// getBondState returns the runtime state of a bond device, as reported by the bonding driver.
+func getBondState(device string) *api.SystemNetworkBondState {
+	bondingPath := filepath.Join(sysClassNetPath, device, "bonding")
+
+	_, err := os.Stat(bondingPath)
+	if err != nil {
+		return nil
+	}
+
+	ret := &api.SystemNetworkBondState{
+		// Values are reported along with their numeric identifier, such as "802.3ad 4".
+		Mode:       readBondingField(bondingPath, "mode"),
+		LACPRate:   readBondingField(bondingPath, "lacp_rate"),
+		HashPolicy: readBondingField(bondingPath, "xmit_hash_policy"),
+		LinkState:  readBondingField(bondingPath, "mii_status"),
+	}
+
+	// The LACP settings only apply to the 802.3ad mode.
+	if ret.Mode != "802.3ad" {
+		ret.LACPRate = ""
+	} else {
+		ret.AggregatorID, _ = strconv.Atoi(readBondingField(bondingPath, "ad_aggregator"))
+	}
+
+	if ret.Mode != "802.3ad" && ret.Mode != "balance-xor" && ret.Mode != "balance-tlb" {
+		ret.HashPolicy = ""
+	}
+
+	// Report the active member by its permanent MAC address, as members are configured by MAC.
+	activeMember := readBondingField(bondingPath, "active_slave")
+	if activeMember != "" {
+		ret.ActiveMember = readBondingField(filepath.Join(sysClassNetPath, activeMember, "bonding_slave"), "perm_hwaddr")
+	}
+
+	return ret
+}
+
+// getBondMemberState returns the runtime state of a bond member, as reported by the bonding driver.
+func getBondMemberState(device string) *api.SystemNetworkBondState {
+	bondingPath := filepath.Join(sysClassNetPath, device, "bonding_slave")
+
+	_, err := os.Stat(bondingPath)
+	if err != nil {
+		return nil
+	}
+
+	ret := &api.SystemNetworkBondState{
+		State:     readBondingField(bondingPath, "state"),
+		LinkState: readBondingField(bondingPath, "mii_status"),
+	}
+
+	ret.LinkFailures, _ = strconv.Atoi(readBondingField(bondingPath, "link_failure_count"))
+	ret.AggregatorID, _ = strconv.Atoi(readBondingField(bondingPath, "ad_aggregator_id"))
+
+	return ret
+}
+
+// readBondingField returns the first word of a bonding driver sysfs file, or an empty string if it can't be read.
+func readBondingField(bondingPath string, field string) string {
+	content, err := os.ReadFile(filepath.Join(bondingPath, field)) //nolint:gosec
+	if err != nil {
+		return ""
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
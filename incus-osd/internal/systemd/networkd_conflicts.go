@@ -0,0 +1,125 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// AddressConflict describes an address of the system which was found to be in use by another host.
+type AddressConflict struct {
+	Interface string
+	Address   string
+	Hwaddr    string // Hardware address of the other host, if known.
+}
+
+// networkdConflictRegex matches systemd-networkd messages reporting an IPv4 address conflict.
+var networkdConflictRegex = regexp.MustCompile(`(?i)\bconflict\b`)
+
+// networkdConflictAddressRegex extracts the IPv4 and hardware addresses from a conflict message.
+var networkdConflictAddressRegex = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b(?:[[:xdigit:]]{2}:){5}[[:xdigit:]]{2}\b`)
+
+// GetAddressConflicts returns the address conflicts detected since the provided time, or since boot if zero.
+// IPv4 conflicts are reported by systemd-networkd when duplicate address detection is enabled, while IPv6
+// addresses failing duplicate address detection are flagged by the kernel for as long as they're configured.
+func GetAddressConflicts(ctx context.Context, since time.Time) ([]AddressConflict, error) {
+	ret, err := getIPv6AddressConflicts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"-u", "systemd-networkd.service", "-b", "0", "-o", "json", "--no-pager"}
+	if !since.IsZero() {
+		args = append(args, "--since", "@"+strconv.FormatInt(since.Unix(), 10))
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "journalctl", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		var fields struct {
+			Interface string `json:"INTERFACE"`
+			Message   string `json:"MESSAGE"`
+		}
+
+		err := json.Unmarshal([]byte(line), &fields)
+		if err != nil || !networkdConflictRegex.MatchString(fields.Message) {
+			continue
+		}
+
+		conflict, ok := parseConflictMessage(fields.Interface, fields.Message)
+		if ok {
+			ret = append(ret, conflict)
+		}
+	}
+
+	return ret, nil
+}
+
+// getIPv6AddressConflicts returns the IPv6 addresses which failed duplicate address detection.
+func getIPv6AddressConflicts(ctx context.Context) ([]AddressConflict, error) {
+	output, err := subprocess.RunCommandContext(ctx, "ip", "-j", "address", "show")
+	if err != nil {
+		return nil, err
+	}
+
+	var links []struct {
+		Name     string `json:"ifname"`
+		AddrInfo []struct {
+			Local     string `json:"local"`
+			DADFailed bool   `json:"dadfailed"`
+		} `json:"addr_info"`
+	}
+
+	err = json.Unmarshal([]byte(output), &links)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []AddressConflict{}
+
+	for _, link := range links {
+		for _, addr := range link.AddrInfo {
+			if addr.DADFailed {
+				ret = append(ret, AddressConflict{
+					Interface: strings.TrimPrefix(link.Name, "_v"),
+					Address:   addr.Local,
+				})
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// parseConflictMessage extracts the conflicting address, and the hardware address of the other host if
+// present, from a systemd-networkd log message.
+func parseConflictMessage(iface string, message string) (AddressConflict, bool) {
+	ret := AddressConflict{
+		Interface: strings.TrimPrefix(iface, "_v"),
+	}
+
+	for _, match := range networkdConflictAddressRegex.FindAllString(message, -1) {
+		if net.ParseIP(match) != nil {
+			if ret.Address == "" {
+				ret.Address = match
+			}
+		} else if ret.Hwaddr == "" {
+			ret.Hwaddr = strings.ToLower(match)
+		}
+	}
+
+	return ret, ret.Address != ""
+}
@@ -0,0 +1,70 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// CheckNeighborReachable verifies that a directly connected host, typically a gateway, answers neighbor
+// (ARP/NDP) resolution on the provided interface. A single UDP datagram is sent to the host to trigger the
+// resolution, whose outcome is then read back from the kernel's neighbor table.
+func CheckNeighborReachable(ctx context.Context, iface string, address string) error {
+	dev := resolveBridge(iface)
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("invalid address %q", address)
+	}
+
+	udpAddr := &net.UDPAddr{IP: ip, Port: 9}
+	if ip.IsLinkLocalUnicast() {
+		udpAddr.Zone = dev
+	}
+
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return err
+	}
+
+	_, _ = conn.Write([]byte{0})
+	_ = conn.Close()
+
+	for {
+		output, err := subprocess.RunCommandContext(ctx, "ip", "-j", "neigh", "show", address, "dev", dev)
+		if err != nil {
+			return err
+		}
+
+		var neighbors []struct {
+			LLAddr string   `json:"lladdr"`
+			State  []string `json:"state"`
+		}
+
+		err = json.Unmarshal([]byte(output), &neighbors)
+		if err != nil {
+			return err
+		}
+
+		for _, neighbor := range neighbors {
+			if slices.Contains(neighbor.State, "FAILED") {
+				return fmt.Errorf("%s didn't answer neighbor resolution on %s", address, iface)
+			}
+
+			if neighbor.LLAddr != "" && !slices.Contains(neighbor.State, "INCOMPLETE") {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s didn't answer neighbor resolution on %s: %w", address, iface, ctx.Err())
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
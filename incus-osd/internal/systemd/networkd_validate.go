@@ -3,6 +3,7 @@ package systemd
 import (
 	"errors"
 	"fmt"
+	"net"
 	"regexp"
 	"slices"
 	"strings"
@@ -51,10 +52,20 @@ func validateInterfaces(interfaces []api.SystemNetworkInterface, requireValidMAC
 			}
 		}
 
+		err = validateNeighbors(iface.Neighbors)
+		if err != nil {
+			return fmt.Errorf("interface %d %s", index, err.Error())
+		}
+
 		err = validateHwaddr(iface.Hwaddr, requireValidMAC)
 		if err != nil {
 			return fmt.Errorf("interface %d %s", index, err.Error())
 		}
+
+		err = validateAuth(iface.Auth)
+		if err != nil {
+			return fmt.Errorf("interface %d %s", index, err.Error())
+		}
 	}
 
 	return nil
@@ -72,6 +83,11 @@ func validateBonds(bonds []api.SystemNetworkBond, requireValidMAC bool) error {
 			return fmt.Errorf("bond %d %s", index, err.Error())
 		}
 
+		err = validateBondParameters(bond)
+		if err != nil {
+			return fmt.Errorf("bond %d %s", index, err.Error())
+		}
+
 		err = validateMTU(bond.MTU)
 		if err != nil {
 			return fmt.Errorf("bond %d %s", index, err.Error())
@@ -106,6 +122,11 @@ func validateBonds(bonds []api.SystemNetworkBond, requireValidMAC bool) error {
 			}
 		}
 
+		err = validateNeighbors(bond.Neighbors)
+		if err != nil {
+			return fmt.Errorf("bond %d %s", index, err.Error())
+		}
+
 		if bond.Hwaddr != "" {
 			err = validateHwaddr(bond.Hwaddr, requireValidMAC)
 			if err != nil {
@@ -177,6 +198,11 @@ func validateVLANs(cfg *api.SystemNetworkConfig) error {
 				return fmt.Errorf("vlan %d route %d 'Via' %s", index, routeIndex, err.Error())
 			}
 		}
+
+		err = validateNeighbors(vlan.Neighbors)
+		if err != nil {
+			return fmt.Errorf("vlan %d %s", index, err.Error())
+		}
 	}
 
 	return nil
@@ -206,6 +232,40 @@ func validateMode(mode string) error {
 	return nil
 }
 
+func validateBondParameters(bond api.SystemNetworkBond) error {
+	if bond.LACPRate != "" {
+		if bond.LACPRate != "slow" && bond.LACPRate != "fast" {
+			return fmt.Errorf("invalid LACPRate value '%s'", bond.LACPRate)
+		}
+
+		if bond.Mode != "802.3ad" {
+			return fmt.Errorf("can't set LACPRate in mode '%s'", bond.Mode)
+		}
+	}
+
+	if bond.HashPolicy != "" {
+		if !slices.Contains([]string{"layer2", "layer2+3", "layer3+4", "encap2+3", "encap3+4", "vlan+srcmac"}, bond.HashPolicy) {
+			return fmt.Errorf("invalid HashPolicy value '%s'", bond.HashPolicy)
+		}
+
+		if !slices.Contains([]string{"802.3ad", "balance-xor", "balance-tlb"}, bond.Mode) {
+			return fmt.Errorf("can't set HashPolicy in mode '%s'", bond.Mode)
+		}
+	}
+
+	if bond.Primary != "" {
+		if !slices.Contains([]string{"active-backup", "balance-tlb", "balance-alb"}, bond.Mode) {
+			return fmt.Errorf("can't set Primary in mode '%s'", bond.Mode)
+		}
+
+		if !slices.ContainsFunc(bond.Members, func(member string) bool { return strings.EqualFold(member, bond.Primary) }) {
+			return fmt.Errorf("primary '%s' isn't a member", bond.Primary)
+		}
+	}
+
+	return nil
+}
+
 func validateParent(parent string, interfaces []api.SystemNetworkInterface, bonds []api.SystemNetworkBond) error {
 	if parent == "" {
 		return errors.New("has no parent")
@@ -322,3 +382,91 @@ func validateHwaddr(hwaddr string, requireValidMAC bool) error {
 
 	return nil
 }
+
+func validateNeighbors(neighbors []api.SystemNetworkNeighbor) error {
+	addresses := make([]string, 0, len(neighbors))
+
+	for index, neighbor := range neighbors {
+		addr := net.ParseIP(neighbor.Address)
+		if addr == nil {
+			return fmt.Errorf("neighbor %d invalid IP address '%s'", index, neighbor.Address)
+		}
+
+		if slices.Contains(addresses, addr.String()) {
+			return fmt.Errorf("neighbor %d address '%s' is listed multiple times", index, neighbor.Address)
+		}
+
+		addresses = append(addresses, addr.String())
+
+		_, err := net.ParseMAC(neighbor.Hwaddr)
+		if err != nil {
+			return fmt.Errorf("neighbor %d invalid MAC address '%s'", index, neighbor.Hwaddr)
+		}
+	}
+
+	return nil
+}
+
+func validateAuth(auth *api.SystemNetworkAuth) error {
+	if auth == nil {
+		return nil
+	}
+
+	if auth.Identity == "" {
+		return errors.New("802.1X authentication requires an identity")
+	}
+
+	// Values are written as quoted strings in the wpa_supplicant configuration.
+	for _, value := range []string{auth.Identity, auth.AnonymousIdentity, auth.Password} {
+		if strings.ContainsAny(value, "\"\n") {
+			return errors.New("802.1X identities and password cannot contain quotes or newlines")
+		}
+	}
+
+	switch auth.Method {
+	case "tls":
+		if auth.ClientCertificate == "" || auth.ClientKey == "" {
+			return errors.New("802.1X method 'tls' requires a client certificate and key")
+		}
+	case "peap":
+		if auth.Password == "" {
+			return errors.New("802.1X method 'peap' requires a password")
+		}
+	default:
+		return errors.New("invalid 802.1X method '" + auth.Method + "'")
+	}
+
+	return nil
+}
+
+func validateDedicatedManagement(networkCfg *api.SystemNetworkConfig) error {
+	if !networkCfg.DedicatedManagement {
+		return nil
+	}
+
+	managementDevices := 0
+
+	for _, iface := range networkCfg.Interfaces {
+		if slices.Contains(iface.Roles, api.SystemNetworkInterfaceRoleManagement) {
+			managementDevices++
+		}
+	}
+
+	for _, bond := range networkCfg.Bonds {
+		if slices.Contains(bond.Roles, api.SystemNetworkInterfaceRoleManagement) {
+			managementDevices++
+		}
+	}
+
+	for _, vlan := range networkCfg.VLANs {
+		if slices.Contains(vlan.Roles, api.SystemNetworkInterfaceRoleManagement) {
+			managementDevices++
+		}
+	}
+
+	if managementDevices != 1 {
+		return errors.New("dedicated management requires exactly one interface, bond or vlan with the management role")
+	}
+
+	return nil
+}
@@ -4,6 +4,12 @@ var (
 	// SystemExtensionsPath is the systemd location for system extensions.
 	SystemExtensionsPath = "/var/lib/extensions"
 
+	// SystemExtensionsStagingPath is the location for downloaded but not yet applied system extensions.
+	SystemExtensionsStagingPath = "/var/lib/extensions.staged"
+
+	// SystemExtensionsPreviousPath is the location for the previous version of updated system extensions, kept for rollback.
+	SystemExtensionsPreviousPath = "/var/lib/extensions.previous"
+
 	// SystemUpdatesPath is the systemd location for system updates.
 	SystemUpdatesPath = "/var/lib/updates"
 
@@ -12,4 +18,7 @@ var (
 
 	// SystemdTimesyncConfigFile is the configuration file for systemd-timesyncd.
 	SystemdTimesyncConfigFile = "/run/systemd/timesyncd.conf"
+
+	// SystemdTimesyncDropinPath is the location for systemd-timesyncd configuration overrides.
+	SystemdTimesyncDropinPath = "/run/systemd/timesyncd.conf.d/"
 )
@@ -4,30 +4,48 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/smallstep/pkcs7"
 
 	"github.com/lxc/incus-os/incus-osd/internal/keyring"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
+// extensionImagePolicy only allows system extensions whose file system is protected by a signed dm-verity root hash.
+const extensionImagePolicy = "root=signed+absent:usr=signed+absent"
+
+// partitionGUIDRegex matches the unique GUID of a partition, as reported by sgdisk.
+var partitionGUIDRegex = regexp.MustCompile(`Partition unique GUID: ([0-9A-Fa-f-]+)`)
+
 type sysextMetadata struct {
-	RootHash               string `json:"rootHast"`               //nolint:tagliatelle
+	RootHash               string `json:"rootHash"`               //nolint:tagliatelle
 	CertificateFingerprint string `json:"certificateFingerprint"` //nolint:tagliatelle
 	Signature              string `json:"signature"`
 }
 
-// RefreshExtensions causes systemd-sysext to re-scan and reload the system extensions.
+// RefreshExtensions causes systemd-sysext to re-scan and reload the system extensions. All the system
+// extensions are verified first, and only those with a signed dm-verity root hash can be activated.
 func RefreshExtensions(ctx context.Context) error {
-	_, err := subprocess.RunCommandContext(ctx, "systemd-sysext", "refresh")
+	err := VerifyExtensions(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "systemd-sysext", "refresh", "--image-policy="+extensionImagePolicy)
 	if err != nil {
 		return err
 	}
@@ -48,18 +66,36 @@ func RemoveExtension(ctx context.Context, name string) error {
 	return RefreshExtensions(ctx)
 }
 
+// VerifyExtensions verifies each of the system extensions about to be activated.
+func VerifyExtensions(ctx context.Context) error {
+	extensions, err := filepath.Glob(filepath.Join(SystemExtensionsPath, "*.raw"))
+	if err != nil {
+		return err
+	}
+
+	for _, extension := range extensions {
+		err := VerifyExtensionCertificateFingerprint(ctx, extension)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // VerifyExtensionCertificateFingerprint takes the filename of a sysext image and verifies its basic
-// format is correct and that its certificate fingerprint matches one currently trusted by the kernel.
-// Actual cryptographic validation of the signature is deferred to systemd-sysext.
+// format is correct, that its certificate fingerprint matches one currently trusted by the kernel and
+// that the signature of its root hash is valid for that certificate. This doesn't depend on how the
+// image was obtained; the kernel then enforces the root hash when systemd-sysext activates the image.
 func VerifyExtensionCertificateFingerprint(ctx context.Context, extensionFile string) error {
-	// Start with a quick baseline validation of the image.
-	_, err := subprocess.RunCommandContext(ctx, "systemd-dissect", "--validate", extensionFile)
+	// Start with a quick baseline validation of the image, which must have a signed root hash.
+	_, err := subprocess.RunCommandContext(ctx, "systemd-dissect", "--validate", "--image-policy="+extensionImagePolicy, extensionFile)
 	if err != nil {
 		return err
 	}
 
 	// Get the offset in the image to read json metadata from.
-	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-p", "-i", "3", extensionFile)
+	output, err := timeout.RunCommand(ctx, "sgdisk", "-p", "-i", "3", extensionFile)
 	if err != nil {
 		return err
 	}
@@ -134,12 +170,12 @@ func VerifyExtensionCertificateFingerprint(ctx context.Context, extensionFile st
 				// using to compute its values. So, instead compare the certificate's first subject name to the kernel's
 				// description of the key.
 				if key.Description == cert.Subject.Names[0].Value {
-					return nil
+					return verifyExtensionSignature(ctx, extensionFile, metadata, &cert)
 				}
 
 				// In some cases, the kernel uses a combination of organization and common name.
 				if len(cert.Subject.Organization) > 0 && key.Description == cert.Subject.Organization[0]+": "+cert.Subject.CommonName {
-					return nil
+					return verifyExtensionSignature(ctx, extensionFile, metadata, &cert)
 				}
 			}
 
@@ -149,3 +185,86 @@ func VerifyExtensionCertificateFingerprint(ctx context.Context, extensionFile st
 
 	return fmt.Errorf("sysext image '%s' is not signed by a trusted certificate", extensionFile)
 }
+
+// verifyExtensionSignature checks that the sysext image's PKCS#7 signature of its root hash was made by the provided
+// certificate, and that the signed root hash is the one of the image's dm-verity partitions.
+func verifyExtensionSignature(ctx context.Context, extensionFile string, metadata sysextMetadata, cert *x509.Certificate) error {
+	if metadata.RootHash == "" || metadata.Signature == "" {
+		return fmt.Errorf("sysext image '%s' is missing its root hash signature", extensionFile)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(metadata.Signature)
+	if err != nil {
+		return err
+	}
+
+	p7, err := pkcs7.Parse(sig)
+	if err != nil {
+		return err
+	}
+
+	if len(p7.Signers) == 0 {
+		return fmt.Errorf("sysext image '%s' root hash signature has no signer", extensionFile)
+	}
+
+	// The signature is detached and doesn't embed the signing certificate.
+	p7.Content = []byte(metadata.RootHash)
+	p7.Certificates = []*x509.Certificate{cert}
+
+	err = p7.Verify()
+	if err != nil {
+		return fmt.Errorf("sysext image '%s' has an invalid root hash signature: %w", extensionFile, err)
+	}
+
+	// Make sure the signature wasn't taken from another image. The data and verity partitions are
+	// identified by the first and last 128 bits of their root hash.
+	partitionGUIDs := []string{}
+
+	for _, partition := range []string{"1", "2"} {
+		output, err := timeout.RunCommand(ctx, "sgdisk", "-i", partition, extensionFile)
+		if err != nil {
+			return err
+		}
+
+		match := partitionGUIDRegex.FindStringSubmatch(output)
+		if match == nil {
+			return fmt.Errorf("sysext image '%s' is missing partition %s", extensionFile, partition)
+		}
+
+		partitionGUIDs = append(partitionGUIDs, match[1])
+	}
+
+	err = checkRootHashPartitions(metadata.RootHash, partitionGUIDs[0], partitionGUIDs[1])
+	if err != nil {
+		return fmt.Errorf("sysext image '%s' doesn't match its signed root hash: %w", extensionFile, err)
+	}
+
+	return nil
+}
+
+// checkRootHashPartitions checks that the GUIDs of the data and verity partitions are derived from the root hash.
+func checkRootHashPartitions(rootHash string, dataGUID string, verityGUID string) error {
+	rootHash = strings.ToLower(rootHash)
+	if len(rootHash) != 64 {
+		return fmt.Errorf("invalid root hash %q", rootHash)
+	}
+
+	_, err := hex.DecodeString(rootHash)
+	if err != nil {
+		return fmt.Errorf("invalid root hash %q: %w", rootHash, err)
+	}
+
+	normalize := func(guid string) string {
+		return strings.ToLower(strings.ReplaceAll(guid, "-", ""))
+	}
+
+	if normalize(dataGUID) != rootHash[:32] {
+		return fmt.Errorf("data partition GUID %q doesn't match root hash", dataGUID)
+	}
+
+	if normalize(verityGUID) != rootHash[32:] {
+		return fmt.Errorf("verity partition GUID %q doesn't match root hash", verityGUID)
+	}
+
+	return nil
+}
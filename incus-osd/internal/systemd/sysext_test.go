@@ -0,0 +1,24 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRootHashPartitions(t *testing.T) {
+	t.Parallel()
+
+	rootHash := "5f0f4a3c7e1b9d2a8c6e4f1a3b5d7c9e0a2b4c6d8e0f1a3b5c7d9e1f3a5b7c9d"
+
+	require.NoError(t, checkRootHashPartitions(rootHash, "5F0F4A3C-7E1B-9D2A-8C6E-4F1A3B5D7C9E", "0A2B4C6D-8E0F-1A3B-5C7D-9E1F3A5B7C9D"))
+
+	// Partitions swapped or taken from another image.
+	require.Error(t, checkRootHashPartitions(rootHash, "0A2B4C6D-8E0F-1A3B-5C7D-9E1F3A5B7C9D", "5F0F4A3C-7E1B-9D2A-8C6E-4F1A3B5D7C9E"))
+	require.Error(t, checkRootHashPartitions(rootHash, "5F0F4A3C-7E1B-9D2A-8C6E-4F1A3B5D7C9E", "00000000-0000-0000-0000-000000000000"))
+
+	// Invalid root hashes.
+	require.Error(t, checkRootHashPartitions("", "", ""))
+	require.Error(t, checkRootHashPartitions(rootHash[:32], "5F0F4A3C-7E1B-9D2A-8C6E-4F1A3B5D7C9E", ""))
+	require.Error(t, checkRootHashPartitions("zz"+rootHash[2:], "ZZ0F4A3C-7E1B-9D2A-8C6E-4F1A3B5D7C9E", "0A2B4C6D-8E0F-1A3B-5C7D-9E1F3A5B7C9D"))
+}
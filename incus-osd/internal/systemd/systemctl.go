@@ -2,6 +2,7 @@ package systemd
 
 import (
 	"context"
+	"strings"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 )
@@ -95,3 +96,30 @@ func IsFailed(ctx context.Context, unit string) bool {
 
 	return result == "failed\n"
 }
+
+// GetUnitProperties returns the requested properties of the specified unit.
+func GetUnitProperties(ctx context.Context, unit string, properties ...string) (map[string]string, error) {
+	args := []string{"show", "--timestamp=unix", unit}
+
+	for _, property := range properties {
+		args = append(args, "--property="+property)
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]string{}
+
+	for line := range strings.SplitSeq(output, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		ret[key] = value
+	}
+
+	return ret, nil
+}
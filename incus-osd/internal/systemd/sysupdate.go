@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/lxc/incus/v6/shared/subprocess"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 )
 
@@ -56,39 +58,95 @@ func GetCurrentRelease(_ context.Context) (string, string, error) { //nolint:rev
 	return "", "", ErrReleaseNotFound
 }
 
-// ApplySystemUpdate instructs systemd-sysupdate to apply any pending update and optionally reboot the system.
-func ApplySystemUpdate(ctx context.Context, luksPassword string, version string, reboot bool) error {
-	// WORKAROUND: Start the boot.mount unit so /boot autofs is active before we create a new mount namespace.
-	err := StartUnit(ctx, "boot.mount")
+// PlanSecureBootKeyChange reports the changes which would be made when applying the provided pending update,
+// should it be signed with a different Secure Boot key, without modifying the system. If the key hasn't changed,
+// nil is returned.
+func PlanSecureBootKeyChange(ctx context.Context, version string) (*api.SystemSecurityRebindPlan, error) {
+	newUKIFile, newUsrImageFile, err := getSystemUpdateFiles(version)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if the Secure Boot key has changed; if it has apply the necessary updates.
-	var newUKIFile string
+	if newUKIFile == "" {
+		return nil, fmt.Errorf("no pending update found for version %q", version)
+	}
 
-	var newUsrImageFile string
+	secureBootKeyChanged, err := secureboot.UKIHasDifferentSecureBootCertificate(newUKIFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !secureBootKeyChanged {
+		return nil, nil //nolint:nilnil
+	}
+
+	return secureboot.HandleSecureBootKeyChange(ctx, "", newUKIFile, newUsrImageFile, true)
+}
+
+// getSystemUpdateFiles returns the paths of the UKI and usr image of a pending update.
+func getSystemUpdateFiles(version string) (string, string, error) {
+	var ukiFile string
+
+	var usrImageFile string
 
 	updateFiles, err := os.ReadDir(SystemUpdatesPath)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
 	for _, file := range updateFiles {
 		if strings.HasSuffix(file.Name(), "_"+version+".efi") {
-			newUKIFile = filepath.Join(SystemUpdatesPath, file.Name())
+			ukiFile = filepath.Join(SystemUpdatesPath, file.Name())
 		} else if strings.Contains(file.Name(), "_"+version+".usr-x86-64.") || strings.Contains(file.Name(), "_"+version+".usr-arm64.") {
-			newUsrImageFile = filepath.Join(SystemUpdatesPath, file.Name())
+			usrImageFile = filepath.Join(SystemUpdatesPath, file.Name())
 		}
 	}
 
+	return ukiFile, usrImageFile, nil
+}
+
+// RemoveSystemUpdate deletes the files of a pending update which hasn't been applied yet.
+func RemoveSystemUpdate(version string) error {
+	updateFiles, err := os.ReadDir(SystemUpdatesPath)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range updateFiles {
+		if !strings.Contains(file.Name(), "_"+version+".") {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(SystemUpdatesPath, file.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplySystemUpdate instructs systemd-sysupdate to apply any pending update and optionally reboot the system.
+func ApplySystemUpdate(ctx context.Context, luksPassword string, version string, reboot bool) error {
+	// WORKAROUND: Start the boot.mount unit so /boot autofs is active before we create a new mount namespace.
+	err := StartUnit(ctx, "boot.mount")
+	if err != nil {
+		return err
+	}
+
+	// Check if the Secure Boot key has changed; if it has apply the necessary updates.
+	newUKIFile, newUsrImageFile, err := getSystemUpdateFiles(version)
+	if err != nil {
+		return err
+	}
+
 	secureBootKeyChanged, err := secureboot.UKIHasDifferentSecureBootCertificate(newUKIFile)
 	if err != nil {
 		return err
 	}
 
 	if secureBootKeyChanged {
-		err := secureboot.HandleSecureBootKeyChange(ctx, luksPassword, newUKIFile, newUsrImageFile)
+		_, err := secureboot.HandleSecureBootKeyChange(ctx, luksPassword, newUKIFile, newUsrImageFile, false)
 		if err != nil {
 			return err
 		}
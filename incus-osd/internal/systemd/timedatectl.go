@@ -0,0 +1,45 @@
+package systemd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// IsTimeSynchronized returns a boolean indicating if the system clock is synchronized with an NTP server.
+func IsTimeSynchronized(ctx context.Context) (bool, error) {
+	output, err := subprocess.RunCommandContext(ctx, "timedatectl", "show", "--property=NTPSynchronized", "--value")
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(output) == "yes", nil
+}
+
+// GetTimesyncServer returns the name and address of the NTP server currently used by systemd-timesyncd, if any.
+func GetTimesyncServer(ctx context.Context) (string, string, error) {
+	output, err := subprocess.RunCommandContext(ctx, "timedatectl", "show-timesync", "--property=ServerName", "--property=ServerAddress")
+	if err != nil {
+		return "", "", err
+	}
+
+	name := ""
+	address := ""
+
+	for line := range strings.SplitSeq(output, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "ServerName":
+			name = value
+		case "ServerAddress":
+			address = value
+		}
+	}
+
+	return name, address, nil
+}
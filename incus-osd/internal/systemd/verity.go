@@ -0,0 +1,58 @@
+package systemd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+// ListVerityVolumes returns a list of each dm-verity volume and its status.
+func ListVerityVolumes(ctx context.Context) ([]api.SystemSecurityVerityVolume, error) {
+	ret := []api.SystemSecurityVerityVolume{}
+
+	output, err := timeout.RunCommand(ctx, "dmsetup", "status", "--target", "verity")
+	if err != nil {
+		return ret, err
+	}
+
+	return parseVerityStatus(output), nil
+}
+
+// parseVerityStatus parses the output of "dmsetup status --target verity".
+func parseVerityStatus(output string) []api.SystemSecurityVerityVolume {
+	ret := []api.SystemSecurityVerityVolume{}
+
+	// Each line looks like "<name>: <start> <length> verity <V|C> ...", where "V" indicates the
+	// volume has been verified so far and "C" that a corruption has been detected.
+	for line := range strings.SplitSeq(output, "\n") {
+		name, status, found := strings.Cut(line, ": ")
+		if !found {
+			continue
+		}
+
+		fields := strings.Fields(status)
+		if len(fields) < 4 || fields[2] != "verity" {
+			continue
+		}
+
+		var state string
+
+		switch fields[3] {
+		case "V":
+			state = "verified"
+		case "C":
+			state = "corrupted"
+		default:
+			state = "unknown"
+		}
+
+		ret = append(ret, api.SystemSecurityVerityVolume{
+			Volume: name,
+			State:  state,
+		})
+	}
+
+	return ret
+}
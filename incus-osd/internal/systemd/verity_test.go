@@ -0,0 +1,48 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestParseVerityStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   []api.SystemSecurityVerityVolume
+	}{
+		{
+			name:   "no volumes",
+			output: "No devices found\n",
+			want:   []api.SystemSecurityVerityVolume{},
+		},
+		{
+			name:   "verified and corrupted",
+			output: "usr: 0 2097152 verity V\nusr-a: 0 2097152 verity C\n",
+			want:   []api.SystemSecurityVerityVolume{{Volume: "usr", State: "verified"}, {Volume: "usr-a", State: "corrupted"}},
+		},
+		{
+			name:   "unknown status",
+			output: "usr: 0 2097152 verity X\n",
+			want:   []api.SystemSecurityVerityVolume{{Volume: "usr", State: "unknown"}},
+		},
+		{
+			name:   "other targets and truncated lines",
+			output: "root: 0 2097152 crypt\nusr: 0 2097152 verity\n",
+			want:   []api.SystemSecurityVerityVolume{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.want, parseVerityStatus(test.output))
+		})
+	}
+}
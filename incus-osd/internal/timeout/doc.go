@@ -0,0 +1,3 @@
+// Package timeout centralizes the timeouts applied to long-running operations, such as external commands and provider requests.
+// The timeouts are defined in a single table which is fixed at build time.
+package timeout
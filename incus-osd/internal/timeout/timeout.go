@@ -0,0 +1,116 @@
+package timeout
+
+import (
+	"context"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// Class represents a class of operations sharing the same timeout.
+type Class string
+
+const (
+	// Command covers any external command without a more specific class.
+	Command Class = "command"
+
+	// Disk covers partitioning and block device queries.
+	Disk Class = "disk"
+
+	// DiskWipe covers commands clearing the content of a whole block device, which can be slow on large drives.
+	DiskWipe Class = "disk-wipe"
+
+	// Encryption covers LUKS, TPM and Tang enrollment commands, as well as sealing credentials.
+	Encryption Class = "encryption"
+
+	// ZFS covers ZFS pool and dataset management commands.
+	ZFS Class = "zfs"
+
+	// ProviderRequest covers each attempt at a metadata request to an update provider.
+	ProviderRequest Class = "provider-request"
+
+	// DNSRequest covers each request to a DNS provider.
+	DNSRequest Class = "dns-request"
+
+	// NetworkTest covers each check of the network reachability self-test.
+	NetworkTest Class = "network-test"
+
+	// CrashDump covers saving a kernel crash dump from the capture kernel.
+	CrashDump Class = "crash-dump"
+
+	// DownloadIdle is how long a download from an update provider may go without receiving any data.
+	DownloadIdle Class = "download-idle"
+)
+
+// Durations holds the timeout of each class of operations. The values are set at build time and can't be
+// changed through the system configuration.
+var Durations = map[Class]time.Duration{
+	Command:         10 * time.Minute,
+	Disk:            2 * time.Minute,
+	DiskWipe:        12 * time.Hour,
+	Encryption:      5 * time.Minute,
+	ZFS:             30 * time.Minute,
+	ProviderRequest: 2 * time.Minute,
+	DNSRequest:      time.Minute,
+	NetworkTest:     15 * time.Second,
+	CrashDump:       30 * time.Minute,
+	DownloadIdle:    5 * time.Minute,
+}
+
+// commandClasses maps external commands to the class of their timeout.
+var commandClasses = map[string]Class{
+	"blkdiscard":          DiskWipe,
+	"clevis":              Encryption,
+	"cryptsetup":          Encryption,
+	"dmsetup":             Disk,
+	"lsblk":               Disk,
+	"makedumpfile":        CrashDump,
+	"sgdisk":              Disk,
+	"systemd-creds":       Encryption,
+	"systemd-cryptenroll": Encryption,
+	"tpm2_selftest":       Encryption,
+	"udevadm":             Disk,
+	"zfs":                 ZFS,
+	"zpool":               ZFS,
+}
+
+// Get returns the timeout for the given class of operations, falling back to the generic command timeout.
+func Get(class Class) time.Duration {
+	d, ok := Durations[class]
+	if !ok {
+		return Durations[Command]
+	}
+
+	return d
+}
+
+// WithTimeout returns a copy of the context which is cancelled once the timeout for the given class of operations expires.
+func WithTimeout(ctx context.Context, class Class) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, Get(class))
+}
+
+// CommandClass returns the class of timeout applied to the given external command.
+func CommandClass(name string) Class {
+	class, ok := commandClasses[name]
+	if !ok {
+		return Command
+	}
+
+	return class
+}
+
+// RunCommand runs an external command, killing it if it doesn't complete within the timeout for its class.
+func RunCommand(ctx context.Context, name string, args ...string) (string, error) {
+	stdout, _, err := RunCommandSplit(ctx, nil, name, args...)
+
+	return stdout, err
+}
+
+// RunCommandSplit runs an external command with the supplied environment, killing it if it doesn't complete
+// within the timeout for its class. Stdout and stderr are returned separately.
+func RunCommandSplit(ctx context.Context, env []string, name string, args ...string) (string, string, error) {
+	ctx, cancel := WithTimeout(ctx, CommandClass(name))
+	defer cancel()
+
+	return subprocess.RunCommandSplit(ctx, env, nil, name, args...)
+}
@@ -0,0 +1,24 @@
+package timeout_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
+)
+
+func TestCommandClass(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, timeout.Disk, timeout.CommandClass("sgdisk"))
+	require.Equal(t, timeout.Encryption, timeout.CommandClass("cryptsetup"))
+	require.Equal(t, timeout.Command, timeout.CommandClass("systemctl"))
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, timeout.Durations[timeout.ZFS], timeout.Get(timeout.ZFS))
+	require.Equal(t, timeout.Durations[timeout.Command], timeout.Get(timeout.Class("unknown")))
+}
@@ -51,6 +51,16 @@ func NewTUI(s *state.State) (*TUI, error) {
 		ttyDevs = append(ttyDevs, "/dev/ttyS0")
 	}
 
+	// If configured, additionally show the console on a serial device.
+	if s.Services.Console.Config.Enabled && s.Services.Console.Config.StatusDisplay && s.Services.Console.Config.Device != "" {
+		ttyDev := "/dev/" + s.Services.Console.Config.Device
+
+		_, err := os.Stat(ttyDev)
+		if err == nil && !slices.Contains(ttyDevs, ttyDev) {
+			ttyDevs = append(ttyDevs, ttyDev)
+		}
+	}
+
 	// Get information about the system's resources. Since we only display CPU
 	// and RAM, caching the results at creation time should be sufficient.
 	ret.systemResources, err = resources.GetResources()
@@ -14,11 +14,11 @@ import (
 	"time"
 
 	"github.com/lxc/incus/v6/shared/revert"
-	"github.com/lxc/incus/v6/shared/subprocess"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/timeout"
 )
 
 // LoadPools will import all managed ZFS pools on the local system and attempt to load
@@ -33,7 +33,7 @@ func LoadPools(ctx context.Context, s *state.State) error {
 
 	// For each pool we manage, import the pool and load its encryption key.
 	for _, pool := range pools {
-		_, err = subprocess.RunCommandContext(ctx, "zpool", "import", pool)
+		_, err = timeout.RunCommand(ctx, "zpool", "import", pool)
 		if err != nil {
 			// If the pool is already imported, don't return an error.
 			if !strings.Contains(err.Error(), "cannot import '"+pool+"': a pool with that name already exists") {
@@ -41,7 +41,7 @@ func LoadPools(ctx context.Context, s *state.State) error {
 			}
 		}
 
-		_, err = subprocess.RunCommandContext(ctx, "zfs", "load-key", pool)
+		_, err = timeout.RunCommand(ctx, "zfs", "load-key", pool)
 		if err != nil {
 			// If the pool's encryption key has already been loaded, don't return an error.
 			if !strings.Contains(err.Error(), "Key load error: Key already loaded for") {
@@ -53,7 +53,7 @@ func LoadPools(ctx context.Context, s *state.State) error {
 	// If the "local" pool isn't automatically imported, this is a first boot and we either
 	// need to create a fresh "local" pool or attempt to recover an existing pool.
 	if !storage.PoolExists(ctx, "local") {
-		_, err := subprocess.RunCommandContext(ctx, "zpool", "import", "local")
+		_, err := timeout.RunCommand(ctx, "zpool", "import", "local")
 		if err != nil {
 			// Failed to import the pool, so create a fresh one.
 			zpool := api.SystemStoragePool{
@@ -78,7 +78,7 @@ func LoadPools(ctx context.Context, s *state.State) error {
 	// Make sure that the Incus volume has the incusos:use property set.
 	// Errors are ignored as the user may have deleted the dataset.
 	// NOTE: This logic can go away in January 2026.
-	_, _ = subprocess.RunCommand("zfs", "set", "incusos:use=incus", "local/incus")
+	_, _ = timeout.RunCommand(ctx, "zfs", "set", "incusos:use=incus", "local/incus")
 
 	return nil
 }
@@ -103,7 +103,7 @@ func recoverLocalPool(ctx context.Context) error {
 		} else {
 			slog.InfoContext(ctx, "Attempting to recover storage pool 'local' using existing non-system drive")
 
-			_, err := subprocess.RunCommandContext(ctx, "zpool", "replace", "local", filepath.Base(poolConfig.DevicesDegraded[0]), actualrootDev)
+			_, err := timeout.RunCommand(ctx, "zpool", "replace", "local", filepath.Base(poolConfig.DevicesDegraded[0]), actualrootDev)
 			if err != nil {
 				return err
 			}
@@ -129,7 +129,7 @@ func recoverLocalPool(ctx context.Context) error {
 
 	// Export the "local" pool. This keeps the logic for allowing the user to set the encryption recovery key
 	// via the import-pool API simple.
-	_, err = subprocess.RunCommandContext(ctx, "zpool", "export", "local")
+	_, err = timeout.RunCommand(ctx, "zpool", "export", "local")
 
 	return err
 }
@@ -288,7 +288,7 @@ func CreateZpool(ctx context.Context, zpool api.SystemStoragePool, s *state.Stat
 		args = append(args, zpool.Log...)
 	}
 
-	_, err = subprocess.RunCommandContext(ctx, "zpool", args...)
+	_, err = timeout.RunCommand(ctx, "zpool", args...)
 	if err != nil {
 		// Remove the encryption key file for the failed zpool.
 		_ = os.Remove(keyfilePath)
@@ -316,7 +316,7 @@ func DestroyZpool(ctx context.Context, zpoolName string) error {
 	}
 
 	// Destroy the zpool.
-	_, err = subprocess.RunCommandContext(ctx, "zpool", "destroy", zpoolName)
+	_, err = timeout.RunCommand(ctx, "zpool", "destroy", zpoolName)
 	if err != nil {
 		return err
 	}
@@ -376,12 +376,12 @@ func DestroyZpool(ctx context.Context, zpoolName string) error {
 // Helper method to zap any GPT table and opportunistically run blkdiscard
 // to fully wipe a device that's just been removed from a storage pool.
 func clearDevice(ctx context.Context, device string) error {
-	_, err := subprocess.RunCommandContext(ctx, "sgdisk", "-Z", device)
+	_, err := timeout.RunCommand(ctx, "sgdisk", "-Z", device)
 	if err != nil {
 		return err
 	}
 
-	_, _ = subprocess.RunCommandContext(ctx, "blkdiscard", "-f", device)
+	_, _ = timeout.RunCommand(ctx, "blkdiscard", "-f", device)
 
 	return nil
 }
@@ -510,13 +510,13 @@ func prepareLocalPoolUpdate(ctx context.Context, currentConfig api.SystemStorage
 				}
 
 				// Wipe the disk first.
-				_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-Z", actualDevice)
+				_, err = timeout.RunCommand(ctx, "sgdisk", "-Z", actualDevice)
 				if err != nil {
 					return err
 				}
 
 				// Create the partition at the correct offset
-				_, err = subprocess.RunCommandContext(ctx, "sgdisk", "-n", "11:69826560:", actualDevice)
+				_, err = timeout.RunCommand(ctx, "sgdisk", "-n", "11:69826560:", actualDevice)
 				if err != nil {
 					return err
 				}
@@ -541,7 +541,7 @@ func prepareLocalPoolUpdate(ctx context.Context, currentConfig api.SystemStorage
 				newDevice = newConfig.Devices[0]
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "zpool", "attach", "local", currentConfig.Devices[0], newDevice)
+			_, err = timeout.RunCommand(ctx, "zpool", "attach", "local", currentConfig.Devices[0], newDevice)
 
 			return err
 		}
@@ -566,7 +566,7 @@ func updateZpoolHelper(ctx context.Context, zpoolName string, vdevName string, c
 				zpoolCmd = "offline"
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "zpool", zpoolCmd, zpoolName, actualDev)
+			_, err = timeout.RunCommand(ctx, "zpool", zpoolCmd, zpoolName, actualDev)
 			if err != nil {
 				return err
 			}
@@ -589,7 +589,7 @@ func updateZpoolHelper(ctx context.Context, zpoolName string, vdevName string, c
 				return err
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "zpool", "replace", zpoolName, actualDevOld, actualDevNew)
+			_, err = timeout.RunCommand(ctx, "zpool", "replace", zpoolName, actualDevOld, actualDevNew)
 			if err != nil {
 				return err
 			}
@@ -611,7 +611,7 @@ func updateZpoolHelper(ctx context.Context, zpoolName string, vdevName string, c
 				return err
 			}
 
-			_, err = subprocess.RunCommandContext(ctx, "zpool", "online", zpoolName, actualDev)
+			_, err = timeout.RunCommand(ctx, "zpool", "online", zpoolName, actualDev)
 			if err != nil {
 				// If we couldn't online the device, then it's brand new, but ZFS requires at least two devices when adding a new mirror.
 				return errors.New("adding to a mirror requires at least two devices")
@@ -628,7 +628,7 @@ func updateZpoolHelper(ctx context.Context, zpoolName string, vdevName string, c
 				args = append(args, actualDev)
 			}
 
-			_, err := subprocess.RunCommandContext(ctx, "zpool", args...)
+			_, err := timeout.RunCommand(ctx, "zpool", args...)
 			if err != nil {
 				return err
 			}
@@ -662,7 +662,7 @@ func updateZpoolHelper(ctx context.Context, zpoolName string, vdevName string, c
 
 			args = append(args, actualDev)
 
-			_, err = subprocess.RunCommandContext(ctx, "zpool", args...)
+			_, err = timeout.RunCommand(ctx, "zpool", args...)
 			if err != nil {
 				if !strings.Contains(err.Error(), actualDev+"-part1 is part of active pool '"+zpoolName+"'") || !strings.HasPrefix(vdevName, "raidz") {
 					return err
@@ -670,7 +670,7 @@ func updateZpoolHelper(ctx context.Context, zpoolName string, vdevName string, c
 
 				// Bit of an edge case where the "new" device might be an offlined member of the raidz. As such, it's still part
 				// of the pool, so try to bring it back online.
-				_, newErr := subprocess.RunCommandContext(ctx, "zpool", "online", zpoolName, actualDev)
+				_, newErr := timeout.RunCommand(ctx, "zpool", "online", zpoolName, actualDev)
 				if newErr != nil {
 					// Return the original error, so we don't confuse the user with a failed "online" command.
 					return err
@@ -717,17 +717,17 @@ func ImportExistingPool(ctx context.Context, pool string, key string) error {
 	defer reverter.Fail()
 
 	// Import the existing pool.
-	_, err := subprocess.RunCommandContext(ctx, "zpool", "import", pool)
+	_, err := timeout.RunCommand(ctx, "zpool", "import", pool)
 	if err != nil {
 		return err
 	}
 
 	reverter.Add(func() {
-		_, _ = subprocess.RunCommandContext(ctx, "zpool", "export", "-f", pool)
+		_, _ = timeout.RunCommand(ctx, "zpool", "export", "-f", pool)
 	})
 
 	// Make sure the pool is encrypted.
-	encryptionStatus, err := subprocess.RunCommandContext(ctx, "zfs", "get", "encryption", "-H", "-o", "value", pool)
+	encryptionStatus, err := timeout.RunCommand(ctx, "zfs", "get", "encryption", "-H", "-o", "value", pool)
 	if err != nil {
 		return err
 	}
@@ -737,7 +737,7 @@ func ImportExistingPool(ctx context.Context, pool string, key string) error {
 	}
 
 	// Make sure the pool is uses a raw key.
-	keyFormat, err := subprocess.RunCommandContext(ctx, "zfs", "get", "keyformat", "-H", "-o", "value", pool)
+	keyFormat, err := timeout.RunCommand(ctx, "zfs", "get", "keyformat", "-H", "-o", "value", pool)
 	if err != nil {
 		return err
 	}
@@ -770,13 +770,13 @@ func ImportExistingPool(ctx context.Context, pool string, key string) error {
 	})
 
 	// Make sure the new pool knows where to find the encryption key.
-	_, err = subprocess.RunCommandContext(ctx, "zfs", "set", "keylocation=file://"+keyfilePath, pool)
+	_, err = timeout.RunCommand(ctx, "zfs", "set", "keylocation=file://"+keyfilePath, pool)
 	if err != nil {
 		return err
 	}
 
 	// Load the pool's encryption key.
-	_, err = subprocess.RunCommandContext(ctx, "zfs", "load-key", pool)
+	_, err = timeout.RunCommand(ctx, "zfs", "load-key", pool)
 	if err != nil {
 		return err
 	}
@@ -794,7 +794,7 @@ func CreateDataset(ctx context.Context, poolName string, name string, properties
 		args = append(args, "-o", k+"="+v)
 	}
 
-	_, err := subprocess.RunCommandContext(ctx, "zfs", args...)
+	_, err := timeout.RunCommand(ctx, "zfs", args...)
 
 	return err
 }
@@ -806,7 +806,7 @@ func DestroyDataset(ctx context.Context, poolName string, name string, force boo
 		args = append(args, "-R")
 	}
 
-	_, err := subprocess.RunCommandContext(ctx, "zfs", args...)
+	_, err := timeout.RunCommand(ctx, "zfs", args...)
 
 	return err
 }